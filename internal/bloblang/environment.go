@@ -168,6 +168,33 @@ func (e *Environment) WithoutFunctions(names ...string) *Environment {
 	return &env
 }
 
+// WithCoverage returns a copy of the environment where the execution of
+// statements and branches of mappings parsed from it are recorded in the
+// provided coverage.
+func (e *Environment) WithCoverage(c *query.Coverage) *Environment {
+	env := *e
+	env.pCtx = env.pCtx.WithCoverage(c)
+	return &env
+}
+
+// WithTracing returns a copy of the environment where the steps of queries
+// within mappings parsed from it record their results when executed with a
+// trace, see mapping.Executor.TraceOnto.
+func (e *Environment) WithTracing() *Environment {
+	env := *e
+	env.pCtx = env.pCtx.WithTracing()
+	return &env
+}
+
+// WithTypeChecking returns a copy of the environment where mappings parsed
+// from it retain the information required in order to statically check their
+// types, see mapping.Executor.CheckTypes.
+func (e *Environment) WithTypeChecking() *Environment {
+	env := *e
+	env.pCtx = env.pCtx.WithTypeChecking()
+	return &env
+}
+
 // WithMaxMapRecursion returns a copy of the environment where the maximum
 // recursion allowed for maps is set to a given value. If the execution of a
 // mapping from this environment matches this number of recursive map calls the
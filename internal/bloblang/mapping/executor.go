@@ -3,6 +3,7 @@ package mapping
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
@@ -45,6 +46,7 @@ type Statement struct {
 	input      []rune
 	assignment Assignment
 	query      query.Function
+	coverage   *query.CoveragePoint
 }
 
 // NewStatement initialises a new mapping statement from an Assignment and
@@ -58,6 +60,13 @@ func NewStatement(input []rune, assignment Assignment, query query.Function) Sta
 	}
 }
 
+// WithCoverage returns a copy of the statement where each execution is
+// recorded against a coverage point.
+func (s Statement) WithCoverage(point *query.CoveragePoint) Statement {
+	s.coverage = point
+	return s
+}
+
 //------------------------------------------------------------------------------
 
 // Executor is a parsed bloblang mapping that can be executed on a Benthos
@@ -67,8 +76,12 @@ type Executor struct {
 	input      []rune
 	maps       map[string]query.Function
 	statements []Statement
+	params     []string
+	scopedMaps bool
 
-	maxMapStacks int
+	maxMapStacks  int
+	fieldCache    *query.FieldCache
+	readOnlyValue bool
 }
 
 const defaultMaxMapStacks = 5000
@@ -89,9 +102,50 @@ func NewExecutor(annotation string, input []rune, maps map[string]query.Function
 
 // SetMaxMapRecursion configures the maximum recursion allowed for maps, if the
 // execution of this mapping matches this number of recursive map calls the
-// mapping will error out.
+// mapping will error out. The limit also applies to the maps of the mapping.
 func (e *Executor) SetMaxMapRecursion(m int) {
 	e.maxMapStacks = m
+	for _, v := range e.maps {
+		if mapExec, ok := v.(*Executor); ok {
+			mapExec.maxMapStacks = m
+		}
+	}
+}
+
+// SetParams configures the executor as a map that declares parameters, and can
+// therefore be called as a function with arguments that are bound to the
+// parameters as named contexts, see CallMap. A map with an empty (but non-nil)
+// list of parameters can be called without arguments.
+func (e *Executor) SetParams(params []string) {
+	e.params = params
+}
+
+// SetScopedMaps configures whether the executor resolves the maps it applies
+// from its own map definitions rather than those of the mapping executing it,
+// which is the case for maps imported under a namespace.
+func (e *Executor) SetScopedMaps(scoped bool) {
+	e.scopedMaps = scoped
+}
+
+// Params returns the parameters declared by the map, or nil if the map does not
+// declare parameters and can only be applied to values.
+func (e *Executor) Params() []string {
+	return e.params
+}
+
+// SetFieldCache configures a cache of the field references of the mapping,
+// where the results of common field paths are shared between the queries of an
+// execution.
+func (e *Executor) SetFieldCache(c *query.FieldCache) {
+	e.fieldCache = c
+}
+
+// SetReadOnlyValue configures whether the mapping is known to never mutate the
+// value of the message it is mapped onto in place, which is the case when it
+// only assigns metadata, variables or the root as a whole. This allows MapOnto
+// to avoid copying the structured contents of the message.
+func (e *Executor) SetReadOnlyValue(v bool) {
+	e.readOnlyValue = v
 }
 
 // Annotation returns a string annotation that describes the mapping executor.
@@ -171,22 +225,33 @@ func (e *Executor) mapPart(appendTo *message.Part, index int, reference Message)
 		newPart = reference.Get(index).ShallowCopy()
 	} else {
 		newPart = appendTo
-		if appendObj, err := appendTo.AsStructuredMut(); err == nil {
+
+		var appendObj any
+		var err error
+		if e.readOnlyValue {
+			appendObj, err = appendTo.AsStructured()
+		} else {
+			appendObj, err = appendTo.AsStructuredMut()
+		}
+		if err == nil {
 			newValue = appendObj
 		}
 	}
 
 	vars := map[string]any{}
-
+	ctx := query.FunctionContext{
+		Maps:     e.maps,
+		Vars:     vars,
+		Index:    index,
+		MsgBatch: reference,
+		NewMeta:  newPart,
+		NewValue: &newValue,
+	}.WithValueFunc(lazyValue).WithFieldCache(e.fieldCache)
+
+	var valueAssigned bool
 	for _, stmt := range e.statements {
-		res, err := stmt.query.Exec(query.FunctionContext{
-			Maps:     e.maps,
-			Vars:     vars,
-			Index:    index,
-			MsgBatch: reference,
-			NewMeta:  newPart,
-			NewValue: &newValue,
-		}.WithValueFunc(lazyValue))
+		stmt.coverage.Hit()
+		res, err := stmt.query.Exec(ctx)
 		if err != nil {
 			var line int
 			if len(e.input) > 0 && len(stmt.input) > 0 {
@@ -217,6 +282,18 @@ func (e *Executor) mapPart(appendTo *message.Part, index int, reference Message)
 			}
 			return nil, fmt.Errorf("failed to assign result (line %v): %w", line, err)
 		}
+		if _, isValue := stmt.assignment.(*JSONAssignment); isValue {
+			valueAssigned = true
+			if appendTo != nil {
+				// The value being mapped onto is also the context value.
+				ctx.ResetFieldCache()
+			}
+		}
+	}
+
+	if appendTo != nil && e.readOnlyValue && !valueAssigned {
+		// The original contents are unchanged and still read-only.
+		return newPart, nil
 	}
 
 	switch newValue.(type) {
@@ -266,6 +343,28 @@ func (e *Executor) AssignmentTargets() []TargetPath {
 
 // Exec this function with a context struct.
 func (e *Executor) Exec(ctx query.FunctionContext) (any, error) {
+	if len(e.params) > 0 {
+		return nil, fmt.Errorf("%v expects %v arguments and must be called as a function", e.annotation, len(e.params))
+	}
+	return e.exec(ctx)
+}
+
+// CallMap executes a map that declares parameters with a list of arguments,
+// which are bound to the parameters as named contexts. The map is executed
+// with isolated variables.
+func (e *Executor) CallMap(ctx query.FunctionContext, args []any) (any, error) {
+	if len(args) != len(e.params) {
+		return nil, fmt.Errorf("%v expects %v arguments, got %v", e.annotation, len(e.params), len(args))
+	}
+
+	ctx.Vars = map[string]any{}
+	for i, name := range e.params {
+		ctx = ctx.WithNamedValue(name, args[i])
+	}
+	return e.exec(ctx)
+}
+
+func (e *Executor) exec(ctx query.FunctionContext) (any, error) {
 	ctx, stackCount := ctx.IncrStackCount()
 	if stackCount > e.maxMapStacks {
 		return nil, &errStacks{annotation: e.annotation, maxStacks: e.maxMapStacks}
@@ -273,8 +372,17 @@ func (e *Executor) Exec(ctx query.FunctionContext) (any, error) {
 
 	var newObj any = value.Nothing(nil)
 	ctx.NewValue = &newObj
+	ctx = ctx.WithFieldCache(e.fieldCache)
+
+	// Maps imported under a namespace resolve other maps from those of the
+	// mapping they were declared within, otherwise maps share the namespace of
+	// the mapping being executed.
+	if e.scopedMaps {
+		ctx.Maps = e.maps
+	}
 
 	for _, stmt := range e.statements {
+		stmt.coverage.Hit()
 		res, err := stmt.query.Exec(ctx)
 		if err != nil {
 			return nil, formatExecErr(err, true, e.input, stmt.input)
@@ -297,20 +405,135 @@ func (e *Executor) Exec(ctx query.FunctionContext) (any, error) {
 
 // ExecOnto a provided assignment context.
 func (e *Executor) ExecOnto(ctx query.FunctionContext, onto AssignmentContext) error {
+	ctx = ctx.WithFieldCache(e.fieldCache)
 	for _, stmt := range e.statements {
-		res, err := stmt.query.Exec(ctx)
+		stmt.coverage.Hit()
+		if _, err := e.execStatementOnto(stmt, ctx, onto); err != nil {
+			return err
+		}
+		if _, isValue := stmt.assignment.(*JSONAssignment); isValue {
+			// The value being mapped onto might also be the context value.
+			ctx.ResetFieldCache()
+		}
+	}
+	return nil
+}
+
+func (e *Executor) execStatementOnto(stmt Statement, ctx query.FunctionContext, onto AssignmentContext) (any, error) {
+	res, err := stmt.query.Exec(ctx)
+	if err != nil {
+		return nil, formatExecErr(err, true, e.input, stmt.input)
+	}
+	if _, isNothing := res.(value.Nothing); isNothing {
+		// Skip assignment entirely
+		return res, nil
+	}
+	if err = stmt.assignment.Apply(res, onto); err != nil {
+		return res, formatExecErr(err, false, e.input, stmt.input)
+	}
+	return res, nil
+}
+
+// AssignmentTrace describes the execution of an assignment statement, the
+// results of the traced steps of its query in the order in which they
+// completed, and the state of the assignment context once the result was
+// assigned.
+type AssignmentTrace struct {
+	Line   int
+	Target TargetPath
+	Steps  []query.TraceStep
+	Result any
+	Value  any
+	Meta   map[string]any
+	Vars   map[string]any
+	Err    error
+}
+
+// TraceOnto executes the mapping onto a provided assignment context in the
+// same way as ExecOnto whilst recording a trace of each assignment, up to and
+// including the first that fails. The steps of queries are only traced when
+// the mapping was parsed with tracing enabled.
+func (e *Executor) TraceOnto(ctx query.FunctionContext, onto AssignmentContext) ([]AssignmentTrace, error) {
+	var traces []AssignmentTrace
+	for _, stmt := range e.statements {
+		stmt.coverage.Hit()
+
+		stepTrace := query.NewTrace()
+		res, err := e.execStatementOnto(stmt, ctx.WithTrace(stepTrace), onto)
+
+		t := AssignmentTrace{
+			Target: stmt.assignment.Target(),
+			Steps:  stepTrace.Steps(),
+			Result: value.IClone(res),
+			Err:    err,
+		}
+		if len(e.input) > 0 && len(stmt.input) > 0 {
+			t.Line, _ = LineAndColOf(e.input, stmt.input)
+		}
+		if onto.Value != nil {
+			t.Value = value.IClone(*onto.Value)
+		}
+		if onto.Meta != nil {
+			t.Meta = map[string]any{}
+			_ = onto.Meta.MetaIterMut(func(k string, v any) error {
+				t.Meta[k] = value.IClone(v)
+				return nil
+			})
+		}
+		if onto.Vars != nil {
+			t.Vars = make(map[string]any, len(onto.Vars))
+			for k, v := range onto.Vars {
+				t.Vars[k] = value.IClone(v)
+			}
+		}
+
+		traces = append(traces, t)
 		if err != nil {
-			return formatExecErr(err, true, e.input, stmt.input)
+			return traces, err
 		}
-		if _, isNothing := res.(value.Nothing); isNothing {
-			// Skip assignment entirely
-			continue
+	}
+	return traces, nil
+}
+
+// CheckTypes statically checks the types of the queries of the mapping and
+// its maps without executing them, where the schema describes the input
+// documents and can be nil when the input is unknown. Only definite problems
+// are reported, and most checks require that the mapping was parsed with type
+// checking enabled.
+func (e *Executor) CheckTypes(input *query.TypeSchema) []query.TypeCheckError {
+	mapNames := make([]string, 0, len(e.maps))
+	for k := range e.maps {
+		mapNames = append(mapNames, k)
+	}
+	sort.Strings(mapNames)
+
+	var errs []query.TypeCheckError
+	for _, k := range mapNames {
+		// Maps can be applied to any value and therefore the input is unknown.
+		if m, ok := e.maps[k].(*Executor); ok {
+			errs = append(errs, m.checkStatementTypes(nil)...)
 		}
-		if err = stmt.assignment.Apply(res, onto); err != nil {
-			return formatExecErr(err, false, e.input, stmt.input)
+	}
+	errs = append(errs, e.checkStatementTypes(input)...)
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line == errs[j].Line {
+			return errs[i].Column < errs[j].Column
+		}
+		return errs[i].Line < errs[j].Line
+	})
+	return errs
+}
+
+func (e *Executor) checkStatementTypes(input *query.TypeSchema) []query.TypeCheckError {
+	checker := query.NewTypeChecker(input)
+	for _, stmt := range e.statements {
+		res := checker.Infer(stmt.query)
+		if v, ok := stmt.assignment.(*VarAssignment); ok {
+			checker.SetVar(v.name, res)
 		}
 	}
-	return nil
+	return checker.Errors()
 }
 
 // ToBytes executes this function for a message of a batch and returns the
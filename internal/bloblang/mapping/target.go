@@ -1,5 +1,11 @@
 package mapping
 
+import (
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+)
+
 // TargetType represents a mapping target type, which is a destination for a
 // query result to be mapped into a message.
 type TargetType int
@@ -26,3 +32,21 @@ func NewTargetPath(t TargetType, path ...string) TargetPath {
 		Path: path,
 	}
 }
+
+// String returns a representation of the target as it would appear on the
+// left hand side of a Bloblang assignment.
+func (t TargetPath) String() string {
+	switch t.Type {
+	case TargetMetadata:
+		if len(t.Path) == 0 {
+			return "meta"
+		}
+		return "meta " + t.Path[0]
+	case TargetVariable:
+		return "let " + strings.Join(t.Path, ".")
+	}
+	if len(t.Path) == 0 {
+		return "root"
+	}
+	return "root." + query.SliceToDotPath(t.Path...)
+}
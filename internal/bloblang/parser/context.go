@@ -5,7 +5,9 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
 )
 
@@ -16,6 +18,21 @@ type Context struct {
 	Methods      *query.MethodSet
 	namedContext *namedContext
 	importer     Importer
+	mapScope     *mapScope
+
+	coverage       *query.Coverage
+	coverageSource *query.CoverageSource
+	coverageInput  []rune
+
+	tracing    bool
+	traceInput []rune
+
+	typeChecking bool
+	typeInput    []rune
+
+	unoptimised bool
+	noFolding   bool
+	fieldCache  *query.FieldCache
 }
 
 // EmptyContext returns a parser context with no functions, methods or import
@@ -89,6 +106,122 @@ func (pCtx Context) WithImporterRelativeToFile(pathStr string) Context {
 	return pCtx
 }
 
+// WithCoverage returns a Context where the statements and branches of parsed
+// mappings record their executions in the provided coverage.
+func (pCtx Context) WithCoverage(c *query.Coverage) Context {
+	pCtx.coverage = c
+	return pCtx
+}
+
+// withCoverageSource returns a Context where coverage points are registered
+// against the source of a mapping, which is a no-op when coverage is disabled.
+func (pCtx Context) withCoverageSource(name string, input []rune) Context {
+	if pCtx.coverage == nil {
+		return pCtx
+	}
+	pCtx.coverageSource = pCtx.coverage.Source(name, string(input))
+	pCtx.coverageInput = input
+	return pCtx
+}
+
+// importedFilePath attempts to resolve the full path of an imported file for
+// the purpose of identifying it in coverage reports.
+func (pCtx Context) importedFilePath(pathStr string) string {
+	if i, ok := pCtx.importer.(*osImporter); ok && !filepath.IsAbs(pathStr) {
+		return filepath.Join(i.relativePath, pathStr)
+	}
+	return pathStr
+}
+
+// coverStatement registers a coverage point for a statement parsed from an
+// input clip.
+func (pCtx Context) coverStatement(input []rune, stmt mapping.Statement) mapping.Statement {
+	if pCtx.coverageSource == nil {
+		return stmt
+	}
+	line, _ := mapping.LineAndColOf(pCtx.coverageInput, input)
+	return stmt.WithCoverage(pCtx.coverageSource.Statement(line))
+}
+
+// coverBranch registers a coverage point for a branch of an expression parsed
+// from an input clip, where the body input is the clip of the branch itself.
+func (pCtx Context) coverBranch(input []rune, branch int, bodyInput []rune, fn query.Function) query.Function {
+	if pCtx.coverageSource == nil {
+		return fn
+	}
+	line, col := mapping.LineAndColOf(pCtx.coverageInput, input)
+	bodyLine, _ := mapping.LineAndColOf(pCtx.coverageInput, bodyInput)
+	return query.NewCoveredFunction(pCtx.coverageSource.Branch(line, col, branch, bodyLine), fn)
+}
+
+// WithTracing returns a Context where the steps of queries within parsed
+// mappings are recorded within the trace of a function context, see
+// query.FunctionContext.WithTrace. Steps of imported mappings are not traced.
+func (pCtx Context) WithTracing() Context {
+	pCtx.tracing = true
+	return pCtx
+}
+
+// withTraceSource returns a Context where the positions of traced steps are
+// resolved against the source of a mapping, which is a no-op when tracing is
+// disabled. A nil source disables tracing of the steps that follow.
+func (pCtx Context) withTraceSource(input []rune) Context {
+	if pCtx.tracing {
+		pCtx.traceInput = input
+	}
+	return pCtx
+}
+
+// traceStep wraps a function parsed from an input clip so that its executions
+// are traced, where the remaining input marks the end of the clip. Literals and
+// named contexts are left untouched as they're inspected by other parsers.
+func (pCtx Context) traceStep(input, remaining []rune, fn query.Function) query.Function {
+	if pCtx.traceInput == nil {
+		return fn
+	}
+	switch fn.(type) {
+	case *query.Literal, *query.NamedContextFunction:
+		return fn
+	}
+	line, col := mapping.LineAndColOf(pCtx.traceInput, input)
+	expr := strings.TrimSpace(string(input[:len(input)-len(remaining)]))
+	return query.NewTracedFunction(line, col, expr, fn)
+}
+
+// WithTypeChecking returns a Context where queries within parsed mappings
+// retain the information required in order to statically check their types,
+// see mapping.Executor.CheckTypes. Queries of imported mappings are not
+// checked.
+func (pCtx Context) WithTypeChecking() Context {
+	pCtx.typeChecking = true
+	return pCtx
+}
+
+// withTypeSource returns a Context where the positions of type checked queries
+// are resolved against the source of a mapping, which is a no-op when type
+// checking is disabled. A nil source disables type checking of the queries
+// that follow.
+func (pCtx Context) withTypeSource(input []rune) Context {
+	if pCtx.typeChecking {
+		pCtx.typeInput = input
+	}
+	return pCtx
+}
+
+// typed wraps a function parsed from an input clip with type information
+// using the provided constructor. Literals and named contexts are left
+// untouched as they're inspected by other parsers.
+func (pCtx Context) typed(input []rune, fn query.Function, wrap func(line, col int) query.Function) query.Function {
+	if pCtx.typeInput == nil {
+		return fn
+	}
+	switch fn.(type) {
+	case *query.Literal, *query.NamedContextFunction:
+		return fn
+	}
+	return wrap(mapping.LineAndColOf(pCtx.typeInput, input))
+}
+
 // Deactivated returns a version of the parser context where all functions and
 // methods exist but can no longer be instantiated. This means it's possible to
 // parse and validate mappings but not execute them. If the context also has an
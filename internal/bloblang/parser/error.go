@@ -150,6 +150,19 @@ func (e *Error) Add(from *Error) {
 	}
 }
 
+// ErrorMessage returns a human readable error string without any positional
+// information or snippet of the input, which is useful when the position of the
+// error is presented separately.
+func (e *Error) ErrorMessage() string {
+	if importErr, isImport := e.Err.(*ImportError); isImport {
+		return fmt.Sprintf(
+			"failed to parse import '%v': %v", importErr.filepath,
+			importErr.perr.ErrorAtPosition(importErr.content),
+		)
+	}
+	return e.errorMsg(false)
+}
+
 // ErrorAtPosition returns a human readable error string including the line and
 // character position of the error.
 func (e *Error) ErrorAtPosition(input []rune) string {
@@ -18,8 +18,9 @@ import (
 // messages.
 func ParseMapping(pCtx Context, expr string) (*mapping.Executor, *Error) {
 	in := []rune(expr)
+	pCtx = pCtx.withCoverageSource("", in).withTraceSource(in).withTypeSource(in)
 
-	resDirectImport := singleRootImport(pCtx)(in)
+	resDirectImport := optimisedExecutor(pCtx, singleRootImport)(in)
 	if resDirectImport.Err != nil && resDirectImport.Err.IsFatal() {
 		return nil, resDirectImport.Err
 	}
@@ -27,11 +28,11 @@ func ParseMapping(pCtx Context, expr string) (*mapping.Executor, *Error) {
 		return resDirectImport.Payload.(*mapping.Executor), nil
 	}
 
-	resExe := parseExecutor(pCtx)(in)
+	resExe := optimisedExecutor(pCtx, parseExecutor)(in)
 	if resExe.Err != nil && resExe.Err.IsFatal() {
 		return nil, resExe.Err
 	}
-	resSingle := singleRootMapping(pCtx)(in)
+	resSingle := optimisedExecutor(pCtx, singleRootMapping)(in)
 
 	res := bestMatch(resExe, resSingle)
 	if res.Err != nil {
@@ -42,14 +43,129 @@ func ParseMapping(pCtx Context, expr string) (*mapping.Executor, *Error) {
 
 //------------------------------------------------------------------------------'
 
+// mapScope contains the maps declared within a mapping as it is parsed, along
+// with the parameters of those that can be called as functions and the
+// namespaces of imports. Since maps can be called before they're declared the
+// calls are checked once parsing is complete.
+type mapScope struct {
+	maps       map[string]query.Function
+	params     map[string][]string
+	namespaces map[string]struct{}
+	calls      []mapCall
+}
+
+type mapCall struct {
+	input    []rune
+	name     string
+	args     []any
+	notFound error
+}
+
+func newMapScope() *mapScope {
+	return &mapScope{
+		maps:       map[string]query.Function{},
+		params:     map[string][]string{},
+		namespaces: map[string]struct{}{},
+	}
+}
+
+// callable returns the parameters of a map that can be called as a function.
+func (s *mapScope) callable(name string) ([]string, bool) {
+	if s == nil {
+		return nil, false
+	}
+	params, exists := s.params[name]
+	return params, exists
+}
+
+// call returns a function that calls a map with parsed arguments, which are
+// either all nameless or all named. The call is checked against the parameters
+// of the map once the mapping has been parsed, where the provided error is
+// returned if the map does not exist.
+func (s *mapScope) call(input []rune, name string, args []any, notFound error) (query.Function, error) {
+	var nameless []query.Function
+	var named map[string]query.Function
+	for _, arg := range args {
+		nArg, isNamed := arg.(namedArg)
+		if !isNamed {
+			nameless = append(nameless, arg.(query.Function))
+			continue
+		}
+		if named == nil {
+			named = map[string]query.Function{}
+		}
+		if _, exists := named[nArg.name]; exists {
+			return nil, fmt.Errorf("duplicate named arg: %v", nArg.name)
+		}
+		named[nArg.name] = nArg.value.(query.Function)
+	}
+	if len(nameless) > 0 && len(named) > 0 {
+		return nil, errors.New("cannot mix named and nameless arguments")
+	}
+
+	s.calls = append(s.calls, mapCall{
+		input:    input,
+		name:     name,
+		args:     args,
+		notFound: notFound,
+	})
+	if named != nil {
+		return query.NewNamedMapCallFunction(s.maps, name, named), nil
+	}
+	return query.NewMapCallFunction(s.maps, name, nameless), nil
+}
+
+// checkCalls returns an error for the first call to a map that either does not
+// exist or does not match the parameters of the map.
+func (s *mapScope) checkCalls() *Error {
+	for _, c := range s.calls {
+		params, exists := s.params[c.name]
+		if !exists {
+			return NewFatalError(c.input, c.notFound)
+		}
+
+		if len(c.args) > 0 {
+			if _, isNamed := c.args[0].(namedArg); isNamed {
+			args:
+				for _, arg := range c.args {
+					name := arg.(namedArg).name
+					for _, p := range params {
+						if p == name {
+							continue args
+						}
+					}
+					return NewFatalError(c.input, fmt.Errorf("map %v has no parameter %v", c.name, name))
+				}
+			}
+		}
+		if len(c.args) != len(params) {
+			return NewFatalError(c.input, fmt.Errorf("map %v expects %v arguments, got %v", c.name, len(params), len(c.args)))
+		}
+	}
+	return nil
+}
+
+// hasNamespace returns true if a namespace has been declared by an import.
+func (s *mapScope) hasNamespace(name string) bool {
+	if s == nil {
+		return false
+	}
+	_, exists := s.namespaces[name]
+	return exists
+}
+
 func parseExecutor(pCtx Context) Func {
 	return func(input []rune) Result {
-		maps := map[string]query.Function{}
+		scope := newMapScope()
+		pCtx := pCtx
+		pCtx.mapScope = scope
+
+		maps := scope.maps
 		statements := []mapping.Statement{}
 
 		statement := OneOf(
-			importParser(maps, pCtx),
-			mapParser(maps, pCtx),
+			importParser(pCtx),
+			mapParser(pCtx),
 			letStatementParser(pCtx),
 			metaStatementParser(false, pCtx),
 			plainMappingStatementParser(pCtx),
@@ -59,6 +175,9 @@ func parseExecutor(pCtx Context) Func {
 
 		res = statement(res.Remaining)
 		if res.Err != nil {
+			if err := scope.checkCalls(); err != nil {
+				return Fail(err, input)
+			}
 			res.Remaining = input
 			return res
 		}
@@ -73,6 +192,9 @@ func parseExecutor(pCtx Context) Func {
 			}
 
 			if res = NewlineAllowComment(res.Remaining); res.Err != nil {
+				if err := scope.checkCalls(); err != nil {
+					return Fail(err, input)
+				}
 				return Fail(res.Err, input)
 			}
 
@@ -82,12 +204,18 @@ func parseExecutor(pCtx Context) Func {
 			}
 
 			if res = statement(res.Remaining); res.Err != nil {
+				if err := scope.checkCalls(); err != nil {
+					return Fail(err, input)
+				}
 				return Fail(res.Err, input)
 			}
 			if mStmt, ok := res.Payload.(mapping.Statement); ok {
 				statements = append(statements, mStmt)
 			}
 		}
+		if err := scope.checkCalls(); err != nil {
+			return Fail(err, input)
+		}
 		return Success(mapping.NewExecutor("", input, maps, statements...), res.Remaining)
 	}
 }
@@ -113,9 +241,12 @@ func singleRootImport(pCtx Context) Func {
 			return Fail(NewFatalError(input, fmt.Errorf("failed to read import: %w", err)), input)
 		}
 
-		nextCtx := pCtx.WithImporterRelativeToFile(fpath)
-
 		importContent := []rune(string(contents))
+		nextCtx := pCtx.
+			withCoverageSource(pCtx.importedFilePath(fpath), importContent).
+			withTraceSource(nil).
+			withTypeSource(nil).
+			WithImporterRelativeToFile(fpath)
 		execRes := parseExecutor(nextCtx)(importContent)
 		if execRes.Err != nil {
 			return Fail(NewFatalError(input, NewImportError(fpath, importContent, execRes.Err)), input)
@@ -129,7 +260,8 @@ func singleRootImport(pCtx Context) Func {
 
 func singleRootMapping(pCtx Context) Func {
 	return func(input []rune) Result {
-		res := queryParser(pCtx)(DiscardedWhitespaceNewlineComments(input).Remaining)
+		queryInput := DiscardedWhitespaceNewlineComments(input).Remaining
+		res := queryParser(pCtx)(queryInput)
 		if res.Err != nil {
 			return res
 		}
@@ -154,7 +286,7 @@ func singleRootMapping(pCtx Context) Func {
 			return Fail(NewError(res.Remaining, expStr), input)
 		}
 
-		stmt := mapping.NewStatement(input, mapping.NewJSONAssignment(), fn)
+		stmt := pCtx.coverStatement(queryInput, mapping.NewStatement(input, mapping.NewJSONAssignment(), fn))
 		return Success(mapping.NewExecutor("", input, map[string]query.Function{}, stmt), nil)
 	}
 }
@@ -183,7 +315,13 @@ var importParserComb = Sequence(
 	),
 )
 
-func importParser(maps map[string]query.Function, pCtx Context) Func {
+var importNamespaceParserComb = Sequence(
+	SpacesAndTabs,
+	Term("as"),
+	SpacesAndTabs,
+)
+
+func importParser(pCtx Context) Func {
 	return func(input []rune) Result {
 		res := importParserComb(input)
 		if res.Err != nil {
@@ -191,14 +329,31 @@ func importParser(maps map[string]query.Function, pCtx Context) Func {
 		}
 
 		fpath := res.Payload.([]any)[2].(string)
+
+		var namespace string
+		if nsRes := importNamespaceParserComb(res.Remaining); nsRes.Err == nil {
+			if res = MustBe(Expect(varNameParser, "namespace"))(nsRes.Remaining); res.Err != nil {
+				return Fail(res.Err, input)
+			}
+			if namespace = res.Payload.(string); namespace == "this" || namespace == "root" {
+				return Fail(NewFatalError(nsRes.Remaining, fmt.Errorf("namespace `%v` is not allowed", namespace)), input)
+			}
+			if pCtx.mapScope.hasNamespace(namespace) {
+				return Fail(NewFatalError(nsRes.Remaining, fmt.Errorf("namespace collision: %v", namespace)), input)
+			}
+		}
+
 		contents, err := pCtx.importer.Import(fpath)
 		if err != nil {
 			return Fail(NewFatalError(input, fmt.Errorf("failed to read import: %w", err)), input)
 		}
 
-		nextCtx := pCtx.WithImporterRelativeToFile(fpath)
-
 		importContent := []rune(string(contents))
+		nextCtx := pCtx.
+			withCoverageSource(pCtx.importedFilePath(fpath), importContent).
+			withTraceSource(nil).
+			withTypeSource(nil).
+			WithImporterRelativeToFile(fpath)
 		execRes := parseExecutor(nextCtx)(importContent)
 		if execRes.Err != nil {
 			return Fail(NewFatalError(input, NewImportError(fpath, importContent, execRes.Err)), input)
@@ -210,25 +365,47 @@ func importParser(maps map[string]query.Function, pCtx Context) Func {
 			return Fail(NewFatalError(input, err), input)
 		}
 
+		scope := pCtx.mapScope
 		collisions := []string{}
 		for k, v := range exec.Maps() {
-			if _, exists := maps[k]; exists {
+			if namespace != "" {
+				k = namespace + "." + k
+			}
+			if _, exists := scope.maps[k]; exists {
 				collisions = append(collisions, k)
-			} else {
-				maps[k] = v
+				continue
+			}
+			scope.maps[k] = v
+			if mapExec, ok := v.(*mapping.Executor); ok {
+				if namespace != "" {
+					mapExec.SetScopedMaps(true)
+				}
+				if mapExec.Params() != nil {
+					scope.params[k] = mapExec.Params()
+				}
 			}
 		}
 		if len(collisions) > 0 {
 			err := fmt.Errorf("map name collisions from import '%v': %v", fpath, collisions)
 			return Fail(NewFatalError(input, err), input)
 		}
+		if namespace != "" {
+			scope.namespaces[namespace] = struct{}{}
+		}
 
 		return Success(fpath, res.Remaining)
 	}
 }
 
-func mapParser(maps map[string]query.Function, pCtx Context) Func {
-	p := Sequence(
+var mapParamsParser = DelimitedPattern(
+	Expect(Sequence(charBracketOpen, DiscardedWhitespaceNewlineComments), "map parameters"),
+	MustBe(Expect(varNameParser, "parameter name")),
+	MustBe(Expect(Sequence(Discard(SpacesAndTabs), charComma, DiscardedWhitespaceNewlineComments), "comma")),
+	MustBe(Expect(Sequence(DiscardedWhitespaceNewlineComments, charBracketClose), "closing bracket")),
+)
+
+func mapParser(pCtx Context) Func {
+	header := Sequence(
 		Term("map"),
 		SpacesAndTabs,
 		// Prevents a missing path from being captured by the next parser
@@ -241,8 +418,12 @@ func mapParser(maps map[string]query.Function, pCtx Context) Func {
 				"map name",
 			),
 		),
+		Optional(mapParamsParser),
 		SpacesAndTabs,
-		DelimitedPattern(
+	)
+
+	body := func(pCtx Context) Func {
+		return DelimitedPattern(
 			Sequence(
 				charSquigOpen,
 				DiscardedWhitespaceNewlineComments,
@@ -261,20 +442,50 @@ func mapParser(maps map[string]query.Function, pCtx Context) Func {
 				DiscardedWhitespaceNewlineComments,
 				charSquigClose,
 			),
-		),
-	)
+		)
+	}
 
 	return func(input []rune) Result {
-		res := p(input)
+		res := header(input)
 		if res.Err != nil {
 			return res
 		}
 
 		seqSlice := res.Payload.([]any)
 		ident := seqSlice[2].(string)
-		stmtSlice := seqSlice[4].([]any)
+		scope := pCtx.mapScope
+
+		// Parameters are captured as named contexts.
+		bodyCtx := pCtx
+		var params []string
+		if paramSlice, declared := seqSlice[3].([]any); declared {
+			if nameRes := SnakeCase([]rune(ident)); nameRes.Err != nil || len(nameRes.Remaining) > 0 {
+				return Fail(NewFatalError(input, fmt.Errorf("map %v declares parameters and must therefore have a snake case name", ident)), input)
+			}
+			if _, err := pCtx.Functions.Params(ident); err == nil {
+				return Fail(NewFatalError(input, fmt.Errorf("map %v would shadow a function of the same name", ident)), input)
+			}
 
-		if _, exists := maps[ident]; exists {
+			params = make([]string, 0, len(paramSlice))
+			for _, p := range paramSlice {
+				name := p.(string)
+				if name == "this" || name == "root" {
+					return Fail(NewFatalError(input, fmt.Errorf("parameter name `%v` is not allowed", name)), input)
+				}
+				if bodyCtx.HasNamedContext(name) {
+					return Fail(NewFatalError(input, fmt.Errorf("duplicate parameter name: %v", name)), input)
+				}
+				bodyCtx = bodyCtx.WithNamedContext(name)
+				params = append(params, name)
+			}
+		}
+
+		if res = body(bodyCtx)(res.Remaining); res.Err != nil {
+			return Fail(res.Err, input)
+		}
+		stmtSlice := res.Payload.([]any)
+
+		if _, exists := scope.maps[ident]; exists {
 			return Fail(NewFatalError(input, fmt.Errorf("map name collision: %v", ident)), input)
 		}
 
@@ -283,7 +494,12 @@ func mapParser(maps map[string]query.Function, pCtx Context) Func {
 			statements[i] = v.(mapping.Statement)
 		}
 
-		maps[ident] = mapping.NewExecutor("map "+ident, input, maps, statements...)
+		exec := mapping.NewExecutor("map "+ident, input, scope.maps, statements...)
+		if params != nil {
+			exec.SetParams(params)
+			scope.params[ident] = params
+		}
+		scope.maps[ident] = exec
 
 		return Success(ident, res.Remaining)
 	}
@@ -316,11 +532,11 @@ func letStatementParser(pCtx Context) Func {
 		}
 		resSlice := res.Payload.([]any)
 		return Success(
-			mapping.NewStatement(
+			pCtx.coverStatement(input, mapping.NewStatement(
 				input,
 				mapping.NewVarAssignment(resSlice[2].(string)),
 				resSlice[6].(query.Function),
-			),
+			)),
 			res.Remaining,
 		)
 	}
@@ -371,11 +587,11 @@ func metaStatementParser(disabled bool, pCtx Context) Func {
 		}
 
 		return Success(
-			mapping.NewStatement(
+			pCtx.coverStatement(input, mapping.NewStatement(
 				input,
 				mapping.NewMetaAssignment(keyPtr),
 				resSlice[6].(query.Function),
-			),
+			)),
 			res.Remaining,
 		)
 	}
@@ -467,11 +683,11 @@ func plainMappingStatementParser(pCtx Context) Func {
 		}
 
 		return Success(
-			mapping.NewStatement(
+			pCtx.coverStatement(input, mapping.NewStatement(
 				input,
 				mapping.NewJSONAssignment(path...),
 				resSlice[4].(query.Function),
-			),
+			)),
 			res.Remaining,
 		)
 	}
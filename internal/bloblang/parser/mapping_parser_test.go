@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,7 +10,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
 	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/value"
 )
 
 func TestMappingErrors(t *testing.T) {
@@ -196,6 +200,11 @@ func TestMappings(t *testing.T) {
 	directMapFile := filepath.Join(dir, "direct_map.blobl")
 	require.NoError(t, os.WriteFile(directMapFile, []byte(`root.nested = this`), 0o777))
 
+	applyingMapFile := filepath.Join(dir, "applying_map.blobl")
+	require.NoError(t, os.WriteFile(applyingMapFile, []byte(`map a {
+  root = this.apply("b")
+}`), 0o777))
+
 	type part struct {
 		Content string
 		Meta    map[string]any
@@ -516,6 +525,21 @@ root = this.apply("foo")`, goodMapFile),
 				Content: `{"foo":"this is valid","nested":{"outer":{"inner":"hello world"}}}`,
 			},
 		},
+		"test imported map applies map of importing mapping": {
+			mapping: fmt.Sprintf(`import "%v"
+
+map b {
+  root = "B"
+}
+
+root = this.apply("a")`, applyingMapFile),
+			input: []part{
+				{Content: `{}`},
+			},
+			output: part{
+				Content: `B`,
+			},
+		},
 		"test directly imported mapping": {
 			mapping: fmt.Sprintf(`from "%v"`, directMapFile),
 			input: []part{
@@ -573,6 +597,297 @@ from "%v"`, directMapFile),
 	}
 }
 
+func TestMappingMapCalls(t *testing.T) {
+	dir := t.TempDir()
+
+	libFile := filepath.Join(dir, "lib.blobl")
+	require.NoError(t, os.WriteFile(libFile, []byte(`map normalise(v) {
+  root = trim_lower(v)
+}
+
+map trim_lower(s) {
+  root = s.trim().lowercase()
+}
+
+map tags {
+  root = this.map_each(t -> t.apply("tag"))
+}
+
+map tag {
+  root = this.uppercase()
+}`), 0o777))
+
+	otherLibFile := filepath.Join(dir, "other_lib.blobl")
+	require.NoError(t, os.WriteFile(otherLibFile, []byte(`map normalise(v) {
+  root = v.uppercase()
+}`), 0o777))
+
+	tests := map[string]struct {
+		mapping string
+		input   string
+		output  string
+	}{
+		"map with parameters": {
+			mapping: `map greet(greeting, name) {
+  root = "%s %s!".format(greeting, name)
+}
+root = greet("hello", this.name)`,
+			input:  `{"name":"foo"}`,
+			output: `hello foo!`,
+		},
+		"map with named arguments": {
+			mapping: `map greet(greeting, name) {
+  root = "%s %s!".format(greeting, name)
+}
+root = greet(name: this.name, greeting: "hey")`,
+			input:  `{"name":"foo"}`,
+			output: `hey foo!`,
+		},
+		"map without parameters": {
+			mapping: `map answer() {
+  root = 42
+}
+root.a = answer()
+root.b = this.apply("answer")`,
+			input:  `{}`,
+			output: `{"a":42,"b":42}`,
+		},
+		"map parameter paths": {
+			mapping: `map full_name(person) {
+  root = person.first + " " + person.last
+}
+root = full_name(this.user)`,
+			input:  `{"user":{"first":"foo","last":"bar"}}`,
+			output: `foo bar`,
+		},
+		"map context is that of the caller": {
+			mapping: `map with_id(v) {
+  root.id = this.id
+  root.value = v
+}
+root = with_id(this.value)`,
+			input:  `{"id":"abc","value":10}`,
+			output: `{"id":"abc","value":10}`,
+		},
+		"map called before declared": {
+			mapping: `root = add(1, 2)
+map add(a, b) {
+  root = double(a) + b
+}
+map double(n) {
+  root = n * 2
+}`,
+			input:  `{}`,
+			output: `4`,
+		},
+		"map variables are isolated": {
+			mapping: `map add(a, b) {
+  let sum = a + b
+  root = $sum
+}
+let sum = "unchanged"
+root.sum = add(1, 2)
+root.var = $sum`,
+			input:  `{}`,
+			output: `{"sum":3,"var":"unchanged"}`,
+		},
+		"recursive map": {
+			mapping: `map fib(n) {
+  root = if n < 2 { n } else { fib(n - 1) + fib(n - 2) }
+}
+root = fib(this.n)`,
+			input:  `{"n":10}`,
+			output: `55`,
+		},
+		"map calls within lambdas": {
+			mapping: `map double(n) {
+  root = n * 2
+}
+root = this.nums.map_each(n -> double(n))`,
+			input:  `{"nums":[1,2,3]}`,
+			output: `[2,4,6]`,
+		},
+		"imported map with parameters": {
+			mapping: fmt.Sprintf(`import "%v"
+root = normalise(this.name)`, libFile),
+			input:  `{"name":"  FOO "}`,
+			output: `foo`,
+		},
+		"namespaced import": {
+			mapping: fmt.Sprintf(`import "%v" as lib
+import "%v" as other
+root.a = lib.normalise(this.name)
+root.b = other.normalise(this.name)
+root.c = this.tags.apply("lib.tags")`, libFile, otherLibFile),
+			input:  `{"name":" Foo ","tags":["a","b"]}`,
+			output: `{"a":"foo","b":" FOO ","c":["A","B"]}`,
+		},
+		"namespace does not shadow fields": {
+			mapping: fmt.Sprintf(`import "%v" as lib
+root.a = lib.normalise
+root.b = this.lib.normalise.uppercase()
+root.c = lib.normalise.uppercase()`, libFile),
+			input:  `{"lib":{"normalise":"foo"}}`,
+			output: `{"a":"foo","b":"FOO","c":"FOO"}`,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			exec, perr := ParseMapping(GlobalContext(), test.mapping)
+			require.Nil(t, perr)
+
+			resPart, err := exec.MapPart(0, message.QuickBatch([][]byte{[]byte(test.input)}))
+			require.NoError(t, err)
+			assert.Equal(t, test.output, string(resPart.AsBytes()))
+		})
+	}
+}
+
+func TestMappingMapCallErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	libFile := filepath.Join(dir, "lib.blobl")
+	require.NoError(t, os.WriteFile(libFile, []byte(`map normalise(v) {
+  root = v.lowercase()
+}`), 0o777))
+
+	tests := map[string]struct {
+		mapping     string
+		errContains string
+	}{
+		"too few arguments": {
+			mapping: `map add(a, b) {
+  root = a + b
+}
+root = add(1)`,
+			errContains: `line 4 char 14: map add expects 2 arguments, got 1`,
+		},
+		"unknown named argument": {
+			mapping: `map add(a, b) {
+  root = a + b
+}
+root = add(a: 1, c: 2)`,
+			errContains: `line 4 char 23: map add has no parameter c`,
+		},
+		"mixed arguments": {
+			mapping: `map add(a, b) {
+  root = a + b
+}
+root = add(1, b: 2)`,
+			errContains: `line 4 char 20: cannot mix named and nameless arguments`,
+		},
+		"unknown function": {
+			mapping: `map add(a, b) {
+  root = a + b
+}
+root = nope(1, 2)`,
+			errContains: `line 4 char 18: unrecognised function 'nope'`,
+		},
+		"unknown function before parse error": {
+			mapping: `root.a = nope(1, 2)
+root.b = this.foo.(`,
+			errContains: `line 1 char 20: unrecognised function 'nope'`,
+		},
+		"map shadows function": {
+			mapping: `map uuid_v4(a) {
+  root = a
+}`,
+			errContains: `line 1 char 1: map uuid_v4 would shadow a function of the same name`,
+		},
+		"duplicate parameters": {
+			mapping: `map add(a, a) {
+  root = a + a
+}`,
+			errContains: `line 1 char 1: duplicate parameter name: a`,
+		},
+		"reserved parameter name": {
+			mapping: `map foo(this) {
+  root = this
+}`,
+			errContains: "line 1 char 1: parameter name `this` is not allowed",
+		},
+		"unknown map within namespace": {
+			mapping: fmt.Sprintf(`import "%v" as lib
+root = lib.nope(this)`, libFile),
+			errContains: `line 2 char 22: unrecognised map 'lib.nope'`,
+		},
+		"namespace collision": {
+			mapping: fmt.Sprintf(`import "%v" as lib
+import "%v" as lib`, libFile, libFile),
+			errContains: `namespace collision: lib`,
+		},
+		"reserved namespace": {
+			mapping:     fmt.Sprintf(`import "%v" as this`, libFile),
+			errContains: "namespace `this` is not allowed",
+		},
+		"missing namespace": {
+			mapping:     fmt.Sprintf(`import "%v" as `, libFile),
+			errContains: `expected namespace`,
+		},
+		"namespaced maps do not collide": {
+			mapping: fmt.Sprintf(`import "%v" as lib
+import "%v"
+import "%v"`, libFile, libFile, libFile),
+			errContains: fmt.Sprintf(`line 3 char 1: map name collisions from import '%v': [normalise]`, libFile),
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			exec, err := ParseMapping(GlobalContext(), test.mapping)
+			require.NotNil(t, err)
+			assert.Contains(t, err.ErrorAtPosition([]rune(test.mapping)), test.errContains)
+			assert.Nil(t, exec)
+		})
+	}
+}
+
+func TestMappingMapCallRecursionLimit(t *testing.T) {
+	exec, perr := ParseMapping(GlobalContext(), `map forever(n) {
+  root = forever(n + 1)
+}
+root = forever(0)`)
+	require.Nil(t, perr)
+
+	exec.SetMaxMapRecursion(10)
+	_, err := exec.MapPart(0, message.QuickBatch([][]byte{[]byte(`{}`)}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "entering map forever exceeded maximum allowed stacks of 10")
+}
+
+func TestMappingMapCallTargets(t *testing.T) {
+	exec, perr := ParseMapping(GlobalContext(), `map walk(v) {
+  root.id = this.id
+  root.children = v.map_each(c -> walk(c.children))
+}
+map walk_applied {
+  root = this.children.map_each(c -> c.apply("walk_applied"))
+}
+root.a = walk(this.children)
+root.b = this.tree.apply("walk_applied")`)
+	require.Nil(t, perr)
+
+	_, targets := exec.QueryTargets(query.TargetsContext{Maps: exec.Maps()})
+	assert.Contains(t, targets, query.NewTargetPath(query.TargetValue, "children"))
+	assert.Contains(t, targets, query.NewTargetPath(query.TargetValue, "id"))
+	assert.Contains(t, targets, query.NewTargetPath(query.TargetValue, "tree", "children"))
+}
+
+func TestMappingMapWithParametersApplied(t *testing.T) {
+	exec, perr := ParseMapping(GlobalContext(), `map add(a, b) {
+  root = a + b
+}
+root = this.apply("add")`)
+	require.Nil(t, perr)
+
+	_, err := exec.MapPart(0, message.QuickBatch([][]byte{[]byte(`{}`)}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "map add expects 2 arguments and must be called as a function")
+}
+
 func BenchmarkMappingParser(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_, err := ParseMapping(GlobalContext(), `
@@ -585,3 +900,301 @@ root.baz = this.baz.slice(0, 10)
 		}
 	}
 }
+
+func TestMappingCoverage(t *testing.T) {
+	dir := t.TempDir()
+
+	importFile := filepath.Join(dir, "imported.blobl")
+	require.NoError(t, os.WriteFile(importFile, []byte(`map double {
+  root = this * 2
+}`), 0o777))
+
+	mapping := fmt.Sprintf(`import "%v"
+root.a = this.a.apply("double")
+root.b = if this.b > 10 {
+  "big"
+} else if this.b > 5 {
+  "medium"
+}
+root.c = match this.c {
+  "foo" => 1
+  "bar" => 2
+}
+`, importFile)
+
+	cov := query.NewCoverage()
+	pCtx := GlobalContext().WithCoverage(cov)
+
+	for i := 0; i < 2; i++ {
+		exec, err := ParseMapping(pCtx, mapping)
+		require.Nil(t, err)
+
+		for _, doc := range []string{
+			`{"a":1,"b":20,"c":"foo"}`,
+			`{"a":2,"b":1,"c":"foo"}`,
+		} {
+			_, mErr := exec.MapPart(0, message.QuickBatch([][]byte{[]byte(doc)}))
+			require.NoError(t, mErr)
+		}
+	}
+
+	sources := cov.Sources()
+	require.Len(t, sources, 2)
+
+	assert.Equal(t, "", sources[0].Name)
+	assert.Equal(t, []query.CoverageLine{
+		{Line: 2, Hits: 4},
+		{Line: 3, Hits: 4},
+		{Line: 5, Hits: 0},
+		{Line: 8, Hits: 4},
+		{Line: 9, Hits: 4},
+		{Line: 10, Hits: 0},
+	}, sources[0].Lines())
+	assert.Equal(t, []query.CoverageBranch{
+		{Line: 3, Block: 0, Branch: 0, Hits: 2},
+		{Line: 3, Block: 0, Branch: 1, Hits: 0},
+		{Line: 3, Block: 0, Branch: 2, Hits: 2},
+		{Line: 8, Block: 1, Branch: 0, Hits: 4},
+		{Line: 8, Block: 1, Branch: 1, Hits: 0},
+		{Line: 8, Block: 1, Branch: 2, Hits: 0},
+	}, sources[0].Branches())
+
+	assert.Equal(t, importFile, sources[1].Name)
+	assert.Equal(t, []query.CoverageLine{
+		{Line: 2, Hits: 4},
+	}, sources[1].Lines())
+	assert.Empty(t, sources[1].Branches())
+}
+
+func traceMapping(t *testing.T, pCtx Context, m, input string) ([]mapping.AssignmentTrace, error) {
+	t.Helper()
+
+	exec, pErr := ParseMapping(pCtx, m)
+	require.Nil(t, pErr)
+
+	part := message.NewPart([]byte(input))
+	inputValue, err := part.AsStructured()
+	require.NoError(t, err)
+
+	vars := map[string]any{}
+	var result any = value.Nothing(nil)
+	return exec.TraceOnto(query.FunctionContext{
+		Maps:     exec.Maps(),
+		Vars:     vars,
+		MsgBatch: message.Batch{part},
+		NewMeta:  part,
+		NewValue: &result,
+	}.WithValueFunc(func() *any { return &inputValue }), mapping.AssignmentContext{
+		Vars:  vars,
+		Meta:  part,
+		Value: &result,
+	})
+}
+
+func TestMappingTrace(t *testing.T) {
+	type step struct {
+		expr  string
+		value any
+	}
+
+	stepsOf := func(trace mapping.AssignmentTrace) (steps []step) {
+		for _, s := range trace.Steps {
+			require.NoError(t, s.Err)
+			steps = append(steps, step{expr: s.Expression, value: s.Value})
+		}
+		return
+	}
+
+	nums := []any{json.Number("1"), json.Number("2"), json.Number("3")}
+
+	traces, err := traceMapping(t, GlobalContext().WithTracing(), `let factor = 2
+root.doubled = this.nums.map_each(n -> n * $factor).filter(n -> n > 2)
+meta count = this.nums.length()
+root.neg = !this.flag
+root.doubled = deleted()`, `{"nums":[1,2,3],"flag":true}`)
+	require.NoError(t, err)
+	require.Len(t, traces, 5)
+
+	assert.Equal(t, 1, traces[0].Line)
+	assert.Equal(t, "let factor", traces[0].Target.String())
+	assert.Empty(t, traces[0].Steps)
+	assert.Equal(t, int64(2), traces[0].Result)
+	assert.Equal(t, map[string]any{"factor": int64(2)}, traces[0].Vars)
+
+	assert.Equal(t, 2, traces[1].Line)
+	assert.Equal(t, "root.doubled", traces[1].Target.String())
+	assert.Equal(t, []step{
+		{expr: "this", value: map[string]any{"nums": nums, "flag": true}},
+		{expr: ".nums", value: nums},
+		{expr: "n", value: json.Number("1")},
+		{expr: "$factor", value: int64(2)},
+		{expr: "n", value: json.Number("2")},
+		{expr: "$factor", value: int64(2)},
+		{expr: "n", value: json.Number("3")},
+		{expr: "$factor", value: int64(2)},
+		{expr: ".map_each(n -> n * $factor)", value: []any{int64(2), int64(4), int64(6)}},
+		{expr: "n", value: int64(2)},
+		{expr: "n", value: int64(4)},
+		{expr: "n", value: int64(6)},
+		{expr: ".filter(n -> n > 2)", value: []any{int64(4), int64(6)}},
+	}, stepsOf(traces[1]))
+	assert.Equal(t, 16, traces[1].Steps[0].Column)
+	assert.Equal(t, 25, traces[1].Steps[8].Column)
+	assert.Equal(t, 52, traces[1].Steps[12].Column)
+	assert.Equal(t, map[string]any{"doubled": []any{int64(4), int64(6)}}, traces[1].Value)
+
+	assert.Equal(t, "meta count", traces[2].Target.String())
+	assert.Equal(t, map[string]any{"count": int64(3)}, traces[2].Meta)
+
+	assert.Equal(t, []step{
+		{expr: "this", value: map[string]any{"nums": nums, "flag": true}},
+		{expr: ".flag", value: true},
+	}, stepsOf(traces[3]))
+	assert.Equal(t, 13, traces[3].Steps[0].Column)
+	assert.Equal(t, false, traces[3].Result)
+	assert.Equal(t, map[string]any{"doubled": []any{int64(4), int64(6)}, "neg": false}, traces[3].Value)
+
+	// Values recorded by earlier assignments are not modified by later ones.
+	assert.Empty(t, traces[4].Steps)
+	assert.Equal(t, value.Delete(nil), traces[4].Result)
+	assert.Equal(t, map[string]any{"neg": false}, traces[4].Value)
+	assert.Equal(t, map[string]any{"doubled": []any{int64(4), int64(6)}}, traces[1].Value)
+}
+
+func TestMappingTraceError(t *testing.T) {
+	traces, err := traceMapping(t, GlobalContext().WithTracing(), `root.a = "foo"
+root.b = this.nope.uppercase()
+root.c = "bar"`, `{"nope":null}`)
+	require.Error(t, err)
+	require.Len(t, traces, 2)
+
+	assert.Equal(t, map[string]any{"a": "foo"}, traces[0].Value)
+
+	require.Len(t, traces[1].Steps, 3)
+	assert.Equal(t, ".uppercase()", traces[1].Steps[2].Expression)
+	assert.Error(t, traces[1].Steps[2].Err)
+	assert.Equal(t, err, traces[1].Err)
+	assert.Equal(t, map[string]any{"a": "foo"}, traces[1].Value)
+}
+
+func TestMappingTraceDisabled(t *testing.T) {
+	traces, err := traceMapping(t, GlobalContext(), `root.a = this.a.uppercase()`, `{"a":"foo"}`)
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+
+	assert.Empty(t, traces[0].Steps)
+	assert.Equal(t, "FOO", traces[0].Result)
+	assert.Equal(t, map[string]any{"a": "FOO"}, traces[0].Value)
+}
+
+func TestMappingTypeChecking(t *testing.T) {
+	tests := map[string]struct {
+		mapping string
+		schema  string
+		errs    []string
+	}{
+		"no problems": {
+			mapping: `root.a = this.a.uppercase()
+root.b = 10 + this.b.length()
+root.c = "foo".uppercase().length()`,
+		},
+		"method on literal": {
+			mapping: `root.a = 10.uppercase()`,
+			errs: []string{
+				"line 1 char 13: method uppercase: expected string or bytes value, got number",
+			},
+		},
+		"arithmetic on method results": {
+			mapping: `root.a = this.a.length() + "foo"
+root.b = $nope
+let foo = "bar"
+root.c = $foo - 5`,
+			errs: []string{
+				"line 1 char 10: cannot add types number and string",
+				"line 4 char 10: cannot subtract types string and number",
+			},
+		},
+		"errors are caught": {
+			mapping: `root.a = 10.uppercase().catch("nope")
+root.b = (10.uppercase() | "nope")
+root.c = (10 - 10.uppercase()).or(0)`,
+		},
+		"map bodies": {
+			mapping: `map foo {
+  root.a = this.a.length().uppercase()
+}
+root = this.apply("foo")`,
+			errs: []string{
+				"line 2 char 28: method uppercase: expected string or bytes value, got number",
+			},
+		},
+		"unreachable match cases": {
+			mapping: `root.a = match this.a.length() {
+  "foo" => "first"
+  5 => "second"
+  5 => "third"
+  _ => "fourth"
+  10 => "fifth"
+}`,
+			errs: []string{
+				"line 2 char 3: match case is unreachable as a number value can never equal a string literal",
+				"line 4 char 3: match case is unreachable as it duplicates a previous case",
+				"line 6 char 3: match case is unreachable as a previous case always matches",
+			},
+		},
+		"input schema": {
+			mapping: `root.a = this.name.uppercase()
+root.b = this.age.uppercase()
+root.c = this.tags.join(",")
+root.d = this.tags.index(0).length()
+root.e = this.nope.uppercase()`,
+			schema: `{
+  "type": "object",
+  "properties": {
+    "name": { "type": "string" },
+    "age": { "type": "integer" },
+    "tags": { "type": "array", "items": { "type": "string" } }
+  },
+  "required": [ "name", "age", "tags" ],
+  "additionalProperties": false
+}`,
+			errs: []string{
+				"line 2 char 19: method uppercase: expected string or bytes value, got number",
+			},
+		},
+		"optional schema fields": {
+			mapping: `root.a = this.name.uppercase()`,
+			schema:  `{"type":"object","properties":{"name":{"type":"boolean"}}}`,
+			errs: []string{
+				"line 1 char 20: method uppercase: expected string or bytes value, got bool or null",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			exec, perr := ParseMapping(GlobalContext().WithTypeChecking(), test.mapping)
+			require.Nil(t, perr)
+
+			var schema *query.TypeSchema
+			if test.schema != "" {
+				var err error
+				schema, err = query.NewTypeSchemaFromJSONSchema([]byte(test.schema))
+				require.NoError(t, err)
+			}
+
+			var errs []string
+			for _, err := range exec.CheckTypes(schema) {
+				errs = append(errs, err.Error())
+			}
+			assert.Equal(t, test.errs, errs)
+		})
+	}
+}
+
+func TestMappingTypeCheckingDisabled(t *testing.T) {
+	exec, perr := ParseMapping(GlobalContext(), `root.a = 10.uppercase()`)
+	require.Nil(t, perr)
+	assert.Empty(t, exec.CheckTypes(nil))
+}
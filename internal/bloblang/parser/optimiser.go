@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+)
+
+// WithoutOptimisation returns a Context where parsed mappings are executed
+// exactly as they're written, without evaluating static queries at parse time
+// or sharing the results of common field paths. This is mostly useful for
+// measuring the effect of optimisations.
+func (pCtx Context) WithoutOptimisation() Context {
+	pCtx.unoptimised = true
+	return pCtx
+}
+
+// withoutFolding returns a Context where static queries are not evaluated at
+// parse time, which is necessary where a literal value has a different meaning
+// to a query, such as the patterns of match cases.
+func (pCtx Context) withoutFolding() Context {
+	pCtx.noFolding = true
+	return pCtx
+}
+
+func (pCtx Context) folding() bool {
+	// Folding is disabled when tracing as folded queries would be missing from
+	// traces.
+	return !pCtx.unoptimised && !pCtx.noFolding && pCtx.traceInput == nil
+}
+
+// foldMethod attempts to replace a method with a literal of its result when its
+// target and arguments are static.
+func (pCtx Context) foldMethod(name string, target query.Function, args *query.ParsedParams, fn query.Function) query.Function {
+	if !pCtx.folding() {
+		return fn
+	}
+	spec, exists := pCtx.Methods.Spec(name)
+	if !exists || !query.FoldableMethod(spec, target, args) {
+		return fn
+	}
+	return query.Fold(fn)
+}
+
+// foldNot attempts to replace a not operator with a literal of its result when
+// its target is static.
+func (pCtx Context) foldNot(target, fn query.Function) query.Function {
+	if !pCtx.folding() {
+		return fn
+	}
+	if _, isLit := target.(*query.Literal); !isLit {
+		return fn
+	}
+	return query.Fold(fn)
+}
+
+// internField registers a field reference with the field cache of the mapping
+// being parsed, if there is one.
+func (pCtx Context) internField(fn query.Function) query.Function {
+	if pCtx.fieldCache == nil {
+		return fn
+	}
+	return pCtx.fieldCache.Intern(fn)
+}
+
+// optimisedExecutor wraps a parser of a mapping executor so that the field
+// references of each attempt are registered with a field cache of their own,
+// which is finalised and attached to the executor once parsed.
+func optimisedExecutor(pCtx Context, parser func(pCtx Context) Func) Func {
+	return func(input []rune) Result {
+		if pCtx.unoptimised || pCtx.traceInput != nil {
+			return parser(pCtx)(input)
+		}
+
+		execCtx := pCtx
+		execCtx.fieldCache = query.NewFieldCache()
+
+		res := parser(execCtx)(input)
+		if res.Err != nil {
+			return res
+		}
+
+		exec := res.Payload.(*mapping.Executor)
+		execCtx.fieldCache.Finalise()
+		exec.SetFieldCache(execCtx.fieldCache)
+
+		readOnly := true
+		for _, t := range exec.AssignmentTargets() {
+			if t.Type == mapping.TargetValue && len(t.Path) > 0 {
+				readOnly = false
+				break
+			}
+		}
+		exec.SetReadOnlyValue(readOnly)
+		return res
+	}
+}
@@ -0,0 +1,330 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+func TestOptimiserFolding(t *testing.T) {
+	tests := map[string]struct {
+		query  string
+		folded any
+	}{
+		"method on literal": {
+			query:  `"foo".uppercase()`,
+			folded: "FOO",
+		},
+		"chained methods": {
+			query:  `"foo,bar".split(",").join("-").length()`,
+			folded: int64(7),
+		},
+		"method with literal args": {
+			query:  `"foo bar".replace_all("bar", "baz")`,
+			folded: "foo baz",
+		},
+		"arithmetic of folded methods": {
+			query:  `"foo".length() + "ba".length()`,
+			folded: int64(5),
+		},
+		"not operator": {
+			query:  `!"foo".has_prefix("f")`,
+			folded: false,
+		},
+		"method on field": {
+			query: `this.foo.uppercase()`,
+		},
+		"method with dynamic args": {
+			query: `"foo".has_prefix(this.prefix)`,
+		},
+		"method with query args": {
+			query: `[1, 2].map_each(this * 2)`,
+		},
+		"method with non-deterministic args": {
+			query: `null.or(uuid_v4())`,
+		},
+		"method that fails": {
+			query: `"foo".number()`,
+		},
+		"contextual method": {
+			query: `"foo".from(0)`,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			res := queryParser(GlobalContext())([]rune(test.query))
+			require.Nil(t, res.Err)
+
+			lit, isLit := res.Payload.(*query.Literal)
+			if test.folded == nil {
+				assert.False(t, isLit, "%T", res.Payload)
+				return
+			}
+			require.True(t, isLit, "%T", res.Payload)
+			assert.Equal(t, test.folded, lit.Value)
+
+			res = queryParser(GlobalContext().WithoutOptimisation())([]rune(test.query))
+			require.Nil(t, res.Err)
+			_, isLit = res.Payload.(*query.Literal)
+			assert.False(t, isLit)
+		})
+	}
+}
+
+func TestOptimiserEquivalence(t *testing.T) {
+	tests := map[string]struct {
+		mapping string
+		input   string
+		mutate  bool
+	}{
+		"repeated paths": {
+			mapping: `root.a = this.user.name.uppercase()
+root.b = this.user.name.lowercase()
+root.c = this.user.age + this.user.age
+root.d = this.user.address.city
+root.e = this.user.address.zip | "none"`,
+			input: `{"user":{"name":"Foo","age":10,"address":{"city":"London"}}}`,
+		},
+		"repeated paths within contexts": {
+			mapping: `root.a = this.items.map_each(this.value * 2)
+root.b = this.items.map_each(item -> item.value + this.offset)
+root.c = this.items.map_each(this.value)
+root.d = this.offset
+root.e = match this.items.index(0) {
+  this.value > 1 => "big"
+  _ => this.value
+}`,
+			input: `{"items":[{"value":1},{"value":2},{"value":3}],"offset":10}`,
+		},
+		"wildcard paths": {
+			mapping: `root.a = this.get("items.*.value")
+root.b = this.get("items.*")
+root.c = this.items.0.value
+root.d = this.get("items.*.value").sum()`,
+			input: `{"items":[{"value":1},{"value":2}]}`,
+		},
+		"empty wildcard paths": {
+			mapping: `root.a = this.get("items.*")
+root.b = this.get("items.*.value")`,
+			input: `{"items":[]}`,
+		},
+		"missing paths": {
+			mapping: `root.a = this.foo.bar.baz
+root.b = this.foo.bar
+root.c = this.foo.bar.baz.buz`,
+			input: `{"foo":{"bar":null}}`,
+		},
+		"maps": {
+			mapping: `map thing {
+  root.name = this.user.name
+  root.age = this.user.age
+}
+root.a = this.apply("thing")
+root.b = this.other.apply("thing")
+root.c = this.user.name`,
+			input: `{"user":{"name":"foo","age":10},"other":{"user":{"name":"bar"}}}`,
+		},
+		"mutations of the context value": {
+			mapping: `root.a = this.foo.bar
+root.foo.bar = "changed"
+root.b = this.foo.bar
+root.foo = {"bar":"replaced"}
+root.c = this.foo.bar`,
+			input:  `{"foo":{"bar":"original"}}`,
+			mutate: true,
+		},
+		"metadata only mutations": {
+			mapping: `meta foo = this.foo.bar
+meta bar = this.foo.bar.uppercase()`,
+			input:  `{"foo":{"bar":"baz"},  "b": 1.0}`,
+			mutate: true,
+		},
+		"root replacement mutations": {
+			mapping: `root = this.foo
+root.baz = this.bar`,
+			input:  `{"foo":{"bar":"baz"}}`,
+			mutate: true,
+		},
+		"folded match patterns": {
+			mapping: `root.result = match "FOO" {
+  "foo".uppercase() => "matched"
+  _ => "not matched"
+}`,
+			input: `{}`,
+		},
+	}
+
+	execute := func(t testing.TB, pCtx Context, mapping, input string, mutate bool) (any, map[string]any) {
+		t.Helper()
+
+		exec, perr := ParseMapping(pCtx, mapping)
+		require.Nil(t, perr)
+
+		part := message.NewPart([]byte(input))
+		var res *message.Part
+		var err error
+		if mutate {
+			res, err = exec.MapOnto(part, 0, message.Batch{part})
+		} else {
+			res, err = exec.MapPart(0, message.Batch{part})
+		}
+		require.NoError(t, err)
+
+		meta := map[string]any{}
+		_ = res.MetaIterMut(func(k string, v any) error {
+			meta[k] = v
+			return nil
+		})
+
+		// Mutations that only assign metadata are expected to leave the raw
+		// contents of a message untouched, and therefore only the structured
+		// forms are compared.
+		v, err := res.AsStructured()
+		require.NoError(t, err)
+		return v, meta
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			expValue, expMeta := execute(t, GlobalContext().WithoutOptimisation(), test.mapping, test.input, test.mutate)
+			actValue, actMeta := execute(t, GlobalContext(), test.mapping, test.input, test.mutate)
+			assert.Equal(t, expValue, actValue)
+			assert.Equal(t, expMeta, actMeta)
+		})
+	}
+}
+
+func TestOptimiserReadOnlyMutation(t *testing.T) {
+	exec, perr := ParseMapping(GlobalContext(), `meta foo = this.foo.uppercase()`)
+	require.Nil(t, perr)
+
+	input := map[string]any{"foo": "bar"}
+	part := message.NewPart(nil)
+	part.SetStructured(input)
+	original := part.ShallowCopy()
+
+	res, err := exec.MapOnto(part, 0, message.Batch{part})
+	require.NoError(t, err)
+	assert.Equal(t, "BAR", res.MetaGetStr("foo"))
+
+	// The structured contents were not copied.
+	v, err := res.AsStructured()
+	require.NoError(t, err)
+	v.(map[string]any)["foo"] = "changed"
+	assert.Equal(t, "changed", input["foo"])
+
+	// But remain read-only, and therefore mutations do not reach the original.
+	v, err = res.AsStructuredMut()
+	require.NoError(t, err)
+	v.(map[string]any)["foo"] = "mutated"
+
+	v, err = original.AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, "changed", v.(map[string]any)["foo"])
+}
+
+func TestOptimiserReadOnlyMutationRaw(t *testing.T) {
+	exec, perr := ParseMapping(GlobalContext(), `meta foo = this.foo`)
+	require.Nil(t, perr)
+
+	part := message.NewPart([]byte(`{"foo":  "bar", "baz": 1.0}`))
+	res, err := exec.MapOnto(part, 0, message.Batch{part})
+	require.NoError(t, err)
+
+	assert.Equal(t, "bar", res.MetaGetStr("foo"))
+	assert.Equal(t, `{"foo":  "bar", "baz": 1.0}`, string(res.AsBytes()))
+}
+
+func BenchmarkMappingOptimisation(b *testing.B) {
+	input := `{
+  "id": "abc123",
+  "user": {
+    "profile": {"name": "Foo Bar", "email": "FOO@EXAMPLE.COM", "age": 42},
+    "address": {"street": "1 Some Road", "city": "London", "country": "GB"}
+  },
+  "items": [{"price": 10, "qty": 2}, {"price": 5, "qty": 1}, {"price": 3, "qty": 7}],
+  "tags": ["a", "b", "c"]
+}`
+
+	tests := map[string]struct {
+		mapping string
+		mutate  bool
+	}{
+		"static queries": {
+			mapping: `root.id = this.id
+root.kind = "Order Event".lowercase().replace_all(" ", "_")
+root.version = "4.25.1".split(".").index(0).number()
+root.source = ["orders", "svc"].join("-").uppercase()
+root.enabled = !"false".has_prefix("t")`,
+		},
+		"repeated paths": {
+			mapping: `root.name = this.user.profile.name
+root.first_name = this.user.profile.name.split(" ").index(0)
+root.email = this.user.profile.email.lowercase()
+root.adult = this.user.profile.age >= 18
+root.street = this.user.address.street
+root.city = this.user.address.city.uppercase()
+root.country = this.user.address.country
+root.location = this.user.address.city + ", " + this.user.address.country`,
+		},
+		"mutation of metadata": {
+			mapping: `meta id = this.id
+meta city = this.user.address.city
+meta country = this.user.address.country`,
+			mutate: true,
+		},
+		"mutation of value": {
+			mapping: `root.user.profile.email = this.user.profile.email.lowercase()
+root.total = this.items.map_each(i -> i.price * i.qty).sum()`,
+			mutate: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		for _, opt := range []struct {
+			name string
+			pCtx Context
+		}{
+			{name: "unoptimised", pCtx: GlobalContext().WithoutOptimisation()},
+			{name: "optimised", pCtx: GlobalContext()},
+		} {
+			opt := opt
+			b.Run(name+"/"+opt.name, func(b *testing.B) {
+				exec, perr := ParseMapping(opt.pCtx, test.mapping)
+				require.Nil(b, perr)
+
+				srcPart := message.NewPart([]byte(input))
+				structured, err := srcPart.AsStructured()
+				require.NoError(b, err)
+				srcPart.SetStructured(structured)
+
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					part := srcPart.ShallowCopy()
+					var res *message.Part
+					if test.mutate {
+						res, err = exec.MapOnto(part, 0, message.Batch{part})
+					} else {
+						res, err = exec.MapPart(0, message.Batch{part})
+					}
+					if err != nil {
+						b.Fatal(err)
+					}
+					if res == nil {
+						b.Fatal("unexpected deleted message")
+					}
+				}
+			})
+		}
+	}
+}
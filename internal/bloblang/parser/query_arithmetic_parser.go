@@ -66,7 +66,7 @@ func arithmeticOpParser(input []rune) Result {
 	return res
 }
 
-func arithmeticParser(fnParser Func) Func {
+func arithmeticParser(fnParser Func, pCtx Context) Func {
 	whitespace := DiscardAll(
 		OneOf(
 			SpacesAndTabs,
@@ -99,18 +99,20 @@ func arithmeticParser(fnParser Func) Func {
 			fnSeq := primaryRes.([]any)
 			fn := fnSeq[1].(query.Function)
 			if fnSeq[0] != nil {
-				var err error
-				if fn, err = query.NewArithmeticExpression(
-					[]query.Function{
-						query.NewLiteralFunction("", int64(0)),
-						fn,
-					},
-					[]query.ArithmeticOperator{
-						query.ArithmeticSub,
-					},
-				); err != nil {
+				negFns := []query.Function{
+					query.NewLiteralFunction("", int64(0)),
+					fn,
+				}
+				negOps := []query.ArithmeticOperator{
+					query.ArithmeticSub,
+				}
+				negFn, err := query.NewArithmeticExpression(negFns, negOps)
+				if err != nil {
 					return Fail(NewFatalError(input, err), input)
 				}
+				fn = pCtx.typed(input, negFn, func(line, col int) query.Function {
+					return query.NewTypedArithmetic(line, col, negFns, negOps, negFn)
+				})
 			}
 			fns = append(fns, fn)
 		}
@@ -122,6 +124,11 @@ func arithmeticParser(fnParser Func) Func {
 		if err != nil {
 			return Fail(NewFatalError(input, err), input)
 		}
+		if len(ops) > 0 {
+			fn = pCtx.typed(input, fn, func(line, col int) query.Function {
+				return query.NewTypedArithmetic(line, col, fns, ops, fn)
+			})
+		}
 		return Success(fn, res.Remaining)
 	}
 }
@@ -3,10 +3,25 @@ package parser
 import (
 	"fmt"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
 	"github.com/benthosdev/benthos/v4/internal/value"
 )
 
+type matchCase struct {
+	input      []rune
+	pattern    query.Function
+	caseFn     query.Function
+	queryFn    query.Function
+	isWildcard bool
+}
+
+// nothingFunction is used as the implicit branch of if and match expressions
+// when coverage is enabled.
+var nothingFunction = query.ClosureFunction("nothing", func(ctx query.FunctionContext) (any, error) {
+	return value.Nothing(nil), nil
+}, nil)
+
 func matchCaseParser(pCtx Context) Func {
 	p := Sequence(
 		OneOf(
@@ -20,7 +35,7 @@ func matchCaseParser(pCtx Context) Func {
 			),
 			Sequence(
 				Expect(
-					queryParser(pCtx),
+					queryParser(pCtx.withoutFolding()),
 					"match case",
 				),
 				Optional(SpacesAndTabs),
@@ -39,9 +54,11 @@ func matchCaseParser(pCtx Context) Func {
 
 		seqSlice := res.Payload.([]any)
 
-		var caseFn query.Function
+		var pattern, caseFn query.Function
+		var isWildcard bool
 		switch t := seqSlice[0].([]any)[0].(type) {
 		case query.Function:
+			pattern = t
 			if lit, isLiteral := t.(*query.Literal); isLiteral {
 				caseFn = query.ClosureFunction("case statement", func(ctx query.FunctionContext) (any, error) {
 					v := ctx.Value()
@@ -55,10 +72,17 @@ func matchCaseParser(pCtx Context) Func {
 			}
 		case string:
 			caseFn = query.NewLiteralFunction("", true)
+			isWildcard = true
 		}
 
 		return Success(
-			query.NewMatchCase(caseFn, seqSlice[2].(query.Function)),
+			matchCase{
+				input:      input,
+				pattern:    pattern,
+				caseFn:     caseFn,
+				queryFn:    seqSlice[2].(query.Function),
+				isWildcard: isWildcard,
+			},
 			res.Remaining,
 		)
 	}
@@ -101,11 +125,30 @@ func matchExpressionParser(pCtx Context) Func {
 		contextFn, _ := seqSlice[2].(query.Function)
 
 		cases := []query.MatchCase{}
-		for _, caseVal := range seqSlice[4].([]any) {
-			cases = append(cases, caseVal.(query.MatchCase))
+		typedCases := []query.TypedMatchCase{}
+		var hasWildcard bool
+		for i, caseVal := range seqSlice[4].([]any) {
+			c := caseVal.(matchCase)
+			queryFn := pCtx.coverBranch(input, i, c.input, c.queryFn)
+			cases = append(cases, query.NewMatchCase(c.caseFn, queryFn))
+			typedCases = append(typedCases, query.TypedMatchCase{Pattern: c.pattern, Query: queryFn})
+			if pCtx.typeInput != nil {
+				typedCases[i].Line, typedCases[i].Column = mapping.LineAndColOf(pCtx.typeInput, c.input)
+			}
+			hasWildcard = hasWildcard || c.isWildcard
+		}
+		if pCtx.coverageSource != nil && !hasWildcard {
+			// Record when no case matches as an implicit branch.
+			cases = append(cases, query.NewMatchCase(
+				query.NewLiteralFunction("", true),
+				pCtx.coverBranch(input, len(cases), input, nothingFunction),
+			))
 		}
 
-		res.Payload = query.NewMatchFunction(contextFn, cases...)
+		fn := query.NewMatchFunction(contextFn, cases...)
+		res.Payload = pCtx.typed(input, fn, func(line, col int) query.Function {
+			return query.NewTypedMatch(line, col, contextFn, typedCases, fn)
+		})
 		return res
 	}
 }
@@ -155,10 +198,11 @@ func ifExpressionParser(pCtx Context) Func {
 
 		seqSlice := res.Payload.([]any)
 		queryFn := seqSlice[2].(query.Function)
-		ifFn := seqSlice[6].(query.Function)
+		ifFn := pCtx.coverBranch(input, 0, input, seqSlice[6].(query.Function))
 
 		var elseIfs []query.ElseIf
 		for {
+			branchInput := DiscardedWhitespaceNewlineComments(res.Remaining).Remaining
 			res = elseIfParser(res.Remaining)
 			if res.Err != nil {
 				return res
@@ -169,21 +213,29 @@ func ifExpressionParser(pCtx Context) Func {
 			seqSlice = res.Payload.([]any)
 			elseIfs = append(elseIfs, query.ElseIf{
 				QueryFn: seqSlice[3].(query.Function),
-				MapFn:   seqSlice[7].(query.Function),
+				MapFn:   pCtx.coverBranch(input, len(elseIfs)+1, branchInput, seqSlice[7].(query.Function)),
 			})
 		}
 
 		var elseFn query.Function
 
+		branchInput := DiscardedWhitespaceNewlineComments(res.Remaining).Remaining
 		res = elseParser(res.Remaining)
 		if res.Err != nil {
 			return res
 		}
 		if res.Payload != nil {
 			elseFn, _ = res.Payload.([]any)[5].(query.Function)
+			elseFn = pCtx.coverBranch(input, len(elseIfs)+1, branchInput, elseFn)
+		} else if pCtx.coverageSource != nil {
+			// Record when no condition passes as an implicit branch.
+			elseFn = pCtx.coverBranch(input, len(elseIfs)+1, input, nothingFunction)
 		}
 
-		res.Payload = query.NewIfFunction(queryFn, ifFn, elseIfs, elseFn)
+		fn := query.NewIfFunction(queryFn, ifFn, elseIfs, elseFn)
+		res.Payload = pCtx.typed(input, fn, func(line, col int) query.Function {
+			return query.NewTypedIf(line, col, queryFn, ifFn, elseIfs, elseFn, fn)
+		})
 		return res
 	}
 }
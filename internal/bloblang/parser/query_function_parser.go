@@ -65,7 +65,7 @@ func parseFunctionTail(fn query.Function, pCtx Context) Func {
 	return func(input []rune) Result {
 		res := pattern(input)
 		if seqSlice, isSlice := res.Payload.([]any); isSlice {
-			method, err := query.NewMapMethod(fn, seqSlice[2].(query.Function))
+			method, err := query.NewMapMethod(pCtx.internField(fn), seqSlice[2].(query.Function))
 			if err != nil {
 				res.Err = NewFatalError(input, err)
 				res.Remaining = input
@@ -97,17 +97,28 @@ func parseWithTails(fnParser Func, pCtx Context) Func {
 		seq := res.Payload.([]any)
 		isNot := seq[0] != nil
 		fn := seq[1].(query.Function)
+
+		headInput := input
+		if isNot {
+			headInput = Discard(SpacesAndTabs)(input[1:]).Remaining
+		}
+		fn = pCtx.traceStep(headInput, res.Remaining, fn)
+
 		for {
+			tailInput := res.Remaining
 			if res = delimPattern(res.Remaining); res.Err != nil {
 				if isNot {
-					fn = query.Not(fn)
+					target, notFn := fn, query.Not(fn)
+					fn = pCtx.typed(input, pCtx.foldNot(target, notFn), func(line, col int) query.Function {
+						return query.NewTypedNot(line, col, target, notFn)
+					})
 				}
 				return Success(fn, res.Remaining)
 			}
 			if res = MustBe(parseFunctionTail(fn, pCtx))(res.Remaining); res.Err != nil {
 				return Fail(res.Err, input)
 			}
-			fn = res.Payload.(query.Function)
+			fn = pCtx.traceStep(tailInput, res.Remaining, res.Payload.(query.Function))
 		}
 	}
 }
@@ -320,32 +331,66 @@ func methodParser(fn query.Function, pCtx Context) Func {
 			return Fail(NewFatalError(res.Remaining, err), input)
 		}
 
-		method, err := pCtx.InitMethod(targetMethod, fn, parsedParams)
+		target := pCtx.internField(fn)
+		method, err := pCtx.InitMethod(targetMethod, target, parsedParams)
 		if err != nil {
 			return Fail(NewFatalError(res.Remaining, err), input)
 		}
+		method = pCtx.foldMethod(targetMethod, target, parsedParams, method)
+		method = pCtx.typed(input, method, func(line, col int) query.Function {
+			spec, _ := pCtx.Methods.Spec(targetMethod)
+			return query.NewTypedMethod(line, col, spec, target, parsedParams, method)
+		})
 		return Success(method, res.Remaining)
 	}
 }
 
+var mapNamespacedNameParser = Sequence(charDot, SnakeCase)
+
 func functionParser(pCtx Context) Func {
-	p := Sequence(Expect(SnakeCase, "function"), functionArgsParser(pCtx))
+	nameParser := Expect(SnakeCase, "function")
+	argsParser := functionArgsParser(pCtx)
 
 	return func(input []rune) Result {
-		res := p(input)
+		res := nameParser(input)
 		if res.Err != nil {
 			return res
 		}
+		targetFunc := res.Payload.(string)
+
+		// A namespace is only captured when it belongs to an import, otherwise
+		// the path is left to be parsed as a field reference.
+		if pCtx.mapScope.hasNamespace(targetFunc) && !pCtx.HasNamedContext(targetFunc) {
+			if nsRes := mapNamespacedNameParser(res.Remaining); nsRes.Err == nil {
+				targetFunc += "." + nsRes.Payload.([]any)[1].(string)
+				res = nsRes
+			}
+		}
 
-		seqSlice := res.Payload.([]any)
+		if res = argsParser(res.Remaining); res.Err != nil {
+			return Fail(res.Err, input)
+		}
+		args := res.Payload.([]any)
 
-		targetFunc := seqSlice[0].(string)
+		// Maps within namespaces, maps that have already been declared and any
+		// unrecognised functions (which might be maps declared later) are
+		// called as maps.
 		params, err := pCtx.Functions.Params(targetFunc)
+		if strings.Contains(targetFunc, ".") {
+			err = fmt.Errorf("unrecognised map '%v'", targetFunc)
+		}
+		if _, isMap := pCtx.mapScope.callable(targetFunc); isMap || (err != nil && pCtx.mapScope != nil) {
+			fn, err := pCtx.mapScope.call(res.Remaining, targetFunc, args, err)
+			if err != nil {
+				return Fail(NewFatalError(res.Remaining, err), input)
+			}
+			return Success(fn, res.Remaining)
+		}
 		if err != nil {
 			return Fail(NewFatalError(res.Remaining, err), input)
 		}
 
-		parsedParams, err := extractArgsParserResult(params, seqSlice[1].([]any))
+		parsedParams, err := extractArgsParserResult(params, args)
 		if err != nil {
 			return Fail(NewFatalError(res.Remaining, err), input)
 		}
@@ -354,6 +399,10 @@ func functionParser(pCtx Context) Func {
 		if err != nil {
 			return Fail(NewFatalError(res.Remaining, err), input)
 		}
+		fn = pCtx.typed(input, fn, func(line, col int) query.Function {
+			spec, _ := pCtx.Functions.Spec(targetFunc)
+			return query.NewTypedFunction(line, col, spec, parsedParams, fn)
+		})
 		return Success(fn, res.Remaining)
 	}
 }
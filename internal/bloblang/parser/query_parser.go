@@ -21,7 +21,7 @@ func queryParser(pCtx Context) Func {
 	), pCtx)
 	return func(input []rune) Result {
 		res := SpacesAndTabs(input)
-		return arithmeticParser(rootParser)(res.Remaining)
+		return arithmeticParser(rootParser, pCtx)(res.Remaining)
 	}
 }
 
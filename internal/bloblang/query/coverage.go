@@ -0,0 +1,243 @@
+package query
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// CoveragePoint counts the number of times a statement or branch of a mapping
+// was executed. A nil point is valid and records nothing.
+type CoveragePoint struct {
+	hits int64
+}
+
+// Hit records a single execution.
+func (p *CoveragePoint) Hit() {
+	if p != nil {
+		atomic.AddInt64(&p.hits, 1)
+	}
+}
+
+// Hits returns the number of recorded executions.
+func (p *CoveragePoint) Hits() int64 {
+	if p == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&p.hits)
+}
+
+//------------------------------------------------------------------------------
+
+// Coverage records which statements and branches of parsed mappings were
+// executed. Mappings are identified by their contents, and therefore parsing
+// the same mapping multiple times results in shared coverage points.
+type Coverage struct {
+	mut     sync.Mutex
+	sources map[string]*CoverageSource
+	ordered []*CoverageSource
+}
+
+// NewCoverage creates an empty coverage recorder.
+func NewCoverage() *Coverage {
+	return &Coverage{
+		sources: map[string]*CoverageSource{},
+	}
+}
+
+// Source returns the coverage of a mapping with the given contents, creating
+// it if it doesn't already exist. The name is optional and identifies the file
+// that the mapping was read from, an existing source that has no name is given
+// the provided name.
+func (c *Coverage) Source(name, content string) *CoverageSource {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if s, exists := c.sources[content]; exists {
+		if s.Name == "" {
+			s.Name = name
+		}
+		return s
+	}
+
+	s := &CoverageSource{
+		Name:       name,
+		Content:    content,
+		statements: map[int]*CoveragePoint{},
+		branches:   map[coverageBranchKey]*coverageBranch{},
+	}
+	c.sources[content] = s
+	c.ordered = append(c.ordered, s)
+	return s
+}
+
+// Sources returns all mapping sources that have been recorded in the order in
+// which they were first parsed.
+func (c *Coverage) Sources() []*CoverageSource {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	sources := make([]*CoverageSource, len(c.ordered))
+	copy(sources, c.ordered)
+	return sources
+}
+
+//------------------------------------------------------------------------------
+
+type coverageBranchKey struct {
+	line, column, branch int
+}
+
+type coverageBranch struct {
+	bodyLine int
+	point    *CoveragePoint
+}
+
+// CoverageSource contains the coverage points of a single mapping.
+type CoverageSource struct {
+	Name    string
+	Content string
+
+	mut        sync.Mutex
+	statements map[int]*CoveragePoint
+	branches   map[coverageBranchKey]*coverageBranch
+}
+
+// Statement returns the coverage point of a statement that begins on a given
+// line.
+func (s *CoverageSource) Statement(line int) *CoveragePoint {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	p, exists := s.statements[line]
+	if !exists {
+		p = &CoveragePoint{}
+		s.statements[line] = p
+	}
+	return p
+}
+
+// Branch returns the coverage point of a branch of an expression such as an if
+// or match, where the line and column identify the beginning of the expression
+// and the branch is the index of the branch within it. The body line is the
+// line on which the branch itself begins.
+func (s *CoverageSource) Branch(line, column, branch, bodyLine int) *CoveragePoint {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	key := coverageBranchKey{line: line, column: column, branch: branch}
+	b, exists := s.branches[key]
+	if !exists {
+		b = &coverageBranch{bodyLine: bodyLine, point: &CoveragePoint{}}
+		s.branches[key] = b
+	}
+	return b.point
+}
+
+// CoverageLine describes the number of executions of a line of a mapping.
+type CoverageLine struct {
+	Line int
+	Hits int64
+}
+
+// Lines returns the execution counts of each line that contains a statement
+// or the beginning of a branch, sorted by line number. When a line contains
+// multiple coverage points the highest count is used.
+func (s *CoverageSource) Lines() []CoverageLine {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	counts := map[int]int64{}
+	addLine := func(line int, hits int64) {
+		if current, exists := counts[line]; !exists || hits > current {
+			counts[line] = hits
+		}
+	}
+	for line, p := range s.statements {
+		addLine(line, p.Hits())
+	}
+	for _, b := range s.branches {
+		addLine(b.bodyLine, b.point.Hits())
+	}
+
+	lines := make([]CoverageLine, 0, len(counts))
+	for line, hits := range counts {
+		lines = append(lines, CoverageLine{Line: line, Hits: hits})
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].Line < lines[j].Line
+	})
+	return lines
+}
+
+// CoverageBranch describes the number of executions of a branch of a mapping.
+// The line is where the expression containing the branch begins and the block
+// is a unique identifier of the expression within the mapping.
+type CoverageBranch struct {
+	Line   int
+	Block  int
+	Branch int
+	Hits   int64
+}
+
+// Branches returns the execution counts of each branch sorted by the position
+// of their expression and then their index.
+func (s *CoverageSource) Branches() []CoverageBranch {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	keys := make([]coverageBranchKey, 0, len(s.branches))
+	for k := range s.branches {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].line != keys[j].line {
+			return keys[i].line < keys[j].line
+		}
+		if keys[i].column != keys[j].column {
+			return keys[i].column < keys[j].column
+		}
+		return keys[i].branch < keys[j].branch
+	})
+
+	branches := make([]CoverageBranch, 0, len(keys))
+	block := -1
+	for i, k := range keys {
+		if i == 0 || k.line != keys[i-1].line || k.column != keys[i-1].column {
+			block++
+		}
+		branches = append(branches, CoverageBranch{
+			Line:   k.line,
+			Block:  block,
+			Branch: k.branch,
+			Hits:   s.branches[k].point.Hits(),
+		})
+	}
+	return branches
+}
+
+//------------------------------------------------------------------------------
+
+// NewCoveredFunction wraps a function so that each execution is recorded
+// against a coverage point.
+func NewCoveredFunction(point *CoveragePoint, fn Function) Function {
+	return &coveredFunction{point: point, fn: fn}
+}
+
+type coveredFunction struct {
+	point *CoveragePoint
+	fn    Function
+}
+
+func (c *coveredFunction) Annotation() string {
+	return c.fn.Annotation()
+}
+
+func (c *coveredFunction) Exec(ctx FunctionContext) (any, error) {
+	c.point.Hit()
+	return c.fn.Exec(ctx)
+}
+
+func (c *coveredFunction) QueryTargets(ctx TargetsContext) (TargetsContext, []TargetPath) {
+	return c.fn.QueryTargets(ctx)
+}
@@ -1,5 +1,9 @@
 package query
 
+import (
+	"github.com/benthosdev/benthos/v4/internal/value"
+)
+
 // ExampleSpec provides a mapping example and some input/output results to
 // display.
 type ExampleSpec struct {
@@ -83,6 +87,10 @@ type FunctionSpec struct {
 	// Params defines the expected arguments of the function.
 	Params Params `json:"params"`
 
+	// ReturnTypes optionally lists the types of value the function returns,
+	// which is used for static type checking.
+	ReturnTypes []value.Type `json:"return_types,omitempty"`
+
 	// Examples shows general usage for the function.
 	Examples []ExampleSpec `json:"examples,omitempty"`
 
@@ -137,6 +145,12 @@ func (s FunctionSpec) Param(def ParamDefinition) FunctionSpec {
 	return s
 }
 
+// Returns sets the types of value the function returns.
+func (s FunctionSpec) Returns(types ...value.Type) FunctionSpec {
+	s.ReturnTypes = types
+	return s
+}
+
 // NewDeprecatedFunctionSpec creates a new function spec that is deprecated.
 func NewDeprecatedFunctionSpec(name, description string, examples ...ExampleSpec) FunctionSpec {
 	return FunctionSpec{
@@ -198,6 +212,14 @@ type MethodSpec struct {
 	// Params defines the expected arguments of the method.
 	Params Params `json:"params"`
 
+	// InputTypes optionally lists the types of target value the method
+	// accepts, which is used for static type checking.
+	InputTypes []value.Type `json:"input_types,omitempty"`
+
+	// ReturnTypes optionally lists the types of value the method returns,
+	// which is used for static type checking.
+	ReturnTypes []value.Type `json:"return_types,omitempty"`
+
 	// Examples shows general usage for the method.
 	Examples []ExampleSpec `json:"examples,omitempty"`
 
@@ -275,6 +297,18 @@ func (m MethodSpec) Param(def ParamDefinition) MethodSpec {
 	return m
 }
 
+// Accepts sets the types of target value the method accepts.
+func (m MethodSpec) Accepts(types ...value.Type) MethodSpec {
+	m.InputTypes = types
+	return m
+}
+
+// Returns sets the types of value the method returns.
+func (m MethodSpec) Returns(types ...value.Type) MethodSpec {
+	m.ReturnTypes = types
+	return m
+}
+
 // VariadicParams configures the method spec to allow variadic parameters.
 func (m MethodSpec) VariadicParams() MethodSpec {
 	m.Params = VariadicParams()
@@ -0,0 +1,188 @@
+package query
+
+import (
+	"strconv"
+
+	"github.com/Jeffail/gabs/v2"
+)
+
+// FieldCache is populated at parse time with the field references of a
+// mapping, allowing field paths that are referenced multiple times (including
+// those that share a common prefix) to be resolved only once for each context
+// value during an execution.
+//
+// Fields are registered with Intern and once parsing is complete Finalise
+// assigns a cache slot to each path prefix that is shared, after which the
+// cache must not be modified. The results themselves are stored within a
+// function context, see FunctionContext.WithFieldCache.
+type FieldCache struct {
+	root   fieldCacheEntry
+	fields []*cachedField
+	slots  int
+}
+
+// NewFieldCache creates an empty field cache.
+func NewFieldCache() *FieldCache {
+	return &FieldCache{}
+}
+
+type fieldCacheEntry struct {
+	children map[string]*fieldCacheEntry
+	uses     int
+	slot     int
+}
+
+// Intern registers a function with the cache and returns a function that
+// shares the results of common paths when executed, or the function itself if
+// it is not a field reference of the context value.
+func (c *FieldCache) Intern(fn Function) Function {
+	f, ok := fn.(*fieldFunction)
+	if !ok || f.fromRoot || f.namedContext != "" || len(f.path) == 0 {
+		return fn
+	}
+
+	cf := &cachedField{cache: c, field: f, slot: -1}
+	entry := &c.root
+	for _, seg := range f.path {
+		// Wildcard segments map over the elements of an array and therefore
+		// resolving the remainder of a path from a cached prefix containing
+		// one would not be equivalent.
+		if seg == "*" {
+			break
+		}
+		next, exists := entry.children[seg]
+		if !exists {
+			if entry.children == nil {
+				entry.children = map[string]*fieldCacheEntry{}
+			}
+			next = &fieldCacheEntry{slot: -1}
+			entry.children[seg] = next
+		}
+		next.uses++
+		cf.prefixes = append(cf.prefixes, next)
+		entry = next
+	}
+
+	c.fields = append(c.fields, cf)
+	return cf
+}
+
+// Finalise assigns cache slots to path prefixes referenced by more than one
+// field, and configures each interned field to resolve its value from the
+// longest cached prefix of its path.
+func (c *FieldCache) Finalise() {
+	c.slots = 0
+	c.root.finalise(&c.slots)
+	for _, f := range c.fields {
+		f.slot, f.depth = -1, 0
+		for i := len(f.prefixes) - 1; i >= 0; i-- {
+			if s := f.prefixes[i].slot; s >= 0 {
+				f.slot, f.depth = s, i+1
+				break
+			}
+		}
+	}
+}
+
+func (e *fieldCacheEntry) finalise(slots *int) {
+	for _, child := range e.children {
+		child.slot = -1
+		if child.uses > 1 {
+			child.slot = *slots
+			*slots++
+		}
+		child.finalise(slots)
+	}
+}
+
+// Slots returns the number of path prefixes cached during an execution.
+func (c *FieldCache) Slots() int {
+	if c == nil {
+		return 0
+	}
+	return c.slots
+}
+
+//------------------------------------------------------------------------------
+
+type fieldCacheValues struct {
+	owner  *FieldCache
+	values []fieldCacheValue
+}
+
+type fieldCacheValue struct {
+	context *any
+	value   any
+}
+
+func (v *fieldCacheValues) reset() {
+	for i := range v.values {
+		v.values[i] = fieldCacheValue{}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type cachedField struct {
+	cache    *FieldCache
+	field    *fieldFunction
+	prefixes []*fieldCacheEntry
+
+	// The slot of the longest cached prefix of the path, and the number of
+	// path segments within that prefix.
+	slot  int
+	depth int
+}
+
+func (c *cachedField) Annotation() string {
+	return c.field.Annotation()
+}
+
+func (c *cachedField) Exec(ctx FunctionContext) (any, error) {
+	if c.slot < 0 || ctx.fieldValues == nil || ctx.fieldValues.owner != c.cache {
+		return c.field.Exec(ctx)
+	}
+	v := ctx.Value()
+	if v == nil {
+		return c.field.Exec(ctx)
+	}
+
+	// The context value is identified by its pointer, which changes each time
+	// a new context value is set, e.g. for each element of a map_each.
+	cached := &ctx.fieldValues.values[c.slot]
+	if cached.context != v {
+		cached.value = resolveFieldPath(*v, c.field.path[:c.depth])
+		cached.context = v
+	}
+	return resolveFieldPath(cached.value, c.field.path[c.depth:]), nil
+}
+
+// resolveFieldPath returns the value at a path within a structure, following
+// the same rules as gabs without allocating for each lookup.
+func resolveFieldPath(v any, path []string) any {
+	for i, seg := range path {
+		switch t := v.(type) {
+		case map[string]any:
+			var exists bool
+			if v, exists = t[seg]; !exists {
+				return nil
+			}
+		case []any:
+			if seg == "*" {
+				return gabs.Wrap(t).S(path[i:]...).Data()
+			}
+			index, err := strconv.Atoi(seg)
+			if err != nil || index < 0 || index >= len(t) {
+				return nil
+			}
+			v = t[index]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+func (c *cachedField) QueryTargets(ctx TargetsContext) (TargetsContext, []TargetPath) {
+	return c.field.QueryTargets(ctx)
+}
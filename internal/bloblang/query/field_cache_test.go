@@ -0,0 +1,79 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveFieldPath(t *testing.T) {
+	value := map[string]any{
+		"foo": map[string]any{
+			"bar": "baz",
+			"nil": nil,
+		},
+		"items": []any{
+			map[string]any{"id": "first", "tags": []any{"a", "b"}},
+			map[string]any{"id": "second"},
+			"not an object",
+		},
+		"str": "hello",
+	}
+
+	for _, path := range []string{
+		"foo",
+		"foo.bar",
+		"foo.nil",
+		"foo.nope",
+		"foo.bar.baz",
+		"items.0",
+		"items.1.id",
+		"items.0.tags.1",
+		"items.3",
+		"items.-1",
+		"items.nope",
+		"items.*",
+		"items.*.id",
+		"items.*.nope",
+		"items.0.tags.*",
+		"str.0",
+	} {
+		segs := gabs.DotPathToSlice(path)
+		assert.Equal(t, gabs.Wrap(value).S(segs...).Data(), resolveFieldPath(value, segs), path)
+	}
+}
+
+func TestFieldCacheFinalise(t *testing.T) {
+	c := NewFieldCache()
+
+	intern := func(path string) *cachedField {
+		t.Helper()
+		cf, ok := c.Intern(NewFieldFunction(path)).(*cachedField)
+		require.True(t, ok)
+		return cf
+	}
+
+	a := intern("foo.bar.baz")
+	b := intern("foo.bar.buz")
+	d := intern("foo.qux")
+	e := intern("items.*.id")
+	f := intern("other")
+
+	_, ok := c.Intern(NewVarFunction("foo")).(*cachedField)
+	assert.False(t, ok)
+
+	c.Finalise()
+	assert.Equal(t, 2, c.Slots())
+
+	assert.Equal(t, 2, a.depth)
+	assert.Equal(t, 2, b.depth)
+	assert.Equal(t, a.slot, b.slot)
+
+	assert.Equal(t, 1, d.depth)
+	assert.NotEqual(t, a.slot, d.slot)
+
+	assert.Equal(t, -1, e.slot)
+	assert.Equal(t, -1, f.slot)
+}
@@ -0,0 +1,59 @@
+package query
+
+// Methods that depend on the execution context beyond their target and
+// arguments, and can therefore never be folded.
+var contextualMethods = map[string]struct{}{
+	"apply":    {},
+	"from":     {},
+	"from_all": {},
+}
+
+// FoldableMethod returns true if a method can be evaluated at parse time, which
+// is the case when the method is pure and both its target and arguments are
+// literal values.
+func FoldableMethod(spec MethodSpec, target Function, args *ParsedParams) bool {
+	if _, isLit := target.(*Literal); !isLit {
+		return false
+	}
+	if spec.Impure {
+		return false
+	}
+	if _, isContextual := contextualMethods[spec.Name]; isContextual {
+		return false
+	}
+	if args == nil {
+		return true
+	}
+	if len(args.dynArgs) > 0 {
+		return false
+	}
+	for _, v := range args.values {
+		// Query arguments are stored as functions and are only static when
+		// they're literals.
+		if fn, isFn := v.(Function); isFn {
+			if _, isLit := fn.(*Literal); !isLit {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Fold attempts to evaluate a function known to be static at parse time, and
+// returns a literal of the result. If the evaluation fails then the function
+// is returned unchanged, leaving the error to occur during execution.
+func Fold(fn Function) (folded Function) {
+	folded = fn
+	defer func() {
+		// Functions that are unexpectedly dependent on the execution context
+		// might not tolerate an empty one.
+		if r := recover(); r != nil {
+			folded = fn
+		}
+	}()
+	v, err := fn.Exec(FunctionContext{})
+	if err != nil {
+		return fn
+	}
+	return NewLiteralFunction(fn.Annotation(), v)
+}
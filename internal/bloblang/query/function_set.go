@@ -66,6 +66,12 @@ func (f *FunctionSet) Params(name string) (Params, error) {
 	return details.spec.Params, nil
 }
 
+// Spec attempts to obtain the spec of a given function.
+func (f *FunctionSet) Spec(name string) (FunctionSpec, bool) {
+	details, exists := f.functions[name]
+	return details.spec, exists
+}
+
 // Init attempts to initialize a function of the set by name and zero or more
 // arguments.
 func (f *FunctionSet) Init(name string, args *ParsedParams) (Function, error) {
@@ -188,7 +188,7 @@ var _ = registerSimpleFunction(
 		NewExampleSpec("",
 			`root = if batch_index() > 0 { deleted() }`,
 		),
-	),
+	).Returns(value.TNumber),
 	func(ctx FunctionContext) (any, error) {
 		return int64(ctx.Index), nil
 	},
@@ -203,7 +203,7 @@ var _ = registerSimpleFunction(
 		NewExampleSpec("",
 			`root.foo = batch_size()`,
 		),
-	),
+	).Returns(value.TNumber),
 	func(ctx FunctionContext) (any, error) {
 		return int64(ctx.MsgBatch.Len()), nil
 	},
@@ -220,7 +220,7 @@ var _ = registerSimpleFunction(
 			`{"foo":"bar"}`,
 			`{"doc":"{\"foo\":\"bar\"}"}`,
 		),
-	),
+	).Returns(value.TBytes),
 	func(ctx FunctionContext) (any, error) {
 		return ctx.MsgBatch.Get(ctx.Index).AsBytes(), nil
 	},
@@ -277,7 +277,7 @@ root.id = count("bloblang_function_example")`,
 			`{"message":"bar"}`,
 			`{"id":2,"message":"bar"}`,
 		),
-	).Param(ParamString("name", "An identifier for the counter.")).MarkImpure(),
+	).Param(ParamString("name", "An identifier for the counter.")).MarkImpure().Returns(value.TNumber),
 	countFunction,
 )
 
@@ -327,7 +327,7 @@ root.bar = deleted()`,
 			`{"nums":[3,11,4,17]}`,
 			`{"new_nums":[1,7]}`,
 		),
-	),
+	).Returns(value.TDelete),
 	func(*ParsedParams) (Function, error) {
 		return NewLiteralFunction("delete", value.Delete(nil)), nil
 	},
@@ -342,7 +342,7 @@ var _ = registerSimpleFunction(
 		NewExampleSpec("",
 			`root.doc.error = error()`,
 		),
-	),
+	).Returns(value.TString, value.TNull),
 	func(ctx FunctionContext) (any, error) {
 		v := ctx.MsgBatch.Get(ctx.Index).ErrorGet()
 		if v != nil {
@@ -359,7 +359,7 @@ var _ = registerSimpleFunction(
 		NewExampleSpec("",
 			`root.doc.status = if errored() { 400 } else { 200 }`,
 		),
-	),
+	).Returns(value.TBool),
 	func(ctx FunctionContext) (any, error) {
 		return ctx.MsgBatch.Get(ctx.Index).ErrorGet() != nil, nil
 	},
@@ -381,7 +381,8 @@ root.c = range(0, -this.max, -2)`,
 	).
 		Param(ParamInt64("start", "The start value.")).
 		Param(ParamInt64("stop", "The stop value.")).
-		Param(ParamInt64("step", "The step value.").Default(1)),
+		Param(ParamInt64("step", "The step value.").Default(1)).
+		Returns(value.TArray),
 	rangeFunction,
 )
 
@@ -697,7 +698,8 @@ root.sixth = random_int(seed:timestamp_unix_nano(), max:20)
 			true,
 		).Default(NewLiteralFunction("", 0))).
 		Param(ParamInt64("min", "The minimum value the random generated number will have. The default value is 0.").Default(0).DisableDynamic()).
-		Param(ParamInt64("max", fmt.Sprintf("The maximum value the random generated number will have. The default value is %d (math.MaxInt64 - 1).", uint64(math.MaxInt64-1))).Default(int64(math.MaxInt64-1)).DisableDynamic()),
+		Param(ParamInt64("max", fmt.Sprintf("The maximum value the random generated number will have. The default value is %d (math.MaxInt64 - 1).", uint64(math.MaxInt64-1))).Default(int64(math.MaxInt64-1)).DisableDynamic()).
+		Returns(value.TNumber),
 	randomIntFunction,
 )
 
@@ -761,7 +763,7 @@ var _ = registerFunction(
 		NewExampleSpec("",
 			`root.received_at = now().ts_format("Mon Jan 2 15:04:05 -0700 MST 2006", "UTC")`,
 		),
-	),
+	).Returns(value.TString),
 	func(args *ParsedParams) (Function, error) {
 		return ClosureFunction("function now", func(_ FunctionContext) (any, error) {
 			return time.Now().Format(time.RFC3339Nano), nil
@@ -776,7 +778,7 @@ var _ = registerSimpleFunction(
 		NewExampleSpec("",
 			`root.received_at = timestamp_unix()`,
 		),
-	),
+	).Returns(value.TNumber),
 	func(_ FunctionContext) (any, error) {
 		return time.Now().Unix(), nil
 	},
@@ -789,7 +791,7 @@ var _ = registerSimpleFunction(
 		NewExampleSpec("",
 			`root.received_at = timestamp_unix_milli()`,
 		),
-	),
+	).Returns(value.TNumber),
 	func(_ FunctionContext) (any, error) {
 		return time.Now().UnixMilli(), nil
 	},
@@ -802,7 +804,7 @@ var _ = registerSimpleFunction(
 		NewExampleSpec("",
 			`root.received_at = timestamp_unix_micro()`,
 		),
-	),
+	).Returns(value.TNumber),
 	func(_ FunctionContext) (any, error) {
 		return time.Now().UnixMicro(), nil
 	},
@@ -815,7 +817,7 @@ var _ = registerSimpleFunction(
 		NewExampleSpec("",
 			`root.received_at = timestamp_unix_nano()`,
 		),
-	),
+	).Returns(value.TNumber),
 	func(_ FunctionContext) (any, error) {
 		return time.Now().UnixNano(), nil
 	},
@@ -858,7 +860,7 @@ var _ = registerSimpleFunction(
 		FunctionCategoryGeneral, "uuid_v4",
 		"Generates a new RFC-4122 UUID each time it is invoked and prints a string representation.",
 		NewExampleSpec("", `root.id = uuid_v4()`),
-	),
+	).Returns(value.TString),
 	func(_ FunctionContext) (any, error) {
 		u4, err := uuid.NewV4()
 		if err != nil {
@@ -879,7 +881,8 @@ var _ = registerFunction(
 		NewExampleSpec("It is also possible to specify an optional custom alphabet after the length parameter.", `root.id = nanoid(54, "abcde")`),
 	).
 		Param(ParamInt64("length", "An optional length.").Optional()).
-		Param(ParamString("alphabet", "An optional custom alphabet to use for generating IDs. When specified the field `length` must also be present.").Optional()),
+		Param(ParamString("alphabet", "An optional custom alphabet to use for generating IDs. When specified the field `length` must also be present.").Optional()).
+		Returns(value.TString),
 	nanoidFunction,
 )
 
@@ -913,7 +916,7 @@ var _ = registerSimpleFunction(
 		FunctionCategoryGeneral, "ksuid",
 		"Generates a new ksuid each time it is invoked and prints a string representation.",
 		NewExampleSpec("", `root.id = ksuid()`),
-	),
+	).Returns(value.TString),
 	func(_ FunctionContext) (any, error) {
 		return ksuid.New().String(), nil
 	},
@@ -934,19 +937,31 @@ var _ = registerFunction(
 
 // NewVarFunction creates a new variable function.
 func NewVarFunction(name string) Function {
-	return ClosureFunction("variable "+name, func(ctx FunctionContext) (any, error) {
-		if ctx.Vars == nil {
-			return nil, errors.New("variables were undefined")
-		}
-		if res, ok := ctx.Vars[name]; ok {
-			return res, nil
-		}
-		return nil, fmt.Errorf("variable '%v' undefined", name)
-	}, func(ctx TargetsContext) (TargetsContext, []TargetPath) {
-		paths := []TargetPath{
-			NewTargetPath(TargetVariable, name),
-		}
-		ctx = ctx.WithValues(paths)
-		return ctx, paths
-	})
+	return &varFunction{name: name}
+}
+
+type varFunction struct {
+	name string
+}
+
+func (v *varFunction) Annotation() string {
+	return "variable " + v.name
+}
+
+func (v *varFunction) Exec(ctx FunctionContext) (any, error) {
+	if ctx.Vars == nil {
+		return nil, errors.New("variables were undefined")
+	}
+	if res, ok := ctx.Vars[v.name]; ok {
+		return res, nil
+	}
+	return nil, fmt.Errorf("variable '%v' undefined", v.name)
+}
+
+func (v *varFunction) QueryTargets(ctx TargetsContext) (TargetsContext, []TargetPath) {
+	paths := []TargetPath{
+		NewTargetPath(TargetVariable, v.name),
+	}
+	ctx = ctx.WithValues(paths)
+	return ctx, paths
 }
@@ -0,0 +1,106 @@
+package query
+
+import (
+	"fmt"
+)
+
+// MapCaller is implemented by maps that declare parameters, which allows them
+// to be called as functions with a list of arguments.
+type MapCaller interface {
+	// Params returns the names of the parameters declared by the map.
+	Params() []string
+
+	// CallMap executes the map with a list of arguments, one for each
+	// parameter.
+	CallMap(ctx FunctionContext, args []any) (any, error)
+}
+
+// NewMapCallFunction creates a query function that calls a map with the
+// results of a list of argument functions. The map is resolved by name from the
+// provided set of maps when executed, as a map might be called before it has
+// been parsed.
+func NewMapCallFunction(maps map[string]Function, name string, args []Function) Function {
+	return &mapCallFunction{maps: maps, name: name, args: args}
+}
+
+// NewNamedMapCallFunction creates a query function that calls a map with the
+// results of argument functions matched to the parameters of the map by name.
+// The map is resolved by name from the provided set of maps when executed, as a
+// map might be called before it has been parsed.
+func NewNamedMapCallFunction(maps map[string]Function, name string, args map[string]Function) Function {
+	return &mapCallFunction{maps: maps, name: name, namedArgs: args}
+}
+
+type mapCallFunction struct {
+	maps      map[string]Function
+	name      string
+	args      []Function
+	namedArgs map[string]Function
+}
+
+func (m *mapCallFunction) Annotation() string {
+	return "map " + m.name
+}
+
+func (m *mapCallFunction) Exec(ctx FunctionContext) (any, error) {
+	mapFn, exists := m.maps[m.name]
+	if !exists {
+		return nil, fmt.Errorf("map %v was not found", m.name)
+	}
+	caller, ok := mapFn.(MapCaller)
+	if !ok {
+		return nil, fmt.Errorf("map %v cannot be called with arguments", m.name)
+	}
+
+	argFns := m.args
+	if m.namedArgs != nil {
+		params := caller.Params()
+		argFns = make([]Function, len(params))
+		for i, p := range params {
+			if argFns[i], ok = m.namedArgs[p]; !ok {
+				return nil, fmt.Errorf("map %v: missing argument %v", m.name, p)
+			}
+		}
+	}
+
+	args := make([]any, len(argFns))
+	for i, fn := range argFns {
+		v, err := fn.Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return caller.CallMap(ctx, args)
+}
+
+func (m *mapCallFunction) QueryTargets(ctx TargetsContext) (TargetsContext, []TargetPath) {
+	var paths []TargetPath
+	for _, fn := range m.argFunctions() {
+		_, tmpPaths := fn.QueryTargets(ctx)
+		paths = append(paths, tmpPaths...)
+	}
+
+	// The context of a called map is that of the caller, and therefore its
+	// targets are relative to the current context.
+	if mapFn, exists := m.maps[m.name]; exists {
+		if mapCtx, ok := ctx.enterMap(mapFn); ok {
+			_, mapPaths := mapFn.QueryTargets(mapCtx)
+			paths = append(paths, mapPaths...)
+		}
+	}
+	return ctx, paths
+}
+
+// argFunctions returns the argument functions of the call in no particular
+// order.
+func (m *mapCallFunction) argFunctions() []Function {
+	if m.namedArgs == nil {
+		return m.args
+	}
+	fns := make([]Function, 0, len(m.namedArgs))
+	for _, fn := range m.namedArgs {
+		fns = append(fns, fn)
+	}
+	return fns
+}
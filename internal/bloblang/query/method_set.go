@@ -60,6 +60,12 @@ func (m *MethodSet) Params(name string) (Params, error) {
 	return details.spec.Params, nil
 }
 
+// Spec attempts to obtain the spec of a given method.
+func (m *MethodSet) Spec(name string) (MethodSpec, bool) {
+	details, exists := m.methods[name]
+	return details.spec, exists
+}
+
 // Init attempts to initialize a method of the set by name from a target
 // function and zero or more arguments.
 func (m *MethodSet) Init(name string, target Function, args *ParsedParams) (Function, error) {
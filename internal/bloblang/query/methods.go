@@ -69,6 +69,9 @@ func applyMethod(target Function, args *ParsedParams) (Function, error) {
 		}
 
 		mapCtx, targets := target.QueryTargets(ctx)
+		if mapCtx, ok = mapCtx.enterMap(mapFn); !ok {
+			return mapCtx, targets
+		}
 		mapCtx = mapCtx.WithValues(targets).WithValuesAsContext()
 
 		returnCtx, mapTargets := mapFn.QueryTargets(mapCtx)
@@ -86,7 +89,7 @@ var _ = registerMethod(
 			`root.foo = this.thing.bool()
 root.bar = this.thing.bool(true)`,
 		),
-	).Param(ParamBool("default", "An optional value to yield if the target cannot be parsed as a boolean.").Optional()),
+	).Param(ParamBool("default", "An optional value to yield if the target cannot be parsed as a boolean.").Optional()).Returns(value.TBool),
 	boolMethod,
 )
 
@@ -412,7 +415,7 @@ var _ = registerMethod(
 			`root.foo = this.thing.number() + 10
 root.bar = this.thing.number(5) * 10`,
 		),
-	).Param(ParamFloat("default", "An optional value to yield if the target cannot be parsed as a number.").Optional()),
+	).Param(ParamFloat("default", "An optional value to yield if the target cannot be parsed as a number.").Optional()).Returns(value.TNumber),
 	numberCoerceMethod,
 )
 
@@ -503,7 +506,7 @@ root.foo_type = this.foo.type()`,
 			`"2022-06-06"`,
 			`{"type":"timestamp"}`,
 		),
-	),
+	).Returns(value.TString),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			return string(value.ITypeOf(v)), nil
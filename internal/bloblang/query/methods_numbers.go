@@ -18,7 +18,7 @@ var _ = registerSimpleMethod(
 			`{"value":-5.9}`,
 			`{"new_value":-5}`,
 		),
-	),
+	).Accepts(value.TNumber).Returns(value.TNumber),
 	func(*ParsedParams) (simpleMethod, error) {
 		return numberMethod(func(f *float64, i *int64, ui *uint64) (any, error) {
 			if f != nil {
@@ -47,7 +47,7 @@ var _ = registerSimpleMethod(
 			`{"value":5.7}`,
 			`{"new_value":5}`,
 		),
-	),
+	).Accepts(value.TNumber).Returns(value.TNumber),
 	func(*ParsedParams) (simpleMethod, error) {
 		return numberMethod(func(f *float64, i *int64, ui *uint64) (any, error) {
 			if f != nil {
@@ -75,7 +75,7 @@ var _ = registerSimpleMethod(
 			`{"value":2.7183}`,
 			`{"new_value":1}`,
 		),
-	),
+	).Accepts(value.TNumber).Returns(value.TNumber),
 	func(*ParsedParams) (simpleMethod, error) {
 		return numberMethod(func(f *float64, i *int64, ui *uint64) (any, error) {
 			var v float64
@@ -101,7 +101,7 @@ var _ = registerSimpleMethod(
 			`{"value":1000}`,
 			`{"new_value":3}`,
 		),
-	),
+	).Accepts(value.TNumber).Returns(value.TNumber),
 	func(*ParsedParams) (simpleMethod, error) {
 		return numberMethod(func(f *float64, i *int64, ui *uint64) (any, error) {
 			var v float64
@@ -135,7 +135,7 @@ var _ = registerSimpleMethod(
 			`{"value":7}`,
 			`{"new_value":7}`,
 		),
-	),
+	).Accepts(value.TArray).Returns(value.TNumber),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			arr, ok := v.([]any)
@@ -178,7 +178,7 @@ var _ = registerSimpleMethod(
 			`{"value":23}`,
 			`{"new_value":10}`,
 		),
-	),
+	).Accepts(value.TArray).Returns(value.TNumber),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			arr, ok := v.([]any)
@@ -216,7 +216,7 @@ var _ = registerSimpleMethod(
 			`{"value":5.9}`,
 			`{"new_value":6}`,
 		),
-	),
+	).Accepts(value.TNumber).Returns(value.TNumber),
 	func(*ParsedParams) (simpleMethod, error) {
 		return numberMethod(func(f *float64, i *int64, ui *uint64) (any, error) {
 			if f != nil {
@@ -47,7 +47,7 @@ var _ = registerSimpleMethod(
 			`{"name":"foobar bazson"}`,
 			`{"first_byte":102}`,
 		),
-	),
+	).Returns(value.TBytes),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			return value.IToBytes(v), nil
@@ -68,7 +68,7 @@ var _ = registerSimpleMethod(
 			`{"title":"the foo bar"}`,
 			`{"title":"The Foo Bar"}`,
 		),
-	),
+	).Accepts(value.TString, value.TBytes).Returns(value.TString, value.TBytes),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			switch t := v.(type) {
@@ -462,7 +462,7 @@ var _ = registerSimpleMethod(
 			`{"value":"foo & bar"}`,
 			`{"escaped":"foo &amp; bar"}`,
 		),
-	),
+	).Accepts(value.TString, value.TBytes, value.TTimestamp).Returns(value.TString),
 	func(*ParsedParams) (simpleMethod, error) {
 		return stringMethod(func(s string) (any, error) {
 			return html.EscapeString(s), nil
@@ -488,7 +488,7 @@ var _ = registerSimpleMethod(
 			`the cat meowed, the dog woofed`,
 			`{"index":8}`,
 		),
-	).Param(ParamString("value", "A string to search for.")),
+	).Param(ParamString("value", "A string to search for.")).Accepts(value.TString, value.TBytes).Returns(value.TNumber),
 	func(args *ParsedParams) (simpleMethod, error) {
 		substring, err := args.FieldString("value")
 		if err != nil {
@@ -519,7 +519,7 @@ var _ = registerSimpleMethod(
 			`{"value":"foo &amp; bar"}`,
 			`{"unescaped":"foo & bar"}`,
 		),
-	),
+	).Accepts(value.TString, value.TBytes, value.TTimestamp).Returns(value.TString),
 	func(*ParsedParams) (simpleMethod, error) {
 		return stringMethod(func(s string) (any, error) {
 			return html.UnescapeString(s), nil
@@ -540,7 +540,7 @@ var _ = registerSimpleMethod(
 			`{"value":"foo & bar"}`,
 			`{"escaped":"foo+%26+bar"}`,
 		),
-	),
+	).Accepts(value.TString, value.TBytes, value.TTimestamp).Returns(value.TString),
 	func(*ParsedParams) (simpleMethod, error) {
 		return stringMethod(func(s string) (any, error) {
 			return url.QueryEscape(s), nil
@@ -561,7 +561,7 @@ var _ = registerSimpleMethod(
 			`{"value":"foo+%26+bar"}`,
 			`{"unescaped":"foo & bar"}`,
 		),
-	),
+	).Accepts(value.TString, value.TBytes, value.TTimestamp).Returns(value.TString),
 	func(*ParsedParams) (simpleMethod, error) {
 		return stringMethod(func(s string) (any, error) {
 			return url.QueryUnescape(s)
@@ -582,7 +582,7 @@ var _ = registerSimpleMethod(
 			strings.ReplaceAll(`{"path_elements":["/foo/","bar.txt"]}`, "/", string(filepath.Separator)),
 			strings.ReplaceAll(`{"path":"/foo/bar.txt"}`, "/", string(filepath.Separator)),
 		),
-	),
+	).Accepts(value.TArray).Returns(value.TString),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			arr, ok := v.([]any)
@@ -615,7 +615,7 @@ var _ = registerSimpleMethod(
 			`{"path":"baz.txt"}`,
 			`{"path_sep":["","baz.txt"]}`,
 		),
-	),
+	).Accepts(value.TString, value.TBytes, value.TTimestamp).Returns(value.TArray),
 	func(*ParsedParams) (simpleMethod, error) {
 		return stringMethod(func(s string) (any, error) {
 			dir, file := filepath.Split(s)
@@ -637,7 +637,7 @@ var _ = registerSimpleMethod(
 			`{"name":"lance","age":37,"fingers":13}`,
 			`{"foo":"lance(37): 13"}`,
 		),
-	).VariadicParams(),
+	).VariadicParams().Accepts(value.TString, value.TBytes, value.TTimestamp).Returns(value.TString),
 	func(args *ParsedParams) (simpleMethod, error) {
 		return stringMethod(func(s string) (any, error) {
 			return fmt.Sprintf(s, args.Raw()...), nil
@@ -659,7 +659,7 @@ root.t2 = this.v2.has_prefix("foo")`,
 			`{"v1":"foobar","v2":"barfoo"}`,
 			`{"t1":true,"t2":false}`,
 		),
-	).Param(ParamString("value", "The string to test.")),
+	).Param(ParamString("value", "The string to test.")).Accepts(value.TString, value.TBytes).Returns(value.TBool),
 	func(args *ParsedParams) (simpleMethod, error) {
 		prefix, err := args.FieldString("value")
 		if err != nil {
@@ -692,7 +692,7 @@ root.t2 = this.v2.has_suffix("foo")`,
 			`{"v1":"foobar","v2":"barfoo"}`,
 			`{"t1":false,"t2":true}`,
 		),
-	).Param(ParamString("value", "The string to test.")),
+	).Param(ParamString("value", "The string to test.")).Accepts(value.TString, value.TBytes).Returns(value.TBool),
 	func(args *ParsedParams) (simpleMethod, error) {
 		suffix, err := args.FieldString("value")
 		if err != nil {
@@ -865,7 +865,7 @@ root.joined_numbers = this.numbers.map_each(this.string()).join(",")`,
 			`{"words":["hello","world"],"numbers":[3,8,11]}`,
 			`{"joined_numbers":"3,8,11","joined_words":"helloworld"}`,
 		),
-	).Param(ParamString("delimiter", "An optional delimiter to add between each string.").Optional()),
+	).Param(ParamString("delimiter", "An optional delimiter to add between each string.").Optional()).Accepts(value.TArray).Returns(value.TString),
 	func(args *ParsedParams) (simpleMethod, error) {
 		delimArg, err := args.FieldOptionalString("delimiter")
 		if err != nil {
@@ -913,7 +913,7 @@ var _ = registerSimpleMethod(
 			`{"foo":"hello world"}`,
 			`{"foo":"HELLO WORLD"}`,
 		),
-	),
+	).Accepts(value.TString, value.TBytes).Returns(value.TString, value.TBytes),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			switch t := v.(type) {
@@ -941,7 +941,7 @@ var _ = registerSimpleMethod(
 			`{"foo":"HELLO WORLD"}`,
 			`{"foo":"hello world"}`,
 		),
-	),
+	).Accepts(value.TString, value.TBytes).Returns(value.TString, value.TBytes),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			switch t := v.(type) {
@@ -1090,7 +1090,7 @@ var _ = registerSimpleMethod(
 			`{"doc":"{\"foo\":\"11380878173205700000000000000000000000000000000\"}"}`,
 			`{"doc":{"foo":"11380878173205700000000000000000000000000000000"}}`,
 		),
-	),
+	).Accepts(value.TString, value.TBytes),
 	func(args *ParsedParams) (simpleMethod, error) {
 		useNumber, err := args.FieldOptionalBool("use_number")
 		if err != nil {
@@ -1168,7 +1168,7 @@ var _ = registerSimpleMethod(
 			`{"doc":{"foo":"bar"}}`,
 			`{"doc":"foo: bar\n"}`,
 		),
-	),
+	).Returns(value.TBytes),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			return yaml.Marshal(v)
@@ -1217,7 +1217,8 @@ var _ = registerSimpleMethod(
 		Param(ParamBool(
 			"no_indent",
 			"Disable indentation.",
-		).Default(false)),
+		).Default(false)).
+		Returns(value.TBytes),
 	func(args *ParsedParams) (simpleMethod, error) {
 		indentOpt, err := args.FieldOptionalString("indent")
 		if err != nil {
@@ -1306,7 +1307,7 @@ var _ = registerSimpleMethod(
 			`{"thing":"backwards"}`,
 			`}"sdrawkcab":"gniht"{`,
 		),
-	),
+	).Accepts(value.TString, value.TBytes).Returns(value.TString, value.TBytes),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			switch t := v.(type) {
@@ -1342,7 +1343,7 @@ var _ = registerSimpleMethod(
 			`{"thing":"foo\nbar"}`,
 			`{"quoted":"\"foo\\nbar\""}`,
 		),
-	),
+	).Accepts(value.TString, value.TBytes, value.TTimestamp).Returns(value.TString),
 	func(*ParsedParams) (simpleMethod, error) {
 		return stringMethod(func(s string) (any, error) {
 			return strconv.Quote(s), nil
@@ -1363,7 +1364,7 @@ var _ = registerSimpleMethod(
 			`{"thing":"\"foo\\nbar\""}`,
 			`{"unquoted":"foo\nbar"}`,
 		),
-	),
+	).Accepts(value.TString, value.TBytes, value.TTimestamp).Returns(value.TString),
 	func(*ParsedParams) (simpleMethod, error) {
 		return stringMethod(func(s string) (any, error) {
 			return strconv.Unquote(s)
@@ -1393,7 +1394,9 @@ var _ = registerSimpleMethod(
 		),
 	).
 		Param(ParamString("old", "A string to match against.")).
-		Param(ParamString("new", "A string to replace with.")),
+		Param(ParamString("new", "A string to replace with.")).
+		Accepts(value.TString, value.TBytes).
+		Returns(value.TString, value.TBytes),
 	replaceAllImpl,
 )
 
@@ -1727,7 +1730,7 @@ var _ = registerSimpleMethod(
 			`{"value":"there are ten puppies"}`,
 			`{"matches":false}`,
 		),
-	).Param(ParamString("pattern", "The pattern to match against.")),
+	).Param(ParamString("pattern", "The pattern to match against.")).Accepts(value.TString, value.TBytes).Returns(value.TBool),
 	func(args *ParsedParams) (simpleMethod, error) {
 		reStr, err := args.FieldString("pattern")
 		if err != nil {
@@ -1819,7 +1822,7 @@ var _ = registerSimpleMethod(
 			`{"value":"foo,bar,baz"}`,
 			`{"new_value":["foo","bar","baz"]}`,
 		),
-	).Param(ParamString("delimiter", "The delimiter to split with.")),
+	).Param(ParamString("delimiter", "The delimiter to split with.")).Accepts(value.TString, value.TBytes).Returns(value.TArray),
 	func(args *ParsedParams) (simpleMethod, error) {
 		delim, err := args.FieldString("delimiter")
 		if err != nil {
@@ -1866,7 +1869,7 @@ var _ = registerSimpleMethod(
 			`{"id":228930314431312345}`,
 			`{"id":"228930314431312345"}`,
 		),
-	),
+	).Returns(value.TString),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			return value.IToString(v), nil
@@ -1892,7 +1895,7 @@ var _ = registerSimpleMethod(
 			`{"value":"<article><p>the plain <strong>old text</strong></p></article>"}`,
 			`{"stripped":"<article>the plain old text</article>"}`,
 		),
-	).Param(ParamArray("preserve", "An optional array of element types to preserve in the output.").Optional()),
+	).Param(ParamArray("preserve", "An optional array of element types to preserve in the output.").Optional()).Accepts(value.TString, value.TBytes).Returns(value.TString, value.TBytes),
 	func(args *ParsedParams) (simpleMethod, error) {
 		p := bluemonday.NewPolicy()
 		tags, err := args.FieldOptionalArray("preserve")
@@ -1935,7 +1938,7 @@ root.description = this.description.trim()`,
 			`{"description":"  something happened and its amazing! ","title":"!!!watch out!?"}`,
 			`{"description":"something happened and its amazing!","title":"watch out"}`,
 		),
-	).Param(ParamString("cutset", "An optional string of characters to trim from the target value.").Optional()),
+	).Param(ParamString("cutset", "An optional string of characters to trim from the target value.").Optional()).Accepts(value.TString, value.TBytes).Returns(value.TString, value.TBytes),
 	func(args *ParsedParams) (simpleMethod, error) {
 		cutset, err := args.FieldOptionalString("cutset")
 		if err != nil {
@@ -1972,7 +1975,9 @@ root.description = this.description.trim_prefix("foobar_")`,
 			`{"description":"unchanged","name":"blobton"}`,
 		),
 	).Param(ParamString("prefix", "The leading prefix substring to trim from the string.")).
-		AtVersion("4.12.0"),
+		AtVersion("4.12.0").
+		Accepts(value.TString, value.TBytes).
+		Returns(value.TString, value.TBytes),
 	func(args *ParsedParams) (simpleMethod, error) {
 		prefix, err := args.FieldString("prefix")
 		if err != nil {
@@ -2004,7 +2009,9 @@ root.description = this.description.trim_suffix("_foobar")`,
 			`{"description":"unchanged","name":"blobton"}`,
 		),
 	).Param(ParamString("suffix", "The trailing suffix substring to trim from the string.")).
-		AtVersion("4.12.0"),
+		AtVersion("4.12.0").
+		Accepts(value.TString, value.TBytes).
+		Returns(value.TString, value.TBytes),
 	func(args *ParsedParams) (simpleMethod, error) {
 		suffix, err := args.FieldString("suffix")
 		if err != nil {
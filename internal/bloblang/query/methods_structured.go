@@ -28,7 +28,7 @@ var _ = registerSimpleMethod(
 			`{"patrons":[{"id":"1","age":45},{"id":"2","age":23}]}`,
 			`{"all_over_21":true}`,
 		),
-	).Param(ParamQuery("test", "A test query to apply to each element.", false)),
+	).Param(ParamQuery("test", "A test query to apply to each element.", false)).Accepts(value.TArray).Returns(value.TBool),
 	func(args *ParsedParams) (simpleMethod, error) {
 		queryFn, err := args.FieldQuery("test")
 		if err != nil {
@@ -74,7 +74,7 @@ var _ = registerSimpleMethod(
 			`{"patrons":[{"id":"1","age":10},{"id":"2","age":12}]}`,
 			`{"any_over_21":false}`,
 		),
-	).Param(ParamQuery("test", "A test query to apply to each element.", false)),
+	).Param(ParamQuery("test", "A test query to apply to each element.", false)).Accepts(value.TArray).Returns(value.TBool),
 	func(args *ParsedParams) (simpleMethod, error) {
 		queryFn, err := args.FieldQuery("test")
 		if err != nil {
@@ -123,7 +123,7 @@ var _ = registerSimpleMethod(
 			`{"foo":["bar","baz"]}`,
 			`{"foo":["bar","baz","and","this"]}`,
 		),
-	).VariadicParams(),
+	).VariadicParams().Accepts(value.TArray).Returns(value.TArray),
 	func(args *ParsedParams) (simpleMethod, error) {
 		argsList := args.Raw()
 		return func(res any, ctx FunctionContext) (any, error) {
@@ -205,7 +205,7 @@ var _ = registerSimpleMethod(
 			`{"thing":"this bar that"}`,
 			`{"has_foo":false}`,
 		),
-	).Param(ParamAny("value", "A value to test against elements of the target.")),
+	).Param(ParamAny("value", "A value to test against elements of the target.")).Accepts(value.TString, value.TBytes, value.TArray, value.TObject).Returns(value.TBool),
 	func(args *ParsedParams) (simpleMethod, error) {
 		compareRight, err := args.Field("value")
 		if err != nil {
@@ -252,7 +252,7 @@ var _ = registerSimpleMethod(
 			`{"foo":["bar","baz"]}`,
 			`{"foo":[{"index":0,"value":"bar"},{"index":1,"value":"baz"}]}`,
 		),
-	),
+	).Accepts(value.TArray).Returns(value.TArray),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			arr, ok := v.([]any)
@@ -286,7 +286,7 @@ var _ = registerSimpleMethod(
 			`{"foo":{}}`,
 			`{"result":false}`,
 		),
-	).Param(ParamString("path", "A [dot path][field_paths] to a field.")),
+	).Param(ParamString("path", "A [dot path][field_paths] to a field.")).Returns(value.TBool),
 	func(args *ParsedParams) (simpleMethod, error) {
 		pathStr, err := args.FieldString("path")
 		if err != nil {
@@ -613,7 +613,7 @@ var _ = registerSimpleMethod(
 			`["foo",["bar","baz"],"buz"]`,
 			`{"result":["foo","bar","baz","buz"]}`,
 		),
-	),
+	).Accepts(value.TArray).Returns(value.TArray),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			array, isArray := v.([]any)
@@ -816,7 +816,7 @@ var _ = registerSimpleMethod(
 			`{"foo":{"bar":1,"baz":2}}`,
 			`{"foo_keys":["bar","baz"]}`,
 		),
-	),
+	).Accepts(value.TObject).Returns(value.TArray),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			if m, ok := v.(map[string]any); ok {
@@ -846,7 +846,7 @@ var _ = registerSimpleMethod(
 			`{"foo":{"bar":1,"baz":2}}`,
 			`{"foo_key_values":[{"key":"bar","value":1},{"key":"baz","value":2}]}`,
 		),
-	),
+	).Accepts(value.TObject).Returns(value.TArray),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			if m, ok := v.(map[string]any); ok {
@@ -885,7 +885,7 @@ var _ = registerSimpleMethod(
 			`{"foo":{"first":"bar","second":"baz"}}`,
 			`{"foo_len":2}`,
 		),
-	),
+	).Accepts(value.TString, value.TBytes, value.TArray, value.TObject).Returns(value.TNumber),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			var length int64
@@ -1173,7 +1173,7 @@ var _ = registerSimpleMethod(
 			`{"a":{}}`,
 			`Error("failed assignment (line 1): field `+"`this.a`"+`: object value is empty")`,
 		),
-	),
+	).Accepts(value.TString, value.TArray, value.TObject),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			switch t := v.(type) {
@@ -1507,7 +1507,7 @@ var _ = registerMethod(
 			`{"foo":[3,8,4]}`,
 			`{"sum":15}`,
 		),
-	),
+	).Accepts(value.TNumber, value.TArray).Returns(value.TNumber),
 	sumMethod,
 )
 
@@ -1557,7 +1557,9 @@ var _ = registerSimpleMethod(
 			"emit",
 			"An optional query that can be used in order to yield a value for each element to determine uniqueness.",
 			false,
-		).Optional()),
+		).Optional()).
+		Accepts(value.TArray).
+		Returns(value.TArray),
 	uniqueMethod,
 )
 
@@ -1654,7 +1656,7 @@ var _ = registerSimpleMethod(
 			`{"foo":{"bar":1,"baz":2}}`,
 			`{"foo_vals":[1,2]}`,
 		),
-	),
+	).Accepts(value.TObject).Returns(value.TArray),
 	func(*ParsedParams) (simpleMethod, error) {
 		return func(v any, ctx FunctionContext) (any, error) {
 			if m, ok := v.(map[string]any); ok {
@@ -1684,7 +1686,7 @@ If a key within a nested path does not exist or is not an object then it is not
 			`{"inner":{"a":"first","b":"second","c":"third"},"d":"fourth","e":"fifth"}`,
 			`{"e":"fifth","inner":{"b":"second"}}`,
 		),
-	).VariadicParams(),
+	).VariadicParams().Accepts(value.TObject).Returns(value.TObject),
 	func(args *ParsedParams) (simpleMethod, error) {
 		excludeList := make([][]string, 0, len(args.Raw()))
 		for i, argVal := range args.Raw() {
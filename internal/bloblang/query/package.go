@@ -60,6 +60,10 @@ type FunctionContext struct {
 
 	// Used to track how many maps we've entered.
 	stackCount int
+
+	trace *Trace
+
+	fieldValues *fieldCacheValues
 }
 
 type namedContextValue struct {
@@ -75,6 +79,36 @@ func (ctx FunctionContext) IncrStackCount() (FunctionContext, int) { //nolint: g
 	return ctx, ctx.stackCount
 }
 
+// WithTrace returns a function context where the executions of traced
+// functions are recorded within the provided trace.
+func (ctx FunctionContext) WithTrace(t *Trace) FunctionContext {
+	ctx.trace = t
+	return ctx
+}
+
+// WithFieldCache returns a function context where fields interned with the
+// provided cache share the results of common paths. The results are retained
+// for the lifetime of the context and any contexts derived from it, unless it
+// already holds results for the same cache, in which case they're reused.
+func (ctx FunctionContext) WithFieldCache(c *FieldCache) FunctionContext {
+	if c.Slots() == 0 || (ctx.fieldValues != nil && ctx.fieldValues.owner == c) {
+		return ctx
+	}
+	ctx.fieldValues = &fieldCacheValues{
+		owner:  c,
+		values: make([]fieldCacheValue, c.slots),
+	}
+	return ctx
+}
+
+// ResetFieldCache discards the results of cached fields, which must be called
+// whenever the context value might have been mutated.
+func (ctx FunctionContext) ResetFieldCache() {
+	if ctx.fieldValues != nil {
+		ctx.fieldValues.reset()
+	}
+}
+
 // NamedValue returns the value of a named context if it exists.
 func (ctx FunctionContext) NamedValue(name string) (any, bool) {
 	current := ctx.namedValue
@@ -74,6 +74,12 @@ type TargetsContext struct {
 	mainContext   []TargetPath
 	prevContext   *prevContextPath
 	namedContext  *namedContextPath
+	enteredMaps   *enteredMap
+}
+
+type enteredMap struct {
+	fn   Function
+	next *enteredMap
 }
 
 type prevContextPath struct {
@@ -148,6 +154,19 @@ func (ctx TargetsContext) WithContextAsNamed(name string) TargetsContext {
 	return ctx
 }
 
+// enterMap returns a targets context where a map is marked as entered, and
+// false if it had already been entered, in which case the map is recursive and
+// its targets have already been accounted for.
+func (ctx TargetsContext) enterMap(fn Function) (TargetsContext, bool) {
+	for current := ctx.enteredMaps; current != nil; current = current.next {
+		if current.fn == fn {
+			return ctx, false
+		}
+	}
+	ctx.enteredMaps = &enteredMap{fn: fn, next: ctx.enteredMaps}
+	return ctx, true
+}
+
 // PopContext returns a targets context with the latest context dropped and the
 // previous (when applicable) returned.
 func (ctx TargetsContext) PopContext() TargetsContext {
@@ -0,0 +1,89 @@
+package query
+
+import (
+	"github.com/benthosdev/benthos/v4/internal/value"
+)
+
+// TraceStep describes a single execution of a traced query step, where the
+// line and column identify the beginning of the expression within a mapping.
+type TraceStep struct {
+	Line       int
+	Column     int
+	Expression string
+	Value      any
+	Err        error
+}
+
+// Trace records the results of traced query steps in the order in which they
+// complete. Since the steps of a chain complete from the inside out the target
+// of a method is always recorded before the method itself. A nil trace is
+// valid and records nothing.
+type Trace struct {
+	steps []TraceStep
+}
+
+// NewTrace creates an empty trace.
+func NewTrace() *Trace {
+	return &Trace{}
+}
+
+func (t *Trace) record(step TraceStep) {
+	if t != nil {
+		t.steps = append(t.steps, step)
+	}
+}
+
+// Steps returns the recorded steps.
+func (t *Trace) Steps() []TraceStep {
+	if t == nil {
+		return nil
+	}
+	return t.steps
+}
+
+//------------------------------------------------------------------------------
+
+// NewTracedFunction wraps a function so that each execution records its result
+// within the trace of the function context, if there is one. The value is
+// cloned at the time of recording so that later mutations made by the mapping
+// are not reflected in the trace.
+func NewTracedFunction(line, column int, expression string, fn Function) Function {
+	return &tracedFunction{
+		line:       line,
+		column:     column,
+		expression: expression,
+		fn:         fn,
+	}
+}
+
+type tracedFunction struct {
+	line       int
+	column     int
+	expression string
+	fn         Function
+}
+
+func (t *tracedFunction) Annotation() string {
+	return t.fn.Annotation()
+}
+
+func (t *tracedFunction) Exec(ctx FunctionContext) (any, error) {
+	v, err := t.fn.Exec(ctx)
+	if ctx.trace != nil {
+		step := TraceStep{
+			Line:       t.line,
+			Column:     t.column,
+			Expression: t.expression,
+			Err:        err,
+		}
+		if err == nil {
+			step.Value = value.IClone(v)
+		}
+		ctx.trace.record(step)
+	}
+	return v, err
+}
+
+func (t *tracedFunction) QueryTargets(ctx TargetsContext) (TargetsContext, []TargetPath) {
+	return t.fn.QueryTargets(ctx)
+}
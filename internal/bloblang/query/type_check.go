@@ -0,0 +1,829 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+)
+
+// TypeSet is a set of the types that a value might be, which is used in order
+// to statically check the types of queries.
+type TypeSet uint16
+
+// All types that can be members of a TypeSet.
+const (
+	TypeString TypeSet = 1 << iota
+	TypeBytes
+	TypeNumber
+	TypeBool
+	TypeTimestamp
+	TypeArray
+	TypeObject
+	TypeNull
+	TypeDelete
+	TypeNothing
+
+	// TypeAny is the set of all types and describes a value of an unknown
+	// type.
+	TypeAny = TypeString | TypeBytes | TypeNumber | TypeBool | TypeTimestamp |
+		TypeArray | TypeObject | TypeNull | TypeDelete | TypeNothing
+)
+
+var typeSetNames = []struct {
+	t TypeSet
+	v value.Type
+}{
+	{TypeString, value.TString},
+	{TypeBytes, value.TBytes},
+	{TypeNumber, value.TNumber},
+	{TypeBool, value.TBool},
+	{TypeTimestamp, value.TTimestamp},
+	{TypeArray, value.TArray},
+	{TypeObject, value.TObject},
+	{TypeNull, value.TNull},
+	{TypeDelete, value.TDelete},
+	{TypeNothing, value.TNothing},
+}
+
+// NewTypeSet creates a set from zero or more value types. Types that cannot be
+// narrowed down, such as queries and unknown types, result in TypeAny.
+func NewTypeSet(types ...value.Type) TypeSet {
+	var t TypeSet
+	for _, vt := range types {
+		switch vt {
+		case value.TInt, value.TFloat:
+			t |= TypeNumber
+			continue
+		case value.TQuery, value.TUnknown:
+			return TypeAny
+		}
+		for _, n := range typeSetNames {
+			if n.v == vt {
+				t |= n.t
+			}
+		}
+	}
+	return t
+}
+
+// Overlaps returns true if any type is a member of both sets.
+func (t TypeSet) Overlaps(other TypeSet) bool {
+	return t&other != 0
+}
+
+func (t TypeSet) String() string {
+	if t == TypeAny {
+		return string(value.TUnknown)
+	}
+	var names []string
+	for _, n := range typeSetNames {
+		if t&n.t != 0 {
+			names = append(names, string(n.v))
+		}
+	}
+	switch len(names) {
+	case 0:
+		return "nothing"
+	case 1:
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+//------------------------------------------------------------------------------
+
+// TypeSchema describes the structure of the values that a query might resolve
+// to. A nil schema describes a value of an unknown type.
+type TypeSchema struct {
+	// Types is the set of types the value might be, where an empty set is
+	// treated as unknown.
+	Types TypeSet
+
+	// Properties describes the known fields of object values.
+	Properties map[string]*TypeSchema
+
+	// Values describes the fields of object values that aren't listed within
+	// Properties.
+	Values *TypeSchema
+
+	// Items describes the elements of array values.
+	Items *TypeSchema
+}
+
+func typeSchemaOf(t TypeSet) *TypeSchema {
+	if t == 0 || t == TypeAny {
+		return nil
+	}
+	return &TypeSchema{Types: t}
+}
+
+func (s *TypeSchema) types() TypeSet {
+	if s == nil || s.Types == 0 {
+		return TypeAny
+	}
+	return s.Types
+}
+
+// field returns the schema of the value found at a path of the value described
+// by the schema, following the semantics of field references.
+func (s *TypeSchema) field(path []string) *TypeSchema {
+	for _, seg := range path {
+		if s == nil {
+			return nil
+		}
+
+		var parts []*TypeSchema
+		types := s.types()
+		if types&^(TypeObject|TypeArray) != 0 {
+			parts = append(parts, &TypeSchema{Types: TypeNull})
+		}
+		if types&TypeObject != 0 {
+			p, exists := s.Properties[seg]
+			if !exists {
+				p = s.Values
+			}
+			parts = append(parts, p)
+		}
+		if types&TypeArray != 0 {
+			if _, err := strconv.Atoi(seg); err != nil {
+				return nil
+			}
+			parts = append(parts, withNullType(s.Items))
+		}
+		s = unionTypeSchemas(parts...)
+	}
+	return s
+}
+
+func withNullType(s *TypeSchema) *TypeSchema {
+	return unionTypeSchemas(s, &TypeSchema{Types: TypeNull})
+}
+
+// unionTypeSchemas returns a schema describing values of any of the provided
+// schemas, which is unknown when any of them are unknown.
+func unionTypeSchemas(schemas ...*TypeSchema) *TypeSchema {
+	if len(schemas) == 0 {
+		return nil
+	}
+	u := schemas[0]
+	for _, s := range schemas[1:] {
+		u = unionTypeSchemaPair(u, s)
+	}
+	return u
+}
+
+func unionTypeSchemaPair(l, r *TypeSchema) *TypeSchema {
+	if l == nil || r == nil || l.types()|r.types() == TypeAny {
+		return nil
+	}
+
+	u := &TypeSchema{Types: l.Types | r.Types}
+	lObj, rObj := l.Types&TypeObject != 0, r.Types&TypeObject != 0
+	switch {
+	case lObj && rObj:
+		for k, lp := range l.Properties {
+			if rp, exists := r.Properties[k]; exists {
+				if p := unionTypeSchemas(lp, rp); p != nil {
+					if u.Properties == nil {
+						u.Properties = map[string]*TypeSchema{}
+					}
+					u.Properties[k] = p
+				}
+			}
+		}
+		if l.Values != nil && r.Values != nil {
+			u.Values = unionTypeSchemas(l.Values, r.Values)
+		}
+	case lObj:
+		u.Properties, u.Values = l.Properties, l.Values
+	case rObj:
+		u.Properties, u.Values = r.Properties, r.Values
+	}
+
+	lArr, rArr := l.Types&TypeArray != 0, r.Types&TypeArray != 0
+	switch {
+	case lArr && rArr:
+		if l.Items != nil && r.Items != nil {
+			u.Items = unionTypeSchemas(l.Items, r.Items)
+		}
+	case lArr:
+		u.Items = l.Items
+	case rArr:
+		u.Items = r.Items
+	}
+	return u
+}
+
+// valueTypeSchema returns the schema of a static value.
+func valueTypeSchema(v any) *TypeSchema {
+	switch t := v.(type) {
+	case map[string]any:
+		s := &TypeSchema{Types: TypeObject, Properties: make(map[string]*TypeSchema, len(t))}
+		for k, e := range t {
+			s.Properties[k] = valueTypeSchema(e)
+		}
+		return s
+	case []any:
+		items := make([]*TypeSchema, len(t))
+		for i, e := range t {
+			items[i] = valueTypeSchema(e)
+		}
+		return &TypeSchema{Types: TypeArray, Items: unionTypeSchemas(items...)}
+	}
+	return typeSchemaOf(NewTypeSet(value.ITypeOf(v)))
+}
+
+//------------------------------------------------------------------------------
+
+// TypeCheckError describes a problem found by statically checking the types
+// of a query, where the line and column identify the beginning of the
+// offending expression within a mapping.
+type TypeCheckError struct {
+	Line    int
+	Column  int
+	Message string
+
+	// Unreachable is true when the error describes a match case that can never
+	// be reached rather than an operation that would fail.
+	Unreachable bool
+}
+
+// Error returns a human readable description of the problem.
+func (e TypeCheckError) Error() string {
+	return fmt.Sprintf("line %v char %v: %v", e.Line, e.Column, e.Message)
+}
+
+// TypeChecker infers the types of queries without executing them in order to
+// find operations that would definitely fail. Only queries parsed with type
+// checking enabled carry the information required for most checks, and
+// problems are only reported when they're certain to occur.
+type TypeChecker struct {
+	input *TypeSchema
+	vars  map[string]*TypeSchema
+	errs  []TypeCheckError
+}
+
+// NewTypeChecker creates a type checker for queries executed against input
+// values described by a schema, which can be nil when the input is unknown.
+func NewTypeChecker(input *TypeSchema) *TypeChecker {
+	return &TypeChecker{
+		input: input,
+		vars:  map[string]*TypeSchema{},
+	}
+}
+
+// SetVar sets the schema of a variable for the queries checked thereafter.
+func (c *TypeChecker) SetVar(name string, s *TypeSchema) {
+	c.vars[name] = s
+}
+
+// Infer returns the schema of the value that a query resolves to when executed
+// against the input, recording any problems found within it.
+func (c *TypeChecker) Infer(fn Function) *TypeSchema {
+	return c.infer(typeScope{value: c.input}, fn)
+}
+
+// Errors returns all problems found so far.
+func (c *TypeChecker) Errors() []TypeCheckError {
+	return c.errs
+}
+
+func (c *TypeChecker) errorf(line, column int, format string, args ...any) {
+	c.errs = append(c.errs, TypeCheckError{
+		Line:    line,
+		Column:  column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *TypeChecker) unreachable(line, column int, format string, args ...any) {
+	c.errs = append(c.errs, TypeCheckError{
+		Line:        line,
+		Column:      column,
+		Message:     fmt.Sprintf(format, args...),
+		Unreachable: true,
+	})
+}
+
+// typeScope contains the schemas of the contexts available to a query.
+type typeScope struct {
+	value *TypeSchema
+	named map[string]*TypeSchema
+}
+
+func (s typeScope) withValue(v *TypeSchema) typeScope {
+	s.value = v
+	return s
+}
+
+func (c *TypeChecker) infer(s typeScope, fn Function) *TypeSchema {
+	switch t := fn.(type) {
+	case *Literal:
+		return valueTypeSchema(t.Value)
+	case *typedFunction:
+		return t.node.inferType(c, s, t.line, t.column)
+	case *tracedFunction:
+		return c.infer(s, t.fn)
+	case *coveredFunction:
+		return c.infer(s, t.fn)
+	case *cachedField:
+		return c.infer(s, t.field)
+	case *fieldFunction:
+		if t.fromRoot {
+			return nil
+		}
+		if t.namedContext != "" {
+			return s.named[t.namedContext].field(t.path)
+		}
+		return s.value.field(t.path)
+	case *getMethod:
+		return c.infer(s, t.fn).field(t.path)
+	case *varFunction:
+		return c.vars[t.name]
+	case *mapCallFunction:
+		for _, arg := range t.argFunctions() {
+			c.infer(s, arg)
+		}
+		return nil
+	case *notMethod:
+		c.infer(s, t.fn)
+		return &TypeSchema{Types: TypeBool}
+	case *NamedContextFunction:
+		named := make(map[string]*TypeSchema, len(s.named)+1)
+		for k, v := range s.named {
+			named[k] = v
+		}
+		if t.name != "_" {
+			named[t.name] = s.value
+		}
+		return c.infer(typeScope{named: named}, t.fn)
+	case *mapLiteral:
+		obj := &TypeSchema{Types: TypeObject, Properties: map[string]*TypeSchema{}}
+		for _, kv := range t.keyValues {
+			key, isStatic := kv[0].(string)
+			if keyFn, ok := kv[0].(Function); ok {
+				c.infer(s, keyFn)
+			}
+			v := valueTypeSchema(kv[1])
+			if vFn, ok := kv[1].(Function); ok {
+				// Fields that resolve to nothing are omitted from the object.
+				if v = c.infer(s, vFn); v.types().Overlaps(TypeDelete | TypeNothing) {
+					v = withNullType(v)
+				}
+			}
+			if isStatic && v != nil {
+				obj.Properties[key] = v
+			}
+		}
+		return obj
+	case *arrayLiteral:
+		items := make([]*TypeSchema, len(t.values))
+		for i, e := range t.values {
+			if eFn, ok := e.(Function); ok {
+				items[i] = c.infer(s, eFn)
+			} else {
+				items[i] = valueTypeSchema(e)
+			}
+		}
+		return &TypeSchema{Types: TypeArray, Items: unionTypeSchemas(items...)}
+	}
+	return nil
+}
+
+// captureErrors returns the problems recorded while running a closure without
+// recording them within the checker.
+func (c *TypeChecker) captureErrors(fn func()) []TypeCheckError {
+	prev := c.errs
+	c.errs = nil
+	fn()
+	captured := c.errs
+	c.errs = prev
+	return captured
+}
+
+// argTypes returns the set of value types accepted by a parameter.
+func argTypes(def ParamDefinition) TypeSet {
+	switch def.ValueType {
+	case value.TInt, value.TFloat, value.TNumber:
+		return TypeNumber
+	case value.TString:
+		return TypeString | TypeBytes
+	case value.TTimestamp:
+		return TypeTimestamp | TypeNumber | TypeString | TypeBytes
+	case value.TBool:
+		return TypeBool | TypeNumber
+	case value.TArray:
+		return TypeArray
+	case value.TObject:
+		return TypeObject
+	}
+	return TypeAny
+}
+
+// checkArgs checks the dynamic arguments of a function or method, and the
+// query arguments, which are executed against an unknown context.
+func (c *TypeChecker) checkArgs(s typeScope, line, column int, callee string, args *ParsedParams) {
+	if args == nil {
+		return
+	}
+	for _, dyn := range args.dynArgs {
+		argType := c.infer(s, dyn.fn).types()
+		if dyn.index >= len(args.source.Definitions) {
+			continue
+		}
+		def := args.source.Definitions[dyn.index]
+		if accepted := argTypes(def); !argType.Overlaps(accepted) {
+			c.errorf(line, column, "%v argument %v: expected %v value, got %v", callee, def.Name, accepted, argType)
+		}
+	}
+	for i, def := range args.source.Definitions {
+		if def.ValueType != value.TQuery || i >= len(args.values) {
+			continue
+		}
+		if fn, ok := args.values[i].(Function); ok {
+			c.infer(s.withValue(nil), fn)
+		}
+	}
+}
+
+func returnTypeSchema(types []value.Type) *TypeSchema {
+	if len(types) == 0 {
+		return nil
+	}
+	return typeSchemaOf(NewTypeSet(types...))
+}
+
+//------------------------------------------------------------------------------
+
+// typeNode describes the structure of a parsed query in order for its type to
+// be inferred.
+type typeNode interface {
+	inferType(c *TypeChecker, s typeScope, line, column int) *TypeSchema
+}
+
+type typedFunction struct {
+	line   int
+	column int
+	node   typeNode
+	fn     Function
+}
+
+func (t *typedFunction) Annotation() string {
+	return t.fn.Annotation()
+}
+
+func (t *typedFunction) Exec(ctx FunctionContext) (any, error) {
+	return t.fn.Exec(ctx)
+}
+
+func (t *typedFunction) QueryTargets(ctx TargetsContext) (TargetsContext, []TargetPath) {
+	return t.fn.QueryTargets(ctx)
+}
+
+//------------------------------------------------------------------------------
+
+// NewTypedMethod wraps a method so that its target and arguments can be type
+// checked against the spec of the method.
+func NewTypedMethod(line, column int, spec MethodSpec, target Function, args *ParsedParams, fn Function) Function {
+	return &typedFunction{
+		line: line, column: column, fn: fn,
+		node: &methodTypeNode{spec: spec, target: target, args: args},
+	}
+}
+
+type methodTypeNode struct {
+	spec   MethodSpec
+	target Function
+	args   *ParsedParams
+}
+
+func (m *methodTypeNode) inferType(c *TypeChecker, s typeScope, line, column int) *TypeSchema {
+	var target *TypeSchema
+	inferTarget := func() {
+		target = c.infer(s, m.target)
+	}
+	switch m.spec.Name {
+	case "catch", "or":
+		// Errors of the target are handled by these methods.
+		c.captureErrors(inferTarget)
+	default:
+		inferTarget()
+	}
+
+	if len(m.spec.InputTypes) > 0 {
+		targetTypes := target.types()
+		if accepted := NewTypeSet(m.spec.InputTypes...); !targetTypes.Overlaps(accepted) {
+			c.errorf(line, column, "method %v: expected %v value, got %v", m.spec.Name, accepted, targetTypes)
+		}
+	}
+	c.checkArgs(s, line, column, "method "+m.spec.Name, m.args)
+	return returnTypeSchema(m.spec.ReturnTypes)
+}
+
+// NewTypedFunction wraps a function so that its arguments can be type checked
+// against the spec of the function.
+func NewTypedFunction(line, column int, spec FunctionSpec, args *ParsedParams, fn Function) Function {
+	return &typedFunction{
+		line: line, column: column, fn: fn,
+		node: &functionTypeNode{spec: spec, args: args},
+	}
+}
+
+type functionTypeNode struct {
+	spec FunctionSpec
+	args *ParsedParams
+}
+
+func (f *functionTypeNode) inferType(c *TypeChecker, s typeScope, line, column int) *TypeSchema {
+	c.checkArgs(s, line, column, "function "+f.spec.Name, f.args)
+	return returnTypeSchema(f.spec.ReturnTypes)
+}
+
+// NewTypedNot wraps a logical NOT of a target function, as created with Not, so
+// that the type of the target can be checked.
+func NewTypedNot(line, column int, target, fn Function) Function {
+	return &typedFunction{
+		line: line, column: column, fn: fn,
+		node: &notTypeNode{target: target},
+	}
+}
+
+type notTypeNode struct {
+	target Function
+}
+
+func (n *notTypeNode) inferType(c *TypeChecker, s typeScope, line, column int) *TypeSchema {
+	if targetTypes := c.infer(s, n.target).types(); !targetTypes.Overlaps(TypeBool) {
+		c.errorf(line, column, "not operator: expected %v value, got %v", TypeBool, targetTypes)
+	}
+	return &TypeSchema{Types: TypeBool}
+}
+
+//------------------------------------------------------------------------------
+
+// NewTypedArithmetic wraps an arithmetic expression so that the types of its
+// operands can be checked.
+func NewTypedArithmetic(line, column int, fns []Function, ops []ArithmeticOperator, fn Function) Function {
+	return &typedFunction{
+		line: line, column: column, fn: fn,
+		node: &arithmeticTypeNode{fns: fns, ops: ops},
+	}
+}
+
+type arithmeticTypeNode struct {
+	fns []Function
+	ops []ArithmeticOperator
+}
+
+type arithmeticOperand struct {
+	types TypeSet
+	errs  []TypeCheckError
+}
+
+// arithmeticPasses mirrors the order of precedence with which operators are
+// resolved by NewArithmeticExpression.
+var arithmeticPasses = []func(op ArithmeticOperator) bool{
+	func(op ArithmeticOperator) bool {
+		return op == ArithmeticMul || op == ArithmeticDiv || op == ArithmeticMod || op == ArithmeticPipe
+	},
+	func(op ArithmeticOperator) bool {
+		return op == ArithmeticAdd || op == ArithmeticSub
+	},
+	func(op ArithmeticOperator) bool {
+		return op >= ArithmeticEq && op <= ArithmeticLte
+	},
+	func(op ArithmeticOperator) bool {
+		return op == ArithmeticAnd || op == ArithmeticOr
+	},
+}
+
+func (a *arithmeticTypeNode) inferType(c *TypeChecker, s typeScope, line, column int) *TypeSchema {
+	operands := make([]arithmeticOperand, len(a.fns))
+	for i, fn := range a.fns {
+		operands[i].errs = c.captureErrors(func() {
+			operands[i].types = c.infer(s, fn).types()
+		})
+	}
+
+	ops := a.ops
+	for _, inPass := range arithmeticPasses {
+		nextOperands, nextOps := operands[:1:1], []ArithmeticOperator{}
+		for i, op := range ops {
+			if !inPass(op) {
+				nextOperands = append(nextOperands, operands[i+1])
+				nextOps = append(nextOps, op)
+				continue
+			}
+			lhs, rhs := nextOperands[len(nextOperands)-1], operands[i+1]
+			nextOperands[len(nextOperands)-1] = c.arithmeticResult(line, column, op, lhs, rhs)
+		}
+		operands, ops = nextOperands, nextOps
+	}
+	if len(operands) != 1 {
+		return nil
+	}
+
+	c.errs = append(c.errs, operands[0].errs...)
+	return typeSchemaOf(operands[0].types)
+}
+
+const typeStringLike = TypeString | TypeBytes | TypeTimestamp
+
+func (c *TypeChecker) arithmeticResult(line, column int, op ArithmeticOperator, lhs, rhs arithmeticOperand) arithmeticOperand {
+	var res arithmeticOperand
+	if op == ArithmeticPipe {
+		// Errors and null values of the left hand side are replaced with the
+		// right hand side.
+		res.types = (lhs.types &^ TypeNull) | rhs.types
+		res.errs = rhs.errs
+		return res
+	}
+	res.errs = append(append(res.errs, lhs.errs...), rhs.errs...)
+
+	var lAccepted TypeSet
+	rAccepted := func(TypeSet) TypeSet { return TypeAny }
+	switch op {
+	case ArithmeticAdd:
+		lAccepted = TypeNumber | TypeString | TypeBytes
+		rAccepted = func(l TypeSet) (r TypeSet) {
+			if l.Overlaps(TypeNumber) {
+				r |= TypeNumber
+			}
+			if l.Overlaps(TypeString | TypeBytes) {
+				r |= typeStringLike
+			}
+			return
+		}
+		if lhs.types.Overlaps(TypeNumber) {
+			res.types |= TypeNumber
+		}
+		if lhs.types.Overlaps(TypeString | TypeBytes) {
+			res.types |= TypeString
+		}
+	case ArithmeticSub, ArithmeticMul, ArithmeticDiv, ArithmeticMod:
+		lAccepted = TypeNumber
+		rAccepted = func(TypeSet) TypeSet { return TypeNumber }
+		res.types = TypeNumber
+	case ArithmeticGt, ArithmeticGte, ArithmeticLt, ArithmeticLte:
+		lAccepted = typeStringLike | TypeNumber
+		rAccepted = func(l TypeSet) (r TypeSet) {
+			if l.Overlaps(typeStringLike) {
+				r |= typeStringLike
+			}
+			if l.Overlaps(TypeNumber) {
+				r |= TypeNumber
+			}
+			return
+		}
+		res.types = TypeBool
+	case ArithmeticAnd, ArithmeticOr:
+		lAccepted = TypeBool | TypeNumber
+		rAccepted = func(TypeSet) TypeSet { return TypeBool | TypeNumber }
+		res.types = TypeBool
+	default:
+		lAccepted = TypeAny
+		res.types = TypeBool
+	}
+
+	if !lhs.types.Overlaps(lAccepted) || !rhs.types.Overlaps(rAccepted(lhs.types&lAccepted)) {
+		res.errs = append(res.errs, TypeCheckError{
+			Line:    line,
+			Column:  column,
+			Message: fmt.Sprintf("cannot %v types %v and %v", op, lhs.types, rhs.types),
+		})
+		res.types = TypeAny
+	}
+	return res
+}
+
+//------------------------------------------------------------------------------
+
+// TypedMatchCase describes a case of a match expression for the purpose of
+// type checking, where a nil pattern indicates a wildcard case and a literal
+// pattern is compared against the context of the match.
+type TypedMatchCase struct {
+	Line    int
+	Column  int
+	Pattern Function
+	Query   Function
+}
+
+// NewTypedMatch wraps a match expression so that the types of its cases can be
+// checked and unreachable cases can be found. The context function can be nil,
+// in which case cases are executed against the current context.
+func NewTypedMatch(line, column int, contextFn Function, cases []TypedMatchCase, fn Function) Function {
+	return &typedFunction{
+		line: line, column: column, fn: fn,
+		node: &matchTypeNode{contextFn: contextFn, cases: cases},
+	}
+}
+
+type matchTypeNode struct {
+	contextFn Function
+	cases     []TypedMatchCase
+}
+
+// literalMatchTypes returns the types of context that a literal pattern of a
+// match case might equal.
+func literalMatchTypes(v any) TypeSet {
+	switch value.ITypeOf(v) {
+	case value.TString:
+		return typeStringLike
+	case value.TNumber:
+		return TypeNumber | TypeBool | TypeTimestamp
+	case value.TBool:
+		return TypeBool | TypeTimestamp
+	case value.TNull:
+		return TypeNull
+	case value.TArray:
+		return TypeArray
+	case value.TObject:
+		return TypeObject
+	}
+	return TypeAny
+}
+
+func (m *matchTypeNode) inferType(c *TypeChecker, s typeScope, line, column int) *TypeSchema {
+	caseScope := s
+	if m.contextFn != nil {
+		caseScope = s.withValue(c.infer(s, m.contextFn))
+	}
+	contextTypes := caseScope.value.types()
+
+	var results []*TypeSchema
+	var seenWildcard bool
+	var seenLiterals []any
+	for _, mc := range m.cases {
+		switch {
+		case seenWildcard:
+			c.unreachable(mc.Line, mc.Column, "match case is unreachable as a previous case always matches")
+		case mc.Pattern == nil:
+			seenWildcard = true
+		default:
+			lit, isLit := mc.Pattern.(*Literal)
+			if !isLit {
+				if patternTypes := c.infer(caseScope, mc.Pattern).types(); !patternTypes.Overlaps(TypeBool) {
+					c.unreachable(mc.Line, mc.Column, "match case is unreachable as it resolves to %v rather than a boolean", patternTypes)
+				}
+				break
+			}
+			if !contextTypes.Overlaps(literalMatchTypes(lit.Value)) {
+				c.unreachable(mc.Line, mc.Column, "match case is unreachable as a %v value can never equal a %v literal", contextTypes, value.ITypeOf(lit.Value))
+				break
+			}
+			for _, prev := range seenLiterals {
+				if value.ITypeOf(prev) == value.ITypeOf(lit.Value) && value.ICompare(prev, lit.Value) {
+					c.unreachable(mc.Line, mc.Column, "match case is unreachable as it duplicates a previous case")
+					break
+				}
+			}
+			seenLiterals = append(seenLiterals, lit.Value)
+		}
+		results = append(results, c.infer(caseScope, mc.Query))
+	}
+	if !seenWildcard {
+		results = append(results, &TypeSchema{Types: TypeNothing})
+	}
+	return unionTypeSchemas(results...)
+}
+
+//------------------------------------------------------------------------------
+
+// NewTypedIf wraps an if expression so that the types of its conditions can be
+// checked.
+func NewTypedIf(line, column int, queryFn, ifFn Function, elseIfs []ElseIf, elseFn, fn Function) Function {
+	return &typedFunction{
+		line: line, column: column, fn: fn,
+		node: &ifTypeNode{queryFn: queryFn, ifFn: ifFn, elseIfs: elseIfs, elseFn: elseFn},
+	}
+}
+
+type ifTypeNode struct {
+	queryFn Function
+	ifFn    Function
+	elseIfs []ElseIf
+	elseFn  Function
+}
+
+func (f *ifTypeNode) inferType(c *TypeChecker, s typeScope, line, column int) *TypeSchema {
+	checkCondition := func(fn Function) {
+		if t := c.infer(s, fn).types(); !t.Overlaps(TypeBool | TypeNull) {
+			c.errorf(line, column, "if condition: expected %v value, got %v", TypeBool, t)
+		}
+	}
+
+	checkCondition(f.queryFn)
+	results := []*TypeSchema{c.infer(s, f.ifFn)}
+	for _, eIf := range f.elseIfs {
+		checkCondition(eIf.QueryFn)
+		results = append(results, c.infer(s, eIf.MapFn))
+	}
+	if f.elseFn != nil {
+		results = append(results, c.infer(s, f.elseFn))
+	} else {
+		results = append(results, &TypeSchema{Types: TypeNothing})
+	}
+	return unionTypeSchemas(results...)
+}
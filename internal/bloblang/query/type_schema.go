@@ -0,0 +1,234 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// NewTypeSchemaFromJSONSchema creates a type schema from a JSON Schema
+// document. Keywords that cannot be represented, such as references, result in
+// the relevant part of the schema being unknown.
+func NewTypeSchemaFromJSONSchema(schema []byte) (*TypeSchema, error) {
+	var root any
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON Schema: %w", err)
+	}
+	return jsonSchemaToTypeSchema(root), nil
+}
+
+func jsonSchemaToTypeSchema(v any) *TypeSchema {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		// Boolean schemas and malformed schemas describe any value.
+		return nil
+	}
+
+	if c, exists := obj["const"]; exists {
+		return valueTypeSchema(c)
+	}
+	if enum, ok := obj["enum"].([]any); ok && len(enum) > 0 {
+		return valueTypeSchema(enum).Items
+	}
+	for _, k := range []string{"anyOf", "oneOf"} {
+		if options, ok := obj[k].([]any); ok && len(options) > 0 {
+			schemas := make([]*TypeSchema, len(options))
+			for i, o := range options {
+				schemas[i] = jsonSchemaToTypeSchema(o)
+			}
+			return unionTypeSchemas(schemas...)
+		}
+	}
+
+	var types TypeSet
+	switch t := obj["type"].(type) {
+	case string:
+		types = jsonSchemaType(t)
+	case []any:
+		for _, e := range t {
+			s, _ := e.(string)
+			types |= jsonSchemaType(s)
+		}
+	default:
+		return nil
+	}
+	if nullable, _ := obj["nullable"].(bool); nullable {
+		types |= TypeNull
+	}
+	if types == 0 || types == TypeAny {
+		return nil
+	}
+
+	s := &TypeSchema{Types: types}
+	if types&TypeObject != 0 {
+		required := map[string]struct{}{}
+		if r, ok := obj["required"].([]any); ok {
+			for _, e := range r {
+				if k, ok := e.(string); ok {
+					required[k] = struct{}{}
+				}
+			}
+		}
+		if props, ok := obj["properties"].(map[string]any); ok {
+			s.Properties = make(map[string]*TypeSchema, len(props))
+			for k, p := range props {
+				ps := jsonSchemaToTypeSchema(p)
+				if _, isRequired := required[k]; !isRequired {
+					ps = withNullType(ps)
+				}
+				s.Properties[k] = ps
+			}
+		}
+		if additional, ok := obj["additionalProperties"].(map[string]any); ok {
+			s.Values = withNullType(jsonSchemaToTypeSchema(additional))
+		}
+	}
+	if types&TypeArray != 0 {
+		s.Items = jsonSchemaToTypeSchema(obj["items"])
+	}
+	return s
+}
+
+func jsonSchemaType(t string) TypeSet {
+	switch t {
+	case "string":
+		return TypeString
+	case "number", "integer":
+		return TypeNumber
+	case "boolean":
+		return TypeBool
+	case "array":
+		return TypeArray
+	case "object":
+		return TypeObject
+	case "null":
+		return TypeNull
+	}
+	return TypeAny
+}
+
+//------------------------------------------------------------------------------
+
+// NewTypeSchemaFromAvro creates a type schema from an Avro schema document,
+// describing values as they're decoded from Avro into JSON. Unions of more
+// than one non-null type and logical types are treated as unknown since their
+// structure depends on how the values were decoded.
+func NewTypeSchemaFromAvro(schema []byte) (*TypeSchema, error) {
+	var root any
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse Avro schema: %w", err)
+	}
+	a := avroTypeSchemas{named: map[string]*TypeSchema{}}
+	s, err := a.convert(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Avro schema: %w", err)
+	}
+	return s, nil
+}
+
+type avroTypeSchemas struct {
+	named map[string]*TypeSchema
+}
+
+func (a avroTypeSchemas) convert(v any) (*TypeSchema, error) {
+	switch t := v.(type) {
+	case string:
+		return a.primitive(t), nil
+	case []any:
+		var nonNull []*TypeSchema
+		for _, e := range t {
+			s, err := a.convert(e)
+			if err != nil {
+				return nil, err
+			}
+			if s.types() != TypeNull {
+				nonNull = append(nonNull, s)
+			}
+		}
+		if len(nonNull) != 1 {
+			return nil, nil
+		}
+		if len(t) > 1 {
+			return withNullType(nonNull[0]), nil
+		}
+		return nonNull[0], nil
+	case map[string]any:
+	default:
+		return nil, fmt.Errorf("unexpected schema type: %T", v)
+	}
+
+	obj := v.(map[string]any)
+	if _, exists := obj["logicalType"]; exists {
+		return nil, nil
+	}
+
+	var s *TypeSchema
+	switch obj["type"] {
+	case "record", "error":
+		fields, _ := obj["fields"].([]any)
+		s = &TypeSchema{Types: TypeObject, Properties: make(map[string]*TypeSchema, len(fields))}
+		a.name(obj, s)
+		for _, f := range fields {
+			fObj, ok := f.(map[string]any)
+			if !ok {
+				return nil, errors.New("record fields must be objects")
+			}
+			name, _ := fObj["name"].(string)
+			fs, err := a.convert(fObj["type"])
+			if err != nil {
+				return nil, fmt.Errorf("field %v: %w", name, err)
+			}
+			s.Properties[name] = fs
+		}
+		return s, nil
+	case "enum":
+		s = &TypeSchema{Types: TypeString}
+	case "fixed":
+		s = &TypeSchema{Types: TypeBytes}
+	case "array":
+		items, err := a.convert(obj["items"])
+		if err != nil {
+			return nil, err
+		}
+		s = &TypeSchema{Types: TypeArray, Items: items}
+	case "map":
+		values, err := a.convert(obj["values"])
+		if err != nil {
+			return nil, err
+		}
+		s = &TypeSchema{Types: TypeObject, Values: withNullType(values)}
+	default:
+		return a.convert(obj["type"])
+	}
+	a.name(obj, s)
+	return s, nil
+}
+
+// name registers a named type so that it can be referenced by later parts of
+// the schema.
+func (a avroTypeSchemas) name(obj map[string]any, s *TypeSchema) {
+	name, _ := obj["name"].(string)
+	if name == "" {
+		return
+	}
+	a.named[name] = s
+	if ns, _ := obj["namespace"].(string); ns != "" {
+		a.named[ns+"."+name] = s
+	}
+}
+
+func (a avroTypeSchemas) primitive(t string) *TypeSchema {
+	switch t {
+	case "null":
+		return &TypeSchema{Types: TypeNull}
+	case "boolean":
+		return &TypeSchema{Types: TypeBool}
+	case "int", "long", "float", "double":
+		return &TypeSchema{Types: TypeNumber}
+	case "bytes":
+		return &TypeSchema{Types: TypeBytes}
+	case "string":
+		return &TypeSchema{Types: TypeString}
+	}
+	return a.named[t]
+}
@@ -0,0 +1,80 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeSetString(t *testing.T) {
+	assert.Equal(t, "string", TypeString.String())
+	assert.Equal(t, "string or bytes", (TypeString | TypeBytes).String())
+	assert.Equal(t, "string, bytes or timestamp", (TypeString | TypeBytes | TypeTimestamp).String())
+	assert.Equal(t, "unknown", TypeAny.String())
+}
+
+func TestTypeSchemaFromJSONSchema(t *testing.T) {
+	s, err := NewTypeSchemaFromJSONSchema([]byte(`{
+  "type": "object",
+  "properties": {
+    "name": { "type": "string" },
+    "age": { "type": ["integer", "null"] },
+    "status": { "enum": [ "on", "off" ] },
+    "tags": { "type": "array", "items": { "type": "string" } },
+    "attrs": { "type": "object", "additionalProperties": { "type": "number" } },
+    "either": { "anyOf": [ { "type": "string" }, { "type": "boolean" } ] },
+    "ref": { "$ref": "#/definitions/foo" }
+  },
+  "required": [ "name", "age", "status", "tags", "attrs", "either", "ref" ]
+}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, TypeObject, s.types())
+	assert.Equal(t, TypeString, s.field([]string{"name"}).types())
+	assert.Equal(t, TypeNumber|TypeNull, s.field([]string{"age"}).types())
+	assert.Equal(t, TypeString, s.field([]string{"status"}).types())
+	assert.Equal(t, TypeArray, s.field([]string{"tags"}).types())
+	assert.Equal(t, TypeString|TypeNull, s.field([]string{"tags", "0"}).types())
+	assert.Equal(t, TypeNumber|TypeNull, s.field([]string{"attrs", "foo"}).types())
+	assert.Equal(t, TypeString|TypeBool, s.field([]string{"either"}).types())
+	assert.Nil(t, s.field([]string{"ref"}))
+	assert.Nil(t, s.field([]string{"nope"}))
+	assert.Equal(t, TypeNull, s.field([]string{"name", "nope"}).types())
+
+	_, err = NewTypeSchemaFromJSONSchema([]byte(`{"type":`))
+	require.Error(t, err)
+}
+
+func TestTypeSchemaFromAvro(t *testing.T) {
+	s, err := NewTypeSchemaFromAvro([]byte(`{
+  "type": "record",
+  "name": "Person",
+  "namespace": "com.example",
+  "fields": [
+    { "name": "name", "type": "string" },
+    { "name": "age", "type": [ "null", "long" ] },
+    { "name": "data", "type": { "type": "fixed", "name": "Data", "size": 4 } },
+    { "name": "tags", "type": { "type": "array", "items": "string" } },
+    { "name": "attrs", "type": { "type": "map", "values": "double" } },
+    { "name": "mixed", "type": [ "string", "long" ] },
+    { "name": "born", "type": { "type": "long", "logicalType": "timestamp-millis" } },
+    { "name": "friend", "type": [ "null", "com.example.Person" ] }
+  ]
+}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, TypeObject, s.types())
+	assert.Equal(t, TypeString, s.field([]string{"name"}).types())
+	assert.Equal(t, TypeNumber|TypeNull, s.field([]string{"age"}).types())
+	assert.Equal(t, TypeBytes, s.field([]string{"data"}).types())
+	assert.Equal(t, TypeString|TypeNull, s.field([]string{"tags", "0"}).types())
+	assert.Equal(t, TypeNumber|TypeNull, s.field([]string{"attrs", "foo"}).types())
+	assert.Nil(t, s.field([]string{"mixed"}))
+	assert.Nil(t, s.field([]string{"born"}))
+	assert.Equal(t, TypeObject|TypeNull, s.field([]string{"friend"}).types())
+	assert.Equal(t, TypeString|TypeNull, s.field([]string{"friend", "name"}).types())
+
+	_, err = NewTypeSchemaFromAvro([]byte(`{"type":"record","fields":[5]}`))
+	require.Error(t, err)
+}
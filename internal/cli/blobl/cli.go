@@ -34,6 +34,8 @@ Provides a convenient tool for mapping JSON documents over the command line:
 
   echo '{"foo":"bar"}' | benthos blobl -f ./mapping.blobl
 
+  echo '{"foo":[1,2,3]}' | benthos blobl --trace 'foo.map_each(this * 2).sum()'
+
 Find out more about Bloblang at: https://benthos.dev/docs/guides/bloblang/about`[1:],
 		Flags: []cli.Flag{
 			&cli.IntFlag{
@@ -62,6 +64,10 @@ Find out more about Bloblang at: https://benthos.dev/docs/guides/bloblang/about`
 				Usage: "Set the buffer size for document lines.",
 				Value: bufio.MaxScanTokenSize,
 			},
+			&cli.BoolFlag{
+				Name:  "trace",
+				Usage: "print a JSON trace of each execution, containing the value of every query step and the state of the message after each assignment.",
+			},
 		},
 		Action: run,
 		Subcommands: []*cli.Command{
@@ -108,6 +114,17 @@ Find out more about Bloblang at: https://benthos.dev/docs/guides/bloblang/about`
 					},
 				},
 			},
+			{
+				Name:  "lsp",
+				Usage: "EXPERIMENTAL: Run a Bloblang language server over stdio",
+				Description: `
+Run a language server that provides diagnostics, completions, hover
+documentation and go-to-definition for Bloblang mappings to editors that
+support the language server protocol.
+
+Both .blobl files and the mapping fields of YAML config files are supported.`[1:],
+				Action: runLSP,
+			},
 		},
 	}
 }
@@ -125,6 +142,18 @@ func newExecCache() *execCache {
 }
 
 func (e *execCache) executeMapping(exec *mapping.Executor, rawInput, prettyOutput bool, input []byte) (string, error) {
+	return e.execute(exec, rawInput, prettyOutput, input, nil)
+}
+
+// traceMapping executes a mapping in the same way as executeMapping, and also
+// returns a trace of each assignment that was executed.
+func (e *execCache) traceMapping(exec *mapping.Executor, rawInput, prettyOutput bool, input []byte) (string, []mapping.AssignmentTrace, error) {
+	var traces []mapping.AssignmentTrace
+	res, err := e.execute(exec, rawInput, prettyOutput, input, &traces)
+	return res, traces, err
+}
+
+func (e *execCache) execute(exec *mapping.Executor, rawInput, prettyOutput bool, input []byte, traces *[]mapping.AssignmentTrace) (string, error) {
 	e.msg.Get(0).SetBytes(input)
 
 	var valuePtr *any
@@ -155,17 +184,25 @@ func (e *execCache) executeMapping(exec *mapping.Executor, rawInput, prettyOutpu
 	}
 
 	var result any = value.Nothing(nil)
-	err := exec.ExecOnto(query.FunctionContext{
+	ctx := query.FunctionContext{
 		Maps:     exec.Maps(),
 		Vars:     e.vars,
 		MsgBatch: e.msg,
 		NewMeta:  e.msg.Get(0),
 		NewValue: &result,
-	}.WithValueFunc(lazyValue), mapping.AssignmentContext{
+	}.WithValueFunc(lazyValue)
+	onto := mapping.AssignmentContext{
 		Vars:  e.vars,
 		Meta:  e.msg.Get(0),
 		Value: &result,
-	})
+	}
+
+	var err error
+	if traces != nil {
+		*traces, err = exec.TraceOnto(ctx, onto)
+	} else {
+		err = exec.ExecOnto(ctx, onto)
+	}
 	if err != nil {
 		var ctxErr query.ErrNoContext
 		if parseErr != nil && errors.As(err, &ctxErr) {
@@ -234,7 +271,12 @@ func run(c *cli.Context) error {
 		m = string(mappingBytes)
 	}
 
+	trace := c.Bool("trace")
+
 	bEnv := bloblang.NewEnvironment().WithImporterRelativeToFile(file)
+	if trace {
+		bEnv = bEnv.WithTracing()
+	}
 	exec, err := bEnv.NewMapping(m)
 	if err != nil {
 		if perr, ok := err.(*parser.Error); ok {
@@ -281,6 +323,11 @@ func run(c *cli.Context) error {
 					return
 				}
 
+				if trace {
+					resultsChan <- execCache.traceJSON(exec, raw, pretty, input)
+					continue
+				}
+
 				resultStr, err := execCache.executeMapping(exec, raw, pretty, input)
 				if err != nil {
 					fmt.Fprintln(os.Stderr, red(fmt.Sprintf("failed to execute map: %v", err)))
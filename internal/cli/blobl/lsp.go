@@ -0,0 +1,643 @@
+package blobl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang"
+	"github.com/benthosdev/benthos/v4/internal/bloblang/parser"
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+)
+
+// LSP enum values used by the server.
+const (
+	lspSyncFull = 1
+
+	lspSeverityError = 1
+
+	lspCompletionMethod   = 2
+	lspCompletionFunction = 3
+)
+
+type lspTextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Text       string `json:"text"`
+}
+
+type lspTextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type lspTextDocumentPositionParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+	Position     lspPosition               `json:"position"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspMarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type lspCompletionItem struct {
+	Label         string            `json:"label"`
+	Kind          int               `json:"kind"`
+	Detail        string            `json:"detail,omitempty"`
+	Documentation *lspMarkupContent `json:"documentation,omitempty"`
+}
+
+type lspHover struct {
+	Contents lspMarkupContent `json:"contents"`
+	Range    *lspRange        `json:"range,omitempty"`
+}
+
+// lspServer implements a language server for Bloblang over the base protocol
+// of the language server protocol. Requests are processed sequentially in the
+// order that they are received.
+type lspServer struct {
+	env  *bloblang.Environment
+	in   *bufio.Reader
+	out  *rpcWriter
+	docs map[string]*lspDocument
+
+	initialized bool
+	shutdown    bool
+}
+
+func newLSPServer(env *bloblang.Environment, in io.Reader, out io.Writer) *lspServer {
+	return &lspServer{
+		env:  env,
+		in:   bufio.NewReader(in),
+		out:  &rpcWriter{w: out},
+		docs: map[string]*lspDocument{},
+	}
+}
+
+var errLSPExit = errors.New("exit requested")
+
+// serve processes messages until the input is closed or an exit notification
+// is received. An error is returned if the client exited without first
+// requesting a shutdown.
+func (s *lspServer) serve() error {
+	for {
+		body, err := readRPCMessage(s.in)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.out.respond(nil, nil, &rpcError{Code: rpcErrParse, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		result, rErr := s.handle(&req)
+		if errors.Is(rErr, errLSPExit) {
+			if !s.shutdown {
+				return errors.New("exit received before shutdown")
+			}
+			return nil
+		}
+		if req.isNotification() {
+			continue
+		}
+
+		var resErr *rpcError
+		if rErr != nil {
+			if !errors.As(rErr, &resErr) {
+				resErr = &rpcError{Code: rpcErrInvalidRequest, Message: rErr.Error()}
+			}
+		}
+		if err := s.out.respond(req.ID, result, resErr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *lspServer) handle(req *rpcRequest) (any, error) {
+	switch req.Method {
+	case "initialize":
+		s.initialized = true
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": lspSyncFull,
+				"completionProvider": map[string]any{
+					"triggerCharacters": []string{"."},
+				},
+				"hoverProvider":      true,
+				"definitionProvider": true,
+			},
+			"serverInfo": map[string]any{
+				"name": "blobl",
+			},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "exit":
+		return nil, errLSPExit
+	}
+
+	if !s.initialized {
+		return nil, &rpcError{Code: rpcErrNotInitialized, Message: "server not initialized"}
+	}
+
+	switch req.Method {
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument lspTextDocumentItem `json:"textDocument"`
+		}
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		doc := newLSPDocument(params.TextDocument.URI, params.TextDocument.LanguageID, params.TextDocument.Text)
+		s.docs[doc.uri] = doc
+		return nil, s.publishDiagnostics(doc)
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument   lspTextDocumentIdentifier `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		doc, exists := s.docs[params.TextDocument.URI]
+		if !exists || len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		doc.setText(params.ContentChanges[len(params.ContentChanges)-1].Text)
+		return nil, s.publishDiagnostics(doc)
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+		}
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.out.notify("textDocument/publishDiagnostics", map[string]any{
+			"uri":         params.TextDocument.URI,
+			"diagnostics": []lspDiagnostic{},
+		})
+	case "textDocument/completion":
+		return s.positionRequest(req, s.completion)
+	case "textDocument/hover":
+		return s.positionRequest(req, s.hover)
+	case "textDocument/definition":
+		return s.positionRequest(req, s.definition)
+	}
+
+	if strings.HasPrefix(req.Method, "$/") || req.isNotification() {
+		return nil, nil
+	}
+	return nil, &rpcError{Code: rpcErrMethodNotFound, Message: fmt.Sprintf("method not found: %v", req.Method)}
+}
+
+func decodeParams(req *rpcRequest, v any) error {
+	if err := json.Unmarshal(req.Params, v); err != nil {
+		return &rpcError{Code: rpcErrInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// positionRequest decodes the parameters of a request that targets a position
+// within a document and calls fn with the region and index of that position. A
+// null result is returned when the position is not within a mapping.
+func (s *lspServer) positionRequest(req *rpcRequest, fn func(doc *lspDocument, r *mappingRegion, i int) any) (any, error) {
+	var params lspTextDocumentPositionParams
+	if err := decodeParams(req, &params); err != nil {
+		return nil, err
+	}
+	doc, exists := s.docs[params.TextDocument.URI]
+	if !exists {
+		return nil, nil
+	}
+	r, i := doc.regionAt(doc.offset(params.Position))
+	if r == nil {
+		return nil, nil
+	}
+	return fn(doc, r, i), nil
+}
+
+//------------------------------------------------------------------------------
+
+func (s *lspServer) diagnostics(doc *lspDocument) []lspDiagnostic {
+	env := s.env
+	if doc.path != "" {
+		env = env.WithImporterRelativeToFile(doc.path)
+	}
+
+	diags := []lspDiagnostic{}
+	for _, r := range doc.regions {
+		_, err := env.NewMapping(string(r.content))
+		if err == nil {
+			continue
+		}
+
+		diag := lspDiagnostic{
+			Severity: lspSeverityError,
+			Source:   "blobl",
+			Message:  err.Error(),
+		}
+
+		var perr *parser.Error
+		if errors.As(err, &perr) {
+			start := len(r.content) - len(perr.Input)
+			if start < 0 {
+				start = 0
+			}
+			start, end := diagnosticSpan(r.content, start)
+			diag.Range = doc.regionRange(r, start, end)
+			diag.Message = perr.ErrorMessage()
+		} else {
+			diag.Range = doc.regionRange(r, 0, 0)
+		}
+		diags = append(diags, diag)
+	}
+	return diags
+}
+
+func (s *lspServer) publishDiagnostics(doc *lspDocument) error {
+	return s.out.notify("textDocument/publishDiagnostics", map[string]any{
+		"uri":         doc.uri,
+		"diagnostics": s.diagnostics(doc),
+	})
+}
+
+// diagnosticSpan returns the span of content to highlight for a parser error at
+// a given index. Errors regarding a function or method call are reported by the
+// parser at the end of the call, in which case the whole call is highlighted.
+// Otherwise the token following the error is highlighted.
+func diagnosticSpan(content []rune, i int) (start, end int) {
+	if i > 0 && content[i-1] == ')' {
+		depth := 0
+		for j := i - 1; j >= 0; j-- {
+			switch content[j] {
+			case ')':
+				depth++
+			case '(':
+				depth--
+			}
+			if depth == 0 {
+				if nameStart, _ := identAt(content, j); nameStart < j {
+					return nameStart, i
+				}
+				break
+			}
+		}
+	}
+
+	end = i
+	for end < len(content) && isIdentRune(content[end]) {
+		end++
+	}
+	if end == i && end < len(content) && content[end] != '\n' {
+		end++
+	}
+	return i, end
+}
+
+//------------------------------------------------------------------------------
+
+func isIdentRune(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+// identAt returns the bounds of the identifier that surrounds an index of the
+// content, which might be empty.
+func identAt(content []rune, i int) (start, end int) {
+	start, end = i, i
+	for start > 0 && isIdentRune(content[start-1]) {
+		start--
+	}
+	for end < len(content) && isIdentRune(content[end]) {
+		end++
+	}
+	return
+}
+
+// inStringOrComment returns whether an index of the content lies within a
+// quoted string or a comment, based on the preceding content of the line.
+func inStringOrComment(content []rune, i int) bool {
+	lineStart := i
+	for lineStart > 0 && content[lineStart-1] != '\n' {
+		lineStart--
+	}
+	inQuote := false
+	for j := lineStart; j < i; j++ {
+		switch content[j] {
+		case '\\':
+			if inQuote {
+				j++
+			}
+		case '"':
+			inQuote = !inQuote
+		case '#':
+			if !inQuote {
+				return true
+			}
+		}
+	}
+	return inQuote
+}
+
+func paramsSignature(name string, params query.Params) string {
+	if params.Variadic {
+		return name + "(...)"
+	}
+	args := make([]string, 0, len(params.Definitions))
+	for _, def := range params.Definitions {
+		arg := fmt.Sprintf("%v: %v", def.Name, def.ValueType)
+		if def.DefaultValue != nil {
+			defBytes, _ := json.Marshal(*def.DefaultValue)
+			arg += " = " + string(defBytes)
+		} else if def.IsOptional {
+			arg += "?"
+		}
+		args = append(args, arg)
+	}
+	return name + "(" + strings.Join(args, ", ") + ")"
+}
+
+func paramsDocs(signature, description string, params query.Params) string {
+	var buf strings.Builder
+	buf.WriteString("```coffee\n")
+	buf.WriteString(signature)
+	buf.WriteString("\n```\n")
+	if description = strings.TrimSpace(description); description != "" {
+		buf.WriteString("\n")
+		buf.WriteString(description)
+		buf.WriteString("\n")
+	}
+	if len(params.Definitions) > 0 {
+		buf.WriteString("\n**Parameters**\n\n")
+		for _, def := range params.Definitions {
+			fmt.Fprintf(&buf, "- `%v` <%v> %v\n", def.Name, def.ValueType, strings.TrimSpace(def.Description))
+		}
+	}
+	return buf.String()
+}
+
+func functionDocs(spec query.FunctionSpec) string {
+	return paramsDocs(paramsSignature(spec.Name, spec.Params), spec.Description, spec.Params)
+}
+
+func methodDocs(spec query.MethodSpec) string {
+	description := spec.Description
+	for _, cat := range spec.Categories {
+		if description != "" {
+			break
+		}
+		description = cat.Description
+	}
+	return paramsDocs("."+paramsSignature(spec.Name, spec.Params), description, spec.Params)
+}
+
+func (s *lspServer) completion(doc *lspDocument, r *mappingRegion, i int) any {
+	items := []lspCompletionItem{}
+	if inStringOrComment(r.content, i) {
+		return items
+	}
+
+	start, _ := identAt(r.content, i)
+	prefix := string(r.content[start:i])
+
+	if start > 0 && r.content[start-1] == '.' {
+		s.env.WalkMethods(func(name string, spec query.MethodSpec) {
+			if spec.Status == query.StatusHidden || spec.Status == query.StatusDeprecated || !strings.HasPrefix(name, prefix) {
+				return
+			}
+			items = append(items, lspCompletionItem{
+				Label:         name,
+				Kind:          lspCompletionMethod,
+				Detail:        paramsSignature(name, spec.Params),
+				Documentation: &lspMarkupContent{Kind: "markdown", Value: methodDocs(spec)},
+			})
+		})
+	} else {
+		s.env.WalkFunctions(func(name string, spec query.FunctionSpec) {
+			if spec.Status == query.StatusHidden || spec.Status == query.StatusDeprecated || !strings.HasPrefix(name, prefix) {
+				return
+			}
+			items = append(items, lspCompletionItem{
+				Label:         name,
+				Kind:          lspCompletionFunction,
+				Detail:        paramsSignature(name, spec.Params),
+				Documentation: &lspMarkupContent{Kind: "markdown", Value: functionDocs(spec)},
+			})
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	return items
+}
+
+func (s *lspServer) hover(doc *lspDocument, r *mappingRegion, i int) any {
+	if inStringOrComment(r.content, i) {
+		return nil
+	}
+
+	start, end := identAt(r.content, i)
+	if start == end {
+		return nil
+	}
+	name := string(r.content[start:end])
+
+	var docs string
+	if start > 0 && r.content[start-1] == '.' {
+		s.env.WalkMethods(func(n string, spec query.MethodSpec) {
+			if n == name {
+				docs = methodDocs(spec)
+			}
+		})
+	} else {
+		next := end
+		for next < len(r.content) && r.content[next] == ' ' {
+			next++
+		}
+		if next >= len(r.content) || r.content[next] != '(' {
+			return nil
+		}
+		s.env.WalkFunctions(func(n string, spec query.FunctionSpec) {
+			if n == name {
+				docs = functionDocs(spec)
+			}
+		})
+	}
+	if docs == "" {
+		return nil
+	}
+
+	rng := doc.regionRange(r, start, end)
+	return lspHover{
+		Contents: lspMarkupContent{Kind: "markdown", Value: docs},
+		Range:    &rng,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var (
+	importPathRegexp = regexp.MustCompile(`(?m)^\s*import\s+"((?:[^"\\]|\\.)*)"(?:[ \t]+as[ \t]+([a-zA-Z0-9_]+))?`)
+	applyCallRegexp  = regexp.MustCompile(`\.apply\(\s*$`)
+	importCallRegexp = regexp.MustCompile(`(^|[\s])(import|from)\s+$`)
+)
+
+func mapDefinitionRegexp(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(?m)^[ \t]*map[ \t]+` + regexp.QuoteMeta(name) + `(\([^)]*\))?[ \t]*\{`)
+}
+
+// stringAt returns the bounds of the contents of a quoted string on the same
+// line that surrounds an index of the content.
+func stringAt(content []rune, i int) (start, end int, ok bool) {
+	lineStart := i
+	for lineStart > 0 && content[lineStart-1] != '\n' {
+		lineStart--
+	}
+	start = -1
+	for j := lineStart; j < len(content) && content[j] != '\n'; j++ {
+		switch content[j] {
+		case '\\':
+			j++
+		case '"':
+			if start == -1 {
+				start = j + 1
+				continue
+			}
+			if i >= start && i <= j {
+				return start, j, true
+			}
+			start = -1
+		}
+		if start == -1 && j >= i {
+			break
+		}
+	}
+	return 0, 0, false
+}
+
+func (s *lspServer) resolveImportPath(doc *lspDocument, path string) string {
+	if filepath.IsAbs(path) || doc.path == "" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(doc.path), path)
+}
+
+func (s *lspServer) definition(doc *lspDocument, r *mappingRegion, i int) any {
+	if start, end, ok := stringAt(r.content, i); ok {
+		value := string(r.content[start:end])
+		preceding := string(r.content[:start-1])
+
+		if importCallRegexp.MatchString(preceding) {
+			path := s.resolveImportPath(doc, value)
+			if _, err := ifs.OS().Stat(path); err != nil {
+				return nil
+			}
+			return []lspLocation{{URI: pathToURI(path)}}
+		}
+
+		if !applyCallRegexp.MatchString(preceding) {
+			return nil
+		}
+		return s.mapDefinition(doc, r, value)
+	}
+
+	if inStringOrComment(r.content, i) {
+		return nil
+	}
+
+	// Otherwise the identifier might be a call of a map with parameters, which
+	// is either plain or prefixed with the namespace of an import.
+	start, end := identAt(r.content, i)
+	if start == end || end >= len(r.content) || r.content[end] != '(' {
+		return nil
+	}
+	name := string(r.content[start:end])
+	if start > 0 && r.content[start-1] == '.' {
+		nsStart, _ := identAt(r.content, start-1)
+		if nsStart == start-1 || (nsStart > 0 && r.content[nsStart-1] == '.') {
+			// A method call.
+			return nil
+		}
+		name = string(r.content[nsStart:start-1]) + "." + name
+	}
+	return s.mapDefinition(doc, r, name)
+}
+
+// mapDefinition returns the location of the definition of a map referenced
+// from a mapping region, where a name prefixed with a namespace refers to a
+// map of the import with that namespace.
+func (s *lspServer) mapDefinition(doc *lspDocument, r *mappingRegion, name string) any {
+	var namespace string
+	if i := strings.Index(name, "."); i >= 0 {
+		namespace, name = name[:i], name[i+1:]
+	}
+
+	defRegexp := mapDefinitionRegexp(name)
+	if namespace == "" {
+		for _, region := range doc.regions {
+			if loc := defRegexp.FindStringIndex(string(region.content)); loc != nil {
+				idx := len([]rune(string(region.content)[:loc[0]]))
+				for region.content[idx] == ' ' || region.content[idx] == '\t' {
+					idx++
+				}
+				return []lspLocation{{URI: doc.uri, Range: doc.regionRange(region, idx, idx)}}
+			}
+		}
+	}
+
+	for _, match := range importPathRegexp.FindAllStringSubmatch(string(r.content), -1) {
+		if match[2] != namespace {
+			continue
+		}
+		path := s.resolveImportPath(doc, match[1])
+		importBytes, err := ifs.ReadFile(ifs.OS(), path)
+		if err != nil {
+			continue
+		}
+		importDoc := newLSPDocument(pathToURI(path), "blobl", string(importBytes))
+		if loc := defRegexp.FindStringIndex(string(importBytes)); loc != nil {
+			idx := len([]rune(string(importBytes)[:loc[0]]))
+			for importDoc.text[idx] == ' ' || importDoc.text[idx] == '\t' {
+				idx++
+			}
+			pos := importDoc.position(idx)
+			return []lspLocation{{URI: importDoc.uri, Range: lspRange{Start: pos, End: pos}}}
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func runLSP(c *cli.Context) error {
+	env := bloblang.GlobalEnvironment().Deactivated()
+	return newLSPServer(env, os.Stdin, os.Stdout).serve()
+}
@@ -0,0 +1,302 @@
+package blobl
+
+import (
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlMappingFields are the names of config fields that contain Bloblang
+// mappings, the values of these fields are treated as mappings when editing a
+// YAML config.
+var yamlMappingFields = map[string]struct{}{
+	"bloblang":       {},
+	"check":          {},
+	"fields_mapping": {},
+	"mapping":        {},
+	"mutation":       {},
+	"request_map":    {},
+	"result_map":     {},
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+// mappingRegion is a Bloblang mapping that lives within a document, which is
+// either the entire document or the value of a field within a YAML config.
+type mappingRegion struct {
+	content []rune
+
+	// offsets contains the rune offset within the document of each rune of the
+	// content, with a final entry for the end of the content.
+	offsets []int
+}
+
+// indexOf returns the index within the region content of a rune offset within
+// the document, or -1 if the offset does not belong to the region.
+func (m *mappingRegion) indexOf(offset int) int {
+	i := sort.SearchInts(m.offsets, offset)
+	if i < len(m.offsets) && m.offsets[i] == offset {
+		return i
+	}
+	return -1
+}
+
+type lspDocument struct {
+	uri        string
+	path       string
+	languageID string
+	text       []rune
+
+	// lineStarts contains the rune offset of the beginning of each line.
+	lineStarts []int
+	regions    []*mappingRegion
+}
+
+func newLSPDocument(uri, languageID, text string) *lspDocument {
+	d := &lspDocument{
+		uri:        uri,
+		path:       uriToPath(uri),
+		languageID: languageID,
+	}
+	d.setText(text)
+	return d
+}
+
+func (d *lspDocument) setText(text string) {
+	d.text = []rune(text)
+	d.lineStarts = []int{0}
+	for i, r := range d.text {
+		if r == '\n' {
+			d.lineStarts = append(d.lineStarts, i+1)
+		}
+	}
+
+	if d.isYAML() {
+		d.regions = d.yamlRegions()
+		return
+	}
+
+	offsets := make([]int, len(d.text)+1)
+	for i := range offsets {
+		offsets[i] = i
+	}
+	d.regions = []*mappingRegion{{content: d.text, offsets: offsets}}
+}
+
+func (d *lspDocument) isYAML() bool {
+	if d.languageID == "yaml" {
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(d.path)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// regionAt returns the mapping region containing a rune offset of the
+// document along with the index of the offset within the region content.
+func (d *lspDocument) regionAt(offset int) (*mappingRegion, int) {
+	for _, r := range d.regions {
+		if i := r.indexOf(offset); i >= 0 {
+			return r, i
+		}
+	}
+	return nil, -1
+}
+
+// position converts a rune offset of the document into an LSP position, where
+// characters are counted in UTF-16 code units.
+func (d *lspDocument) position(offset int) lspPosition {
+	if offset > len(d.text) {
+		offset = len(d.text)
+	}
+	line := sort.Search(len(d.lineStarts), func(i int) bool {
+		return d.lineStarts[i] > offset
+	}) - 1
+
+	var char int
+	for _, r := range d.text[d.lineStarts[line]:offset] {
+		char += utf16.RuneLen(r)
+	}
+	return lspPosition{Line: line, Character: char}
+}
+
+// offset converts an LSP position into a rune offset of the document.
+func (d *lspDocument) offset(pos lspPosition) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lineStarts) {
+		return len(d.text)
+	}
+	offset := d.lineStarts[pos.Line]
+	for char := 0; offset < len(d.text) && d.text[offset] != '\n'; offset++ {
+		if char >= pos.Character {
+			break
+		}
+		char += utf16.RuneLen(d.text[offset])
+	}
+	return offset
+}
+
+// regionRange returns the document range of a span of a region content.
+func (d *lspDocument) regionRange(r *mappingRegion, start, end int) lspRange {
+	return lspRange{
+		Start: d.position(r.offsets[start]),
+		End:   d.position(r.offsets[end]),
+	}
+}
+
+func (d *lspDocument) yamlRegions() []*mappingRegion {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(string(d.text)), &root); err != nil {
+		return nil
+	}
+
+	var regions []*mappingRegion
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k, v := n.Content[i], n.Content[i+1]
+				if _, exists := yamlMappingFields[k.Value]; exists && v.Kind == yaml.ScalarNode {
+					if r := d.yamlScalarRegion(k, v); r != nil {
+						regions = append(regions, r)
+					}
+				}
+			}
+		}
+		for _, c := range n.Content {
+			walk(c)
+		}
+	}
+	walk(&root)
+	return regions
+}
+
+// yamlScalarRegion extracts the region of a YAML scalar value from the raw
+// document text. Only values where the raw text is an exact representation of
+// the value (without escapes or folding) are supported.
+func (d *lspDocument) yamlScalarRegion(key, v *yaml.Node) *mappingRegion {
+	if v.Line < 1 || v.Line > len(d.lineStarts) {
+		return nil
+	}
+
+	if v.Style&yaml.LiteralStyle != 0 {
+		return d.yamlBlockRegion(key.Column-1, v.Line)
+	}
+	if v.Style&yaml.FoldedStyle != 0 {
+		return nil
+	}
+
+	start := d.lineStarts[v.Line-1] + v.Column - 1
+	if v.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		start++
+	}
+
+	content := []rune(v.Value)
+	if start+len(content) > len(d.text) || string(d.text[start:start+len(content)]) != v.Value {
+		return nil
+	}
+
+	offsets := make([]int, len(content)+1)
+	for i := range offsets {
+		offsets[i] = start + i
+	}
+	return &mappingRegion{content: content, offsets: offsets}
+}
+
+// yamlBlockRegion extracts the region of a literal block scalar, which begins
+// on the line following the block indicator and continues for as long as the
+// lines are indented further than the key.
+func (d *lspDocument) yamlBlockRegion(keyIndent, indicatorLine int) *mappingRegion {
+	r := &mappingRegion{}
+
+	indent := -1
+	for line := indicatorLine; line < len(d.lineStarts); line++ {
+		lineStart := d.lineStarts[line]
+		lineEnd := len(d.text)
+		if line+1 < len(d.lineStarts) {
+			lineEnd = d.lineStarts[line+1] - 1
+		}
+		lineText := d.text[lineStart:lineEnd]
+
+		lineIndent := 0
+		for lineIndent < len(lineText) && lineText[lineIndent] == ' ' {
+			lineIndent++
+		}
+		blank := lineIndent == len(lineText)
+		if blank && indent == -1 {
+			continue
+		}
+		if !blank {
+			if lineIndent <= keyIndent {
+				break
+			}
+			if indent == -1 {
+				indent = lineIndent
+			}
+			if lineIndent < indent {
+				break
+			}
+		}
+
+		if len(r.offsets) > 0 {
+			r.content = append(r.content, '\n')
+			r.offsets = append(r.offsets, lineStart-1)
+		}
+		for i := indent; i < len(lineText); i++ {
+			r.content = append(r.content, lineText[i])
+			r.offsets = append(r.offsets, lineStart+i)
+		}
+	}
+	if indent == -1 {
+		return nil
+	}
+
+	// Trailing blank lines are not part of the mapping.
+	for len(r.content) > 0 && r.content[len(r.content)-1] == '\n' {
+		r.content = r.content[:len(r.content)-1]
+		r.offsets = r.offsets[:len(r.offsets)-1]
+	}
+
+	end := len(d.text)
+	if len(r.offsets) > 0 {
+		end = r.offsets[len(r.offsets)-1] + 1
+	}
+	r.offsets = append(r.offsets, end)
+	return r
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func pathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,134 @@
+package blobl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSON-RPC error codes used by the language server protocol.
+const (
+	rpcErrParse          = -32700
+	rpcErrInvalidRequest = -32600
+	rpcErrMethodNotFound = -32601
+	rpcErrInvalidParams  = -32602
+	rpcErrNotInitialized = -32002
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// isNotification returns true when the request has no ID and therefore must
+// not be responded to.
+func (r *rpcRequest) isNotification() bool {
+	return len(r.ID) == 0
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// readRPCMessage reads a single message body framed with a Content-Length
+// header as described by the language server protocol base protocol.
+func readRPCMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	lengthStr := strings.TrimSpace(header.Get("Content-Length"))
+	if lengthStr == "" {
+		return nil, errors.New("missing Content-Length header")
+	}
+	length, err := strconv.Atoi(lengthStr)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header: %v", lengthStr)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	return body, nil
+}
+
+// rpcWriter writes framed messages to an underlying writer, it is safe to use
+// from multiple goroutines.
+type rpcWriter struct {
+	mut sync.Mutex
+	w   io.Writer
+}
+
+func (w *rpcWriter) write(v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	if _, err := fmt.Fprintf(w.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = w.w.Write(body)
+	return err
+}
+
+func (w *rpcWriter) respond(id json.RawMessage, result any, rErr *rpcError) error {
+	res := rpcResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   rErr,
+	}
+	if len(res.ID) == 0 {
+		res.ID = json.RawMessage("null")
+	}
+	if rErr == nil {
+		resBytes, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		res.Result = resBytes
+	}
+	return w.write(res)
+}
+
+func (w *rpcWriter) notify(method string, params any) error {
+	return w.write(rpcNotification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
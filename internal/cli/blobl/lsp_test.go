@@ -0,0 +1,397 @@
+package blobl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang"
+)
+
+func lspFrame(t testing.TB, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(b), b)
+}
+
+func lspRequest(t testing.TB, id int, method string, params any) string {
+	return lspFrame(t, map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+}
+
+func lspNotification(t testing.TB, method string, params any) string {
+	return lspFrame(t, map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// runLSPSession runs a server against a sequence of framed messages and
+// returns all messages written by the server.
+func runLSPSession(t testing.TB, msgs ...string) []map[string]any {
+	t.Helper()
+
+	var in, out bytes.Buffer
+	for _, m := range msgs {
+		in.WriteString(m)
+	}
+	require.NoError(t, newLSPServer(bloblang.GlobalEnvironment().Deactivated(), &in, &out).serve())
+
+	var res []map[string]any
+	r := bufio.NewReader(&out)
+	for {
+		body, err := readRPCMessage(r)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		var m map[string]any
+		require.NoError(t, json.Unmarshal(body, &m))
+		res = append(res, m)
+	}
+	return res
+}
+
+func lspResult(t testing.TB, msgs []map[string]any, id int) any {
+	t.Helper()
+	for _, m := range msgs {
+		if m["id"] == float64(id) {
+			return m["result"]
+		}
+	}
+	t.Fatalf("no response for request %v", id)
+	return nil
+}
+
+func lspDiagnostics(t testing.TB, msgs []map[string]any) []any {
+	t.Helper()
+	var diags []any
+	for _, m := range msgs {
+		if m["method"] == "textDocument/publishDiagnostics" {
+			diags = m["params"].(map[string]any)["diagnostics"].([]any)
+		}
+	}
+	return diags
+}
+
+func lspOpen(t testing.TB, uri, languageID, text string) string {
+	return lspNotification(t, "textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": languageID, "version": 1, "text": text},
+	})
+}
+
+func lspAt(t testing.TB, id int, method, uri string, line, char int) string {
+	return lspRequest(t, id, method, map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line, "character": char},
+	})
+}
+
+func TestLSPLifecycle(t *testing.T) {
+	msgs := runLSPSession(t,
+		lspRequest(t, 1, "initialize", map[string]any{}),
+		lspNotification(t, "initialized", map[string]any{}),
+		lspRequest(t, 2, "textDocument/foo", map[string]any{}),
+		lspRequest(t, 3, "shutdown", nil),
+		lspNotification(t, "exit", nil),
+	)
+	require.Len(t, msgs, 3)
+
+	caps := lspResult(t, msgs, 1).(map[string]any)["capabilities"].(map[string]any)
+	assert.Equal(t, float64(lspSyncFull), caps["textDocumentSync"])
+	assert.Equal(t, true, caps["hoverProvider"])
+	assert.Equal(t, true, caps["definitionProvider"])
+
+	assert.Equal(t, float64(rpcErrMethodNotFound), msgs[1]["error"].(map[string]any)["code"])
+
+	assert.Contains(t, msgs[2], "result")
+	assert.Nil(t, msgs[2]["result"])
+}
+
+func TestLSPExitWithoutShutdown(t *testing.T) {
+	var out bytes.Buffer
+	in := bytes.NewBufferString(lspNotification(t, "exit", nil))
+	require.Error(t, newLSPServer(bloblang.GlobalEnvironment(), in, &out).serve())
+}
+
+func TestLSPDiagnostics(t *testing.T) {
+	tests := []struct {
+		name       string
+		uri        string
+		languageID string
+		text       string
+		start      [2]int
+		end        [2]int
+		message    string
+	}{
+		{
+			name:       "blobl file",
+			uri:        "file:///tmp/foo.blobl",
+			languageID: "blobl",
+			text:       "root.a = this.a\nroot.b = this.b.uppercase(\n",
+			start:      [2]int{2, 0},
+			end:        [2]int{2, 0},
+			message:    "required: expected function argument",
+		},
+		{
+			name:       "unknown function",
+			uri:        "file:///tmp/foo.blobl",
+			languageID: "blobl",
+			text:       "root.a = this.a\n# 🎉 comment\nroot.b = \"🎉\" + nope()\n",
+			start:      [2]int{2, 16},
+			end:        [2]int{2, 22},
+			message:    "unrecognised function 'nope'",
+		},
+		{
+			name:       "yaml literal block",
+			uri:        "file:///tmp/foo.yaml",
+			languageID: "yaml",
+			text: `pipeline:
+  processors:
+    - mapping: |
+        root.a = this.a
+
+        root.b = this.b.nah()
+    - mutation: 'root.c = this.c'
+`,
+			start:   [2]int{5, 24},
+			end:     [2]int{5, 29},
+			message: "unrecognised method 'nah'",
+		},
+		{
+			name:       "yaml single line",
+			uri:        "file:///tmp/foo.yaml",
+			languageID: "yaml",
+			text: `pipeline:
+  processors:
+    - mapping: 'root = this'
+    - switch:
+        - check: this.foo ==
+`,
+			start: [2]int{4, 28},
+			end:   [2]int{4, 28},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			msgs := runLSPSession(t,
+				lspRequest(t, 1, "initialize", map[string]any{}),
+				lspOpen(t, test.uri, test.languageID, test.text),
+				lspRequest(t, 2, "shutdown", nil),
+				lspNotification(t, "exit", nil),
+			)
+
+			diags := lspDiagnostics(t, msgs)
+			require.Len(t, diags, 1)
+
+			diag := diags[0].(map[string]any)
+			rng := diag["range"].(map[string]any)
+			start, end := rng["start"].(map[string]any), rng["end"].(map[string]any)
+			assert.Equal(t, [2]int{test.start[0], test.start[1]}, [2]int{int(start["line"].(float64)), int(start["character"].(float64))})
+			assert.Equal(t, [2]int{test.end[0], test.end[1]}, [2]int{int(end["line"].(float64)), int(end["character"].(float64))})
+			if test.message != "" {
+				assert.Contains(t, diag["message"], test.message)
+			}
+		})
+	}
+}
+
+func TestLSPDiagnosticsCleared(t *testing.T) {
+	uri := "file:///tmp/foo.blobl"
+	msgs := runLSPSession(t,
+		lspRequest(t, 1, "initialize", map[string]any{}),
+		lspOpen(t, uri, "blobl", "root = nope()"),
+		lspNotification(t, "textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": 2},
+			"contentChanges": []any{map[string]any{"text": "root = now()"}},
+		}),
+		lspRequest(t, 2, "shutdown", nil),
+		lspNotification(t, "exit", nil),
+	)
+	assert.Empty(t, lspDiagnostics(t, msgs))
+}
+
+func TestLSPCompletion(t *testing.T) {
+	uri := "file:///tmp/foo.blobl"
+	msgs := runLSPSession(t,
+		lspRequest(t, 1, "initialize", map[string]any{}),
+		lspOpen(t, uri, "blobl", "root.a = this.a.uppe\nroot.b = uuid_\nroot.c = \"uuid_\""),
+		lspAt(t, 2, "textDocument/completion", uri, 0, 20),
+		lspAt(t, 3, "textDocument/completion", uri, 1, 14),
+		lspAt(t, 4, "textDocument/completion", uri, 2, 15),
+		lspRequest(t, 5, "shutdown", nil),
+		lspNotification(t, "exit", nil),
+	)
+
+	labels := func(id int) (l []string) {
+		for _, item := range lspResult(t, msgs, id).([]any) {
+			l = append(l, item.(map[string]any)["label"].(string))
+		}
+		return
+	}
+
+	assert.Equal(t, []string{"uppercase"}, labels(2))
+	assert.Equal(t, []string{"uuid_v4"}, labels(3))
+	assert.Empty(t, labels(4))
+
+	item := lspResult(t, msgs, 2).([]any)[0].(map[string]any)
+	assert.Equal(t, float64(lspCompletionMethod), item["kind"])
+	assert.Equal(t, "uppercase()", item["detail"])
+	assert.Contains(t, item["documentation"].(map[string]any)["value"], "Convert a string value into uppercase.")
+}
+
+func TestLSPHover(t *testing.T) {
+	uri := "file:///tmp/foo.blobl"
+	msgs := runLSPSession(t,
+		lspRequest(t, 1, "initialize", map[string]any{}),
+		lspOpen(t, uri, "blobl", `root.a = this.a.replace_all("a", "b")
+root.b = now()
+root.c = this.now`),
+		lspAt(t, 2, "textDocument/hover", uri, 0, 18),
+		lspAt(t, 3, "textDocument/hover", uri, 1, 9),
+		lspAt(t, 4, "textDocument/hover", uri, 2, 15),
+		lspRequest(t, 5, "shutdown", nil),
+		lspNotification(t, "exit", nil),
+	)
+
+	hover := lspResult(t, msgs, 2).(map[string]any)
+	contents := hover["contents"].(map[string]any)["value"].(string)
+	assert.Contains(t, contents, ".replace_all(old: string, new: string)")
+	assert.Contains(t, contents, "- `old` <string>")
+	assert.Equal(t, map[string]any{
+		"start": map[string]any{"line": float64(0), "character": float64(16)},
+		"end":   map[string]any{"line": float64(0), "character": float64(27)},
+	}, hover["range"])
+
+	hover = lspResult(t, msgs, 3).(map[string]any)
+	assert.Contains(t, hover["contents"].(map[string]any)["value"], "now()")
+
+	assert.Nil(t, lspResult(t, msgs, 4))
+}
+
+func TestLSPDefinition(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "maps.blobl"), []byte(`# Some maps
+  map from_import {
+  root = this
+}
+`), 0o644))
+
+	mainPath := filepath.Join(dir, "main.blobl")
+	uri := pathToURI(mainPath)
+	text := `import "./maps.blobl"
+
+map local {
+  root = this
+}
+
+root.a = this.apply("local")
+root.b = this.apply("from_import")
+`
+
+	msgs := runLSPSession(t,
+		lspRequest(t, 1, "initialize", map[string]any{}),
+		lspOpen(t, uri, "blobl", text),
+		lspAt(t, 2, "textDocument/definition", uri, 0, 12),
+		lspAt(t, 3, "textDocument/definition", uri, 6, 23),
+		lspAt(t, 4, "textDocument/definition", uri, 7, 25),
+		lspAt(t, 5, "textDocument/definition", uri, 6, 3),
+		lspRequest(t, 6, "shutdown", nil),
+		lspNotification(t, "exit", nil),
+	)
+
+	assert.Empty(t, lspDiagnostics(t, msgs))
+
+	location := func(id int) (string, [2]int) {
+		locs := lspResult(t, msgs, id).([]any)
+		require.Len(t, locs, 1)
+		loc := locs[0].(map[string]any)
+		start := loc["range"].(map[string]any)["start"].(map[string]any)
+		return loc["uri"].(string), [2]int{int(start["line"].(float64)), int(start["character"].(float64))}
+	}
+
+	locURI, pos := location(2)
+	assert.Equal(t, pathToURI(filepath.Join(dir, "maps.blobl")), locURI)
+	assert.Equal(t, [2]int{0, 0}, pos)
+
+	locURI, pos = location(3)
+	assert.Equal(t, uri, locURI)
+	assert.Equal(t, [2]int{2, 0}, pos)
+
+	locURI, pos = location(4)
+	assert.Equal(t, pathToURI(filepath.Join(dir, "maps.blobl")), locURI)
+	assert.Equal(t, [2]int{1, 2}, pos)
+
+	assert.Nil(t, lspResult(t, msgs, 5))
+}
+
+func TestLSPDefinitionMapCalls(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib.blobl"), []byte(`map greet(name) {
+  root = "hello " + name
+}
+`), 0o644))
+
+	mainPath := filepath.Join(dir, "main.blobl")
+	uri := pathToURI(mainPath)
+	text := `import "./lib.blobl" as lib
+
+map double(n) {
+  root = n * 2
+}
+
+root.a = double(this.a)
+root.b = lib.greet(this.b)
+root.c = this.c.apply("lib.greet")
+root.d = this.d.double()
+root.e = now()
+`
+
+	msgs := runLSPSession(t,
+		lspRequest(t, 1, "initialize", map[string]any{}),
+		lspOpen(t, uri, "blobl", text),
+		lspAt(t, 2, "textDocument/definition", uri, 6, 11),
+		lspAt(t, 3, "textDocument/definition", uri, 7, 15),
+		lspAt(t, 4, "textDocument/definition", uri, 8, 26),
+		lspAt(t, 5, "textDocument/definition", uri, 9, 17),
+		lspAt(t, 6, "textDocument/definition", uri, 10, 10),
+		lspAt(t, 7, "textDocument/definition", uri, 7, 10),
+		lspRequest(t, 8, "shutdown", nil),
+		lspNotification(t, "exit", nil),
+	)
+
+	location := func(id int) (string, [2]int) {
+		locs := lspResult(t, msgs, id).([]any)
+		require.Len(t, locs, 1)
+		loc := locs[0].(map[string]any)
+		start := loc["range"].(map[string]any)["start"].(map[string]any)
+		return loc["uri"].(string), [2]int{int(start["line"].(float64)), int(start["character"].(float64))}
+	}
+
+	locURI, pos := location(2)
+	assert.Equal(t, uri, locURI)
+	assert.Equal(t, [2]int{2, 0}, pos)
+
+	locURI, pos = location(3)
+	assert.Equal(t, pathToURI(filepath.Join(dir, "lib.blobl")), locURI)
+	assert.Equal(t, [2]int{0, 0}, pos)
+
+	locURI, pos = location(4)
+	assert.Equal(t, pathToURI(filepath.Join(dir, "lib.blobl")), locURI)
+	assert.Equal(t, [2]int{0, 0}, pos)
+
+	// Method calls, function calls and namespaces are not map calls.
+	assert.Nil(t, lspResult(t, msgs, 5))
+	assert.Nil(t, lspResult(t, msgs, 6))
+	assert.Nil(t, lspResult(t, msgs, 7))
+}
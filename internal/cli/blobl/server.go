@@ -160,10 +160,13 @@ func runServer(c *cli.Context) error {
 		fSync.update(req.Input, req.Mapping)
 
 		res := struct {
-			ParseError   string `json:"parse_error"`
-			MappingError string `json:"mapping_error"`
-			Result       string `json:"result"`
-		}{}
+			ParseError   string            `json:"parse_error"`
+			MappingError string            `json:"mapping_error"`
+			Result       string            `json:"result"`
+			Trace        []traceAssignment `json:"trace"`
+		}{
+			Trace: []traceAssignment{},
+		}
 		defer func() {
 			resBytes, err := json.Marshal(res)
 			if err != nil {
@@ -173,7 +176,7 @@ func runServer(c *cli.Context) error {
 			_, _ = w.Write(resBytes)
 		}()
 
-		exec, err := bloblang.GlobalEnvironment().NewMapping(req.Mapping)
+		exec, err := bloblang.GlobalEnvironment().WithTracing().NewMapping(req.Mapping)
 		if err != nil {
 			if perr, ok := err.(*parser.Error); ok {
 				res.ParseError = fmt.Sprintf("failed to parse mapping: %v\n", perr.ErrorAtPositionStructured("", []rune(req.Mapping)))
@@ -184,7 +187,8 @@ func runServer(c *cli.Context) error {
 		}
 
 		execCache := newExecCache()
-		output, err := execCache.executeMapping(exec, false, true, []byte(req.Input))
+		output, traces, err := execCache.traceMapping(exec, false, true, []byte(req.Input))
+		res.Trace = newTraceJSON(traces)
 		if err != nil {
 			res.MappingError = err.Error()
 		} else {
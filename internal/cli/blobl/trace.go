@@ -0,0 +1,136 @@
+package blobl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
+	"github.com/benthosdev/benthos/v4/internal/value"
+)
+
+type traceStep struct {
+	Line       int    `json:"line"`
+	Column     int    `json:"column"`
+	Expression string `json:"expression"`
+	Value      any    `json:"value"`
+	Error      string `json:"error,omitempty"`
+}
+
+type traceAssignment struct {
+	Line      int            `json:"line"`
+	Target    string         `json:"target"`
+	Steps     []traceStep    `json:"steps"`
+	Result    any            `json:"result"`
+	Skipped   bool           `json:"skipped,omitempty"`
+	Deleted   bool           `json:"deleted,omitempty"`
+	Root      any            `json:"root"`
+	Meta      map[string]any `json:"meta"`
+	Variables map[string]any `json:"variables"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// traceJSONValue converts a value into one that marshals into JSON in the same
+// way that it would be printed as a mapping result.
+func traceJSONValue(v any) any {
+	switch t := v.(type) {
+	case []byte:
+		return string(t)
+	case map[string]any:
+		m := make(map[string]any, len(t))
+		for k, e := range t {
+			m[k] = traceJSONValue(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(t))
+		for i, e := range t {
+			s[i] = traceJSONValue(e)
+		}
+		return s
+	case value.Delete, value.Nothing:
+		return nil
+	}
+	return value.ISanitize(v)
+}
+
+func traceJSONMap(m map[string]any) map[string]any {
+	if m == nil {
+		return map[string]any{}
+	}
+	return traceJSONValue(m).(map[string]any)
+}
+
+func newTraceJSON(traces []mapping.AssignmentTrace) []traceAssignment {
+	assignments := make([]traceAssignment, 0, len(traces))
+	for _, t := range traces {
+		a := traceAssignment{
+			Line:      t.Line,
+			Target:    t.Target.String(),
+			Steps:     make([]traceStep, 0, len(t.Steps)),
+			Result:    traceJSONValue(t.Result),
+			Root:      traceJSONValue(t.Value),
+			Meta:      traceJSONMap(t.Meta),
+			Variables: traceJSONMap(t.Vars),
+		}
+		switch t.Result.(type) {
+		case value.Nothing:
+			a.Skipped = true
+		case value.Delete:
+			a.Deleted = true
+		}
+		if t.Err != nil {
+			a.Error = t.Err.Error()
+		}
+		for _, s := range t.Steps {
+			step := traceStep{
+				Line:       s.Line,
+				Column:     s.Column,
+				Expression: s.Expression,
+				Value:      traceJSONValue(s.Value),
+			}
+			if s.Err != nil {
+				step.Error = s.Err.Error()
+			}
+			a.Steps = append(a.Steps, step)
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments
+}
+
+type traceOutput struct {
+	Result      string            `json:"result"`
+	Error       string            `json:"error,omitempty"`
+	Assignments []traceAssignment `json:"assignments"`
+}
+
+// traceJSON executes a mapping with tracing and returns the result along with
+// the trace as a JSON document.
+func (e *execCache) traceJSON(exec *mapping.Executor, rawInput, prettyOutput bool, input []byte) string {
+	res, traces, err := e.traceMapping(exec, rawInput, false, input)
+
+	out := traceOutput{
+		Result:      res,
+		Assignments: newTraceJSON(traces),
+	}
+	if err != nil {
+		out.Error = err.Error()
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if prettyOutput {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(out); err != nil {
+		buf.Reset()
+		_ = enc.Encode(traceOutput{
+			Error:       fmt.Sprintf("failed to marshal trace: %v", err),
+			Assignments: []traceAssignment{},
+		})
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -73,7 +73,7 @@ func RunService(c *cli.Context, version, dateBuilt string, streamsMode bool) int
 	watching := c.Bool("watcher")
 	if streamsMode {
 		enableStreamsAPI := !c.Bool("no-api")
-		stoppableStream = initStreamsMode(strict, watching, enableStreamsAPI, confReader, stoppableManager.Manager())
+		stoppableStream = initStreamsMode(strict, watching, enableStreamsAPI, confReader, conf.ResourceConfig, stoppableManager.Manager())
 	} else {
 		stoppableStream, dataStreamClosedChan = initNormalMode(conf, strict, watching, confReader, stoppableManager.Manager())
 	}
@@ -108,10 +108,11 @@ func DelayShutdown(ctx context.Context, duration time.Duration) error {
 func initStreamsMode(
 	strict, watching, enableAPI bool,
 	confReader *config.Reader,
+	resConf manager.ResourceConfig,
 	mgr *manager.Type,
 ) Stoppable {
 	logger := mgr.Logger()
-	streamMgr := strmmgr.New(mgr, strmmgr.OptAPIEnabled(enableAPI))
+	streamMgr := strmmgr.New(mgr, strmmgr.OptAPIEnabled(enableAPI), strmmgr.OptResourceConfig(resConf))
 
 	streamConfs := map[string]stream.Config{}
 	lints, err := confReader.ReadStreams(streamConfs)
@@ -13,6 +13,7 @@ import (
 	"github.com/fatih/color"
 	"github.com/urfave/cli/v2"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/config"
 	"github.com/benthosdev/benthos/v4/internal/docs"
@@ -165,6 +166,11 @@ files with the .yaml or .yml extension.`[1:],
 				Value: false,
 				Usage: "Do not produce lint errors when environment interpolations exist without defaults within configs but aren't defined.",
 			},
+			&cli.StringFlag{
+				Name:  "bloblang-input-schema",
+				Value: "",
+				Usage: "A path to a JSON Schema or Avro schema (with the extension .avsc) describing the input documents of all Bloblang mappings, which is used in order to type check them.",
+			},
 		},
 		Action: func(c *cli.Context) error {
 			if code := LintAction(c, os.Stderr); code != 0 {
@@ -175,6 +181,17 @@ files with the .yaml or .yml extension.`[1:],
 	}
 }
 
+func readTypeSchemaFile(schemaPath string) (*query.TypeSchema, error) {
+	schemaBytes, err := ifs.ReadFile(ifs.OS(), schemaPath)
+	if err != nil {
+		return nil, err
+	}
+	if path.Ext(schemaPath) == ".avsc" {
+		return query.NewTypeSchemaFromAvro(schemaBytes)
+	}
+	return query.NewTypeSchemaFromJSONSchema(schemaBytes)
+}
+
 // LintAction performs the benthos lint subcommand and returns the appropriate
 // exit code. This function is exported for testing purposes only.
 func LintAction(c *cli.Context, stderr io.Writer) int {
@@ -192,6 +209,12 @@ func LintAction(c *cli.Context, stderr io.Writer) int {
 	lConf.RejectDeprecated = c.Bool("deprecated")
 	lConf.RequireLabels = c.Bool("labels")
 	skipEnvVarCheck := c.Bool("skip-env-var-check")
+	if schemaPath := c.String("bloblang-input-schema"); schemaPath != "" {
+		if lConf.BloblangInputSchema, err = readTypeSchemaFile(schemaPath); err != nil {
+			fmt.Fprintf(stderr, "Bloblang input schema error: %v\n", err)
+			return 1
+		}
+	}
 
 	var pathLintMut sync.Mutex
 	var pathLints []pathLint
@@ -2,9 +2,12 @@ package test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/fs"
+	"time"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang"
 	iprocessor "github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/config/test"
 	"github.com/benthosdev/benthos/v4/internal/message"
@@ -32,9 +35,21 @@ type ProcProvider interface {
 	ProvideBloblang(path string) ([]iprocessor.V1, error)
 }
 
+// streamCaseTimeout is the maximum period of time to wait for each input batch
+// of a stream test case to be acknowledged.
+var streamCaseTimeout = time.Second * 30
+
 // ExecuteFrom executes a test case from the perspective of a given directory,
 // which is used for obtaining relative condition file imports.
 func ExecuteFrom(fs fs.FS, dir string, c test.Case, provider ProcProvider) (failures []CaseFailure, err error) {
+	if c.TargetStream {
+		sProvider, ok := provider.(StreamProvider)
+		if !ok {
+			return nil, errors.New("provider does not support stream targets")
+		}
+		return executeStreamFrom(fs, dir, c, sProvider)
+	}
+
 	var procSet []iprocessor.V1
 	if c.TargetMapping != "" {
 		if procSet, err = provider.ProvideBloblang(c.TargetMapping); err != nil {
@@ -55,7 +70,92 @@ func ExecuteFrom(fs fs.FS, dir string, c test.Case, provider ProcProvider) (fail
 	}
 
 	var inputMsg []message.Batch
+	if inputMsg, err = caseInputBatches(fs, dir, c, provider); err != nil {
+		return
+	}
+
+	outputBatches, result := iprocessor.ExecuteAll(context.Background(), procSet, inputMsg...)
+	if result != nil {
+		reportFailure(fmt.Sprintf("processors resulted in error: %v", result))
+	}
+
+	checkCaseOutputBatches(fs, dir, c, outputBatches, reportFailure)
+	return
+}
+
+func executeStreamFrom(fs fs.FS, dir string, c test.Case, provider StreamProvider) (failures []CaseFailure, err error) {
+	reportFailure := func(reason string) {
+		failures = append(failures, CaseFailure{
+			Name:     c.Name,
+			TestLine: c.Line(),
+			Reason:   reason,
+		})
+	}
+
+	var inputMsg []message.Batch
+	if inputMsg, err = caseInputBatches(fs, dir, c, provider); err != nil {
+		return
+	}
+
+	var strm *MockedStream
+	if strm, err = provider.ProvideStream(c.Environment, c.Mocks); err != nil {
+		return nil, fmt.Errorf("failed to initialise stream: %v", err)
+	}
+
+	var syncResponses []message.Batch
+	for i, batch := range inputMsg {
+		ctx, done := context.WithTimeout(context.Background(), streamCaseTimeout)
+		res, sErr := strm.Send(ctx, batch)
+		done()
+		if sErr != nil {
+			reportFailure(fmt.Sprintf("input batch %v was not delivered: %v", i, sErr))
+			continue
+		}
+		syncResponses = append(syncResponses, res...)
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), streamCaseTimeout)
+	defer done()
+	if err = strm.Close(ctx); err != nil {
+		return nil, fmt.Errorf("failed to close stream: %v", err)
+	}
+
+	// The output batches of a case target the root output, which is only
+	// possible when it's a mocked output.
+	if outputBatches, tErr := strm.OutputBatches("/output"); tErr == nil {
+		checkCaseOutputBatches(fs, dir, c, outputBatches, reportFailure)
+	} else if len(c.OutputBatches) > 0 {
+		return nil, tErr
+	} else if len(c.OutputInvariants) > 0 {
+		checkOutputInvariants(fs, dir, c.OutputInvariants, strm.AllOutputBatches(), reportFailure)
+	}
 
+	for _, target := range c.OutputTargets {
+		var outputBatches []message.Batch
+		if outputBatches, err = strm.OutputBatches(target.Target); err != nil {
+			return nil, err
+		}
+		targetName := target.Target
+		checkOutputBatches(fs, dir, target.OutputBatches, outputBatches, func(reason string) {
+			reportFailure(fmt.Sprintf("output '%v': %v", targetName, reason))
+		})
+	}
+
+	if c.HasSyncResponses {
+		checkOutputBatches(fs, dir, c.SyncResponses, syncResponses, func(reason string) {
+			reportFailure(fmt.Sprintf("sync response: %v", reason))
+		})
+	}
+	return
+}
+
+// inputEnvProvider is implemented by providers that customise the Bloblang
+// environment used for generating the inputs of test cases.
+type inputEnvProvider interface {
+	inputBloblangEnvironment() *bloblang.Environment
+}
+
+func caseInputBatches(fs fs.FS, dir string, c test.Case, provider any) (inputMsg []message.Batch, err error) {
 	for _, inputBatch := range c.InputBatches {
 		parts := make([]*message.Part, len(inputBatch))
 		for i, v := range inputBatch {
@@ -67,24 +167,60 @@ func ExecuteFrom(fs fs.FS, dir string, c test.Case, provider ProcProvider) (fail
 
 		currentBatch := message.Batch(parts)
 		inputMsg = append(inputMsg, currentBatch)
+	}
+	if c.InputGenerator != nil {
+		var generated []message.Batch
+		env := bloblang.GlobalEnvironment()
+		if p, ok := provider.(inputEnvProvider); ok {
+			env = p.inputBloblangEnvironment()
+		}
+		if generated, err = c.InputGenerator.Generate(env, fs, dir); err != nil {
+			err = fmt.Errorf("failed to generate test inputs: %w", err)
+			return
+		}
+		inputMsg = append(inputMsg, generated...)
+	}
+	return
+}
 
+// checkCaseOutputBatches checks the output batches of a case, where the
+// batches are compared against the expected output batches unless only output
+// invariants are specified.
+func checkCaseOutputBatches(fs fs.FS, dir string, c test.Case, outputBatches []message.Batch, reportFailure func(reason string)) {
+	if len(c.OutputInvariants) == 0 || len(c.OutputBatches) > 0 {
+		checkOutputBatches(fs, dir, c.OutputBatches, outputBatches, reportFailure)
 	}
+	if len(c.OutputInvariants) > 0 {
+		checkOutputInvariants(fs, dir, c.OutputInvariants, outputBatches, reportFailure)
+	}
+}
 
-	outputBatches, result := iprocessor.ExecuteAll(context.Background(), procSet, inputMsg...)
-	if result != nil {
-		reportFailure(fmt.Sprintf("processors resulted in error: %v", result))
+func checkOutputInvariants(fs fs.FS, dir string, invariants test.OutputConditionsMap, outputBatches []message.Batch, reportFailure func(reason string)) {
+	for i, v := range outputBatches {
+		_ = v.Iter(func(i2 int, part *message.Part) error {
+			condErrs := invariants.CheckAll(fs, dir, part)
+			for _, condErr := range condErrs {
+				reportFailure(fmt.Sprintf("invariant violated by batch %v message %v: %v\n  message: %s", i, i2, condErr, part.AsBytes()))
+			}
+			if procErr := part.ErrorGet(); procErr != nil && len(condErrs) > 0 {
+				reportFailure(fmt.Sprintf("batch %v message %v: %v", i, i2, red(procErr)))
+			}
+			return nil
+		})
 	}
+}
 
-	if lExp, lAct := len(c.OutputBatches), len(outputBatches); lAct < lExp {
+func checkOutputBatches(fs fs.FS, dir string, expectedBatches [][]test.OutputConditionsMap, outputBatches []message.Batch, reportFailure func(reason string)) {
+	if lExp, lAct := len(expectedBatches), len(outputBatches); lAct < lExp {
 		reportFailure(fmt.Sprintf("wrong batch count, expected %v, got %v", lExp, lAct))
 	}
 
 	for i, v := range outputBatches {
-		if len(c.OutputBatches) <= i {
+		if len(expectedBatches) <= i {
 			reportFailure(fmt.Sprintf("unexpected batch: %s", message.GetAllBytes(v)))
 			continue
 		}
-		expectedBatch := c.OutputBatches[i]
+		expectedBatch := expectedBatches[i]
 		if lExp, lAct := len(expectedBatch), v.Len(); lExp != lAct {
 			reportFailure(fmt.Sprintf("mismatch of output batch %v message counts, expected %v, got %v", i, lExp, lAct))
 		}
@@ -103,5 +239,4 @@ func ExecuteFrom(fs fs.FS, dir string, c test.Case, provider ProcProvider) (fail
 			return nil
 		})
 	}
-	return
 }
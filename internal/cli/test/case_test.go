@@ -403,3 +403,76 @@ output_batches:
 		},
 	}, fails)
 }
+
+func TestGeneratedCaseInputs(t *testing.T) {
+	color.NoColor = true
+
+	provider := mockProvider{}
+	procConf := processor.NewConfig()
+
+	procConf.Type = "bloblang"
+	procConf.Plugin = `root = this
+root.doubled = this.value * 2`
+	proc, err := mock.NewManager().NewProcessor(procConf)
+	require.NoError(t, err)
+
+	provider["/pipeline/processors"] = []processor.V1{proc}
+
+	node, err := docs.UnmarshalYAML([]byte(`
+name: generated
+input_generator:
+  mapping: |
+    root.id = this.index
+    root.value = random_int(seed: this.seed, max: 10)
+    root.text = if this.index % 4 == 0 { null } else { "foo" }
+  count: 10
+  batch_size: 3
+  seed: 5
+output_invariants:
+  bloblang: 'this.doubled == this.value * 2 && this.value <= 10'
+`))
+	require.NoError(t, err)
+
+	c, err := dtest.CaseFromAny(node)
+	require.NoError(t, err)
+
+	fails, err := test.ExecuteFrom(ifs.OS(), "", c, provider)
+	require.NoError(t, err)
+	assert.Empty(t, fails)
+
+	node, err = docs.UnmarshalYAML([]byte(`
+name: generated failures
+input_generator:
+  mapping: |
+    root.id = this.index
+    root.value = 1
+    root.text = if this.index % 4 == 0 { null } else { "foo" }
+  count: 6
+  batch_size: 4
+output_batches:
+  - - bloblang: 'this.id == 0'
+output_invariants:
+  bloblang: 'this.text != null'
+`))
+	require.NoError(t, err)
+
+	c, err = dtest.CaseFromAny(node)
+	require.NoError(t, err)
+
+	fails, err = test.ExecuteFrom(ifs.OS(), "", c, provider)
+	require.NoError(t, err)
+
+	var reasons []string
+	for _, f := range fails {
+		reasons = append(reasons, f.Reason)
+	}
+	assert.Equal(t, []string{
+		"mismatch of output batch 0 message counts, expected 1, got 4",
+		"unexpected message from batch 0: {\"doubled\":2,\"id\":1,\"text\":\"foo\",\"value\":1}",
+		"unexpected message from batch 0: {\"doubled\":2,\"id\":2,\"text\":\"foo\",\"value\":1}",
+		"unexpected message from batch 0: {\"doubled\":2,\"id\":3,\"text\":\"foo\",\"value\":1}",
+		"unexpected batch: [{\"doubled\":2,\"id\":4,\"text\":null,\"value\":1} {\"doubled\":2,\"id\":5,\"text\":\"foo\",\"value\":1}]",
+		"invariant violated by batch 0 message 0: bloblang: bloblang expression was false\n  message: {\"doubled\":2,\"id\":0,\"text\":null,\"value\":1}",
+		"invariant violated by batch 1 message 0: bloblang: bloblang expression was false\n  message: {\"doubled\":2,\"id\":4,\"text\":null,\"value\":1}",
+	}, reasons)
+}
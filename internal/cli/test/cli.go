@@ -23,6 +23,7 @@ fail the process will report the errors and exit with a status code 1.
   benthos test ./path/to/configs/...
   benthos test ./foo_configs/*.yaml ./bar_configs/*.yaml
   benthos test ./foo.yaml
+  benthos test --coverage --coverage-lcov ./coverage.lcov ./...
 
 For more information check out the docs at:
 https://benthos.dev/docs/configuration/unit_testing`[1:],
@@ -32,6 +33,21 @@ https://benthos.dev/docs/configuration/unit_testing`[1:],
 				Value: "",
 				Usage: "allow components to write logs at a provided level to stdout.",
 			},
+			&cli.BoolFlag{
+				Name:  "update-snapshots",
+				Value: false,
+				Usage: "rewrite the files of snapshot conditions from the messages produced by the tests rather than compare against them.",
+			},
+			&cli.BoolFlag{
+				Name:  "coverage",
+				Value: false,
+				Usage: "print a report of which lines and branches of Bloblang mappings were executed by the tests.",
+			},
+			&cli.StringFlag{
+				Name:  "coverage-lcov",
+				Value: "",
+				Usage: "write the coverage of Bloblang mappings executed by the tests to a file in the lcov format.",
+			},
 		},
 		Action: func(c *cli.Context) error {
 			if len(c.StringSlice("set")) > 0 {
@@ -44,18 +60,39 @@ https://benthos.dev/docs/configuration/unit_testing`[1:],
 				fmt.Printf("Failed to resolve resource glob pattern: %v\n", err)
 				os.Exit(1)
 			}
+			logger := log.Noop()
 			if logLevel := c.String("log"); len(logLevel) > 0 {
 				logConf := log.NewConfig()
 				logConf.LogLevel = logLevel
-				logger, err := log.New(os.Stdout, ifs.OS(), logConf)
-				if err != nil {
+				if logger, err = log.New(os.Stdout, ifs.OS(), logConf); err != nil {
 					fmt.Printf("Failed to init logger: %v\n", err)
 					os.Exit(1)
 				}
-				if RunAll(c.Args().Slice(), "_benthos_test", true, logger, resourcesPaths) {
-					os.Exit(0)
+			}
+
+			opts := []func(*ProcessorsProvider){
+				OptProcessorsProviderUpdateSnapshots(c.Bool("update-snapshots")),
+			}
+			var coverage *CoverageReport
+			lcovPath := c.String("coverage-lcov")
+			if c.Bool("coverage") || lcovPath != "" {
+				coverage = NewCoverageReport()
+				opts = append(opts, OptProcessorsProviderSetCoverage(coverage))
+			}
+
+			succeeded := RunAll(c.Args().Slice(), "_benthos_test", true, logger, resourcesPaths, opts...)
+			if coverage != nil {
+				if c.Bool("coverage") {
+					coverage.Print(os.Stdout)
+				}
+				if lcovPath != "" {
+					if err := writeLCOVFile(coverage, lcovPath); err != nil {
+						fmt.Fprintf(os.Stderr, "Failed to write coverage: %v\n", err)
+						os.Exit(1)
+					}
 				}
-			} else if RunAll(c.Args().Slice(), "_benthos_test", true, log.Noop(), resourcesPaths) {
+			}
+			if succeeded {
 				os.Exit(0)
 			}
 			os.Exit(1)
@@ -63,3 +100,15 @@ https://benthos.dev/docs/configuration/unit_testing`[1:],
 		},
 	}
 }
+
+func writeLCOVFile(coverage *CoverageReport, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := coverage.WriteLCOV(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -117,8 +117,9 @@ func lintTarget(path, testSuffix string) ([]docs.Lint, error) {
 
 // RunAll executes the test command for a slice of paths. The path can either be
 // a config file, a config files test definition file, a directory, or the
-// wildcard pattern './...'.
-func RunAll(paths []string, testSuffix string, lint bool, logger log.Modular, resourcesPaths []string) bool {
+// wildcard pattern './...'. Additional options are applied to the processors
+// provider of each test target.
+func RunAll(paths []string, testSuffix string, lint bool, logger log.Modular, resourcesPaths []string, opts ...func(*ProcessorsProvider)) bool {
 	targets, err := GetTestTargets(paths, testSuffix)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to obtain test targets: %v\n", err)
@@ -151,7 +152,7 @@ func RunAll(paths []string, testSuffix string, lint bool, logger log.Modular, re
 				return false
 			}
 		}
-		if failCases, err = Execute(targets[target], target, resourcesPaths, logger); err != nil {
+		if failCases, err = Execute(targets[target], target, resourcesPaths, logger, opts...); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to execute test target '%v': %v\n", target, err)
 			return false
 		}
@@ -0,0 +1,302 @@
+package test
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+)
+
+// CoverageReport accumulates the coverage of Bloblang mappings executed by
+// test cases, and reports it against the files that the mappings were defined
+// within.
+type CoverageReport struct {
+	cov *query.Coverage
+
+	filesMut sync.Mutex
+	files    []string
+}
+
+// NewCoverageReport creates an empty coverage report.
+func NewCoverageReport() *CoverageReport {
+	return &CoverageReport{
+		cov: query.NewCoverage(),
+	}
+}
+
+// addFiles registers config files that mappings may have been defined within.
+func (c *CoverageReport) addFiles(paths ...string) {
+	c.filesMut.Lock()
+	defer c.filesMut.Unlock()
+
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		var exists bool
+		for _, e := range c.files {
+			if exists = e == p; exists {
+				break
+			}
+		}
+		if !exists {
+			c.files = append(c.files, p)
+		}
+	}
+}
+
+type coverageLine struct {
+	line           int
+	hits           int64
+	branches       int
+	branchesHit    int
+	sourceLineText string
+}
+
+type coverageBranch struct {
+	line, block, branch int
+	hits                int64
+	blockHit            bool
+}
+
+type coverageFile struct {
+	path     string
+	lines    []coverageLine
+	branches []coverageBranch
+}
+
+func (f *coverageFile) linesHit() (hit int) {
+	for _, l := range f.lines {
+		if l.hits > 0 {
+			hit++
+		}
+	}
+	return
+}
+
+func (f *coverageFile) branchesHit() (hit int) {
+	for _, b := range f.branches {
+		if b.hits > 0 {
+			hit++
+		}
+	}
+	return
+}
+
+// locateMapping attempts to find a mapping within the scalar values of a YAML
+// document, and returns the line number that precedes the first line of the
+// mapping.
+func locateMapping(node *yaml.Node, content string) (int, bool) {
+	if node.Kind == yaml.ScalarNode && node.Value == content {
+		if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			return node.Line, true
+		}
+		return node.Line - 1, true
+	}
+	for _, child := range node.Content {
+		if offset, ok := locateMapping(child, content); ok {
+			return offset, true
+		}
+	}
+	return 0, false
+}
+
+// resolveFiles resolves the coverage of each mapping into the files that they
+// were defined within. Mappings that cannot be located within a file, such as
+// mocks defined within test definitions, are omitted.
+func (c *CoverageReport) resolveFiles() []*coverageFile {
+	c.filesMut.Lock()
+	candidatePaths := make([]string, len(c.files))
+	copy(candidatePaths, c.files)
+	c.filesMut.Unlock()
+
+	candidates := map[string]*yaml.Node{}
+	fileLines := map[string][]string{}
+	for _, p := range candidatePaths {
+		fileBytes, err := ifs.ReadFile(ifs.OS(), p)
+		if err != nil {
+			continue
+		}
+		fileLines[p] = strings.Split(string(fileBytes), "\n")
+
+		var node yaml.Node
+		if err := yaml.Unmarshal(fileBytes, &node); err != nil {
+			continue
+		}
+		candidates[p] = &node
+	}
+
+	files := map[string]*coverageFile{}
+	nextBlock := map[string]int{}
+
+	for _, src := range c.cov.Sources() {
+		var path string
+		var offset int
+		if src.Name != "" {
+			path = filepath.Clean(src.Name)
+			if _, exists := fileLines[path]; !exists {
+				fileLines[path] = strings.Split(src.Content, "\n")
+			}
+		} else {
+			for _, p := range candidatePaths {
+				node, exists := candidates[p]
+				if !exists {
+					continue
+				}
+				var ok bool
+				if offset, ok = locateMapping(node, src.Content); ok {
+					path = p
+					break
+				}
+			}
+			if path == "" {
+				continue
+			}
+		}
+
+		f, exists := files[path]
+		if !exists {
+			f = &coverageFile{path: path}
+			files[path] = f
+		}
+
+		branchCounts := map[int][2]int{}
+		blocksHit := map[int]bool{}
+		srcBranches := src.Branches()
+		for _, b := range srcBranches {
+			if b.Hits > 0 {
+				blocksHit[b.Block] = true
+			}
+		}
+		for _, b := range srcBranches {
+			counts := branchCounts[b.Line]
+			counts[0]++
+			if b.Hits > 0 {
+				counts[1]++
+			}
+			branchCounts[b.Line] = counts
+
+			f.branches = append(f.branches, coverageBranch{
+				line:     b.Line + offset,
+				block:    b.Block + nextBlock[path],
+				branch:   b.Branch,
+				hits:     b.Hits,
+				blockHit: blocksHit[b.Block],
+			})
+		}
+		if len(srcBranches) > 0 {
+			nextBlock[path] += srcBranches[len(srcBranches)-1].Block + 1
+		}
+
+		for _, l := range src.Lines() {
+			line := coverageLine{
+				line:        l.Line + offset,
+				hits:        l.Hits,
+				branches:    branchCounts[l.Line][0],
+				branchesHit: branchCounts[l.Line][1],
+			}
+			if lines := fileLines[path]; line.line-1 < len(lines) {
+				line.sourceLineText = strings.TrimSpace(lines[line.line-1])
+			}
+			f.lines = append(f.lines, line)
+		}
+	}
+
+	sorted := make([]*coverageFile, 0, len(files))
+	for _, f := range files {
+		sort.SliceStable(f.lines, func(i, j int) bool {
+			return f.lines[i].line < f.lines[j].line
+		})
+		sort.SliceStable(f.branches, func(i, j int) bool {
+			return f.branches[i].block < f.branches[j].block
+		})
+		sorted = append(sorted, f)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].path < sorted[j].path
+	})
+	return sorted
+}
+
+func coveragePercent(hit, total int) string {
+	if total == 0 {
+		return "100.0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(hit)/float64(total)*100)
+}
+
+// Print writes a human readable report of the coverage of each line of each
+// file containing mappings.
+func (c *CoverageReport) Print(w io.Writer) {
+	files := c.resolveFiles()
+
+	fmt.Fprintf(w, "\nBloblang coverage:\n")
+	if len(files) == 0 {
+		fmt.Fprintf(w, "\n%v\n", yellow("No mappings were executed"))
+		return
+	}
+
+	var totalLines, totalLinesHit, totalBranches, totalBranchesHit int
+	for _, f := range files {
+		linesHit, branchesHit := f.linesHit(), f.branchesHit()
+		totalLines += len(f.lines)
+		totalLinesHit += linesHit
+		totalBranches += len(f.branches)
+		totalBranchesHit += branchesHit
+
+		fmt.Fprintf(w, "\n--- %v: %v/%v lines (%v), %v/%v branches (%v) ---\n\n",
+			f.path,
+			linesHit, len(f.lines), coveragePercent(linesHit, len(f.lines)),
+			branchesHit, len(f.branches), coveragePercent(branchesHit, len(f.branches)),
+		)
+		for _, l := range f.lines {
+			var branchStr string
+			if l.branches > 0 {
+				branchStr = fmt.Sprintf(" [branches %v/%v]", l.branchesHit, l.branches)
+			}
+			lineStr := fmt.Sprintf("%5d | %6dx | %v%v", l.line, l.hits, l.sourceLineText, branchStr)
+			switch {
+			case l.hits == 0:
+				lineStr = red(lineStr)
+			case l.branchesHit < l.branches:
+				lineStr = yellow(lineStr)
+			}
+			fmt.Fprintln(w, lineStr)
+		}
+	}
+
+	fmt.Fprintf(w, "\nTotal: %v/%v lines (%v), %v/%v branches (%v)\n",
+		totalLinesHit, totalLines, coveragePercent(totalLinesHit, totalLines),
+		totalBranchesHit, totalBranches, coveragePercent(totalBranchesHit, totalBranches),
+	)
+}
+
+// WriteLCOV writes the coverage of each file containing mappings in the lcov
+// tracefile format.
+func (c *CoverageReport) WriteLCOV(w io.Writer) error {
+	var b strings.Builder
+	for _, f := range c.resolveFiles() {
+		b.WriteString("TN:\n")
+		fmt.Fprintf(&b, "SF:%v\n", f.path)
+		for _, br := range f.branches {
+			taken := "-"
+			if br.blockHit {
+				taken = fmt.Sprintf("%v", br.hits)
+			}
+			fmt.Fprintf(&b, "BRDA:%v,%v,%v,%v\n", br.line, br.block, br.branch, taken)
+		}
+		fmt.Fprintf(&b, "BRF:%v\nBRH:%v\n", len(f.branches), f.branchesHit())
+		for _, l := range f.lines {
+			fmt.Fprintf(&b, "DA:%v,%v\n", l.line, l.hits)
+		}
+		fmt.Fprintf(&b, "LF:%v\nLH:%v\n", len(f.lines), f.linesHit())
+		b.WriteString("end_of_record\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,101 @@
+package test_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/cli/test"
+	dtest "github.com/benthosdev/benthos/v4/internal/config/test"
+	"github.com/benthosdev/benthos/v4/internal/log"
+)
+
+func TestCoverageReport(t *testing.T) {
+	color.NoColor = true
+
+	testDir, err := initTestFiles(t, map[string]string{
+		"config1.yaml": `
+pipeline:
+  processors:
+    - mapping: |
+        root.id = this.id
+        root.size = if this.n > 10 {
+          "big"
+        } else {
+          "small"
+        }
+    - mapping: 'root = this.without("id")'
+`,
+		"mapping.blobl": `root.kind = match this.kind {
+  "a" => "first"
+  _ => "other"
+}`,
+	})
+	require.NoError(t, err)
+
+	def := []dtest.Case{
+		{
+			Name:             "processors test",
+			TargetProcessors: "/pipeline/processors",
+			InputBatches: [][]dtest.InputConfig{
+				{{Content: `{"id":"a","n":20}`}},
+				{{Content: `{"id":"b","n":30}`}},
+			},
+		},
+		{
+			Name:          "mapping test",
+			TargetMapping: "./mapping.blobl",
+			InputBatches: [][]dtest.InputConfig{
+				{{Content: `{"kind":"a"}`}},
+			},
+		},
+	}
+
+	coverage := test.NewCoverageReport()
+	_, err = test.Execute(def, filepath.Join(testDir, "config1.yaml"), nil, log.Noop(), test.OptProcessorsProviderSetCoverage(coverage))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, coverage.WriteLCOV(&buf))
+	assert.Equal(t, `TN:
+SF:`+filepath.Join(testDir, "config1.yaml")+`
+BRDA:6,0,0,2
+BRDA:6,0,1,0
+BRF:2
+BRH:1
+DA:5,2
+DA:6,2
+DA:8,0
+DA:11,2
+LF:4
+LH:3
+end_of_record
+TN:
+SF:`+filepath.Join(testDir, "mapping.blobl")+`
+BRDA:1,0,0,1
+BRDA:1,0,1,0
+BRF:2
+BRH:1
+DA:1,1
+DA:2,1
+DA:3,0
+LF:3
+LH:2
+end_of_record
+`, buf.String())
+
+	buf.Reset()
+	coverage.Print(&buf)
+	assert.Contains(t, buf.String(), `--- `+filepath.Join(testDir, "config1.yaml")+`: 3/4 lines (75.0%), 1/2 branches (50.0%) ---
+
+    5 |      2x | root.id = this.id
+    6 |      2x | root.size = if this.n > 10 { [branches 1/2]
+    8 |      0x | } else {
+   11 |      2x | - mapping: 'root = this.without("id")'
+`)
+	assert.Contains(t, buf.String(), `Total: 5/7 lines (71.4%), 2/4 branches (50.0%)`)
+}
@@ -9,18 +9,24 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/log"
 )
 
-// Execute the test definition.
-func Execute(cases []test.Case, testFilePath string, resourcesPaths []string, logger log.Modular) ([]CaseFailure, error) {
+// Execute the test definition. Additional options are applied to the
+// processors provider used by test cases.
+func Execute(cases []test.Case, testFilePath string, resourcesPaths []string, logger log.Modular, opts ...func(*ProcessorsProvider)) ([]CaseFailure, error) {
 	procsProvider := NewProcessorsProvider(
 		testFilePath,
-		OptAddResourcesPaths(resourcesPaths),
-		OptProcessorsProviderSetLogger(logger),
+		append([]func(*ProcessorsProvider){
+			OptAddResourcesPaths(resourcesPaths),
+			OptProcessorsProviderSetLogger(logger),
+		}, opts...)...,
 	)
 
 	dir := filepath.Dir(testFilePath)
 
 	var totalFailures []CaseFailure
 	for i, c := range cases {
+		if procsProvider.updateSnapshots {
+			c.UpdateSnapshots()
+		}
 		cleanupEnv := setEnvironment(c.Environment)
 		failures, err := ExecuteFrom(ifs.OS(), dir, c, procsProvider)
 		if err != nil {
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/cli/test"
 	dtest "github.com/benthosdev/benthos/v4/internal/config/test"
@@ -166,3 +168,49 @@ pipeline:
 		t.Errorf("Mismatched fail message: %v != %v", act, exp)
 	}
 }
+
+func TestDefinitionUpdateSnapshots(t *testing.T) {
+	color.NoColor = true
+
+	testDir, err := initTestFiles(t, map[string]string{
+		"config1.yaml": `
+pipeline:
+  processors:
+  - bloblang: 'root = content().uppercase()'
+`,
+		"snapshots/foo.json": `{"content":"FOO","metadata":{}}`,
+	})
+	require.NoError(t, err)
+
+	newDef := func() []dtest.Case {
+		return []dtest.Case{
+			{
+				Name:             "snapshot test",
+				TargetProcessors: "/pipeline/processors",
+				InputBatches: [][]dtest.InputConfig{
+					{{Content: "bar"}},
+				},
+				OutputBatches: [][]dtest.OutputConditionsMap{
+					{{"snapshot": dtest.SnapshotCondition{Path: "./snapshots/foo.json"}}},
+				},
+			},
+		}
+	}
+
+	failures, err := test.Execute(newDef(), filepath.Join(testDir, "config1.yaml"), nil, log.Noop())
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Contains(t, failures[0].Reason, "snapshot mismatch")
+
+	failures, err = test.Execute(newDef(), filepath.Join(testDir, "config1.yaml"), nil, log.Noop(), test.OptProcessorsProviderUpdateSnapshots(true))
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+
+	failures, err = test.Execute(newDef(), filepath.Join(testDir, "config1.yaml"), nil, log.Noop())
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+
+	snapshotBytes, err := os.ReadFile(filepath.Join(testDir, "snapshots", "foo.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(snapshotBytes), `"content": "BAR"`)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/Jeffail/gabs/v2"
 	yaml "gopkg.in/yaml.v3"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang"
 	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/internal/bloblang/parser"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
@@ -38,7 +39,9 @@ type ProcessorsProvider struct {
 	resourcesPaths []string
 	cachedConfigs  map[string]cachedConfig
 
-	logger log.Modular
+	logger          log.Modular
+	coverage        *CoverageReport
+	updateSnapshots bool
 }
 
 // NewProcessorsProvider returns a new processors provider aimed at a filepath.
@@ -51,6 +54,10 @@ func NewProcessorsProvider(targetPath string, opts ...func(*ProcessorsProvider))
 	for _, opt := range opts {
 		opt(p)
 	}
+	if p.coverage != nil {
+		p.coverage.addFiles(p.targetPath)
+		p.coverage.addFiles(p.resourcesPaths...)
+	}
 	return p
 }
 
@@ -68,6 +75,39 @@ func OptProcessorsProviderSetLogger(logger log.Modular) func(*ProcessorsProvider
 	}
 }
 
+// OptProcessorsProviderSetCoverage sets a coverage report in which the
+// execution of Bloblang mappings of tested components is recorded.
+func OptProcessorsProviderSetCoverage(coverage *CoverageReport) func(*ProcessorsProvider) {
+	return func(p *ProcessorsProvider) {
+		p.coverage = coverage
+	}
+}
+
+// OptProcessorsProviderUpdateSnapshots sets whether test cases executed with
+// the provider rewrite their snapshot files rather than compare against them.
+func OptProcessorsProviderUpdateSnapshots(update bool) func(*ProcessorsProvider) {
+	return func(p *ProcessorsProvider) {
+		p.updateSnapshots = update
+	}
+}
+
+// inputBloblangEnvironment returns the environment used for parsing the
+// mappings that generate test inputs, which unlike the mappings of tested
+// components are not recorded in coverage reports.
+func (p *ProcessorsProvider) inputBloblangEnvironment() *bloblang.Environment {
+	return bloblang.GlobalEnvironment()
+}
+
+// bloblangEnvironment returns the environment used for parsing the mappings of
+// tested components.
+func (p *ProcessorsProvider) bloblangEnvironment() *bloblang.Environment {
+	env := p.inputBloblangEnvironment()
+	if p.coverage != nil {
+		env = env.WithCoverage(p.coverage.cov)
+	}
+	return env
+}
+
 //------------------------------------------------------------------------------
 
 // Provide attempts to extract an array of processors from a Benthos config.
@@ -95,6 +135,10 @@ func (p *ProcessorsProvider) ProvideBloblang(pathStr string) ([]processor.V1, er
 	}
 
 	pCtx := parser.GlobalContext().WithImporterRelativeToFile(pathStr)
+	if p.coverage != nil {
+		p.coverage.cov.Source(pathStr, string(mappingBytes))
+		pCtx = pCtx.WithCoverage(p.coverage.cov)
+	}
 	exec, mapErr := parser.ParseMapping(pCtx, string(mappingBytes))
 	if mapErr != nil {
 		return nil, mapErr
@@ -146,7 +190,10 @@ func (b *bloblangProc) Close(context.Context) error {
 //------------------------------------------------------------------------------
 
 func (p *ProcessorsProvider) initProcs(confs cachedConfig) ([]processor.V1, error) {
-	mgr, err := manager.New(confs.mgr, manager.OptSetLogger(p.logger))
+	mgr, err := manager.New(confs.mgr,
+		manager.OptSetLogger(p.logger),
+		manager.OptSetBloblangEnvironment(p.bloblangEnvironment()),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialise resources: %v", err)
 	}
@@ -265,6 +312,80 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 		targetPath = p.targetPath
 	}
 
+	root, labelsToPaths, err := p.readMockedConfig(targetPath, environment, mocks)
+	if err != nil {
+		return confs, err
+	}
+
+	confSpec := config.Spec()
+	pConf, err := confSpec.ParsedConfigFromAny(root)
+	if err != nil {
+		return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
+	}
+
+	mgrWrapper, err := p.resourcesFromParsed(targetPath, pConf, environment)
+	if err != nil {
+		return confs, err
+	}
+
+	// We can clear all input and output resources as they're not used by procs
+	// under any circumstances.
+	mgrWrapper.ResourceInputs = nil
+	mgrWrapper.ResourceOutputs = nil
+
+	confs.mgr = mgrWrapper
+
+	var pathSlice []string
+	if strings.HasPrefix(procPath, "/") {
+		if pathSlice, err = gabs.JSONPointerToSlice(procPath); err != nil {
+			return confs, fmt.Errorf("failed to parse case processors path '%v': %w", procPath, err)
+		}
+	} else {
+		if len(labelsToPaths) == 0 {
+			confSpec.YAMLLabelsToPaths(bundle.GlobalEnvironment, root, labelsToPaths, nil)
+		}
+		if pathSlice, exists = labelsToPaths[procPath]; !exists {
+			return confs, fmt.Errorf("target for label '%v' failed as the label was not found in the test target file, it is not currently possible to target resources imported separate to the test file", procPath)
+		}
+	}
+
+	if root, err = docs.GetYAMLPath(root, pathSlice...); err != nil {
+		return confs, fmt.Errorf("failed to resolve case processors from '%v': %v", targetPath, err)
+	}
+
+	if root.Kind == yaml.SequenceNode {
+		for _, n := range root.Content {
+			procConf, err := processor.FromAny(bundle.GlobalEnvironment, n)
+			if err != nil {
+				return confs, fmt.Errorf("failed to resolve case processors from '%v': %v", targetPath, err)
+			}
+			confs.procs = append(confs.procs, procConf)
+		}
+	} else {
+		procConf, err := processor.FromAny(bundle.GlobalEnvironment, root)
+		if err != nil {
+			return confs, fmt.Errorf("failed to resolve case processors from '%v': %v", targetPath, err)
+		}
+		confs.procs = append(confs.procs, procConf)
+	}
+
+	p.cachedConfigs[cacheKey] = confs
+	return confs, nil
+}
+
+func envVarLookupFunc(environment map[string]string) func(name string) (string, bool) {
+	return func(name string) (string, bool) {
+		if s, ok := environment[name]; ok {
+			return s, true
+		}
+		return os.LookupEnv(name)
+	}
+}
+
+// readMockedConfig reads a config file with environment variables swapped and
+// all mocks applied, and returns the resulting YAML tree along with any label
+// to path mappings that were resolved in the process.
+func (p *ProcessorsProvider) readMockedConfig(targetPath string, environment map[string]string, mocks map[string]any) (*yaml.Node, map[string][]string, error) {
 	// Set custom environment vars.
 	ogEnvVars := map[string]string{}
 	for k, v := range environment {
@@ -275,26 +396,19 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 	cleanupEnv := setEnvironment(environment)
 	defer cleanupEnv()
 
-	envVarLookup := func(name string) (string, bool) {
-		if s, ok := environment[name]; ok {
-			return s, true
-		}
-		return os.LookupEnv(name)
-	}
-
 	remainingMocks := map[string]any{}
 	for k, v := range mocks {
 		remainingMocks[k] = v
 	}
 
-	configBytes, _, _, err := config.ReadFileEnvSwap(ifs.OS(), targetPath, envVarLookup)
+	configBytes, _, _, err := config.ReadFileEnvSwap(ifs.OS(), targetPath, envVarLookupFunc(environment))
 	if err != nil {
-		return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
+		return nil, nil, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
 	}
 
 	root, err := docs.UnmarshalYAML(configBytes)
 	if err != nil {
-		return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
+		return nil, nil, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
 	}
 
 	// Replace mock components, starting with all absolute paths in JSON pointer
@@ -306,10 +420,10 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 		}
 		mockPathSlice, err := gabs.JSONPointerToSlice(k)
 		if err != nil {
-			return confs, fmt.Errorf("failed to parse mock path '%v': %w", k, err)
+			return nil, nil, fmt.Errorf("failed to parse mock path '%v': %w", k, err)
 		}
 		if err = setMock(confSpec, root, &v, mockPathSlice...); err != nil {
-			return confs, fmt.Errorf("failed to set mock '%v': %w", k, err)
+			return nil, nil, fmt.Errorf("failed to set mock '%v': %w", k, err)
 		}
 		delete(remainingMocks, k)
 	}
@@ -320,81 +434,39 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 		for k, v := range remainingMocks {
 			mockPathSlice, exists := labelsToPaths[k]
 			if !exists {
-				return confs, fmt.Errorf("mock for label '%v' could not be applied as the label was not found in the test target file, it is not currently possible to mock resources imported separate to the test file", k)
+				return nil, nil, fmt.Errorf("mock for label '%v' could not be applied as the label was not found in the test target file, it is not currently possible to mock resources imported separate to the test file", k)
 			}
 			if err = setMock(confSpec, root, &v, mockPathSlice...); err != nil {
-				return confs, fmt.Errorf("failed to set mock '%v': %w", k, err)
+				return nil, nil, fmt.Errorf("failed to set mock '%v': %w", k, err)
 			}
 			delete(remainingMocks, k)
 		}
 	}
+	return root, labelsToPaths, nil
+}
 
-	pConf, err := confSpec.ParsedConfigFromAny(root)
-	if err != nil {
-		return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
-	}
-
+// resourcesFromParsed extracts the resources of a parsed config file and merges
+// them with any resources imported from separate files.
+func (p *ProcessorsProvider) resourcesFromParsed(targetPath string, pConf *docs.ParsedConfig, environment map[string]string) (manager.ResourceConfig, error) {
 	mgrWrapper, err := manager.FromParsed(bundle.GlobalEnvironment, pConf)
 	if err != nil {
-		return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
+		return mgrWrapper, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
 	}
 
+	envVarLookup := envVarLookupFunc(environment)
 	for _, path := range p.resourcesPaths {
 		resourceBytes, _, _, err := config.ReadFileEnvSwap(ifs.OS(), path, envVarLookup)
 		if err != nil {
-			return confs, fmt.Errorf("failed to parse resources config file '%v': %v", path, err)
+			return mgrWrapper, fmt.Errorf("failed to parse resources config file '%v': %v", path, err)
 		}
 
 		extraMgrWrapper, err := testutil.ManagerFromYAML(string(resourceBytes))
 		if err != nil {
-			return confs, fmt.Errorf("failed to parse resources config file '%v': %v", path, err)
+			return mgrWrapper, fmt.Errorf("failed to parse resources config file '%v': %v", path, err)
 		}
 		if err = mgrWrapper.AddFrom(&extraMgrWrapper); err != nil {
-			return confs, fmt.Errorf("failed to merge resources from '%v': %v", path, err)
-		}
-	}
-
-	// We can clear all input and output resources as they're not used by procs
-	// under any circumstances.
-	mgrWrapper.ResourceInputs = nil
-	mgrWrapper.ResourceOutputs = nil
-
-	confs.mgr = mgrWrapper
-
-	var pathSlice []string
-	if strings.HasPrefix(procPath, "/") {
-		if pathSlice, err = gabs.JSONPointerToSlice(procPath); err != nil {
-			return confs, fmt.Errorf("failed to parse case processors path '%v': %w", procPath, err)
-		}
-	} else {
-		if len(labelsToPaths) == 0 {
-			confSpec.YAMLLabelsToPaths(bundle.GlobalEnvironment, root, labelsToPaths, nil)
-		}
-		if pathSlice, exists = labelsToPaths[procPath]; !exists {
-			return confs, fmt.Errorf("target for label '%v' failed as the label was not found in the test target file, it is not currently possible to target resources imported separate to the test file", procPath)
+			return mgrWrapper, fmt.Errorf("failed to merge resources from '%v': %v", path, err)
 		}
 	}
-
-	if root, err = docs.GetYAMLPath(root, pathSlice...); err != nil {
-		return confs, fmt.Errorf("failed to resolve case processors from '%v': %v", targetPath, err)
-	}
-
-	if root.Kind == yaml.SequenceNode {
-		for _, n := range root.Content {
-			procConf, err := processor.FromAny(bundle.GlobalEnvironment, n)
-			if err != nil {
-				return confs, fmt.Errorf("failed to resolve case processors from '%v': %v", targetPath, err)
-			}
-			confs.procs = append(confs.procs, procConf)
-		}
-	} else {
-		procConf, err := processor.FromAny(bundle.GlobalEnvironment, root)
-		if err != nil {
-			return confs, fmt.Errorf("failed to resolve case processors from '%v': %v", targetPath, err)
-		}
-		confs.procs = append(confs.procs, procConf)
-	}
-
-	p.cachedConfigs[cacheKey] = confs
-	return confs, nil
+	return mgrWrapper, nil
 }
@@ -0,0 +1,331 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/internal/bundle"
+	"github.com/benthosdev/benthos/v4/internal/config"
+	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/internal/manager"
+	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/stream"
+	"github.com/benthosdev/benthos/v4/internal/transaction"
+)
+
+const (
+	mockedInputPipe        = "benthos_test_input"
+	mockedOutputPipePrefix = "benthos_test_output_"
+)
+
+// unmockedOutputs are output types that remain in place when a stream is
+// tested. Resources are mocked at the point of their definition, and the
+// remaining types have behaviour that test cases are likely to depend on, such
+// as rejecting messages in order to exercise a fallback.
+var unmockedOutputs = map[string]struct{}{
+	"resource":      {},
+	"reject":        {},
+	"sync_response": {},
+}
+
+// StreamProvider constructs a stream from a Benthos config where the input and
+// outputs are replaced with in-memory mocks.
+type StreamProvider interface {
+	ProvideStream(environment map[string]string, mocks map[string]any) (*MockedStream, error)
+}
+
+type mockedOutput struct {
+	path  string
+	label string
+	pipe  string
+
+	batchesMut sync.Mutex
+	batches    []message.Batch
+}
+
+func (m *mockedOutput) consume(pipe <-chan message.Transaction, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for tran := range pipe {
+		m.batchesMut.Lock()
+		m.batches = append(m.batches, tran.Payload.DeepCopy())
+		m.batchesMut.Unlock()
+		_ = tran.Ack(context.Background(), nil)
+	}
+}
+
+// MockedStream is a running stream where the input has been replaced with a
+// channel that test batches are written to, and each output that does not wrap
+// other outputs has been replaced with an in-memory capture of the batches it
+// receives.
+type MockedStream struct {
+	mgr     *manager.Type
+	strm    *stream.Type
+	outputs []*mockedOutput
+
+	tranChan  chan message.Transaction
+	outputsWG sync.WaitGroup
+}
+
+// Send a batch through the stream and block until it has been acknowledged.
+// Any synchronous responses set for the batch are returned.
+func (m *MockedStream) Send(ctx context.Context, batch message.Batch) ([]message.Batch, error) {
+	store := transaction.NewResultStore()
+	transaction.AddResultStore(batch, store)
+
+	resChan := make(chan error, 1)
+	select {
+	case m.tranChan <- message.NewTransaction(batch, resChan):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case err := <-resChan:
+		if err != nil {
+			return nil, err
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return store.Get(), nil
+}
+
+// OutputBatches returns the batches received by a mocked output, identified
+// either by its label or a JSON Pointer to its position within the config.
+func (m *MockedStream) OutputBatches(target string) ([]message.Batch, error) {
+	for _, o := range m.outputs {
+		if o.path == target || (o.label != "" && o.label == target) {
+			o.batchesMut.Lock()
+			batches := o.batches
+			o.batchesMut.Unlock()
+			return batches, nil
+		}
+	}
+	return nil, fmt.Errorf("output target '%v' does not match a mocked output, only outputs that do not wrap other outputs can be targeted", target)
+}
+
+// AllOutputBatches returns the batches received by all mocked outputs.
+func (m *MockedStream) AllOutputBatches() (batches []message.Batch) {
+	for _, o := range m.outputs {
+		o.batchesMut.Lock()
+		batches = append(batches, o.batches...)
+		o.batchesMut.Unlock()
+	}
+	return
+}
+
+// Close the stream along with all resources, waiting for the mocked outputs to
+// finish.
+func (m *MockedStream) Close(ctx context.Context) error {
+	err := m.strm.Stop(ctx)
+	m.mgr.UnsetPipe(mockedInputPipe, m.tranChan)
+
+	m.mgr.TriggerStopConsuming()
+	if mErr := m.mgr.WaitForClose(ctx); mErr != nil && err == nil {
+		err = mErr
+	}
+	if err != nil {
+		return err
+	}
+	m.outputsWG.Wait()
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProvideStream attempts to construct the full stream of a Benthos config,
+// where the input is replaced with a channel and outputs are replaced with
+// in-memory captures. Mocked components are applied before the input and
+// outputs are replaced.
+func (p *ProcessorsProvider) ProvideStream(environment map[string]string, mocks map[string]any) (*MockedStream, error) {
+	root, _, err := p.readMockedConfig(p.targetPath, environment, mocks)
+	if err != nil {
+		return nil, err
+	}
+
+	confSpec := config.Spec()
+	outputs, err := mockStreamComponents(confSpec, root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mock stream components of '%v': %v", p.targetPath, err)
+	}
+
+	pConf, err := confSpec.ParsedConfigFromAny(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%v': %v", p.targetPath, err)
+	}
+
+	mgrConf, err := p.resourcesFromParsed(p.targetPath, pConf, environment)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resource inputs are never consumed as the stream input is replaced.
+	mgrConf.ResourceInputs = nil
+
+	strmConf, err := stream.FromParsed(bundle.GlobalEnvironment, pConf, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%v': %v", p.targetPath, err)
+	}
+
+	mgr, err := manager.New(mgrConf,
+		manager.OptSetLogger(p.logger),
+		manager.OptSetBloblangEnvironment(p.bloblangEnvironment()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise resources: %v", err)
+	}
+
+	m := &MockedStream{
+		mgr:      mgr,
+		outputs:  outputs,
+		tranChan: make(chan message.Transaction),
+	}
+	mgr.SetPipe(mockedInputPipe, m.tranChan)
+
+	if m.strm, err = stream.New(strmConf, mgr); err != nil {
+		mgr.TriggerCloseNow()
+		return nil, fmt.Errorf("failed to initialise stream: %v", err)
+	}
+
+	for _, o := range outputs {
+		pipe, err := mgr.GetPipe(o.pipe)
+		if err != nil {
+			// The output is defined but not used by the stream, e.g. an unused
+			// resource.
+			continue
+		}
+		m.outputsWG.Add(1)
+		go o.consume(pipe, &m.outputsWG)
+	}
+	return m, nil
+}
+
+// mockStreamComponents modifies a config in place so that the input is replaced
+// with an inproc input and each terminal output is replaced with an inproc
+// output. The replaced outputs are returned.
+func mockStreamComponents(confSpec docs.FieldSpecs, root *yaml.Node) ([]*mockedOutput, error) {
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected object, got %v", root.ShortTag())
+	}
+
+	inputNode, err := getOrCreateMapping(root, "input")
+	if err != nil {
+		return nil, err
+	}
+	replaceWithInproc(inputNode, mockedInputPipe)
+
+	// A missing output would otherwise default to stdout.
+	outputNode, err := getOrCreateMapping(root, "output")
+	if err != nil {
+		return nil, err
+	}
+	if len(outputNode.Content) == 0 {
+		outputNode.Content = []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "stdout"},
+			{Kind: yaml.MappingNode},
+		}
+	}
+
+	nodePaths := map[*yaml.Node][]string{}
+	yamlNodePaths(root, nil, nodePaths)
+
+	var outputs []*mockedOutput
+	var outputNodes []*yaml.Node
+	if err := confSpec.WalkYAML(root, bundle.GlobalEnvironment, func(c docs.WalkedYAMLComponent) error {
+		if c.ComponentType != docs.TypeOutput {
+			return nil
+		}
+		if _, exists := unmockedOutputs[c.Name]; exists {
+			return nil
+		}
+		spec, exists := bundle.GlobalEnvironment.GetDocs(c.Name, docs.TypeOutput)
+		if !exists || fieldsContainOutput(docs.FieldSpecs{spec.Config}) {
+			return nil
+		}
+		path, exists := nodePaths[c.Conf]
+		if !exists {
+			return nil
+		}
+		outputs = append(outputs, &mockedOutput{
+			path:  "/" + strings.Join(path, "/"),
+			label: c.Label,
+			pipe:  mockedOutputPipePrefix + strconv.Itoa(len(outputs)),
+		})
+		outputNodes = append(outputNodes, c.Conf)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// Nodes are replaced once the walk is complete in order to avoid modifying
+	// the tree whilst it is being walked.
+	for i, n := range outputNodes {
+		replaceWithInproc(n, outputs[i].pipe)
+	}
+	return outputs, nil
+}
+
+func getOrCreateMapping(root *yaml.Node, key string) (*yaml.Node, error) {
+	for i := 0; i < len(root.Content)-1; i += 2 {
+		if root.Content[i].Value == key {
+			node := root.Content[i+1]
+			if node.Kind == yaml.ScalarNode && node.Value == "" {
+				node.Kind = yaml.MappingNode
+				node.Tag = ""
+			}
+			if node.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("%v: expected object, got %v", key, node.ShortTag())
+			}
+			return node, nil
+		}
+	}
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, node)
+	return node, nil
+}
+
+// replaceWithInproc swaps the plugin config of a component for an inproc
+// component connected to a named pipe, retaining the label and processors.
+func replaceWithInproc(node *yaml.Node, pipe string) {
+	var newContent []*yaml.Node
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		switch node.Content[i].Value {
+		case "label", "processors":
+			newContent = append(newContent, node.Content[i], node.Content[i+1])
+		}
+	}
+	node.Content = append(newContent,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: "inproc"},
+		&yaml.Node{Kind: yaml.ScalarNode, Value: pipe},
+	)
+}
+
+func fieldsContainOutput(fields docs.FieldSpecs) bool {
+	for _, f := range fields {
+		if f.Type == docs.FieldTypeOutput || fieldsContainOutput(f.Children) {
+			return true
+		}
+	}
+	return false
+}
+
+func yamlNodePaths(node *yaml.Node, path []string, nodePaths map[*yaml.Node][]string) {
+	nodePaths[node] = path
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			childPath := append(append([]string{}, path...), node.Content[i].Value)
+			yamlNodePaths(node.Content[i+1], childPath, nodePaths)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			childPath := append(append([]string{}, path...), strconv.Itoa(i))
+			yamlNodePaths(child, childPath, nodePaths)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package test_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/cli/test"
+	dtest "github.com/benthosdev/benthos/v4/internal/config/test"
+	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+)
+
+func TestStreamProviderCases(t *testing.T) {
+	color.NoColor = true
+
+	files := map[string]string{
+		"config.yaml": `
+input:
+  label: the_input
+  generate:
+    mapping: 'root = "never used"'
+  processors:
+    - mapping: 'root = content().uppercase()'
+
+pipeline:
+  processors:
+    - sync_response: {}
+
+output:
+  switch:
+    cases:
+      - check: content().contains("FOO")
+        output:
+          label: foo_output
+          drop: {}
+      - output:
+          fallback:
+            - label: bar_primary
+              http_client:
+                url: http://localhost:1/nope
+            - label: bar_fallback
+              drop: {}
+`,
+	}
+
+	testDir, err := initTestFiles(t, files)
+	require.NoError(t, err)
+
+	provider := test.NewProcessorsProvider(filepath.Join(testDir, "config.yaml"))
+
+	tests := []struct {
+		name     string
+		conf     string
+		expected []test.CaseFailure
+	}{
+		{
+			name: "routes to switch cases",
+			conf: `
+name: routes to switch cases
+target_stream: true
+input_batches:
+  - - content: foo 1
+  - - content: bar 1
+  - - content: foo 2
+mocks:
+  bar_primary:
+    drop: {}
+output_targets:
+  - target: foo_output
+    output_batches:
+      - - content_equals: FOO 1
+      - - content_equals: FOO 2
+  - target: /output/switch/cases/1/output/fallback/0
+    output_batches:
+      - - content_equals: BAR 1
+  - target: bar_fallback
+    output_batches: []
+sync_response_batches:
+  - - content_equals: FOO 1
+  - - content_equals: BAR 1
+  - - content_equals: FOO 2
+`,
+		},
+		{
+			name: "routes to fallback",
+			conf: `
+name: routes to fallback
+target_stream: true
+input_batch:
+  - content: bar 1
+mocks:
+  bar_primary:
+    reject: 'nope'
+output_targets:
+  - target: foo_output
+    output_batches: []
+  - target: bar_fallback
+    output_batches:
+      - - content_equals: BAR 1
+          bloblang: '@fallback_error == "nope"'
+`,
+		},
+		{
+			name: "expects no sync response",
+			conf: `
+name: expects no sync response
+target_stream: true
+input_batch:
+  - content: foo 1
+sync_response_batches: []
+`,
+			expected: []test.CaseFailure{
+				{
+					Name:     "expects no sync response",
+					TestLine: 2,
+					Reason:   "sync response: unexpected batch: [FOO 1]",
+				},
+			},
+		},
+		{
+			name: "reports failures",
+			conf: `
+name: reports failures
+target_stream: true
+input_batch:
+  - content: foo 1
+mocks:
+  bar_primary:
+    drop: {}
+output_targets:
+  - target: foo_output
+    output_batches:
+      - - content_equals: BAR 1
+  - target: bar_fallback
+    output_batches:
+      - - content_equals: BAR 1
+sync_response_batches:
+  - - content_equals: FOO 1
+  - - content_equals: FOO 2
+`,
+			expected: []test.CaseFailure{
+				{
+					Name:     "reports failures",
+					TestLine: 2,
+					Reason:   "output 'foo_output': batch 0 message 0: content_equals: content mismatch\n  expected: BAR 1\n  received: FOO 1",
+				},
+				{
+					Name:     "reports failures",
+					TestLine: 2,
+					Reason:   "output 'bar_fallback': wrong batch count, expected 1, got 0",
+				},
+				{
+					Name:     "reports failures",
+					TestLine: 2,
+					Reason:   "sync response: wrong batch count, expected 2, got 1",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := docs.UnmarshalYAML([]byte(tt.conf))
+			require.NoError(t, err)
+
+			c, err := dtest.CaseFromAny(node)
+			require.NoError(t, err)
+
+			fails, err := test.ExecuteFrom(ifs.OS(), testDir, c, provider)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, fails)
+		})
+	}
+}
+
+func TestStreamProviderErrors(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+output:
+  broker:
+    outputs:
+      - drop: {}
+      - label: second
+        drop: {}
+`,
+	}
+
+	testDir, err := initTestFiles(t, files)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	provider := test.NewProcessorsProvider(filepath.Join(testDir, "config.yaml"))
+
+	for _, conf := range []string{
+		`
+name: root output is a broker
+target_stream: true
+input_batch:
+  - content: foo
+output_batches:
+  - - content_equals: foo
+`,
+		`
+name: target is a broker
+target_stream: true
+input_batch:
+  - content: foo
+output_targets:
+  - target: /output
+    output_batches:
+      - - content_equals: foo
+`,
+		`
+name: target does not exist
+target_stream: true
+input_batch:
+  - content: foo
+output_targets:
+  - target: nope
+`,
+	} {
+		node, err := docs.UnmarshalYAML([]byte(conf))
+		require.NoError(t, err)
+
+		c, err := dtest.CaseFromAny(node)
+		require.NoError(t, err)
+
+		_, err = test.ExecuteFrom(ifs.OS(), testDir, c, provider)
+		require.Error(t, err, c.Name)
+	}
+
+	c, err := dtest.CaseFromAny(map[string]any{
+		"name":          "broker outputs",
+		"target_stream": true,
+		"input_batch": []any{
+			map[string]any{"content": "foo"},
+		},
+		"output_targets": []any{
+			map[string]any{
+				"target":         "/output/broker/outputs/0",
+				"output_batches": []any{[]any{map[string]any{"content_equals": "foo"}}},
+			},
+			map[string]any{
+				"target":         "second",
+				"output_batches": []any{[]any{map[string]any{"content_equals": "foo"}}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	fails, err := test.ExecuteFrom(ifs.OS(), testDir, c, provider)
+	require.NoError(t, err)
+	assert.Empty(t, fails)
+}
@@ -31,6 +31,14 @@ type metricsCache struct {
 	mDelError   metrics.StatCounter
 	mDelSuccess metrics.StatCounter
 	mDelLatency metrics.StatTimer
+
+	mIncrError   metrics.StatCounter
+	mIncrSuccess metrics.StatCounter
+	mIncrLatency metrics.StatTimer
+
+	mScanError   metrics.StatCounter
+	mScanSuccess metrics.StatCounter
+	mScanLatency metrics.StatTimer
 }
 
 // MetricsForCache wraps a cache with a struct that adds standard metrics over
@@ -60,6 +68,14 @@ func MetricsForCache(c V1, stats metrics.Type) V1 {
 		mDelError:   cacheError.With("delete"),
 		mDelSuccess: cacheSuccess.With("delete"),
 		mDelLatency: cacheLatency.With("delete"),
+
+		mIncrError:   cacheError.With("incr"),
+		mIncrSuccess: cacheSuccess.With("incr"),
+		mIncrLatency: cacheLatency.With("incr"),
+
+		mScanError:   cacheError.With("scan"),
+		mScanSuccess: cacheSuccess.With("scan"),
+		mScanLatency: cacheLatency.With("scan"),
 	}
 }
 
@@ -79,6 +95,19 @@ func (a *metricsCache) Get(ctx context.Context, key string) ([]byte, error) {
 	return b, err
 }
 
+func (a *metricsCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	started := time.Now()
+	values, err := GetMulti(ctx, a.c, keys...)
+	a.mGetLatency.Timing(int64(time.Since(started)))
+	if err != nil {
+		a.mGetError.Incr(int64(len(keys)))
+	} else {
+		a.mGetSuccess.Incr(int64(len(values)))
+		a.mGetNotFound.Incr(int64(len(keys) - len(values)))
+	}
+	return values, err
+}
+
 func (a *metricsCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	started := time.Now()
 	err := a.c.Set(ctx, key, value, ttl)
@@ -119,6 +148,18 @@ func (a *metricsCache) Add(ctx context.Context, key string, value []byte, ttl *t
 	return err
 }
 
+func (a *metricsCache) Incr(ctx context.Context, key string, delta int64, ttl *time.Duration) (int64, error) {
+	started := time.Now()
+	n, err := Incr(ctx, a.c, key, delta, ttl)
+	a.mIncrLatency.Timing(int64(time.Since(started)))
+	if err != nil {
+		a.mIncrError.Incr(1)
+	} else {
+		a.mIncrSuccess.Incr(1)
+	}
+	return n, err
+}
+
 func (a *metricsCache) Delete(ctx context.Context, key string) error {
 	started := time.Now()
 	err := a.c.Delete(ctx, key)
@@ -131,6 +172,22 @@ func (a *metricsCache) Delete(ctx context.Context, key string) error {
 	return err
 }
 
+func (a *metricsCache) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) error) error {
+	started := time.Now()
+	err := Scan(ctx, a.c, prefix, fn)
+	a.mScanLatency.Timing(int64(time.Since(started)))
+	if err != nil {
+		a.mScanError.Incr(1)
+	} else {
+		a.mScanSuccess.Incr(1)
+	}
+	return err
+}
+
+func (a *metricsCache) ScanSupported() bool {
+	return ScanSupported(a.c)
+}
+
 func (a *metricsCache) Close(ctx context.Context) error {
 	return a.c.Close(ctx)
 }
@@ -2,10 +2,13 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/metrics"
@@ -253,3 +256,83 @@ func TestCacheAirGapDelete(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, map[string]testCacheItem{}, rl.m)
 }
+
+func TestCacheAirGapIncr(t *testing.T) {
+	ctx := context.Background()
+	rl := &closableCache{
+		m: map[string]testCacheItem{
+			"foo": {b: []byte("10")},
+			"bar": {b: []byte("nope")},
+		},
+	}
+	agrl := MetricsForCache(rl, metrics.Noop()).(CounterV1)
+
+	ttl := time.Second
+	n, err := agrl.Incr(ctx, "foo", 5, &ttl)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(15), n)
+	assert.Equal(t, testCacheItem{b: []byte("15"), ttl: &ttl}, rl.m["foo"])
+
+	n, err = agrl.Incr(ctx, "baz", -2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-2), n)
+	assert.Equal(t, "-2", string(rl.m["baz"].b))
+
+	_, err = agrl.Incr(ctx, "bar", 1, nil)
+	assert.EqualError(t, err, `value of key 'bar' is not an integer: strconv.ParseInt: parsing "nope": invalid syntax`)
+}
+
+func TestCacheAirGapGetMulti(t *testing.T) {
+	ctx := context.Background()
+	rl := &closableCache{
+		m: map[string]testCacheItem{
+			"foo": {b: []byte("1")},
+			"bar": {b: []byte("2")},
+		},
+	}
+	agrl := MetricsForCache(rl, metrics.Noop()).(GetMultiV1)
+
+	values, err := agrl.GetMulti(ctx, "foo", "bar", "baz")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("1"),
+		"bar": []byte("2"),
+	}, values)
+}
+
+func (c *closableCache) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) error) error {
+	for k, v := range c.m {
+		if strings.HasPrefix(k, prefix) {
+			if err := fn(k, v.b); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func TestCacheAirGapScan(t *testing.T) {
+	ctx := context.Background()
+	rl := &closableCache{
+		m: map[string]testCacheItem{
+			"foo1": {b: []byte("1")},
+			"foo2": {b: []byte("2")},
+			"bar1": {b: []byte("3")},
+		},
+	}
+	agrl := MetricsForCache(rl, metrics.Noop()).(ScannerV1)
+
+	values := map[string]string{}
+	require.NoError(t, agrl.Scan(ctx, "foo", func(key string, value []byte) error {
+		values[key] = string(value)
+		return nil
+	}))
+	assert.Equal(t, map[string]string{"foo1": "1", "foo2": "2"}, values)
+
+	errStop := errors.New("stop")
+	assert.Equal(t, errStop, agrl.Scan(ctx, "", func(key string, value []byte) error {
+		return errStop
+	}))
+
+	assert.Equal(t, ErrScanUnsupported, Scan(ctx, struct{ V1 }{rl}, "", nil))
+}
@@ -2,7 +2,12 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
 	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/component"
 )
 
 // TTLItem contains a value to cache along with an optional TTL.
@@ -38,3 +43,127 @@ type V1 interface {
 	// is cancelled.
 	Close(ctx context.Context) error
 }
+
+// CounterV1 is an optional extension of V1 implemented by caches that are
+// able to atomically increment integer values.
+type CounterV1 interface {
+	V1
+
+	// Incr atomically increments the integer value of a key by delta and
+	// returns the result. Keys that do not exist are treated as zero. The ttl
+	// is applied in the same way as Set, and an error is returned if the
+	// existing value is not an integer.
+	Incr(ctx context.Context, key string, delta int64, ttl *time.Duration) (int64, error)
+}
+
+// GetMultiV1 is an optional extension of V1 implemented by caches that are
+// able to obtain the values of multiple keys in as few requests as possible.
+type GetMultiV1 interface {
+	V1
+
+	// GetMulti attempts to obtain the values of multiple keys. Keys that do
+	// not exist are omitted from the result, and an error is returned if the
+	// command fails.
+	GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error)
+}
+
+// Incr increments the integer value of a key by delta and returns the result.
+// Caches that do not implement CounterV1 are incremented with IncrNonAtomic.
+func Incr(ctx context.Context, c V1, key string, delta int64, ttl *time.Duration) (int64, error) {
+	if cc, ok := c.(CounterV1); ok {
+		return cc.Incr(ctx, key, delta, ttl)
+	}
+	return IncrNonAtomic(ctx, c, key, delta, ttl)
+}
+
+// IncrNonAtomic increments the integer value of a key by delta with a Get
+// followed by a Set, and is therefore only safe when callers synchronise
+// concurrent increments of the same cache themselves.
+func IncrNonAtomic(ctx context.Context, c V1, key string, delta int64, ttl *time.Duration) (int64, error) {
+	var current int64
+	b, err := c.Get(ctx, key)
+	if err == nil {
+		if current, err = ParseCounter(key, b); err != nil {
+			return 0, err
+		}
+	} else if !errors.Is(err, component.ErrKeyNotFound) {
+		return 0, err
+	}
+	current += delta
+	if err := c.Set(ctx, key, strconv.AppendInt(nil, current, 10), ttl); err != nil {
+		return 0, err
+	}
+	return current, nil
+}
+
+// ParseCounter parses the value of a key as an integer counter.
+func ParseCounter(key string, value []byte) (int64, error) {
+	n, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("value of key '%v' is not an integer: %w", key, err)
+	}
+	return n, nil
+}
+
+// GetMulti obtains the values of multiple keys, omitting keys that do not
+// exist. Caches that do not implement GetMultiV1 are queried one key at a
+// time.
+func GetMulti(ctx context.Context, c V1, keys ...string) (map[string][]byte, error) {
+	if gc, ok := c.(GetMultiV1); ok {
+		return gc.GetMulti(ctx, keys...)
+	}
+	values := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		b, err := c.Get(ctx, k)
+		if err != nil {
+			if errors.Is(err, component.ErrKeyNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		values[k] = b
+	}
+	return values, nil
+}
+
+// ScannerV1 is an optional extension of V1 implemented by caches that are able
+// to enumerate the keys they hold.
+type ScannerV1 interface {
+	V1
+
+	// Scan calls fn with each key held by the cache that begins with prefix,
+	// along with its value. Keys are visited in no particular order, and keys
+	// modified during a scan may or may not be visited. The scan stops when fn
+	// returns an error, which is then returned by Scan.
+	Scan(ctx context.Context, prefix string, fn func(key string, value []byte) error) error
+}
+
+// ScanSupporterV1 is an optional interface implemented by caches that wrap
+// another cache, and therefore implement ScannerV1 regardless of whether the
+// wrapped cache is able to scan, in order to report whether it is.
+type ScanSupporterV1 interface {
+	ScanSupported() bool
+}
+
+// ScanSupported returns whether a cache is able to enumerate the keys it holds.
+func ScanSupported(c V1) bool {
+	if ss, ok := c.(ScanSupporterV1); ok {
+		return ss.ScanSupported()
+	}
+	_, ok := c.(ScannerV1)
+	return ok
+}
+
+// ErrScanUnsupported is returned when attempting to scan a cache that does not
+// implement ScannerV1.
+var ErrScanUnsupported = errors.New("cache does not support scanning keys")
+
+// Scan calls fn with each key held by a cache that begins with prefix, along
+// with its value. ErrScanUnsupported is returned if the cache does not
+// implement ScannerV1.
+func Scan(ctx context.Context, c V1, prefix string, fn func(key string, value []byte) error) error {
+	if sc, ok := c.(ScannerV1); ok {
+		return sc.Scan(ctx, prefix, fn)
+	}
+	return ErrScanUnsupported
+}
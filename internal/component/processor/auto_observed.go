@@ -98,7 +98,7 @@ func (a *v2ToV1Processor) ProcessBatch(ctx context.Context, msg message.Batch) (
 		if err != nil {
 			a.mError.Incr(1)
 			a.mgr.Logger().Debug("Processor failed: %v", err)
-			MarkErr(part, span, err)
+			MarkErr(part, span, componentErr(a.typeStr, a.mgr, err))
 			nextParts = append(nextParts, part)
 		}
 
@@ -142,8 +142,10 @@ type BatchProcContext struct {
 	spans []*tracing.Span
 	parts []*message.Part
 
-	mError metrics.StatCounter
-	logger log.Modular
+	typeStr string
+	mgr     component.Observability
+	mError  metrics.StatCounter
+	logger  log.Modular
 }
 
 // Context returns the underlying processor context.Context.
@@ -181,6 +183,9 @@ func (b *BatchProcContext) OnError(err error, index int, p *message.Part) {
 	if p == nil && len(b.parts) > index && index >= 0 {
 		p = b.parts[index]
 	}
+	if b.mgr != nil {
+		err = componentErr(b.typeStr, b.mgr, err)
+	}
 	MarkErr(p, span, err)
 }
 
@@ -221,15 +226,18 @@ func (a *v2BatchedToV1Processor) ProcessBatch(ctx context.Context, msg message.B
 	_, spans := tracing.WithChildSpans(a.mgr.Tracer(), a.typeStr, msg)
 
 	outputBatches, err := a.p.ProcessBatch(&BatchProcContext{
-		ctx:    ctx,
-		spans:  spans,
-		parts:  msg,
-		mError: a.mError,
-		logger: a.mgr.Logger(),
+		ctx:     ctx,
+		spans:   spans,
+		parts:   msg,
+		typeStr: a.typeStr,
+		mgr:     a.mgr,
+		mError:  a.mError,
+		logger:  a.mgr.Logger(),
 	}, msg)
 	if err != nil {
 		a.mError.Incr(int64(msg.Len()))
 		a.mgr.Logger().Debug("Processor failed: %v", err)
+		err = componentErr(a.typeStr, a.mgr, err)
 		_ = msg.Iter(func(i int, p *message.Part) error {
 			MarkErr(p, spans[i], err)
 			return nil
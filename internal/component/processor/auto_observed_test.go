@@ -89,6 +89,45 @@ func TestProcessorAirGapOneToError(t *testing.T) {
 	assert.EqualError(t, msgs[0].Get(0).ErrorGet(), "invalid character 'o' in literal null (expecting 'u')")
 }
 
+type labelledObservability struct {
+	component.Observability
+}
+
+func (l labelledObservability) Label() string {
+	return "bar"
+}
+
+func (l labelledObservability) Path() []string {
+	return []string{"pipeline", "processors", "0"}
+}
+
+func TestProcessorAirGapErrorComponent(t *testing.T) {
+	tCtx := context.Background()
+
+	agrp := NewAutoObservedProcessor("foo", &fnProcessor{
+		fn: func(c context.Context, m *message.Part) ([]*message.Part, error) {
+			return nil, errors.New("nope")
+		},
+	}, labelledObservability{component.NoopObservability()})
+
+	msgs, res := agrp.ProcessBatch(tCtx, message.QuickBatch([][]byte{[]byte("hello")}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	err := msgs[0].Get(0).ErrorGet()
+	assert.EqualError(t, err, "nope")
+	assert.Equal(t, "bar", ErrorComponent(err))
+
+	var cErr *ComponentError
+	require.ErrorAs(t, err, &cErr)
+	assert.Equal(t, "foo", cErr.Type)
+	assert.Equal(t, []string{"pipeline", "processors", "0"}, cErr.Path)
+
+	cErr.Label = ""
+	assert.Equal(t, "root.pipeline.processors.0", ErrorComponent(err))
+	assert.Equal(t, "", ErrorComponent(errors.New("nope")))
+}
+
 func TestProcessorAirGapOneToMany(t *testing.T) {
 	tCtx := context.Background()
 
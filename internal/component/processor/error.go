@@ -1,6 +1,10 @@
 package processor
 
 import (
+	"errors"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/internal/tracing"
 )
@@ -26,3 +30,70 @@ func MarkErr(part *message.Part, span *tracing.Span, err error) {
 		)
 	}
 }
+
+//------------------------------------------------------------------------------
+
+// ComponentError is an error flagged on a message by a processor, it carries
+// the type, label and path of the processor that flagged it. The error string
+// is identical to that of the underlying error.
+type ComponentError struct {
+	Type  string
+	Label string
+	Path  []string
+	Err   error
+}
+
+// Error returns the error string of the underlying error.
+func (c *ComponentError) Error() string {
+	return c.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (c *ComponentError) Unwrap() error {
+	return c.Err
+}
+
+// Component returns a string identifying the processor that flagged the error,
+// which is its label when set, otherwise its path or type.
+func (c *ComponentError) Component() string {
+	if c.Label != "" {
+		return c.Label
+	}
+	if len(c.Path) > 0 {
+		return "root." + query.SliceToDotPath(c.Path...)
+	}
+	return c.Type
+}
+
+// ErrorComponent returns a string identifying the processor that flagged an
+// error, or an empty string if the error does not carry one.
+func ErrorComponent(err error) string {
+	var cErr *ComponentError
+	if errors.As(err, &cErr) {
+		return cErr.Component()
+	}
+	return ""
+}
+
+type labelledComponent interface {
+	Label() string
+	Path() []string
+}
+
+// componentErr wraps an error with the identity of the processor that produced
+// it, errors that are already identified are returned unchanged.
+func componentErr(typeStr string, mgr component.Observability, err error) error {
+	if err == nil {
+		return nil
+	}
+	var existing *ComponentError
+	if errors.As(err, &existing) {
+		return err
+	}
+	cErr := &ComponentError{Type: typeStr, Err: err}
+	if l, ok := mgr.(labelledComponent); ok {
+		cErr.Label = l.Label()
+		cErr.Path = l.Path()
+	}
+	return cErr
+}
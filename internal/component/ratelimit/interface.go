@@ -2,6 +2,7 @@ package ratelimit
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
@@ -18,3 +19,37 @@ type V1 interface {
 	// is cancelled.
 	Close(ctx context.Context) error
 }
+
+// WeightedV1 is an optional extension of V1 implemented by rate limits that
+// are able to grant access to the rate limited resource at a cost of more than
+// one token, allowing callers to limit by bytes, batch sizes, API cost units,
+// etc.
+type WeightedV1 interface {
+	V1
+
+	// AccessN is equivalent to Access but consumes n tokens rather than one.
+	// ErrCostExceedsCapacity is returned if n exceeds the number of tokens
+	// that could ever be available within a single period.
+	AccessN(ctx context.Context, n int64) (time.Duration, error)
+}
+
+// ErrWeightedAccessUnsupported is returned when a cost of more than one token
+// is requested from a rate limit that does not implement WeightedV1.
+var ErrWeightedAccessUnsupported = errors.New("rate limit does not support a cost of more than one token")
+
+// ErrCostExceedsCapacity is returned by weighted rate limits when the requested
+// cost exceeds the number of tokens that could ever be available within a
+// single period, and therefore could never be granted.
+var ErrCostExceedsCapacity = errors.New("cost exceeds the capacity of the rate limit")
+
+// AccessN attempts to access a rate limit at a cost of n tokens. Rate limits
+// that do not implement WeightedV1 can only be accessed at a cost of one.
+func AccessN(ctx context.Context, r V1, n int64) (time.Duration, error) {
+	if w, ok := r.(WeightedV1); ok {
+		return w.AccessN(ctx, n)
+	}
+	if n > 1 {
+		return 0, ErrWeightedAccessUnsupported
+	}
+	return r.Access(ctx)
+}
@@ -28,8 +28,12 @@ func MetricsForRateLimit(r V1, stats metrics.Type) V1 {
 }
 
 func (r *metricsRateLimit) Access(ctx context.Context) (time.Duration, error) {
+	return r.AccessN(ctx, 1)
+}
+
+func (r *metricsRateLimit) AccessN(ctx context.Context, n int64) (time.Duration, error) {
 	r.mChecked.Incr(1)
-	tout, err := r.r.Access(ctx)
+	tout, err := AccessN(ctx, r.r, n)
 	if err != nil {
 		r.mErr.Incr(1)
 	} else if tout > 0 {
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/component/metrics"
 )
@@ -31,3 +32,33 @@ func TestRateLimitAirGapShutdown(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, rl.closed)
 }
+
+type weightedRateLimit struct {
+	closableRateLimit
+	tokens int64
+}
+
+func (w *weightedRateLimit) AccessN(ctx context.Context, n int64) (time.Duration, error) {
+	w.tokens += n
+	return 0, nil
+}
+
+func TestRateLimitAirGapAccessN(t *testing.T) {
+	ctx := context.Background()
+
+	wrl := &weightedRateLimit{}
+	agrl := MetricsForRateLimit(wrl, metrics.Noop())
+
+	_, err := AccessN(ctx, agrl, 5)
+	require.NoError(t, err)
+	_, err = agrl.Access(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), wrl.tokens)
+
+	agrl = MetricsForRateLimit(&closableRateLimit{}, metrics.Noop())
+
+	_, err = AccessN(ctx, agrl, 1)
+	require.NoError(t, err)
+	_, err = AccessN(ctx, agrl, 2)
+	require.ErrorIs(t, err, ErrWeightedAccessUnsupported)
+}
@@ -9,20 +9,44 @@ const (
 	fieldCaseEnvironment      = "environment"
 	fieldCaseTargetProcessors = "target_processors"
 	fieldCaseTargetMapping    = "target_mapping"
+	fieldCaseTargetStream     = "target_stream"
 	fieldCaseMocks            = "mocks"
 	fieldCaseInputBatch       = "input_batch"
 	fieldCaseInputBatches     = "input_batches"
+	fieldCaseInputGenerator   = "input_generator"
 	fieldCaseOutputBatches    = "output_batches"
+	fieldCaseOutputInvariants = "output_invariants"
+	fieldCaseOutputTargets    = "output_targets"
+	fieldCaseSyncResponses    = "sync_response_batches"
+
+	fieldOutputTargetTarget  = "target"
+	fieldOutputTargetBatches = "output_batches"
 )
 
+// OutputTarget describes the batches expected to reach a particular output of
+// a stream when a test case targets the full stream.
+type OutputTarget struct {
+	Target        string
+	OutputBatches [][]OutputConditionsMap
+}
+
 type Case struct {
 	Name             string
 	Environment      map[string]string
 	TargetProcessors string
 	TargetMapping    string
+	TargetStream     bool
 	Mocks            map[string]any
 	InputBatches     [][]InputConfig
+	InputGenerator   *InputGeneratorConfig
 	OutputBatches    [][]OutputConditionsMap
+	OutputInvariants OutputConditionsMap
+	OutputTargets    []OutputTarget
+	SyncResponses    [][]OutputConditionsMap
+
+	// HasSyncResponses is true when the sync response batches of the case are
+	// set, including when they are set to an empty list.
+	HasSyncResponses bool
 
 	line int
 }
@@ -31,6 +55,31 @@ func (c *Case) Line() int {
 	return c.line
 }
 
+// UpdateSnapshots configures the snapshot conditions of the case to rewrite
+// their snapshot files from the messages being checked rather than compare
+// against them.
+func (c *Case) UpdateSnapshots() {
+	updateConds := func(m OutputConditionsMap) {
+		if s, ok := m[fieldOutputSnapshot].(SnapshotCondition); ok {
+			s.Update = true
+			m[fieldOutputSnapshot] = s
+		}
+	}
+	updateBatches := func(batches [][]OutputConditionsMap) {
+		for _, b := range batches {
+			for _, m := range b {
+				updateConds(m)
+			}
+		}
+	}
+	updateBatches(c.OutputBatches)
+	updateBatches(c.SyncResponses)
+	for _, t := range c.OutputTargets {
+		updateBatches(t.OutputBatches)
+	}
+	updateConds(c.OutputInvariants)
+}
+
 func caseFields() docs.FieldSpecs {
 	return docs.FieldSpecs{
 		docs.FieldString(fieldCaseName, "The name of the test, this should be unique and give a rough indication of what behaviour is being tested."),
@@ -48,6 +97,9 @@ It is also possible to target processors in a separate file by prefixing the tar
 		docs.FieldString(fieldCaseTargetMapping,
 			"A file path relative to the test definition path of a Bloblang file to execute as an alternative to testing processors with the `target_processors` field. This allows you to define unit tests for Bloblang mappings directly.",
 		).HasDefault(""),
+		docs.FieldBool(fieldCaseTargetStream,
+			"Execute the entire stream of the config file as an alternative to testing processors with the `target_processors` field. The input of the stream is replaced with the batches of the test case, and every output that does not wrap other outputs is replaced with an in-memory capture that can be checked with the `output_targets` field. When the root output of the config is a single output the field `output_batches` can be used instead.",
+		).HasDefault(false),
 		docs.FieldAnything(fieldCaseMocks,
 			"An optional map of processors to mock. Keys should contain either a label or a JSON pointer of a processor that should be mocked. Values should contain a processor definition, which will replace the mocked processor. Most of the time you'll want to use a [`mapping` processor][processors.mapping] here, and use it to create a result that emulates the target processor.",
 			map[string]any{
@@ -65,9 +117,38 @@ It is also possible to target processors in a separate file by prefixing the tar
 			Array().Optional().WithChildren(inputFields()...),
 		docs.FieldObject(fieldCaseInputBatches, "Define a series of batches of messages to feed into your test, specify either an `input_batch` or a series of `input_batches`.").
 			ArrayOfArrays().Optional().WithChildren(inputFields()...),
+		docs.FieldObject(fieldCaseInputGenerator, "Generate a series of messages to feed into your test with a Bloblang mapping, which are fed in after any messages defined with `input_batch` or `input_batches`. Since the exact contents of generated messages are not usually known in advance they are best checked with the `output_invariants` field.").
+			Optional().WithChildren(inputGeneratorFields()...),
 		docs.FieldObject(fieldCaseOutputBatches, "List of output batches.").
 			ArrayOfArrays().Optional().WithChildren(outputFields()...),
+		docs.FieldObject(fieldCaseOutputInvariants, "A set of conditions that every output message must satisfy regardless of which batch it belongs to. When this field is set the number of output batches is only checked if `output_batches` is also set.").
+			Optional().WithChildren(outputFields()...),
+		docs.FieldObject(fieldCaseOutputTargets, "When `target_stream` is `true` this field lists the outputs of the stream to check along with the batches that are expected to reach them.").
+			Array().Optional().WithChildren(
+			docs.FieldString(fieldOutputTargetTarget, "Either the label or a [JSON Pointer][json-pointer] of an output within the config file. The output must not be a wrapper of other outputs such as a `switch` or `broker`, instead target the outputs that it wraps.", "/output/switch/cases/0/output", "foo_output"),
+			docs.FieldObject(fieldOutputTargetBatches, "List of batches expected to reach the output.").
+				ArrayOfArrays().Optional().WithChildren(outputFields()...),
+		),
+		docs.FieldObject(fieldCaseSyncResponses, "When `target_stream` is `true` this field lists the batches expected to be returned as a synchronous response to the input, as would be set by a [`sync_response` output][outputs.sync_response] or processor.").
+			ArrayOfArrays().Optional().HasDefault(nil).WithChildren(outputFields()...),
+	}
+}
+
+func outputBatchesFromParsed(pConf *docs.ParsedConfig, field string) (batches [][]OutputConditionsMap, err error) {
+	var oBListOfList [][]*docs.ParsedConfig
+	if oBListOfList, err = pConf.FieldObjectListOfLists(field); err != nil {
+		return
 	}
+	for _, ol := range oBListOfList {
+		tmpList := make([]OutputConditionsMap, len(ol))
+		for i, il := range ol {
+			if tmpList[i], err = OutputConditionsFromParsed(il); err != nil {
+				return
+			}
+		}
+		batches = append(batches, tmpList)
+	}
+	return
 }
 
 func CaseFromAny(v any) (Case, error) {
@@ -94,6 +175,9 @@ func CaseFromParsed(pConf *docs.ParsedConfig) (c Case, err error) {
 	if c.TargetMapping, err = pConf.FieldString(fieldCaseTargetMapping); err != nil {
 		return
 	}
+	if c.TargetStream, err = pConf.FieldBool(fieldCaseTargetStream); err != nil {
+		return
+	}
 
 	if pConf.Contains(fieldCaseMocks) {
 		var tmpMocksAny map[string]*docs.ParsedConfig
@@ -142,19 +226,51 @@ func CaseFromParsed(pConf *docs.ParsedConfig) (c Case, err error) {
 		}
 	}
 
+	if pConf.Contains(fieldCaseInputGenerator) {
+		var genConf InputGeneratorConfig
+		if genConf, err = InputGeneratorFromParsed(pConf.Namespace(fieldCaseInputGenerator)); err != nil {
+			return
+		}
+		c.InputGenerator = &genConf
+	}
+
 	if pConf.Contains(fieldCaseOutputBatches) {
-		var oBListOfList [][]*docs.ParsedConfig
-		if oBListOfList, err = pConf.FieldObjectListOfLists(fieldCaseOutputBatches); err != nil {
+		if c.OutputBatches, err = outputBatchesFromParsed(pConf, fieldCaseOutputBatches); err != nil {
 			return
 		}
-		for _, ol := range oBListOfList {
-			tmpList := make([]OutputConditionsMap, len(ol))
-			for i, il := range ol {
-				if tmpList[i], err = OutputConditionsFromParsed(il); err != nil {
+	}
+
+	if pConf.Contains(fieldCaseOutputInvariants) {
+		if c.OutputInvariants, err = OutputConditionsFromParsed(pConf.Namespace(fieldCaseOutputInvariants)); err != nil {
+			return
+		}
+	}
+
+	if pConf.Contains(fieldCaseOutputTargets) {
+		var oTList []*docs.ParsedConfig
+		if oTList, err = pConf.FieldObjectList(fieldCaseOutputTargets); err != nil {
+			return
+		}
+		for _, otp := range oTList {
+			var target OutputTarget
+			if target.Target, err = otp.FieldString(fieldOutputTargetTarget); err != nil {
+				return
+			}
+			if otp.Contains(fieldOutputTargetBatches) {
+				if target.OutputBatches, err = outputBatchesFromParsed(otp, fieldOutputTargetBatches); err != nil {
 					return
 				}
 			}
-			c.OutputBatches = append(c.OutputBatches, tmpList)
+			c.OutputTargets = append(c.OutputTargets, target)
+		}
+	}
+
+	// The sync response batches default to null rather than an empty list so
+	// that an empty list can be used to check that no sync responses occur.
+	if sr, _ := pConf.FieldAny(fieldCaseSyncResponses); sr != nil {
+		c.HasSyncResponses = true
+		if c.SyncResponses, err = outputBatchesFromParsed(pConf, fieldCaseSyncResponses); err != nil {
+			return
 		}
 	}
 	return
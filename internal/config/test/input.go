@@ -1,10 +1,19 @@
 package test
 
 import (
+	crand "crypto/rand"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/fs"
+	"math/rand"
 	"path/filepath"
+	"sync"
+	"time"
 
+	"github.com/go-faker/faker/v4"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang"
 	"github.com/benthosdev/benthos/v4/internal/docs"
 	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
 	"github.com/benthosdev/benthos/v4/internal/message"
@@ -15,6 +24,11 @@ const (
 	fieldInputJSONContent = "json_content"
 	fieldInputFileContent = "file_content"
 	fieldInputMetadata    = "metadata"
+
+	fieldInputGeneratorMapping   = "mapping"
+	fieldInputGeneratorCount     = "count"
+	fieldInputGeneratorBatchSize = "batch_size"
+	fieldInputGeneratorSeed      = "seed"
 )
 
 func inputFields() docs.FieldSpecs {
@@ -91,3 +105,105 @@ func InputFromParsed(pConf *docs.ParsedConfig) (conf InputConfig, err error) {
 	}
 	return
 }
+
+func inputGeneratorFields() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldBloblang(fieldInputGeneratorMapping, "A [Bloblang mapping][bloblang] that is executed once for each generated message in order to create its content and metadata. The mapping is executed against a document containing the fields `index`, which is the index of the message being generated, and `seed`, which is the configured seed.",
+			`root.name = fake("name")`,
+			`root.age = random_int(seed: this.seed, max: 120)`,
+		),
+		docs.FieldInt(fieldInputGeneratorCount, "The number of messages to generate.").HasDefault(100),
+		docs.FieldInt(fieldInputGeneratorBatchSize, "The number of generated messages to feed into the test within each batch.").HasDefault(1),
+		docs.FieldInt(fieldInputGeneratorSeed, "A seed for the random functions used by the mapping such as `fake`, which results in the same messages being generated each time the test is run. Functions that accept a seed argument such as `random_int` should be given the value of `this.seed`.").HasDefault(0),
+	}
+}
+
+// InputGeneratorConfig describes a series of input messages that are
+// generated with a Bloblang mapping.
+type InputGeneratorConfig struct {
+	Mapping   string
+	Count     int
+	BatchSize int
+	Seed      int64
+}
+
+// generatorMut serialises the generation of inputs, as the random sources of
+// faker are global and are therefore seeded for the duration of a generation.
+var generatorMut sync.Mutex
+
+// Generate executes the generator mapping in order to create batches of
+// messages. The mapping is parsed with the provided environment, and relative
+// imports are resolved from a directory of a filesystem. Generation is
+// deterministic for a given seed as long as the mapping only uses functions
+// that are seeded.
+func (g InputGeneratorConfig) Generate(env *bloblang.Environment, fs fs.FS, dir string) ([]message.Batch, error) {
+	if g.BatchSize <= 0 {
+		return nil, errors.New("batch_size must be greater than zero")
+	}
+
+	exec, err := env.WithCustomImporter(func(name string) ([]byte, error) {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		return ifs.ReadFile(fs, name)
+	}).NewMapping(g.Mapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mapping: %w", err)
+	}
+
+	generatorMut.Lock()
+	defer generatorMut.Unlock()
+
+	rng := rand.New(rand.NewSource(g.Seed))
+	faker.SetRandomSource(faker.NewSafeSource(rand.NewSource(g.Seed)))
+	faker.SetCryptoSource(rng)
+	defer func() {
+		faker.SetRandomSource(faker.NewSafeSource(rand.NewSource(time.Now().UnixNano())))
+		faker.SetCryptoSource(crand.Reader)
+	}()
+
+	var batches []message.Batch
+	var batch message.Batch
+	for i := 0; i < g.Count; i++ {
+		ref := message.NewPart(nil)
+		ref.SetStructuredMut(map[string]any{
+			"index": int64(i),
+			"seed":  g.Seed,
+		})
+
+		part, err := exec.MapPart(0, message.Batch{ref})
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate message %v: %w", i, err)
+		}
+		if part == nil {
+			continue
+		}
+
+		if batch = append(batch, part); len(batch) >= g.BatchSize {
+			batches = append(batches, batch)
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches, nil
+}
+
+func InputGeneratorFromParsed(pConf *docs.ParsedConfig) (conf InputGeneratorConfig, err error) {
+	if conf.Mapping, err = pConf.FieldString(fieldInputGeneratorMapping); err != nil {
+		return
+	}
+	if conf.Count, err = pConf.FieldInt(fieldInputGeneratorCount); err != nil {
+		return
+	}
+	if conf.BatchSize, err = pConf.FieldInt(fieldInputGeneratorBatchSize); err != nil {
+		return
+	}
+	var seed int
+	if seed, err = pConf.FieldInt(fieldInputGeneratorSeed); err != nil {
+		return
+	}
+	conf.Seed = int64(seed)
+	return
+}
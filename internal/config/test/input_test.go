@@ -0,0 +1,87 @@
+package test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang"
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+func TestInputGenerator(t *testing.T) {
+	gen := InputGeneratorConfig{
+		Mapping: `
+root.id = this.index
+root.n = random_int(seed: this.seed, max: 1000)
+root = if this.index == 3 { deleted() }
+`,
+		Count:     7,
+		BatchSize: 2,
+		Seed:      10,
+	}
+
+	allContents := func(batches []message.Batch) (contents [][]string) {
+		for _, b := range batches {
+			var bContents []string
+			for _, p := range b {
+				bContents = append(bContents, string(p.AsBytes()))
+			}
+			contents = append(contents, bContents)
+		}
+		return
+	}
+
+	batches, err := gen.Generate(bloblang.GlobalEnvironment(), ifs.OS(), "")
+	require.NoError(t, err)
+	require.Len(t, batches, 3)
+	assert.Len(t, batches[0], 2)
+	assert.Len(t, batches[1], 2)
+	assert.Len(t, batches[2], 2)
+
+	v, err := batches[1][1].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), v.(map[string]any)["id"])
+
+	// Generating again with the same seed results in identical messages.
+	again, err := gen.Generate(bloblang.GlobalEnvironment(), ifs.OS(), "")
+	require.NoError(t, err)
+	assert.Equal(t, allContents(batches), allContents(again))
+
+	gen.Seed = 11
+	different, err := gen.Generate(bloblang.GlobalEnvironment(), ifs.OS(), "")
+	require.NoError(t, err)
+	assert.NotEqual(t, allContents(batches), allContents(different))
+
+	gen.BatchSize = 0
+	_, err = gen.Generate(bloblang.GlobalEnvironment(), ifs.OS(), "")
+	require.Error(t, err)
+}
+
+func TestInputGeneratorImports(t *testing.T) {
+	gen := InputGeneratorConfig{
+		Mapping: `
+import "./lib.blobl"
+root = this.apply("doc")
+`,
+		Count:     2,
+		BatchSize: 1,
+	}
+
+	testFS := fstest.MapFS{
+		"tests/lib.blobl": &fstest.MapFile{
+			Data: []byte(`map doc {
+  root.id = this.index
+}`),
+		},
+	}
+
+	batches, err := gen.Generate(bloblang.GlobalEnvironment(), testFS, "tests")
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+	assert.Equal(t, `{"id":0}`, string(batches[0][0].AsBytes()))
+	assert.Equal(t, `{"id":1}`, string(batches[1][0].AsBytes()))
+}
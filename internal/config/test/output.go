@@ -36,6 +36,7 @@ const (
 	fieldOutputFileJSONContains = "file_json_contains"
 	fieldOutputJSONEquals       = "json_equals"
 	fieldOutputJSONContains     = "json_contains"
+	fieldOutputSnapshot         = "snapshot"
 )
 
 func outputFields() docs.FieldSpecs {
@@ -65,6 +66,9 @@ func outputFields() docs.FieldSpecs {
 		docs.FieldString(fieldOutputFileJSONContains, "Checks that both the message and the file contents are valid JSON documents, and that the message is a superset of the condition. Will ignore formatting and ordering differences. The path of the file should be relative to the path of the test file.",
 			"./foo/bar.json",
 		).Optional(),
+		docs.FieldString(fieldOutputSnapshot, "Checks the contents and metadata of a message against a snapshot file, which is created from the message when it does not yet exist. Snapshot files can be rewritten from the current messages by running `benthos test --update-snapshots`. The path of the file should be relative to the path of the test file.",
+			"./snapshots/foo.json",
+		).Optional(),
 	}
 }
 
@@ -180,6 +184,14 @@ func OutputConditionsFromParsed(pConf *docs.ParsedConfig) (m OutputConditionsMap
 		}
 		m[fieldOutputJSONContains] = ContentJSONContainsCondition(tmpStr)
 	}
+
+	if pConf.Contains(fieldOutputSnapshot) {
+		var tmpStr string
+		if tmpStr, err = pConf.FieldString(fieldOutputSnapshot); err != nil {
+			return
+		}
+		m[fieldOutputSnapshot] = SnapshotCondition{Path: tmpStr}
+	}
 	return
 }
 
@@ -292,6 +304,72 @@ func (c FileJSONContainsCondition) Check(fs fs.FS, dir string, p *message.Part)
 	return comparison.Check(fs, dir, p)
 }
 
+// SnapshotCondition checks the contents and metadata of a message against a
+// snapshot file. When the file does not exist, or when Update is true, the
+// file is written from the message instead.
+type SnapshotCondition struct {
+	Path   string
+	Update bool
+}
+
+func snapshotFromPart(p *message.Part) ([]byte, error) {
+	snapshot := map[string]any{}
+	if structured, err := p.AsStructured(); err == nil {
+		snapshot[fieldInputJSONContent] = structured
+	} else {
+		snapshot[fieldInputContent] = string(p.AsBytes())
+	}
+
+	meta := map[string]any{}
+	_ = p.MetaIterMut(func(k string, v any) error {
+		meta[k] = v
+		return nil
+	})
+	snapshot[fieldInputMetadata] = meta
+
+	snapshotBytes, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(snapshotBytes, '\n'), nil
+}
+
+func (s SnapshotCondition) Check(f fs.FS, dir string, p *message.Part) error {
+	relPath := filepath.Join(dir, s.Path)
+
+	actual, err := snapshotFromPart(p)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	expected, err := ifs.ReadFile(f, relPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read snapshot file: %w", err)
+	}
+	if err != nil || s.Update {
+		if writeErr := writeSnapshot(f, relPath, actual); writeErr != nil {
+			return fmt.Errorf("failed to write snapshot file: %w", writeErr)
+		}
+		return nil
+	}
+
+	jdopts := jsondiff.DefaultConsoleOptions()
+	diff, explanation := jsondiff.Compare(actual, expected, &jdopts)
+	if diff != jsondiff.FullMatch {
+		return fmt.Errorf("snapshot mismatch, run with --update-snapshots to accept the new snapshot\n%v", explanation)
+	}
+	return nil
+}
+
+func writeSnapshot(f fs.FS, path string, data []byte) error {
+	if ef, ok := f.(ifs.FS); ok {
+		if err := ef.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+	}
+	return ifs.WriteFile(f, path, data, 0o644)
+}
+
 type MetadataEqualsCondition map[string]any
 
 func (m MetadataEqualsCondition) Check(fs fs.FS, dir string, p *message.Part) error {
@@ -579,3 +579,60 @@ func TestFileJSONContainsCondition(t *testing.T) {
 		})
 	}
 }
+
+func TestSnapshotCondition(t *testing.T) {
+	color.NoColor = true
+
+	tmpDir := t.TempDir()
+	snapshotPath := filepath.Join(tmpDir, "snapshots", "foo.json")
+
+	newPart := func(content, metaValue string) *message.Part {
+		p := message.NewPart([]byte(content))
+		p.MetaSetMut("foo", metaValue)
+		return p
+	}
+
+	// Snapshots are created when they don't exist.
+	cond := SnapshotCondition{Path: "./snapshots/foo.json"}
+	require.NoError(t, cond.Check(ifs.OS(), tmpDir, newPart(`{"id":"a","n":1}`, "bar")))
+
+	snapshotBytes, err := os.ReadFile(snapshotPath)
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "json_content": {
+    "id": "a",
+    "n": 1
+  },
+  "metadata": {
+    "foo": "bar"
+  }
+}
+`, string(snapshotBytes))
+
+	require.NoError(t, cond.Check(ifs.OS(), tmpDir, newPart(`{"n":1,"id":"a"}`, "bar")))
+
+	err = cond.Check(ifs.OS(), tmpDir, newPart(`{"id":"b","n":1}`, "bar"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "snapshot mismatch")
+
+	err = cond.Check(ifs.OS(), tmpDir, newPart(`{"id":"a","n":1}`, "baz"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "snapshot mismatch")
+
+	// Update mode rewrites the snapshot.
+	cond.Update = true
+	require.NoError(t, cond.Check(ifs.OS(), tmpDir, newPart(`not json`, "baz")))
+
+	snapshotBytes, err = os.ReadFile(snapshotPath)
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "content": "not json",
+  "metadata": {
+    "foo": "baz"
+  }
+}
+`, string(snapshotBytes))
+
+	cond.Update = false
+	require.NoError(t, cond.Check(ifs.OS(), tmpDir, newPart(`not json`, "baz")))
+}
@@ -1,6 +1,10 @@
 package docs
 
 import (
+	"errors"
+
+	ibloblang "github.com/benthosdev/benthos/v4/internal/bloblang"
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/public/bloblang"
 )
 
@@ -14,9 +18,15 @@ func LintBloblangMapping(ctx LintContext, line, col int, v any) []Lint {
 	if str == "" {
 		return nil
 	}
-	_, err := ctx.conf.BloblangEnv.Parse(str)
+	env := ctx.conf.BloblangEnv
+	if u, ok := env.XUnwrapper().(interface {
+		Unwrap() *ibloblang.Environment
+	}); ok {
+		env = bloblang.XWrapEnvironment(u.Unwrap().WithTypeChecking())
+	}
+	exec, err := env.Parse(str)
 	if err == nil {
-		return nil
+		return lintBloblangTypes(ctx, line, col, exec)
 	}
 	if mErr, ok := err.(*bloblang.ParseError); ok {
 		lint := NewLintError(line+mErr.Line-1, LintBadBloblang, mErr)
@@ -26,6 +36,30 @@ func LintBloblangMapping(ctx LintContext, line, col int, v any) []Lint {
 	return []Lint{NewLintError(line, LintBadBloblang, err)}
 }
 
+// lintBloblangTypes reports the problems found by statically checking the types
+// of a parsed mapping, where unreachable match cases are only warnings as they
+// don't result in failed executions.
+func lintBloblangTypes(ctx LintContext, line, col int, exec *bloblang.Executor) []Lint {
+	u, ok := exec.XUnwrapper().(interface {
+		Unwrap() *mapping.Executor
+	})
+	if !ok {
+		return nil
+	}
+	var lints []Lint
+	for _, tErr := range u.Unwrap().CheckTypes(ctx.conf.BloblangInputSchema) {
+		var lint Lint
+		if tErr.Unreachable {
+			lint = NewLintWarning(line+tErr.Line-1, LintBadBloblang, tErr.Message)
+		} else {
+			lint = NewLintError(line+tErr.Line-1, LintBadBloblang, errors.New(tErr.Message))
+		}
+		lint.Column = col + tErr.Column
+		lints = append(lints, lint)
+	}
+	return lints
+}
+
 // LintBloblangField is function for linting a config field expected to be an
 // interpolation string.
 func LintBloblangField(ctx LintContext, line, col int, v any) []Lint {
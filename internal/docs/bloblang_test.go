@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/docs"
 )
@@ -41,6 +42,38 @@ func TestLintBloblangMapping(t *testing.T) {
 				},
 			},
 		},
+		"mapping type error": {
+			mapping: `root.foo = "bar"
+root.bar = 10.uppercase()`,
+			line: 2,
+			col:  4,
+			wantLints: []docs.Lint{
+				{
+					Line:   3,
+					Column: 19,
+					Level:  docs.LintError,
+					Type:   docs.LintBadBloblang,
+					What:   `method uppercase: expected string or bytes value, got number`,
+				},
+			},
+		},
+		"unreachable match case": {
+			mapping: `root.foo = match "bar" {
+  _ => "baz"
+  "bar" => "buz"
+}`,
+			line: 2,
+			col:  4,
+			wantLints: []docs.Lint{
+				{
+					Line:   4,
+					Column: 7,
+					Level:  docs.LintWarning,
+					Type:   docs.LintBadBloblang,
+					What:   `match case is unreachable as a previous case always matches`,
+				},
+			},
+		},
 	}
 
 	ctx := docs.NewLintContext(docs.NewLintConfig(bundle.GlobalEnvironment))
@@ -53,6 +86,30 @@ func TestLintBloblangMapping(t *testing.T) {
 	}
 }
 
+func TestLintBloblangMappingInputSchema(t *testing.T) {
+	schema, err := query.NewTypeSchemaFromJSONSchema([]byte(`{
+  "type": "object",
+  "properties": { "id": { "type": "integer" } },
+  "required": [ "id" ]
+}`))
+	require.NoError(t, err)
+
+	conf := docs.NewLintConfig(bundle.GlobalEnvironment)
+	conf.BloblangInputSchema = schema
+	ctx := docs.NewLintContext(conf)
+
+	require.Empty(t, docs.LintBloblangMapping(ctx, 0, 0, `root.id = this.id + 1`))
+	require.EqualValues(t, []docs.Lint{
+		{
+			Line:   0,
+			Column: 19,
+			Level:  docs.LintError,
+			Type:   docs.LintBadBloblang,
+			What:   `method trim: expected string or bytes value, got number`,
+		},
+	}, docs.LintBloblangMapping(ctx, 0, 0, `root.id = this.id.trim()`))
+}
+
 func TestLintBloblangField(t *testing.T) {
 	type Test struct {
 		mapping   string
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
 	"github.com/benthosdev/benthos/v4/internal/value"
 	"github.com/benthosdev/benthos/v4/public/bloblang"
 )
@@ -675,6 +676,10 @@ type LintConfig struct {
 	// Provides an isolated context for Bloblang parsing.
 	BloblangEnv *bloblang.Environment
 
+	// Describes the input documents of Bloblang mappings for the purpose of
+	// type checking them, when nil the input is treated as unknown.
+	BloblangInputSchema *query.TypeSchema
+
 	// Reject any deprecated components or fields as linting errors.
 	RejectDeprecated bool
 
@@ -696,6 +701,10 @@ type LintContext struct {
 	// A map of label names to the line they were defined at.
 	labelsToLine map[string]int
 
+	// The column of the key of the field being linted, or zero if the field is
+	// not a key of an object.
+	keyColumn int
+
 	conf LintConfig
 }
 
@@ -493,12 +493,21 @@ func customLintFromYAML(ctx LintContext, spec FieldSpec, node *yaml.Node) []Lint
 		// that we'll capture this type error elsewhere.
 		return []Lint{}
 	}
-	line := node.Line
+	line, col := node.Line, node.Column
 	if node.Style == yaml.LiteralStyle {
+		// The contents of a literal block begin on the line following the
+		// indicator, and are indented relative to the key of the field. The
+		// indentation of the contents isn't retained by the parsed node and
+		// so the common indentation of two spaces is assumed. Since the lines
+		// of a block share the same indentation the column provided is that
+		// which precedes the contents, as is the case with quoted strings.
 		line++
+		if ctx.keyColumn > 0 {
+			col = ctx.keyColumn + 1
+		}
 	}
 
-	lints := lintFn(ctx, line, node.Column, fieldValue)
+	lints := lintFn(ctx, line, col, fieldValue)
 	return lints
 }
 
@@ -692,7 +701,9 @@ func (f FieldSpecs) LintYAML(ctx LintContext, node *yaml.Node) []Lint {
 				continue
 			}
 			lints = append(lints, lintYAMLFromOmit(f, spec, walkNode, walkNode.Content[i+1])...)
-			lints = append(lints, spec.LintYAML(ctx, walkNode.Content[i+1])...)
+			fieldCtx := ctx
+			fieldCtx.keyColumn = walkNode.Content[i].Column
+			lints = append(lints, spec.LintYAML(fieldCtx, walkNode.Content[i+1])...)
 			delete(specNamesMissing, walkNode.Content[i].Value)
 		}
 	}
@@ -1164,6 +1164,27 @@ func TestYAMLLinting(t *testing.T) {
 				docs.NewLintError(1, docs.LintMissing, errors.New("field baz is required")),
 			},
 		},
+		{
+			name: "bloblang type error in literal block",
+			inputSpec: docs.FieldObject("foo", "").WithChildren(
+				docs.FieldObject("bar", "").WithChildren(
+					docs.FieldBloblang("mapping", ""),
+				),
+			),
+			inputConf: `bar:
+  mapping: |
+    root.a = "a"
+    root.b = this.x.length() + "a"`,
+			res: []docs.Lint{
+				{
+					Line:   4,
+					Column: 14,
+					Level:  docs.LintError,
+					Type:   docs.LintBadBloblang,
+					What:   "cannot add types number and string",
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
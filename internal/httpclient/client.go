@@ -3,6 +3,7 @@ package httpclient
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
@@ -14,8 +15,11 @@ import (
 	"time"
 
 	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/ratelimit"
 	"github.com/benthosdev/benthos/v4/internal/old/util/throttle"
 	"github.com/benthosdev/benthos/v4/internal/tracing/v2"
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
@@ -30,6 +34,7 @@ type Client struct {
 
 	// Request execution and retry logic
 	rateLimit     string
+	rateLimitCost *bloblang.Executor
 	numRetries    int
 	retryThrottle *throttle.Type
 	backoffOn     map[int]struct{}
@@ -129,6 +134,7 @@ func NewClientFromOldConfig(conf OldConfig, mgr *service.Resources, opts ...Requ
 		if !h.mgr.HasRateLimit(h.rateLimit) {
 			return nil, fmt.Errorf("rate limit resource '%v' was not found", h.rateLimit)
 		}
+		h.rateLimitCost = conf.RateLimitCost
 	}
 
 	h.numRetries = conf.NumRetries
@@ -165,18 +171,47 @@ func (h *Client) incrCode(code int) {
 	h.codesMut.Unlock()
 }
 
-func (h *Client) waitForAccess(ctx context.Context) bool {
-	if h.rateLimit == "" {
-		return true
+// accessCost returns the number of rate limit tokens that sending a batch costs.
+func (h *Client) accessCost(sendMsg service.MessageBatch) (int64, error) {
+	if h.rateLimitCost == nil || len(sendMsg) == 0 {
+		return 1, nil
+	}
+	v, err := sendMsg.BloblangQueryValue(0, h.rateLimitCost)
+	if err != nil {
+		return 0, fmt.Errorf("rate limit cost mapping failed: %w", err)
+	}
+	cost, err := value.IGetInt(v)
+	if err != nil {
+		return 0, fmt.Errorf("rate limit cost mapping failed: %w", err)
+	}
+	if cost < 0 {
+		return 0, fmt.Errorf("rate limit cost mapping resulted in a negative cost: %v", cost)
+	}
+	return cost, nil
+}
+
+// waitForAccess blocks until the rate limit grants access at a given cost,
+// returning an error if the context is cancelled or the cost can never be
+// granted.
+func (h *Client) waitForAccess(ctx context.Context, cost int64) error {
+	if h.rateLimit == "" || cost == 0 {
+		return nil
 	}
 	for {
 		var period time.Duration
 		var err error
 		if rerr := h.mgr.AccessRateLimit(ctx, h.rateLimit, func(rl service.RateLimit) {
-			period, err = rl.Access(ctx)
+			if wrl, ok := rl.(service.WeightedRateLimit); ok {
+				period, err = wrl.AccessN(ctx, cost)
+			} else {
+				period, err = rl.Access(ctx)
+			}
 		}); rerr != nil {
 			err = rerr
 		}
+		if errors.Is(err, service.ErrRateLimitCostExceedsCapacity) || errors.Is(err, ratelimit.ErrWeightedAccessUnsupported) {
+			return err
+		}
 		if err != nil {
 			h.log.Errorf("Rate limit error: %v\n", err)
 			period = time.Second
@@ -186,10 +221,10 @@ func (h *Client) waitForAccess(ctx context.Context) bool {
 			select {
 			case <-time.After(period):
 			case <-ctx.Done():
-				return false
+				return component.ErrTypeClosed
 			}
 		} else {
-			return true
+			return nil
 		}
 	}
 }
@@ -333,8 +368,13 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg service.MessageBatc
 		}
 	}()
 
-	if !h.waitForAccess(ctx) {
-		return nil, component.ErrTypeClosed
+	var cost int64
+	if cost, err = h.accessCost(sendMsg); err != nil {
+		logErr(err)
+		return nil, err
+	}
+	if err = h.waitForAccess(ctx, cost); err != nil {
+		return nil, err
 	}
 
 	rateLimited := false
@@ -371,8 +411,8 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg service.MessageBatc
 				return nil, component.ErrTypeClosed
 			}
 		}
-		if !h.waitForAccess(ctx) {
-			return nil, component.ErrTypeClosed
+		if err = h.waitForAccess(ctx, cost); err != nil {
+			return nil, err
 		}
 		rateLimited = false
 
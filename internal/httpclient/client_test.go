@@ -712,3 +712,42 @@ tls:
 	require.NoError(t, err)
 	assert.Equal(t, "HELLO WORLD", string(mBytes))
 }
+
+func TestHTTPClientRateLimitCost(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	var accessed uint32
+	mgr := service.MockResources(service.MockResourcesOptAddRateLimit("foo", func(ctx context.Context) (time.Duration, error) {
+		atomic.AddUint32(&accessed, 1)
+		return 0, nil
+	}))
+
+	conf := clientConfig(t, `
+url: %v
+rate_limit: foo
+rate_limit_cost: 'root = batch_size() - 1'
+`, ts.URL)
+
+	h, err := NewClientFromOldConfig(conf, mgr)
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	ctx := context.Background()
+
+	// A cost of zero does not access the rate limit.
+	_, err = h.Send(ctx, service.MessageBatch{service.NewMessage([]byte("a"))})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0), atomic.LoadUint32(&accessed))
+
+	_, err = h.Send(ctx, service.MessageBatch{service.NewMessage([]byte("a")), service.NewMessage([]byte("b"))})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&accessed))
+
+	// The mock rate limit does not support a cost of more than one token.
+	_, err = h.Send(ctx, service.MessageBatch{service.NewMessage([]byte("a")), service.NewMessage([]byte("b")), service.NewMessage([]byte("c"))})
+	require.Error(t, err)
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&accessed))
+}
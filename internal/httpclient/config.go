@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"time"
 
+	"github.com/benthosdev/benthos/v4/public/bloblang"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
@@ -14,6 +15,7 @@ const (
 	hcFieldMetadata            = "metadata"
 	hcFieldExtractHeaders      = "extract_headers"
 	hcFieldRateLimit           = "rate_limit"
+	hcFieldRateLimitCost       = "rate_limit_cost"
 	hcFieldTimeout             = "timeout"
 	hcFieldRetryPeriod         = "retry_period"
 	hcFieldMaxRetryBackoff     = "max_retry_backoff"
@@ -67,6 +69,12 @@ func ConfigField(defaultVerb string, forOutput bool, extraChildren ...*service.C
 		service.NewStringField(hcFieldRateLimit).
 			Description("An optional [rate limit](/docs/components/rate_limits/about) to throttle requests by.").
 			Optional(),
+		service.NewBloblangField(hcFieldRateLimitCost).
+			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that computes the number of tokens each request consumes from the `rate_limit`, which must result in a positive integer. The mapping is executed against the first message of the batch being sent, and functions such as `batch_size()` refer to the whole batch. When omitted each request costs one token. Only the `local` and `redis` rate limits support a cost greater than one.").
+			Examples(`root = batch_size()`, `root = content().length()`).
+			Version("4.26.0").
+			Advanced().
+			Optional(),
 		service.NewDurationField(hcFieldTimeout).
 			Description("A static timeout to apply to requests.").
 			Default("5s"),
@@ -125,6 +133,11 @@ func ConfigFromParsed(pConf *service.ParsedConfig) (conf OldConfig, err error) {
 		return
 	}
 	conf.RateLimit, _ = pConf.FieldString(hcFieldRateLimit)
+	if pConf.Contains(hcFieldRateLimitCost) {
+		if conf.RateLimitCost, err = pConf.FieldBloblang(hcFieldRateLimitCost); err != nil {
+			return
+		}
+	}
 	if conf.Timeout, err = pConf.FieldDuration(hcFieldTimeout); err != nil {
 		return
 	}
@@ -168,6 +181,7 @@ type OldConfig struct {
 	Metadata            *service.MetadataFilter
 	ExtractMetadata     *service.MetadataFilter
 	RateLimit           string
+	RateLimitCost       *bloblang.Executor
 	Timeout             time.Duration
 	Retry               time.Duration
 	MaxBackoff          time.Duration
@@ -132,6 +132,7 @@ type dynamoDBAPIV2 interface {
 	DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
 	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
 	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
 }
 
 type dynamodbCache struct {
@@ -387,6 +388,44 @@ func (d *dynamodbCache) delete(ctx context.Context, key string) error {
 	return err
 }
 
+func (d *dynamodbCache) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) error) error {
+	input := &dynamodb.ScanInput{
+		TableName:      &d.table,
+		ConsistentRead: aws.Bool(d.consistentRead),
+	}
+	if prefix != "" {
+		input.FilterExpression = aws.String("begins_with(#key, :prefix)")
+		input.ExpressionAttributeNames = map[string]string{
+			"#key": d.hashKey,
+		}
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberS{Value: prefix},
+		}
+	}
+
+	paginator := dynamodb.NewScanPaginator(d.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, item := range page.Items {
+			key, ok := item[d.hashKey].(*types.AttributeValueMemberS)
+			if !ok {
+				continue
+			}
+			val, ok := item[d.dataKey].(*types.AttributeValueMemberB)
+			if !ok {
+				continue
+			}
+			if err := fn(key.Value, val.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (d *dynamodbCache) putItemInput(key string, value []byte, ttl *time.Duration) *dynamodb.PutItemInput {
 	input := dynamodb.PutItemInput{
 		Item: map[string]types.AttributeValue{
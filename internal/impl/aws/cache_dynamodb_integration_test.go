@@ -122,6 +122,7 @@ cache_resources:
 		integration.CacheTestDoubleAdd(),
 		integration.CacheTestDelete(),
 		integration.CacheTestGetAndSet(50),
+		integration.CacheTestScan(50),
 	)
 	suite.Run(
 		t, template,
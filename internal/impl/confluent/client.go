@@ -57,6 +57,7 @@ func newSchemaRegistryClient(
 
 type SchemaInfo struct {
 	ID         int               `json:"id"`
+	Version    int               `json:"version"`
 	Type       string            `json:"schemaType"`
 	Schema     string            `json:"schema"`
 	References []SchemaReference `json:"references"`
@@ -99,6 +100,21 @@ func (c *schemaRegistryClient) GetSchemaByID(ctx context.Context, id int) (resPa
 }
 
 func (c *schemaRegistryClient) GetSchemaBySubjectAndVersion(ctx context.Context, subject string, version *int) (resPayload SchemaInfo, err error) {
+	var found bool
+	if resPayload, found, err = c.getSchemaBySubjectAndVersion(ctx, subject, version); err == nil && !found {
+		err = fmt.Errorf("schema subject '%v' not found by registry", subject)
+		c.mgr.Logger().Errorf(err.Error())
+	}
+	return
+}
+
+// GetLatestSchemaBySubject obtains the latest schema of a subject, returning
+// false if the subject does not exist.
+func (c *schemaRegistryClient) GetLatestSchemaBySubject(ctx context.Context, subject string) (SchemaInfo, bool, error) {
+	return c.getSchemaBySubjectAndVersion(ctx, subject, nil)
+}
+
+func (c *schemaRegistryClient) getSchemaBySubjectAndVersion(ctx context.Context, subject string, version *int) (resPayload SchemaInfo, found bool, err error) {
 	var path string
 	if version != nil {
 		path = fmt.Sprintf("/subjects/%s/versions/%v", url.PathEscape(subject), *version)
@@ -115,8 +131,6 @@ func (c *schemaRegistryClient) GetSchemaBySubjectAndVersion(ctx context.Context,
 	}
 
 	if resCode == http.StatusNotFound {
-		err = fmt.Errorf("schema subject '%v' not found by registry", subject)
-		c.mgr.Logger().Errorf(err.Error())
 		return
 	}
 
@@ -130,9 +144,90 @@ func (c *schemaRegistryClient) GetSchemaBySubjectAndVersion(ctx context.Context,
 		c.mgr.Logger().Errorf("failed to parse response for schema subject '%v': %v", subject, err)
 		return
 	}
+	found = true
+	return
+}
+
+// RegisterSchema registers a schema under a subject, returning its ID. If the
+// schema is already registered under the subject then the ID of the existing
+// schema is returned.
+func (c *schemaRegistryClient) RegisterSchema(ctx context.Context, subject string, info SchemaInfo) (id int, err error) {
+	var reqBody []byte
+	if reqBody, err = schemaRequestBody(info); err != nil {
+		return
+	}
+
+	var resCode int
+	var resBody []byte
+	path := fmt.Sprintf("/subjects/%s/versions", url.PathEscape(subject))
+	if resCode, resBody, err = c.doRequestWithBody(ctx, "POST", path, reqBody); err != nil {
+		err = fmt.Errorf("request failed to register schema for subject '%v': %v", subject, err)
+		c.mgr.Logger().Errorf(err.Error())
+		return
+	}
+
+	if resCode == http.StatusNotFound {
+		err = fmt.Errorf("schema registry endpoint for registering subject '%v' not found", subject)
+		c.mgr.Logger().Errorf(err.Error())
+		return
+	}
+
+	var resPayload struct {
+		ID int `json:"id"`
+	}
+	if err = json.Unmarshal(resBody, &resPayload); err != nil {
+		c.mgr.Logger().Errorf("failed to parse response for registering schema subject '%v': %v", subject, err)
+		return
+	}
+	id = resPayload.ID
 	return
 }
 
+// LookupSchema checks whether a schema is already registered under a subject,
+// returning the registered schema if so and false if either the subject does
+// not exist or the schema has not been registered under it.
+func (c *schemaRegistryClient) LookupSchema(ctx context.Context, subject string, info SchemaInfo) (resPayload SchemaInfo, found bool, err error) {
+	var reqBody []byte
+	if reqBody, err = schemaRequestBody(info); err != nil {
+		return
+	}
+
+	var resCode int
+	var resBody []byte
+	path := fmt.Sprintf("/subjects/%s", url.PathEscape(subject))
+	if resCode, resBody, err = c.doRequestWithBody(ctx, "POST", path, reqBody); err != nil {
+		err = fmt.Errorf("request failed to look up schema for subject '%v': %v", subject, err)
+		c.mgr.Logger().Errorf(err.Error())
+		return
+	}
+
+	if resCode == http.StatusNotFound {
+		return
+	}
+
+	if err = json.Unmarshal(resBody, &resPayload); err != nil {
+		c.mgr.Logger().Errorf("failed to parse response for looking up schema subject '%v': %v", subject, err)
+		return
+	}
+	found = true
+	return
+}
+
+func schemaRequestBody(info SchemaInfo) ([]byte, error) {
+	reqPayload := struct {
+		Type       string            `json:"schemaType,omitempty"`
+		Schema     string            `json:"schema"`
+		References []SchemaReference `json:"references,omitempty"`
+	}{
+		Schema:     info.Schema,
+		References: info.References,
+	}
+	if info.Type != "AVRO" {
+		reqPayload.Type = info.Type
+	}
+	return json.Marshal(reqPayload)
+}
+
 type RefWalkFn func(ctx context.Context, name string, info SchemaInfo) error
 
 // For each reference provided the schema info is obtained and the provided
@@ -169,21 +264,39 @@ func (c *schemaRegistryClient) walkReferencesTracked(ctx context.Context, seen m
 }
 
 func (c *schemaRegistryClient) doRequest(ctx context.Context, verb, reqPath string) (resCode int, resBody []byte, err error) {
+	return c.doRequestWithBody(ctx, verb, reqPath, nil)
+}
+
+func (c *schemaRegistryClient) doRequestWithBody(ctx context.Context, verb, reqPath string, reqBody []byte) (resCode int, resBody []byte, err error) {
 	reqURL := *c.schemaRegistryBaseURL
 	if reqURL.Path, err = url.JoinPath(reqURL.Path, reqPath); err != nil {
 		return
 	}
 
+	var body io.Reader = http.NoBody
+	if reqBody != nil {
+		body = bytes.NewReader(reqBody)
+	}
+
 	var req *http.Request
-	if req, err = http.NewRequestWithContext(ctx, verb, reqURL.String(), http.NoBody); err != nil {
+	if req, err = http.NewRequestWithContext(ctx, verb, reqURL.String(), body); err != nil {
 		return
 	}
 	req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json")
+	if reqBody != nil {
+		req.Header.Add("Content-Type", "application/vnd.schemaregistry.v1+json")
+	}
 	if err = c.requestSigner(c.mgr.FS(), req); err != nil {
 		return
 	}
 
 	for i := 0; i < 3; i++ {
+		if i > 0 && req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return
+			}
+		}
+
 		var res *http.Response
 		if res, err = c.client.Do(req); err != nil {
 			c.mgr.Logger().Errorf("request failed: %v", err)
@@ -0,0 +1,203 @@
+package confluent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// fakeSchemaRegistry is an in-memory implementation of the subset of the
+// schema registry API used by the schema registry processors.
+type fakeSchemaRegistry struct {
+	mut      sync.Mutex
+	schemas  []SchemaInfo
+	subjects map[string][]int
+}
+
+func runFakeSchemaRegistry(t testing.TB) (*fakeSchemaRegistry, string) {
+	t.Helper()
+
+	f := &fakeSchemaRegistry{subjects: map[string][]int{}}
+	ts := httptest.NewServer(f)
+	t.Cleanup(ts.Close)
+	return f, ts.URL
+}
+
+// Register adds a schema to a subject, returning its ID.
+func (f *fakeSchemaRegistry) Register(subject string, info SchemaInfo) int {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if info.Type == "" {
+		info.Type = "AVRO"
+	}
+	for _, i := range f.subjects[subject] {
+		if s := f.schemas[i]; s.Type == info.Type && s.Schema == info.Schema {
+			return s.ID
+		}
+	}
+
+	info.ID = len(f.schemas) + 1
+	info.Version = len(f.subjects[subject]) + 1
+	f.schemas = append(f.schemas, info)
+	f.subjects[subject] = append(f.subjects[subject], info.ID-1)
+	return info.ID
+}
+
+// Versions returns the IDs of each version of a subject.
+func (f *fakeSchemaRegistry) Versions(subject string) []int {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	var ids []int
+	for _, i := range f.subjects[subject] {
+		ids = append(ids, f.schemas[i].ID)
+	}
+	return ids
+}
+
+func (f *fakeSchemaRegistry) writeJSON(w http.ResponseWriter, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = w.Write(b)
+}
+
+func (f *fakeSchemaRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && len(path) == 3 && path[0] == "schemas" && path[1] == "ids":
+		id, err := strconv.Atoi(path[2])
+		f.mut.Lock()
+		defer f.mut.Unlock()
+		if err != nil || id < 1 || id > len(f.schemas) {
+			http.Error(w, "schema not found", http.StatusNotFound)
+			return
+		}
+		f.writeJSON(w, f.schemas[id-1])
+	case r.Method == http.MethodGet && len(path) == 4 && path[0] == "subjects" && path[2] == "versions":
+		subject, err := url.PathUnescape(path[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mut.Lock()
+		defer f.mut.Unlock()
+		versions := f.subjects[subject]
+		version := len(versions)
+		if path[3] != "latest" {
+			if version, err = strconv.Atoi(path[3]); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if version < 1 || version > len(versions) {
+			http.Error(w, "version not found", http.StatusNotFound)
+			return
+		}
+		f.writeJSON(w, f.schemas[versions[version-1]])
+	case r.Method == http.MethodPost && len(path) == 2 && path[0] == "subjects":
+		subject, err := url.PathUnescape(path[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var info SchemaInfo
+		if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if info.Type == "" {
+			info.Type = "AVRO"
+		}
+		f.mut.Lock()
+		defer f.mut.Unlock()
+		for _, i := range f.subjects[subject] {
+			if s := f.schemas[i]; s.Type == info.Type && s.Schema == info.Schema {
+				f.writeJSON(w, s)
+				return
+			}
+		}
+		http.Error(w, "schema not found", http.StatusNotFound)
+	case r.Method == http.MethodPost && len(path) == 3 && path[0] == "subjects" && path[2] == "versions":
+		subject, err := url.PathUnescape(path[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var info SchemaInfo
+		if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.writeJSON(w, map[string]int{"id": f.Register(subject, info)})
+	default:
+		http.Error(w, fmt.Sprintf("unexpected request %v %v", r.Method, r.URL.Path), http.StatusBadRequest)
+	}
+}
+
+func TestSchemaRegistryClientRegister(t *testing.T) {
+	fake, urlStr := runFakeSchemaRegistry(t)
+
+	client, err := newSchemaRegistryClient(urlStr, noopReqSign, nil, service.MockResources())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	_, exists, err := client.GetLatestSchemaBySubject(ctx, "foo-value")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = client.GetSchemaBySubjectAndVersion(ctx, "foo-value", nil)
+	require.EqualError(t, err, "schema subject 'foo-value' not found by registry")
+
+	id, err := client.RegisterSchema(ctx, "foo-value", SchemaInfo{Type: "JSON", Schema: `{"type":"string"}`})
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+
+	id, err = client.RegisterSchema(ctx, "foo-value", SchemaInfo{Type: "JSON", Schema: `{"type":"string"}`})
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+
+	id, err = client.RegisterSchema(ctx, "foo-value", SchemaInfo{Type: "AVRO", Schema: `"string"`})
+	require.NoError(t, err)
+	assert.Equal(t, 2, id)
+	assert.Equal(t, []int{1, 2}, fake.Versions("foo-value"))
+
+	info, exists, err := client.GetLatestSchemaBySubject(ctx, "foo-value")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, SchemaInfo{ID: 2, Version: 2, Type: "AVRO", Schema: `"string"`}, info)
+
+	info, err = client.GetSchemaByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, SchemaInfo{ID: 1, Version: 1, Type: "JSON", Schema: `{"type":"string"}`}, info)
+
+	info, exists, err = client.LookupSchema(ctx, "foo-value", SchemaInfo{Type: "JSON", Schema: `{"type":"string"}`})
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, SchemaInfo{ID: 1, Version: 1, Type: "JSON", Schema: `{"type":"string"}`}, info)
+
+	_, exists, err = client.LookupSchema(ctx, "foo-value", SchemaInfo{Type: "JSON", Schema: `{"type":"number"}`})
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, exists, err = client.LookupSchema(ctx, "bar-value", SchemaInfo{Type: "JSON", Schema: `{"type":"string"}`})
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
@@ -0,0 +1,629 @@
+package confluent
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/protobuf"
+)
+
+const (
+	compatibilityNone     = "none"
+	compatibilityBackward = "backward"
+	compatibilityForward  = "forward"
+	compatibilityFull     = "full"
+)
+
+// schemaIncompatibleError is returned when a schema fails a compatibility
+// check against the previous version of a subject.
+type schemaIncompatibleError struct {
+	subject       string
+	version       int
+	compatibility string
+	reasons       []string
+}
+
+func (e *schemaIncompatibleError) Error() string {
+	return fmt.Sprintf("schema is not %v compatible with version %v of subject '%v': %v", e.compatibility, e.version, e.subject, strings.Join(e.reasons, "; "))
+}
+
+// checkSchemaCompatibility checks whether a new schema is compatible with a
+// previous schema of the same type, returning a list of reasons why it is not.
+//
+// A backward compatible schema is able to read data written with the previous
+// schema, a forward compatible schema writes data that can be read with the
+// previous schema, and a fully compatible schema is both.
+func checkSchemaCompatibility(schemaType, compatibility string, previous, next string) ([]string, error) {
+	var canRead func(reader, writer string) ([]string, error)
+	switch schemaType {
+	case "", "AVRO":
+		canRead = avroCanRead
+	case "JSON":
+		canRead = jsonSchemaCanRead
+	case "PROTOBUF":
+		canRead = protobufCanRead
+	default:
+		return nil, fmt.Errorf("schema type %v not supported", schemaType)
+	}
+
+	var reasons []string
+	if compatibility == compatibilityBackward || compatibility == compatibilityFull {
+		r, err := canRead(next, previous)
+		if err != nil {
+			return nil, err
+		}
+		for _, reason := range r {
+			reasons = append(reasons, "backward: "+reason)
+		}
+	}
+	if compatibility == compatibilityForward || compatibility == compatibilityFull {
+		r, err := canRead(previous, next)
+		if err != nil {
+			return nil, err
+		}
+		for _, reason := range r {
+			reasons = append(reasons, "forward: "+reason)
+		}
+	}
+	return reasons, nil
+}
+
+//------------------------------------------------------------------------------
+
+// avroCanRead follows the schema resolution rules of the Avro specification in
+// order to determine whether data written with the writer schema can be read
+// with the reader schema.
+func avroCanRead(reader, writer string) ([]string, error) {
+	var r, w any
+	if err := json.Unmarshal([]byte(reader), &r); err != nil {
+		return nil, fmt.Errorf("failed to parse reader schema: %w", err)
+	}
+	if err := json.Unmarshal([]byte(writer), &w); err != nil {
+		return nil, fmt.Errorf("failed to parse writer schema: %w", err)
+	}
+
+	c := &avroCompat{
+		readerNames: map[string]map[string]any{},
+		writerNames: map[string]map[string]any{},
+		seen:        map[string]struct{}{},
+	}
+	avroCollectNames(r, "", c.readerNames)
+	avroCollectNames(w, "", c.writerNames)
+	return c.canRead("root", r, w), nil
+}
+
+type avroCompat struct {
+	readerNames map[string]map[string]any
+	writerNames map[string]map[string]any
+	seen        map[string]struct{}
+}
+
+var avroPromotions = map[string][]string{
+	"int":    {"long", "float", "double"},
+	"long":   {"float", "double"},
+	"float":  {"double"},
+	"string": {"bytes"},
+	"bytes":  {"string"},
+}
+
+func avroIsNamedType(t string) bool {
+	switch t {
+	case "record", "error", "enum", "fixed":
+		return true
+	}
+	return false
+}
+
+func avroShortName(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// avroCollectNames walks a schema and registers all named types by both their
+// full and short names.
+func avroCollectNames(s any, namespace string, names map[string]map[string]any) {
+	switch t := s.(type) {
+	case []any:
+		for _, b := range t {
+			avroCollectNames(b, namespace, names)
+		}
+	case map[string]any:
+		typeStr, ok := t["type"].(string)
+		if !ok {
+			avroCollectNames(t["type"], namespace, names)
+			return
+		}
+		switch typeStr {
+		case "array":
+			avroCollectNames(t["items"], namespace, names)
+			return
+		case "map":
+			avroCollectNames(t["values"], namespace, names)
+			return
+		}
+		if !avroIsNamedType(typeStr) {
+			return
+		}
+
+		name, _ := t["name"].(string)
+		if ns, ok := t["namespace"].(string); ok {
+			namespace = ns
+		}
+		fullName := name
+		if !strings.Contains(name, ".") && namespace != "" {
+			fullName = namespace + "." + name
+		}
+		if i := strings.LastIndex(fullName, "."); i >= 0 {
+			namespace = fullName[:i]
+		}
+		names[fullName] = t
+		names[avroShortName(name)] = t
+
+		fields, _ := t["fields"].([]any)
+		for _, f := range fields {
+			if fObj, ok := f.(map[string]any); ok {
+				avroCollectNames(fObj["type"], namespace, names)
+			}
+		}
+	}
+}
+
+func avroResolve(s any, names map[string]map[string]any) any {
+	for {
+		switch t := s.(type) {
+		case string:
+			if n, exists := names[t]; exists {
+				return n
+			}
+			if n, exists := names[avroShortName(t)]; exists {
+				return n
+			}
+			return t
+		case map[string]any:
+			// Schemas such as {"type":"string"} or {"type":{...}} are
+			// equivalent to their type.
+			switch inner := t["type"].(type) {
+			case string:
+				if avroIsNamedType(inner) || inner == "array" || inner == "map" {
+					return t
+				}
+				s = inner
+			default:
+				s = inner
+			}
+		default:
+			return s
+		}
+	}
+}
+
+func avroTypeOf(s any) string {
+	switch t := s.(type) {
+	case string:
+		return t
+	case []any:
+		return "union"
+	case map[string]any:
+		if typeStr, ok := t["type"].(string); ok {
+			return typeStr
+		}
+	}
+	return "unknown"
+}
+
+func avroNameMatches(reader, writer map[string]any) bool {
+	rName, _ := reader["name"].(string)
+	wName, _ := writer["name"].(string)
+	if avroShortName(rName) == avroShortName(wName) {
+		return true
+	}
+	aliases, _ := reader["aliases"].([]any)
+	for _, a := range aliases {
+		if aStr, _ := a.(string); avroShortName(aStr) == avroShortName(wName) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *avroCompat) canRead(path string, reader, writer any) []string {
+	reader = avroResolve(reader, c.readerNames)
+	writer = avroResolve(writer, c.writerNames)
+
+	// Every branch of a writer union must be readable.
+	if wUnion, ok := writer.([]any); ok {
+		var reasons []string
+		for _, b := range wUnion {
+			reasons = append(reasons, c.canRead(path, reader, b)...)
+		}
+		return reasons
+	}
+
+	// At least one branch of a reader union must be able to read the writer.
+	if rUnion, ok := reader.([]any); ok {
+		for _, b := range rUnion {
+			if len(c.canRead(path, b, writer)) == 0 {
+				return nil
+			}
+		}
+		return []string{fmt.Sprintf("%v: type %v is not present in the reader union", path, avroTypeOf(writer))}
+	}
+
+	rType, wType := avroTypeOf(reader), avroTypeOf(writer)
+	if rType != wType {
+		for _, p := range avroPromotions[wType] {
+			if p == rType {
+				return nil
+			}
+		}
+		return []string{fmt.Sprintf("%v: type %v cannot be read as %v", path, wType, rType)}
+	}
+
+	rObj, _ := reader.(map[string]any)
+	wObj, _ := writer.(map[string]any)
+
+	switch rType {
+	case "record", "error":
+		if !avroNameMatches(rObj, wObj) {
+			return []string{fmt.Sprintf("%v: record name %v does not match %v", path, wObj["name"], rObj["name"])}
+		}
+
+		// Recursive types are assumed to be compatible whilst they are being
+		// checked.
+		key := fmt.Sprintf("%v|%v", rObj["name"], wObj["name"])
+		if _, exists := c.seen[key]; exists {
+			return nil
+		}
+		c.seen[key] = struct{}{}
+		defer delete(c.seen, key)
+
+		wFields := map[string]map[string]any{}
+		wFieldsList, _ := wObj["fields"].([]any)
+		for _, f := range wFieldsList {
+			if fObj, ok := f.(map[string]any); ok {
+				name, _ := fObj["name"].(string)
+				wFields[name] = fObj
+			}
+		}
+
+		var reasons []string
+		rFieldsList, _ := rObj["fields"].([]any)
+		for _, f := range rFieldsList {
+			rField, ok := f.(map[string]any)
+			if !ok {
+				continue
+			}
+			name, _ := rField["name"].(string)
+			wField, exists := wFields[name]
+			if !exists {
+				aliases, _ := rField["aliases"].([]any)
+				for _, a := range aliases {
+					aStr, _ := a.(string)
+					if wField, exists = wFields[aStr]; exists {
+						break
+					}
+				}
+			}
+			if exists {
+				reasons = append(reasons, c.canRead(path+"."+name, rField["type"], wField["type"])...)
+				continue
+			}
+			if _, hasDefault := rField["default"]; !hasDefault {
+				reasons = append(reasons, fmt.Sprintf("%v: field %v is missing from the writer and has no default value", path, name))
+			}
+		}
+		return reasons
+	case "enum":
+		if !avroNameMatches(rObj, wObj) {
+			return []string{fmt.Sprintf("%v: enum name %v does not match %v", path, wObj["name"], rObj["name"])}
+		}
+		if _, hasDefault := rObj["default"]; hasDefault {
+			return nil
+		}
+		rSymbols := map[any]struct{}{}
+		rSymbolsList, _ := rObj["symbols"].([]any)
+		for _, s := range rSymbolsList {
+			rSymbols[s] = struct{}{}
+		}
+		var reasons []string
+		wSymbolsList, _ := wObj["symbols"].([]any)
+		for _, s := range wSymbolsList {
+			if _, exists := rSymbols[s]; !exists {
+				reasons = append(reasons, fmt.Sprintf("%v: enum symbol %v is missing from the reader", path, s))
+			}
+		}
+		return reasons
+	case "fixed":
+		if !avroNameMatches(rObj, wObj) {
+			return []string{fmt.Sprintf("%v: fixed name %v does not match %v", path, wObj["name"], rObj["name"])}
+		}
+		if !reflect.DeepEqual(rObj["size"], wObj["size"]) {
+			return []string{fmt.Sprintf("%v: fixed size %v does not match %v", path, wObj["size"], rObj["size"])}
+		}
+	case "array":
+		return c.canRead(path+"[]", rObj["items"], wObj["items"])
+	case "map":
+		return c.canRead(path+"{}", rObj["values"], wObj["values"])
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// jsonSchemaCanRead determines whether all documents that satisfy the writer
+// schema also satisfy the reader schema. Only the keywords type, enum,
+// required, properties, additionalProperties, items and numeric and length
+// bounds are compared, references are not followed.
+func jsonSchemaCanRead(reader, writer string) ([]string, error) {
+	var r, w any
+	if err := json.Unmarshal([]byte(reader), &r); err != nil {
+		return nil, fmt.Errorf("failed to parse reader schema: %w", err)
+	}
+	if err := json.Unmarshal([]byte(writer), &w); err != nil {
+		return nil, fmt.Errorf("failed to parse writer schema: %w", err)
+	}
+	return jsonSchemaAccepts("#", r, w), nil
+}
+
+func jsonSchemaTypes(s map[string]any) map[string]struct{} {
+	types := map[string]struct{}{}
+	switch t := s["type"].(type) {
+	case string:
+		types[t] = struct{}{}
+	case []any:
+		for _, v := range t {
+			if vStr, ok := v.(string); ok {
+				types[vStr] = struct{}{}
+			}
+		}
+	}
+	return types
+}
+
+func jsonSchemaStringSet(v any) map[string]struct{} {
+	set := map[string]struct{}{}
+	list, _ := v.([]any)
+	for _, e := range list {
+		if eStr, ok := e.(string); ok {
+			set[eStr] = struct{}{}
+		}
+	}
+	return set
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func jsonSchemaAccepts(path string, reader, writer any) []string {
+	if rBool, ok := reader.(bool); ok && rBool {
+		return nil
+	}
+	if wBool, ok := writer.(bool); ok && !wBool {
+		return nil
+	}
+
+	rObj, _ := reader.(map[string]any)
+	wObj, _ := writer.(map[string]any)
+	if rBool, ok := reader.(bool); ok && !rBool {
+		return []string{fmt.Sprintf("%v: the reader accepts no documents", path)}
+	}
+	if wObj == nil {
+		wObj = map[string]any{}
+	}
+	if len(rObj) == 0 {
+		return nil
+	}
+
+	var reasons []string
+
+	rTypes, wTypes := jsonSchemaTypes(rObj), jsonSchemaTypes(wObj)
+	if len(rTypes) > 0 {
+		if len(wTypes) == 0 {
+			reasons = append(reasons, fmt.Sprintf("%v: the reader is restricted to types %v but the writer is not", path, sortedKeys(rTypes)))
+		}
+		for _, wt := range sortedKeys(wTypes) {
+			if _, exists := rTypes[wt]; exists {
+				continue
+			}
+			if _, exists := rTypes["number"]; exists && wt == "integer" {
+				continue
+			}
+			reasons = append(reasons, fmt.Sprintf("%v: type %v is not accepted by the reader", path, wt))
+		}
+	}
+
+	if rEnum, ok := rObj["enum"].([]any); ok {
+		wEnum, ok := wObj["enum"].([]any)
+		if !ok {
+			reasons = append(reasons, fmt.Sprintf("%v: the reader is restricted to an enum but the writer is not", path))
+		}
+		for _, wv := range wEnum {
+			var found bool
+			for _, rv := range rEnum {
+				if reflect.DeepEqual(rv, wv) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				reasons = append(reasons, fmt.Sprintf("%v: enum value %v is not accepted by the reader", path, wv))
+			}
+		}
+	}
+
+	for _, k := range []string{"maximum", "exclusiveMaximum", "maxLength", "maxItems", "maxProperties"} {
+		rv, ok := rObj[k].(float64)
+		if !ok {
+			continue
+		}
+		if wv, ok := wObj[k].(float64); !ok || wv > rv {
+			reasons = append(reasons, fmt.Sprintf("%v: %v of the reader is more restrictive than the writer", path, k))
+		}
+	}
+	for _, k := range []string{"minimum", "exclusiveMinimum", "minLength", "minItems", "minProperties"} {
+		rv, ok := rObj[k].(float64)
+		if !ok {
+			continue
+		}
+		if wv, ok := wObj[k].(float64); !ok || wv < rv {
+			reasons = append(reasons, fmt.Sprintf("%v: %v of the reader is more restrictive than the writer", path, k))
+		}
+	}
+
+	wRequired := jsonSchemaStringSet(wObj["required"])
+	for _, k := range sortedKeys(jsonSchemaStringSet(rObj["required"])) {
+		if _, exists := wRequired[k]; !exists {
+			reasons = append(reasons, fmt.Sprintf("%v: property %v is required by the reader but not the writer", path, k))
+		}
+	}
+
+	rProps, _ := rObj["properties"].(map[string]any)
+	wProps, _ := wObj["properties"].(map[string]any)
+	for _, k := range sortedKeys(rProps) {
+		if wProp, exists := wProps[k]; exists {
+			reasons = append(reasons, jsonSchemaAccepts(path+"/properties/"+k, rProps[k], wProp)...)
+		}
+	}
+	if rAdditional, ok := rObj["additionalProperties"].(bool); ok && !rAdditional {
+		if wAdditional, ok := wObj["additionalProperties"].(bool); !ok || wAdditional {
+			reasons = append(reasons, fmt.Sprintf("%v: the reader does not accept additional properties but the writer does", path))
+		}
+		for _, k := range sortedKeys(wProps) {
+			if _, exists := rProps[k]; !exists {
+				reasons = append(reasons, fmt.Sprintf("%v: property %v is not accepted by the reader", path, k))
+			}
+		}
+	}
+
+	if rItems, exists := rObj["items"]; exists {
+		wItems, exists := wObj["items"]
+		if !exists {
+			wItems = true
+		}
+		reasons = append(reasons, jsonSchemaAccepts(path+"/items", rItems, wItems)...)
+	}
+	return reasons
+}
+
+//------------------------------------------------------------------------------
+
+// protobufCanRead determines whether messages written with the writer schema
+// can be read with the reader schema by comparing the wire types of fields that
+// share a number within messages of the same name.
+func protobufCanRead(reader, writer string) ([]string, error) {
+	rMsgs, err := protobufMessages(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse reader schema: %w", err)
+	}
+	wMsgs, err := protobufMessages(writer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse writer schema: %w", err)
+	}
+
+	var reasons []string
+	for _, name := range sortedKeys(wMsgs) {
+		wMsg := wMsgs[name]
+		rMsg, exists := rMsgs[name]
+		if !exists {
+			reasons = append(reasons, fmt.Sprintf("message %v is missing from the reader", name))
+			continue
+		}
+
+		wFields := wMsg.Fields()
+		for i := 0; i < wFields.Len(); i++ {
+			wField := wFields.Get(i)
+			rField := rMsg.Fields().ByNumber(wField.Number())
+			if rField == nil {
+				continue
+			}
+			if rField.Cardinality() == protoreflect.Repeated && wField.Cardinality() != protoreflect.Repeated ||
+				rField.Cardinality() != protoreflect.Repeated && wField.Cardinality() == protoreflect.Repeated {
+				reasons = append(reasons, fmt.Sprintf("message %v field %v: repeated label does not match", name, wField.Number()))
+				continue
+			}
+			if rField.IsMap() != wField.IsMap() {
+				reasons = append(reasons, fmt.Sprintf("message %v field %v: map type does not match", name, wField.Number()))
+				continue
+			}
+			if !protobufKindsCompatible(rField, wField) {
+				reasons = append(reasons, fmt.Sprintf("message %v field %v: type %v cannot be read as %v", name, wField.Number(), protobufKindName(wField), protobufKindName(rField)))
+			}
+		}
+	}
+	return reasons, nil
+}
+
+func protobufMessages(schema string) (map[string]protoreflect.MessageDescriptor, error) {
+	files, _, err := protobuf.RegistriesFromMap(map[string]string{".": schema})
+	if err != nil {
+		return nil, err
+	}
+	file, err := files.FindFileByPath(".")
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := map[string]protoreflect.MessageDescriptor{}
+	var walk func(descs protoreflect.MessageDescriptors)
+	walk = func(descs protoreflect.MessageDescriptors) {
+		for i := 0; i < descs.Len(); i++ {
+			d := descs.Get(i)
+			msgs[string(d.FullName())] = d
+			walk(d.Messages())
+		}
+	}
+	walk(file.Messages())
+	return msgs, nil
+}
+
+// Kinds within the same group share a wire type and can be read as each other.
+var protobufKindGroups = map[protoreflect.Kind]int{
+	protoreflect.Int32Kind:    1,
+	protoreflect.Uint32Kind:   1,
+	protoreflect.Int64Kind:    1,
+	protoreflect.Uint64Kind:   1,
+	protoreflect.BoolKind:     1,
+	protoreflect.EnumKind:     1,
+	protoreflect.Sint32Kind:   2,
+	protoreflect.Sint64Kind:   2,
+	protoreflect.Fixed32Kind:  3,
+	protoreflect.Sfixed32Kind: 3,
+	protoreflect.Fixed64Kind:  4,
+	protoreflect.Sfixed64Kind: 4,
+	protoreflect.StringKind:   5,
+	protoreflect.BytesKind:    5,
+}
+
+func protobufKindsCompatible(reader, writer protoreflect.FieldDescriptor) bool {
+	rKind, wKind := reader.Kind(), writer.Kind()
+	if rKind == protoreflect.MessageKind || rKind == protoreflect.GroupKind ||
+		wKind == protoreflect.MessageKind || wKind == protoreflect.GroupKind {
+		return rKind == wKind && reader.Message().FullName() == writer.Message().FullName()
+	}
+	if rKind == wKind {
+		return true
+	}
+	rGroup, rExists := protobufKindGroups[rKind]
+	wGroup, wExists := protobufKindGroups[wKind]
+	return rExists && wExists && rGroup == wGroup
+}
+
+func protobufKindName(field protoreflect.FieldDescriptor) string {
+	if field.Kind() == protoreflect.MessageKind || field.Kind() == protoreflect.GroupKind {
+		return string(field.Message().FullName())
+	}
+	return field.Kind().String()
+}
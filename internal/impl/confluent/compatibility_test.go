@@ -0,0 +1,335 @@
+package confluent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvroCompatibility(t *testing.T) {
+	v1 := `{"type":"record","name":"user","namespace":"foo","fields":[
+  {"name":"name","type":"string"},
+  {"name":"age","type":"int"},
+  {"name":"status","type":{"type":"enum","name":"status","symbols":["ACTIVE","INACTIVE"]}}
+]}`
+
+	tests := []struct {
+		name          string
+		next          string
+		compatibility string
+		reasons       []string
+	}{
+		{
+			name:          "identical",
+			next:          v1,
+			compatibility: compatibilityFull,
+		},
+		{
+			name: "added field with default",
+			next: `{"type":"record","name":"user","namespace":"foo","fields":[
+  {"name":"name","type":"string"},
+  {"name":"age","type":"int"},
+  {"name":"status","type":{"type":"enum","name":"status","symbols":["ACTIVE","INACTIVE"]}},
+  {"name":"email","type":["null","string"],"default":null}
+]}`,
+			compatibility: compatibilityFull,
+		},
+		{
+			name: "added field without default",
+			next: `{"type":"record","name":"user","namespace":"foo","fields":[
+  {"name":"name","type":"string"},
+  {"name":"age","type":"int"},
+  {"name":"status","type":{"type":"enum","name":"status","symbols":["ACTIVE","INACTIVE"]}},
+  {"name":"email","type":"string"}
+]}`,
+			compatibility: compatibilityFull,
+			reasons: []string{
+				"backward: root: field email is missing from the writer and has no default value",
+			},
+		},
+		{
+			name: "removed field without default",
+			next: `{"type":"record","name":"user","namespace":"foo","fields":[
+  {"name":"name","type":"string"},
+  {"name":"status","type":{"type":"enum","name":"status","symbols":["ACTIVE","INACTIVE"]}}
+]}`,
+			compatibility: compatibilityBackward,
+		},
+		{
+			name: "removed field without default forward",
+			next: `{"type":"record","name":"user","namespace":"foo","fields":[
+  {"name":"name","type":"string"},
+  {"name":"status","type":{"type":"enum","name":"status","symbols":["ACTIVE","INACTIVE"]}}
+]}`,
+			compatibility: compatibilityForward,
+			reasons: []string{
+				"forward: root: field age is missing from the writer and has no default value",
+			},
+		},
+		{
+			name: "promoted type",
+			next: `{"type":"record","name":"user","namespace":"foo","fields":[
+  {"name":"name","type":"string"},
+  {"name":"age","type":"long"},
+  {"name":"status","type":{"type":"enum","name":"status","symbols":["ACTIVE","INACTIVE"]}}
+]}`,
+			compatibility: compatibilityFull,
+			reasons: []string{
+				"forward: root.age: type long cannot be read as int",
+			},
+		},
+		{
+			name: "added enum symbol",
+			next: `{"type":"record","name":"user","namespace":"foo","fields":[
+  {"name":"name","type":"string"},
+  {"name":"age","type":"int"},
+  {"name":"status","type":{"type":"enum","name":"status","symbols":["ACTIVE","INACTIVE","BANNED"]}}
+]}`,
+			compatibility: compatibilityFull,
+			reasons: []string{
+				"forward: root.status: enum symbol BANNED is missing from the reader",
+			},
+		},
+		{
+			name: "renamed record",
+			next: `{"type":"record","name":"person","namespace":"foo","fields":[
+  {"name":"name","type":"string"},
+  {"name":"age","type":"int"},
+  {"name":"status","type":{"type":"enum","name":"status","symbols":["ACTIVE","INACTIVE"]}}
+]}`,
+			compatibility: compatibilityBackward,
+			reasons: []string{
+				"backward: root: record name user does not match person",
+			},
+		},
+		{
+			name: "renamed record with alias",
+			next: `{"type":"record","name":"person","aliases":["user"],"namespace":"foo","fields":[
+  {"name":"full_name","aliases":["name"],"type":"string"},
+  {"name":"age","type":"int"},
+  {"name":"status","type":{"type":"enum","name":"status","symbols":["ACTIVE","INACTIVE"]}}
+]}`,
+			compatibility: compatibilityBackward,
+		},
+		{
+			name:          "changed type without checks",
+			next:          `"string"`,
+			compatibility: compatibilityNone,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			reasons, err := checkSchemaCompatibility("AVRO", test.compatibility, v1, test.next)
+			require.NoError(t, err)
+			assert.Equal(t, test.reasons, reasons)
+		})
+	}
+}
+
+func TestAvroCompatibilityUnions(t *testing.T) {
+	reasons, err := checkSchemaCompatibility("AVRO", compatibilityFull, `["null","string"]`, `["null","string","int"]`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"forward: root: type int is not present in the reader union",
+	}, reasons)
+
+	reasons, err = checkSchemaCompatibility("AVRO", compatibilityBackward, `"int"`, `["null","long"]`)
+	require.NoError(t, err)
+	assert.Empty(t, reasons)
+
+	reasons, err = checkSchemaCompatibility("AVRO", compatibilityBackward, `{"type":"array","items":"int"}`, `{"type":"array","items":"string"}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"backward: root[]: type int cannot be read as string",
+	}, reasons)
+
+	// Named type references
+	reasons, err = checkSchemaCompatibility("AVRO", compatibilityBackward,
+		`{"type":"record","name":"pair","namespace":"foo","fields":[{"name":"a","type":{"type":"fixed","name":"id","size":16}},{"name":"b","type":"id"}]}`,
+		`{"type":"record","name":"pair","namespace":"foo","fields":[{"name":"a","type":{"type":"fixed","name":"id","size":8}},{"name":"b","type":"foo.id"}]}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"backward: root.a: fixed size 16 does not match 8",
+		"backward: root.b: fixed size 16 does not match 8",
+	}, reasons)
+
+	// Recursive types
+	list := `{"type":"record","name":"node","fields":[{"name":"value","type":"int"},{"name":"next","type":["null","node"]}]}`
+	reasons, err = checkSchemaCompatibility("AVRO", compatibilityFull, list, list)
+	require.NoError(t, err)
+	assert.Empty(t, reasons)
+
+	_, err = checkSchemaCompatibility("AVRO", compatibilityFull, `{`, list)
+	require.Error(t, err)
+}
+
+func TestJSONSchemaCompatibility(t *testing.T) {
+	v1 := `{
+  "type": "object",
+  "properties": {
+    "name": { "type": "string", "maxLength": 100 },
+    "age": { "type": "integer" },
+    "status": { "enum": ["active", "inactive"] }
+  },
+  "required": ["name"]
+}`
+
+	tests := []struct {
+		name          string
+		next          string
+		compatibility string
+		reasons       []string
+	}{
+		{
+			name:          "identical",
+			next:          v1,
+			compatibility: compatibilityFull,
+		},
+		{
+			name: "widened types",
+			next: `{
+  "type": "object",
+  "properties": {
+    "name": { "type": "string" },
+    "age": { "type": "number" },
+    "status": { "enum": ["active", "inactive", "banned"] }
+  },
+  "required": ["name"]
+}`,
+			compatibility: compatibilityFull,
+			reasons: []string{
+				"forward: #/properties/age: type number is not accepted by the reader",
+				"forward: #/properties/name: maxLength of the reader is more restrictive than the writer",
+				"forward: #/properties/status: enum value banned is not accepted by the reader",
+			},
+		},
+		{
+			name: "new required property",
+			next: `{
+  "type": "object",
+  "properties": {
+    "name": { "type": "string", "maxLength": 100 },
+    "age": { "type": "integer" },
+    "status": { "enum": ["active", "inactive"] }
+  },
+  "required": ["name", "age"]
+}`,
+			compatibility: compatibilityBackward,
+			reasons: []string{
+				"backward: #: property age is required by the reader but not the writer",
+			},
+		},
+		{
+			name: "closed content model",
+			next: `{
+  "type": "object",
+  "properties": {
+    "name": { "type": "string", "maxLength": 100 }
+  },
+  "additionalProperties": false,
+  "required": ["name"]
+}`,
+			compatibility: compatibilityBackward,
+			reasons: []string{
+				"backward: #: the reader does not accept additional properties but the writer does",
+				"backward: #: property age is not accepted by the reader",
+				"backward: #: property status is not accepted by the reader",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			reasons, err := checkSchemaCompatibility("JSON", test.compatibility, v1, test.next)
+			require.NoError(t, err)
+			assert.Equal(t, test.reasons, reasons)
+		})
+	}
+}
+
+func TestProtobufCompatibility(t *testing.T) {
+	v1 := `
+syntax = "proto3";
+package foo;
+
+message User {
+  string name = 1;
+  int32 age = 2;
+  repeated string tags = 3;
+}
+`
+
+	tests := []struct {
+		name          string
+		next          string
+		compatibility string
+		reasons       []string
+	}{
+		{
+			name:          "identical",
+			next:          v1,
+			compatibility: compatibilityFull,
+		},
+		{
+			name: "compatible changes",
+			next: `
+syntax = "proto3";
+package foo;
+
+message User {
+  bytes name = 1;
+  int64 age = 2;
+  repeated string tags = 3;
+  string email = 4;
+}
+`,
+			compatibility: compatibilityFull,
+		},
+		{
+			name: "incompatible changes",
+			next: `
+syntax = "proto3";
+package foo;
+
+message User {
+  int32 name = 1;
+  int32 age = 2;
+  string tags = 3;
+}
+`,
+			compatibility: compatibilityBackward,
+			reasons: []string{
+				"backward: message foo.User field 1: type string cannot be read as int32",
+				"backward: message foo.User field 3: repeated label does not match",
+			},
+		},
+		{
+			name: "removed message",
+			next: `
+syntax = "proto3";
+package foo;
+
+message Person {
+  string name = 1;
+}
+`,
+			compatibility: compatibilityBackward,
+			reasons: []string{
+				"backward: message foo.User is missing from the reader",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			reasons, err := checkSchemaCompatibility("PROTOBUF", test.compatibility, v1, test.next)
+			require.NoError(t, err)
+			assert.Equal(t, test.reasons, reasons)
+		})
+	}
+}
@@ -6,12 +6,14 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	ifs "io/fs"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/benthosdev/benthos/v4/internal/httpclient"
 	"github.com/benthosdev/benthos/v4/internal/shutdown"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
@@ -56,6 +58,12 @@ This processor encodes protobuf messages either from any format parsed within Be
 When a target subject presents a protobuf schema that contains multiple messages it becomes ambiguous which message definition a given input data should be encoded against. In such scenarios Benthos will attempt to encode the data against each of them and select the first to successfully match against the data, this process currently *ignores all nested message definitions*. In order to speed up this exhaustive search the last known successful message will be attempted first for each subsequent input.
 
 We will be considering alternative approaches in future so please [get in touch](/community) with thoughts and feedback.
+
+### Schema Registration
+
+By default messages are encoded with the latest schema of the subject, which must already exist within the registry. Alternatively, when [` + "`auto_register.enabled`" + `](#auto_registerenabled) is ` + "`true`" + ` the schema to encode with is provided either from a file or by a Bloblang mapping executed on each message, and is registered under the subject before being used.
+
+A schema that is already registered under the subject, as any version, is used as is. Before a new schema is registered it is checked locally for [` + "`auto_register.compatibility`" + `](#auto_registercompatibility) against the latest version of the subject, and when the check fails the entire batch is failed with an error describing each incompatibility. The registry may also enforce its own compatibility rules, in which case a rejected registration fails the batch in the same way. Schemas are registered once and then cached, and schema references are not supported for registered schemas.
 `).
 		Field(service.NewURLField("url").Description("The base URL of the schema registry service.")).
 		Field(service.NewInterpolatedStringField("subject").Description("The schema subject to derive schemas from.").
@@ -68,7 +76,28 @@ We will be considering alternative approaches in future so please [get in touch]
 			Example("1h")).
 		Field(service.NewBoolField("avro_raw_json").
 			Description("Whether messages encoded in Avro format should be parsed as normal JSON (\"json that meets the expectations of regular internet json\") rather than [Avro JSON](https://avro.apache.org/docs/current/specification/_print/#json-encoding). If `true` the schema returned from the subject should be parsed as [standard json](https://pkg.go.dev/github.com/linkedin/goavro/v2#NewCodecForStandardJSONFull) instead of as [avro json](https://pkg.go.dev/github.com/linkedin/goavro/v2#NewCodec). There is a [comment in goavro](https://github.com/linkedin/goavro/blob/5ec5a5ee7ec82e16e6e2b438d610e1cab2588393/union.go#L224-L249), the [underlining library used for avro serialization](https://github.com/linkedin/goavro), that explains in more detail the difference between standard json and avro json.").
-			Advanced().Default(false).Version("3.59.0"))
+			Advanced().Default(false).Version("3.59.0")).
+		Field(service.NewObjectField("auto_register",
+			service.NewBoolField("enabled").
+				Description("Whether to register the schema to encode with under the subject, rather than using the latest schema of the subject.").
+				Default(false),
+			service.NewStringEnumField("schema_type", "AVRO", "PROTOBUF", "JSON").
+				Description("The type of the schema being registered.").
+				Default("AVRO"),
+			service.NewStringField("schema_path").
+				Description("The path of a file containing the schema to register. Either this field or `schema_mapping` must be set when registration is enabled.").
+				Example("./schemas/user.avsc").
+				Optional(),
+			service.NewBloblangField("schema_mapping").
+				Description("A [Bloblang mapping](/docs/guides/bloblang/about) executed on each message that provides the schema to register, either as a string or as a structured document that is serialised as JSON. Either this field or `schema_path` must be set when registration is enabled.").
+				Example(`root = meta("schema")`).
+				Example(`root = {"type":"record","name":"user","fields":[{"name":"name","type":"string"}]}`).
+				Optional(),
+			service.NewStringEnumField("compatibility", compatibilityNone, compatibilityBackward, compatibilityForward, compatibilityFull).
+				Description("The compatibility that a new schema is checked for against the latest version of the subject before it is registered. A `backward` compatible schema can read data written with the previous version, a `forward` compatible schema writes data that can be read with the previous version, and a `full` compatible schema is both.").
+				Default(compatibilityBackward),
+		).Description("Register schemas with the subject before encoding messages.").
+			Advanced().Version("4.26.0"))
 
 	for _, f := range httpclient.AuthFieldSpecs() {
 		spec = spec.Field(f.Version("4.7.0"))
@@ -95,8 +124,10 @@ type schemaRegistryEncoder struct {
 	subject            *service.InterpolatedString
 	avroRawJSON        bool
 	schemaRefreshAfter time.Duration
+	autoRegister       *schemaRegistryAutoRegister
 
 	schemas    map[string]*cachedSchemaEncoder
+	registered map[string]*cachedSchemaEncoder
 	cacheMut   sync.RWMutex
 	requestMut sync.Mutex
 	shutSig    *shutdown.Signaller
@@ -139,7 +170,80 @@ func newSchemaRegistryEncoderFromConfig(conf *service.ParsedConfig, mgr *service
 	if err != nil {
 		return nil, err
 	}
-	return newSchemaRegistryEncoder(urlStr, authSigner, tlsConf, subject, avroRawJSON, refreshPeriod, refreshTicker, mgr)
+	autoRegister, err := autoRegisterFromConfig(conf.Namespace("auto_register"), mgr)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSchemaRegistryEncoder(urlStr, authSigner, tlsConf, subject, avroRawJSON, refreshPeriod, refreshTicker, mgr)
+	if err != nil {
+		return nil, err
+	}
+	s.autoRegister = autoRegister
+	return s, nil
+}
+
+type schemaRegistryAutoRegister struct {
+	schemaType    string
+	schema        string
+	schemaMapping *bloblang.Executor
+	compatibility string
+}
+
+func autoRegisterFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*schemaRegistryAutoRegister, error) {
+	if enabled, err := conf.FieldBool("enabled"); err != nil || !enabled {
+		return nil, err
+	}
+
+	a := &schemaRegistryAutoRegister{}
+	var err error
+	if a.schemaType, err = conf.FieldString("schema_type"); err != nil {
+		return nil, err
+	}
+	if a.compatibility, err = conf.FieldString("compatibility"); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains("schema_path") {
+		schemaPath, err := conf.FieldString("schema_path")
+		if err != nil {
+			return nil, err
+		}
+		schemaBytes, err := ifs.ReadFile(mgr.FS(), schemaPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema file: %w", err)
+		}
+		a.schema = string(schemaBytes)
+	}
+	if conf.Contains("schema_mapping") {
+		if a.schema != "" {
+			return nil, errors.New("auto_register fields schema_path and schema_mapping cannot both be set")
+		}
+		if a.schemaMapping, err = conf.FieldBloblang("schema_mapping"); err != nil {
+			return nil, err
+		}
+	}
+	if a.schema == "" && a.schemaMapping == nil {
+		return nil, errors.New("auto_register requires either schema_path or schema_mapping to be set")
+	}
+	return a, nil
+}
+
+func (a *schemaRegistryAutoRegister) schemaFor(batch service.MessageBatch, i int) (string, error) {
+	if a.schemaMapping == nil {
+		return a.schema, nil
+	}
+	res, err := batch.BloblangQuery(i, a.schemaMapping)
+	if err != nil {
+		return "", fmt.Errorf("schema mapping failed: %w", err)
+	}
+	if res == nil {
+		return "", errors.New("schema mapping resulted in a deleted message")
+	}
+	schemaBytes, err := res.AsBytes()
+	if err != nil {
+		return "", fmt.Errorf("schema mapping failed: %w", err)
+	}
+	return string(schemaBytes), nil
 }
 
 func newSchemaRegistryEncoder(
@@ -156,6 +260,7 @@ func newSchemaRegistryEncoder(
 		avroRawJSON:        avroRawJSON,
 		schemaRefreshAfter: schemaRefreshAfter,
 		schemas:            map[string]*cachedSchemaEncoder{},
+		registered:         map[string]*cachedSchemaEncoder{},
 		shutSig:            shutdown.NewSignaller(),
 		logger:             mgr.Logger(),
 		mgr:                mgr,
@@ -189,8 +294,19 @@ func (s *schemaRegistryEncoder) ProcessBatch(ctx context.Context, batch service.
 			continue
 		}
 
-		encoder, id, err := s.getEncoder(subject)
-		if err != nil {
+		var encoder schemaEncoder
+		var id int
+		if s.autoRegister != nil {
+			schema, err := s.autoRegister.schemaFor(batch, i)
+			if err != nil {
+				msg.SetError(err)
+				continue
+			}
+			if encoder, id, err = s.getRegisteredEncoder(subject, schema); err != nil {
+				s.logger.Errorf("Failed to register schema for subject '%v': %v", subject, err)
+				return nil, err
+			}
+		} else if encoder, id, err = s.getEncoder(subject); err != nil {
 			msg.SetError(err)
 			continue
 		}
@@ -225,6 +341,9 @@ func (s *schemaRegistryEncoder) Close(ctx context.Context) error {
 	for k := range s.schemas {
 		delete(s.schemas, k)
 	}
+	for k := range s.registered {
+		delete(s.registered, k)
+	}
 	return nil
 }
 
@@ -262,6 +381,12 @@ func (s *schemaRegistryEncoder) refreshEncoders() {
 			refreshTargets = append(refreshTargets, k)
 		}
 	}
+	var purgeRegisteredTargets []string
+	for k, v := range s.registered {
+		if atomic.LoadInt64(&v.lastUsedUnixSeconds) < purgeTargetTime {
+			purgeRegisteredTargets = append(purgeRegisteredTargets, k)
+		}
+	}
 	s.cacheMut.RUnlock()
 
 	// Second pass fully locks schemas and removes stale decoders
@@ -274,6 +399,15 @@ func (s *schemaRegistryEncoder) refreshEncoders() {
 		}
 		s.cacheMut.Unlock()
 	}
+	if len(purgeRegisteredTargets) > 0 {
+		s.cacheMut.Lock()
+		for _, k := range purgeRegisteredTargets {
+			if s.registered[k].lastUsedUnixSeconds < purgeTargetTime {
+				delete(s.registered, k)
+			}
+		}
+		s.cacheMut.Unlock()
+	}
 
 	// Each refresh target gets updated passively
 	if len(refreshTargets) > 0 {
@@ -305,22 +439,127 @@ func (s *schemaRegistryEncoder) getLatestEncoder(subject string) (schemaEncoder,
 
 	s.logger.Tracef("Loaded new codec for subject %v: %s", subject, resPayload.Schema)
 
-	var encoder schemaEncoder
-	switch resPayload.Type {
+	encoder, err := s.getEncoderForSchema(ctx, resPayload)
+	if err != nil {
+		return nil, 0, err
+	}
+	return encoder, resPayload.ID, nil
+}
+
+func (s *schemaRegistryEncoder) getEncoderForSchema(ctx context.Context, info SchemaInfo) (encoder schemaEncoder, err error) {
+	switch info.Type {
 	case "PROTOBUF":
-		encoder, err = s.getProtobufEncoder(ctx, resPayload)
+		encoder, err = s.getProtobufEncoder(ctx, info)
 	case "", "AVRO":
-		encoder, err = s.getAvroEncoder(ctx, resPayload)
+		encoder, err = s.getAvroEncoder(ctx, info)
 	case "JSON":
-		encoder, err = s.getJSONEncoder(ctx, resPayload)
+		encoder, err = s.getJSONEncoder(ctx, info)
 	default:
-		err = fmt.Errorf("schema type %v not supported", resPayload.Type)
+		err = fmt.Errorf("schema type %v not supported", info.Type)
+	}
+	return
+}
+
+// registerSchema registers a schema under a subject after checking that it is
+// compatible with the latest version of the subject. Schemas that are already
+// registered under the subject are reused without a compatibility check.
+func (s *schemaRegistryEncoder) registerSchema(ctx context.Context, subject, schema string) (SchemaInfo, error) {
+	info := SchemaInfo{
+		Type:   s.autoRegister.schemaType,
+		Schema: schema,
 	}
+
+	registered, exists, err := s.client.LookupSchema(ctx, subject, info)
+	if err != nil {
+		return info, err
+	}
+	if exists {
+		return registered, nil
+	}
+
+	latest, exists, err := s.client.GetLatestSchemaBySubject(ctx, subject)
+	if err != nil {
+		return info, err
+	}
+	if latestType := latest.Type; exists {
+		if latestType == "" {
+			latestType = "AVRO"
+		}
+		if s.autoRegister.compatibility != compatibilityNone {
+			var reasons []string
+			if latestType != info.Type {
+				reasons = []string{fmt.Sprintf("schema type %v does not match %v", info.Type, latestType)}
+			} else if reasons, err = checkSchemaCompatibility(info.Type, s.autoRegister.compatibility, latest.Schema, schema); err != nil {
+				return info, err
+			}
+			if len(reasons) > 0 {
+				return info, &schemaIncompatibleError{
+					subject:       subject,
+					version:       latest.Version,
+					compatibility: s.autoRegister.compatibility,
+					reasons:       reasons,
+				}
+			}
+		}
+	}
+
+	if info.ID, err = s.client.RegisterSchema(ctx, subject, info); err != nil {
+		return info, err
+	}
+	s.logger.Debugf("Registered schema %v for subject %v", info.ID, subject)
+	return info, nil
+}
+
+func (s *schemaRegistryEncoder) getRegisteredEncoder(subject, schema string) (schemaEncoder, int, error) {
+	key := subject + "\x00" + schema
+
+	s.cacheMut.RLock()
+	c, ok := s.registered[key]
+	s.cacheMut.RUnlock()
+	if ok {
+		atomic.StoreInt64(&c.lastUsedUnixSeconds, s.nowFn().Unix())
+		return c.encoder, c.id, nil
+	}
+
+	s.requestMut.Lock()
+	defer s.requestMut.Unlock()
+
+	s.cacheMut.RLock()
+	c, ok = s.registered[key]
+	s.cacheMut.RUnlock()
+	if ok {
+		atomic.StoreInt64(&c.lastUsedUnixSeconds, s.nowFn().Unix())
+		return c.encoder, c.id, nil
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	// The schema is parsed before it is registered in order to avoid
+	// registering invalid schemas.
+	encoder, err := s.getEncoderForSchema(ctx, SchemaInfo{
+		Type:   s.autoRegister.schemaType,
+		Schema: schema,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse schema: %w", err)
+	}
+
+	info, err := s.registerSchema(ctx, subject, schema)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	return encoder, resPayload.ID, nil
+	s.cacheMut.Lock()
+	s.registered[key] = &cachedSchemaEncoder{
+		lastUsedUnixSeconds:    s.nowFn().Unix(),
+		lastUpdatedUnixSeconds: s.nowFn().Unix(),
+		id:                     info.ID,
+		encoder:                encoder,
+	}
+	s.cacheMut.Unlock()
+
+	return encoder, info.ID, nil
 }
 
 func (s *schemaRegistryEncoder) getEncoder(subject string) (schemaEncoder, int, error) {
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io/fs"
 	"net/http"
+	"os"
+	"path/filepath"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -650,3 +652,259 @@ func TestSchemaRegistryEncodeJSON(t *testing.T) {
 	assert.Len(t, encoder.schemas, 0)
 	encoder.cacheMut.Unlock()
 }
+
+func TestSchemaRegistryEncoderAutoRegisterConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "schema.avsc")
+	require.NoError(t, os.WriteFile(schemaPath, []byte(`"string"`), 0o644))
+
+	configTests := []struct {
+		name        string
+		config      string
+		errContains string
+	}{
+		{
+			name: "schema path",
+			config: fmt.Sprintf(`
+url: http://example.com
+subject: foo
+auto_register:
+  enabled: true
+  schema_path: %v
+`, schemaPath),
+		},
+		{
+			name: "schema mapping",
+			config: `
+url: http://example.com
+subject: foo
+auto_register:
+  enabled: true
+  schema_mapping: 'root = meta("schema")'
+`,
+		},
+		{
+			name: "no schema",
+			config: `
+url: http://example.com
+subject: foo
+auto_register:
+  enabled: true
+`,
+			errContains: "auto_register requires either schema_path or schema_mapping to be set",
+		},
+		{
+			name: "both schemas",
+			config: fmt.Sprintf(`
+url: http://example.com
+subject: foo
+auto_register:
+  enabled: true
+  schema_path: %v
+  schema_mapping: 'root = meta("schema")'
+`, schemaPath),
+			errContains: "auto_register fields schema_path and schema_mapping cannot both be set",
+		},
+		{
+			name: "missing schema file",
+			config: fmt.Sprintf(`
+url: http://example.com
+subject: foo
+auto_register:
+  enabled: true
+  schema_path: %v
+`, filepath.Join(tmpDir, "nope.avsc")),
+			errContains: "failed to read schema file",
+		},
+	}
+
+	spec := schemaRegistryEncoderConfig()
+	env := service.NewEnvironment()
+	for _, test := range configTests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := spec.ParseYAML(test.config, env)
+			require.NoError(t, err)
+
+			e, err := newSchemaRegistryEncoderFromConfig(conf, service.MockResources())
+			if test.errContains == "" {
+				require.NoError(t, err)
+				assert.NotNil(t, e.autoRegister)
+				_ = e.Close(context.Background())
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+			}
+		})
+	}
+}
+
+func TestSchemaRegistryEncodeAutoRegisterFromFile(t *testing.T) {
+	fake, urlStr := runFakeSchemaRegistry(t)
+
+	schemaPath := filepath.Join(t.TempDir(), "schema.avsc")
+	require.NoError(t, os.WriteFile(schemaPath, []byte(testSchema), 0o644))
+
+	conf, err := schemaRegistryEncoderConfig().ParseYAML(fmt.Sprintf(`
+url: %v
+subject: foo
+auto_register:
+  enabled: true
+  schema_path: %v
+`, urlStr, schemaPath), nil)
+	require.NoError(t, err)
+
+	encoder, err := newSchemaRegistryEncoderFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = encoder.Close(context.Background())
+	})
+
+	for i := 0; i < 2; i++ {
+		outBatches, err := encoder.ProcessBatch(context.Background(), service.MessageBatch{
+			service.NewMessage([]byte(`{"Name":"foo","MaybeHobby":null}`)),
+		})
+		require.NoError(t, err)
+		require.Len(t, outBatches, 1)
+		require.Len(t, outBatches[0], 1)
+		require.NoError(t, outBatches[0][0].GetError())
+
+		b, err := outBatches[0][0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, "\x00\x00\x00\x00\x01\x06foo\x00\x00", string(b))
+	}
+	assert.Equal(t, []int{1}, fake.Versions("foo"))
+}
+
+func TestSchemaRegistryEncodeAutoRegisterCompatibility(t *testing.T) {
+	fake, urlStr := runFakeSchemaRegistry(t)
+	fake.Register("foo", SchemaInfo{
+		Type:   "JSON",
+		Schema: `{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}`,
+	})
+
+	conf, err := schemaRegistryEncoderConfig().ParseYAML(fmt.Sprintf(`
+url: %v
+subject: foo
+auto_register:
+  enabled: true
+  schema_type: JSON
+  schema_mapping: 'root = meta("schema")'
+  compatibility: backward
+`, urlStr), nil)
+	require.NoError(t, err)
+
+	encoder, err := newSchemaRegistryEncoderFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = encoder.Close(context.Background())
+	})
+
+	newMsg := func(content, schema string) *service.Message {
+		msg := service.NewMessage([]byte(content))
+		msg.MetaSetMut("schema", schema)
+		return msg
+	}
+
+	// A compatible schema is registered as a new version.
+	compatible := `{"type":"object","properties":{"name":{"type":"string"},"age":{"type":"integer"}},"required":["name"]}`
+	outBatches, err := encoder.ProcessBatch(context.Background(), service.MessageBatch{
+		newMsg(`{"name":"foo","age":10}`, compatible),
+		newMsg(`{"name":"bar","age":"nope"}`, compatible),
+	})
+	require.NoError(t, err)
+	require.Len(t, outBatches, 1)
+	require.Len(t, outBatches[0], 2)
+
+	require.NoError(t, outBatches[0][0].GetError())
+	b, err := outBatches[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "\x00\x00\x00\x00\x02"+`{"name":"foo","age":10}`, string(b))
+
+	require.Error(t, outBatches[0][1].GetError())
+	assert.Contains(t, outBatches[0][1].GetError().Error(), "json message does not conform to schema")
+	assert.Equal(t, []int{1, 2}, fake.Versions("foo"))
+
+	// An incompatible schema fails the entire batch and is not registered.
+	incompatible := `{"type":"object","properties":{"name":{"type":"string"},"age":{"type":"integer"},"email":{"type":"string"}},"required":["name","email"]}`
+	_, err = encoder.ProcessBatch(context.Background(), service.MessageBatch{
+		newMsg(`{"name":"foo","age":10}`, compatible),
+		newMsg(`{"name":"foo","email":"foo@example.com"}`, incompatible),
+	})
+	require.EqualError(t, err, "schema is not backward compatible with version 2 of subject 'foo': backward: #: property email is required by the reader but not the writer")
+	assert.Equal(t, []int{1, 2}, fake.Versions("foo"))
+
+	// An invalid schema fails the batch and is not registered.
+	_, err = encoder.ProcessBatch(context.Background(), service.MessageBatch{
+		newMsg(`{"name":"foo"}`, `{"type":"nope"}`),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse schema")
+	assert.Equal(t, []int{1, 2}, fake.Versions("foo"))
+}
+
+func TestSchemaRegistryEncodeAutoRegisterTypeMismatch(t *testing.T) {
+	fake, urlStr := runFakeSchemaRegistry(t)
+	fake.Register("foo", SchemaInfo{Type: "AVRO", Schema: `"string"`})
+
+	conf, err := schemaRegistryEncoderConfig().ParseYAML(fmt.Sprintf(`
+url: %v
+subject: foo
+auto_register:
+  enabled: true
+  schema_type: JSON
+  schema_mapping: 'root = {"type":"string"}'
+  compatibility: full
+`, urlStr), nil)
+	require.NoError(t, err)
+
+	encoder, err := newSchemaRegistryEncoderFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = encoder.Close(context.Background())
+	})
+
+	_, err = encoder.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`"foo"`)),
+	})
+	require.EqualError(t, err, "schema is not full compatible with version 1 of subject 'foo': schema type JSON does not match AVRO")
+	assert.Equal(t, []int{1}, fake.Versions("foo"))
+}
+
+func TestSchemaRegistryEncodeAutoRegisterExistingVersion(t *testing.T) {
+	fake, urlStr := runFakeSchemaRegistry(t)
+	fake.Register("foo", SchemaInfo{Type: "JSON", Schema: `{"type":"string"}`})
+	fake.Register("foo", SchemaInfo{Type: "JSON", Schema: `{"type":"number"}`})
+
+	conf, err := schemaRegistryEncoderConfig().ParseYAML(fmt.Sprintf(`
+url: %v
+subject: foo
+auto_register:
+  enabled: true
+  schema_type: JSON
+  schema_mapping: 'root = {"type":"string"}'
+  compatibility: full
+`, urlStr), nil)
+	require.NoError(t, err)
+
+	encoder, err := newSchemaRegistryEncoderFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = encoder.Close(context.Background())
+	})
+
+	// The schema is incompatible with the latest version but is already
+	// registered as an earlier version, which is reused.
+	outBatches, err := encoder.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`"foo"`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, outBatches, 1)
+	require.Len(t, outBatches[0], 1)
+	require.NoError(t, outBatches[0][0].GetError())
+
+	b, err := outBatches[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "\x00\x00\x00\x00\x01"+`"foo"`, string(b))
+	assert.Equal(t, []int{1, 2}, fake.Versions("foo"))
+}
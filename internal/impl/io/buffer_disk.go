@@ -0,0 +1,1029 @@
+package io
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	dbufFieldPath           = "path"
+	dbufFieldMaxSegmentSize = "max_segment_size"
+	dbufFieldFsync          = "fsync"
+	dbufFieldFsyncInterval  = "fsync_interval"
+	dbufFieldRetention      = "retention"
+	dbufFieldRetentionBytes = "max_bytes"
+	dbufFieldRetentionAge   = "max_age"
+	dbufFieldPreProcessors  = "pre_processors"
+	dbufFieldPostProcessors = "post_processors"
+)
+
+func diskBufferConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.26.0").
+		Summary("Stores messages in an append-only log of segment files on disk and acknowledges them at the input level.").
+		Description(`
+Batches are appended to a segment file within the configured directory, and once a segment reaches `+"`max_segment_size`"+` a new segment is started. Batches are then consumed in the order that they were written, and a segment is deleted once all of its batches have been successfully sent at the output level.
+
+The position of the oldest batch that has not yet been delivered is stored in a checkpoint file alongside the segments. When the service is restarted, including after a crash, Benthos resumes consumption from this checkpoint, which means any batches that were not delivered are replayed. Data at the end of a segment that was only partially written, for example due to a power loss, is detected and truncated on startup.
+
+This buffer does not depend on any external database or driver, and is intended for deployments that need to ride out long outages of a downstream service without losing data, such as edge deployments.
+
+## Delivery Guarantees
+
+Messages are not acknowledged at the input level until they have been written to a segment, and they are not removed from disk until they have been successfully delivered. The point at which written data is guaranteed to have reached the disk depends on the `+"`fsync`"+` policy, and with `+"`fsync: always`"+` at-least-once delivery guarantees are preserved even in cases where the machine itself is shut down unexpectedly. However, these delivery guarantees are not resilient to disk corruption or loss.
+
+A retention policy can be configured in order to limit the amount of disk used by the buffer, but when a limit is reached the oldest segments are deleted regardless of whether they have been delivered, which weakens the delivery guarantees of the pipeline.
+
+## Metrics
+
+This buffer emits the gauge `+"`buffer_disk_usage_bytes`"+`, which is the total size of all segment files, and the gauge `+"`buffer_disk_lag_bytes`"+`, which is the size of the data that has not yet been delivered. The counter `+"`buffer_disk_dropped_bytes`"+` is incremented when data that has not been delivered is deleted due to the retention policy.
+
+## Batching
+
+Messages that are logically batched at the point where they are added to the buffer will continue to be associated with that batch when they are consumed. This buffer is also more efficient when storing messages within batches, and therefore it is recommended to use batching at the input level in high-throughput use cases even if they are not required for processing.
+`).
+		Field(service.NewStringField(dbufFieldPath).
+			Description("The directory within which segment files are stored, which will be created if it does not already exist. Each disk buffer must be given a directory of its own.").
+			Example("./buffer")).
+		Field(service.NewIntField(dbufFieldMaxSegmentSize).
+			Description("The size in bytes at which a segment is closed and a new one is started. Segments are only deleted once all of their batches have been delivered, and therefore smaller segments release disk space sooner at the cost of more files.").
+			Default(67108864).
+			Advanced()).
+		Field(service.NewStringAnnotatedEnumField(dbufFieldFsync, map[string]string{
+			"always":   "Sync data to disk after each batch is written, and before the batch is acknowledged at the input level. This is the safest and slowest option.",
+			"interval": "Sync data to disk periodically at the period specified by `fsync_interval`. Batches written since the last sync may be lost if the machine is shut down unexpectedly.",
+			"never":    "Never explicitly sync data to disk and instead leave this to the operating system.",
+		}).
+			Description("The policy for syncing written data to disk.").
+			Default("interval")).
+		Field(service.NewDurationField(dbufFieldFsyncInterval).
+			Description("The period of time between syncs when `fsync` is set to `interval`.").
+			Default("1s").
+			Advanced()).
+		Field(service.NewObjectField(dbufFieldRetention,
+			service.NewIntField(dbufFieldRetentionBytes).
+				Description("The maximum total size in bytes of all segments. When exceeded the oldest segments are deleted, even if they contain batches that have not yet been delivered. Set to `0` to disable this limit.").
+				Default(0),
+			service.NewDurationField(dbufFieldRetentionAge).
+				Description("The maximum period of time since a segment was last written to. When exceeded the segment is deleted, even if it contains batches that have not yet been delivered. The segment currently being written to is never deleted.").
+				Example("24h").
+				Optional(),
+		).
+			Description("An optional policy for limiting the disk used by the buffer by deleting the oldest segments.").
+			Advanced()).
+		Field(service.NewProcessorListField(dbufFieldPreProcessors).
+			Description(`An optional list of processors to apply to messages before they are stored within the buffer. These processors are useful for compressing, archiving or otherwise reducing the data in size before it's stored on disk.`).
+			Optional()).
+		Field(service.NewProcessorListField(dbufFieldPostProcessors).
+			Description("An optional list of processors to apply to messages after they are consumed from the buffer. These processors are useful for undoing any compression, archiving, etc that may have been done by your `pre_processors`.").
+			Optional()).
+		Example("Riding out output outages", "This buffer stores up to 10GB of data, and once that limit is reached the oldest data is deleted in favour of new data.", `
+input:
+  mqtt:
+    urls: [ tcp://localhost:1883 ]
+    topics: [ sensors/# ]
+
+buffer:
+  disk:
+    path: ./data/buffer
+    fsync: interval
+    retention:
+      max_bytes: 10000000000
+
+output:
+  http_client:
+    url: https://example.com/ingest
+    verb: POST
+`)
+}
+
+func init() {
+	err := service.RegisterBatchBuffer(
+		"disk", diskBufferConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchBuffer, error) {
+			return newDiskBufferFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type diskFsyncPolicy int
+
+const (
+	diskFsyncInterval diskFsyncPolicy = iota
+	diskFsyncAlways
+	diskFsyncNever
+)
+
+func newDiskBufferFromConfig(conf *service.ParsedConfig, res *service.Resources) (*diskBuffer, error) {
+	b := &diskBuffer{
+		log:      res.Logger(),
+		mUsage:   res.Metrics().NewGauge("buffer_disk_usage_bytes"),
+		mLag:     res.Metrics().NewGauge("buffer_disk_lag_bytes"),
+		mDropped: res.Metrics().NewCounter("buffer_disk_dropped_bytes"),
+		cond:     sync.NewCond(&sync.Mutex{}),
+		closedC:  make(chan struct{}),
+	}
+
+	var err error
+	if b.dir, err = conf.FieldString(dbufFieldPath); err != nil {
+		return nil, err
+	}
+
+	var segSize int
+	if segSize, err = conf.FieldInt(dbufFieldMaxSegmentSize); err != nil {
+		return nil, err
+	}
+	if segSize <= 0 {
+		return nil, fmt.Errorf("%v must be greater than zero", dbufFieldMaxSegmentSize)
+	}
+	b.maxSegmentSize = int64(segSize)
+
+	var fsyncStr string
+	if fsyncStr, err = conf.FieldString(dbufFieldFsync); err != nil {
+		return nil, err
+	}
+	switch fsyncStr {
+	case "interval":
+		b.fsync = diskFsyncInterval
+	case "always":
+		b.fsync = diskFsyncAlways
+	case "never":
+		b.fsync = diskFsyncNever
+	default:
+		return nil, fmt.Errorf("unrecognised fsync policy: %v", fsyncStr)
+	}
+	if b.fsyncInterval, err = conf.FieldDuration(dbufFieldFsyncInterval); err != nil {
+		return nil, err
+	}
+
+	var retentionBytes int
+	if retentionBytes, err = conf.FieldInt(dbufFieldRetention, dbufFieldRetentionBytes); err != nil {
+		return nil, err
+	}
+	b.retentionBytes = int64(retentionBytes)
+	if conf.Contains(dbufFieldRetention, dbufFieldRetentionAge) {
+		if b.retentionAge, err = conf.FieldDuration(dbufFieldRetention, dbufFieldRetentionAge); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Contains(dbufFieldPreProcessors) {
+		if b.preProcs, err = conf.FieldProcessorList(dbufFieldPreProcessors); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(dbufFieldPostProcessors) {
+		if b.postProcs, err = conf.FieldProcessorList(dbufFieldPostProcessors); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := b.open(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	diskSegmentSuffix    = ".log"
+	diskCheckpointFile   = "checkpoint"
+	diskRecordHeaderSize = 8
+)
+
+var errDiskRecordCorrupt = errors.New("the record appears to be corrupt")
+
+// diskPos is the position of a record within the log.
+type diskPos struct {
+	seq    uint64
+	offset int64
+}
+
+type diskSegment struct {
+	seq     uint64
+	size    int64
+	modTime time.Time
+	reader  *os.File
+}
+
+type diskRecord struct {
+	pos     diskPos
+	acked   bool
+	dropped bool
+}
+
+// diskSegmentWriter appends records to the active segment, and is satisfied by
+// *os.File.
+type diskSegmentWriter interface {
+	io.Writer
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+type diskAckableBatch struct {
+	b   service.MessageBatch
+	aFn service.AckFunc
+}
+
+type diskBuffer struct {
+	dir            string
+	maxSegmentSize int64
+	fsync          diskFsyncPolicy
+	fsyncInterval  time.Duration
+	retentionBytes int64
+	retentionAge   time.Duration
+	preProcs       []*service.OwnedProcessor
+	postProcs      []*service.OwnedProcessor
+
+	log      *service.Logger
+	mUsage   *service.MetricGauge
+	mLag     *service.MetricGauge
+	mDropped *service.MetricCounter
+
+	cond     *sync.Cond
+	segments []*diskSegment
+	writer   diskSegmentWriter
+	dirty    bool
+	usage    int64
+
+	// The position of the next record to read, and the position of the oldest
+	// record that has not yet been delivered.
+	readPos    diskPos
+	checkpoint diskPos
+
+	// Records that have been read but not yet acknowledged, in the order that
+	// they were read, and records that need to be read again after a rejection.
+	inflight []*diskRecord
+	requeued []*diskRecord
+	pending  []diskAckableBatch
+
+	endOfInput bool
+	closed     bool
+	closedC    chan struct{}
+}
+
+func (b *diskBuffer) segmentPath(seq uint64) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%020d%v", seq, diskSegmentSuffix))
+}
+
+func (b *diskBuffer) open() error {
+	if err := os.MkdirAll(b.dir, 0o755); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), diskSegmentSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(e.Name(), diskSegmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		b.segments = append(b.segments, &diskSegment{
+			seq:     seq,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	sort.Slice(b.segments, func(i, j int) bool {
+		return b.segments[i].seq < b.segments[j].seq
+	})
+
+	if b.checkpoint, err = b.readCheckpoint(); err != nil {
+		return err
+	}
+
+	// Segments prior to the checkpoint were fully delivered before the last
+	// shutdown but were not yet deleted.
+	for len(b.segments) > 0 && b.segments[0].seq < b.checkpoint.seq {
+		if err := os.Remove(b.segmentPath(b.segments[0].seq)); err != nil {
+			return err
+		}
+		b.segments = b.segments[1:]
+	}
+
+	if len(b.segments) == 0 {
+		b.segments = append(b.segments, &diskSegment{
+			seq:     b.checkpoint.seq,
+			modTime: time.Now(),
+		})
+		b.checkpoint.offset = 0
+	} else if b.checkpoint.seq < b.segments[0].seq {
+		b.checkpoint = diskPos{seq: b.segments[0].seq}
+	}
+
+	// The last segment may have been partially written during a crash, in
+	// which case we truncate it to the last valid record.
+	last := b.segments[len(b.segments)-1]
+	validSize, err := scanDiskSegment(b.segmentPath(last.seq))
+	if err != nil {
+		return err
+	}
+	if validSize < last.size {
+		b.log.Warnf("Truncating %v bytes of corrupt data from the end of disk buffer segment %v", last.size-validSize, last.seq)
+		if err := os.Truncate(b.segmentPath(last.seq), validSize); err != nil {
+			return err
+		}
+		last.size = validSize
+	}
+	if b.checkpoint.seq == last.seq && b.checkpoint.offset > last.size {
+		b.checkpoint.offset = last.size
+	}
+
+	if b.writer, err = os.OpenFile(b.segmentPath(last.seq), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644); err != nil {
+		return err
+	}
+
+	for _, s := range b.segments {
+		b.usage += s.size
+	}
+	b.readPos = b.checkpoint
+	b.updateMetrics()
+
+	if b.fsync == diskFsyncInterval || b.retentionAge > 0 {
+		go b.loop()
+	}
+	return nil
+}
+
+// scanDiskSegment reads each record of a segment and returns the size of the
+// segment up until the first record that is incomplete or corrupt.
+func scanDiskSegment(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var offset int64
+	var header [diskRecordHeaderSize]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return offset, nil
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[:4]))
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return offset, nil
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+			return offset, nil
+		}
+		offset += int64(diskRecordHeaderSize + len(payload))
+	}
+}
+
+func (b *diskBuffer) readCheckpoint() (diskPos, error) {
+	cBytes, err := os.ReadFile(filepath.Join(b.dir, diskCheckpointFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return diskPos{}, nil
+		}
+		return diskPos{}, err
+	}
+	if len(cBytes) != 16 {
+		return diskPos{}, errors.New("the checkpoint file appears to be corrupt")
+	}
+	return diskPos{
+		seq:    binary.BigEndian.Uint64(cBytes[:8]),
+		offset: int64(binary.BigEndian.Uint64(cBytes[8:])),
+	}, nil
+}
+
+func (b *diskBuffer) writeCheckpoint() error {
+	var cBytes [16]byte
+	binary.BigEndian.PutUint64(cBytes[:8], b.checkpoint.seq)
+	binary.BigEndian.PutUint64(cBytes[8:], uint64(b.checkpoint.offset))
+
+	// Write to a temporary file first so that the checkpoint is replaced
+	// atomically.
+	tmpPath := filepath.Join(b.dir, diskCheckpointFile+".tmp")
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(cBytes[:]); err == nil && b.fsync == diskFsyncAlways {
+		err = f.Sync()
+	}
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(b.dir, diskCheckpointFile))
+}
+
+func (b *diskBuffer) loop() {
+	period := time.Second
+	if b.fsync == diskFsyncInterval {
+		period = b.fsyncInterval
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.closedC:
+			return
+		}
+
+		b.cond.L.Lock()
+		if b.closed {
+			b.cond.L.Unlock()
+			return
+		}
+		if b.fsync == diskFsyncInterval && b.dirty {
+			if err := b.writer.Sync(); err != nil {
+				b.log.Errorf("Failed to sync disk buffer segment: %v", err)
+			} else {
+				b.dirty = false
+			}
+		}
+		if b.retentionAge > 0 {
+			b.enforceRetention()
+		}
+		b.cond.L.Unlock()
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (b *diskBuffer) updateMetrics() {
+	lag := b.usage
+	if b.segments[0].seq == b.checkpoint.seq {
+		lag -= b.checkpoint.offset
+	}
+	b.mUsage.Set(b.usage)
+	b.mLag.Set(lag)
+}
+
+func (b *diskBuffer) getSegment(seq uint64) (int, *diskSegment) {
+	for i, s := range b.segments {
+		if s.seq == seq {
+			return i, s
+		}
+	}
+	return -1, nil
+}
+
+func (b *diskBuffer) removeOldestSegment() {
+	s := b.segments[0]
+	if s.reader != nil {
+		_ = s.reader.Close()
+	}
+	if err := os.Remove(b.segmentPath(s.seq)); err != nil {
+		b.log.Errorf("Failed to remove disk buffer segment %v: %v", s.seq, err)
+	}
+	b.usage -= s.size
+	b.segments = b.segments[1:]
+}
+
+func (b *diskBuffer) rotate() error {
+	if b.fsync != diskFsyncNever && b.dirty {
+		if err := b.writer.Sync(); err != nil {
+			return err
+		}
+		b.dirty = false
+	}
+	if err := b.writer.Close(); err != nil {
+		return err
+	}
+
+	seg := &diskSegment{
+		seq:     b.segments[len(b.segments)-1].seq + 1,
+		modTime: time.Now(),
+	}
+
+	w, err := os.OpenFile(b.segmentPath(seg.seq), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	b.writer = w
+	b.segments = append(b.segments, seg)
+	return nil
+}
+
+// appendRecord writes a record to the end of the active segment. When the
+// write fails partway the segment is truncated back to its prior size, or if
+// that also fails a new segment is started, so that subsequent records are not
+// written after a torn one.
+func (b *diskBuffer) appendRecord(record []byte) error {
+	active := b.segments[len(b.segments)-1]
+
+	n, err := b.writer.Write(record)
+	if err != nil {
+		if n > 0 {
+			if tErr := b.writer.Truncate(active.size); tErr != nil {
+				b.log.Errorf("Failed to truncate a partially written record from disk buffer segment %v: %v", active.seq, tErr)
+				if rErr := b.rotate(); rErr != nil {
+					b.log.Errorf("Failed to rotate disk buffer segment %v: %v", active.seq, rErr)
+				}
+			}
+		}
+		return err
+	}
+
+	active.size += int64(n)
+	active.modTime = time.Now()
+	b.usage += int64(n)
+	b.dirty = true
+	return nil
+}
+
+// advance moves the checkpoint forward to the oldest record that has not yet
+// been delivered, and deletes any segments that precede it.
+func (b *diskBuffer) advance() {
+	for len(b.inflight) > 0 && b.inflight[0].acked {
+		b.inflight[0] = nil
+		b.inflight = b.inflight[1:]
+	}
+
+	wm := b.readPos
+	if len(b.inflight) > 0 {
+		wm = b.inflight[0].pos
+	}
+
+	for len(b.segments) > 1 && b.segments[0].seq < wm.seq {
+		b.removeOldestSegment()
+	}
+
+	if wm != b.checkpoint {
+		b.checkpoint = wm
+		if err := b.writeCheckpoint(); err != nil {
+			b.log.Errorf("Failed to write disk buffer checkpoint: %v", err)
+		}
+	}
+	b.updateMetrics()
+}
+
+// enforceRetention deletes the oldest segments until the retention policy is
+// satisfied, the segment being written to is never deleted.
+func (b *diskBuffer) enforceRetention() {
+	var dropped bool
+	for len(b.segments) > 1 {
+		oldest := b.segments[0]
+		exceedsBytes := b.retentionBytes > 0 && b.usage > b.retentionBytes
+		exceedsAge := b.retentionAge > 0 && time.Since(oldest.modTime) > b.retentionAge
+		if !exceedsBytes && !exceedsAge {
+			break
+		}
+
+		lostBytes := oldest.size
+		if b.checkpoint.seq == oldest.seq {
+			lostBytes -= b.checkpoint.offset
+		}
+		if lostBytes > 0 {
+			b.log.Warnf("Deleting disk buffer segment %v due to retention policy, %v bytes of undelivered data were lost", oldest.seq, lostBytes)
+			b.mDropped.Incr(lostBytes)
+		}
+
+		tmpInflight := b.inflight[:0]
+		for _, r := range b.inflight {
+			if r.pos.seq == oldest.seq {
+				r.dropped = true
+			} else {
+				tmpInflight = append(tmpInflight, r)
+			}
+		}
+		b.inflight = tmpInflight
+
+		if b.readPos.seq == oldest.seq {
+			b.readPos = diskPos{seq: b.segments[1].seq}
+		}
+		b.removeOldestSegment()
+		dropped = true
+	}
+	if dropped {
+		b.advance()
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type diskMessage struct {
+	Content  []byte         `msgpack:"c"`
+	Metadata map[string]any `msgpack:"m"`
+}
+
+func encodeDiskBatch(batch service.MessageBatch) ([]byte, error) {
+	msgs := make([]diskMessage, len(batch))
+	for i, msg := range batch {
+		var err error
+		if msgs[i].Content, err = msg.AsBytes(); err != nil {
+			return nil, err
+		}
+		msgs[i].Metadata = map[string]any{}
+		_ = msg.MetaWalkMut(func(key string, value any) error {
+			msgs[i].Metadata[key] = value
+			return nil
+		})
+	}
+	payload, err := msgpack.Marshal(msgs)
+	if err != nil {
+		return nil, err
+	}
+
+	record := make([]byte, diskRecordHeaderSize, diskRecordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:], crc32.ChecksumIEEE(payload))
+	return append(record, payload...), nil
+}
+
+func decodeDiskBatch(payload []byte) (service.MessageBatch, error) {
+	var msgs []diskMessage
+	if err := msgpack.Unmarshal(payload, &msgs); err != nil {
+		return nil, err
+	}
+	batch := make(service.MessageBatch, len(msgs))
+	for i, m := range msgs {
+		batch[i] = service.NewMessage(m.Content)
+		for k, v := range m.Metadata {
+			batch[i].MetaSetMut(k, v)
+		}
+	}
+	return batch, nil
+}
+
+// readRecord reads the batch at a given position and returns it along with
+// the position of the next record.
+func (b *diskBuffer) readRecord(pos diskPos) (service.MessageBatch, diskPos, error) {
+	_, seg := b.getSegment(pos.seq)
+	if seg == nil {
+		return nil, pos, fmt.Errorf("segment %v does not exist", pos.seq)
+	}
+	if seg.reader == nil {
+		var err error
+		if seg.reader, err = os.Open(b.segmentPath(seg.seq)); err != nil {
+			return nil, pos, err
+		}
+	}
+
+	var header [diskRecordHeaderSize]byte
+	if _, err := seg.reader.ReadAt(header[:], pos.offset); err != nil {
+		return nil, pos, err
+	}
+
+	payloadLen := int64(binary.BigEndian.Uint32(header[:4]))
+	next := diskPos{seq: pos.seq, offset: pos.offset + diskRecordHeaderSize + payloadLen}
+	if next.offset > seg.size {
+		return nil, pos, errDiskRecordCorrupt
+	}
+
+	payload := make([]byte, payloadLen)
+	if _, err := seg.reader.ReadAt(payload, pos.offset+diskRecordHeaderSize); err != nil {
+		return nil, pos, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, pos, errDiskRecordCorrupt
+	}
+
+	batch, err := decodeDiskBatch(payload)
+	return batch, next, err
+}
+
+// nextRecord returns the next record to be consumed, prioritising those that
+// were rejected. Returns nil when there are no records ready.
+func (b *diskBuffer) nextRecord() (*diskRecord, service.MessageBatch) {
+	for len(b.requeued) > 0 {
+		rec := b.requeued[0]
+		b.requeued[0] = nil
+		b.requeued = b.requeued[1:]
+		if rec.dropped || rec.acked {
+			continue
+		}
+		batch, _, err := b.readRecord(rec.pos)
+		if err != nil {
+			b.log.Errorf("Failed to read disk buffer record from segment %v: %v", rec.pos.seq, err)
+			rec.acked = true
+			b.advance()
+			continue
+		}
+		return rec, batch
+	}
+
+	for {
+		i, seg := b.getSegment(b.readPos.seq)
+		if seg == nil {
+			return nil, nil
+		}
+		if b.readPos.offset >= seg.size {
+			if i == len(b.segments)-1 {
+				return nil, nil
+			}
+			b.readPos = diskPos{seq: b.segments[i+1].seq}
+			b.advance()
+			continue
+		}
+
+		batch, next, err := b.readRecord(b.readPos)
+		if err != nil {
+			b.log.Errorf("Skipping the remainder of disk buffer segment %v due to a failed read: %v", seg.seq, err)
+			b.readPos.offset = seg.size
+			continue
+		}
+
+		rec := &diskRecord{pos: b.readPos}
+		b.readPos = next
+		b.inflight = append(b.inflight, rec)
+		return rec, batch
+	}
+}
+
+func (b *diskBuffer) recordAckFn(rec *diskRecord) service.AckFunc {
+	return func(ctx context.Context, err error) error {
+		b.cond.L.Lock()
+		defer b.cond.L.Unlock()
+
+		if rec.dropped || rec.acked || b.closed {
+			return nil
+		}
+		if err != nil {
+			b.requeued = append(b.requeued, rec)
+		} else {
+			rec.acked = true
+			b.advance()
+		}
+		b.cond.Broadcast()
+		return nil
+	}
+}
+
+func (b *diskBuffer) toAckableBatches(batches []service.MessageBatch, rec *diskRecord) []diskAckableBatch {
+	endAckFn := b.recordAckFn(rec)
+	if len(batches) == 1 {
+		return []diskAckableBatch{
+			{b: batches[0], aFn: endAckFn},
+		}
+	}
+
+	pendingResponses := int64(len(batches))
+	aBatches := make([]diskAckableBatch, len(batches))
+	var ackOnce sync.Once
+	for i := range batches {
+		aBatches[i] = diskAckableBatch{b: batches[i], aFn: func(ctx context.Context, err error) error {
+			if atomic.AddInt64(&pendingResponses, -1) == 0 || err != nil {
+				var ackErr error
+				ackOnce.Do(func() {
+					ackErr = endAckFn(ctx, err)
+				})
+				return ackErr
+			}
+			return nil
+		}}
+	}
+	return aBatches
+}
+
+// ReadBatch attempts to read the next batch from disk.
+func (b *diskBuffer) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	ctx, done := context.WithCancel(ctx)
+	defer done()
+
+	go func() {
+		<-ctx.Done()
+		b.cond.Broadcast()
+	}()
+
+	b.cond.L.Lock()
+	defer b.cond.L.Unlock()
+
+	for len(b.pending) == 0 {
+		if b.closed {
+			return nil, nil, service.ErrEndOfBuffer
+		}
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+
+		if rec, nextBatch := b.nextRecord(); rec != nil {
+			resBatches := []service.MessageBatch{nextBatch}
+			for _, proc := range b.postProcs {
+				var tmpResBatch []service.MessageBatch
+				for _, batch := range resBatches {
+					resBatches, err := proc.ProcessBatch(ctx, batch)
+					if err != nil {
+						b.requeued = append(b.requeued, rec)
+						return nil, nil, err
+					}
+					tmpResBatch = append(tmpResBatch, resBatches...)
+				}
+				resBatches = tmpResBatch
+			}
+			if len(resBatches) == 0 {
+				// All messages were filtered by the post processors, and
+				// therefore the record is considered delivered.
+				rec.acked = true
+				b.advance()
+				continue
+			}
+			b.pending = b.toAckableBatches(resBatches, rec)
+			break
+		}
+		if b.endOfInput && len(b.inflight) == 0 {
+			return nil, nil, service.ErrEndOfBuffer
+		}
+
+		// None of our exit conditions triggered, so exit
+		b.cond.Wait()
+	}
+
+	tmp := b.pending[0]
+	b.pending = b.pending[1:]
+	return tmp.b, tmp.aFn, nil
+}
+
+// WriteBatch appends a batch to the active segment. When pre-processors split
+// the batch into multiple records either all of them are written or none are,
+// so that a rejected batch does not leave duplicates behind once it is
+// redelivered.
+func (b *diskBuffer) WriteBatch(ctx context.Context, msgBatch service.MessageBatch, aFn service.AckFunc) error {
+	msgBatches := []service.MessageBatch{msgBatch}
+	for _, proc := range b.preProcs {
+		var tmpResBatch []service.MessageBatch
+		for _, batch := range msgBatches {
+			resBatches, err := proc.ProcessBatch(ctx, batch)
+			if err != nil {
+				return err
+			}
+			tmpResBatch = append(tmpResBatch, resBatches...)
+		}
+		msgBatches = tmpResBatch
+	}
+
+	records := make([][]byte, len(msgBatches))
+	for i, batch := range msgBatches {
+		var err error
+		if records[i], err = encodeDiskBatch(batch); err != nil {
+			return err
+		}
+	}
+
+	if err := b.appendRecords(records); err != nil {
+		return err
+	}
+	return aFn(ctx, nil)
+}
+
+func (b *diskBuffer) appendRecords(records [][]byte) error {
+	b.cond.L.Lock()
+	defer b.cond.L.Unlock()
+
+	if b.closed {
+		return component.ErrTypeClosed
+	}
+
+	active := b.segments[len(b.segments)-1]
+	start := diskPos{seq: active.seq, offset: active.size}
+
+	var rotated bool
+	err := func() error {
+		for _, record := range records {
+			active := b.segments[len(b.segments)-1]
+			if active.size > 0 && active.size+int64(len(record)) > b.maxSegmentSize {
+				rotated = true
+				if err := b.rotate(); err != nil {
+					return err
+				}
+			}
+			if err := b.appendRecord(record); err != nil {
+				return err
+			}
+		}
+		if b.fsync == diskFsyncAlways && b.dirty {
+			if err := b.writer.Sync(); err != nil {
+				return err
+			}
+			b.dirty = false
+		}
+		return nil
+	}()
+	if err != nil {
+		if tErr := b.truncateTo(start, rotated); tErr != nil {
+			b.log.Errorf("Failed to remove a partially written batch from disk buffer segment %v: %v", start.seq, tErr)
+		}
+		b.updateMetrics()
+		return err
+	}
+
+	b.enforceRetention()
+	b.updateMetrics()
+	b.cond.Broadcast()
+	return nil
+}
+
+// truncateTo discards everything written after a position, including any
+// segments that were started after it. When the writer was rotated away from
+// the segment of the position it is reopened.
+func (b *diskBuffer) truncateTo(pos diskPos, rotated bool) error {
+	if rotated {
+		_ = b.writer.Close()
+		for len(b.segments) > 1 && b.segments[len(b.segments)-1].seq > pos.seq {
+			s := b.segments[len(b.segments)-1]
+			if s.reader != nil {
+				_ = s.reader.Close()
+			}
+			if err := os.Remove(b.segmentPath(s.seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				b.log.Errorf("Failed to remove disk buffer segment %v: %v", s.seq, err)
+			}
+			b.usage -= s.size
+			b.segments = b.segments[:len(b.segments)-1]
+		}
+
+		w, err := os.OpenFile(b.segmentPath(pos.seq), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			return err
+		}
+		b.writer = w
+	}
+
+	active := b.segments[len(b.segments)-1]
+	if active.seq != pos.seq || active.size <= pos.offset {
+		return nil
+	}
+	if err := b.writer.Truncate(pos.offset); err != nil {
+		if rErr := b.rotate(); rErr != nil {
+			b.log.Errorf("Failed to rotate disk buffer segment %v: %v", active.seq, rErr)
+		}
+		return err
+	}
+	b.usage -= active.size - pos.offset
+	active.size = pos.offset
+	return nil
+}
+
+// EndOfInput signals to the buffer that the input is finished and therefore
+// once the remaining data has been delivered it should close.
+func (b *diskBuffer) EndOfInput() {
+	go func() {
+		b.cond.L.Lock()
+		defer b.cond.L.Unlock()
+
+		b.endOfInput = true
+		b.cond.Broadcast()
+	}()
+}
+
+// Close syncs any remaining data to disk and closes all files.
+func (b *diskBuffer) Close(ctx context.Context) error {
+	b.cond.L.Lock()
+	defer b.cond.L.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	close(b.closedC)
+	b.cond.Broadcast()
+
+	var err error
+	if b.fsync != diskFsyncNever && b.dirty {
+		err = b.writer.Sync()
+	}
+	if cErr := b.writer.Close(); err == nil {
+		err = cErr
+	}
+	for _, s := range b.segments {
+		if s.reader != nil {
+			_ = s.reader.Close()
+			s.reader = nil
+		}
+	}
+	return err
+}
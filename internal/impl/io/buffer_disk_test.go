@@ -0,0 +1,346 @@
+package io
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func diskBufFromConf(t testing.TB, conf string) *diskBuffer {
+	t.Helper()
+
+	parsedConf, err := diskBufferConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	buf, err := newDiskBufferFromConfig(parsedConf, service.MockResources())
+	require.NoError(t, err)
+
+	return buf
+}
+
+func diskBufWrite(t testing.TB, buf *diskBuffer, contents ...string) {
+	t.Helper()
+
+	var batch service.MessageBatch
+	for _, c := range contents {
+		msg := service.NewMessage([]byte(c))
+		msg.MetaSetMut("content", c)
+		batch = append(batch, msg)
+	}
+
+	var acked bool
+	require.NoError(t, buf.WriteBatch(context.Background(), batch, func(ctx context.Context, err error) error {
+		acked = true
+		return err
+	}))
+	assert.True(t, acked)
+}
+
+func diskBufRead(t testing.TB, buf *diskBuffer, contents ...string) service.AckFunc {
+	t.Helper()
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	batch, ackFn, err := buf.ReadBatch(tCtx)
+	require.NoError(t, err)
+	require.Len(t, batch, len(contents))
+
+	for i, c := range contents {
+		mBytes, err := batch[i].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, c, string(mBytes))
+
+		v, exists := batch[i].MetaGetMut("content")
+		require.True(t, exists)
+		assert.Equal(t, c, v)
+	}
+	return ackFn
+}
+
+func diskBufSegments(t testing.TB, dir string) []string {
+	t.Helper()
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	require.NoError(t, err)
+	for i, m := range matches {
+		matches[i] = filepath.Base(m)
+	}
+	return matches
+}
+
+func TestDiskBufferWriteRead(t *testing.T) {
+	dir := t.TempDir()
+	buf := diskBufFromConf(t, `
+path: `+dir+`
+fsync: always
+`)
+
+	diskBufWrite(t, buf, "hello", "world")
+	diskBufWrite(t, buf, "foo")
+
+	ackFn := diskBufRead(t, buf, "hello", "world")
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	ackFn = diskBufRead(t, buf, "foo")
+	require.NoError(t, ackFn(context.Background(), errors.New("nope")))
+
+	ackFn = diskBufRead(t, buf, "foo")
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	assert.Equal(t, buf.usage, buf.checkpoint.offset)
+
+	buf.EndOfInput()
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	_, _, err := buf.ReadBatch(tCtx)
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+	require.NoError(t, buf.Close(tCtx))
+}
+
+func TestDiskBufferReplay(t *testing.T) {
+	dir := t.TempDir()
+	conf := `
+path: ` + dir + `
+fsync: never
+`
+
+	buf := diskBufFromConf(t, conf)
+	diskBufWrite(t, buf, "first")
+	diskBufWrite(t, buf, "second")
+	diskBufWrite(t, buf, "third")
+
+	firstAckFn := diskBufRead(t, buf, "first")
+	_ = diskBufRead(t, buf, "second")
+	thirdAckFn := diskBufRead(t, buf, "third")
+
+	require.NoError(t, thirdAckFn(context.Background(), nil))
+	require.NoError(t, firstAckFn(context.Background(), nil))
+	require.NoError(t, buf.Close(context.Background()))
+
+	// The second batch was never acknowledged and is therefore replayed along
+	// with everything after it.
+	buf = diskBufFromConf(t, conf)
+	diskBufWrite(t, buf, "fourth")
+
+	ackFn := diskBufRead(t, buf, "second")
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	ackFn = diskBufRead(t, buf, "third")
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	ackFn = diskBufRead(t, buf, "fourth")
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	require.NoError(t, buf.Close(context.Background()))
+}
+
+func TestDiskBufferSegmentsAndRetention(t *testing.T) {
+	dir := t.TempDir()
+	buf := diskBufFromConf(t, `
+path: `+dir+`
+max_segment_size: 10
+`)
+
+	diskBufWrite(t, buf, "first")
+	diskBufWrite(t, buf, "second")
+	diskBufWrite(t, buf, "third")
+
+	assert.Equal(t, []string{
+		"00000000000000000000.log",
+		"00000000000000000001.log",
+		"00000000000000000002.log",
+	}, diskBufSegments(t, dir))
+
+	ackFn := diskBufRead(t, buf, "first")
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	// Reading the next record moves the read position into the next segment
+	// and the first can be deleted.
+	ackFn = diskBufRead(t, buf, "second")
+	assert.Equal(t, []string{
+		"00000000000000000001.log",
+		"00000000000000000002.log",
+	}, diskBufSegments(t, dir))
+
+	require.NoError(t, ackFn(context.Background(), nil))
+	require.NoError(t, buf.Close(context.Background()))
+
+	// Reopen with a retention policy that only allows a single segment.
+	buf = diskBufFromConf(t, `
+path: `+dir+`
+max_segment_size: 10
+retention:
+  max_bytes: 1
+`)
+	diskBufWrite(t, buf, "fourth")
+	assert.Equal(t, []string{
+		"00000000000000000003.log",
+	}, diskBufSegments(t, dir))
+
+	ackFn = diskBufRead(t, buf, "fourth")
+	require.NoError(t, ackFn(context.Background(), nil))
+	require.NoError(t, buf.Close(context.Background()))
+}
+
+func TestDiskBufferCorruptTail(t *testing.T) {
+	dir := t.TempDir()
+	conf := `
+path: ` + dir + `
+`
+
+	buf := diskBufFromConf(t, conf)
+	diskBufWrite(t, buf, "first")
+	diskBufWrite(t, buf, "second")
+	require.NoError(t, buf.Close(context.Background()))
+
+	segPath := filepath.Join(dir, "00000000000000000000.log")
+	info, err := os.Stat(segPath)
+	require.NoError(t, err)
+
+	// Simulate a crash part way through writing the second record.
+	require.NoError(t, os.Truncate(segPath, info.Size()-3))
+
+	buf = diskBufFromConf(t, conf)
+	diskBufWrite(t, buf, "third")
+
+	ackFn := diskBufRead(t, buf, "first")
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	ackFn = diskBufRead(t, buf, "third")
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	require.NoError(t, buf.Close(context.Background()))
+}
+
+type tornDiskSegmentWriter struct {
+	diskSegmentWriter
+}
+
+func (t tornDiskSegmentWriter) Write(p []byte) (int, error) {
+	n, _ := t.diskSegmentWriter.Write(p[:len(p)/2])
+	return n, errors.New("disk full")
+}
+
+func TestDiskBufferTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	conf := `
+path: ` + dir + `
+`
+
+	buf := diskBufFromConf(t, conf)
+	diskBufWrite(t, buf, "first")
+
+	// Simulate a write that fails part way through a record.
+	writer := buf.writer
+	buf.writer = tornDiskSegmentWriter{diskSegmentWriter: writer}
+	err := buf.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("torn")),
+	}, func(ctx context.Context, err error) error {
+		t.Error("unexpected ack")
+		return err
+	})
+	require.EqualError(t, err, "disk full")
+	buf.writer = writer
+
+	diskBufWrite(t, buf, "second")
+
+	ackFn := diskBufRead(t, buf, "first")
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	ackFn = diskBufRead(t, buf, "second")
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	require.NoError(t, buf.Close(context.Background()))
+
+	// The segment contains only complete records.
+	segPath := filepath.Join(dir, "00000000000000000000.log")
+	info, err := os.Stat(segPath)
+	require.NoError(t, err)
+
+	validSize, err := scanDiskSegment(segPath)
+	require.NoError(t, err)
+	assert.Equal(t, info.Size(), validSize)
+}
+
+type failingDiskSegmentWriter struct {
+	diskSegmentWriter
+	writes int
+}
+
+func (f *failingDiskSegmentWriter) Write(p []byte) (int, error) {
+	if f.writes--; f.writes < 0 {
+		return 0, errors.New("disk full")
+	}
+	return f.diskSegmentWriter.Write(p)
+}
+
+func TestDiskBufferSplitBatchWriteFailure(t *testing.T) {
+	dir := t.TempDir()
+	conf := `
+path: ` + dir + `
+pre_processors:
+  - split:
+      size: 1
+`
+
+	buf := diskBufFromConf(t, conf)
+	diskBufWrite(t, buf, "first")
+	sizeBefore := buf.usage
+
+	// Simulate a write that fails on the second record of a split batch.
+	writer := buf.writer
+	buf.writer = &failingDiskSegmentWriter{diskSegmentWriter: writer, writes: 1}
+	err := buf.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("a")),
+		service.NewMessage([]byte("b")),
+		service.NewMessage([]byte("c")),
+	}, func(ctx context.Context, err error) error {
+		t.Error("unexpected ack")
+		return err
+	})
+	require.EqualError(t, err, "disk full")
+	buf.writer = writer
+	assert.Equal(t, sizeBefore, buf.usage)
+
+	diskBufWrite(t, buf, "second")
+
+	ackFn := diskBufRead(t, buf, "first")
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	ackFn = diskBufRead(t, buf, "second")
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	require.NoError(t, buf.Close(context.Background()))
+}
+
+func TestDiskBufferPrePostProcessors(t *testing.T) {
+	dir := t.TempDir()
+	buf := diskBufFromConf(t, `
+path: `+dir+`
+pre_processors:
+  - mapping: 'root = if content() == "drop me" { deleted() }'
+post_processors:
+  - mapping: 'root = if content() == "filter me" { deleted() }'
+`)
+
+	diskBufWrite(t, buf, "drop me")
+	diskBufWrite(t, buf, "filter me")
+	diskBufWrite(t, buf, "keep me")
+
+	ackFn := diskBufRead(t, buf, "keep me")
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	assert.Equal(t, buf.usage, buf.checkpoint.offset)
+	require.NoError(t, buf.Close(context.Background()))
+}
@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
@@ -88,6 +89,35 @@ func (f *fileCache) Delete(_ context.Context, key string) error {
 	return f.mgr.FS().Remove(filepath.Join(f.dir, key))
 }
 
+func (f *fileCache) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) error) error {
+	root := filepath.Clean(f.dir)
+	return fs.WalkDir(f.mgr.FS(), root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		key, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if key = filepath.ToSlash(key); !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		b, err := ifs.ReadFile(f.mgr.FS(), p)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		return fn(key, b)
+	})
+}
+
 func (f *fileCache) Close(context.Context) error {
 	return nil
 }
@@ -3,6 +3,7 @@ package io
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -48,3 +49,36 @@ func TestFileCache(t *testing.T) {
 	_, err = c.Get(tCtx, "foo")
 	assert.Equal(t, service.ErrKeyNotFound, err)
 }
+
+func TestFileCacheScan(t *testing.T) {
+	dir := t.TempDir()
+
+	tCtx := context.Background()
+	c := newFileCache(dir, service.MockResources())
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "foo"), 0o755))
+	require.NoError(t, c.Set(tCtx, "foo/a", []byte("1"), nil))
+	require.NoError(t, c.Set(tCtx, "foo/b", []byte("2"), nil))
+	require.NoError(t, c.Set(tCtx, "bar", []byte("3"), nil))
+
+	values := map[string]string{}
+	require.NoError(t, c.Scan(tCtx, "", func(key string, value []byte) error {
+		values[key] = string(value)
+		return nil
+	}))
+	assert.Equal(t, map[string]string{
+		"foo/a": "1",
+		"foo/b": "2",
+		"bar":   "3",
+	}, values)
+
+	values = map[string]string{}
+	require.NoError(t, c.Scan(tCtx, "foo/", func(key string, value []byte) error {
+		values[key] = string(value)
+		return nil
+	}))
+	assert.Equal(t, map[string]string{
+		"foo/a": "1",
+		"foo/b": "2",
+	}, values)
+}
@@ -0,0 +1,201 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// franzTopicConfig describes a topic to be created, where a partition count or
+// replication factor of -1 selects the default of the cluster.
+type franzTopicConfig struct {
+	partitions        int32
+	replicationFactor int16
+	configs           map[string]string
+}
+
+// franzCreateTopic creates a topic, returning an error wrapping
+// kerr.TopicAlreadyExists if it already exists.
+func franzCreateTopic(ctx context.Context, cl *kgo.Client, topic string, conf franzTopicConfig) error {
+	reqTopic := kmsg.NewCreateTopicsRequestTopic()
+	reqTopic.Topic = topic
+	reqTopic.NumPartitions = conf.partitions
+	reqTopic.ReplicationFactor = conf.replicationFactor
+	for k, v := range conf.configs {
+		reqConfig := kmsg.NewCreateTopicsRequestTopicConfig()
+		reqConfig.Name = k
+		reqConfig.Value = kmsg.StringPtr(v)
+		reqTopic.Configs = append(reqTopic.Configs, reqConfig)
+	}
+
+	req := kmsg.NewPtrCreateTopicsRequest()
+	req.Topics = append(req.Topics, reqTopic)
+	if deadline, ok := ctx.Deadline(); ok {
+		req.TimeoutMillis = int32(time.Until(deadline).Milliseconds())
+	}
+
+	res, err := req.RequestWith(ctx, cl)
+	if err != nil {
+		return err
+	}
+	if len(res.Topics) != 1 {
+		return fmt.Errorf("expected one topic in response, saw %d", len(res.Topics))
+	}
+	return franzErrorWithMessage(res.Topics[0].ErrorCode, res.Topics[0].ErrorMessage)
+}
+
+// franzDeleteTopic deletes a topic, returning an error wrapping
+// kerr.UnknownTopicOrPartition if it does not exist.
+func franzDeleteTopic(ctx context.Context, cl *kgo.Client, topic string) error {
+	reqTopic := kmsg.NewDeleteTopicsRequestTopic()
+	reqTopic.Topic = kmsg.StringPtr(topic)
+
+	req := kmsg.NewPtrDeleteTopicsRequest()
+	req.TopicNames = []string{topic}
+	req.Topics = append(req.Topics, reqTopic)
+	if deadline, ok := ctx.Deadline(); ok {
+		req.TimeoutMillis = int32(time.Until(deadline).Milliseconds())
+	}
+
+	res, err := req.RequestWith(ctx, cl)
+	if err != nil {
+		return err
+	}
+	if len(res.Topics) != 1 {
+		return fmt.Errorf("expected one topic in response, saw %d", len(res.Topics))
+	}
+	return franzErrorWithMessage(res.Topics[0].ErrorCode, res.Topics[0].ErrorMessage)
+}
+
+type franzPartitionLag struct {
+	Topic           string `json:"topic"`
+	Partition       int32  `json:"partition"`
+	CommittedOffset int64  `json:"committed_offset"`
+	EndOffset       int64  `json:"end_offset"`
+	Lag             int64  `json:"lag"`
+}
+
+// franzGroupLag returns the lag of each topic partition that a consumer group
+// has committed offsets for, sorted by topic and partition.
+func franzGroupLag(ctx context.Context, cl *kgo.Client, group string) ([]franzPartitionLag, error) {
+	fetchReq := kmsg.NewPtrOffsetFetchRequest()
+	fetchReq.Group = group
+
+	fetchRes, err := fetchReq.RequestWith(ctx, cl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch offsets: %w", err)
+	}
+	if err := kerr.ErrorForCode(fetchRes.ErrorCode); err != nil {
+		return nil, fmt.Errorf("failed to fetch offsets: %w", err)
+	}
+
+	var lags []franzPartitionLag
+	listReq := kmsg.NewPtrListOffsetsRequest()
+	listReq.ReplicaID = -1
+	for _, t := range fetchRes.Topics {
+		listTopic := kmsg.NewListOffsetsRequestTopic()
+		listTopic.Topic = t.Topic
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return nil, fmt.Errorf("failed to fetch offset of topic %v partition %v: %w", t.Topic, p.Partition, err)
+			}
+			lags = append(lags, franzPartitionLag{
+				Topic:           t.Topic,
+				Partition:       p.Partition,
+				CommittedOffset: p.Offset,
+			})
+
+			listPartition := kmsg.NewListOffsetsRequestTopicPartition()
+			listPartition.Partition = p.Partition
+			listPartition.Timestamp = -1 // The latest offset
+			listTopic.Partitions = append(listTopic.Partitions, listPartition)
+		}
+		listReq.Topics = append(listReq.Topics, listTopic)
+	}
+	if len(lags) == 0 {
+		return lags, nil
+	}
+
+	listRes, err := listReq.RequestWith(ctx, cl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list end offsets: %w", err)
+	}
+
+	endOffsets := map[string]map[int32]int64{}
+	for _, t := range listRes.Topics {
+		partitions := map[int32]int64{}
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return nil, fmt.Errorf("failed to list end offset of topic %v partition %v: %w", t.Topic, p.Partition, err)
+			}
+			partitions[p.Partition] = p.Offset
+		}
+		endOffsets[t.Topic] = partitions
+	}
+
+	for i, l := range lags {
+		end, exists := endOffsets[l.Topic][l.Partition]
+		if !exists {
+			return nil, fmt.Errorf("missing end offset of topic %v partition %v", l.Topic, l.Partition)
+		}
+		lags[i].EndOffset = end
+		if l.CommittedOffset >= 0 {
+			lags[i].Lag = end - l.CommittedOffset
+		} else {
+			lags[i].Lag = end
+		}
+	}
+
+	sort.Slice(lags, func(i, j int) bool {
+		if lags[i].Topic != lags[j].Topic {
+			return lags[i].Topic < lags[j].Topic
+		}
+		return lags[i].Partition < lags[j].Partition
+	})
+	return lags, nil
+}
+
+// franzAlterGroupOffsets commits offsets on behalf of a consumer group, which
+// must have no active members.
+func franzAlterGroupOffsets(ctx context.Context, cl *kgo.Client, group string, offsets map[string]map[int32]int64) error {
+	req := kmsg.NewPtrOffsetCommitRequest()
+	req.Group = group
+	req.Generation = -1
+	for topic, partitions := range offsets {
+		reqTopic := kmsg.NewOffsetCommitRequestTopic()
+		reqTopic.Topic = topic
+		for partition, offset := range partitions {
+			reqPartition := kmsg.NewOffsetCommitRequestTopicPartition()
+			reqPartition.Partition = partition
+			reqPartition.Offset = offset
+			reqTopic.Partitions = append(reqTopic.Partitions, reqPartition)
+		}
+		req.Topics = append(req.Topics, reqTopic)
+	}
+
+	res, err := req.RequestWith(ctx, cl)
+	if err != nil {
+		return err
+	}
+	for _, t := range res.Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return fmt.Errorf("failed to commit offset of topic %v partition %v: %w", t.Topic, p.Partition, err)
+			}
+		}
+	}
+	return nil
+}
+
+func franzErrorWithMessage(code int16, msg *string) error {
+	err := kerr.ErrorForCode(code)
+	if err == nil || msg == nil || *msg == "" {
+		return err
+	}
+	return fmt.Errorf("%w: %v", err, *msg)
+}
@@ -4,15 +4,21 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
+	"path"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/twmb/franz-go/pkg/kerr"
 	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
 	"github.com/twmb/franz-go/pkg/sasl"
 
 	"github.com/benthosdev/benthos/v4/internal/checkpoint"
+	"github.com/benthosdev/benthos/v4/internal/component/interop"
 	"github.com/benthosdev/benthos/v4/internal/shutdown"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
@@ -41,6 +47,12 @@ This input adds the following metadata fields to each message:
 - kafka_tombstone_message
 - All record headers
 ` + "```" + `
+
+### Seeking
+
+By default a consumer group resumes from its committed offsets, and the field ` + "`start_from_oldest`" + ` only applies to partitions without committed offsets. The fields ` + "`start_from_timestamp`, `start_from_offsets` and `start_from_messages_back`" + ` can instead be used to move partitions to a specific position, overriding committed offsets the first time that each partition is assigned to this input. Since these fields are applied again when Benthos is restarted they should be removed once a replay is complete.
+
+When this input has a label it is also possible to rewind a running consumer by sending a POST request to the endpoint ` + "`/kafka_franz/{label}/rewind?timestamp={timestamp}`" + `, where the timestamp is either RFC3339 or a unix timestamp in milliseconds. This causes the consumer to reconnect, and all partitions that are assigned to it are moved to the first record at or after the timestamp. When other instances are members of the same consumer group only the partitions assigned to this instance are rewound.
 `).
 		Field(service.NewStringListField("seed_brokers").
 			Description("A list of broker addresses to connect to in order to establish connections. If an item of the list contains commas it will be expanded into multiple addresses.").
@@ -79,15 +91,39 @@ Finally, it's also possible to specify an explicit offset to consume from by add
 			Default(1024).
 			Advanced()).
 		Field(service.NewDurationField("commit_period").
-			Description("The period of time between each commit of the current partition offsets. Offsets are always committed during shutdown.").
+			Description("The period of time between each commit of the current partition offsets. Offsets are always committed during shutdown. When `transactional` is enabled this only applies to the offsets of messages that were acknowledged without being written by an output, such as those deleted by processors.").
 			Default("5s").
 			Advanced()).
 		Field(service.NewBoolField("start_from_oldest").
 			Description("Determines whether to consume from the oldest available offset, otherwise messages are consumed from the latest offset. The setting is applied when creating a new consumer group or the saved offset no longer exists.").
 			Default(true).
 			Advanced()).
+		Field(service.NewStringField("start_from_timestamp").
+			Description("Consume each partition from the first record at or after a timestamp, which is either RFC3339 or a unix timestamp in milliseconds. When a consumer group is specified this overrides the committed offsets of the group.").
+			Example("2024-01-01T00:00:00Z").
+			Example("1704067200000").
+			Optional().
+			Advanced().
+			Version("4.26.0")).
+		Field(service.NewStringListField("start_from_offsets").
+			Description("A list of explicit offsets to consume partitions from in the form `topic:partition:offset`, which override the committed offsets of the consumer group. Partitions that are not listed resume from their committed offsets. This field requires a `consumer_group`, in order to consume explicit offsets without a consumer group specify them in the `topics` field instead.").
+			Example([]string{"foo:0:1500", "foo:1:1420"}).
+			Optional().
+			Advanced().
+			Version("4.26.0")).
+		Field(service.NewIntField("start_from_messages_back").
+			Description("Consume the last N records of each partition. When a consumer group is specified this overrides the committed offsets of the group.").
+			Example(1000).
+			Optional().
+			Advanced().
+			Version("4.26.0")).
 		Field(service.NewTLSToggledField("tls")).
 		Field(saslField()).
+		Field(service.NewBoolField("transactional").
+			Description("Whether the offsets of consumed records should be committed by a [`kafka_franz` output](/docs/components/outputs/kafka_franz) with a `transactional_id` as part of the transactions that write them, rather than by this input. When enabled only records of committed transactions are consumed, and partitions are not released during a rebalance until all of their pending messages have been written. The offset of a partition is never committed beyond a message that has yet to be written or acknowledged, and so when a batch fails the messages consumed after it are written again should Benthos restart before the failed batch is delivered. This field requires a `consumer_group`.").
+			Default(false).
+			Advanced().
+			Version("4.26.0")).
 		Field(service.NewBoolField("multi_header").Description("Decode headers into lists to allow handling of multiple values with the same key").Default(false).Advanced()).
 		Field(service.NewBatchPolicyField("batching").
 			Description("Allows you to configure a [batching policy](/docs/configuration/batching) that applies to individual topic partitions in order to batch messages together before flushing them for processing. Batching can be beneficial for performance as well as useful for windowed processing, and doing so this way preserves the ordering of topic partitions.").
@@ -100,6 +136,12 @@ root = if $has_topic_partitions {
   } else if this.regexp_topics {
     "this input does not support both regular expression topics and explicit topic partitions"
   }
+} else if this.transactional.or(false) && this.consumer_group.or("") == "" {
+  "a consumer group must be specified when transactional is enabled"
+} else if this.exists("start_from_offsets") && this.consumer_group.or("") == "" {
+  "a consumer group must be specified when start_from_offsets is set, otherwise specify explicit offsets in the topics field"
+} else if [this.exists("start_from_timestamp"), this.exists("start_from_offsets"), this.exists("start_from_messages_back")].filter(v -> v).length() > 1 {
+  "only one of start_from_timestamp, start_from_offsets and start_from_messages_back can be specified"
 }
 `)
 }
@@ -139,7 +181,11 @@ type franzKafkaReader struct {
 	commitPeriod    time.Duration
 	regexPattern    bool
 	multiHeader     bool
+	transactional   bool
 	batchPolicy     service.BatchPolicy
+	seeker          *franzSeeker
+
+	reconnectPending atomic.Bool
 
 	batchChan atomic.Value
 	res       *service.Resources
@@ -175,6 +221,10 @@ func newFranzKafkaReaderFromConfig(conf *service.ParsedConfig, res *service.Reso
 		return nil, err
 	}
 
+	if f.seeker, err = newFranzSeekerFromConfig(conf); err != nil {
+		return nil, err
+	}
+
 	topicList, err := conf.FieldStringList("topics")
 	if err != nil {
 		return nil, err
@@ -194,7 +244,11 @@ func newFranzKafkaReaderFromConfig(conf *service.ParsedConfig, res *service.Reso
 		for topic, partitions := range topicPartitions {
 			partMap := map[int32]kgo.Offset{}
 			for part, offset := range partitions {
-				partMap[part] = kgo.NewOffset().At(offset)
+				if offset == defaultOffset && f.seeker.startAll != nil {
+					partMap[part] = *f.seeker.startAll
+				} else {
+					partMap[part] = kgo.NewOffset().At(offset)
+				}
 			}
 			f.topicPartitions[topic] = partMap
 		}
@@ -238,6 +292,25 @@ func newFranzKafkaReaderFromConfig(conf *service.ParsedConfig, res *service.Reso
 	if f.multiHeader, err = conf.FieldBool("multi_header"); err != nil {
 		return nil, err
 	}
+	if f.transactional, err = conf.FieldBool("transactional"); err != nil {
+		return nil, err
+	}
+	if f.transactional && f.consumerGroup == "" {
+		return nil, errors.New("a consumer group must be specified when transactional is enabled")
+	}
+	if f.seeker.startPartitions != nil && f.consumerGroup == "" {
+		return nil, errors.New("a consumer group must be specified when start_from_offsets is set")
+	}
+
+	if label := res.Label(); label != "" {
+		interop.UnwrapManagement(res).RegisterEndpoint(
+			path.Join("/kafka_franz", label, "rewind"),
+			"Rewind the consumer of a kafka_franz input to the timestamp provided by the query parameter `timestamp`.",
+			f.seeker.rewindHandler(func() {
+				f.reconnectPending.Store(true)
+			}),
+		)
+	}
 	if f.saslConfs, err = saslMechanismsFromConfig(conf); err != nil {
 		return nil, err
 	}
@@ -250,6 +323,112 @@ type msgWithRecord struct {
 	r   *kgo.Record
 }
 
+// franzTxnSource describes the consumer group of a transactional input, which
+// a transactional output requires in order to commit the offsets of consumed
+// records within its own transactions.
+type franzTxnSource struct {
+	group    string
+	metadata func() (memberID string, generation int32)
+
+	// Serialises the commits of offsets to the consumer group, whether they are
+	// made within the transaction of an output or by the input itself.
+	commitMut sync.Mutex
+}
+
+// franzTxnRecord is attached to the context of each message consumed by a
+// transactional input.
+type franzTxnRecord struct {
+	source    *franzTxnSource
+	topic     string
+	partition int32
+	offset    int64
+	tracker   *franzTxnPartition
+}
+
+// franzTxnPartition tracks the records consumed from a partition by a
+// transactional input whose offsets have not yet been committed. The offset of
+// a partition is only ever committed up to the first record that has been
+// neither acknowledged nor written by the committing transaction, as otherwise
+// the commit of a later batch would skip over one that failed.
+type franzTxnPartition struct {
+	mut       sync.Mutex
+	pending   []franzTxnOffset
+	committed int64
+	revoked   atomic.Bool
+}
+
+type franzTxnOffset struct {
+	offset      int64
+	leaderEpoch int32
+	acked       bool
+}
+
+func newFranzTxnPartition() *franzTxnPartition {
+	return &franzTxnPartition{committed: -1}
+}
+
+func (p *franzTxnPartition) track(offset int64, leaderEpoch int32) {
+	p.mut.Lock()
+	p.pending = append(p.pending, franzTxnOffset{offset: offset, leaderEpoch: leaderEpoch})
+	p.mut.Unlock()
+}
+
+// ack marks the records within an inclusive range of offsets as acknowledged,
+// which includes records that were dropped before reaching an output.
+func (p *franzTxnPartition) ack(from, to int64) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	i := sort.Search(len(p.pending), func(i int) bool {
+		return p.pending[i].offset >= from
+	})
+	for ; i < len(p.pending) && p.pending[i].offset <= to; i++ {
+		p.pending[i].acked = true
+	}
+}
+
+// watermark returns the offset that can be committed for the partition, where
+// records that the provided function reports as written are treated as
+// acknowledged. Returns false if the offset has not advanced since the last
+// commit.
+func (p *franzTxnPartition) watermark(written func(offset int64) bool) (kgo.EpochOffset, bool) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	next := kgo.EpochOffset{Epoch: -1, Offset: p.committed}
+	for _, o := range p.pending {
+		if !o.acked && (written == nil || !written(o.offset)) {
+			break
+		}
+		next = kgo.EpochOffset{Epoch: o.leaderEpoch, Offset: o.offset + 1}
+	}
+	return next, next.Offset > p.committed
+}
+
+// commit records that an offset has been committed, after which the records
+// prior to it are no longer tracked.
+func (p *franzTxnPartition) commit(offset int64) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if offset <= p.committed {
+		return
+	}
+	p.committed = offset
+
+	i := sort.Search(len(p.pending), func(i int) bool {
+		return p.pending[i].offset >= offset
+	})
+	p.pending = p.pending[i:]
+}
+
+type franzTxnRecordKey struct{}
+
+func franzTxnRecordFromMessage(msg *service.Message) *franzTxnRecord {
+	r, _ := msg.Context().Value(franzTxnRecordKey{}).(*franzTxnRecord)
+	return r
+}
+
 func (f *franzKafkaReader) recordToMessage(record *kgo.Record) *msgWithRecord {
 	msg := service.NewMessage(record.Value)
 	msg.MetaSetMut("kafka_key", string(record.Key))
@@ -300,6 +479,10 @@ type partitionTracker struct {
 	outBatchChan chan<- batchWithAckFn
 	commitFn     func(r *kgo.Record)
 
+	// Set when the records of the partition are committed by a transactional
+	// output.
+	txn *franzTxnPartition
+
 	shutSig *shutdown.Signaller
 }
 
@@ -398,6 +581,11 @@ func (p *partitionTracker) sendBatch(ctx context.Context, b service.MessageBatch
 	releaseFn := p.checkpointer.Track(r, int64(len(b)))
 	p.checkpointerLock.Unlock()
 
+	txnFrom := r.Offset
+	if tr := franzTxnRecordFromMessage(b[0]); tr != nil {
+		txnFrom = tr.offset
+	}
+
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -411,6 +599,9 @@ func (p *partitionTracker) sendBatch(ctx context.Context, b service.MessageBatch
 			if releaseRecord != nil && *releaseRecord != nil {
 				p.commitFn(*releaseRecord)
 			}
+			if p.txn != nil {
+				p.txn.ack(txnFrom, r.Offset)
+			}
 		},
 	}:
 	}
@@ -452,6 +643,12 @@ func (p *partitionTracker) add(ctx context.Context, m *msgWithRecord, limit int)
 	return
 }
 
+func (p *partitionTracker) pending() int64 {
+	p.checkpointerLock.Lock()
+	defer p.checkpointerLock.Unlock()
+	return p.checkpointer.Pending()
+}
+
 func (p *partitionTracker) pauseFetch(limit int) (pauseFetch bool) {
 	p.checkpointerLock.Lock()
 	pauseFetch = p.checkpointer.Pending() >= int64(limit)
@@ -479,6 +676,10 @@ type checkpointTracker struct {
 	batchChan chan<- batchWithAckFn
 	commitFn  func(r *kgo.Record)
 	batchPol  service.BatchPolicy
+
+	// When set each message is annotated with its record offset so that it
+	// can be committed by a transactional output.
+	txnSource *franzTxnSource
 }
 
 func newCheckpointTracker(
@@ -528,9 +729,23 @@ func (c *checkpointTracker) addRecord(ctx context.Context, m *msgWithRecord, lim
 			}
 		}
 		partTracker = newPartitionTracker(batcher, c.batchChan, c.commitFn)
+		if c.txnSource != nil {
+			partTracker.txn = newFranzTxnPartition()
+		}
 		topicTracker[m.r.Partition] = partTracker
 	}
 
+	if partTracker.txn != nil {
+		partTracker.txn.track(m.r.Offset, m.r.LeaderEpoch)
+		m.msg = m.msg.WithContext(context.WithValue(m.msg.Context(), franzTxnRecordKey{}, &franzTxnRecord{
+			source:    c.txnSource,
+			topic:     m.r.Topic,
+			partition: m.r.Partition,
+			offset:    m.r.Offset,
+			tracker:   partTracker.txn,
+		}))
+	}
+
 	return partTracker.add(ctx, m, limit)
 }
 
@@ -550,6 +765,32 @@ func (c *checkpointTracker) pauseFetch(topic string, partition int32, limit int)
 	return partTracker.pauseFetch(limit)
 }
 
+// waitForPending blocks until all messages consumed from the given topic
+// partitions have been acknowledged, or the context is cancelled.
+func (c *checkpointTracker) waitForPending(ctx context.Context, m map[string][]int32) {
+	hasPending := func() bool {
+		c.mut.Lock()
+		defer c.mut.Unlock()
+
+		for topicName, partitions := range m {
+			for _, partition := range partitions {
+				if tracker := c.topics[topicName][partition]; tracker != nil && tracker.pending() > 0 {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for hasPending() {
+		select {
+		case <-time.After(time.Millisecond * 50):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 func (c *checkpointTracker) removeTopicPartitions(ctx context.Context, m map[string][]int32) {
 	c.mut.Lock()
 	defer c.mut.Unlock()
@@ -561,6 +802,9 @@ func (c *checkpointTracker) removeTopicPartitions(ctx context.Context, m map[str
 		}
 		for _, lostPartition := range lostTopic {
 			if trackedPartition, exists := trackedTopic[lostPartition]; exists {
+				if trackedPartition.txn != nil {
+					trackedPartition.txn.revoked.Store(true)
+				}
 				_ = trackedPartition.close(ctx)
 			}
 			delete(trackedTopic, lostPartition)
@@ -571,6 +815,62 @@ func (c *checkpointTracker) removeTopicPartitions(ctx context.Context, m map[str
 	}
 }
 
+// commitTxnAcked commits the offsets of transactional partitions that have
+// advanced through acknowledgements alone, which happens when messages are
+// dropped by processors and are therefore never written by an output
+// transaction.
+func (c *checkpointTracker) commitTxnAcked(ctx context.Context, cl *kgo.Client) error {
+	c.txnSource.commitMut.Lock()
+	defer c.txnSource.commitMut.Unlock()
+
+	offsets := map[string]map[int32]kgo.EpochOffset{}
+	trackers := map[string]map[int32]*franzTxnPartition{}
+
+	c.mut.Lock()
+	for topicName, partitions := range c.topics {
+		for partition, tracker := range partitions {
+			if tracker.txn == nil {
+				continue
+			}
+			offset, advanced := tracker.txn.watermark(nil)
+			if !advanced {
+				continue
+			}
+			if offsets[topicName] == nil {
+				offsets[topicName] = map[int32]kgo.EpochOffset{}
+				trackers[topicName] = map[int32]*franzTxnPartition{}
+			}
+			offsets[topicName][partition] = offset
+			trackers[topicName][partition] = tracker.txn
+		}
+	}
+	c.mut.Unlock()
+
+	if len(offsets) == 0 {
+		return nil
+	}
+
+	var err error
+	cl.CommitOffsetsSync(ctx, offsets, func(_ *kgo.Client, _ *kmsg.OffsetCommitRequest, res *kmsg.OffsetCommitResponse, commitErr error) {
+		if commitErr != nil {
+			err = commitErr
+			return
+		}
+		for _, t := range res.Topics {
+			for _, p := range t.Partitions {
+				if pErr := kerr.ErrorForCode(p.ErrorCode); pErr != nil {
+					err = fmt.Errorf("topic %v partition %v: %w", t.Topic, p.Partition, pErr)
+					continue
+				}
+				if tracker := trackers[t.Topic][p.Partition]; tracker != nil {
+					tracker.commit(offsets[t.Topic][p.Partition].Offset)
+				}
+			}
+		}
+	})
+	return err
+}
+
 //------------------------------------------------------------------------------
 
 func (f *franzKafkaReader) Connect(ctx context.Context) error {
@@ -583,18 +883,43 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 		return service.ErrEndOfInput
 	}
 
+	f.reconnectPending.Store(false)
+
 	var initialOffset kgo.Offset
 	if f.startFromOldest {
 		initialOffset = kgo.NewOffset().AtStart()
 	} else {
 		initialOffset = kgo.NewOffset().AtEnd()
 	}
+	initialOffset = f.seeker.resetOffset(initialOffset)
+
+	topicPartitions := f.topicPartitions
+	if f.consumerGroup == "" {
+		// Without a consumer group a rewind is applied to all partitions. Topics
+		// consumed without explicit partitions have no committed offsets, and
+		// therefore start from the reset offset of the new client.
+		if rewind := f.seeker.takeRewind(); rewind != nil {
+			if topicPartitions != nil {
+				topicPartitions = map[string]map[int32]kgo.Offset{}
+				for topic, partitions := range f.topicPartitions {
+					partMap := map[int32]kgo.Offset{}
+					for part := range partitions {
+						partMap[part] = *rewind
+					}
+					topicPartitions[topic] = partMap
+				}
+			}
+			if len(f.topics) > 0 {
+				initialOffset = *rewind
+			}
+		}
+	}
 
 	batchChan := make(chan batchWithAckFn)
 
 	var cl *kgo.Client
 	commitFn := func(r *kgo.Record) {}
-	if f.consumerGroup != "" {
+	if f.consumerGroup != "" && !f.transactional {
 		commitFn = func(r *kgo.Record) {
 			if cl == nil {
 				return
@@ -603,11 +928,22 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 		}
 	}
 	checkpoints := newCheckpointTracker(f.res, batchChan, commitFn, f.batchPolicy)
+	if f.transactional {
+		checkpoints.txnSource = &franzTxnSource{
+			group: f.consumerGroup,
+			metadata: func() (string, int32) {
+				if cl == nil {
+					return "", -1
+				}
+				return cl.GroupMetadata()
+			},
+		}
+	}
 
 	clientOpts := []kgo.Opt{
 		kgo.SeedBrokers(f.seedBrokers...),
 		kgo.ConsumeTopics(f.topics...),
-		kgo.ConsumePartitions(f.topicPartitions),
+		kgo.ConsumePartitions(topicPartitions),
 		kgo.ConsumeResetOffset(initialOffset),
 		kgo.SASL(f.saslConfs...),
 		kgo.ConsumerGroup(f.consumerGroup),
@@ -615,7 +951,28 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 		kgo.Rack(f.rackID),
 	}
 
-	if f.consumerGroup != "" {
+	if f.transactional {
+		// Offsets are committed by a transactional output, therefore we hold
+		// onto revoked partitions until their pending messages are written.
+		clientOpts = append(clientOpts,
+			kgo.FetchIsolationLevel(kgo.ReadCommitted()),
+			kgo.RequireStableFetchOffsets(),
+			kgo.OnPartitionsRevoked(func(rctx context.Context, _ *kgo.Client, m map[string][]int32) {
+				waitCtx, done := f.shutSig.CloseAtLeisureCtx(rctx)
+				checkpoints.waitForPending(waitCtx, m)
+				done()
+				if commitErr := checkpoints.commitTxnAcked(rctx, cl); commitErr != nil {
+					f.log.Errorf("Commit error on partition revoke: %v", commitErr)
+				}
+				checkpoints.removeTopicPartitions(rctx, m)
+			}),
+			kgo.OnPartitionsLost(func(rctx context.Context, _ *kgo.Client, m map[string][]int32) {
+				checkpoints.removeTopicPartitions(rctx, m)
+			}),
+			kgo.DisableAutoCommit(),
+			kgo.WithLogger(&kgoLogger{f.log}),
+		)
+	} else if f.consumerGroup != "" {
 		clientOpts = append(clientOpts,
 			kgo.OnPartitionsRevoked(func(rctx context.Context, c *kgo.Client, m map[string][]int32) {
 				if commitErr := c.CommitMarkedOffsets(rctx); commitErr != nil {
@@ -633,6 +990,12 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 		)
 	}
 
+	if f.consumerGroup != "" {
+		clientOpts = append(clientOpts, kgo.AdjustFetchOffsetsFn(func(_ context.Context, offsets map[string]map[int32]kgo.Offset) (map[string]map[int32]kgo.Offset, error) {
+			return f.seeker.adjust(offsets), nil
+		}))
+	}
+
 	if f.tlsConf != nil {
 		clientOpts = append(clientOpts, kgo.DialTLSConfig(f.tlsConf))
 	}
@@ -648,6 +1011,13 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 
 	go func() {
 		defer func() {
+			if f.transactional {
+				commitCtx, done := f.shutSig.CloseNowCtx(context.Background())
+				if commitErr := checkpoints.commitTxnAcked(commitCtx, cl); commitErr != nil {
+					f.log.Errorf("Commit error on shutdown: %v", commitErr)
+				}
+				done()
+			}
 			cl.Close()
 			checkpoints.close()
 			f.storeBatchChan(nil)
@@ -660,6 +1030,7 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 		closeCtx, done := f.shutSig.CloseAtLeisureCtx(context.Background())
 		defer done()
 
+		lastTxnCommit := time.Now()
 		for {
 			// Using a stall prevention context here because I've realised we
 			// might end up disabling literally all the partitions and topics
@@ -700,6 +1071,10 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 			if closeCtx.Err() != nil {
 				return
 			}
+			if f.reconnectPending.Load() {
+				f.log.Infof("Reconnecting in order to rewind consumer")
+				return
+			}
 
 			pauseTopicPartitions := map[string][]int32{}
 			iter := fetches.RecordIter()
@@ -723,6 +1098,13 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 			if len(resumeTopicPartitions) > 0 {
 				cl.ResumeFetchPartitions(resumeTopicPartitions)
 			}
+
+			if f.transactional && time.Since(lastTxnCommit) >= f.commitPeriod {
+				lastTxnCommit = time.Now()
+				if commitErr := checkpoints.commitTxnAcked(closeCtx, cl); commitErr != nil {
+					f.log.Errorf("Failed to commit offsets of acknowledged messages: %v", commitErr)
+				}
+			}
 		}
 	}()
 
@@ -0,0 +1,204 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// parseFranzTimestamp parses either an RFC3339 timestamp or a unix timestamp
+// in milliseconds, returning the unix timestamp in milliseconds.
+func parseFranzTimestamp(s string) (int64, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ms, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0, fmt.Errorf("expected an RFC3339 timestamp or unix timestamp in milliseconds: %w", err)
+	}
+	return t.UnixMilli(), nil
+}
+
+// franzSeeker determines the offsets that partitions are moved to when they are
+// assigned to the consumer, overriding any offsets committed by the consumer
+// group.
+type franzSeeker struct {
+	mut sync.Mutex
+
+	// Configured start offsets are applied to each partition the first time it
+	// is assigned.
+	startAll        *kgo.Offset
+	startPartitions map[string]map[int32]int64
+	started         map[string]map[int32]struct{}
+
+	// A rewind is applied to all partitions of the next assignment.
+	rewind *kgo.Offset
+}
+
+func newFranzSeeker() *franzSeeker {
+	return &franzSeeker{
+		started: map[string]map[int32]struct{}{},
+	}
+}
+
+func newFranzSeekerFromConfig(conf *service.ParsedConfig) (*franzSeeker, error) {
+	s := newFranzSeeker()
+
+	if conf.Contains("start_from_timestamp") {
+		tsStr, err := conf.FieldString("start_from_timestamp")
+		if err != nil {
+			return nil, err
+		}
+		ts, err := parseFranzTimestamp(tsStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse start_from_timestamp: %w", err)
+		}
+		o := kgo.NewOffset().AfterMilli(ts)
+		s.startAll = &o
+	}
+
+	if conf.Contains("start_from_messages_back") {
+		n, err := conf.FieldInt("start_from_messages_back")
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, errors.New("start_from_messages_back cannot be negative")
+		}
+		if s.startAll != nil {
+			return nil, errors.New("only one of start_from_timestamp, start_from_offsets and start_from_messages_back can be specified")
+		}
+		o := kgo.NewOffset().AtEnd().Relative(int64(-n))
+		s.startAll = &o
+	}
+
+	if conf.Contains("start_from_offsets") {
+		offsetsList, err := conf.FieldStringList("start_from_offsets")
+		if err != nil {
+			return nil, err
+		}
+		if s.startAll != nil {
+			return nil, errors.New("only one of start_from_timestamp, start_from_offsets and start_from_messages_back can be specified")
+		}
+		topics, topicPartitions, err := parseTopics(offsetsList, -1, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse start_from_offsets: %w", err)
+		}
+		if len(topics) > 0 {
+			return nil, fmt.Errorf("start_from_offsets entries must be of the form topic:partition:offset, got %v", topics)
+		}
+		for _, partitions := range topicPartitions {
+			for _, offset := range partitions {
+				if offset < 0 {
+					return nil, errors.New("start_from_offsets entries must be of the form topic:partition:offset with a non-negative offset")
+				}
+			}
+		}
+		s.startPartitions = topicPartitions
+	}
+	return s, nil
+}
+
+// resetOffset returns the offset to consume from when a partition has no
+// committed offset, or when consuming partitions directly. A pending rewind is
+// not included as the reset offset applies for the lifetime of a client, and
+// would therefore also apply to partitions assigned after the rewind.
+func (s *franzSeeker) resetOffset(fallback kgo.Offset) kgo.Offset {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.startAll != nil {
+		return *s.startAll
+	}
+	return fallback
+}
+
+// takeRewind returns and clears a pending rewind, if any.
+func (s *franzSeeker) takeRewind() *kgo.Offset {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	r := s.rewind
+	s.rewind = nil
+	return r
+}
+
+// setRewind schedules all partitions of the next assignment to be moved to the
+// first offset at or after a timestamp.
+func (s *franzSeeker) setRewind(ts int64) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	o := kgo.NewOffset().AfterMilli(ts)
+	s.rewind = &o
+}
+
+// adjust is called with the offsets of partitions assigned by the consumer
+// group before consumption of them begins.
+func (s *franzSeeker) adjust(offsets map[string]map[int32]kgo.Offset) map[string]map[int32]kgo.Offset {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	rewind := s.rewind
+	s.rewind = nil
+
+	for topic, partitions := range offsets {
+		started := s.started[topic]
+		if started == nil {
+			started = map[int32]struct{}{}
+			s.started[topic] = started
+		}
+		for partition := range partitions {
+			_, alreadyStarted := started[partition]
+			started[partition] = struct{}{}
+
+			if rewind != nil {
+				partitions[partition] = *rewind
+				continue
+			}
+			if alreadyStarted {
+				continue
+			}
+			if s.startAll != nil {
+				partitions[partition] = *s.startAll
+			} else if o, exists := s.startPartitions[topic][partition]; exists {
+				partitions[partition] = kgo.NewOffset().At(o)
+			}
+		}
+	}
+	return offsets
+}
+
+// rewindHandler returns an HTTP handler that rewinds the consumer to the
+// timestamp provided by the query parameter `timestamp`, calling the provided
+// closure in order to trigger a reconnect.
+func (s *franzSeeker) rewindHandler(reconnect func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		tsStr := r.URL.Query().Get("timestamp")
+		if tsStr == "" {
+			http.Error(w, "Missing query parameter: timestamp", http.StatusBadRequest)
+			return
+		}
+		ts, err := parseFranzTimestamp(tsStr)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid query parameter timestamp: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		s.setRewind(ts)
+		reconnect()
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
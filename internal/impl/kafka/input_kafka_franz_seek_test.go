@@ -0,0 +1,173 @@
+package kafka
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestParseFranzTimestamp(t *testing.T) {
+	ts, err := parseFranzTimestamp("1704067200000")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1704067200000), ts)
+
+	ts, err = parseFranzTimestamp("2024-01-01T00:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1704067200000), ts)
+
+	ts, err = parseFranzTimestamp("2024-01-01T01:00:00.5+01:00")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1704067200500), ts)
+
+	_, err = parseFranzTimestamp("yesterday")
+	require.Error(t, err)
+}
+
+func TestFranzSeekerConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		conf      string
+		errString string
+	}{
+		{
+			name: "bad timestamp",
+			conf: `
+start_from_timestamp: nope
+`,
+			errString: `failed to parse start_from_timestamp: expected an RFC3339 timestamp or unix timestamp in milliseconds: parsing time "nope" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "nope" as "2006"`,
+		},
+		{
+			name: "negative messages back",
+			conf: `
+start_from_messages_back: -1
+`,
+			errString: "start_from_messages_back cannot be negative",
+		},
+		{
+			name: "offsets without an offset",
+			conf: `
+start_from_offsets: [ foo ]
+`,
+			errString: "start_from_offsets entries must be of the form topic:partition:offset, got [foo]",
+		},
+		{
+			name: "offsets with a negative offset",
+			conf: `
+start_from_offsets: [ foo:0 ]
+`,
+			errString: "start_from_offsets entries must be of the form topic:partition:offset with a non-negative offset",
+		},
+		{
+			name: "multiple seeks",
+			conf: `
+start_from_timestamp: 2024-01-01T00:00:00Z
+start_from_messages_back: 10
+`,
+			errString: "only one of start_from_timestamp, start_from_offsets and start_from_messages_back can be specified",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := franzKafkaInputConfig().ParseYAML(`
+seed_brokers: [ foo:1234 ]
+topics: [ foo ]
+consumer_group: bar
+`+test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newFranzSeekerFromConfig(conf)
+			require.EqualError(t, err, test.errString)
+		})
+	}
+}
+
+func franzTestOffsets(partitions ...int32) map[string]map[int32]kgo.Offset {
+	offsets := map[string]map[int32]kgo.Offset{"foo": {}}
+	for _, p := range partitions {
+		offsets["foo"][p] = kgo.NewOffset().At(100)
+	}
+	return offsets
+}
+
+func TestFranzSeekerStartPartitions(t *testing.T) {
+	s := newFranzSeeker()
+	s.startPartitions = map[string]map[int32]int64{
+		"foo": {0: 10},
+	}
+
+	offsets := s.adjust(franzTestOffsets(0, 1))
+	assert.Equal(t, kgo.NewOffset().At(10), offsets["foo"][0])
+	assert.Equal(t, kgo.NewOffset().At(100), offsets["foo"][1])
+
+	// The start offsets are only applied on the first assignment.
+	offsets = s.adjust(franzTestOffsets(0, 1))
+	assert.Equal(t, kgo.NewOffset().At(100), offsets["foo"][0])
+	assert.Equal(t, kgo.NewOffset().At(100), offsets["foo"][1])
+}
+
+func TestFranzSeekerStartAll(t *testing.T) {
+	s := newFranzSeeker()
+	start := kgo.NewOffset().AtEnd().Relative(-5)
+	s.startAll = &start
+
+	assert.Equal(t, start, s.resetOffset(kgo.NewOffset().AtStart()))
+
+	offsets := s.adjust(franzTestOffsets(0))
+	assert.Equal(t, start, offsets["foo"][0])
+
+	// New partitions are moved to the start offset on their first assignment.
+	offsets = s.adjust(franzTestOffsets(0, 1))
+	assert.Equal(t, kgo.NewOffset().At(100), offsets["foo"][0])
+	assert.Equal(t, start, offsets["foo"][1])
+}
+
+func TestFranzSeekerRewind(t *testing.T) {
+	s := newFranzSeeker()
+
+	var reconnects int
+	handler := s.rewindHandler(func() {
+		reconnects++
+	})
+
+	for _, test := range []struct {
+		method string
+		target string
+		status int
+	}{
+		{method: http.MethodGet, target: "/rewind?timestamp=1000", status: http.StatusMethodNotAllowed},
+		{method: http.MethodPost, target: "/rewind", status: http.StatusBadRequest},
+		{method: http.MethodPost, target: "/rewind?timestamp=nope", status: http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(test.method, test.target, http.NoBody))
+		assert.Equal(t, test.status, rec.Code, test.target)
+	}
+	assert.Equal(t, 0, reconnects)
+	assert.Nil(t, s.takeRewind())
+
+	_ = s.adjust(franzTestOffsets(0))
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/rewind?timestamp=1000", http.NoBody))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, 1, reconnects)
+
+	// A rewind does not change the reset offset, which applies to partitions
+	// without committed offsets for the lifetime of the client.
+	assert.Equal(t, kgo.NewOffset().AtStart(), s.resetOffset(kgo.NewOffset().AtStart()))
+
+	// A rewind applies to all partitions of the next assignment only.
+	offsets := s.adjust(franzTestOffsets(0, 1))
+	assert.Equal(t, kgo.NewOffset().AfterMilli(1000), offsets["foo"][0])
+	assert.Equal(t, kgo.NewOffset().AfterMilli(1000), offsets["foo"][1])
+
+	offsets = s.adjust(franzTestOffsets(0, 1))
+	assert.Equal(t, kgo.NewOffset().At(100), offsets["foo"][0])
+	assert.Equal(t, kgo.NewOffset().At(100), offsets["foo"][1])
+}
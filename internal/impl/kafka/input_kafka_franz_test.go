@@ -0,0 +1,136 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestKafkaFranzInputBadParams(t *testing.T) {
+	testCases := []struct {
+		name        string
+		conf        string
+		errContains string
+	}{
+		{
+			name: "transactional with a consumer group",
+			conf: `
+kafka_franz:
+  seed_brokers: [ foo:1234 ]
+  topics: [ foo ]
+  consumer_group: bar
+  transactional: true
+`,
+		},
+		{
+			name: "transactional without a consumer group",
+			conf: `
+kafka_franz:
+  seed_brokers: [ foo:1234 ]
+  topics: [ foo ]
+  transactional: true
+`,
+			errContains: "a consumer group must be specified when transactional is enabled",
+		},
+		{
+			name: "consumer group with explicit partitions",
+			conf: `
+kafka_franz:
+  seed_brokers: [ foo:1234 ]
+  topics: [ foo:0 ]
+  consumer_group: bar
+`,
+			errContains: "this input does not support both a consumer group and explicit topic partitions",
+		},
+		{
+			name: "start from offsets without a consumer group",
+			conf: `
+kafka_franz:
+  seed_brokers: [ foo:1234 ]
+  topics: [ foo ]
+  start_from_offsets: [ foo:0:10 ]
+`,
+			errContains: "a consumer group must be specified when start_from_offsets is set",
+		},
+		{
+			name: "multiple start offsets",
+			conf: `
+kafka_franz:
+  seed_brokers: [ foo:1234 ]
+  topics: [ foo ]
+  consumer_group: bar
+  start_from_timestamp: 2024-01-01T00:00:00Z
+  start_from_messages_back: 10
+`,
+			errContains: "only one of start_from_timestamp, start_from_offsets and start_from_messages_back can be specified",
+		},
+		{
+			name: "start from timestamp without a consumer group",
+			conf: `
+kafka_franz:
+  seed_brokers: [ foo:1234 ]
+  topics: [ foo:0-3 ]
+  start_from_timestamp: 2024-01-01T00:00:00Z
+`,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			err := service.NewStreamBuilder().AddInputYAML(test.conf)
+			if test.errContains == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+			}
+		})
+	}
+}
+
+func TestKafkaFranzTxnPartition(t *testing.T) {
+	p := newFranzTxnPartition()
+
+	_, advanced := p.watermark(nil)
+	assert.False(t, advanced)
+
+	for _, offset := range []int64{3, 4, 6, 7, 8, 9} {
+		p.track(offset, 2)
+	}
+
+	// A failed batch holds back the offsets of later acknowledged batches.
+	p.ack(6, 7)
+	_, advanced = p.watermark(nil)
+	assert.False(t, advanced)
+
+	p.ack(3, 4)
+	offset, advanced := p.watermark(nil)
+	require.True(t, advanced)
+	assert.Equal(t, kgo.EpochOffset{Epoch: 2, Offset: 8}, offset)
+
+	offset, advanced = p.watermark(func(offset int64) bool { return offset == 8 })
+	require.True(t, advanced)
+	assert.Equal(t, kgo.EpochOffset{Epoch: 2, Offset: 9}, offset)
+
+	p.commit(9)
+	_, advanced = p.watermark(nil)
+	assert.False(t, advanced)
+
+	// Records that were dropped before reaching an output still advance the
+	// offset once acknowledged.
+	p.ack(9, 9)
+	offset, advanced = p.watermark(nil)
+	require.True(t, advanced)
+	assert.Equal(t, kgo.EpochOffset{Epoch: 2, Offset: 10}, offset)
+
+	// Commits of older offsets are ignored.
+	p.commit(10)
+	p.commit(5)
+	_, advanced = p.watermark(nil)
+	assert.False(t, advanced)
+}
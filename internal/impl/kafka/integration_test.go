@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/benthosdev/benthos/v4/internal/integration"
+	"github.com/benthosdev/benthos/v4/public/service"
 
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
@@ -350,3 +351,291 @@ input:
 		integration.StreamTestOptPort(kafkaPortStr),
 	)
 }
+
+func TestIntegrationKafkaTransactional(t *testing.T) {
+	integration.CheckSkip(t)
+	t.Parallel()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+
+	kafkaPort, err := integration.GetFreePort()
+	require.NoError(t, err)
+
+	kafkaPortStr := strconv.Itoa(kafkaPort)
+	address := "localhost:" + kafkaPortStr
+
+	options := &dockertest.RunOptions{
+		Repository:   "docker.vectorized.io/vectorized/redpanda",
+		Tag:          "latest",
+		Hostname:     "redpanda",
+		ExposedPorts: []string{"9092"},
+		PortBindings: map[docker.Port][]docker.PortBinding{
+			"9092/tcp": {{HostIP: "", HostPort: kafkaPortStr}},
+		},
+		Cmd: []string{
+			"redpanda", "start", "--smp 1", "--overprovisioned",
+			"--kafka-addr 0.0.0.0:9092",
+			fmt.Sprintf("--advertise-kafka-addr localhost:%v", kafkaPort),
+		},
+	}
+
+	pool.MaxWait = time.Minute
+	resource, err := pool.RunWithOptions(options)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, pool.Purge(resource))
+	})
+
+	_ = resource.Expire(900)
+	require.NoError(t, pool.Retry(func() error {
+		return createKafkaTopic(context.Background(), address, "testingconnection", 1)
+	}))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Minute*3)
+	defer done()
+
+	require.NoError(t, createKafkaTopic(ctx, address, "txnin", 4))
+	require.NoError(t, createKafkaTopic(ctx, address, "txnout", 4))
+
+	const n = 500
+
+	producer, err := kgo.NewClient(kgo.SeedBrokers(address))
+	require.NoError(t, err)
+	t.Cleanup(producer.Close)
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, producer.ProduceSync(ctx, &kgo.Record{
+			Topic: "topic-txnin",
+			Key:   []byte(strconv.Itoa(i)),
+			Value: []byte(fmt.Sprintf(`{"id":%v}`, i)),
+		}).FirstErr())
+	}
+
+	runStream := func(d time.Duration) {
+		streamBuilder := service.NewStreamBuilder()
+		require.NoError(t, streamBuilder.SetYAML(fmt.Sprintf(`
+input:
+  kafka_franz:
+    seed_brokers: [ %v ]
+    topics: [ topic-txnin ]
+    consumer_group: txngroup
+    transactional: true
+    batching:
+      count: 10
+      period: 100ms
+
+output:
+  kafka_franz:
+    seed_brokers: [ %v ]
+    topic: topic-txnout
+    transactional_id: txnwriter
+`, address, address)))
+		require.NoError(t, streamBuilder.SetLoggerYAML(`level: warn`))
+
+		stream, err := streamBuilder.Build()
+		require.NoError(t, err)
+
+		runCtx, runDone := context.WithTimeout(ctx, d)
+		defer runDone()
+
+		err = stream.Run(runCtx)
+		if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			require.NoError(t, err)
+		}
+		require.NoError(t, stream.StopWithin(time.Second*10))
+	}
+
+	countCommitted := func() int {
+		consumer, err := kgo.NewClient(
+			kgo.SeedBrokers(address),
+			kgo.ConsumeTopics("topic-txnout"),
+			kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+			kgo.FetchIsolationLevel(kgo.ReadCommitted()),
+		)
+		require.NoError(t, err)
+		defer consumer.Close()
+
+		seen := 0
+		for {
+			pollCtx, pollDone := context.WithTimeout(ctx, time.Second*5)
+			fetches := consumer.PollFetches(pollCtx)
+			pollDone()
+			if fetches.NumRecords() == 0 {
+				return seen
+			}
+			seen += fetches.NumRecords()
+		}
+	}
+
+	// Run the stream twice, the second run must not write any records as the
+	// offsets of the first were committed within its transactions.
+	runStream(time.Second * 30)
+	assert.Equal(t, n, countCommitted())
+
+	runStream(time.Second * 10)
+	assert.Equal(t, n, countCommitted())
+}
+
+func TestIntegrationKafkaAdmin(t *testing.T) {
+	integration.CheckSkip(t)
+	t.Parallel()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+
+	kafkaPort, err := integration.GetFreePort()
+	require.NoError(t, err)
+
+	kafkaPortStr := strconv.Itoa(kafkaPort)
+	address := "localhost:" + kafkaPortStr
+
+	options := &dockertest.RunOptions{
+		Repository:   "docker.vectorized.io/vectorized/redpanda",
+		Tag:          "latest",
+		Hostname:     "redpanda",
+		ExposedPorts: []string{"9092"},
+		PortBindings: map[docker.Port][]docker.PortBinding{
+			"9092/tcp": {{HostIP: "", HostPort: kafkaPortStr}},
+		},
+		Cmd: []string{
+			"redpanda", "start", "--smp 1", "--overprovisioned",
+			"--kafka-addr 0.0.0.0:9092",
+			"--set redpanda.auto_create_topics_enabled=false",
+			fmt.Sprintf("--advertise-kafka-addr localhost:%v", kafkaPort),
+		},
+	}
+
+	pool.MaxWait = time.Minute
+	resource, err := pool.RunWithOptions(options)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, pool.Purge(resource))
+	})
+
+	_ = resource.Expire(900)
+	require.NoError(t, pool.Retry(func() error {
+		return createKafkaTopic(context.Background(), address, "testingconnection", 1)
+	}))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Minute*3)
+	defer done()
+
+	t.Run("output creates topics", func(t *testing.T) {
+		streamBuilder := service.NewStreamBuilder()
+		require.NoError(t, streamBuilder.AddOutputYAML(fmt.Sprintf(`
+kafka_franz:
+  seed_brokers: [ %v ]
+  topic: 'tenant-${! json("tenant") }'
+  create_topics:
+    enabled: true
+    partitions: 2
+`, address)))
+
+		sendFn, err := streamBuilder.AddProducerFunc()
+		require.NoError(t, err)
+
+		stream, err := streamBuilder.Build()
+		require.NoError(t, err)
+
+		go func() {
+			_ = stream.Run(ctx)
+		}()
+		t.Cleanup(func() {
+			require.NoError(t, stream.StopWithin(time.Second*10))
+		})
+
+		for _, tenant := range []string{"a", "b", "a"} {
+			require.NoError(t, sendFn(ctx, service.NewMessage([]byte(fmt.Sprintf(`{"tenant":%q}`, tenant)))))
+		}
+
+		consumer, err := kgo.NewClient(
+			kgo.SeedBrokers(address),
+			kgo.ConsumeTopics("tenant-a", "tenant-b"),
+			kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+		)
+		require.NoError(t, err)
+		defer consumer.Close()
+
+		seen := 0
+		for seen < 3 {
+			fetches := consumer.PollFetches(ctx)
+			require.NoError(t, fetches.Err())
+			seen += fetches.NumRecords()
+		}
+	})
+
+	t.Run("admin processor", func(t *testing.T) {
+		streamBuilder := service.NewStreamBuilder()
+		require.NoError(t, streamBuilder.AddProcessorYAML(fmt.Sprintf(`
+kafka_admin:
+  seed_brokers: [ %v ]
+  operation: ${! meta("operation") }
+`, address)))
+
+		sendFn, err := streamBuilder.AddBatchProducerFunc()
+		require.NoError(t, err)
+
+		results := make(chan *service.Message, 1)
+		require.NoError(t, streamBuilder.AddConsumerFunc(func(ctx context.Context, m *service.Message) error {
+			results <- m
+			return nil
+		}))
+
+		stream, err := streamBuilder.Build()
+		require.NoError(t, err)
+
+		go func() {
+			_ = stream.Run(ctx)
+		}()
+		t.Cleanup(func() {
+			require.NoError(t, stream.StopWithin(time.Second*10))
+		})
+
+		runOp := func(operation, content string) *service.Message {
+			t.Helper()
+			msg := service.NewMessage([]byte(content))
+			msg.MetaSetMut("operation", operation)
+			require.NoError(t, sendFn(ctx, service.MessageBatch{msg}))
+			select {
+			case res := <-results:
+				require.NoError(t, res.GetError())
+				return res
+			case <-ctx.Done():
+				t.Fatal(ctx.Err())
+			}
+			return nil
+		}
+
+		runOp("create_topic", `{"topic":"admin-topic","partitions":2}`)
+		runOp("create_topic", `{"topic":"admin-topic","partitions":2}`)
+
+		producer, err := kgo.NewClient(kgo.SeedBrokers(address), kgo.RecordPartitioner(kgo.ManualPartitioner()))
+		require.NoError(t, err)
+		defer producer.Close()
+		for i := 0; i < 10; i++ {
+			require.NoError(t, producer.ProduceSync(ctx, &kgo.Record{
+				Topic:     "admin-topic",
+				Partition: int32(i % 2),
+				Value:     []byte("hello world"),
+			}).FirstErr())
+		}
+
+		runOp("alter_consumer_group_offsets", `{"group":"admin-group","offsets":[{"topic":"admin-topic","partition":0,"offset":2},{"topic":"admin-topic","partition":1,"offset":5}]}`)
+
+		res := runOp("describe_consumer_group_lag", `{"group":"admin-group"}`)
+		resBytes, err := res.AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+  "group": "admin-group",
+  "lag": 3,
+  "partitions": [
+    {"topic":"admin-topic","partition":0,"committed_offset":2,"end_offset":5,"lag":3},
+    {"topic":"admin-topic","partition":1,"committed_offset":5,"end_offset":5,"lag":0}
+  ]
+}`, string(resBytes))
+
+		runOp("delete_topic", `{"topic":"admin-topic"}`)
+		runOp("delete_topic", `{"topic":"admin-topic"}`)
+	})
+}
@@ -3,14 +3,18 @@ package kafka
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/twmb/franz-go/pkg/kerr"
 	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
 	"github.com/twmb/franz-go/pkg/sasl"
 
 	"github.com/benthosdev/benthos/v4/public/service"
@@ -25,8 +29,37 @@ func franzKafkaOutputConfig() *service.ConfigSpec {
 		Description(`
 Writes a batch of messages to Kafka brokers and waits for acknowledgement before propagating it back to the input.
 
-This output often out-performs the traditional ` + "`kafka`" + ` output as well as providing more useful logs and error messages.
+This output often out-performs the traditional `+"`kafka`"+` output as well as providing more useful logs and error messages.
+
+### Transactions
+
+When a `+"`transactional_id`"+` is configured each batch is written within a Kafka transaction. If the messages of a batch were consumed by a `+"[`kafka_franz` input](/docs/components/inputs/kafka_franz)"+` with `+"`transactional` set to `true`"+` then the offsets of those messages are committed within the same transaction, which provides exactly-once delivery from a Kafka topic to another. The offset of a partition is only committed up to the first of its messages that has yet to be delivered or acknowledged, and therefore when a batch fails the messages consumed after it may be written again should Benthos restart before the failed batch is delivered. In this mode batches are written one at a time, and messages of partitions that were revoked from the consumer group before they could be written are dropped, as they will be consumed again by the new owner of the partition.
+
+Offsets are committed in the order that batches are written, and therefore pipelines that might reorder messages, such as those with more than one processing thread, should be avoided.
 `).
+		Example(
+			"Exactly-Once Delivery",
+			"In this example messages are consumed from one topic, processed and written to another, with the offsets of consumed messages committed within the transaction that writes them.",
+			`
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ orders ]
+    consumer_group: orders_enrichment
+    transactional: true
+
+pipeline:
+  threads: 1
+  processors:
+    - mapping: 'root = this.merge({"enriched": true})'
+
+output:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topic: orders_enriched
+    transactional_id: orders_enrichment
+`,
+		).
 		Field(service.NewStringListField("seed_brokers").
 			Description("A list of broker addresses to connect to in order to establish connections. If an item of the list contains commas it will be expanded into multiple addresses.").
 			Example([]string{"localhost:9092"}).
@@ -60,6 +93,30 @@ This output often out-performs the traditional ` + "`kafka`" + ` output as well
 			Description("Enable the idempotent write producer option. This requires the `IDEMPOTENT_WRITE` permission on `CLUSTER` and can be disabled if this permission is not available.").
 			Default(true).
 			Advanced()).
+		Field(service.NewObjectField("create_topics",
+			service.NewBoolField("enabled").
+				Description("Whether topics that do not exist should be created before messages are written to them.").
+				Default(false),
+			service.NewIntField("partitions").
+				Description("The number of partitions of created topics, or -1 to use the default of the cluster.").
+				Default(-1),
+			service.NewIntField("replication_factor").
+				Description("The replication factor of created topics, or -1 to use the default of the cluster.").
+				Default(-1),
+			service.NewStringMapField("configs").
+				Description("A map of topic configs to set on created topics.").
+				Example(map[string]any{"retention.ms": "86400000", "cleanup.policy": "compact"}).
+				Default(map[string]any{}),
+		).
+			Description("Allows topics resolved from the `topic` field to be created by this output before they are written to, rather than relying on the cluster to create them automatically. Topics are created at most once per topic for the lifetime of the output, and topics that already exist are left unchanged.").
+			Advanced().
+			Version("4.26.0")).
+		Field(service.NewStringField("transactional_id").
+			Description("An optional transactional ID, which when set causes each batch to be written within a transaction. The ID should be unique to each instance of Benthos writing to the same cluster, but remain consistent across restarts so that unfinished transactions of prior instances can be aborted. When set `max_in_flight` is always 1.").
+			Example("orders_enrichment").
+			Optional().
+			Advanced().
+			Version("4.26.0")).
 		Field(service.NewMetadataFilterField("metadata").
 			Description("Determine which (if any) metadata values should be added to messages as headers.").
 			Optional()).
@@ -90,6 +147,8 @@ root = if this.partitioner == "manual" {
   }
 } else if this.partition.or("") != "" {
   "a partition cannot be specified unless the partitioner is set to manual"
+} else if this.transactional_id.or("") != "" && !this.idempotent_write.or(true) {
+  "idempotent_write must be enabled when a transactional_id is set"
 }`)
 }
 
@@ -107,7 +166,15 @@ func init() {
 			if batchPolicy, err = conf.FieldBatchPolicy("batching"); err != nil {
 				return
 			}
-			output, err = newFranzKafkaWriterFromConfig(conf, mgr.Logger())
+			var w *franzKafkaWriter
+			if w, err = newFranzKafkaWriterFromConfig(conf, mgr.Logger()); err != nil {
+				return
+			}
+			if w.transactionalID != "" {
+				// Offsets of consumed records must be committed in order.
+				maxInFlight = 1
+			}
+			output = w
 			return
 		})
 	if err != nil {
@@ -126,6 +193,8 @@ type franzKafkaWriter struct {
 	clientID         string
 	rackID           string
 	idempotentWrite  bool
+	transactionalID  string
+	createTopics     *franzTopicConfig
 	tlsConf          *tls.Config
 	saslConfs        []sasl.Mechanism
 	metaFilter       *service.MetadataFilter
@@ -136,6 +205,9 @@ type franzKafkaWriter struct {
 
 	client *kgo.Client
 
+	createdTopicsMut sync.Mutex
+	createdTopics    map[string]struct{}
+
 	log *service.Logger
 }
 
@@ -244,6 +316,19 @@ func newFranzKafkaWriterFromConfig(conf *service.ParsedConfig, log *service.Logg
 		return nil, err
 	}
 
+	if conf.Contains("transactional_id") {
+		if f.transactionalID, err = conf.FieldString("transactional_id"); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.createTopicsFromConfig(conf.Namespace("create_topics")); err != nil {
+		return nil, err
+	}
+
+	if f.transactionalID != "" && !f.idempotentWrite {
+		return nil, errors.New("idempotent_write must be enabled when a transactional_id is set")
+	}
+
 	if conf.Contains("metadata") {
 		if f.metaFilter, err = conf.FieldMetadataFilter("metadata"); err != nil {
 			return nil, err
@@ -264,6 +349,41 @@ func newFranzKafkaWriterFromConfig(conf *service.ParsedConfig, log *service.Logg
 	return &f, nil
 }
 
+func (f *franzKafkaWriter) createTopicsFromConfig(conf *service.ParsedConfig) error {
+	if enabled, err := conf.FieldBool("enabled"); err != nil || !enabled {
+		return err
+	}
+
+	partitions, err := conf.FieldInt("partitions")
+	if err != nil {
+		return err
+	}
+	if partitions == 0 || partitions < -1 || partitions > math.MaxInt32 {
+		return fmt.Errorf("invalid create_topics.partitions: %v", partitions)
+	}
+
+	replicationFactor, err := conf.FieldInt("replication_factor")
+	if err != nil {
+		return err
+	}
+	if replicationFactor == 0 || replicationFactor < -1 || replicationFactor > math.MaxInt16 {
+		return fmt.Errorf("invalid create_topics.replication_factor: %v", replicationFactor)
+	}
+
+	configs, err := conf.FieldStringMap("configs")
+	if err != nil {
+		return err
+	}
+
+	f.createTopics = &franzTopicConfig{
+		partitions:        int32(partitions),
+		replicationFactor: int16(replicationFactor),
+		configs:           configs,
+	}
+	f.createdTopics = map[string]struct{}{}
+	return nil
+}
+
 //------------------------------------------------------------------------------
 
 func (f *franzKafkaWriter) Connect(ctx context.Context) error {
@@ -290,6 +410,9 @@ func (f *franzKafkaWriter) Connect(ctx context.Context) error {
 	if !f.idempotentWrite {
 		clientOpts = append(clientOpts, kgo.DisableIdempotentWrite())
 	}
+	if f.transactionalID != "" {
+		clientOpts = append(clientOpts, kgo.TransactionalID(f.transactionalID))
+	}
 	if len(f.compressionPrefs) > 0 {
 		clientOpts = append(clientOpts, kgo.ProducerBatchCompression(f.compressionPrefs...))
 	}
@@ -309,8 +432,22 @@ func (f *franzKafkaWriter) WriteBatch(ctx context.Context, b service.MessageBatc
 		return service.ErrNotConnected
 	}
 
+	var offsets franzTxnOffsets
 	records := make([]*kgo.Record, 0, len(b))
 	for i, msg := range b {
+		if f.transactionalID != "" {
+			if tr := franzTxnRecordFromMessage(msg); tr != nil {
+				if tr.tracker.revoked.Load() {
+					// The partition this message was consumed from has been
+					// revoked, and will be consumed again by its new owner.
+					continue
+				}
+				if err = offsets.add(tr); err != nil {
+					return
+				}
+			}
+		}
+
 		var topic string
 		if topic, err = b.TryInterpolatedString(i, f.topic); err != nil {
 			return fmt.Errorf("topic interpolation error: %w", err)
@@ -346,12 +483,202 @@ func (f *franzKafkaWriter) WriteBatch(ctx context.Context, b service.MessageBatc
 		records = append(records, record)
 	}
 
+	if f.createTopics != nil {
+		if err = f.ensureTopics(ctx, records); err != nil {
+			return
+		}
+	}
+
+	if f.transactionalID != "" {
+		return f.writeTransaction(ctx, records, &offsets)
+	}
+
 	// TODO: This is very cool and allows us to easily return granular errors,
 	// so we should honor travis by doing it.
 	err = f.client.ProduceSync(ctx, records...).FirstErr()
 	return
 }
 
+// ensureTopics creates any topics of a batch that have not yet been created by
+// this output.
+func (f *franzKafkaWriter) ensureTopics(ctx context.Context, records []*kgo.Record) error {
+	f.createdTopicsMut.Lock()
+	defer f.createdTopicsMut.Unlock()
+
+	for _, r := range records {
+		if _, exists := f.createdTopics[r.Topic]; exists {
+			continue
+		}
+		if err := franzCreateTopic(ctx, f.client, r.Topic, *f.createTopics); err != nil && !errors.Is(err, kerr.TopicAlreadyExists) {
+			return fmt.Errorf("failed to create topic %v: %w", r.Topic, err)
+		} else if err == nil {
+			f.log.Infof("Created Kafka topic: %v", r.Topic)
+		}
+		f.createdTopics[r.Topic] = struct{}{}
+	}
+	return nil
+}
+
+// franzTxnOffsets tracks the offsets of each topic partition consumed by a
+// transactional input that are written within a batch.
+type franzTxnOffsets struct {
+	source *franzTxnSource
+	topics map[string]map[int32]*franzTxnWritten
+}
+
+type franzTxnWritten struct {
+	tracker *franzTxnPartition
+	offsets map[int64]struct{}
+}
+
+func (w *franzTxnWritten) contains(offset int64) bool {
+	_, exists := w.offsets[offset]
+	return exists
+}
+
+func (o *franzTxnOffsets) add(r *franzTxnRecord) error {
+	if o.source == nil {
+		o.source = r.source
+		o.topics = map[string]map[int32]*franzTxnWritten{}
+	} else if o.source != r.source {
+		return errors.New("messages consumed by multiple transactional inputs cannot be written within the same transaction")
+	}
+
+	partitions := o.topics[r.topic]
+	if partitions == nil {
+		partitions = map[int32]*franzTxnWritten{}
+		o.topics[r.topic] = partitions
+	}
+	written := partitions[r.partition]
+	if written == nil {
+		written = &franzTxnWritten{
+			tracker: r.tracker,
+			offsets: map[int64]struct{}{},
+		}
+		partitions[r.partition] = written
+	}
+	written.offsets[r.offset] = struct{}{}
+	return nil
+}
+
+// watermarks returns the offsets that can be committed for each partition of
+// the batch, which only cover written records when all prior records of the
+// partition have either been acknowledged or are also written by the batch.
+func (o *franzTxnOffsets) watermarks() map[string]map[int32]kgo.EpochOffset {
+	offsets := map[string]map[int32]kgo.EpochOffset{}
+	for topic, partitions := range o.topics {
+		for partition, written := range partitions {
+			offset, advanced := written.tracker.watermark(written.contains)
+			if !advanced {
+				continue
+			}
+			if offsets[topic] == nil {
+				offsets[topic] = map[int32]kgo.EpochOffset{}
+			}
+			offsets[topic][partition] = offset
+		}
+	}
+	return offsets
+}
+
+func (f *franzKafkaWriter) writeTransaction(ctx context.Context, records []*kgo.Record, offsets *franzTxnOffsets) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	if err := f.client.BeginTransaction(); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	var committed map[string]map[int32]kgo.EpochOffset
+	err := f.client.ProduceSync(ctx, records...).FirstErr()
+	if err == nil && offsets.source != nil {
+		// Offsets are calculated and committed whilst holding the lock of the
+		// source, as otherwise they could be committed out of order.
+		offsets.source.commitMut.Lock()
+		defer offsets.source.commitMut.Unlock()
+
+		if committed = offsets.watermarks(); len(committed) > 0 {
+			err = f.commitTxnOffsets(ctx, offsets.source, committed)
+		}
+	}
+	if err == nil {
+		if err = f.client.EndTransaction(ctx, kgo.TryCommit); err == nil {
+			for topic, partitions := range committed {
+				for partition, offset := range partitions {
+					offsets.topics[topic][partition].tracker.commit(offset.Offset)
+				}
+			}
+			return nil
+		}
+		if !errors.Is(err, kerr.OperationNotAttempted) {
+			// The outcome of the transaction is unknown, and so we reset the
+			// client in order for it to be aborted when we reconnect.
+			f.disconnect()
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+	}
+
+	if abortErr := f.client.EndTransaction(ctx, kgo.TryAbort); abortErr != nil {
+		f.log.Errorf("Failed to abort transaction: %v", abortErr)
+		f.disconnect()
+	}
+	return err
+}
+
+func (f *franzKafkaWriter) commitTxnOffsets(ctx context.Context, source *franzTxnSource, offsets map[string]map[int32]kgo.EpochOffset) error {
+	producerID, producerEpoch, err := f.client.ProducerID(ctx)
+	if err != nil {
+		return err
+	}
+
+	addReq := kmsg.NewPtrAddOffsetsToTxnRequest()
+	addReq.TransactionalID = f.transactionalID
+	addReq.ProducerID = producerID
+	addReq.ProducerEpoch = producerEpoch
+	addReq.Group = source.group
+
+	addRes, err := addReq.RequestWith(ctx, f.client)
+	if err == nil {
+		err = kerr.ErrorForCode(addRes.ErrorCode)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add offsets to transaction: %w", err)
+	}
+
+	req := kmsg.NewPtrTxnOffsetCommitRequest()
+	req.TransactionalID = f.transactionalID
+	req.Group = source.group
+	req.ProducerID = producerID
+	req.ProducerEpoch = producerEpoch
+	req.MemberID, req.Generation = source.metadata()
+	for topic, partitions := range offsets {
+		reqTopic := kmsg.NewTxnOffsetCommitRequestTopic()
+		reqTopic.Topic = topic
+		for partition, offset := range partitions {
+			reqPartition := kmsg.NewTxnOffsetCommitRequestTopicPartition()
+			reqPartition.Partition = partition
+			reqPartition.Offset = offset.Offset
+			reqPartition.LeaderEpoch = offset.Epoch
+			reqTopic.Partitions = append(reqTopic.Partitions, reqPartition)
+		}
+		req.Topics = append(req.Topics, reqTopic)
+	}
+
+	res, err := req.RequestWith(ctx, f.client)
+	if err != nil {
+		return fmt.Errorf("failed to commit offsets within transaction: %w", err)
+	}
+	for _, t := range res.Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return fmt.Errorf("failed to commit offset of topic %v partition %v within transaction: %w", t.Topic, p.Partition, err)
+			}
+		}
+	}
+	return nil
+}
+
 func (f *franzKafkaWriter) disconnect() {
 	if f.client == nil {
 		return
@@ -1,10 +1,12 @@
 package kafka
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
 
 	"github.com/benthosdev/benthos/v4/public/service"
 )
@@ -53,6 +55,17 @@ kafka_franz:
 `,
 			errContains: "a partition cannot be specified unless the partitioner is set to manual",
 		},
+		{
+			name: "transactional id without idempotent writes",
+			conf: `
+kafka_franz:
+  seed_brokers: [ foo:1234 ]
+  topic: foo
+  transactional_id: foo
+  idempotent_write: false
+`,
+			errContains: "idempotent_write must be enabled when a transactional_id is set",
+		},
 	}
 
 	for _, test := range testCases {
@@ -68,3 +81,94 @@ kafka_franz:
 		})
 	}
 }
+
+func TestKafkaFranzTxnOffsets(t *testing.T) {
+	sourceA, sourceB := &franzTxnSource{group: "a"}, &franzTxnSource{group: "b"}
+
+	fooZero, fooOne, barZero := newFranzTxnPartition(), newFranzTxnPartition(), newFranzTxnPartition()
+	for i := int64(0); i < 10; i++ {
+		fooZero.track(i, 1)
+		fooOne.track(i, 1)
+		barZero.track(i, 1)
+	}
+
+	// The first records of foo partition 0 were acknowledged without being
+	// written, and records 3 and 4 belong to a batch that failed.
+	fooZero.ack(0, 2)
+
+	var offsets franzTxnOffsets
+	for _, r := range []*franzTxnRecord{
+		{source: sourceA, topic: "foo", partition: 0, offset: 6, tracker: fooZero},
+		{source: sourceA, topic: "foo", partition: 0, offset: 5, tracker: fooZero},
+		{source: sourceA, topic: "foo", partition: 1, offset: 0, tracker: fooOne},
+		{source: sourceA, topic: "foo", partition: 1, offset: 1, tracker: fooOne},
+		{source: sourceA, topic: "bar", partition: 0, offset: 2, tracker: barZero},
+	} {
+		require.NoError(t, offsets.add(r))
+	}
+
+	assert.Equal(t, map[string]map[int32]kgo.EpochOffset{
+		"foo": {
+			0: {Epoch: 1, Offset: 3},
+			1: {Epoch: 1, Offset: 2},
+		},
+	}, offsets.watermarks())
+
+	require.EqualError(t, offsets.add(&franzTxnRecord{source: sourceB, topic: "foo", partition: 0, offset: 1, tracker: fooZero}),
+		"messages consumed by multiple transactional inputs cannot be written within the same transaction")
+}
+
+func TestKafkaFranzTxnRecordFromMessage(t *testing.T) {
+	assert.Nil(t, franzTxnRecordFromMessage(service.NewMessage(nil)))
+
+	r := &franzTxnRecord{topic: "foo", offset: 5}
+	msg := service.NewMessage(nil)
+	msg = msg.WithContext(context.WithValue(msg.Context(), franzTxnRecordKey{}, r))
+	assert.Equal(t, r, franzTxnRecordFromMessage(msg.Copy()))
+}
+
+func TestKafkaFranzOutputCreateTopicsConfig(t *testing.T) {
+	conf, err := franzKafkaOutputConfig().ParseYAML(`
+seed_brokers: [ foo:1234 ]
+topic: foo
+create_topics:
+  enabled: true
+  partitions: 3
+  configs:
+    retention.ms: "1000"
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newFranzKafkaWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NotNil(t, w.createTopics)
+	assert.Equal(t, franzTopicConfig{
+		partitions:        3,
+		replicationFactor: -1,
+		configs:           map[string]string{"retention.ms": "1000"},
+	}, *w.createTopics)
+
+	conf, err = franzKafkaOutputConfig().ParseYAML(`
+seed_brokers: [ foo:1234 ]
+topic: foo
+create_topics:
+  partitions: 3
+`, nil)
+	require.NoError(t, err)
+
+	w, err = newFranzKafkaWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+	assert.Nil(t, w.createTopics)
+
+	conf, err = franzKafkaOutputConfig().ParseYAML(`
+seed_brokers: [ foo:1234 ]
+topic: foo
+create_topics:
+  enabled: true
+  replication_factor: 0
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newFranzKafkaWriterFromConfig(conf, nil)
+	require.EqualError(t, err, "invalid create_topics.replication_factor: 0")
+}
@@ -0,0 +1,407 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	kaOpCreateTopic      = "create_topic"
+	kaOpDeleteTopic      = "delete_topic"
+	kaOpDescribeGroupLag = "describe_consumer_group_lag"
+	kaOpAlterGroupOffset = "alter_consumer_group_offsets"
+)
+
+func kafkaAdminProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Integration").
+		Version("4.26.0").
+		Summary("Performs administrative operations against a Kafka cluster using the [Franz Kafka client library](https://github.com/twmb/franz-go), with the parameters of each operation taken from the contents of messages.").
+		Description(`
+The operation to perform is resolved for each message from the `+"`operation`"+` field, and its parameters are read from the structured contents of the message. In order to build the parameters from a different structure use a `+"[`mapping` processor](/docs/components/processors/mapping)"+` beforehand, and in order to keep the original contents of a message compose this processor within a `+"[`branch` processor](/docs/components/processors/branch)"+`.
+
+### Operations
+
+#### `+"`create_topic`"+`
+
+Creates a topic, with the parameters `+"`topic`"+`, and optionally `+"`partitions`, `replication_factor`"+` and a `+"`configs`"+` object of topic configs. When the partitions or replication factor are omitted the defaults of the cluster are used. Creating a topic that already exists is not an error. The contents of the message are unchanged.
+
+`+"```json"+`
+{"topic":"tenant_foo_events","partitions":6,"replication_factor":3,"configs":{"retention.ms":"86400000"}}
+`+"```"+`
+
+#### `+"`delete_topic`"+`
+
+Deletes a topic, with the parameter `+"`topic`"+`. Deleting a topic that does not exist is not an error. The contents of the message are unchanged.
+
+`+"```json"+`
+{"topic":"tenant_foo_events"}
+`+"```"+`
+
+#### `+"`describe_consumer_group_lag`"+`
+
+Describes the lag of each topic partition that a consumer group has committed offsets for, with the parameter `+"`group`"+`. The contents of the message are replaced with the result:
+
+`+"```json"+`
+{"group":"foo","lag":15,"partitions":[{"topic":"bar","partition":0,"committed_offset":100,"end_offset":115,"lag":15}]}
+`+"```"+`
+
+#### `+"`alter_consumer_group_offsets`"+`
+
+Commits offsets on behalf of a consumer group, with the parameters `+"`group`"+` and `+"`offsets`"+`, an array of objects with the fields `+"`topic`, `partition` and `offset`"+`. The consumer group must not have any active members. The contents of the message are unchanged.
+
+`+"```json"+`
+{"group":"foo","offsets":[{"topic":"bar","partition":0,"offset":100}]}
+`+"```"+``).
+		Example(
+			"Per-Tenant Topic Provisioning",
+			"In this example tenant sign up events are used to create a topic for each new tenant, with the original events then passed through unchanged.",
+			`
+pipeline:
+  processors:
+    - branch:
+        request_map: 'root.topic = "tenant_" + this.tenant_id + "_events"'
+        processors:
+          - kafka_admin:
+              seed_brokers: [ localhost:9092 ]
+              operation: create_topic
+`,
+		).
+		Example(
+			"Monitoring Consumer Group Lag",
+			"In this example the total lag of a consumer group is periodically emitted as a message.",
+			`
+input:
+  generate:
+    interval: 1m
+    mapping: 'root.group = "orders_enrichment"'
+
+pipeline:
+  processors:
+    - kafka_admin:
+        seed_brokers: [ localhost:9092 ]
+        operation: describe_consumer_group_lag
+    - mapping: 'root = { "group": this.group, "lag": this.lag }'
+`,
+		).
+		Field(service.NewStringListField("seed_brokers").
+			Description("A list of broker addresses to connect to in order to establish connections. If an item of the list contains commas it will be expanded into multiple addresses.").
+			Example([]string{"localhost:9092"}).
+			Example([]string{"foo:9092", "bar:9092"}).
+			Example([]string{"foo:9092,bar:9092"})).
+		Field(service.NewInterpolatedStringField("operation").
+			Description("The operation to perform, which must resolve to one of `" + strings.Join([]string{kaOpCreateTopic, kaOpDeleteTopic, kaOpDescribeGroupLag, kaOpAlterGroupOffset}, "`, `") + "`.").
+			Example(kaOpCreateTopic).
+			Example(`${! meta("operation") }`)).
+		Field(service.NewStringField("client_id").
+			Description("An identifier for the client connection.").
+			Default("benthos").
+			Advanced()).
+		Field(service.NewDurationField("timeout").
+			Description("The maximum period of time to wait for an operation to complete.").
+			Default("10s").
+			Advanced()).
+		Field(service.NewTLSToggledField("tls")).
+		Field(saslField())
+}
+
+func init() {
+	err := service.RegisterProcessor("kafka_admin", kafkaAdminProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newKafkaAdminProcessorFromConfig(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type kafkaAdminProcessor struct {
+	operation *service.InterpolatedString
+	timeout   time.Duration
+
+	client *kgo.Client
+	log    *service.Logger
+}
+
+func newKafkaAdminProcessorFromConfig(conf *service.ParsedConfig, log *service.Logger) (*kafkaAdminProcessor, error) {
+	k := kafkaAdminProcessor{
+		log: log,
+	}
+
+	brokerList, err := conf.FieldStringList("seed_brokers")
+	if err != nil {
+		return nil, err
+	}
+	var seedBrokers []string
+	for _, b := range brokerList {
+		seedBrokers = append(seedBrokers, strings.Split(b, ",")...)
+	}
+
+	if k.operation, err = conf.FieldInterpolatedString("operation"); err != nil {
+		return nil, err
+	}
+
+	if k.timeout, err = conf.FieldDuration("timeout"); err != nil {
+		return nil, err
+	}
+
+	clientID, err := conf.FieldString("client_id")
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
+	if err != nil {
+		return nil, err
+	}
+
+	saslConfs, err := saslMechanismsFromConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOpts := []kgo.Opt{
+		kgo.SeedBrokers(seedBrokers...),
+		kgo.SASL(saslConfs...),
+		kgo.ClientID(clientID),
+		kgo.WithLogger(&kgoLogger{log}),
+	}
+	if tlsEnabled {
+		clientOpts = append(clientOpts, kgo.DialTLSConfig(tlsConf))
+	}
+
+	// The client connects lazily and so this does not block.
+	if k.client, err = kgo.NewClient(clientOpts...); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (k *kafkaAdminProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	operation, err := k.operation.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("operation interpolation error: %w", err)
+	}
+
+	structured, err := msg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse operation parameters: %w", err)
+	}
+	params, ok := structured.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected operation parameters to be an object, got %T", structured)
+	}
+
+	ctx, done := context.WithTimeout(ctx, k.timeout)
+	defer done()
+
+	switch operation {
+	case kaOpCreateTopic:
+		err = k.createTopic(ctx, params)
+	case kaOpDeleteTopic:
+		err = k.deleteTopic(ctx, params)
+	case kaOpDescribeGroupLag:
+		var result map[string]any
+		if result, err = k.describeGroupLag(ctx, params); err == nil {
+			msg.SetStructuredMut(result)
+		}
+	case kaOpAlterGroupOffset:
+		err = k.alterGroupOffsets(ctx, params)
+	default:
+		return nil, fmt.Errorf("operation not recognised: %v", operation)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", operation, err)
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (k *kafkaAdminProcessor) createTopic(ctx context.Context, params map[string]any) error {
+	topic, err := kaParamString(params, "topic")
+	if err != nil {
+		return err
+	}
+
+	conf := franzTopicConfig{partitions: -1, replicationFactor: -1}
+	if _, exists := params["partitions"]; exists {
+		partitions, err := kaParamInt(params, "partitions", 1, math.MaxInt32)
+		if err != nil {
+			return err
+		}
+		conf.partitions = int32(partitions)
+	}
+	if _, exists := params["replication_factor"]; exists {
+		replicationFactor, err := kaParamInt(params, "replication_factor", 1, math.MaxInt16)
+		if err != nil {
+			return err
+		}
+		conf.replicationFactor = int16(replicationFactor)
+	}
+	if v, exists := params["configs"]; exists {
+		configsObj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("expected field configs to be an object, got %T", v)
+		}
+		conf.configs = make(map[string]string, len(configsObj))
+		for k, v := range configsObj {
+			conf.configs[k] = fmt.Sprintf("%v", v)
+		}
+	}
+
+	if err := franzCreateTopic(ctx, k.client, topic, conf); err != nil {
+		if errors.Is(err, kerr.TopicAlreadyExists) {
+			return nil
+		}
+		return err
+	}
+	k.log.Infof("Created Kafka topic: %v", topic)
+	return nil
+}
+
+func (k *kafkaAdminProcessor) deleteTopic(ctx context.Context, params map[string]any) error {
+	topic, err := kaParamString(params, "topic")
+	if err != nil {
+		return err
+	}
+	if err := franzDeleteTopic(ctx, k.client, topic); err != nil {
+		if errors.Is(err, kerr.UnknownTopicOrPartition) {
+			return nil
+		}
+		return err
+	}
+	k.log.Infof("Deleted Kafka topic: %v", topic)
+	return nil
+}
+
+func (k *kafkaAdminProcessor) describeGroupLag(ctx context.Context, params map[string]any) (map[string]any, error) {
+	group, err := kaParamString(params, "group")
+	if err != nil {
+		return nil, err
+	}
+
+	lags, err := franzGroupLag(ctx, k.client, group)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	partitions := make([]any, 0, len(lags))
+	for _, l := range lags {
+		total += l.Lag
+		partitions = append(partitions, map[string]any{
+			"topic":            l.Topic,
+			"partition":        int64(l.Partition),
+			"committed_offset": l.CommittedOffset,
+			"end_offset":       l.EndOffset,
+			"lag":              l.Lag,
+		})
+	}
+	return map[string]any{
+		"group":      group,
+		"lag":        total,
+		"partitions": partitions,
+	}, nil
+}
+
+func (k *kafkaAdminProcessor) alterGroupOffsets(ctx context.Context, params map[string]any) error {
+	group, err := kaParamString(params, "group")
+	if err != nil {
+		return err
+	}
+
+	offsetsArr, ok := params["offsets"].([]any)
+	if !ok {
+		return fmt.Errorf("expected field offsets to be an array, got %T", params["offsets"])
+	}
+
+	offsets := map[string]map[int32]int64{}
+	for i, v := range offsetsArr {
+		offsetObj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("expected offsets index %v to be an object, got %T", i, v)
+		}
+		topic, err := kaParamString(offsetObj, "topic")
+		if err != nil {
+			return fmt.Errorf("offsets index %v: %w", i, err)
+		}
+		partition, err := kaParamInt(offsetObj, "partition", 0, math.MaxInt32)
+		if err != nil {
+			return fmt.Errorf("offsets index %v: %w", i, err)
+		}
+		offset, err := kaParamInt(offsetObj, "offset", 0, math.MaxInt64)
+		if err != nil {
+			return fmt.Errorf("offsets index %v: %w", i, err)
+		}
+		if offsets[topic] == nil {
+			offsets[topic] = map[int32]int64{}
+		}
+		offsets[topic][int32(partition)] = offset
+	}
+	if len(offsets) == 0 {
+		return errors.New("at least one offset must be specified")
+	}
+	return franzAlterGroupOffsets(ctx, k.client, group, offsets)
+}
+
+func (k *kafkaAdminProcessor) Close(ctx context.Context) error {
+	k.client.Close()
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func kaParamString(params map[string]any, key string) (string, error) {
+	v, exists := params[key]
+	if !exists {
+		return "", fmt.Errorf("missing field %v", key)
+	}
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return "", fmt.Errorf("expected field %v to be a non-empty string, got %T", key, v)
+	}
+	return s, nil
+}
+
+func kaParamInt(params map[string]any, key string, minValue, maxValue int64) (int64, error) {
+	v, exists := params[key]
+	if !exists {
+		return 0, fmt.Errorf("missing field %v", key)
+	}
+
+	var i int64
+	switch t := v.(type) {
+	case int64:
+		i = t
+	case int:
+		i = int64(t)
+	case float64:
+		if t != math.Trunc(t) {
+			return 0, fmt.Errorf("expected field %v to be an integer, got %v", key, t)
+		}
+		i = int64(t)
+	case json.Number:
+		var err error
+		if i, err = t.Int64(); err != nil {
+			return 0, fmt.Errorf("expected field %v to be an integer: %w", key, err)
+		}
+	default:
+		return 0, fmt.Errorf("expected field %v to be an integer, got %T", key, v)
+	}
+	if i < minValue || i > maxValue {
+		return 0, fmt.Errorf("field %v value %v is out of range", key, i)
+	}
+	return i, nil
+}
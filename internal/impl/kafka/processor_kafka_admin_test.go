@@ -0,0 +1,111 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestKafkaAdminProcessorBadParams(t *testing.T) {
+	conf, err := kafkaAdminProcessorConfig().ParseYAML(`
+seed_brokers: [ localhost:1234 ]
+operation: ${! meta("operation") }
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newKafkaAdminProcessorFromConfig(conf, service.MockResources().Logger())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+
+	tests := []struct {
+		name      string
+		operation string
+		content   string
+		errString string
+	}{
+		{
+			name:      "unknown operation",
+			operation: "nope",
+			content:   `{"topic":"foo"}`,
+			errString: "operation not recognised: nope",
+		},
+		{
+			name:      "not an object",
+			operation: "create_topic",
+			content:   `["foo"]`,
+			errString: "expected operation parameters to be an object, got []interface {}",
+		},
+		{
+			name:      "missing topic",
+			operation: "create_topic",
+			content:   `{"partitions":3}`,
+			errString: "create_topic: missing field topic",
+		},
+		{
+			name:      "bad partitions",
+			operation: "create_topic",
+			content:   `{"topic":"foo","partitions":0}`,
+			errString: "create_topic: field partitions value 0 is out of range",
+		},
+		{
+			name:      "fractional replication factor",
+			operation: "create_topic",
+			content:   `{"topic":"foo","replication_factor":1.5}`,
+			errString: "create_topic: expected field replication_factor to be an integer: strconv.ParseInt: parsing \"1.5\": invalid syntax",
+		},
+		{
+			name:      "bad configs",
+			operation: "create_topic",
+			content:   `{"topic":"foo","configs":"nope"}`,
+			errString: "create_topic: expected field configs to be an object, got string",
+		},
+		{
+			name:      "empty topic",
+			operation: "delete_topic",
+			content:   `{"topic":""}`,
+			errString: "delete_topic: expected field topic to be a non-empty string, got string",
+		},
+		{
+			name:      "missing group",
+			operation: "describe_consumer_group_lag",
+			content:   `{}`,
+			errString: "describe_consumer_group_lag: missing field group",
+		},
+		{
+			name:      "missing offsets",
+			operation: "alter_consumer_group_offsets",
+			content:   `{"group":"foo"}`,
+			errString: "alter_consumer_group_offsets: expected field offsets to be an array, got <nil>",
+		},
+		{
+			name:      "empty offsets",
+			operation: "alter_consumer_group_offsets",
+			content:   `{"group":"foo","offsets":[]}`,
+			errString: "alter_consumer_group_offsets: at least one offset must be specified",
+		},
+		{
+			name:      "bad offset",
+			operation: "alter_consumer_group_offsets",
+			content:   `{"group":"foo","offsets":[{"topic":"bar","partition":0,"offset":-1}]}`,
+			errString: "alter_consumer_group_offsets: offsets index 0: field offset value -1 is out of range",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			msg := service.NewMessage([]byte(test.content))
+			msg.MetaSetMut("operation", test.operation)
+
+			_, err := proc.Process(context.Background(), msg)
+			require.Error(t, err)
+			assert.Equal(t, test.errString, err.Error())
+		})
+	}
+}
@@ -3,6 +3,8 @@ package memcached
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -40,14 +42,14 @@ func init() {
 	err := service.RegisterCache(
 		"memcached", memcachedConfig(),
 		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
-			return newMemcachedFromConfig(conf)
+			return newMemcachedFromConfig(conf, mgr.Logger())
 		})
 	if err != nil {
 		panic(err)
 	}
 }
 
-func newMemcachedFromConfig(conf *service.ParsedConfig) (*memcachedCache, error) {
+func newMemcachedFromConfig(conf *service.ParsedConfig, log *service.Logger) (*memcachedCache, error) {
 	addresses, err := conf.FieldStringList("addresses")
 	if err != nil {
 		return nil, err
@@ -69,7 +71,7 @@ func newMemcachedFromConfig(conf *service.ParsedConfig) (*memcachedCache, error)
 	if err != nil {
 		return nil, err
 	}
-	return newMemcachedCache(addresses, prefix, ttl, backOff)
+	return newMemcachedCache(addresses, prefix, ttl, backOff, log)
 }
 
 //------------------------------------------------------------------------------
@@ -80,6 +82,7 @@ type memcachedCache struct {
 
 	mc       *memcache.Client
 	boffPool sync.Pool
+	log      *service.Logger
 }
 
 func newMemcachedCache(
@@ -87,6 +90,7 @@ func newMemcachedCache(
 	prefix string,
 	defaultTTL time.Duration,
 	backOff *backoff.ExponentialBackOff,
+	log *service.Logger,
 ) (*memcachedCache, error) {
 	addresses := []string{}
 	for _, addr := range inAddresses {
@@ -100,6 +104,7 @@ func newMemcachedCache(
 		mc:         memcache.New(addresses...),
 		prefix:     prefix,
 		defaultTTL: defaultTTL,
+		log:        log,
 		boffPool: sync.Pool{
 			New: func() any {
 				bo := *backOff
@@ -152,6 +157,42 @@ func (m *memcachedCache) Get(ctx context.Context, key string) ([]byte, error) {
 	}
 }
 
+func (m *memcachedCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	boff := m.boffPool.Get().(backoff.BackOff)
+	defer func() {
+		boff.Reset()
+		m.boffPool.Put(boff)
+	}()
+
+	prefixedKeys := make([]string, len(keys))
+	for i, k := range keys {
+		prefixedKeys[i] = m.prefix + k
+	}
+
+	for {
+		items, err := m.mc.GetMulti(prefixedKeys)
+		if err == nil {
+			values := make(map[string][]byte, len(items))
+			for i, k := range prefixedKeys {
+				if item, exists := items[k]; exists {
+					values[keys[i]] = item.Value
+				}
+			}
+			return values, nil
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return nil, err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
+
 func (m *memcachedCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	boff := m.boffPool.Get().(backoff.BackOff)
 	defer func() {
@@ -207,6 +248,77 @@ func (m *memcachedCache) Add(ctx context.Context, key string, value []byte, ttl
 	}
 }
 
+// Incr atomically increments a counter. Memcached counters are unsigned and
+// therefore a decrement is capped at zero. Refreshing the TTL of the counter is
+// best effort, as it cannot be applied atomically with the increment.
+func (m *memcachedCache) Incr(ctx context.Context, key string, delta int64, ttl *time.Duration) (int64, error) {
+	boff := m.boffPool.Get().(backoff.BackOff)
+	defer func() {
+		boff.Reset()
+		m.boffPool.Put(boff)
+	}()
+
+	for {
+		n, err := m.incr(key, delta, ttl)
+		if err == nil {
+			// Refreshing the TTL is not retried as that would require the
+			// increment to be repeated, and a failure is not returned as the
+			// increment has already been applied.
+			if item := m.getItemFor(key, nil, ttl); item.Expiration > 0 {
+				if err = m.mc.Touch(item.Key, item.Expiration); err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+					m.log.Warnf("Failed to refresh ttl of counter '%v': %v", key, err)
+				}
+			}
+			return n, nil
+		}
+		if strings.HasPrefix(err.Error(), "memcache: client error") {
+			return 0, fmt.Errorf("value of key '%v' is not an integer: %w", key, err)
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return 0, err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return 0, err
+		}
+	}
+}
+
+func (m *memcachedCache) incr(key string, delta int64, ttl *time.Duration) (int64, error) {
+	item := m.getItemFor(key, nil, ttl)
+	for {
+		var n uint64
+		var err error
+		if delta >= 0 {
+			n, err = m.mc.Increment(item.Key, uint64(delta))
+		} else {
+			n, err = m.mc.Decrement(item.Key, uint64(-delta))
+		}
+		if err == nil {
+			return int64(n), nil
+		}
+		if !errors.Is(err, memcache.ErrCacheMiss) {
+			return 0, err
+		}
+
+		// The counter does not exist yet and so we attempt to create it, and
+		// if another client beats us to it then we try incrementing again.
+		if delta < 0 {
+			delta = 0
+		}
+		item.Value = strconv.AppendInt(nil, delta, 10)
+		if err = m.mc.Add(item); err == nil {
+			return delta, nil
+		}
+		if !errors.Is(err, memcache.ErrNotStored) {
+			return 0, err
+		}
+	}
+}
+
 // Delete attempts to remove a key.
 func (m *memcachedCache) Delete(ctx context.Context, key string) error {
 	boff := m.boffPool.Get().(backoff.BackOff)
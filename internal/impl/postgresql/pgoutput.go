@@ -0,0 +1,306 @@
+package postgresql
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// The pgoutput plugin streams changes as messages documented at
+// https://www.postgresql.org/docs/current/protocol-logicalrep-message-formats.html
+
+type pgoutputBegin struct {
+	finalLSN   pgLSN
+	commitTime time.Time
+	xid        uint32
+}
+
+type pgoutputCommit struct {
+	commitLSN  pgLSN
+	endLSN     pgLSN
+	commitTime time.Time
+}
+
+type pgoutputColumn struct {
+	name    string
+	typeOID uint32
+	isKey   bool
+}
+
+type pgoutputRelation struct {
+	id        uint32
+	namespace string
+	name      string
+	columns   []pgoutputColumn
+}
+
+type pgoutputTupleColumn struct {
+	kind  byte
+	value []byte
+}
+
+const (
+	pgoutputTupleNull      = 'n'
+	pgoutputTupleUnchanged = 'u'
+	pgoutputTupleText      = 't'
+)
+
+type pgoutputChange struct {
+	operation  string
+	relationID uint32
+	before     []pgoutputTupleColumn
+	after      []pgoutputTupleColumn
+}
+
+type pgoutputTruncate struct {
+	relationIDs []uint32
+}
+
+type pgoutputReader struct {
+	data []byte
+	err  error
+}
+
+func (r *pgoutputReader) fail(format string, args ...any) {
+	if r.err == nil {
+		r.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (r *pgoutputReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.data) < n {
+		r.fail("message truncated, expected %v more bytes but got %v", n, len(r.data))
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *pgoutputReader) byte() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *pgoutputReader) uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *pgoutputReader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *pgoutputReader) uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *pgoutputReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	for i, c := range r.data {
+		if c == 0 {
+			s := string(r.data[:i])
+			r.data = r.data[i+1:]
+			return s
+		}
+	}
+	r.fail("message truncated, expected a null terminated string")
+	return ""
+}
+
+func (r *pgoutputReader) tuple() []pgoutputTupleColumn {
+	n := int(r.uint16())
+	if r.err != nil {
+		return nil
+	}
+	cols := make([]pgoutputTupleColumn, n)
+	for i := range cols {
+		cols[i].kind = r.byte()
+		switch cols[i].kind {
+		case pgoutputTupleNull, pgoutputTupleUnchanged:
+		case pgoutputTupleText:
+			cols[i].value = r.next(int(r.uint32()))
+		default:
+			r.fail("unsupported tuple column kind '%c'", cols[i].kind)
+		}
+	}
+	return cols
+}
+
+// parsePgoutputMessage parses a pgoutput message into one of the pgoutput types,
+// returning nil for messages that are not relevant to change data capture.
+func parsePgoutputMessage(data []byte) (any, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty pgoutput message")
+	}
+
+	r := &pgoutputReader{data: data[1:]}
+
+	var msg any
+	switch data[0] {
+	case 'B':
+		msg = pgoutputBegin{
+			finalLSN:   pgLSN(r.uint64()),
+			commitTime: pgTimeFromMicros(int64(r.uint64())),
+			xid:        r.uint32(),
+		}
+	case 'C':
+		_ = r.byte() // Flags are currently unused
+		msg = pgoutputCommit{
+			commitLSN:  pgLSN(r.uint64()),
+			endLSN:     pgLSN(r.uint64()),
+			commitTime: pgTimeFromMicros(int64(r.uint64())),
+		}
+	case 'R':
+		rel := pgoutputRelation{
+			id:        r.uint32(),
+			namespace: r.string(),
+			name:      r.string(),
+		}
+		_ = r.byte() // Replica identity
+		rel.columns = make([]pgoutputColumn, r.uint16())
+		for i := range rel.columns {
+			rel.columns[i].isKey = r.byte()&1 == 1
+			rel.columns[i].name = r.string()
+			rel.columns[i].typeOID = r.uint32()
+			_ = r.uint32() // Type modifier
+		}
+		msg = rel
+	case 'I':
+		c := pgoutputChange{operation: "insert", relationID: r.uint32()}
+		if kind := r.byte(); kind != 'N' && r.err == nil {
+			return nil, fmt.Errorf("unexpected insert tuple type '%c'", kind)
+		}
+		c.after = r.tuple()
+		msg = c
+	case 'U':
+		c := pgoutputChange{operation: "update", relationID: r.uint32()}
+		kind := r.byte()
+		if kind == 'K' || kind == 'O' {
+			c.before = r.tuple()
+			kind = r.byte()
+		}
+		if kind != 'N' && r.err == nil {
+			return nil, fmt.Errorf("unexpected update tuple type '%c'", kind)
+		}
+		c.after = r.tuple()
+		msg = c
+	case 'D':
+		c := pgoutputChange{operation: "delete", relationID: r.uint32()}
+		if kind := r.byte(); kind != 'K' && kind != 'O' && r.err == nil {
+			return nil, fmt.Errorf("unexpected delete tuple type '%c'", kind)
+		}
+		c.before = r.tuple()
+		msg = c
+	case 'T':
+		t := pgoutputTruncate{relationIDs: make([]uint32, r.uint32())}
+		_ = r.byte() // Options
+		for i := range t.relationIDs {
+			t.relationIDs[i] = r.uint32()
+		}
+		msg = t
+	case 'O', 'Y', 'M':
+		// Origins, types and logical decoding messages are ignored.
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported pgoutput message type '%c'", data[0])
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to parse pgoutput message '%c': %w", data[0], r.err)
+	}
+	return msg, nil
+}
+
+// tupleToMap converts the columns of a tuple into a map of column names to
+// values, where unchanged values that were not sent are omitted.
+func (rel *pgoutputRelation) tupleToMap(tuple []pgoutputTupleColumn) (map[string]any, error) {
+	if tuple == nil {
+		return nil, nil
+	}
+	if len(tuple) > len(rel.columns) {
+		return nil, fmt.Errorf("tuple has %v columns but relation %v.%v has %v", len(tuple), rel.namespace, rel.name, len(rel.columns))
+	}
+	m := make(map[string]any, len(tuple))
+	for i, c := range tuple {
+		col := rel.columns[i]
+		switch c.kind {
+		case pgoutputTupleNull:
+			m[col.name] = nil
+		case pgoutputTupleText:
+			v, err := decodePgText(col.typeOID, c.value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode column %v: %w", col.name, err)
+			}
+			m[col.name] = v
+		}
+	}
+	return m, nil
+}
+
+// Type OIDs from pg_type that are decoded into values other than strings.
+const (
+	pgTypeBool    = 16
+	pgTypeInt8    = 20
+	pgTypeInt2    = 21
+	pgTypeInt4    = 23
+	pgTypeOID     = 26
+	pgTypeJSON    = 114
+	pgTypeFloat4  = 700
+	pgTypeFloat8  = 701
+	pgTypeNumeric = 1700
+	pgTypeJSONB   = 3802
+)
+
+// decodePgText decodes the text representation of a value of a given type.
+// Types without a natural JSON representation are kept as strings.
+func decodePgText(typeOID uint32, text []byte) (any, error) {
+	s := string(text)
+	switch typeOID {
+	case pgTypeBool:
+		return s == "t", nil
+	case pgTypeInt2, pgTypeInt4, pgTypeInt8, pgTypeOID:
+		return strconv.ParseInt(s, 10, 64)
+	case pgTypeFloat4, pgTypeFloat8:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return s, nil
+		}
+		return f, nil
+	case pgTypeNumeric:
+		// Numerics are kept as their exact text, with the exception of special
+		// values such as NaN that are not valid JSON numbers.
+		if f, err := strconv.ParseFloat(s, 64); err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return s, nil
+		}
+		return json.Number(s), nil
+	case pgTypeJSON, pgTypeJSONB:
+		var v any
+		if err := json.Unmarshal(text, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	return s, nil
+}
@@ -0,0 +1,242 @@
+package postgresql
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pgoutputWriter struct {
+	b []byte
+}
+
+func (w *pgoutputWriter) byte(b byte) *pgoutputWriter {
+	w.b = append(w.b, b)
+	return w
+}
+
+func (w *pgoutputWriter) uint16(v uint16) *pgoutputWriter {
+	w.b = binary.BigEndian.AppendUint16(w.b, v)
+	return w
+}
+
+func (w *pgoutputWriter) uint32(v uint32) *pgoutputWriter {
+	w.b = binary.BigEndian.AppendUint32(w.b, v)
+	return w
+}
+
+func (w *pgoutputWriter) uint64(v uint64) *pgoutputWriter {
+	w.b = binary.BigEndian.AppendUint64(w.b, v)
+	return w
+}
+
+func (w *pgoutputWriter) string(s string) *pgoutputWriter {
+	w.b = append(append(w.b, s...), 0)
+	return w
+}
+
+// tuple writes a tuple where nil values are null, and values of "\x00" are
+// unchanged.
+func (w *pgoutputWriter) tuple(values ...*string) *pgoutputWriter {
+	w.uint16(uint16(len(values)))
+	for _, v := range values {
+		switch {
+		case v == nil:
+			w.byte('n')
+		case *v == "\x00":
+			w.byte('u')
+		default:
+			w.byte('t').uint32(uint32(len(*v)))
+			w.b = append(w.b, *v...)
+		}
+	}
+	return w
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestPgoutputParseTransaction(t *testing.T) {
+	msg, err := parsePgoutputMessage((&pgoutputWriter{}).byte('B').uint64(0x30).uint64(1000000).uint32(7).b)
+	require.NoError(t, err)
+	assert.Equal(t, pgoutputBegin{
+		finalLSN:   0x30,
+		commitTime: pgTimeFromMicros(1000000),
+		xid:        7,
+	}, msg)
+
+	msg, err = parsePgoutputMessage((&pgoutputWriter{}).byte('C').byte(0).uint64(0x30).uint64(0x38).uint64(1000000).b)
+	require.NoError(t, err)
+	assert.Equal(t, pgoutputCommit{
+		commitLSN:  0x30,
+		endLSN:     0x38,
+		commitTime: pgTimeFromMicros(1000000),
+	}, msg)
+
+	msg, err = parsePgoutputMessage((&pgoutputWriter{}).byte('O').uint64(0x30).string("foo").b)
+	require.NoError(t, err)
+	assert.Nil(t, msg)
+
+	_, err = parsePgoutputMessage((&pgoutputWriter{}).byte('B').uint64(0x30).b)
+	require.EqualError(t, err, "failed to parse pgoutput message 'B': message truncated, expected 8 more bytes but got 0")
+
+	_, err = parsePgoutputMessage([]byte("Z"))
+	require.EqualError(t, err, "unsupported pgoutput message type 'Z'")
+}
+
+func testRelationMessage() []byte {
+	return (&pgoutputWriter{}).byte('R').uint32(5).string("public").string("users").byte('d').uint16(4).
+		byte(1).string("id").uint32(pgTypeInt4).uint32(0xFFFFFFFF).
+		byte(0).string("name").uint32(25).uint32(0xFFFFFFFF).
+		byte(0).string("meta").uint32(pgTypeJSONB).uint32(0xFFFFFFFF).
+		byte(0).string("active").uint32(pgTypeBool).uint32(0xFFFFFFFF).b
+}
+
+func TestPgoutputParseChanges(t *testing.T) {
+	msg, err := parsePgoutputMessage(testRelationMessage())
+	require.NoError(t, err)
+	rel, ok := msg.(pgoutputRelation)
+	require.True(t, ok)
+	assert.Equal(t, pgoutputRelation{
+		id:        5,
+		namespace: "public",
+		name:      "users",
+		columns: []pgoutputColumn{
+			{name: "id", typeOID: pgTypeInt4, isKey: true},
+			{name: "name", typeOID: 25},
+			{name: "meta", typeOID: pgTypeJSONB},
+			{name: "active", typeOID: pgTypeBool},
+		},
+	}, rel)
+
+	tests := []struct {
+		name      string
+		input     []byte
+		operation string
+		before    map[string]any
+		after     map[string]any
+	}{
+		{
+			name:      "insert",
+			input:     (&pgoutputWriter{}).byte('I').uint32(5).byte('N').tuple(strPtr("1"), strPtr("foo"), strPtr(`{"a":[1,2]}`), strPtr("t")).b,
+			operation: "insert",
+			after: map[string]any{
+				"id":     int64(1),
+				"name":   "foo",
+				"meta":   map[string]any{"a": []any{1.0, 2.0}},
+				"active": true,
+			},
+		},
+		{
+			name:      "update with key",
+			input:     (&pgoutputWriter{}).byte('U').uint32(5).byte('K').tuple(strPtr("1"), nil, nil, nil).byte('N').tuple(strPtr("2"), strPtr("bar"), strPtr("\x00"), nil).b,
+			operation: "update",
+			before: map[string]any{
+				"id":     int64(1),
+				"name":   nil,
+				"meta":   nil,
+				"active": nil,
+			},
+			after: map[string]any{
+				"id":     int64(2),
+				"name":   "bar",
+				"active": nil,
+			},
+		},
+		{
+			name:      "update without key",
+			input:     (&pgoutputWriter{}).byte('U').uint32(5).byte('N').tuple(strPtr("2"), strPtr("bar"), nil, strPtr("f")).b,
+			operation: "update",
+			after: map[string]any{
+				"id":     int64(2),
+				"name":   "bar",
+				"meta":   nil,
+				"active": false,
+			},
+		},
+		{
+			name:      "delete with old tuple",
+			input:     (&pgoutputWriter{}).byte('D').uint32(5).byte('O').tuple(strPtr("2"), strPtr("bar"), nil, strPtr("f")).b,
+			operation: "delete",
+			before: map[string]any{
+				"id":     int64(2),
+				"name":   "bar",
+				"meta":   nil,
+				"active": false,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			msg, err := parsePgoutputMessage(test.input)
+			require.NoError(t, err)
+
+			c, ok := msg.(pgoutputChange)
+			require.True(t, ok)
+			assert.Equal(t, test.operation, c.operation)
+			assert.Equal(t, uint32(5), c.relationID)
+
+			before, err := rel.tupleToMap(c.before)
+			require.NoError(t, err)
+			assert.Equal(t, test.before, before)
+
+			after, err := rel.tupleToMap(c.after)
+			require.NoError(t, err)
+			assert.Equal(t, test.after, after)
+		})
+	}
+}
+
+func TestPgoutputParseTruncate(t *testing.T) {
+	msg, err := parsePgoutputMessage((&pgoutputWriter{}).byte('T').uint32(2).byte(0).uint32(5).uint32(6).b)
+	require.NoError(t, err)
+	assert.Equal(t, pgoutputTruncate{relationIDs: []uint32{5, 6}}, msg)
+}
+
+func TestPgoutputParseErrors(t *testing.T) {
+	_, err := parsePgoutputMessage((&pgoutputWriter{}).byte('I').uint32(5).byte('X').b)
+	require.EqualError(t, err, "unexpected insert tuple type 'X'")
+
+	_, err = parsePgoutputMessage((&pgoutputWriter{}).byte('I').uint32(5).byte('N').uint16(1).byte('b').b)
+	require.EqualError(t, err, "failed to parse pgoutput message 'I': unsupported tuple column kind 'b'")
+
+	_, err = parsePgoutputMessage((&pgoutputWriter{}).byte('R').uint32(5).string("public").b)
+	require.EqualError(t, err, "failed to parse pgoutput message 'R': message truncated, expected a null terminated string")
+
+	rel := &pgoutputRelation{namespace: "public", name: "users"}
+	_, err = rel.tupleToMap([]pgoutputTupleColumn{{kind: pgoutputTupleNull}})
+	require.EqualError(t, err, "tuple has 1 columns but relation public.users has 0")
+}
+
+func TestDecodePgText(t *testing.T) {
+	tests := []struct {
+		typeOID  uint32
+		input    string
+		expected any
+	}{
+		{typeOID: pgTypeBool, input: "t", expected: true},
+		{typeOID: pgTypeBool, input: "f", expected: false},
+		{typeOID: pgTypeInt8, input: "-9000000000", expected: int64(-9000000000)},
+		{typeOID: pgTypeFloat8, input: "1.5", expected: 1.5},
+		{typeOID: pgTypeFloat4, input: "NaN", expected: "NaN"},
+		{typeOID: pgTypeNumeric, input: "12345678901234567890.123", expected: json.Number("12345678901234567890.123")},
+		{typeOID: pgTypeNumeric, input: "NaN", expected: "NaN"},
+		{typeOID: pgTypeJSON, input: `{"a":"b"}`, expected: map[string]any{"a": "b"}},
+		{typeOID: 1184, input: "2024-01-01 00:00:00+00", expected: "2024-01-01 00:00:00+00"},
+	}
+
+	for _, test := range tests {
+		v, err := decodePgText(test.typeOID, []byte(test.input))
+		require.NoError(t, err, test.input)
+		assert.Equal(t, test.expected, v, test.input)
+	}
+
+	_, err := decodePgText(pgTypeInt4, []byte("nope"))
+	require.Error(t, err)
+}
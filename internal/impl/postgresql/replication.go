@@ -0,0 +1,141 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+)
+
+// pgLSN is a PostgreSQL log sequence number, which is a position within the
+// write-ahead log.
+type pgLSN uint64
+
+func (l pgLSN) String() string {
+	return fmt.Sprintf("%X/%X", uint32(l>>32), uint32(l))
+}
+
+// parsePgLSN parses an LSN in the form XXX/XXX.
+func parsePgLSN(s string) (pgLSN, error) {
+	var hi, lo uint32
+	if _, err := fmt.Sscanf(s, "%X/%X", &hi, &lo); err != nil {
+		return 0, fmt.Errorf("failed to parse LSN '%v': %w", s, err)
+	}
+	return pgLSN(uint64(hi)<<32 | uint64(lo)), nil
+}
+
+// Timestamps within the replication protocol are microseconds since the
+// PostgreSQL epoch.
+var pgEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func pgTimeFromMicros(micros int64) time.Time {
+	return pgEpoch.Add(time.Duration(micros) * time.Microsecond)
+}
+
+func pgTimeToMicros(t time.Time) int64 {
+	return t.Sub(pgEpoch).Microseconds()
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+//------------------------------------------------------------------------------
+
+// createReplicationSlot creates a logical replication slot using the pgoutput
+// plugin, returning the LSN from which the slot is consistent and, if
+// requested, the name of an exported snapshot of the database at that LSN.
+func createReplicationSlot(ctx context.Context, conn *pgconn.PgConn, slotName string, exportSnapshot bool) (pgLSN, string, error) {
+	snapshotAction := "NOEXPORT_SNAPSHOT"
+	if exportSnapshot {
+		snapshotAction = "EXPORT_SNAPSHOT"
+	}
+
+	results, err := conn.Exec(ctx, fmt.Sprintf("CREATE_REPLICATION_SLOT %v LOGICAL pgoutput %v", slotName, snapshotAction)).ReadAll()
+	if err != nil {
+		return 0, "", err
+	}
+	if len(results) != 1 || len(results[0].Rows) != 1 || len(results[0].Rows[0]) < 3 {
+		return 0, "", errors.New("unexpected response to slot creation")
+	}
+
+	row := results[0].Rows[0]
+	lsn, err := parsePgLSN(string(row[1]))
+	if err != nil {
+		return 0, "", err
+	}
+	return lsn, string(row[2]), nil
+}
+
+// startReplication starts streaming changes from a logical replication slot
+// for a publication, after which the connection can only be used for streaming.
+func startReplication(ctx context.Context, conn *pgconn.PgConn, slotName string, startLSN pgLSN, publication string) error {
+	query := fmt.Sprintf("START_REPLICATION SLOT %v LOGICAL %v (proto_version '1', publication_names %v)", slotName, startLSN, quoteLiteral(publication))
+	if err := conn.SendBytes(ctx, (&pgproto3.Query{String: query}).Encode(nil)); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := conn.ReceiveMessage(ctx)
+		if err != nil {
+			return err
+		}
+		switch m := msg.(type) {
+		case *pgproto3.CopyBothResponse:
+			return nil
+		case *pgproto3.ErrorResponse:
+			return pgconn.ErrorResponseToPgError(m)
+		case *pgproto3.NoticeResponse:
+		default:
+			return fmt.Errorf("unexpected message type %T when starting replication", msg)
+		}
+	}
+}
+
+// sendStandbyStatusUpdate informs the server of the position up to which
+// changes have been received and flushed, which allows the server to discard
+// write-ahead log that is no longer needed by the slot.
+func sendStandbyStatusUpdate(ctx context.Context, conn *pgconn.PgConn, written, flushed pgLSN) error {
+	data := make([]byte, 34)
+	data[0] = 'r'
+	binary.BigEndian.PutUint64(data[1:], uint64(written))
+	binary.BigEndian.PutUint64(data[9:], uint64(flushed))
+	binary.BigEndian.PutUint64(data[17:], uint64(flushed))
+	binary.BigEndian.PutUint64(data[25:], uint64(pgTimeToMicros(time.Now())))
+	return conn.SendBytes(ctx, (&pgproto3.CopyData{Data: data}).Encode(nil))
+}
+
+type primaryKeepalive struct {
+	serverWALEnd   pgLSN
+	replyRequested bool
+}
+
+func parsePrimaryKeepalive(data []byte) (primaryKeepalive, error) {
+	if len(data) != 17 {
+		return primaryKeepalive{}, fmt.Errorf("expected keepalive message of length 17, got %v", len(data))
+	}
+	return primaryKeepalive{
+		serverWALEnd:   pgLSN(binary.BigEndian.Uint64(data)),
+		replyRequested: data[16] != 0,
+	}, nil
+}
+
+type xLogData struct {
+	walStart pgLSN
+	walData  []byte
+}
+
+func parseXLogData(data []byte) (xLogData, error) {
+	if len(data) < 24 {
+		return xLogData{}, fmt.Errorf("expected XLogData message of at least length 24, got %v", len(data))
+	}
+	return xLogData{
+		walStart: pgLSN(binary.BigEndian.Uint64(data)),
+		walData:  data[24:],
+	}, nil
+}
@@ -0,0 +1,58 @@
+package postgresql
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLSN(t *testing.T) {
+	lsn, err := parsePgLSN("16/B374D848")
+	require.NoError(t, err)
+	assert.Equal(t, pgLSN(0x16B374D848), lsn)
+	assert.Equal(t, "16/B374D848", lsn.String())
+
+	assert.Equal(t, "0/0", pgLSN(0).String())
+
+	_, err = parsePgLSN("nope")
+	require.Error(t, err)
+}
+
+func TestPgTime(t *testing.T) {
+	ts := time.Date(2024, time.March, 4, 5, 6, 7, 8000, time.UTC)
+	assert.Equal(t, ts, pgTimeFromMicros(pgTimeToMicros(ts)))
+	assert.Equal(t, int64(0), pgTimeToMicros(pgEpoch))
+}
+
+func TestParseReplicationMessages(t *testing.T) {
+	keepalive := make([]byte, 17)
+	binary.BigEndian.PutUint64(keepalive, 0x10)
+	keepalive[16] = 1
+
+	k, err := parsePrimaryKeepalive(keepalive)
+	require.NoError(t, err)
+	assert.Equal(t, primaryKeepalive{serverWALEnd: 0x10, replyRequested: true}, k)
+
+	_, err = parsePrimaryKeepalive(keepalive[:16])
+	require.Error(t, err)
+
+	xld := make([]byte, 24, 27)
+	binary.BigEndian.PutUint64(xld, 0x20)
+	xld = append(xld, "foo"...)
+
+	x, err := parseXLogData(xld)
+	require.NoError(t, err)
+	assert.Equal(t, pgLSN(0x20), x.walStart)
+	assert.Equal(t, "foo", string(x.walData))
+
+	_, err = parseXLogData(xld[:10])
+	require.Error(t, err)
+}
+
+func TestQuoteLiteral(t *testing.T) {
+	assert.Equal(t, `'foo'`, quoteLiteral("foo"))
+	assert.Equal(t, `'it''s'`, quoteLiteral("it's"))
+}
@@ -18,7 +18,7 @@ func tumblingWindowBufferConfig() *service.ConfigSpec {
 		Beta().
 		Version("3.53.0").
 		Categories("Windowing").
-		Summary("Chops a stream of messages into tumbling or sliding windows of fixed temporal size, following the system clock.").
+		Summary("Chops a stream of messages into tumbling or sliding windows of fixed temporal size, session windows separated by gaps of inactivity, or windows of a fixed number of messages, following the system clock.").
 		Description(`
 A window is a grouping of messages that fit within a discrete measure of time following the system clock. Messages are allocated to a window either by the processing time (the time at which they're ingested) or by the event time, and this is controlled via the `+"[`timestamp_mapping` field](#timestamp_mapping)"+`.
 
@@ -32,17 +32,41 @@ When a message is added to a window it has a metadata field `+"`window_end_times
 
 Sliding windows begin from an offset of the prior windows' beginning rather than its end, and therefore messages may belong to multiple windows. In order to produce sliding windows specify a `+"[`slide` duration](#slide)"+`.
 
+## Session Windows
+
+Session windows group together messages that arrive close together in time, and are closed once no further messages have arrived for a given gap of time. In order to produce session windows specify a `+"[`session.gap`](#sessiongap)"+` instead of a `+"`size`"+`.
+
+Sessions are tracked separately for each key provided by the `+"[`key_mapping`](#key_mapping)"+`, and each session is flushed independently once the system clock surpasses the timestamp of its last message plus the gap (plus any `+"`allowed_lateness`"+`). A message that arrives after the session it would have belonged to has been flushed starts a new session, unless that session would also have already expired, in which case the message is dropped.
+
+## Count Windows
+
+Count windows group together a fixed number of messages for each key provided by the `+"[`key_mapping`](#key_mapping)"+`. In order to produce count windows specify a `+"[`count.size`](#countsize)"+` instead of a `+"`size`"+`, and optionally a `+"[`count.slide`](#countslide)"+` in order to produce sliding count windows.
+
+Messages of each key are ordered by their timestamp, and a window is flushed once it is full and the system clock surpasses the timestamp of its newest message plus any `+"`allowed_lateness`"+`. Messages that arrive with a timestamp older than the newest message of the last window flushed for their key are dropped, until the `+"`allowed_lateness`"+` has passed since that flush without any further messages for the key, after which the key is forgotten. Windows that are only partially full are not flushed until they are filled.
+
+Messages flushed from session and count windows also have a metadata field `+"`window_key`"+` added to them containing the key of the window.
+
+## Event Time Watermarks
+
+By default windows are closed according to the system clock, which means that when historical data is consumed, for example when replaying a backlog, messages with event timestamps that are older than the current window are considered late and dropped. Specifying a `+"[`watermark`](#watermark)"+` instead closes windows according to a watermark that follows the timestamps of messages as they are observed, and therefore a backfill produces the same windows as live processing would have.
+
+The watermark is the newest timestamp observed minus the `+"[`watermark.max_out_of_orderness`](#watermarkmax_out_of_orderness)"+`, and a window is flushed once the watermark passes its end (plus any `+"`allowed_lateness`"+`). Fixed size windows are flushed in order when following a watermark, and messages are never dropped due to back pressure.
+
+Since the watermark only advances when messages are observed, the final windows of a stream would otherwise remain open until more data arrives. When a `+"[`watermark.idle_timeout`](#watermarkidle_timeout)"+` is specified and no messages have been observed for that duration, the watermark advances at the rate of the system clock. When the input ends all pending windows are considered complete and are flushed.
+
 ## Back Pressure
 
 If back pressure is applied to this buffer either due to output services being unavailable or resources being saturated, windows older than the current and last according to the system clock will be dropped in order to prevent unbounded resource usage. This means you should ensure that under the worst case scenario you have enough system memory to store two windows' worth of data at a given time (plus extra for redundancy and other services).
 
 If messages could potentially arrive with event timestamps in the future (according to the system clock) then you should also factor in these extra messages in memory usage estimates.
 
+Session and count windows are not dropped due to back pressure, and the state of a key is discarded once all of its windows have been flushed. However, count windows that are never filled are held in memory until the service is shut down, and therefore the number of distinct keys should be bounded.
+
 ## Delivery Guarantees
 
 This buffer honours the transaction model within Benthos in order to ensure that messages are not acknowledged until they are either intentionally dropped or successfully delivered to outputs. However, since messages belonging to an expired window are intentionally dropped there are circumstances where not all messages entering the system will be delivered.
 
-When this buffer is configured with a slide duration it is possible for messages to belong to multiple windows, and therefore be delivered multiple times. In this case the first time the message is delivered it will be acked (or nacked) and subsequent deliveries of the same message will be a "best attempt".
+When this buffer is configured with a slide duration, or with a count slide, it is possible for messages to belong to multiple windows, and therefore be delivered multiple times. In this case the first time the message is delivered it will be acked (or nacked) and subsequent deliveries of the same message will be a "best attempt".
 
 During graceful termination if the current window is partially populated with messages they will be nacked such that they are re-consumed the next time the service starts.
 `).
@@ -54,8 +78,14 @@ The timestamp value assigned to `+"`root`"+` must either be a numerical unix tim
 `).
 			Default("root = now()").
 			Example("root = this.created_at").Example(`root = meta("kafka_timestamp_unix").number()`)).
+		Field(service.NewBloblangField("key_mapping").
+			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) applied to each message during ingestion that provides a key, where session and count windows are tracked and flushed independently for each key. This field can only be used with session or count windows, and when it is omitted all messages share a single key.").
+			Optional().
+			Advanced().
+			Example("root = this.user_id").Example(`root = meta("kafka_key")`)).
 		Field(service.NewStringField("size").
-			Description("A duration string describing the size of each window. By default windows are aligned to the zeroth minute and zeroth hour on the UTC clock, meaning windows of 1 hour duration will match the turn of each hour in the day, this can be adjusted with the `offset` field.").
+			Description("A duration string describing the size of each window. By default windows are aligned to the zeroth minute and zeroth hour on the UTC clock, meaning windows of 1 hour duration will match the turn of each hour in the day, this can be adjusted with the `offset` field. This field is required unless session or count windows are configured.").
+			Default("").
 			Example("30s").Example("10m")).
 		Field(service.NewStringField("slide").
 			Description("An optional duration string describing by how much time the beginning of each window should be offset from the beginning of the previous, and therefore creates sliding windows instead of tumbling. When specified this duration must be smaller than the `size` of the window.").
@@ -69,6 +99,38 @@ The timestamp value assigned to `+"`root`"+` must either be a numerical unix tim
 			Description("An optional duration string describing the length of time to wait after a window has ended before flushing it, allowing late arrivals to be included. Since this windowing buffer uses the system clock an allowed lateness can improve the matching of messages when using event time.").
 			Default("").
 			Example("10s").Example("1m")).
+		Field(service.NewObjectField("session",
+			service.NewStringField("gap").
+				Description("A duration string describing the length of time after the last message of a session before the session is closed.").
+				Example("30s").Example("10m"),
+		).
+			Description("Produce session windows instead of fixed size windows.").
+			Optional().
+			Advanced()).
+		Field(service.NewObjectField("count",
+			service.NewIntField("size").
+				Description("The number of messages within each window."),
+			service.NewIntField("slide").
+				Description("An optional number of messages by which the beginning of each window should be offset from the beginning of the previous, and therefore creates sliding windows instead of tumbling. When zero the slide is equal to the size of the window.").
+				Default(0),
+		).
+			Description("Produce windows of a fixed number of messages instead of fixed size windows.").
+			Optional().
+			Advanced()).
+		Field(service.NewObjectField("watermark",
+			service.NewStringField("max_out_of_orderness").
+				Description("A duration string describing how far behind the newest observed timestamp the watermark should be, allowing messages that arrive out of order by up to this length of time to be allocated to their window.").
+				Default("").
+				Example("5s").Example("1m"),
+			service.NewStringField("idle_timeout").
+				Description("An optional duration string describing how long to wait without observing any messages before the watermark begins to advance at the rate of the system clock.").
+				Default("").
+				Example("30s").Example("5m"),
+		).
+			Description("Close windows according to a watermark derived from the timestamps of messages rather than the system clock.").
+			Optional().
+			Advanced()).
+		LintRule(`root = if this.size.or("") == "" && !this.exists("session") && !this.exists("count") { "field size is required unless session or count windows are configured" }`).
 		Example("Counting Passengers at Traffic", `Given a stream of messages relating to cars passing through various traffic lights of the form:
 
 `+"```json"+`
@@ -115,6 +177,28 @@ pipeline:
             "passengers": json("passengers").from_all().sum(),
           }
         } else { deleted() }
+`,
+		).
+		Example("User Sessions", `Given a stream of page view events containing a user ID, we can group the events of each user into sessions that end after ten minutes of inactivity, and reduce each session into a single summary message:`,
+			`
+buffer:
+  system_window:
+    timestamp_mapping: root = this.viewed_at
+    key_mapping: root = this.user_id
+    session:
+      gap: 10m
+    allowed_lateness: 30s
+
+pipeline:
+  processors:
+    - mapping: |
+        root = if batch_index() == 0 {
+          {
+            "user_id": this.user_id,
+            "session_end": meta("window_end_timestamp"),
+            "pages": json("page").from_all(),
+          }
+        } else { deleted() }
 `,
 		)
 }
@@ -138,6 +222,12 @@ func init() {
 	err := service.RegisterBatchBuffer(
 		"system_window", tumblingWindowBufferConfig(),
 		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchBuffer, error) {
+			if conf.Contains("session") || conf.Contains("count") {
+				return keyedWindowBufferFromConfig(conf, mgr)
+			}
+			if conf.Contains("key_mapping") {
+				return nil, errors.New("field key_mapping can only be used with session or count windows")
+			}
 			size, err := getDuration(conf, true, "size")
 			if err != nil {
 				return nil, err
@@ -170,9 +260,20 @@ func init() {
 			if err != nil {
 				return nil, err
 			}
-			return newSystemWindowBuffer(tsMapping, func() time.Time {
+			watermark, err := windowWatermarkFromConfig(conf)
+			if err != nil {
+				return nil, err
+			}
+			w, err := newSystemWindowBuffer(tsMapping, func() time.Time {
 				return time.Now().UTC()
 			}, size, slide, offset, allowedLateness, mgr.Logger())
+			if err != nil {
+				return nil, err
+			}
+			if watermark != nil {
+				w.useWatermark(watermark)
+			}
+			return w, nil
 		})
 	if err != nil {
 		panic(err)
@@ -194,6 +295,7 @@ type systemWindowBuffer struct {
 
 	tsMapping                            *bloblang.Executor
 	clock                                utcNowProvider
+	watermark                            *eventTimeWatermark
 	size, slide, offset, allowedLateness time.Duration
 
 	latestFlushedWindowEnd time.Time
@@ -231,13 +333,28 @@ func newSystemWindowBuffer(
 	return w, nil
 }
 
-func (w *systemWindowBuffer) nextSystemWindow() (prevStart, prevEnd, start, end time.Time) {
-	now := w.clock()
+// useWatermark replaces the system clock with an event time watermark, where
+// windows are flushed in order once the watermark passes their end.
+func (w *systemWindowBuffer) useWatermark(watermark *eventTimeWatermark) {
+	w.watermark = watermark
+	w.clock = watermark.now
+}
 
-	windowEpoch := w.size
+func (w *systemWindowBuffer) windowEpoch() time.Duration {
 	if w.slide > 0 {
-		windowEpoch = w.slide
+		return w.slide
 	}
+	return w.size
+}
+
+func (w *systemWindowBuffer) nextSystemWindow() (prevStart, prevEnd, start, end time.Time) {
+	return w.windowsAt(w.clock())
+}
+
+// windowsAt returns the oldest window that contains the provided time, along
+// with the window prior to it.
+func (w *systemWindowBuffer) windowsAt(now time.Time) (prevStart, prevEnd, start, end time.Time) {
+	windowEpoch := w.windowEpoch()
 
 	// The start is now, rounded by our window epoch to the UTC clock, and with
 	// our offset (plus one to avoid overlapping with the previous window)
@@ -245,7 +362,7 @@ func (w *systemWindowBuffer) nextSystemWindow() (prevStart, prevEnd, start, end
 	//
 	// If the result is after now then we rounded upwards, so we roll it back by
 	// the window epoch.
-	if start = w.clock().Round(windowEpoch).Add(1 + w.offset); start.After(now) {
+	if start = now.Round(windowEpoch).Add(1 + w.offset); start.After(now) {
 		start = start.Add(-windowEpoch)
 	}
 
@@ -266,9 +383,13 @@ func (w *systemWindowBuffer) nextSystemWindow() (prevStart, prevEnd, start, end
 }
 
 func (w *systemWindowBuffer) getTimestamp(i int, batch service.MessageBatch) (ts time.Time, err error) {
+	return windowTimestamp(w.logger, w.tsMapping, i, batch)
+}
+
+func windowTimestamp(logger *service.Logger, tsMapping *bloblang.Executor, i int, batch service.MessageBatch) (ts time.Time, err error) {
 	var tsValueMsg *service.Message
-	if tsValueMsg, err = batch.BloblangQuery(i, w.tsMapping); err != nil {
-		w.logger.Errorf("Timestamp mapping failed for message: %v", err)
+	if tsValueMsg, err = batch.BloblangQuery(i, tsMapping); err != nil {
+		logger.Errorf("Timestamp mapping failed for message: %v", err)
 		err = fmt.Errorf("timestamp mapping failed: %w", err)
 		return
 	}
@@ -281,13 +402,13 @@ func (w *systemWindowBuffer) getTimestamp(i int, batch service.MessageBatch) (ts
 		}
 	}
 	if err != nil {
-		w.logger.Errorf("Timestamp mapping failed for message: unable to parse result as structured value: %v", err)
+		logger.Errorf("Timestamp mapping failed for message: unable to parse result as structured value: %v", err)
 		err = fmt.Errorf("unable to parse result of timestamp mapping as structured value: %w", err)
 		return
 	}
 
 	if ts, err = value.IGetTimestamp(tsValue); err != nil {
-		w.logger.Errorf("Timestamp mapping failed for message: %v", err)
+		logger.Errorf("Timestamp mapping failed for message: %v", err)
 		err = fmt.Errorf("unable to parse result of timestamp mapping as timestamp: %w", err)
 	}
 	return
@@ -298,9 +419,11 @@ func (w *systemWindowBuffer) WriteBatch(ctx context.Context, msgBatch service.Me
 	defer w.pendingMut.Unlock()
 
 	// If our output is blocked and therefore we haven't flushed more than the
-	// last two windows we purge messages that wouldn't fit within them.
+	// last two windows we purge messages that wouldn't fit within them. When
+	// following a watermark windows are always flushed in order and therefore
+	// nothing is purged.
 	prevStart, _, _, _ := w.nextSystemWindow()
-	if w.latestFlushedWindowEnd.Before(prevStart) && w.oldestTS.Before(prevStart) {
+	if w.watermark == nil && w.latestFlushedWindowEnd.Before(prevStart) && w.oldestTS.Before(prevStart) {
 		newOldestTS := w.clock()
 		newPending := make([]*tsMessage, 0, len(w.pending))
 		for _, pending := range w.pending {
@@ -327,6 +450,9 @@ func (w *systemWindowBuffer) WriteBatch(ctx context.Context, msgBatch service.Me
 		if err != nil {
 			return err
 		}
+		if w.watermark != nil {
+			w.watermark.observe(ts)
+		}
 
 		// Don't add messages older than our current window start.
 		if !ts.After(w.latestFlushedWindowEnd) { //nolint: gocritic
@@ -400,7 +526,20 @@ func (w *systemWindowBuffer) flushWindow(ctx context.Context, start, end time.Ti
 
 var errWindowClosed = errors.New("message rejected as window did not complete")
 
+func (w *systemWindowBuffer) nackPending(ctx context.Context) {
+	w.pendingMut.Lock()
+	for _, pending := range w.pending {
+		_ = pending.ackFn(ctx, errWindowClosed)
+	}
+	w.pending = nil
+	w.pendingMut.Unlock()
+}
+
 func (w *systemWindowBuffer) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	if w.watermark != nil {
+		return w.readBatchWatermark(ctx)
+	}
+
 	prevStart, prevEnd, nextStart, nextEnd := w.nextSystemWindow()
 
 	// We haven't been read since the previous window ended, so create that one
@@ -430,12 +569,7 @@ func (w *systemWindowBuffer) ReadBatch(ctx context.Context) (service.MessageBatc
 			// Nack all pending messages so that we re-consume them on the next
 			// start up. TODO: Eventually allow users to customize this as they
 			// may wish to flush partial windows instead.
-			w.pendingMut.Lock()
-			for _, pending := range w.pending {
-				_ = pending.ackFn(ctx, errWindowClosed)
-			}
-			w.pending = nil
-			w.pendingMut.Unlock()
+			w.nackPending(ctx)
 			return nil, nil, service.ErrEndOfBuffer
 		}
 		if msgBatch, aFn, err := w.flushWindow(ctx, nextStart, nextEnd); len(msgBatch) > 0 || err != nil {
@@ -447,6 +581,51 @@ func (w *systemWindowBuffer) ReadBatch(ctx context.Context) (service.MessageBatc
 	}
 }
 
+// readBatchWatermark flushes the window of the oldest pending message once the
+// watermark has passed its end, and therefore windows containing messages are
+// never skipped regardless of how quickly the watermark advances.
+func (w *systemWindowBuffer) readBatchWatermark(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	for {
+		w.pendingMut.Lock()
+		var oldest time.Time
+		for _, pending := range w.pending {
+			if oldest.IsZero() || pending.ts.Before(oldest) {
+				oldest = pending.ts
+			}
+		}
+		w.pendingMut.Unlock()
+
+		var flushChan <-chan time.Time
+		if !oldest.IsZero() {
+			_, _, start, end := w.windowsAt(oldest)
+			for !end.After(w.latestFlushedWindowEnd) {
+				start, end = start.Add(w.windowEpoch()), end.Add(w.windowEpoch())
+			}
+			if flushAt := end.Add(w.allowedLateness); w.clock().Before(flushAt) {
+				flushChan = w.watermark.after(flushAt)
+			} else {
+				if msgBatch, aFn, err := w.flushWindow(ctx, start, end); len(msgBatch) > 0 || err != nil {
+					return msgBatch, aFn, err
+				}
+				continue
+			}
+		}
+
+		select {
+		case <-flushChan:
+		case <-w.watermark.advancedChan:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-w.endOfInputChan:
+			if oldest.IsZero() {
+				return nil, nil, service.ErrEndOfBuffer
+			}
+			// Once the input has ended all pending windows are complete.
+			w.watermark.endOfInput()
+		}
+	}
+}
+
 func (w *systemWindowBuffer) EndOfInput() {
 	w.closeEndOfInputOnce.Do(func() {
 		close(w.endOfInputChan)
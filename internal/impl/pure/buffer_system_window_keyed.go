@@ -0,0 +1,512 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/batch"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func keyedWindowBufferFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*keyedWindowBuffer, error) {
+	if conf.Contains("session") && conf.Contains("count") {
+		return nil, errors.New("session and count windows cannot be configured at the same time")
+	}
+	for _, f := range []string{"size", "slide", "offset"} {
+		if v, _ := conf.FieldString(f); v != "" {
+			return nil, fmt.Errorf("field %v cannot be used with session or count windows", f)
+		}
+	}
+
+	tsMapping, err := conf.FieldBloblang("timestamp_mapping")
+	if err != nil {
+		return nil, err
+	}
+	var keyMapping *bloblang.Executor
+	if conf.Contains("key_mapping") {
+		if keyMapping, err = conf.FieldBloblang("key_mapping"); err != nil {
+			return nil, err
+		}
+	}
+	allowedLateness, err := getDuration(conf, false, "allowed_lateness")
+	if err != nil {
+		return nil, err
+	}
+	watermark, err := windowWatermarkFromConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	var windows keyedWindows
+	if conf.Contains("session") {
+		gap, err := getDuration(conf.Namespace("session"), true, "gap")
+		if err != nil {
+			return nil, err
+		}
+		if gap <= 0 {
+			return nil, fmt.Errorf("invalid session gap '%v' must be greater than zero", gap)
+		}
+		windows = newSessionWindows(gap, allowedLateness)
+	} else {
+		countConf := conf.Namespace("count")
+		size, err := countConf.FieldInt("size")
+		if err != nil {
+			return nil, err
+		}
+		if size <= 0 {
+			return nil, fmt.Errorf("invalid count size '%v' must be greater than zero", size)
+		}
+		slide, err := countConf.FieldInt("slide")
+		if err != nil {
+			return nil, err
+		}
+		if slide < 0 || slide > size {
+			return nil, fmt.Errorf("invalid count slide '%v' must be between zero and the size '%v'", slide, size)
+		}
+		if slide == 0 {
+			slide = size
+		}
+		windows = newCountWindows(size, slide, allowedLateness)
+	}
+
+	w := newKeyedWindowBuffer(tsMapping, keyMapping, func() time.Time {
+		return time.Now().UTC()
+	}, windows, mgr.Logger())
+	if watermark != nil {
+		w.useWatermark(watermark)
+	}
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
+
+// keyedWindows describes a strategy for allocating messages to windows that
+// are tracked and flushed independently for each key. Implementations are not
+// safe for concurrent use.
+type keyedWindows interface {
+	// add a message to the windows of a key, returns false if the message is
+	// too late to be added to any window.
+	add(key string, msg *tsMessage, now time.Time) bool
+
+	// flush removes and returns the messages of the window with the earliest
+	// flush deadline that has passed, along with the end timestamp of that
+	// window. If no windows are ready to be flushed then the earliest deadline
+	// of pending windows is returned instead, which is zero when there are no
+	// pending windows.
+	flush(now time.Time) (w flushedWindow, ok bool, nextDeadline time.Time)
+
+	// drain removes and returns all pending messages.
+	drain() []*tsMessage
+}
+
+type flushedWindow struct {
+	key      string
+	end      time.Time
+	messages []*tsMessage
+}
+
+//------------------------------------------------------------------------------
+
+type windowSession struct {
+	start, end time.Time
+	pending    []*tsMessage
+}
+
+type sessionWindows struct {
+	gap, allowedLateness time.Duration
+	keys                 map[string][]*windowSession
+}
+
+func newSessionWindows(gap, allowedLateness time.Duration) *sessionWindows {
+	return &sessionWindows{
+		gap:             gap,
+		allowedLateness: allowedLateness,
+		keys:            map[string][]*windowSession{},
+	}
+}
+
+func (s *sessionWindows) deadline(sess *windowSession) time.Time {
+	return sess.end.Add(s.gap + s.allowedLateness)
+}
+
+func (s *sessionWindows) add(key string, msg *tsMessage, now time.Time) bool {
+	merged := &windowSession{start: msg.ts, end: msg.ts, pending: []*tsMessage{msg}}
+
+	// Any existing sessions that are within the gap of the new message are
+	// merged with it, which may bridge two sessions together.
+	var mergedExisting bool
+	sessions := make([]*windowSession, 0, len(s.keys[key])+1)
+	for _, sess := range s.keys[key] {
+		if msg.ts.Before(sess.start.Add(-s.gap)) || msg.ts.After(sess.end.Add(s.gap)) {
+			sessions = append(sessions, sess)
+			continue
+		}
+		mergedExisting = true
+		if sess.start.Before(merged.start) {
+			merged.start = sess.start
+		}
+		if sess.end.After(merged.end) {
+			merged.end = sess.end
+		}
+		merged.pending = append(sess.pending, merged.pending...)
+	}
+
+	if !mergedExisting && now.After(s.deadline(merged)) {
+		return false
+	}
+
+	sessions = append(sessions, merged)
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].start.Before(sessions[j].start)
+	})
+	s.keys[key] = sessions
+	return true
+}
+
+func (s *sessionWindows) flush(now time.Time) (w flushedWindow, ok bool, nextDeadline time.Time) {
+	var flushKey string
+	var flushIndex int
+	var flushDeadline time.Time
+
+	for key, sessions := range s.keys {
+		for i, sess := range sessions {
+			deadline := s.deadline(sess)
+			if now.Before(deadline) {
+				if nextDeadline.IsZero() || deadline.Before(nextDeadline) {
+					nextDeadline = deadline
+				}
+				continue
+			}
+			if !ok || deadline.Before(flushDeadline) {
+				ok, flushKey, flushIndex, flushDeadline = true, key, i, deadline
+			}
+		}
+	}
+	if !ok {
+		return
+	}
+
+	sessions := s.keys[flushKey]
+	sess := sessions[flushIndex]
+	if sessions = append(sessions[:flushIndex], sessions[flushIndex+1:]...); len(sessions) == 0 {
+		delete(s.keys, flushKey)
+	} else {
+		s.keys[flushKey] = sessions
+	}
+
+	sort.SliceStable(sess.pending, func(i, j int) bool {
+		return sess.pending[i].ts.Before(sess.pending[j].ts)
+	})
+	w = flushedWindow{
+		key:      flushKey,
+		end:      sess.end.Add(s.gap),
+		messages: sess.pending,
+	}
+	return
+}
+
+func (s *sessionWindows) drain() (pending []*tsMessage) {
+	for _, sessions := range s.keys {
+		for _, sess := range sessions {
+			pending = append(pending, sess.pending...)
+		}
+	}
+	s.keys = map[string][]*windowSession{}
+	return
+}
+
+//------------------------------------------------------------------------------
+
+type countWindowKey struct {
+	// Pending messages ordered by timestamp, which may include messages that
+	// were already flushed as part of a prior sliding window.
+	pending []*tsMessage
+
+	// The timestamp of the newest message of the last flushed window, and the
+	// time at which it was flushed. A key without pending messages is kept as a
+	// tombstone until the allowed lateness has passed since the flush so that
+	// late messages continue to be dropped.
+	flushedTS time.Time
+	flushedAt time.Time
+}
+
+type countWindows struct {
+	size, slide     int
+	allowedLateness time.Duration
+	keys            map[string]*countWindowKey
+}
+
+func newCountWindows(size, slide int, allowedLateness time.Duration) *countWindows {
+	return &countWindows{
+		size:            size,
+		slide:           slide,
+		allowedLateness: allowedLateness,
+		keys:            map[string]*countWindowKey{},
+	}
+}
+
+func (c *countWindows) add(key string, msg *tsMessage, now time.Time) bool {
+	k, exists := c.keys[key]
+	if !exists {
+		k = &countWindowKey{}
+		c.keys[key] = k
+	}
+	if msg.ts.Before(k.flushedTS) {
+		return false
+	}
+
+	i := sort.Search(len(k.pending), func(i int) bool {
+		return k.pending[i].ts.After(msg.ts)
+	})
+	k.pending = append(k.pending, nil)
+	copy(k.pending[i+1:], k.pending[i:])
+	k.pending[i] = msg
+	return true
+}
+
+func (c *countWindows) flush(now time.Time) (w flushedWindow, ok bool, nextDeadline time.Time) {
+	var flushKey string
+	var flushDeadline time.Time
+
+	for key, k := range c.keys {
+		if len(k.pending) == 0 {
+			if !now.Before(k.flushedAt.Add(c.allowedLateness)) {
+				delete(c.keys, key)
+			}
+			continue
+		}
+		if len(k.pending) < c.size {
+			continue
+		}
+		deadline := k.pending[c.size-1].ts.Add(c.allowedLateness)
+		if now.Before(deadline) {
+			if nextDeadline.IsZero() || deadline.Before(nextDeadline) {
+				nextDeadline = deadline
+			}
+			continue
+		}
+		if !ok || deadline.Before(flushDeadline) {
+			ok, flushKey, flushDeadline = true, key, deadline
+		}
+	}
+	if !ok {
+		return
+	}
+
+	k := c.keys[flushKey]
+	w = flushedWindow{
+		key:      flushKey,
+		end:      k.pending[c.size-1].ts,
+		messages: append([]*tsMessage(nil), k.pending[:c.size]...),
+	}
+	k.flushedTS, k.flushedAt = w.end, now
+	k.pending = k.pending[c.slide:]
+	return
+}
+
+func (c *countWindows) drain() (pending []*tsMessage) {
+	for _, k := range c.keys {
+		pending = append(pending, k.pending...)
+	}
+	c.keys = map[string]*countWindowKey{}
+	return
+}
+
+//------------------------------------------------------------------------------
+
+type keyedWindowBuffer struct {
+	logger *service.Logger
+
+	tsMapping  *bloblang.Executor
+	keyMapping *bloblang.Executor
+	clock      utcNowProvider
+	watermark  *eventTimeWatermark
+
+	windows    keyedWindows
+	windowsMut sync.Mutex
+
+	// Signals the reader that new messages have been added, which may result
+	// in an earlier flush deadline.
+	addedChan chan struct{}
+
+	endOfInputChan      chan struct{}
+	closeEndOfInputOnce sync.Once
+}
+
+func newKeyedWindowBuffer(
+	tsMapping, keyMapping *bloblang.Executor,
+	clock utcNowProvider,
+	windows keyedWindows,
+	logger *service.Logger,
+) *keyedWindowBuffer {
+	return &keyedWindowBuffer{
+		logger:         logger,
+		tsMapping:      tsMapping,
+		keyMapping:     keyMapping,
+		clock:          clock,
+		windows:        windows,
+		addedChan:      make(chan struct{}, 1),
+		endOfInputChan: make(chan struct{}),
+	}
+}
+
+// useWatermark replaces the system clock with an event time watermark.
+func (w *keyedWindowBuffer) useWatermark(watermark *eventTimeWatermark) {
+	w.watermark = watermark
+	w.clock = watermark.now
+}
+
+func (w *keyedWindowBuffer) getKey(i int, batch service.MessageBatch) (string, error) {
+	if w.keyMapping == nil {
+		return "", nil
+	}
+	keyMsg, err := batch.BloblangQuery(i, w.keyMapping)
+	if err != nil {
+		w.logger.Errorf("Key mapping failed for message: %v", err)
+		return "", fmt.Errorf("key mapping failed: %w", err)
+	}
+	if keyMsg == nil {
+		return "", nil
+	}
+	keyBytes, err := keyMsg.AsBytes()
+	if err != nil {
+		w.logger.Errorf("Key mapping failed for message: %v", err)
+		return "", fmt.Errorf("key mapping failed: %w", err)
+	}
+	return string(keyBytes), nil
+}
+
+func (w *keyedWindowBuffer) WriteBatch(ctx context.Context, msgBatch service.MessageBatch, aFn service.AckFunc) error {
+	type keyedMessage struct {
+		key string
+		ts  time.Time
+	}
+
+	// Mappings are executed before obtaining the lock so that a failure
+	// rejects the entire batch without modifying any windows.
+	keyed := make([]keyedMessage, len(msgBatch))
+	for i := range msgBatch {
+		ts, err := windowTimestamp(w.logger, w.tsMapping, i, msgBatch)
+		if err != nil {
+			return err
+		}
+		key, err := w.getKey(i, msgBatch)
+		if err != nil {
+			return err
+		}
+		keyed[i] = keyedMessage{key: key, ts: ts}
+	}
+
+	if w.watermark != nil {
+		for _, k := range keyed {
+			w.watermark.observe(k.ts)
+		}
+	}
+
+	w.windowsMut.Lock()
+	defer w.windowsMut.Unlock()
+
+	messageAdded := false
+	aggregatedAck := batch.NewCombinedAcker(batch.AckFunc(aFn))
+
+	now := w.clock()
+	for i, msg := range msgBatch {
+		tsMsg := &tsMessage{ts: keyed[i].ts, m: msg}
+		if !w.windows.add(keyed[i].key, tsMsg, now) {
+			continue
+		}
+		tsMsg.ackFn = service.AckFunc(aggregatedAck.Derive())
+		messageAdded = true
+	}
+
+	if !messageAdded {
+		// If none of the messages have fit into a window we reject them by
+		// acknowledging the batch.
+		_ = aFn(ctx, nil)
+		return nil
+	}
+
+	select {
+	case w.addedChan <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (w *keyedWindowBuffer) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	for {
+		w.windowsMut.Lock()
+		flushed, ok, nextDeadline := w.windows.flush(w.clock())
+		w.windowsMut.Unlock()
+
+		if ok {
+			flushBatch := make(service.MessageBatch, 0, len(flushed.messages))
+			flushAcks := make([]service.AckFunc, 0, len(flushed.messages))
+			for _, pending := range flushed.messages {
+				tmpMsg := pending.m.Copy()
+				tmpMsg.MetaSet("window_end_timestamp", flushed.end.Format(time.RFC3339Nano))
+				tmpMsg.MetaSet("window_key", flushed.key)
+				flushBatch = append(flushBatch, tmpMsg)
+				flushAcks = append(flushAcks, pending.ackFn)
+			}
+			return flushBatch, func(ctx context.Context, err error) error {
+				for _, aFn := range flushAcks {
+					_ = aFn(ctx, err)
+				}
+				return nil
+			}, nil
+		}
+
+		// A nil channel blocks forever, and therefore when there are no
+		// pending windows we wait for messages to be added.
+		var deadlineChan <-chan time.Time
+		var watermarkChan <-chan struct{}
+		if w.watermark != nil {
+			watermarkChan = w.watermark.advancedChan
+			if !nextDeadline.IsZero() {
+				deadlineChan = w.watermark.after(nextDeadline)
+			}
+		} else if !nextDeadline.IsZero() {
+			deadlineChan = time.After(nextDeadline.Sub(w.clock()))
+		}
+
+		select {
+		case <-deadlineChan:
+		case <-watermarkChan:
+		case <-w.addedChan:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-w.endOfInputChan:
+			if w.watermark != nil && !w.clock().Equal(watermarkEndOfInput) {
+				// Once the input has ended all pending windows are complete,
+				// and are flushed before the remainder is rejected.
+				w.watermark.endOfInput()
+				continue
+			}
+
+			// Nack all pending messages so that we re-consume them on the next
+			// start up.
+			w.windowsMut.Lock()
+			for _, pending := range w.windows.drain() {
+				_ = pending.ackFn(ctx, errWindowClosed)
+			}
+			w.windowsMut.Unlock()
+			return nil, nil, service.ErrEndOfBuffer
+		}
+	}
+}
+
+func (w *keyedWindowBuffer) EndOfInput() {
+	w.closeEndOfInputOnce.Do(func() {
+		close(w.endOfInputChan)
+	})
+}
+
+func (w *keyedWindowBuffer) Close(ctx context.Context) error {
+	return nil
+}
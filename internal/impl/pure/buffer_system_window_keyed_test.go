@@ -0,0 +1,290 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testKeyedWindowBuffer(t testing.TB, windows keyedWindows, clock utcNowProvider) *keyedWindowBuffer {
+	t.Helper()
+
+	tsMapping, err := bloblang.Parse(`root = this.ts`)
+	require.NoError(t, err)
+
+	keyMapping, err := bloblang.Parse(`root = this.key`)
+	require.NoError(t, err)
+
+	return newKeyedWindowBuffer(tsMapping, keyMapping, clock, windows, nil)
+}
+
+func assertKeyedWindow(t testing.TB, w *keyedWindowBuffer, key, end string, contents ...string) service.AckFunc {
+	t.Helper()
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second)
+	defer done()
+
+	resBatch, aFn, err := w.ReadBatch(tCtx)
+	require.NoError(t, err)
+	require.Len(t, resBatch, len(contents))
+
+	for i, c := range contents {
+		msgBytes, err := resBatch[i].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, c, string(msgBytes))
+
+		v, _ := resBatch[i].MetaGet("window_key")
+		assert.Equal(t, key, v)
+
+		v, _ = resBatch[i].MetaGet("window_end_timestamp")
+		assert.Equal(t, end, v)
+	}
+	return aFn
+}
+
+func assertNoKeyedWindow(t testing.TB, w *keyedWindowBuffer) {
+	t.Helper()
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer done()
+
+	_, _, err := w.ReadBatch(tCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSessionWindows(t *testing.T) {
+	currentTS := time.Unix(10, 0).UTC()
+	w := testKeyedWindowBuffer(t, newSessionWindows(time.Second, 0), func() time.Time {
+		return currentTS
+	})
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"key":"a","ts":10}`)),
+		service.NewMessage([]byte(`{"key":"b","ts":10.25}`)),
+		service.NewMessage([]byte(`{"key":"a","ts":10.5}`)),
+		service.NewMessage([]byte(`{"key":"b","ts":12}`)),
+		service.NewMessage([]byte(`{"key":"a","ts":11.5}`)),
+	}, noopAck))
+
+	assertNoKeyedWindow(t, w)
+
+	// The first session of b closes first.
+	currentTS = time.Unix(11, 300_000_000).UTC()
+	assertKeyedWindow(t, w, "b", "1970-01-01T00:00:11.25Z", `{"key":"b","ts":10.25}`)
+	assertNoKeyedWindow(t, w)
+
+	currentTS = time.Unix(12, 500_000_000).UTC()
+	assertKeyedWindow(t, w, "a", "1970-01-01T00:00:12.5Z",
+		`{"key":"a","ts":10}`,
+		`{"key":"a","ts":10.5}`,
+		`{"key":"a","ts":11.5}`,
+	)
+	assertNoKeyedWindow(t, w)
+
+	// A late message that bridges the gap is merged into the pending session
+	// of b, whereas a late message of a is too old for a new session.
+	var ackCalled bool
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"key":"a","ts":11}`)),
+	}, func(ctx context.Context, err error) error {
+		ackCalled = true
+		return err
+	}))
+	assert.True(t, ackCalled)
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"key":"b","ts":11.25}`)),
+		service.NewMessage([]byte(`{"key":"b","ts":12.5}`)),
+	}, noopAck))
+
+	currentTS = time.Unix(13, 500_000_000).UTC()
+	assertKeyedWindow(t, w, "b", "1970-01-01T00:00:13.5Z",
+		`{"key":"b","ts":11.25}`,
+		`{"key":"b","ts":12}`,
+		`{"key":"b","ts":12.5}`,
+	)
+	assert.Empty(t, w.windows.(*sessionWindows).keys)
+}
+
+func TestSessionWindowsMerge(t *testing.T) {
+	currentTS := time.Unix(10, 0).UTC()
+	w := testKeyedWindowBuffer(t, newSessionWindows(time.Second, time.Second), func() time.Time {
+		return currentTS
+	})
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"key":"a","ts":10}`)),
+		service.NewMessage([]byte(`{"key":"a","ts":12}`)),
+	}, noopAck))
+	require.Len(t, w.windows.(*sessionWindows).keys["a"], 2)
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"key":"a","ts":11}`)),
+	}, noopAck))
+	require.Len(t, w.windows.(*sessionWindows).keys["a"], 1)
+
+	// Allowed lateness is respected.
+	currentTS = time.Unix(13, 500_000_000).UTC()
+	assertNoKeyedWindow(t, w)
+
+	currentTS = time.Unix(14, 0).UTC()
+	assertKeyedWindow(t, w, "a", "1970-01-01T00:00:13Z",
+		`{"key":"a","ts":10}`,
+		`{"key":"a","ts":11}`,
+		`{"key":"a","ts":12}`,
+	)
+}
+
+func TestCountWindows(t *testing.T) {
+	currentTS := time.Unix(20, 0).UTC()
+	w := testKeyedWindowBuffer(t, newCountWindows(2, 2, 0), func() time.Time {
+		return currentTS
+	})
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"key":"a","ts":11}`)),
+		service.NewMessage([]byte(`{"key":"b","ts":12}`)),
+		service.NewMessage([]byte(`{"key":"a","ts":10}`)),
+		service.NewMessage([]byte(`{"key":"a","ts":13}`)),
+	}, noopAck))
+
+	assertKeyedWindow(t, w, "a", "1970-01-01T00:00:11Z",
+		`{"key":"a","ts":10}`,
+		`{"key":"a","ts":11}`,
+	)
+	assertNoKeyedWindow(t, w)
+
+	// Messages older than the last flushed window are dropped.
+	var ackCalled bool
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"key":"a","ts":9}`)),
+	}, func(ctx context.Context, err error) error {
+		ackCalled = true
+		return err
+	}))
+	assert.True(t, ackCalled)
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"key":"b","ts":14}`)),
+	}, noopAck))
+
+	assertKeyedWindow(t, w, "b", "1970-01-01T00:00:14Z",
+		`{"key":"b","ts":12}`,
+		`{"key":"b","ts":14}`,
+	)
+	assertNoKeyedWindow(t, w)
+
+	assert.Len(t, w.windows.(*countWindows).keys, 1)
+}
+
+func TestCountWindowsSliding(t *testing.T) {
+	currentTS := time.Unix(10, 500_000_000).UTC()
+	w := testKeyedWindowBuffer(t, newCountWindows(3, 1, time.Second), func() time.Time {
+		return currentTS
+	})
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"key":"a","ts":9}`)),
+		service.NewMessage([]byte(`{"key":"a","ts":9.25}`)),
+		service.NewMessage([]byte(`{"key":"a","ts":9.5}`)),
+		service.NewMessage([]byte(`{"key":"a","ts":9.75}`)),
+	}, noopAck))
+
+	assertKeyedWindow(t, w, "a", "1970-01-01T00:00:09.5Z",
+		`{"key":"a","ts":9}`,
+		`{"key":"a","ts":9.25}`,
+		`{"key":"a","ts":9.5}`,
+	)
+
+	// Allowed lateness is respected.
+	assertNoKeyedWindow(t, w)
+
+	currentTS = time.Unix(10, 750_000_000).UTC()
+	assertKeyedWindow(t, w, "a", "1970-01-01T00:00:09.75Z",
+		`{"key":"a","ts":9.25}`,
+		`{"key":"a","ts":9.5}`,
+		`{"key":"a","ts":9.75}`,
+	)
+	assertNoKeyedWindow(t, w)
+}
+
+func TestCountWindowsLateAfterFlush(t *testing.T) {
+	currentTS := time.Unix(20, 0).UTC()
+	w := testKeyedWindowBuffer(t, newCountWindows(2, 2, 5*time.Second), func() time.Time {
+		return currentTS
+	})
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"key":"a","ts":10}`)),
+		service.NewMessage([]byte(`{"key":"a","ts":11}`)),
+	}, noopAck))
+
+	assertKeyedWindow(t, w, "a", "1970-01-01T00:00:11Z",
+		`{"key":"a","ts":10}`,
+		`{"key":"a","ts":11}`,
+	)
+	assertNoKeyedWindow(t, w)
+
+	// The key has no pending messages but a late message is still dropped.
+	var ackCalled bool
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"key":"a","ts":9}`)),
+	}, func(ctx context.Context, err error) error {
+		ackCalled = true
+		return err
+	}))
+	assert.True(t, ackCalled)
+
+	currentTS = time.Unix(24, 0).UTC()
+	assertNoKeyedWindow(t, w)
+	assert.Len(t, w.windows.(*countWindows).keys, 1)
+
+	// Once the allowed lateness has passed since the flush the key is
+	// forgotten.
+	currentTS = time.Unix(25, 0).UTC()
+	assertNoKeyedWindow(t, w)
+	assert.Empty(t, w.windows.(*countWindows).keys)
+}
+
+func TestKeyedWindowWaitsForWrites(t *testing.T) {
+	w := testKeyedWindowBuffer(t, newCountWindows(1, 1, 0), func() time.Time {
+		return time.Unix(10, 0).UTC()
+	})
+
+	go func() {
+		<-time.After(time.Millisecond * 50)
+		assert.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+			service.NewMessage([]byte(`{"key":"a","ts":9}`)),
+		}, noopAck))
+	}()
+
+	assertKeyedWindow(t, w, "a", "1970-01-01T00:00:09Z", `{"key":"a","ts":9}`)
+}
+
+func TestKeyedWindowEndOfInput(t *testing.T) {
+	w := testKeyedWindowBuffer(t, newSessionWindows(time.Second, 0), func() time.Time {
+		return time.Unix(10, 0).UTC()
+	})
+
+	var ackErr error
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"key":"a","ts":10}`)),
+		service.NewMessage([]byte(`{"key":"b","ts":10}`)),
+	}, func(ctx context.Context, err error) error {
+		ackErr = err
+		return nil
+	}))
+
+	w.EndOfInput()
+
+	_, _, err := w.ReadBatch(context.Background())
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+	assert.True(t, errors.Is(ackErr, errWindowClosed))
+}
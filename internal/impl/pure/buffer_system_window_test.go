@@ -90,6 +90,85 @@ system_window:
 `,
 			buildErrContains: "invalid allowed_lateness",
 		},
+		{
+			config: `
+system_window:
+  key_mapping: root = this.id
+  session:
+    gap: 10s
+  allowed_lateness: 2m
+`,
+		},
+		{
+			config: `
+system_window:
+  count:
+    size: 10
+    slide: 5
+`,
+		},
+		{
+			config: `
+system_window:
+  size: 60m
+  key_mapping: root = this.id
+`,
+			buildErrContains: "field key_mapping can only be used",
+		},
+		{
+			config: `
+system_window:
+  size: 60m
+  session:
+    gap: 10s
+`,
+			buildErrContains: "field size cannot be used",
+		},
+		{
+			config: `
+system_window:
+  session:
+    gap: 10s
+  count:
+    size: 10
+`,
+			buildErrContains: "cannot be configured at the same time",
+		},
+		{
+			config: `
+system_window:
+  session:
+    gap: 0s
+`,
+			buildErrContains: "invalid session gap",
+		},
+		{
+			config: `
+system_window:
+  size: 60m
+  watermark:
+    max_out_of_orderness: 10s
+    idle_timeout: 1m
+`,
+		},
+		{
+			config: `
+system_window:
+  size: 60m
+  watermark:
+    idle_timeout: nope
+`,
+			buildErrContains: "failed to parse field 'idle_timeout'",
+		},
+		{
+			config: `
+system_window:
+  count:
+    size: 10
+    slide: 20
+`,
+			buildErrContains: "invalid count slide",
+		},
 	}
 
 	for i, test := range tests {
@@ -0,0 +1,147 @@
+package pure
+
+import (
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func windowWatermarkFromConfig(conf *service.ParsedConfig) (*eventTimeWatermark, error) {
+	if !conf.Contains("watermark") {
+		return nil, nil
+	}
+	wmConf := conf.Namespace("watermark")
+	maxOutOfOrderness, err := getDuration(wmConf, false, "max_out_of_orderness")
+	if err != nil {
+		return nil, err
+	}
+	idleTimeout, err := getDuration(wmConf, false, "idle_timeout")
+	if err != nil {
+		return nil, err
+	}
+	return newEventTimeWatermark(maxOutOfOrderness, idleTimeout, func() time.Time {
+		return time.Now().UTC()
+	}), nil
+}
+
+// watermarkEndOfInput is the watermark reported once the input has ended, and
+// is later than any window could end.
+var watermarkEndOfInput = time.Unix(1<<62, 0).UTC()
+
+// eventTimeWatermark tracks the progress of event time as observed from the
+// timestamps of messages, and is used in place of the system clock in order to
+// decide when windows are complete.
+type eventTimeWatermark struct {
+	maxOutOfOrderness time.Duration
+	idleTimeout       time.Duration
+	systemClock       utcNowProvider
+
+	mut          sync.Mutex
+	maxTS        time.Time
+	lastObserved time.Time
+	ended        bool
+
+	// The watermark never regresses, which could otherwise happen when a
+	// message is observed after the watermark advanced due to being idle.
+	floor time.Time
+
+	// Signals that the watermark may have advanced.
+	advancedChan chan struct{}
+}
+
+func newEventTimeWatermark(maxOutOfOrderness, idleTimeout time.Duration, systemClock utcNowProvider) *eventTimeWatermark {
+	return &eventTimeWatermark{
+		maxOutOfOrderness: maxOutOfOrderness,
+		idleTimeout:       idleTimeout,
+		systemClock:       systemClock,
+		advancedChan:      make(chan struct{}, 1),
+	}
+}
+
+// observe the timestamps of a batch of messages.
+func (e *eventTimeWatermark) observe(timestamps ...time.Time) {
+	e.mut.Lock()
+	e.floor = e.nowLocked()
+	for _, ts := range timestamps {
+		if ts.After(e.maxTS) {
+			e.maxTS = ts
+		}
+	}
+	e.lastObserved = e.systemClock()
+	e.mut.Unlock()
+
+	e.signal()
+}
+
+// endOfInput advances the watermark beyond all windows.
+func (e *eventTimeWatermark) endOfInput() {
+	e.mut.Lock()
+	e.ended = true
+	e.mut.Unlock()
+
+	e.signal()
+}
+
+func (e *eventTimeWatermark) signal() {
+	select {
+	case e.advancedChan <- struct{}{}:
+	default:
+	}
+}
+
+// now returns the current watermark, which is the newest observed timestamp
+// minus the maximum out of orderness. When no messages have been observed for
+// the idle timeout the watermark advances at the rate of the system clock.
+func (e *eventTimeWatermark) now() time.Time {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	return e.nowLocked()
+}
+
+func (e *eventTimeWatermark) nowLocked() time.Time {
+	if e.ended {
+		return watermarkEndOfInput
+	}
+	if e.maxTS.IsZero() {
+		return time.Time{}
+	}
+	wm := e.maxTS.Add(-e.maxOutOfOrderness)
+	if e.idleTimeout > 0 {
+		if idle := e.systemClock().Sub(e.lastObserved) - e.idleTimeout; idle > 0 {
+			wm = wm.Add(idle)
+		}
+	}
+	if wm.Before(e.floor) {
+		return e.floor
+	}
+	return wm
+}
+
+// after returns a channel that emits once the watermark is expected to reach
+// the provided time without any further messages being observed, or a nil
+// channel if only new messages can advance the watermark.
+func (e *eventTimeWatermark) after(t time.Time) <-chan time.Time {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	wm := e.nowLocked()
+	if !wm.Before(t) {
+		return newClosedTimerChan()
+	}
+	if e.idleTimeout <= 0 || e.maxTS.IsZero() || e.ended {
+		return nil
+	}
+
+	waitFor := t.Sub(wm)
+	if untilIdle := e.lastObserved.Add(e.idleTimeout).Sub(e.systemClock()); untilIdle > 0 {
+		waitFor += untilIdle
+	}
+	return time.After(waitFor)
+}
+
+func newClosedTimerChan() <-chan time.Time {
+	c := make(chan time.Time)
+	close(c)
+	return c
+}
@@ -0,0 +1,184 @@
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestEventTimeWatermark(t *testing.T) {
+	systemTS := time.Unix(1000, 0).UTC()
+	wm := newEventTimeWatermark(time.Second, time.Second*10, func() time.Time {
+		return systemTS
+	})
+
+	assert.True(t, wm.now().IsZero())
+	assert.Nil(t, wm.after(time.Unix(5, 0)))
+
+	wm.observe(time.Unix(10, 0), time.Unix(12, 0), time.Unix(11, 0))
+	assert.Equal(t, time.Unix(11, 0).UTC(), wm.now().UTC())
+
+	select {
+	case <-wm.after(time.Unix(11, 0)):
+	default:
+		t.Error("expected closed channel")
+	}
+
+	// Not idle yet.
+	systemTS = systemTS.Add(time.Second * 5)
+	assert.Equal(t, time.Unix(11, 0).UTC(), wm.now().UTC())
+
+	// Idle for three seconds beyond the timeout.
+	systemTS = systemTS.Add(time.Second * 8)
+	assert.Equal(t, time.Unix(14, 0).UTC(), wm.now().UTC())
+
+	// Observing a message resets the idle period, but the watermark never
+	// regresses.
+	wm.observe(time.Unix(13, 0))
+	assert.Equal(t, time.Unix(14, 0).UTC(), wm.now().UTC())
+
+	wm.observe(time.Unix(16, 0))
+	assert.Equal(t, time.Unix(15, 0).UTC(), wm.now().UTC())
+
+	wm.endOfInput()
+	assert.Equal(t, watermarkEndOfInput, wm.now())
+}
+
+func TestSystemWindowWatermarkBackfill(t *testing.T) {
+	mapping, err := bloblang.Parse(`root = this.ts`)
+	require.NoError(t, err)
+
+	// The system clock is far ahead of all event timestamps, which would
+	// otherwise result in every message being dropped.
+	systemTS := time.Unix(100000, 0).UTC()
+	w, err := newSystemWindowBuffer(mapping, func() time.Time {
+		return systemTS
+	}, time.Second, 0, 0, 0, nil)
+	require.NoError(t, err)
+	w.useWatermark(newEventTimeWatermark(time.Millisecond*500, 0, func() time.Time {
+		return systemTS
+	}))
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"1","ts":10.1}`)),
+		service.NewMessage([]byte(`{"id":"2","ts":10.8}`)),
+		service.NewMessage([]byte(`{"id":"3","ts":12.2}`)),
+		service.NewMessage([]byte(`{"id":"4","ts":11.9}`)),
+	}, noopAck))
+	require.Len(t, w.pending, 4)
+
+	readWindow := func(end string, ids ...string) {
+		t.Helper()
+
+		tCtx, done := context.WithTimeout(context.Background(), time.Second)
+		defer done()
+
+		resBatch, _, err := w.ReadBatch(tCtx)
+		require.NoError(t, err)
+		require.Len(t, resBatch, len(ids))
+		for i, id := range ids {
+			structured, err := resBatch[i].AsStructured()
+			require.NoError(t, err)
+			assert.Equal(t, id, structured.(map[string]any)["id"])
+
+			v, _ := resBatch[i].MetaGet("window_end_timestamp")
+			assert.Equal(t, end, v)
+		}
+	}
+
+	readWindow("1970-01-01T00:00:11Z", "1", "2")
+
+	// The watermark is at 11.7 and so the next window remains open.
+	tCtx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	_, _, err = w.ReadBatch(tCtx)
+	done()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Messages from flushed windows are dropped.
+	var acked bool
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"5","ts":10.5}`)),
+	}, func(ctx context.Context, err error) error {
+		acked = true
+		return err
+	}))
+	assert.True(t, acked)
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"6","ts":12.1}`)),
+		service.NewMessage([]byte(`{"id":"7","ts":15.7}`)),
+	}, noopAck))
+
+	// The watermark jumps beyond several windows, but they're still flushed
+	// in order.
+	readWindow("1970-01-01T00:00:12Z", "4")
+	readWindow("1970-01-01T00:00:13Z", "3", "6")
+
+	// The final window is flushed once the input ends.
+	w.EndOfInput()
+	readWindow("1970-01-01T00:00:16Z", "7")
+
+	_, _, err = w.ReadBatch(context.Background())
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+}
+
+func TestSystemWindowWatermarkIdle(t *testing.T) {
+	mapping, err := bloblang.Parse(`root = this.ts`)
+	require.NoError(t, err)
+
+	w, err := newSystemWindowBuffer(mapping, func() time.Time {
+		return time.Now().UTC()
+	}, time.Second, 0, 0, 0, nil)
+	require.NoError(t, err)
+	w.useWatermark(newEventTimeWatermark(0, time.Millisecond*50, func() time.Time {
+		return time.Now().UTC()
+	}))
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"1","ts":10.9}`)),
+	}, noopAck))
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	resBatch, _, err := w.ReadBatch(tCtx)
+	require.NoError(t, err)
+	require.Len(t, resBatch, 1)
+}
+
+func TestKeyedWindowWatermark(t *testing.T) {
+	systemTS := time.Unix(100000, 0).UTC()
+	w := testKeyedWindowBuffer(t, newSessionWindows(time.Second, 0), func() time.Time {
+		return systemTS
+	})
+	w.useWatermark(newEventTimeWatermark(0, 0, func() time.Time {
+		return systemTS
+	}))
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"key":"a","ts":10}`)),
+		service.NewMessage([]byte(`{"key":"b","ts":10.5}`)),
+		service.NewMessage([]byte(`{"key":"a","ts":10.75}`)),
+	}, noopAck))
+	assertNoKeyedWindow(t, w)
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"key":"c","ts":11.5}`)),
+	}, noopAck))
+	assertKeyedWindow(t, w, "b", "1970-01-01T00:00:11.5Z", `{"key":"b","ts":10.5}`)
+	assertNoKeyedWindow(t, w)
+
+	// Remaining sessions are flushed once the input ends.
+	w.EndOfInput()
+	assertKeyedWindow(t, w, "a", "1970-01-01T00:00:11.75Z", `{"key":"a","ts":10}`, `{"key":"a","ts":10.75}`)
+	assertKeyedWindow(t, w, "c", "1970-01-01T00:00:12.5Z", `{"key":"c","ts":11.5}`)
+
+	_, _, err := w.ReadBatch(context.Background())
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+}
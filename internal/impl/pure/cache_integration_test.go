@@ -26,6 +26,8 @@ cache_resources:
 		integration.CacheTestDoubleAdd(),
 		integration.CacheTestDelete(),
 		integration.CacheTestGetAndSet(50),
+		integration.CacheTestIncr(50),
+		integration.CacheTestGetMulti(50),
 	)
 	suite.Run(t, template)
 }
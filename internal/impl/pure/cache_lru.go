@@ -3,6 +3,7 @@ package pure
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -221,6 +222,7 @@ type lruCache interface {
 	Get(key string) (value []byte, ok bool)
 	Add(key string, value []byte)
 	Remove(key string)
+	Keys() []string
 }
 
 type lruv2SimpleCacheAdaptor[K comparable, V any] struct {
@@ -293,6 +295,22 @@ func (ca *lruCacheAdapter) Delete(_ context.Context, key string) error {
 	return nil
 }
 
+func (ca *lruCacheAdapter) Scan(_ context.Context, prefix string, fn func(key string, value []byte) error) error {
+	for _, k := range ca.inner.Keys() {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		value, ok := ca.inner.Peek(k)
+		if !ok {
+			continue
+		}
+		if err := fn(k, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (ca *lruCacheAdapter) Close(_ context.Context) error {
 	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -140,6 +141,14 @@ func BenchmarkLRUParallel(b *testing.B) {
 	})
 }
 
+func TestLRUCacheScan(t *testing.T) {
+	for _, algo := range []string{"standard", "arc", "two_queues"} {
+		c, err := lruMemCache(100, algo, nil, nil, nil, false)
+		require.NoError(t, err, algo)
+		testServiceScannableCache(t, c)
+	}
+}
+
 func testServiceCache(t *testing.T, c service.Cache) {
 	t.Helper()
 
@@ -205,3 +214,80 @@ func testServiceCache(t *testing.T, c service.Cache) {
 		t.Errorf("wrong error returned on c.Get(ctx, %q): %v != %v", key, act, expErr)
 	}
 }
+
+func testServiceCounterCache(t *testing.T, c service.Cache) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	cc, ok := c.(service.CounterCache)
+	require.True(t, ok)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, err := cc.Incr(ctx, "counter", 1, nil)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	n, err := cc.Incr(ctx, "counter", -500, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), n)
+
+	require.NoError(t, c.Set(ctx, "not_a_counter", []byte("nope"), nil))
+	_, err = cc.Incr(ctx, "not_a_counter", 1, nil)
+	require.Error(t, err)
+
+	mc, ok := c.(service.MultiGetCache)
+	require.True(t, ok)
+
+	values, err := mc.GetMulti(ctx, "counter", "not_a_counter", "does_not_exist")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"counter":       []byte("500"),
+		"not_a_counter": []byte("nope"),
+	}, values)
+}
+
+func testServiceScannableCache(t *testing.T, c service.Cache) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	sc, ok := c.(service.ScannableCache)
+	require.True(t, ok)
+
+	require.NoError(t, c.Set(ctx, "scan:a", []byte("1"), nil))
+	require.NoError(t, c.Set(ctx, "scan:b", []byte("2"), nil))
+	require.NoError(t, c.Set(ctx, "other", []byte("3"), nil))
+
+	values := map[string]string{}
+	require.NoError(t, sc.Scan(ctx, "scan:", func(key string, value []byte) error {
+		values[key] = string(value)
+		return nil
+	}))
+	assert.Equal(t, map[string]string{
+		"scan:a": "1",
+		"scan:b": "2",
+	}, values)
+
+	// The cache can be modified during a scan.
+	errStop := errors.New("stop")
+	require.Equal(t, errStop, sc.Scan(ctx, "", func(key string, value []byte) error {
+		require.NoError(t, c.Delete(ctx, key))
+		return errStop
+	}))
+
+	var remaining int
+	require.NoError(t, sc.Scan(ctx, "", func(key string, value []byte) error {
+		remaining++
+		return nil
+	}))
+	assert.Equal(t, 2, remaining)
+}
@@ -2,11 +2,14 @@ package pure
 
 import (
 	"context"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/OneOfOne/xxhash"
 
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
@@ -196,6 +199,20 @@ func (m *memoryCache) Get(_ context.Context, key string) ([]byte, error) {
 	return k.value, nil
 }
 
+func (m *memoryCache) GetMulti(_ context.Context, keys ...string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		shard := m.getShard(key)
+		shard.RLock()
+		k, exists := shard.items[key]
+		shard.RUnlock()
+		if exists && !shard.isExpired(k) {
+			values[key] = k.value
+		}
+	}
+	return values, nil
+}
+
 func (m *memoryCache) Set(_ context.Context, key string, value []byte, ttl *time.Duration) error {
 	var expires time.Time
 	if ttl != nil {
@@ -230,6 +247,31 @@ func (m *memoryCache) Add(_ context.Context, key string, value []byte, ttl *time
 	return nil
 }
 
+func (m *memoryCache) Incr(_ context.Context, key string, delta int64, ttl *time.Duration) (int64, error) {
+	var expires time.Time
+	if ttl != nil {
+		expires = time.Now().Add(*ttl)
+	} else {
+		expires = time.Now().Add(m.defaultTTL)
+	}
+	shard := m.getShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	var current int64
+	if k, exists := shard.items[key]; exists && !shard.isExpired(k) {
+		var err error
+		if current, err = cache.ParseCounter(key, k.value); err != nil {
+			return 0, err
+		}
+	}
+	current += delta
+
+	shard.compaction()
+	shard.items[key] = item{value: strconv.AppendInt(nil, current, 10), expires: expires}
+	return current, nil
+}
+
 func (m *memoryCache) Delete(_ context.Context, key string) error {
 	shard := m.getShard(key)
 	shard.Lock()
@@ -239,6 +281,27 @@ func (m *memoryCache) Delete(_ context.Context, key string) error {
 	return nil
 }
 
+func (m *memoryCache) Scan(_ context.Context, prefix string, fn func(key string, value []byte) error) error {
+	for _, shard := range m.shards {
+		// Copy matching items so that fn is free to access the cache.
+		shard.RLock()
+		items := map[string][]byte{}
+		for k, v := range shard.items {
+			if strings.HasPrefix(k, prefix) && !shard.isExpired(v) {
+				items[k] = v.value
+			}
+		}
+		shard.RUnlock()
+
+		for k, v := range items {
+			if err := fn(k, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (m *memoryCache) Close(context.Context) error {
 	return nil
 }
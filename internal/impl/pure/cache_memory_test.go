@@ -253,3 +253,32 @@ shards: 10
 		assert.Equal(b, value, res)
 	}
 }
+
+func TestMemoryCacheCounter(t *testing.T) {
+	c := newMemCache(time.Minute, 0, 1, nil)
+	testServiceCounterCache(t, c)
+
+	c = newMemCache(time.Minute, 0, 10, nil)
+	testServiceCounterCache(t, c)
+}
+
+func TestMemoryCacheCounterExpired(t *testing.T) {
+	c := newMemCache(time.Minute, time.Hour, 1, nil)
+	ctx := context.Background()
+
+	ttl := time.Millisecond * 10
+	n, err := c.Incr(ctx, "foo", 5, &ttl)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+
+	<-time.After(time.Millisecond * 50)
+
+	n, err = c.Incr(ctx, "foo", 5, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+}
+
+func TestMemoryCacheScan(t *testing.T) {
+	testServiceScannableCache(t, newMemCache(time.Minute, 0, 1, nil))
+	testServiceScannableCache(t, newMemCache(time.Minute, 0, 10, nil))
+}
@@ -3,6 +3,7 @@ package pure
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/benthosdev/benthos/v4/public/service"
@@ -12,6 +13,8 @@ func multilevelCacheConfig() *service.ConfigSpec {
 	spec := service.NewConfigSpec().
 		Stable().
 		Summary(`Combines multiple caches as levels, performing read-through and write-through operations across them.`).
+		Description(`
+Counters incremented with the `+"`incr`"+` operator are only incremented atomically within the last level, which is treated as the source of truth, and the result is then written to all other levels.`).
 		Field(service.NewStringListField("")).
 		Example(
 			"Hot and cold cache",
@@ -126,6 +129,41 @@ func (l *multilevelCache) Get(ctx context.Context, key string) ([]byte, error) {
 	return nil, service.ErrKeyNotFound
 }
 
+func (l *multilevelCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	for i, name := range l.caches {
+		var levelValues map[string][]byte
+		var err error
+		if cerr := l.mgr.AccessCache(ctx, name, func(c service.Cache) {
+			mc, ok := c.(service.MultiGetCache)
+			if !ok {
+				err = fmt.Errorf("cache '%v' does not support get_multi", name)
+				return
+			}
+			levelValues, err = mc.GetMulti(ctx, keys...)
+		}); cerr != nil {
+			return nil, fmt.Errorf("unable to access cache '%v': %v", name, cerr)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var remaining []string
+		for _, k := range keys {
+			if v, exists := levelValues[k]; exists {
+				l.setUpToLevelPassive(ctx, i, k, v)
+				values[k] = v
+			} else {
+				remaining = append(remaining, k)
+			}
+		}
+		if keys = remaining; len(keys) == 0 {
+			break
+		}
+	}
+	return values, nil
+}
+
 func (l *multilevelCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	for _, name := range l.caches {
 		var err error
@@ -181,6 +219,41 @@ func (l *multilevelCache) Add(ctx context.Context, key string, value []byte, ttl
 	return nil
 }
 
+// Incr increments the counter held by the last cache level, which is treated as
+// the source of truth, and then sets the result on all other levels.
+func (l *multilevelCache) Incr(ctx context.Context, key string, delta int64, ttl *time.Duration) (int64, error) {
+	last := l.caches[len(l.caches)-1]
+
+	var n int64
+	var err error
+	if cerr := l.mgr.AccessCache(ctx, last, func(c service.Cache) {
+		cc, ok := c.(service.CounterCache)
+		if !ok {
+			err = fmt.Errorf("cache '%v' does not support incr", last)
+			return
+		}
+		n, err = cc.Incr(ctx, key, delta, ttl)
+	}); cerr != nil {
+		return 0, fmt.Errorf("unable to access cache '%v': %v", last, cerr)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	value := strconv.AppendInt(nil, n, 10)
+	for i := len(l.caches) - 2; i >= 0; i-- {
+		if cerr := l.mgr.AccessCache(ctx, l.caches[i], func(c service.Cache) {
+			err = c.Set(ctx, key, value, ttl)
+		}); cerr != nil {
+			return 0, fmt.Errorf("unable to access cache '%v': %v", l.caches[i], cerr)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
 func (l *multilevelCache) Delete(ctx context.Context, key string) error {
 	for _, name := range l.caches {
 		var err error
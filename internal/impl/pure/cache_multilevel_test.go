@@ -361,3 +361,67 @@ func TestMultilevelCacheAddMoreCaches(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, val, []byte("test value 4"))
 }
+
+func TestMultilevelCacheIncr(t *testing.T) {
+	memCache1 := newMemCache(time.Minute, 0, 1, nil)
+	memCache2 := newMemCache(time.Minute, 0, 1, nil)
+	p := &mockCacheProv{
+		caches: map[string]service.Cache{
+			"foo": memCache1,
+			"bar": memCache2,
+		},
+	}
+
+	c, err := newMultilevelCache([]string{"foo", "bar"}, p, nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// The last level is the source of truth for counters.
+	require.NoError(t, memCache1.Set(ctx, "foo", []byte("100"), nil))
+	require.NoError(t, memCache2.Set(ctx, "foo", []byte("10"), nil))
+
+	n, err := c.(service.CounterCache).Incr(ctx, "foo", 5, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), n)
+
+	val, err := memCache1.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("15"), val)
+
+	val, err = memCache2.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("15"), val)
+}
+
+func TestMultilevelCacheGetMulti(t *testing.T) {
+	memCache1 := newMemCache(time.Minute, 0, 1, nil)
+	memCache2 := newMemCache(time.Minute, 0, 1, nil)
+	p := &mockCacheProv{
+		caches: map[string]service.Cache{
+			"foo": memCache1,
+			"bar": memCache2,
+		},
+	}
+
+	c, err := newMultilevelCache([]string{"foo", "bar"}, p, nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	require.NoError(t, memCache1.Set(ctx, "a", []byte("a1"), nil))
+	require.NoError(t, memCache2.Set(ctx, "a", []byte("a2"), nil))
+	require.NoError(t, memCache2.Set(ctx, "b", []byte("b2"), nil))
+
+	values, err := c.(service.MultiGetCache).GetMulti(ctx, "a", "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"a": []byte("a1"),
+		"b": []byte("b2"),
+	}, values)
+
+	// Keys found in lower levels are set on the levels above.
+	val, err := memCache1.Get(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("b2"), val)
+}
@@ -3,11 +3,14 @@ package pure
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/hashicorp/golang-lru/v2/expirable"
 
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
@@ -179,6 +182,16 @@ func (ca *ttlruCacheAdapter) Get(_ context.Context, key string) ([]byte, error)
 	return value, nil
 }
 
+func (ca *ttlruCacheAdapter) GetMulti(_ context.Context, keys ...string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if value, ok := ca.inner.Get(key); ok {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
 func (ca *ttlruCacheAdapter) Set(_ context.Context, key string, value []byte, _ *time.Duration) error {
 	_ = ca.inner.Add(key, value)
 
@@ -210,12 +223,45 @@ func (ca *ttlruCacheAdapter) Add(_ context.Context, key string, value []byte, _
 	return err
 }
 
+func (ca *ttlruCacheAdapter) Incr(_ context.Context, key string, delta int64, _ *time.Duration) (int64, error) {
+	ca.Lock()
+	defer ca.Unlock()
+
+	var current int64
+	if value, ok := ca.inner.Peek(key); ok {
+		var err error
+		if current, err = cache.ParseCounter(key, value); err != nil {
+			return 0, err
+		}
+	}
+	current += delta
+
+	_ = ca.inner.Add(key, strconv.AppendInt(nil, current, 10))
+	return current, nil
+}
+
 func (ca *ttlruCacheAdapter) Delete(_ context.Context, key string) error {
 	_ = ca.inner.Remove(key)
 
 	return nil
 }
 
+func (ca *ttlruCacheAdapter) Scan(_ context.Context, prefix string, fn func(key string, value []byte) error) error {
+	for _, k := range ca.inner.Keys() {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		value, ok := ca.inner.Peek(k)
+		if !ok {
+			continue
+		}
+		if err := fn(k, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (ca *ttlruCacheAdapter) Close(_ context.Context) error {
 	return nil
 }
@@ -42,6 +42,30 @@ without_reset: true # must be ignored
 	testServiceCache(t, c)
 }
 
+func TestTTLRUCacheCounter(t *testing.T) {
+	t.Parallel()
+
+	defConf, err := ttlruCacheConfig().ParseYAML(`optimistic: true`, nil)
+	require.NoError(t, err)
+
+	c, err := ttlruMemCacheFromConfig(defConf, service.MockResources().Logger())
+	require.NoError(t, err)
+
+	testServiceCounterCache(t, c)
+}
+
+func TestTTLRUCacheScan(t *testing.T) {
+	t.Parallel()
+
+	defConf, err := ttlruCacheConfig().ParseYAML(``, nil)
+	require.NoError(t, err)
+
+	c, err := ttlruMemCacheFromConfig(defConf, service.MockResources().Logger())
+	require.NoError(t, err)
+
+	testServiceScannableCache(t, c)
+}
+
 func TestTTLRUCacheInitValues(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,263 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	ciFieldResource = "resource"
+	ciFieldPrefix   = "prefix"
+)
+
+func cacheInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.26.0").
+		Summary("Reads all key/value pairs from a [cache resource](/docs/components/caches/about), emitting one message per key.").
+		Description(`
+The keys of the cache are scanned once, optionally filtered by a prefix, and the value of each key is then read and emitted as the contents of a message. Once all keys have been consumed the input shuts down, which makes it possible to snapshot, migrate or replay the contents of a cache.
+
+Only caches that support scanning keys can be used with this input, which currently includes `+"`memory`, `lru`, `ttlru`, `file`, `redis` and `aws_dynamodb`"+`. Keys that are added whilst a scan is in progress may or may not be emitted depending on the cache, keys that are modified are emitted with their latest value and keys that are removed before they are read are not emitted. If a scan fails it is restarted from the beginning, and therefore keys may be emitted more than once.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- cache_key
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Example(
+			"Migrating a Cache",
+			"In this example all keys of a Redis cache beginning with `session:` are copied into a DynamoDB table, keeping their original keys.",
+			`
+input:
+  cache:
+    resource: redis_sessions
+    prefix: 'session:'
+
+output:
+  cache:
+    target: dynamo_sessions
+    key: ${! @cache_key }
+
+cache_resources:
+  - label: redis_sessions
+    redis:
+      url: redis://localhost:6379
+  - label: dynamo_sessions
+    aws_dynamodb:
+      table: sessions
+      hash_key: id
+      data_key: data
+`,
+		).
+		Field(service.NewStringField(ciFieldResource).
+			Description("The [`cache` resource](/docs/components/caches/about) to read keys from.")).
+		Field(service.NewStringField(ciFieldPrefix).
+			Description("An optional prefix that keys must begin with in order to be read.").
+			Example("session:").
+			Default(""))
+}
+
+func init() {
+	err := service.RegisterInput(
+		"cache", cacheInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newCacheInputFromConfig(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type cacheScanItem struct {
+	key   string
+	value []byte
+}
+
+type cacheInput struct {
+	resource string
+	prefix   string
+
+	mgr *service.Resources
+	log *service.Logger
+
+	mut     sync.Mutex
+	items   chan cacheScanItem
+	scanErr error
+	done    bool
+	cancel  context.CancelFunc
+}
+
+func newCacheInputFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*cacheInput, error) {
+	resource, err := conf.FieldString(ciFieldResource)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := conf.FieldString(ciFieldPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(resource) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", resource)
+	}
+	var scannable bool
+	if err := mgr.AccessCache(context.Background(), resource, func(c service.Cache) {
+		_, scannable = c.(service.ScannableCache)
+	}); err != nil {
+		return nil, err
+	}
+	if !scannable {
+		return nil, fmt.Errorf("cache resource '%v' does not support scanning keys", resource)
+	}
+	return &cacheInput{
+		resource: resource,
+		prefix:   prefix,
+		mgr:      mgr,
+		log:      mgr.Logger(),
+	}, nil
+}
+
+func (c *cacheInput) Connect(ctx context.Context) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.done {
+		return service.ErrEndOfInput
+	}
+	if c.items != nil {
+		return nil
+	}
+
+	scanCtx, cancel := context.WithCancel(context.Background())
+	items := make(chan cacheScanItem)
+
+	c.items = items
+	c.scanErr = nil
+	c.cancel = cancel
+
+	go func() {
+		defer close(items)
+
+		scanErr := c.scan(scanCtx, items)
+
+		c.mut.Lock()
+		c.scanErr = scanErr
+		c.mut.Unlock()
+	}()
+	return nil
+}
+
+// scan collects the keys of the cache and then sends the value of each key
+// down a channel. The cache is only accessed for the duration of the scan and
+// of each individual read, rather than whilst waiting on downstream, as access
+// blocks updates to the cache resource.
+func (c *cacheInput) scan(ctx context.Context, items chan<- cacheScanItem) error {
+	var keys []string
+	var err error
+	if aErr := c.mgr.AccessCache(ctx, c.resource, func(ca service.Cache) {
+		sc, ok := ca.(service.ScannableCache)
+		if !ok {
+			err = service.ErrCacheScanUnsupported
+			return
+		}
+		err = sc.Scan(ctx, c.prefix, func(key string, _ []byte) error {
+			keys = append(keys, key)
+			return nil
+		})
+	}); aErr != nil {
+		return aErr
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		var value []byte
+		if aErr := c.mgr.AccessCache(ctx, c.resource, func(ca service.Cache) {
+			value, err = ca.Get(ctx, key)
+		}); aErr != nil {
+			return aErr
+		}
+		if errors.Is(err, service.ErrKeyNotFound) {
+			// The key was removed since it was scanned.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		select {
+		case items <- cacheScanItem{key: key, value: value}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (c *cacheInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	c.mut.Lock()
+	items := c.items
+	c.mut.Unlock()
+
+	if items == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	var item cacheScanItem
+	var open bool
+	select {
+	case item, open = <-items:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	if !open {
+		c.mut.Lock()
+		defer c.mut.Unlock()
+
+		c.items = nil
+		if c.cancel != nil {
+			c.cancel()
+			c.cancel = nil
+		}
+		if err := c.scanErr; err != nil {
+			c.log.Errorf("Failed to scan cache resource '%v': %v", c.resource, err)
+			return nil, nil, service.ErrNotConnected
+		}
+		c.done = true
+		return nil, nil, service.ErrEndOfInput
+	}
+
+	msg := service.NewMessage(item.value)
+	msg.MetaSetMut("cache_key", item.key)
+	return msg, func(context.Context, error) error {
+		return nil
+	}, nil
+}
+
+func (c *cacheInput) Close(ctx context.Context) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+	return nil
+}
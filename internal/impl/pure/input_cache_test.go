@@ -0,0 +1,230 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/testutil"
+	"github.com/benthosdev/benthos/v4/internal/manager"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestCacheInputMissingResource(t *testing.T) {
+	conf, err := cacheInputSpec().ParseYAML(`
+resource: foo
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newCacheInputFromConfig(conf, service.MockResources())
+	require.EqualError(t, err, "cache resource 'foo' was not found")
+}
+
+func TestCacheInputScanUnsupported(t *testing.T) {
+	resConf, err := testutil.ManagerFromYAML(`
+cache_resources:
+  - label: foo
+    memory: {}
+  - label: baz
+    memory: {}
+  - label: bar
+    multilevel: [ foo, baz ]
+`)
+	require.NoError(t, err)
+
+	mgr, err := manager.New(resConf)
+	require.NoError(t, err)
+
+	inConf, err := testutil.InputFromYAML(`
+cache:
+  resource: bar
+`)
+	require.NoError(t, err)
+
+	_, err = mgr.NewInput(inConf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cache resource 'bar' does not support scanning keys")
+}
+
+func TestCacheInputReadAll(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	conf, err := cacheInputSpec().ParseYAML(`
+resource: foo
+prefix: 'user:'
+`, nil)
+	require.NoError(t, err)
+
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foo"))
+	require.NoError(t, mgr.AccessCache(ctx, "foo", func(c service.Cache) {
+		require.NoError(t, c.Set(ctx, "user:1", []byte("alice"), nil))
+		require.NoError(t, c.Set(ctx, "user:2", []byte("bob"), nil))
+		require.NoError(t, c.Set(ctx, "group:1", []byte("admins"), nil))
+	}))
+
+	in, err := newCacheInputFromConfig(conf, mgr)
+	require.NoError(t, err)
+
+	_, _, err = in.Read(ctx)
+	require.Equal(t, service.ErrNotConnected, err)
+
+	require.NoError(t, in.Connect(ctx))
+
+	var keys, values []string
+	for {
+		msg, ackFn, err := in.Read(ctx)
+		if errors.Is(err, service.ErrEndOfInput) {
+			break
+		}
+		require.NoError(t, err)
+		require.NoError(t, ackFn(ctx, nil))
+
+		key, _ := msg.MetaGet("cache_key")
+		keys = append(keys, key)
+
+		value, err := msg.AsBytes()
+		require.NoError(t, err)
+		values = append(values, string(value))
+	}
+
+	assert.Equal(t, []string{"user:1", "user:2"}, keys)
+	assert.Equal(t, []string{"alice", "bob"}, values)
+
+	require.Equal(t, service.ErrEndOfInput, in.Connect(ctx))
+	require.NoError(t, in.Close(ctx))
+}
+
+func TestCacheInputCloseDuringScan(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	conf, err := cacheInputSpec().ParseYAML(`
+resource: foo
+`, nil)
+	require.NoError(t, err)
+
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foo"))
+	require.NoError(t, mgr.AccessCache(ctx, "foo", func(c service.Cache) {
+		require.NoError(t, c.Set(ctx, "a", []byte("1"), nil))
+		require.NoError(t, c.Set(ctx, "b", []byte("2"), nil))
+	}))
+
+	in, err := newCacheInputFromConfig(conf, mgr)
+	require.NoError(t, err)
+	require.NoError(t, in.Connect(ctx))
+
+	msg, _, err := in.Read(ctx)
+	require.NoError(t, err)
+
+	key, _ := msg.MetaGet("cache_key")
+	assert.Equal(t, "a", key)
+
+	require.NoError(t, in.Close(ctx))
+
+	// The cache must be released once the scan is cancelled.
+	require.NoError(t, mgr.AccessCache(ctx, "foo", func(c service.Cache) {
+		_, err := c.Get(ctx, "b")
+		require.NoError(t, err)
+	}))
+}
+
+func TestCacheInputStream(t *testing.T) {
+	builder := service.NewStreamBuilder()
+	require.NoError(t, builder.AddCacheYAML(`
+label: foo
+memory:
+  init_values:
+    a: hello
+    b: world
+`))
+	require.NoError(t, builder.AddInputYAML(`
+cache:
+  resource: foo
+`))
+
+	var values []string
+	require.NoError(t, builder.AddConsumerFunc(func(ctx context.Context, m *service.Message) error {
+		key, _ := m.MetaGet("cache_key")
+		b, err := m.AsBytes()
+		if err != nil {
+			return err
+		}
+		values = append(values, key+"="+string(b))
+		return nil
+	}))
+
+	strm, err := builder.Build()
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+	require.NoError(t, strm.Run(ctx))
+
+	assert.ElementsMatch(t, []string{"a=hello", "b=world"}, values)
+}
+
+func TestCacheInputUpdateResourceDuringScan(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	resConf, err := testutil.ManagerFromYAML(`
+cache_resources:
+  - label: foo
+    memory:
+      init_values:
+        a: foo
+        b: bar
+        c: baz
+        d: buz
+        e: bev
+`)
+	require.NoError(t, err)
+
+	mgr, err := manager.New(resConf)
+	require.NoError(t, err)
+
+	inConf, err := testutil.InputFromYAML(`
+cache:
+  resource: foo
+`)
+	require.NoError(t, err)
+
+	in, err := mgr.NewInput(inConf)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		in.TriggerCloseNow()
+		require.NoError(t, in.WaitForClose(context.Background()))
+	})
+
+	// Read one message so that the scan is blocked by downstream.
+	select {
+	case tran := <-in.TransactionChan():
+		require.NoError(t, tran.Ack(ctx, nil))
+	case <-ctx.Done():
+		t.Fatal("timed out")
+	}
+
+	cacheConf, err := testutil.CacheFromYAML(`
+memory:
+  init_values:
+    a: replaced
+`)
+	require.NoError(t, err)
+
+	// The cache resource can be replaced whilst the scan is blocked.
+	storeErr := make(chan error, 1)
+	go func() {
+		storeErr <- mgr.StoreCache(ctx, "foo", cacheConf)
+	}()
+	select {
+	case err := <-storeErr:
+		require.NoError(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for the cache resource to be stored")
+	}
+}
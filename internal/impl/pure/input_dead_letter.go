@@ -0,0 +1,177 @@
+package pure
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	dlqiFieldInput = "input"
+)
+
+func deadLetterInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.26.0").
+		Summary("Consumes messages written by a `dead_letter` output from a child input, and restores their original contents and metadata so that they can be replayed through a stream.").
+		Description(`
+Messages consumed from the child input are expected to be JSON envelopes in the format written by the `+"[`dead_letter` output](/docs/components/outputs/dead_letter)"+`. The original contents and metadata of each message are restored, and details of the failure are added as metadata fields.
+
+Messages that are not valid dead letter envelopes are passed through unchanged, but flagged with an error that can be caught with [error handling patterns](/docs/configuration/error_handling).
+
+### Metadata
+
+This input adds the following metadata fields to each replayed message:
+
+`+"```text"+`
+- dlq_error
+- dlq_component
+- dlq_attempts
+- dlq_failed_at
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Example(
+			"Replaying Failed Messages",
+			"In this example messages that were previously routed to a Kafka topic by a `dead_letter` output are replayed through the same processors and output, and those that fail again are dropped.",
+			`
+input:
+  dead_letter:
+    input:
+      kafka_franz:
+        seed_brokers: [ localhost:9092 ]
+        topics: [ documents_dlq ]
+        consumer_group: documents_replay
+
+pipeline:
+  processors:
+    - label: parse_document
+      mapping: 'root = content().parse_json()'
+
+output:
+  dead_letter:
+    output:
+      http_client:
+        url: http://example.com/documents
+        verb: POST
+    dead_letter:
+      drop: {}
+`,
+		).
+		Field(service.NewInputField(dlqiFieldInput).
+			Description("A child input to consume dead letter messages from."))
+}
+
+func init() {
+	err := service.RegisterBatchInput(
+		"dead_letter", deadLetterInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			child, err := conf.FieldInput(dlqiFieldInput)
+			if err != nil {
+				return nil, err
+			}
+			return &deadLetterInput{child: child}, nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type deadLetterInput struct {
+	child *service.OwnedInput
+}
+
+func (d *deadLetterInput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (d *deadLetterInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	batch, ackFn, err := d.child.ReadBatch(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	replayed := make(service.MessageBatch, len(batch))
+	for i, m := range batch {
+		if replayed[i], err = replayDeadLetter(m); err != nil {
+			m.SetError(fmt.Errorf("failed to parse dead letter message: %w", err))
+			replayed[i] = m
+		}
+	}
+	return replayed, ackFn, nil
+}
+
+func (d *deadLetterInput) Close(ctx context.Context) error {
+	return d.child.Close(ctx)
+}
+
+// replayDeadLetter restores the original message from a dead letter envelope.
+func replayDeadLetter(m *service.Message) (*service.Message, error) {
+	envBytes, err := m.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Numbers are decoded as json.Number so that integer metadata values are
+	// restored as integers rather than floats.
+	dec := json.NewDecoder(bytes.NewReader(envBytes))
+	dec.UseNumber()
+
+	var env deadLetterEnvelope
+	if err := dec.Decode(&env); err != nil {
+		return nil, err
+	}
+
+	var content []byte
+	switch {
+	case env.Content != nil:
+		content = []byte(*env.Content)
+	case env.ContentBase64 != nil:
+		if content, err = base64.StdEncoding.DecodeString(*env.ContentBase64); err != nil {
+			return nil, fmt.Errorf("failed to decode content: %w", err)
+		}
+	default:
+		return nil, errors.New("missing content")
+	}
+
+	newMsg := service.NewMessage(content).WithContext(m.Context())
+	for k, v := range env.Metadata {
+		newMsg.MetaSetMut(k, restoreDeadLetterNumbers(v))
+	}
+	newMsg.MetaSetMut("dlq_error", env.Error)
+	newMsg.MetaSetMut("dlq_component", env.Component)
+	newMsg.MetaSetMut("dlq_attempts", env.Attempts)
+	if !env.FailedAt.IsZero() {
+		newMsg.MetaSetMut("dlq_failed_at", env.FailedAt.Format(time.RFC3339Nano))
+	}
+	return newMsg, nil
+}
+
+// restoreDeadLetterNumbers converts the json.Number values of a decoded
+// metadata value into integers where possible, and floats otherwise.
+func restoreDeadLetterNumbers(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		return value.ISanitize(t)
+	case []any:
+		for i, e := range t {
+			t[i] = restoreDeadLetterNumbers(e)
+		}
+	case map[string]any:
+		for k, e := range t {
+			t[k] = restoreDeadLetterNumbers(e)
+		}
+	}
+	return v
+}
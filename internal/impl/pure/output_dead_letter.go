@@ -0,0 +1,472 @@
+package pure
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+	"github.com/benthosdev/benthos/v4/internal/bundle"
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/interop"
+	"github.com/benthosdev/benthos/v4/internal/component/output"
+	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/log"
+	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/shutdown"
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	dlqoFieldOutput          = "output"
+	dlqoFieldDeadLetter      = "dead_letter"
+	dlqoFieldProcessorErrors = "processor_errors"
+)
+
+func deadLetterOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.26.0").
+		Summary("Attempts to write messages to a child output, and routes messages that could not be delivered, or that were flagged with errors by processors, to a dead letter output.").
+		Description(`
+Messages are written to the dead letter output wrapped within a JSON envelope that describes the failure, which is the format expected by the `+"[`dead_letter` input](/docs/components/inputs/dead_letter)"+`, allowing messages to be replayed back through a stream once the cause of the failure has been fixed:
+
+`+"```json"+`
+{
+  "content": "the original contents of the message",
+  "error": "the error that caused the message to fail",
+  "component": "the label or path of the component that failed",
+  "attempts": 3,
+  "metadata": { "the": "original metadata of the message" },
+  "failed_at": "2024-03-01T10:00:00Z"
+}
+`+"```"+`
+
+When the contents of a message are not valid UTF-8 they are instead encoded as a base64 string within the field `+"`content_base64`"+`. The original metadata of each message is also retained on the envelope message, allowing it to be referenced by the dead letter output with interpolation functions.
+
+### Delivery Failures
+
+Messages that fail to be written to the child output are retried according to the `+"`max_retries`"+` and `+"`backoff`"+` fields, and once these are exhausted the messages are written to the dead letter output, where `+"`attempts`"+` is the number of times delivery was attempted and `+"`component`"+` is the label of this output. Messages replayed with a [`+"`dead_letter`"+` input](/docs/components/inputs/dead_letter) carry their previous attempts in the metadata field `+"`dlq_attempts`"+`, which are added to the attempts of any further failures. If a batch fails to be delivered then the entire batch is sent to the dead letter output.
+
+### Processor Errors
+
+When `+"`processor_errors`"+` is `+"`true`"+` messages that were flagged with an error by a processor are written directly to the dead letter output without being written to the child output, in which case `+"`attempts`"+` is zero and `+"`component`"+` is the label of the processor that flagged the error, or its path within the config when it does not have a label.
+
+If the dead letter output fails to write a message then it is nacked, and it will be reattempted from the source of the message.`).
+		Example(
+			"Routing Failures to a Queue",
+			"In this example messages that fail to be delivered to an HTTP endpoint after three retries, or that fail to be parsed, are written to a Kafka topic to be replayed later.",
+			`
+pipeline:
+  processors:
+    - label: parse_document
+      mapping: 'root = content().parse_json()'
+
+output:
+  dead_letter:
+    max_retries: 3
+    output:
+      http_client:
+        url: http://example.com/documents
+        verb: POST
+    dead_letter:
+      kafka_franz:
+        seed_brokers: [ localhost:9092 ]
+        topic: documents_dlq
+`,
+		).
+		Fields(
+			service.NewOutputField(dlqoFieldOutput).
+				Description("A child output to deliver messages to."),
+			service.NewOutputField(dlqoFieldDeadLetter).
+				Description("An output to deliver messages to when they fail."),
+			service.NewBoolField(dlqoFieldProcessorErrors).
+				Description("Whether messages flagged with errors by processors should be written directly to the dead letter output.").
+				Default(true),
+		).
+		Fields(CommonRetryBackOffFields(3, "500ms", "3s", "0s")...)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"dead_letter", deadLetterOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			var s output.Streamed
+			if s, err = newDeadLetterOutputFromParsed(conf, interop.UnwrapManagement(mgr)); err != nil {
+				return
+			}
+			out = interop.NewUnwrapInternalOutput(s)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// deadLetterEnvelope is the structure of messages written to a dead letter
+// output.
+type deadLetterEnvelope struct {
+	Content       *string        `json:"content,omitempty"`
+	ContentBase64 *string        `json:"content_base64,omitempty"`
+	Error         string         `json:"error"`
+	Component     string         `json:"component"`
+	Attempts      int            `json:"attempts"`
+	Metadata      map[string]any `json:"metadata"`
+	FailedAt      time.Time      `json:"failed_at"`
+}
+
+func newDeadLetterPart(p *message.Part, err error, label string, attempts int, failedAt time.Time) (*message.Part, error) {
+	// Messages replayed from a dead letter carry the attempts of their previous
+	// deliveries, which are added to rather than reset.
+	if v, exists := p.MetaGetMut("dlq_attempts"); exists {
+		if prev, vErr := value.IGetInt(v); vErr == nil {
+			attempts += int(prev)
+		}
+	}
+
+	env := deadLetterEnvelope{
+		Component: label,
+		Attempts:  attempts,
+		Metadata:  map[string]any{},
+		FailedAt:  failedAt,
+	}
+	if err != nil {
+		env.Error = err.Error()
+	}
+
+	content := p.AsBytes()
+	if utf8.Valid(content) {
+		str := string(content)
+		env.Content = &str
+	} else {
+		str := base64.StdEncoding.EncodeToString(content)
+		env.ContentBase64 = &str
+	}
+
+	_ = p.MetaIterMut(func(k string, v any) error {
+		env.Metadata[k] = v
+		return nil
+	})
+
+	envBytes, jErr := json.Marshal(env)
+	if jErr != nil {
+		return nil, jErr
+	}
+
+	newPart := message.NewPart(envBytes).WithContext(p.GetContext())
+	for k, v := range env.Metadata {
+		newPart.MetaSetMut(k, v)
+	}
+	return newPart, nil
+}
+
+//------------------------------------------------------------------------------
+
+func newDeadLetterOutputFromParsed(conf *service.ParsedConfig, mgr bundle.NewManagement) (*deadLetterOutput, error) {
+	pOut, err := conf.FieldOutput(dlqoFieldOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	pDLQ, err := conf.FieldOutput(dlqoFieldDeadLetter)
+	if err != nil {
+		return nil, err
+	}
+
+	procErrors, err := conf.FieldBool(dlqoFieldProcessorErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	boffCtor, err := CommonRetryBackOffCtorFromParsed(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	label := mgr.Label()
+	if label == "" {
+		label = "root." + query.SliceToDotPath(mgr.Path()...)
+	}
+
+	return newDeadLetterOutput(
+		mgr.Logger(), label, procErrors, boffCtor,
+		interop.UnwrapOwnedOutput(pOut), interop.UnwrapOwnedOutput(pDLQ),
+	)
+}
+
+// deadLetterOutput is an output type that writes messages to a child output
+// and routes messages that fail to a dead letter output.
+type deadLetterOutput struct {
+	log         log.Modular
+	component   string
+	procErrors  bool
+	backoffCtor func() backoff.BackOff
+	nowFn       func() time.Time
+
+	wrapped    output.Streamed
+	deadLetter output.Streamed
+
+	transactionsIn <-chan message.Transaction
+	wrappedTChan   chan message.Transaction
+	deadLetterChan chan message.Transaction
+
+	shutSig *shutdown.Signaller
+}
+
+func newDeadLetterOutput(
+	log log.Modular,
+	label string,
+	procErrors bool,
+	backoffCtor func() backoff.BackOff,
+	wrapped, deadLetter output.Streamed,
+) (*deadLetterOutput, error) {
+	d := &deadLetterOutput{
+		log:            log,
+		component:      label,
+		procErrors:     procErrors,
+		backoffCtor:    backoffCtor,
+		nowFn:          time.Now,
+		wrapped:        wrapped,
+		deadLetter:     deadLetter,
+		wrappedTChan:   make(chan message.Transaction),
+		deadLetterChan: make(chan message.Transaction),
+		shutSig:        shutdown.NewSignaller(),
+	}
+	if err := d.wrapped.Consume(d.wrappedTChan); err != nil {
+		return nil, err
+	}
+	if err := d.deadLetter.Consume(d.deadLetterChan); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a messages channel for the output to read.
+func (d *deadLetterOutput) Consume(ts <-chan message.Transaction) error {
+	if d.transactionsIn != nil {
+		return component.ErrAlreadyStarted
+	}
+	d.transactionsIn = ts
+	go d.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (d *deadLetterOutput) Connected() bool {
+	return d.wrapped.Connected() && d.deadLetter.Connected()
+}
+
+// deadLetterAck aggregates the results of the parts of a transaction that were
+// split between the child and dead letter outputs.
+type deadLetterAck struct {
+	mut     sync.Mutex
+	pending int
+	err     error
+	tran    message.Transaction
+}
+
+func (a *deadLetterAck) ack(ctx context.Context, err error) error {
+	a.mut.Lock()
+	if err != nil && a.err == nil {
+		a.err = err
+	}
+	a.pending--
+	pending, aErr := a.pending, a.err
+	a.mut.Unlock()
+
+	if pending > 0 {
+		return nil
+	}
+	return a.tran.Ack(ctx, aErr)
+}
+
+func (d *deadLetterOutput) loop() {
+	wg := sync.WaitGroup{}
+
+	defer func() {
+		wg.Wait()
+		close(d.wrappedTChan)
+		close(d.deadLetterChan)
+		_ = closeAllOutputs(context.Background(), []output.Streamed{d.wrapped, d.deadLetter})
+		d.shutSig.ShutdownComplete()
+	}()
+
+	cnCtx, cnDone := d.shutSig.CloseNowCtx(context.Background())
+	defer cnDone()
+
+	for !d.shutSig.ShouldCloseAtLeisure() {
+		var tran message.Transaction
+		var open bool
+		select {
+		case tran, open = <-d.transactionsIn:
+			if !open {
+				return
+			}
+		case <-d.shutSig.CloseAtLeisureChan():
+			return
+		}
+
+		var deliver, failed message.Batch
+		for _, p := range tran.Payload {
+			if d.procErrors && p.ErrorGet() != nil {
+				failed = append(failed, p)
+			} else {
+				deliver = append(deliver, p)
+			}
+		}
+
+		aggAck := &deadLetterAck{tran: tran}
+		if len(deliver) > 0 {
+			aggAck.pending++
+		}
+		if len(failed) > 0 {
+			aggAck.pending++
+		}
+		if aggAck.pending == 0 {
+			_ = tran.Ack(cnCtx, nil)
+			continue
+		}
+
+		if len(failed) > 0 {
+			dlqBatch, err := d.processorFailures(failed)
+			if err != nil {
+				d.log.Error("Failed to create dead letter messages: %v\n", err)
+				_ = aggAck.ack(cnCtx, err)
+			} else {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					d.sendDeadLetter(cnCtx, dlqBatch, aggAck.ack)
+				}()
+			}
+		}
+
+		if len(deliver) > 0 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.deliver(cnCtx, deliver, aggAck.ack)
+			}()
+		}
+	}
+}
+
+func (d *deadLetterOutput) processorFailures(parts message.Batch) (message.Batch, error) {
+	failedAt := d.nowFn()
+	dlqBatch := make(message.Batch, 0, len(parts))
+	for _, p := range parts {
+		err := p.ErrorGet()
+		dlqPart, jErr := newDeadLetterPart(p, err, processor.ErrorComponent(err), 0, failedAt)
+		if jErr != nil {
+			return nil, jErr
+		}
+		dlqBatch = append(dlqBatch, dlqPart)
+	}
+	return dlqBatch, nil
+}
+
+// deliver attempts to write a batch to the child output until either it
+// succeeds or the retries are exhausted, in which case the batch is written to
+// the dead letter output instead.
+func (d *deadLetterOutput) deliver(ctx context.Context, batch message.Batch, ackFn func(context.Context, error) error) {
+	var boff backoff.BackOff
+	attempts := 0
+	for {
+		attempts++
+
+		resChan := make(chan error)
+		select {
+		case d.wrappedTChan <- message.NewTransaction(batch.ShallowCopy(), resChan):
+		case <-ctx.Done():
+			return
+		}
+
+		var res error
+		select {
+		case res = <-resChan:
+		case <-ctx.Done():
+			return
+		}
+		if res == nil {
+			_ = ackFn(ctx, nil)
+			return
+		}
+
+		if boff == nil {
+			boff = d.backoffCtor()
+		}
+		nextBackoff := boff.NextBackOff()
+		if nextBackoff == backoff.Stop {
+			d.log.Error("Failed to send message, routing to dead letter output: %v\n", res)
+
+			failedAt := d.nowFn()
+			dlqBatch := make(message.Batch, 0, len(batch))
+			for _, p := range batch {
+				dlqPart, err := newDeadLetterPart(p, res, d.component, attempts, failedAt)
+				if err != nil {
+					_ = ackFn(ctx, err)
+					return
+				}
+				dlqBatch = append(dlqBatch, dlqPart)
+			}
+			d.sendDeadLetter(ctx, dlqBatch, ackFn)
+			return
+		}
+		d.log.Warn("Failed to send message: %v\n", res)
+
+		select {
+		case <-time.After(nextBackoff):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *deadLetterOutput) sendDeadLetter(ctx context.Context, batch message.Batch, ackFn func(context.Context, error) error) {
+	resChan := make(chan error)
+	select {
+	case d.deadLetterChan <- message.NewTransaction(batch, resChan):
+	case <-ctx.Done():
+		return
+	}
+
+	var res error
+	select {
+	case res = <-resChan:
+	case <-ctx.Done():
+		return
+	}
+	if res != nil {
+		d.log.Error("Failed to send message to dead letter output: %v\n", res)
+		res = errors.New("message failed to reach a dead letter destination")
+	}
+	_ = ackFn(ctx, res)
+}
+
+// TriggerCloseNow shuts down the output and stops processing requests.
+func (d *deadLetterOutput) TriggerCloseNow() {
+	d.shutSig.CloseNow()
+}
+
+// WaitForClose blocks until the output has closed down.
+func (d *deadLetterOutput) WaitForClose(ctx context.Context) error {
+	select {
+	case <-d.shutSig.HasClosedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
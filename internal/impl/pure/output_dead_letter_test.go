@@ -0,0 +1,262 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/output"
+	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/log"
+	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+var _ output.Streamed = &deadLetterOutput{}
+
+func newTestDeadLetterOutput(t testing.TB, maxRetries uint64) (d *deadLetterOutput, wrapped, deadLetter *mock.OutputChanneled, tChan chan message.Transaction) {
+	t.Helper()
+
+	wrapped, deadLetter = &mock.OutputChanneled{}, &mock.OutputChanneled{}
+	d, err := newDeadLetterOutput(log.Noop(), "foo_output", true, func() backoff.BackOff {
+		return backoff.WithMaxRetries(&backoff.ZeroBackOff{}, maxRetries)
+	}, wrapped, deadLetter)
+	require.NoError(t, err)
+	d.nowFn = func() time.Time {
+		return time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	}
+
+	tChan = make(chan message.Transaction)
+	require.NoError(t, d.Consume(tChan))
+
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		d.TriggerCloseNow()
+		require.NoError(t, d.WaitForClose(ctx))
+	})
+	return
+}
+
+func readTestTran(t testing.TB, ctx context.Context, c <-chan message.Transaction) message.Transaction {
+	t.Helper()
+	select {
+	case tran := <-c:
+		return tran
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for transaction")
+	}
+	return message.Transaction{}
+}
+
+func TestDeadLetterOutputHappyPath(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	_, wrapped, _, tChan := newTestDeadLetterOutput(t, 1)
+
+	resChan := make(chan error)
+	tChan <- message.NewTransaction(message.QuickBatch([][]byte{[]byte("foo"), []byte("bar")}), resChan)
+
+	tran := readTestTran(t, tCtx, wrapped.TChan)
+	require.Len(t, tran.Payload, 2)
+	assert.Equal(t, "foo", string(tran.Payload[0].AsBytes()))
+	assert.Equal(t, "bar", string(tran.Payload[1].AsBytes()))
+	require.NoError(t, tran.Ack(tCtx, nil))
+
+	require.NoError(t, <-resChan)
+}
+
+func TestDeadLetterOutputDeliveryFailure(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	_, wrapped, deadLetter, tChan := newTestDeadLetterOutput(t, 1)
+
+	part := message.NewPart([]byte("foo"))
+	part.MetaSetMut("key", "value")
+
+	resChan := make(chan error)
+	tChan <- message.NewTransaction(message.Batch{part}, resChan)
+
+	for i := 0; i < 2; i++ {
+		tran := readTestTran(t, tCtx, wrapped.TChan)
+		require.NoError(t, tran.Ack(tCtx, errors.New("nope")))
+	}
+
+	tran := readTestTran(t, tCtx, deadLetter.TChan)
+	require.Len(t, tran.Payload, 1)
+	assert.JSONEq(t, `{
+  "content": "foo",
+  "error": "nope",
+  "component": "foo_output",
+  "attempts": 2,
+  "metadata": { "key": "value" },
+  "failed_at": "2024-03-01T10:00:00Z"
+}`, string(tran.Payload[0].AsBytes()))
+	v, _ := tran.Payload[0].MetaGetMut("key")
+	assert.Equal(t, "value", v)
+	require.NoError(t, tran.Ack(tCtx, nil))
+
+	require.NoError(t, <-resChan)
+}
+
+func TestDeadLetterOutputReplayedAttempts(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	_, wrapped, deadLetter, tChan := newTestDeadLetterOutput(t, 1)
+
+	part := message.NewPart([]byte("foo"))
+	part.MetaSetMut("dlq_attempts", int64(2))
+
+	resChan := make(chan error)
+	tChan <- message.NewTransaction(message.Batch{part}, resChan)
+
+	for i := 0; i < 2; i++ {
+		tran := readTestTran(t, tCtx, wrapped.TChan)
+		require.NoError(t, tran.Ack(tCtx, errors.New("nope")))
+	}
+
+	tran := readTestTran(t, tCtx, deadLetter.TChan)
+	require.Len(t, tran.Payload, 1)
+	assert.JSONEq(t, `{
+  "content": "foo",
+  "error": "nope",
+  "component": "foo_output",
+  "attempts": 4,
+  "metadata": { "dlq_attempts": 2 },
+  "failed_at": "2024-03-01T10:00:00Z"
+}`, string(tran.Payload[0].AsBytes()))
+	require.NoError(t, tran.Ack(tCtx, nil))
+
+	require.NoError(t, <-resChan)
+}
+
+func TestDeadLetterOutputProcessorErrors(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	_, wrapped, deadLetter, tChan := newTestDeadLetterOutput(t, 1)
+
+	batch := message.QuickBatch([][]byte{[]byte("foo"), {0xff, 0xfe}, []byte("baz")})
+	batch[1].ErrorSet(&processor.ComponentError{
+		Type:  "mapping",
+		Label: "parse_document",
+		Err:   errors.New("bad document"),
+	})
+
+	resChan := make(chan error)
+	tChan <- message.NewTransaction(batch, resChan)
+
+	tran := readTestTran(t, tCtx, deadLetter.TChan)
+	require.Len(t, tran.Payload, 1)
+	assert.JSONEq(t, `{
+  "content_base64": "//4=",
+  "error": "bad document",
+  "component": "parse_document",
+  "attempts": 0,
+  "metadata": {},
+  "failed_at": "2024-03-01T10:00:00Z"
+}`, string(tran.Payload[0].AsBytes()))
+	assert.NoError(t, tran.Payload[0].ErrorGet())
+
+	wTran := readTestTran(t, tCtx, wrapped.TChan)
+	require.Len(t, wTran.Payload, 2)
+	assert.Equal(t, "foo", string(wTran.Payload[0].AsBytes()))
+	assert.Equal(t, "baz", string(wTran.Payload[1].AsBytes()))
+
+	require.NoError(t, wTran.Ack(tCtx, nil))
+	require.NoError(t, tran.Ack(tCtx, errors.New("dlq down")))
+
+	require.Error(t, <-resChan)
+}
+
+func TestDeadLetterReplay(t *testing.T) {
+	dir := t.TempDir()
+	dlqPath := filepath.Join(dir, "dlq.jsonl")
+
+	builder := service.NewStreamBuilder()
+	require.NoError(t, builder.AddInputYAML(`
+generate:
+  mapping: |
+    let id = count("TEST_DEAD_LETTER_REPLAY")
+    root.id = $id
+    meta id = $id
+  count: 4
+  interval: ""
+`))
+	require.NoError(t, builder.AddProcessorYAML(`
+label: reject_even
+mapping: 'root = if this.id % 2 == 0 { throw("even id") }'
+`))
+	require.NoError(t, builder.AddOutputYAML(`
+dead_letter:
+  output:
+    drop: {}
+  dead_letter:
+    file:
+      path: `+dlqPath+`
+      codec: lines
+`))
+
+	strm, err := builder.Build()
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+	require.NoError(t, strm.Run(ctx))
+
+	dlqBytes, err := os.ReadFile(dlqPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(dlqBytes), `"component":"reject_even"`)
+
+	builder = service.NewStreamBuilder()
+	require.NoError(t, builder.AddInputYAML(`
+dead_letter:
+  input:
+    file:
+      paths: [ `+dlqPath+` ]
+      codec: lines
+`))
+
+	var outMsgs []*service.Message
+	require.NoError(t, builder.AddConsumerFunc(func(ctx context.Context, m *service.Message) error {
+		outMsgs = append(outMsgs, m.Copy())
+		return nil
+	}))
+
+	strm, err = builder.Build()
+	require.NoError(t, err)
+	require.NoError(t, strm.Run(ctx))
+
+	require.Len(t, outMsgs, 2)
+	for i, m := range outMsgs {
+		b, err := m.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, []string{`{"id":2}`, `{"id":4}`}[i], string(b))
+
+		v, _ := m.MetaGetMut("dlq_component")
+		assert.Equal(t, "reject_even", v)
+
+		v, _ = m.MetaGetMut("dlq_error")
+		assert.Contains(t, v, "even id")
+
+		v, _ = m.MetaGetMut("dlq_attempts")
+		assert.Equal(t, 0, v)
+
+		v, _ = m.MetaGetMut("id")
+		assert.Equal(t, int64((i+1)*2), v)
+
+		_, exists := m.MetaGetMut("path")
+		assert.False(t, exists)
+	}
+}
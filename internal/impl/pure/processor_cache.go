@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/benthosdev/benthos/v4/internal/bloblang/field"
@@ -56,7 +57,26 @@ can be detected with [processor error handling](/docs/configuration/error_handli
 ### `+"`delete`"+`
 
 Delete a key and its contents from the cache.  If the key does not exist the
-action is a no-op and will not fail with an error.`).
+action is a no-op and will not fail with an error.
+
+### `+"`incr`"+`
+
+Atomically increment the integer value of a key by the `+"`value`"+` field, or by
+one when the value is not set, and replace the original message payload with the
+result. A negative value decrements the key. If the key does not exist it is
+created with the value, and if the existing value is not an integer the action
+fails with an error.
+
+Caches that do not support atomic increments natively fall back to a read
+followed by a write, which is only atomic with respect to other increments made
+through the same cache resource within a single instance of Benthos.
+
+### `+"`get_multi`"+`
+
+Retrieve the contents of the keys of all messages of a batch, in as few requests
+as the cache allows, and replace the payload of each message with its respective
+result. Messages with a key that does not exist fail with an error, which can be
+detected with [processor error handling](/docs/configuration/error_handling).`).
 		Example("Deduplication", `
 Deduplication can be done using the add operator with a key extracted from the message payload, since it fails when a key already exists we can remove the duplicates using a [`+"`mapping` processor"+`](/docs/components/processors/mapping):`,
 			`
@@ -96,6 +116,25 @@ pipeline:
         root = if errored().from(0) {
           deleted()
         }
+`).
+		Example("Counting Events", `
+The incr operator can be used to maintain a count of events for each key, which is safe to execute in parallel. Here we count events per user within hourly windows and add the running count to each message:`,
+			`
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - cache:
+              resource: foocache
+              operator: incr
+              key: '${! json("user.id") }-${! (timestamp_unix() / 3600).floor() }'
+              ttl: 1h
+        result_map: 'root.user.events_this_hour = content().number()'
+
+cache_resources:
+  - label: foocache
+    redis:
+      url: tcp://TODO:6379
 `).
 		Example("Hydration", `
 It's possible to enrich payloads with content previously stored in a cache by using the [`+"`branch`"+`](/docs/components/processors/branch) processor:`,
@@ -122,7 +161,7 @@ cache_resources:
 		Fields(
 			service.NewStringField(cachePFieldResource).
 				Description("The [`cache` resource](/docs/components/caches/about) to target with this processor."),
-			service.NewStringEnumField(cachePFieldOperator, "set", "add", "get", "delete").
+			service.NewStringEnumField(cachePFieldOperator, "set", "add", "get", "delete", "incr", "get_multi").
 				Description("The [operation](#operators) to perform with the cache."),
 			service.NewInterpolatedStringField(cachePFieldKey).
 				Description("A key to use with the cache."),
@@ -187,6 +226,7 @@ type cacheProc struct {
 	mgr       bundle.NewManagement
 	cacheName string
 	operator  cacheOperator
+	getMulti  bool
 }
 
 func newCache(conf cacheProcConfig, mgr bundle.NewManagement) (*cacheProc, error) {
@@ -195,9 +235,13 @@ func newCache(conf cacheProcConfig, mgr bundle.NewManagement) (*cacheProc, error
 		return nil, errors.New("cache name must be specified")
 	}
 
-	op, err := cacheOperatorFromString(conf.Operator)
-	if err != nil {
-		return nil, err
+	var op cacheOperator
+	getMulti := conf.Operator == "get_multi"
+	if !getMulti {
+		var err error
+		if op, err = cacheOperatorFromString(conf.Operator); err != nil {
+			return nil, err
+		}
 	}
 
 	key, err := mgr.BloblEnvironment().NewField(conf.Key)
@@ -227,6 +271,7 @@ func newCache(conf cacheProcConfig, mgr bundle.NewManagement) (*cacheProc, error
 		mgr:       mgr,
 		cacheName: cacheName,
 		operator:  op,
+		getMulti:  getMulti,
 	}, nil
 }
 
@@ -262,6 +307,23 @@ func newCacheDeleteOperator() cacheOperator {
 	}
 }
 
+func newCacheIncrOperator() cacheOperator {
+	return func(ctx context.Context, c cache.V1, key string, value []byte, ttl *time.Duration) ([]byte, bool, error) {
+		delta := int64(1)
+		if len(value) > 0 {
+			var err error
+			if delta, err = strconv.ParseInt(string(value), 10, 64); err != nil {
+				return nil, false, fmt.Errorf("value must be an integer: %w", err)
+			}
+		}
+		n, err := cache.Incr(ctx, c, key, delta, ttl)
+		if err != nil {
+			return nil, false, err
+		}
+		return strconv.AppendInt(nil, n, 10), true, nil
+	}
+}
+
 func cacheOperatorFromString(operator string) (cacheOperator, error) {
 	switch operator {
 	case "set":
@@ -272,46 +334,55 @@ func cacheOperatorFromString(operator string) (cacheOperator, error) {
 		return newCacheGetOperator(), nil
 	case "delete":
 		return newCacheDeleteOperator(), nil
+	case "incr":
+		return newCacheIncrOperator(), nil
 	}
 	return nil, fmt.Errorf("operator not recognised: %v", operator)
 }
 
 //------------------------------------------------------------------------------
 
-func (c *cacheProc) ProcessBatch(ctx *processor.BatchProcContext, msg message.Batch) ([]message.Batch, error) {
-	_ = msg.Iter(func(index int, part *message.Part) error {
-		key, err := c.key.String(index, msg)
-		if err != nil {
-			err = fmt.Errorf("key interpolation error: %w", err)
-			ctx.OnError(err, index, nil)
-			return nil
-		}
+func (c *cacheProc) resolve(index int, msg message.Batch) (key string, value []byte, ttl *time.Duration, err error) {
+	if key, err = c.key.String(index, msg); err != nil {
+		err = fmt.Errorf("key interpolation error: %w", err)
+		return
+	}
 
-		value, err := c.value.Bytes(index, msg)
-		if err != nil {
-			err = fmt.Errorf("value interpolation error: %w", err)
-			ctx.OnError(err, index, nil)
-			return nil
+	if value, err = c.value.Bytes(index, msg); err != nil {
+		err = fmt.Errorf("value interpolation error: %w", err)
+		return
+	}
+
+	var ttls string
+	if ttls, err = c.ttl.String(index, msg); err != nil {
+		err = fmt.Errorf("ttl interpolation error: %w", err)
+		return
+	}
+
+	if ttls != "" {
+		var td time.Duration
+		if td, err = time.ParseDuration(ttls); err != nil {
+			err = fmt.Errorf("ttl must be a duration: %w", err)
+			return
 		}
+		ttl = &td
+	}
+	return
+}
+
+func (c *cacheProc) ProcessBatch(ctx *processor.BatchProcContext, msg message.Batch) ([]message.Batch, error) {
+	if c.getMulti {
+		c.processGetMulti(ctx, msg)
+		return []message.Batch{msg}, nil
+	}
 
-		var ttl *time.Duration
-		ttls, err := c.ttl.String(index, msg)
+	_ = msg.Iter(func(index int, part *message.Part) error {
+		key, value, ttl, err := c.resolve(index, msg)
 		if err != nil {
-			err = fmt.Errorf("ttl interpolation error: %w", err)
 			ctx.OnError(err, index, nil)
 			return nil
 		}
 
-		if ttls != "" {
-			td, err := time.ParseDuration(ttls)
-			if err != nil {
-				err = fmt.Errorf("ttl must be a duration: %w", err)
-				ctx.OnError(err, index, nil)
-				return nil
-			}
-			ttl = &td
-		}
-
 		var result []byte
 		var useResult bool
 		if cerr := c.mgr.AccessCache(context.Background(), c.cacheName, func(cache cache.V1) {
@@ -338,6 +409,50 @@ func (c *cacheProc) ProcessBatch(ctx *processor.BatchProcContext, msg message.Ba
 	return []message.Batch{msg}, nil
 }
 
+func (c *cacheProc) processGetMulti(ctx *processor.BatchProcContext, msg message.Batch) {
+	keys := make([]string, len(msg))
+	resolved := make([]bool, len(msg))
+
+	reqKeys := make([]string, 0, len(msg))
+	for i := range msg {
+		// Only the key is interpolated as the value and ttl are not used.
+		key, err := c.key.String(i, msg)
+		if err != nil {
+			ctx.OnError(fmt.Errorf("key interpolation error: %w", err), i, nil)
+			continue
+		}
+		keys[i], resolved[i] = key, true
+		reqKeys = append(reqKeys, key)
+	}
+	if len(reqKeys) == 0 {
+		return
+	}
+
+	var values map[string][]byte
+	var err error
+	if cerr := c.mgr.AccessCache(context.Background(), c.cacheName, func(ca cache.V1) {
+		values, err = cache.GetMulti(context.Background(), ca, reqKeys...)
+	}); cerr != nil {
+		err = cerr
+	}
+
+	for i, part := range msg {
+		if !resolved[i] {
+			continue
+		}
+		if err != nil {
+			ctx.OnError(fmt.Errorf("operator failed for key '%s': %v", keys[i], err), i, nil)
+			continue
+		}
+		v, exists := values[keys[i]]
+		if !exists {
+			ctx.OnError(fmt.Errorf("operator failed for key '%s': %v", keys[i], component.ErrKeyNotFound), i, nil)
+			continue
+		}
+		part.SetBytes(v)
+	}
+}
+
 func (c *cacheProc) Close(ctx context.Context) error {
 	return nil
 }
@@ -214,3 +214,96 @@ cache:
 	_, ok = mgr.Caches["foocache"]["3"]
 	require.False(t, ok)
 }
+
+func TestCacheIncr(t *testing.T) {
+	mgr := mock.NewManager()
+	mgr.Caches["foocache"] = map[string]mock.CacheItem{
+		"1": {Value: "10"},
+		"3": {Value: "nope"},
+	}
+
+	conf, err := testutil.ProcessorFromYAML(`
+cache:
+  operator: incr
+  key: ${!json("key")}
+  value: ${!json("by").or("")}
+  resource: foocache
+`)
+	require.NoError(t, err)
+
+	proc, err := mgr.NewProcessor(conf)
+	require.NoError(t, err)
+
+	input := message.QuickBatch([][]byte{
+		[]byte(`{"key":"1"}`),
+		[]byte(`{"key":"2","by":5}`),
+		[]byte(`{"key":"1","by":-3}`),
+		[]byte(`{"key":"3"}`),
+		[]byte(`{"key":"2","by":"nah"}`),
+	})
+
+	output, res := proc.ProcessBatch(context.Background(), input)
+	require.NoError(t, res)
+	require.Len(t, output, 1)
+
+	assert.Equal(t, [][]byte{
+		[]byte(`11`),
+		[]byte(`5`),
+		[]byte(`8`),
+		[]byte(`{"key":"3"}`),
+		[]byte(`{"key":"2","by":"nah"}`),
+	}, message.GetAllBytes(output[0]))
+
+	assert.NoError(t, output[0].Get(0).ErrorGet())
+	assert.NoError(t, output[0].Get(1).ErrorGet())
+	assert.NoError(t, output[0].Get(2).ErrorGet())
+	assert.Error(t, output[0].Get(3).ErrorGet())
+	assert.Error(t, output[0].Get(4).ErrorGet())
+
+	assert.Equal(t, "8", mgr.Caches["foocache"]["1"].Value)
+	assert.Equal(t, "5", mgr.Caches["foocache"]["2"].Value)
+}
+
+func TestCacheGetMulti(t *testing.T) {
+	mgr := mock.NewManager()
+	mgr.Caches["foocache"] = map[string]mock.CacheItem{
+		"1": {Value: "foo 1"},
+		"2": {Value: "foo 2"},
+	}
+
+	conf, err := testutil.ProcessorFromYAML(`
+cache:
+  operator: get_multi
+  key: ${!json("key")}
+  value: ${!json("value").not_null()}
+  ttl: ${!json("ttl").not_null()}
+  resource: foocache
+`)
+	require.NoError(t, err)
+
+	proc, err := mgr.NewProcessor(conf)
+	require.NoError(t, err)
+
+	input := message.QuickBatch([][]byte{
+		[]byte(`{"key":"1"}`),
+		[]byte(`{"key":"3"}`),
+		[]byte(`{"key":"2"}`),
+		[]byte(`{"key":"1"}`),
+	})
+
+	output, res := proc.ProcessBatch(context.Background(), input)
+	require.NoError(t, res)
+	require.Len(t, output, 1)
+
+	assert.Equal(t, [][]byte{
+		[]byte(`foo 1`),
+		[]byte(`{"key":"3"}`),
+		[]byte(`foo 2`),
+		[]byte(`foo 1`),
+	}, message.GetAllBytes(output[0]))
+
+	assert.NoError(t, output[0].Get(0).ErrorGet())
+	assert.EqualError(t, output[0].Get(1).ErrorGet(), "operator failed for key '3': key does not exist")
+	assert.NoError(t, output[0].Get(2).ErrorGet())
+	assert.NoError(t, output[0].Get(3).ErrorGet())
+}
@@ -2,21 +2,25 @@ package pure
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/interop"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/component/ratelimit"
 	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/value"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
 const (
 	rlimitFieldResource = "resource"
+	rlimitFieldCost     = "cost"
 )
 
 func rlimitProcSpec() *service.ConfigSpec {
@@ -25,7 +29,12 @@ func rlimitProcSpec() *service.ConfigSpec {
 		Stable().
 		Summary(`Throttles the throughput of a pipeline according to a specified ` + "[`rate_limit`](/docs/components/rate_limits/about)" + ` resource. Rate limits are shared across components and therefore apply globally to all processing pipelines.`).
 		Field(service.NewStringField(rlimitFieldResource).
-			Description("The target [`rate_limit` resource](/docs/components/rate_limits/about)."))
+			Description("The target [`rate_limit` resource](/docs/components/rate_limits/about).")).
+		Field(service.NewBloblangField(rlimitFieldCost).
+			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that computes the number of tokens to consume from the rate limit for each message, which must result in a positive integer. When omitted each message costs one token, and messages with a cost of zero are not rate limited. Only the `local` and `redis` rate limits support a cost greater than one, and messages with a cost that cannot be granted by the rate limit are flagged with an error.").
+			Version("4.26.0").
+			Examples(`root = content().length()`, `root = this.records.length()`).
+			Optional())
 }
 
 func init() {
@@ -42,6 +51,11 @@ func init() {
 			if err != nil {
 				return nil, err
 			}
+			if costStr, _ := conf.FieldString(rlimitFieldCost); costStr != "" {
+				if r.cost, err = mgr.BloblEnvironment().NewMapping(costStr); err != nil {
+					return nil, fmt.Errorf("failed to parse cost mapping: %w", err)
+				}
+			}
 			return interop.NewUnwrapInternalBatchProcessor(processor.NewAutoObservedProcessor("rate_limit", r, mgr)), nil
 		})
 	if err != nil {
@@ -51,6 +65,7 @@ func init() {
 
 type rateLimitProc struct {
 	rlName string
+	cost   *mapping.Executor
 	mgr    bundle.NewManagement
 
 	closeChan chan struct{}
@@ -69,18 +84,53 @@ func newRateLimitProc(resStr string, mgr bundle.NewManagement) (*rateLimitProc,
 	return r, nil
 }
 
+func (r *rateLimitProc) msgCost(msg *message.Part) (int64, error) {
+	if r.cost == nil {
+		return 1, nil
+	}
+	costPart, err := r.cost.MapPart(0, message.Batch{msg})
+	if err != nil {
+		return 0, fmt.Errorf("cost mapping failed: %w", err)
+	}
+	if costPart == nil {
+		return 0, errors.New("cost mapping deleted the message")
+	}
+	costV, err := costPart.AsStructured()
+	if err != nil {
+		return 0, fmt.Errorf("cost mapping failed: %w", err)
+	}
+	cost, err := value.IGetInt(costV)
+	if err != nil {
+		return 0, fmt.Errorf("cost mapping failed: %w", err)
+	}
+	if cost < 0 {
+		return 0, fmt.Errorf("cost mapping resulted in a negative cost: %v", cost)
+	}
+	return cost, nil
+}
+
 func (r *rateLimitProc) Process(ctx context.Context, msg *message.Part) ([]*message.Part, error) {
+	cost, err := r.msgCost(msg)
+	if err != nil {
+		return nil, err
+	}
+	if cost == 0 {
+		return []*message.Part{msg}, nil
+	}
 	for {
 		var waitFor time.Duration
 		var err error
 		if rerr := r.mgr.AccessRateLimit(ctx, r.rlName, func(rl ratelimit.V1) {
-			waitFor, err = rl.Access(ctx)
+			waitFor, err = ratelimit.AccessN(ctx, rl, cost)
 		}); rerr != nil {
 			err = rerr
 		}
 		if ctx.Err() != nil {
 			return nil, err
 		}
+		if errors.Is(err, ratelimit.ErrCostExceedsCapacity) || errors.Is(err, ratelimit.ErrWeightedAccessUnsupported) {
+			return nil, err
+		}
 		if err != nil {
 			r.mgr.Logger().Error("Failed to access rate limit: %v", err)
 			waitFor = time.Second
@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/component/testutil"
+	"github.com/benthosdev/benthos/v4/internal/manager"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
 	"github.com/benthosdev/benthos/v4/internal/message"
 
@@ -168,3 +169,57 @@ rate_limit:
 		t.Error("Timed out")
 	}
 }
+
+func TestRateLimitCost(t *testing.T) {
+	resConf, err := testutil.ManagerFromYAML(`
+rate_limit_resources:
+  - label: foo
+    local:
+      count: 10
+      interval: 1h
+`)
+	require.NoError(t, err)
+
+	mgr, err := manager.New(resConf)
+	require.NoError(t, err)
+
+	conf, err := testutil.ProcessorFromYAML(`
+rate_limit:
+  resource: foo
+  cost: 'root = this.records.or(deleted())'
+`)
+	require.NoError(t, err)
+
+	proc, err := mgr.NewProcessor(conf)
+	require.NoError(t, err)
+
+	output, res := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte(`{"records":4}`),
+		[]byte(`{"records":6}`),
+		[]byte(`{"records":0}`),
+		[]byte(`{"records":11}`),
+		[]byte(`{"records":"nope"}`),
+		[]byte(`{}`),
+	}))
+	require.NoError(t, res)
+	require.Len(t, output, 1)
+	require.Len(t, output[0], 6)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, output[0][i].ErrorGet(), i)
+	}
+	assert.ErrorContains(t, output[0][3].ErrorGet(), "cost exceeds the capacity")
+	assert.ErrorContains(t, output[0][4].ErrorGet(), "cost mapping failed")
+	assert.ErrorContains(t, output[0][5].ErrorGet(), "cost mapping deleted the message")
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer done()
+
+	// The rate limit is now exhausted.
+	output, res = proc.ProcessBatch(ctx, message.QuickBatch([][]byte{
+		[]byte(`{"records":1}`),
+	}))
+	require.NoError(t, res)
+	require.Len(t, output, 1)
+	assert.Error(t, output[0][0].ErrorGet())
+}
@@ -3,22 +3,66 @@ package pure
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
+const (
+	lrlFieldCount     = "count"
+	lrlFieldInterval  = "interval"
+	lrlFieldAlgorithm = "algorithm"
+	lrlFieldBurst     = "burst"
+
+	lrlAlgorithmFixedWindow = "fixed_window"
+	lrlAlgorithmTokenBucket = "token_bucket"
+	lrlAlgorithmSlidingLog  = "sliding_log"
+	lrlAlgorithmGCRA        = "gcra"
+)
+
 func localRatelimitConfig() *service.ConfigSpec {
 	spec := service.NewConfigSpec().
 		Stable().
 		Summary(`The local rate limit is a simple X every Y type rate limit that can be shared across any number of components within the pipeline but does not support distributed rate limits across multiple running instances of Benthos.`).
-		Field(service.NewIntField("count").
-			Description("The maximum number of requests to allow for a given period of time.").
+		Description(`
+By default the rate limit uses a fixed window algorithm, where up to `+"`count`"+` requests are allowed within each `+"`interval`"+`. This is simple and cheap, but allows bursts of up to twice the count across the boundary of two windows. Other algorithms can be selected with the `+"`algorithm`"+` field in order to enforce a smoother rate.`).
+		Field(service.NewIntField(lrlFieldCount).
+			Description("The maximum number of requests to allow for a given period of time. Components that access the rate limit with a cost consume that number of tokens from the count rather than one.").
 			Default(1000)).
-		Field(service.NewDurationField("interval").
+		Field(service.NewDurationField(lrlFieldInterval).
 			Description("The time window to limit requests by.").
-			Default("1s"))
+			Default("1s")).
+		Field(service.NewStringAnnotatedEnumField(lrlFieldAlgorithm, map[string]string{
+			lrlAlgorithmFixedWindow: "Allows up to `count` requests within each `interval`, after which requests are limited until the next interval begins.",
+			lrlAlgorithmTokenBucket: "Tokens are added to a bucket at a steady rate of `count` every `interval`, and the bucket holds at most `burst` tokens.",
+			lrlAlgorithmSlidingLog:  "Allows up to `count` requests within any period of `interval` by keeping a log of the times at which previous requests were allowed.",
+			lrlAlgorithmGCRA:        "The generic cell rate algorithm, which spaces requests evenly at a rate of `count` every `interval` whilst allowing bursts of up to `burst` requests, with a constant memory footprint.",
+		}).
+			Description("The algorithm used to enforce the rate limit.").
+			Advanced().
+			Version("4.26.0").
+			Default(lrlAlgorithmFixedWindow)).
+		Field(service.NewIntField(lrlFieldBurst).
+			Description("The maximum number of requests that can be allowed at once by the `token_bucket` and `gcra` algorithms. When zero the `count` is used.").
+			Advanced().
+			Version("4.26.0").
+			Default(0).
+			LintRule(`root = if this < 0 { [ "burst cannot be negative" ] }`)).
+		Example(
+			"Smooth Rate With Bursts",
+			"Allows a steady rate of 100 requests per second, with up to 20 requests allowed at once after a period of inactivity.",
+			`
+rate_limit_resources:
+  - label: partner_api
+    local:
+      count: 100
+      interval: 1s
+      algorithm: gcra
+      burst: 20
+`,
+		)
 
 	return spec
 }
@@ -35,59 +79,228 @@ func init() {
 }
 
 func newLocalRatelimitFromConfig(conf *service.ParsedConfig) (*localRatelimit, error) {
-	count, err := conf.FieldInt("count")
+	count, err := conf.FieldInt(lrlFieldCount)
+	if err != nil {
+		return nil, err
+	}
+	interval, err := conf.FieldDuration(lrlFieldInterval)
 	if err != nil {
 		return nil, err
 	}
-	interval, err := conf.FieldDuration("interval")
+	algorithm, err := conf.FieldString(lrlFieldAlgorithm)
 	if err != nil {
 		return nil, err
 	}
-	return newLocalRatelimit(count, interval)
+	burst, err := conf.FieldInt(lrlFieldBurst)
+	if err != nil {
+		return nil, err
+	}
+	return newLocalRatelimit(algorithm, count, burst, interval)
 }
 
 //------------------------------------------------------------------------------
 
-type localRatelimit struct {
-	mut         sync.Mutex
-	bucket      int
-	lastRefresh time.Time
+// localAlgorithm is the state of a rate limiting algorithm, which is accessed
+// at a given time and returns a period to wait before the cost of n can be
+// granted, or zero if it was granted.
+type localAlgorithm interface {
+	accessN(now time.Time, n int64) time.Duration
+}
 
-	size   int
-	period time.Duration
+type localRatelimit struct {
+	mut      sync.Mutex
+	algo     localAlgorithm
+	capacity int64
+	nowFn    func() time.Time
 }
 
-func newLocalRatelimit(count int, interval time.Duration) (*localRatelimit, error) {
+func newLocalRatelimit(algorithm string, count, burst int, interval time.Duration) (*localRatelimit, error) {
 	if count <= 0 {
 		return nil, errors.New("count must be larger than zero")
 	}
-	return &localRatelimit{
-		bucket:      count,
-		lastRefresh: time.Now(),
-		size:        count,
-		period:      interval,
-	}, nil
+	if burst < 0 {
+		return nil, errors.New("burst cannot be negative")
+	}
+	if burst == 0 {
+		burst = count
+	}
+
+	now := time.Now()
+	r := &localRatelimit{
+		capacity: int64(count),
+		nowFn:    time.Now,
+	}
+	switch algorithm {
+	case lrlAlgorithmFixedWindow, "":
+		r.algo = &fixedWindowAlgorithm{
+			bucket:      int64(count),
+			lastRefresh: now,
+			size:        int64(count),
+			period:      interval,
+		}
+	case lrlAlgorithmTokenBucket:
+		r.capacity = int64(burst)
+		r.algo = &tokenBucketAlgorithm{
+			tokens:     float64(burst),
+			lastRefill: now,
+			burst:      float64(burst),
+			rate:       float64(count) / float64(interval),
+		}
+	case lrlAlgorithmSlidingLog:
+		r.algo = &slidingLogAlgorithm{
+			size:   int64(count),
+			period: interval,
+		}
+	case lrlAlgorithmGCRA:
+		r.capacity = int64(burst)
+		emission := interval / time.Duration(count)
+		if emission <= 0 {
+			return nil, fmt.Errorf("interval %v is too short for a count of %v with the gcra algorithm, which requires at least one nanosecond per token", interval, count)
+		}
+		r.algo = &gcraAlgorithm{
+			tat:       now,
+			emission:  emission,
+			tolerance: emission * time.Duration(burst),
+		}
+	default:
+		return nil, fmt.Errorf("algorithm not recognised: %v", algorithm)
+	}
+	return r, nil
 }
 
 func (r *localRatelimit) Access(ctx context.Context) (time.Duration, error) {
+	return r.AccessN(ctx, 1)
+}
+
+func (r *localRatelimit) AccessN(ctx context.Context, n int64) (time.Duration, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+	if n > r.capacity {
+		return 0, fmt.Errorf("%w: %v > %v", service.ErrRateLimitCostExceedsCapacity, n, r.capacity)
+	}
+
 	r.mut.Lock()
-	r.bucket--
+	defer r.mut.Unlock()
+	return r.algo.accessN(r.nowFn(), n), nil
+}
+
+func (r *localRatelimit) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type fixedWindowAlgorithm struct {
+	bucket      int64
+	lastRefresh time.Time
 
-	if r.bucket < 0 {
-		r.bucket = 0
-		remaining := r.period - time.Since(r.lastRefresh)
+	size   int64
+	period time.Duration
+}
 
-		if remaining > 0 {
-			r.mut.Unlock()
-			return remaining, nil
+func (f *fixedWindowAlgorithm) accessN(now time.Time, n int64) time.Duration {
+	if f.bucket < n {
+		if remaining := f.period - now.Sub(f.lastRefresh); remaining > 0 {
+			return remaining
 		}
-		r.bucket = r.size - 1
-		r.lastRefresh = time.Now()
+		f.bucket = f.size
+		f.lastRefresh = now
 	}
-	r.mut.Unlock()
-	return 0, nil
+	f.bucket -= n
+	return 0
 }
 
-func (r *localRatelimit) Close(ctx context.Context) error {
-	return nil
+//------------------------------------------------------------------------------
+
+type tokenBucketAlgorithm struct {
+	tokens     float64
+	lastRefill time.Time
+
+	burst float64
+	rate  float64 // Tokens per nanosecond
+}
+
+func (b *tokenBucketAlgorithm) accessN(now time.Time, n int64) time.Duration {
+	if elapsed := now.Sub(b.lastRefill); elapsed > 0 {
+		b.tokens += float64(elapsed) * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.lastRefill = now
+	}
+	if missing := float64(n) - b.tokens; missing > 0 {
+		wait := time.Duration(missing / b.rate)
+		if wait <= 0 {
+			wait = 1
+		}
+		return wait
+	}
+	b.tokens -= float64(n)
+	return 0
+}
+
+//------------------------------------------------------------------------------
+
+type slidingLogEntry struct {
+	at   time.Time
+	cost int64
+}
+
+type slidingLogAlgorithm struct {
+	log  []slidingLogEntry
+	used int64
+
+	size   int64
+	period time.Duration
+}
+
+func (s *slidingLogAlgorithm) accessN(now time.Time, n int64) time.Duration {
+	// Drop entries that are no longer within the window.
+	var expired int
+	for expired < len(s.log) && now.Sub(s.log[expired].at) >= s.period {
+		s.used -= s.log[expired].cost
+		expired++
+	}
+	s.log = s.log[expired:]
+
+	if s.used+n <= s.size {
+		s.log = append(s.log, slidingLogEntry{at: now, cost: n})
+		s.used += n
+		return 0
+	}
+
+	// Find the time at which enough entries will have expired for the cost
+	// to be granted.
+	excess := s.used + n - s.size
+	for _, e := range s.log {
+		if excess -= e.cost; excess <= 0 {
+			return s.period - now.Sub(e.at)
+		}
+	}
+	return s.period
+}
+
+//------------------------------------------------------------------------------
+
+type gcraAlgorithm struct {
+	// The theoretical arrival time of the next request when requests are
+	// perfectly spaced.
+	tat time.Time
+
+	emission  time.Duration
+	tolerance time.Duration
+}
+
+func (g *gcraAlgorithm) accessN(now time.Time, n int64) time.Duration {
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	newTAT := tat.Add(g.emission * time.Duration(n))
+	if allowAt := newTAT.Add(-g.tolerance); allowAt.After(now) {
+		return allowAt.Sub(now)
+	}
+	g.tat = newTAT
+	return 0
 }
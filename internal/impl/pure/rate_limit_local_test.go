@@ -8,6 +8,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
 )
 
 func TestLocalRateLimitConfErrors(t *testing.T) {
@@ -138,3 +140,130 @@ interval: 1ns
 	close(startChan)
 	wg.Wait()
 }
+
+func TestLocalRateLimitAccessN(t *testing.T) {
+	rl, err := newLocalRatelimit(lrlAlgorithmFixedWindow, 10, 0, time.Millisecond*20)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	period, err := rl.AccessN(ctx, 6)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), period)
+
+	period, err = rl.AccessN(ctx, 4)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), period)
+
+	period, err = rl.AccessN(ctx, 1)
+	require.NoError(t, err)
+	assert.Greater(t, period, time.Duration(0))
+
+	_, err = rl.AccessN(ctx, 11)
+	require.ErrorIs(t, err, service.ErrRateLimitCostExceedsCapacity)
+
+	<-time.After(time.Millisecond * 25)
+
+	period, err = rl.AccessN(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), period)
+
+	period, err = rl.AccessN(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), period)
+}
+
+func TestLocalRateLimitAlgorithmConf(t *testing.T) {
+	conf, err := localRatelimitConfig().ParseYAML(`
+count: 10
+interval: 1s
+algorithm: gcra
+burst: 5
+`, nil)
+	require.NoError(t, err)
+
+	rl, err := newLocalRatelimitFromConfig(conf)
+	require.NoError(t, err)
+	assert.IsType(t, &gcraAlgorithm{}, rl.algo)
+	assert.Equal(t, int64(5), rl.capacity)
+
+	_, err = rl.AccessN(context.Background(), 6)
+	require.ErrorIs(t, err, service.ErrRateLimitCostExceedsCapacity)
+
+	_, err = newLocalRatelimit("nope", 10, 0, time.Second)
+	require.EqualError(t, err, "algorithm not recognised: nope")
+
+	_, err = newLocalRatelimit(lrlAlgorithmTokenBucket, 10, -1, time.Second)
+	require.Error(t, err)
+
+	_, err = newLocalRatelimit(lrlAlgorithmGCRA, 2000, 0, time.Microsecond)
+	require.EqualError(t, err, "interval 1µs is too short for a count of 2000 with the gcra algorithm, which requires at least one nanosecond per token")
+}
+
+func TestLocalRateLimitTokenBucket(t *testing.T) {
+	now := time.Unix(1000, 0)
+	algo := &tokenBucketAlgorithm{
+		tokens:     5,
+		lastRefill: now,
+		burst:      5,
+		rate:       10 / float64(time.Second),
+	}
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, time.Duration(0), algo.accessN(now, 1), i)
+	}
+	assert.Equal(t, time.Millisecond*100, algo.accessN(now, 1))
+	assert.Equal(t, time.Millisecond*50, algo.accessN(now.Add(time.Millisecond*50), 1))
+
+	now = now.Add(time.Millisecond * 100)
+	assert.Equal(t, time.Duration(0), algo.accessN(now, 1))
+	assert.Equal(t, time.Millisecond*200, algo.accessN(now, 2))
+
+	// Tokens do not accumulate beyond the burst.
+	now = now.Add(time.Hour)
+	assert.Equal(t, time.Duration(0), algo.accessN(now, 5))
+	assert.Greater(t, algo.accessN(now, 1), time.Duration(0))
+}
+
+func TestLocalRateLimitSlidingLog(t *testing.T) {
+	now := time.Unix(1000, 0)
+	algo := &slidingLogAlgorithm{
+		size:   3,
+		period: time.Second,
+	}
+
+	assert.Equal(t, time.Duration(0), algo.accessN(now, 1))
+	assert.Equal(t, time.Duration(0), algo.accessN(now.Add(time.Millisecond*500), 2))
+	assert.Equal(t, time.Millisecond*400, algo.accessN(now.Add(time.Millisecond*600), 1))
+
+	// Unlike a fixed window the second entry still counts after the first
+	// period has elapsed.
+	assert.Equal(t, time.Duration(0), algo.accessN(now.Add(time.Second), 1))
+	assert.Equal(t, time.Millisecond*400, algo.accessN(now.Add(time.Millisecond*1100), 1))
+	assert.Equal(t, time.Duration(0), algo.accessN(now.Add(time.Millisecond*1500), 2))
+}
+
+func TestLocalRateLimitGCRA(t *testing.T) {
+	now := time.Unix(1000, 0)
+	algo := &gcraAlgorithm{
+		tat:       now,
+		emission:  time.Millisecond * 100,
+		tolerance: time.Millisecond * 300,
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, time.Duration(0), algo.accessN(now, 1), i)
+	}
+	assert.Equal(t, time.Millisecond*100, algo.accessN(now, 1))
+
+	// Requests are then spaced evenly at the emission interval.
+	now = now.Add(time.Millisecond * 100)
+	assert.Equal(t, time.Duration(0), algo.accessN(now, 1))
+	assert.Equal(t, time.Millisecond*100, algo.accessN(now, 1))
+	assert.Equal(t, time.Millisecond*200, algo.accessN(now, 2))
+
+	// After a period of inactivity the full burst is available again.
+	now = now.Add(time.Second)
+	assert.Equal(t, time.Duration(0), algo.accessN(now, 3))
+	assert.Equal(t, time.Millisecond*100, algo.accessN(now, 1))
+}
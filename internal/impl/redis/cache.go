@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"time"
 
@@ -143,6 +144,77 @@ func (r *redisCache) Get(ctx context.Context, key string) ([]byte, error) {
 	}
 }
 
+func (r *redisCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	boff := r.boffPool.Get().(backoff.BackOff)
+	defer func() {
+		boff.Reset()
+		r.boffPool.Put(boff)
+	}()
+
+	prefixedKeys := keys
+	if len(r.prefix) > 0 {
+		prefixedKeys = make([]string, len(keys))
+		for i, k := range keys {
+			prefixedKeys[i] = r.prefix + k
+		}
+	}
+
+	for {
+		values, err := r.getMulti(ctx, keys, prefixedKeys)
+		if err == nil {
+			return values, nil
+		}
+		// Errors returned by the server will not be resolved by retrying.
+		var rErr redis.Error
+		if errors.As(err, &rErr) {
+			return nil, err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return nil, err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
+
+// getMulti obtains the values of keys with a pipeline of GET commands rather
+// than MGET as keys may belong to different cluster slots.
+func (r *redisCache) getMulti(ctx context.Context, keys, prefixedKeys []string) (map[string][]byte, error) {
+	cmds := make([]*redis.StringCmd, len(prefixedKeys))
+	if _, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, k := range prefixedKeys {
+			cmds[i] = pipe.Get(ctx, k)
+		}
+		return nil
+	}); err != nil {
+		// Errors returned by the server are checked for each command.
+		var rErr redis.Error
+		if !errors.As(err, &rErr) {
+			return nil, err
+		}
+	}
+
+	values := make(map[string][]byte, len(keys))
+	for i, cmd := range cmds {
+		value, err := cmd.Bytes()
+		if err != nil {
+			// Keys that do not exist or that hold a type other than a string
+			// are omitted, matching the behaviour of MGET.
+			if errors.Is(err, redis.Nil) || strings.HasPrefix(err.Error(), "WRONGTYPE") {
+				continue
+			}
+			return nil, err
+		}
+		values[keys[i]] = value
+	}
+	return values, nil
+}
+
 func (r *redisCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	boff := r.boffPool.Get().(backoff.BackOff)
 	defer func() {
@@ -219,6 +291,55 @@ func (r *redisCache) Add(ctx context.Context, key string, value []byte, ttl *tim
 	}
 }
 
+func (r *redisCache) Incr(ctx context.Context, key string, delta int64, ttl *time.Duration) (int64, error) {
+	boff := r.boffPool.Get().(backoff.BackOff)
+	defer func() {
+		boff.Reset()
+		r.boffPool.Put(boff)
+	}()
+
+	if len(r.prefix) > 0 {
+		key = r.prefix + key
+	}
+
+	var t time.Duration
+	if ttl != nil {
+		t = *ttl
+	} else {
+		t = r.defaultTTL
+	}
+
+	for {
+		var incr *redis.IntCmd
+		_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			incr = pipe.IncrBy(ctx, key, delta)
+			if t > 0 {
+				pipe.PExpire(ctx, key, t)
+			}
+			return nil
+		})
+		if err == nil {
+			return incr.Val(), nil
+		}
+		// Errors returned by the server, such as the existing value not being
+		// an integer, will not be resolved by retrying.
+		var rErr redis.Error
+		if errors.As(err, &rErr) {
+			return 0, err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return 0, err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return 0, err
+		}
+	}
+}
+
 func (r *redisCache) Delete(ctx context.Context, key string) error {
 	boff := r.boffPool.Get().(backoff.BackOff)
 	defer func() {
@@ -248,6 +369,77 @@ func (r *redisCache) Delete(ctx context.Context, key string) error {
 	}
 }
 
+func (r *redisCache) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) error) error {
+	match := redisGlobEscape(r.prefix+prefix) + "*"
+	if cc, ok := r.client.(*redis.ClusterClient); ok {
+		return cc.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return r.scanNode(ctx, client, match, fn)
+		})
+	}
+	return r.scanNode(ctx, r.client, match, fn)
+}
+
+func (r *redisCache) scanNode(ctx context.Context, client redis.Cmdable, match string, fn func(key string, value []byte) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, match, 100).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			// Values are obtained with a pipeline of GET commands rather than
+			// MGET as keys may belong to different cluster slots.
+			cmds := make([]*redis.StringCmd, len(keys))
+			if _, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for i, k := range keys {
+					cmds[i] = pipe.Get(ctx, k)
+				}
+				return nil
+			}); err != nil {
+				// Errors returned by the server are checked for each command.
+				var rErr redis.Error
+				if !errors.As(err, &rErr) {
+					return err
+				}
+			}
+
+			for i, k := range keys {
+				value, err := cmds[i].Bytes()
+				if err != nil {
+					// Keys that were deleted since the scan or that hold a
+					// type other than a string are skipped.
+					if errors.Is(err, redis.Nil) || strings.HasPrefix(err.Error(), "WRONGTYPE") {
+						continue
+					}
+					return err
+				}
+				if err := fn(strings.TrimPrefix(k, r.prefix), value); err != nil {
+					return err
+				}
+			}
+		}
+
+		if cursor = next; cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// redisGlobEscape escapes characters that have a special meaning within the
+// MATCH pattern of a SCAN command.
+func redisGlobEscape(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
 func (r *redisCache) Close(ctx context.Context) error {
 	return r.client.Close()
 }
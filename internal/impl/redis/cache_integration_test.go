@@ -61,6 +61,9 @@ cache_resources:
 		integration.CacheTestDoubleAdd(),
 		integration.CacheTestDelete(),
 		integration.CacheTestGetAndSet(50),
+		integration.CacheTestIncr(50),
+		integration.CacheTestGetMulti(50),
+		integration.CacheTestScan(50),
 	)
 	suite.Run(
 		t, template,
@@ -154,6 +157,7 @@ cache_resources:
 		integration.CacheTestDoubleAdd(),
 		integration.CacheTestDelete(),
 		integration.CacheTestGetAndSet(50),
+		integration.CacheTestGetMulti(50),
 	)
 	suite.Run(
 		t, template,
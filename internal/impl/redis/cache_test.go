@@ -0,0 +1,125 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisGlobEscape(t *testing.T) {
+	for in, exp := range map[string]string{
+		"":          "",
+		"foo":       "foo",
+		"foo:*":     `foo:\*`,
+		`a?b[c]d\e`: `a\?b\[c\]d\\e`,
+	} {
+		assert.Equal(t, exp, redisGlobEscape(in), in)
+	}
+}
+
+type fakeRedisError string
+
+func (e fakeRedisError) Error() string { return string(e) }
+
+func (fakeRedisError) RedisError() {}
+
+// fakeClusterHook serves commands from memory without a connection, and
+// rejects commands spanning multiple keys as a cluster would when the keys
+// belong to different slots.
+type fakeClusterHook struct {
+	values    map[string]string
+	errKeys   map[string]string
+	pipelines int
+}
+
+func (f *fakeClusterHook) process(cmd redis.Cmder) {
+	args := cmd.Args()
+	switch strings.ToLower(args[0].(string)) {
+	case "mget":
+		if len(args) > 2 {
+			cmd.SetErr(fakeRedisError("CROSSSLOT Keys in request don't hash to the same slot"))
+			return
+		}
+	case "get":
+		key := args[1].(string)
+		if e, exists := f.errKeys[key]; exists {
+			cmd.SetErr(fakeRedisError(e))
+			return
+		}
+		v, exists := f.values[key]
+		if !exists {
+			cmd.SetErr(redis.Nil)
+			return
+		}
+		cmd.(*redis.StringCmd).SetVal(v)
+		return
+	}
+	cmd.SetErr(errors.New("unsupported command"))
+}
+
+func (f *fakeClusterHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (f *fakeClusterHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		f.process(cmd)
+		return cmd.Err()
+	}
+}
+
+func (f *fakeClusterHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) (err error) {
+		f.pipelines++
+		for _, cmd := range cmds {
+			f.process(cmd)
+			if cErr := cmd.Err(); cErr != nil && err == nil {
+				err = cErr
+			}
+		}
+		return
+	}
+}
+
+func TestRedisCacheGetMultiCrossSlot(t *testing.T) {
+	hook := &fakeClusterHook{
+		values: map[string]string{
+			"pre:foo": "foo value",
+			"pre:bar": "bar value",
+		},
+		errKeys: map[string]string{
+			"pre:list": "WRONGTYPE Operation against a key holding the wrong kind of value",
+			"pre:bad":  "ERR nope",
+		},
+	}
+	client := redis.NewClient(&redis.Options{Addr: "localhost:1"})
+	client.AddHook(hook)
+
+	boff := backoff.NewExponentialBackOff()
+	boff.InitialInterval = time.Millisecond
+	boff.MaxElapsedTime = time.Second
+	c, err := newRedisCache(0, "pre:", client, boff)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	values, err := c.GetMulti(ctx, "foo", "bar", "baz", "list")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("foo value"),
+		"bar": []byte("bar value"),
+	}, values)
+	assert.Equal(t, 1, hook.pipelines)
+
+	// Errors returned by the server are not retried.
+	_, err = c.GetMulti(ctx, "foo", "bad")
+	require.EqualError(t, err, "ERR nope")
+	assert.Equal(t, 2, hook.pipelines)
+}
@@ -10,9 +10,16 @@ import (
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
+const (
+	rrlAlgorithmFixedWindow = "fixed_window"
+	rrlAlgorithmGCRA        = "gcra"
+)
+
 func redisRatelimitConfig() *service.ConfigSpec {
 	spec := service.NewConfigSpec().
 		Summary(`A rate limit implementation using Redis. It works by using a simple token bucket algorithm to limit the number of requests to a given count within a given time period. The rate limit is shared across all instances of Benthos that use the same Redis instance, which must all have a consistent count and interval.`).
+		Description(`
+The ` + "`gcra`" + ` algorithm can be selected in order to space requests evenly across the fleet rather than allowing the full count at the start of each interval. In this mode the state of the rate limit is a single timestamp derived from the clock of the Redis server, and therefore all instances must also have a consistent burst. The spacing between requests is tracked to the nearest microsecond, and therefore the `+"`interval`"+` divided by the `+"`count`"+` must be at least one microsecond.`).
 		Version("4.12.0")
 
 	for _, f := range clientFields() {
@@ -20,13 +27,27 @@ func redisRatelimitConfig() *service.ConfigSpec {
 	}
 
 	spec.Field(service.NewIntField("count").
-		Description("The maximum number of messages to allow for a given period of time.").
+		Description("The maximum number of messages to allow for a given period of time. Components that access the rate limit with a cost consume that number of tokens from the count rather than one.").
 		Default(1000).LintRule(`root = if this <= 0 { [ "count must be larger than zero" ] }`)).
 		Field(service.NewDurationField("interval").
 			Description("The time window to limit requests by.").
 			Default("1s")).
 		Field(service.NewStringField("key").
-			Description("The key to use for the rate limit."))
+			Description("The key to use for the rate limit.")).
+		Field(service.NewStringAnnotatedEnumField("algorithm", map[string]string{
+			rrlAlgorithmFixedWindow: "Allows up to `count` requests within each `interval`, after which requests are limited until the key expires.",
+			rrlAlgorithmGCRA:        "The generic cell rate algorithm, which spaces requests evenly at a rate of `count` every `interval` whilst allowing bursts of up to `burst` requests.",
+		}).
+			Description("The algorithm used to enforce the rate limit.").
+			Advanced().
+			Version("4.26.0").
+			Default(rrlAlgorithmFixedWindow)).
+		Field(service.NewIntField("burst").
+			Description("The maximum number of requests that can be allowed at once by the `gcra` algorithm. When zero the `count` is used.").
+			Advanced().
+			Version("4.26.0").
+			Default(0).
+			LintRule(`root = if this < 0 { [ "burst cannot be negative" ] }`))
 
 	return spec
 }
@@ -49,6 +70,11 @@ type redisRatelimit struct {
 	key    string
 	period time.Duration
 
+	// When gcra is enabled the emission interval and tolerance are set.
+	gcra      bool
+	emission  time.Duration
+	tolerance time.Duration
+
 	client redis.UniversalClient
 
 	accessScript *redis.Script
@@ -75,9 +101,48 @@ func newRedisRatelimitFromConfig(conf *service.ParsedConfig) (*redisRatelimit, e
 		return nil, err
 	}
 
+	algorithm, err := conf.FieldString("algorithm")
+	if err != nil {
+		return nil, err
+	}
+
+	burst, err := conf.FieldInt("burst")
+	if err != nil {
+		return nil, err
+	}
+
 	if count <= 0 {
 		return nil, fmt.Errorf("count must be larger than zero")
 	}
+	if burst < 0 {
+		return nil, fmt.Errorf("burst cannot be negative")
+	}
+	if burst == 0 {
+		burst = count
+	}
+
+	switch algorithm {
+	case rrlAlgorithmFixedWindow:
+	case rrlAlgorithmGCRA:
+		// The gcra script works in microseconds, and therefore the emission
+		// interval must be at least one microsecond.
+		emission := interval / time.Duration(count)
+		if emission < time.Microsecond {
+			return nil, fmt.Errorf("interval %v is too short for a count of %v with the gcra algorithm, which requires at least one microsecond per token", interval, count)
+		}
+		return &redisRatelimit{
+			size:         burst,
+			key:          key,
+			period:       interval,
+			gcra:         true,
+			emission:     emission,
+			tolerance:    emission * time.Duration(burst),
+			client:       client,
+			accessScript: redis.NewScript(gcraScript),
+		}, nil
+	default:
+		return nil, fmt.Errorf("algorithm not recognised: %v", algorithm)
+	}
 
 	return &redisRatelimit{
 		size:   count,
@@ -85,9 +150,9 @@ func newRedisRatelimitFromConfig(conf *service.ParsedConfig) (*redisRatelimit, e
 		client: client,
 		key:    key,
 		accessScript: redis.NewScript(`
-local current = redis.call("INCR",KEYS[1])
+local current = redis.call("INCRBY",KEYS[1],tonumber(ARGV[3]))
 
-if current == 1 then
+if current == tonumber(ARGV[3]) then
     redis.call("PEXPIRE", KEYS[1], tonumber(ARGV[2]))
 end
 
@@ -100,10 +165,68 @@ return 0
 	}, nil
 }
 
+// gcraScript stores the theoretical arrival time of the next request in
+// microseconds according to the clock of the Redis server, and returns the
+// number of microseconds to wait before the cost can be granted, or zero if it
+// was granted.
+const gcraScript = `
+redis.replicate_commands()
+
+local emission = tonumber(ARGV[1])
+local tolerance = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local tat = tonumber(redis.call("GET", KEYS[1]))
+if tat == nil or tat < now then
+	tat = now
+end
+
+local new_tat = tat + emission * cost
+local allow_at = new_tat - tolerance
+if allow_at > now then
+	return math.ceil(allow_at - now)
+end
+
+redis.call("SET", KEYS[1], string.format("%.0f", new_tat), "PX", math.ceil((new_tat - now) / 1000) + 1)
+return 0
+`
+
+// gcraMicroseconds converts a duration into the microseconds used by gcraScript,
+// rounding to the nearest microsecond rather than truncating.
+func gcraMicroseconds(d time.Duration) int64 {
+	return d.Round(time.Microsecond).Microseconds()
+}
+
 //------------------------------------------------------------------------------
 
 func (r *redisRatelimit) Access(ctx context.Context) (time.Duration, error) {
-	result := r.accessScript.Run(ctx, r.client, []string{r.key}, r.size, int(r.period.Milliseconds()))
+	return r.AccessN(ctx, 1)
+}
+
+func (r *redisRatelimit) AccessN(ctx context.Context, n int64) (time.Duration, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+	if n > int64(r.size) {
+		return 0, fmt.Errorf("%w: %v > %v", service.ErrRateLimitCostExceedsCapacity, n, r.size)
+	}
+
+	if r.gcra {
+		result := r.accessScript.Run(ctx, r.client, []string{r.key}, gcraMicroseconds(r.emission), gcraMicroseconds(r.tolerance), n)
+		if result.Err() != nil {
+			return 0, fmt.Errorf("accessing redis rate limit: %w", result.Err())
+		}
+		wait, err := result.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("accessing redis rate limit: %w", err)
+		}
+		return time.Duration(wait) * time.Microsecond, nil
+	}
+
+	result := r.accessScript.Run(ctx, r.client, []string{r.key}, r.size, int(r.period.Milliseconds()), n)
 
 	if result.Err() != nil {
 		return 0, fmt.Errorf("accessing redis rate limit: %w", result.Err())
@@ -63,6 +63,14 @@ func TestIntegrationRedisRateLimit(t *testing.T) {
 	t.Run("testRedisRateLimitRefresh", func(t *testing.T) {
 		testRedisRateLimitRefresh(t, urlStr)
 	})
+
+	t.Run("testRedisRateLimitAccessN", func(t *testing.T) {
+		testRedisRateLimitAccessN(t, urlStr)
+	})
+
+	t.Run("testRedisRateLimitGCRA", func(t *testing.T) {
+		testRedisRateLimitGCRA(t, urlStr)
+	})
 }
 
 func testRedisRateLimitBasic(t *testing.T, url string) {
@@ -152,3 +160,72 @@ url: `+url, nil)
 		t.Errorf("Period beyond interval: %v", period)
 	}
 }
+
+func testRedisRateLimitAccessN(t *testing.T, url string) {
+	conf, err := redisRatelimitConfig().ParseYAML(`
+key: rate_limit_access_n
+count: 10
+interval: 1s
+url: `+url, nil)
+	require.NoError(t, err)
+
+	rl, err := newRedisRatelimitFromConfig(conf)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	period, err := rl.AccessN(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), period)
+
+	period, err = rl.AccessN(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), period)
+
+	period, err = rl.AccessN(ctx, 2)
+	require.NoError(t, err)
+	if period == 0 {
+		t.Error("Expected limit on final request")
+	} else if period > time.Second {
+		t.Errorf("Period beyond interval: %v", period)
+	}
+
+	_, err = rl.AccessN(ctx, 11)
+	require.Error(t, err)
+}
+
+func testRedisRateLimitGCRA(t *testing.T, url string) {
+	conf, err := redisRatelimitConfig().ParseYAML(`
+key: rate_limit_gcra
+count: 10
+interval: 1s
+algorithm: gcra
+burst: 3
+url: `+url, nil)
+	require.NoError(t, err)
+
+	rl, err := newRedisRatelimitFromConfig(conf)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		period, err := rl.Access(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), period, i)
+	}
+
+	period, err := rl.Access(ctx)
+	require.NoError(t, err)
+	assert.Greater(t, period, time.Duration(0))
+	assert.LessOrEqual(t, period, time.Millisecond*100)
+
+	<-time.After(period)
+
+	period, err = rl.Access(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), period)
+
+	_, err = rl.AccessN(ctx, 4)
+	require.Error(t, err)
+}
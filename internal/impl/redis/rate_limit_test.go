@@ -1,8 +1,11 @@
 package redis
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -25,9 +28,57 @@ key: asdf`, nil)
 	_, err = newRedisRatelimitFromConfig(conf)
 	require.Error(t, err)
 
+	conf, err = redisRatelimitConfig().ParseYAML(`
+url: redis://localhost:6379
+count: 2000
+interval: 1us
+algorithm: gcra
+key: asdf`, nil)
+	require.NoError(t, err)
+
+	_, err = newRedisRatelimitFromConfig(conf)
+	require.ErrorContains(t, err, "too short for a count of 2000")
+
 	_, err = redisRatelimitConfig().ParseYAML(`key: asdf`, nil)
 	require.Error(t, err)
 
 	_, err = redisRatelimitConfig().ParseYAML(`url: redis://localhost:6379`, nil)
 	require.Error(t, err)
 }
+
+func TestRedisRateLimitGCRAMicroseconds(t *testing.T) {
+	for _, test := range []struct {
+		count       int
+		interval    string
+		emission    int64
+		tolerance   int64
+		errContains string
+	}{
+		{count: 1000000, interval: "1s", emission: 1, tolerance: 1000000},
+		{count: 700000, interval: "1s", emission: 1, tolerance: 999600},
+		{count: 3, interval: "5us", emission: 2, tolerance: 5},
+		{count: 10, interval: "1s", emission: 100000, tolerance: 1000000},
+		{count: 1000001, interval: "1s", errContains: "requires at least one microsecond per token"},
+		{count: 2, interval: "1us", errContains: "too short for a count of 2"},
+	} {
+		conf, err := redisRatelimitConfig().ParseYAML(fmt.Sprintf(`
+url: redis://localhost:6379
+count: %v
+interval: %v
+algorithm: gcra
+key: asdf`, test.count, test.interval), nil)
+		require.NoError(t, err)
+
+		r, err := newRedisRatelimitFromConfig(conf)
+		if test.errContains != "" {
+			require.ErrorContains(t, err, test.errContains)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, test.emission, gcraMicroseconds(r.emission), "%v/%v", test.interval, test.count)
+		assert.Equal(t, test.tolerance, gcraMicroseconds(r.tolerance), "%v/%v", test.interval, test.count)
+	}
+
+	assert.Equal(t, int64(1), gcraMicroseconds(1499*time.Nanosecond))
+	assert.Equal(t, int64(2), gcraMicroseconds(1500*time.Nanosecond))
+}
@@ -0,0 +1,811 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/benthosdev/benthos/v4/internal/checkpoint"
+	"github.com/benthosdev/benthos/v4/internal/shutdown"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// The interval at which the server sends heartbeats when there are no events,
+// which allows us to detect broken connections.
+const mysqlCDCHeartbeatPeriod = 30 * time.Second
+
+func mysqlCDCInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.26.0").
+		Summary("Streams row changes from the binlog of a MySQL server.").
+		Description(`
+Connects to a MySQL server as a replica and creates a message for each row inserted, updated or deleted within a list of tables. The server must have binary logging enabled with `+"`binlog_format=ROW`"+`, and the user must have the `+"`REPLICATION SLAVE`"+` and `+"`REPLICATION CLIENT`"+` privileges as well as access to the tables being captured. In order for messages to contain the full row before updates and deletes the server should use `+"`binlog_row_image=FULL`"+`, which is the default.
+
+Column values are decoded using the current schema of each table, which is obtained from `+"`information_schema`"+` and refreshed whenever a statement that isn't a row change is seen in the binlog. Changes written with a different number of columns than the current schema of a table result in an error, and therefore schema changes should be avoided whilst changes made before them remain unread.
+
+### Message Format
+
+Each message is a JSON object containing the row before and after the change:
+
+`+"```json"+`
+{
+  "before": { "id": 1, "name": "foo" },
+  "after": { "id": 1, "name": "bar" }
+}
+`+"```"+`
+
+The `+"`before`"+` image of inserts and the `+"`after`"+` image of deletes are `+"`null`"+`. Integers, floats and years are decoded into numbers, decimals are decoded into exact numbers, JSON columns are parsed, binary strings are decoded as bytes, `+"`TIMESTAMP`"+` columns are formatted as RFC 3339 strings in UTC, and `+"`DATETIME`, `DATE` and `TIME`"+` columns are formatted as they would be by MySQL.
+
+### Checkpointing
+
+The position of the binlog up to which all changes have been acknowledged is stored within the cache resource `+"[`checkpoint_cache`](#checkpoint_cache)"+`, from which the input resumes when restarted. The position is only advanced past a transaction once all of its changes, and all changes before it, have been acknowledged, and therefore changes are delivered at least once. Binlog positions are specific to a server, and therefore the checkpoint should be removed when switching to a different server.
+
+When there is no checkpoint, changes are streamed from the current end of the binlog, or from the start of the oldest binlog file when `+"[`start_from_oldest`](#start_from_oldest)"+` is set.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- operation
+- database
+- table
+- binlog_file
+- binlog_position
+- gtid
+- timestamp
+`+"```"+`
+
+The operation is one of `+"`insert`, `update` or `delete`"+`. The binlog position is the position of the event containing the change, and the `+"`gtid`"+` field is only added when the server has GTIDs enabled.
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Field(service.NewStringEnumField("driver", "mysql").
+			Description("The database driver to use, which determines the format of the `dsn`. Currently only `mysql` is supported.").
+			Default("mysql").
+			Advanced()).
+		Field(service.NewStringField("dsn").
+			Description("A Data Source Name to identify the target server in the format `[username[:password]@][protocol[(address)]]/dbname[?param1=value1&...&paramN=valueN]`. The database is used for tables that are not qualified with a database name, and TLS is configured with the parameter `tls`.").
+			Example("foouser:foopassword@tcp(localhost:3306)/foodb")).
+		Field(service.NewStringListField("tables").
+			Description("A list of tables to stream changes from, which can be qualified with a database name.").
+			Example([]string{"users", "inventory.products"})).
+		Field(service.NewStringField("checkpoint_cache").
+			Description("A [cache resource](/docs/components/caches/about) to use for storing the binlog position up to which changes have been acknowledged, which allows the input to resume from that position upon restart.").
+			Optional()).
+		Field(service.NewStringField("checkpoint_key").
+			Description("The key to use for storing the binlog position within the `checkpoint_cache`.").
+			Default("mysql_binlog_position").
+			Advanced()).
+		Field(service.NewBoolField("start_from_oldest").
+			Description("Whether to stream changes from the oldest binlog file available rather than the current end of the binlog when there is no checkpoint.").
+			Default(false)).
+		Field(service.NewIntField("server_id").
+			Description("The server ID to connect to the server with, which must be unique amongst all replicas of the server. A random ID is used by default.").
+			Optional().
+			Advanced()).
+		Field(service.NewIntField("max_batch_size").
+			Description("The maximum number of messages within a batch, transactions with more changes than this are split across batches.").
+			Default(500).
+			Advanced()).
+		Field(service.NewIntField("checkpoint_limit").
+			Description("The maximum number of messages that can be pending acknowledgement at any given time, after which the input waits for messages to be acknowledged before streaming more changes.").
+			Default(1024).
+			Advanced()).
+		Example("Replicating Tables",
+			"In this example changes to two tables are streamed into a Kafka topic for each table keyed by the primary key, where the position of the binlog is stored within a Redis cache.",
+			`
+input:
+  mysql_cdc:
+    dsn: replicator:password@tcp(localhost:3306)/shop
+    tables: [ users, orders ]
+    checkpoint_cache: binlog_position
+
+cache_resources:
+  - label: binlog_position
+    redis:
+      url: redis://localhost:6379
+
+output:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topic: 'shop.${! @table }'
+    key: '${! (this.after | this.before).id }'
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchInput(
+		"mysql_cdc", mysqlCDCInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			i, err := newMySQLCDCInputFromConfig(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksBatched(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type mysqlCDCBatch struct {
+	batch service.MessageBatch
+	onAck func(context.Context) error
+}
+
+type mysqlCDCInput struct {
+	driver          string
+	dsn             string
+	mysqlConf       *mysql.Config
+	tables          map[string]struct{}
+	checkpointCache string
+	checkpointKey   string
+	startFromOldest bool
+	serverID        uint32
+	maxBatchSize    int
+	checkpointLimit int
+
+	db      *sql.DB
+	schemas map[string][]binlogColumn
+
+	// The position up to which changes have been acknowledged, and whether it
+	// has been stored within the cache.
+	posMut    sync.Mutex
+	position  *mysqlBinlogPosition
+	posStored bool
+
+	batchChan atomic.Value
+	res       *service.Resources
+	log       *service.Logger
+	shutSig   *shutdown.Signaller
+}
+
+func newMySQLCDCInputFromConfig(conf *service.ParsedConfig, res *service.Resources) (*mysqlCDCInput, error) {
+	m := &mysqlCDCInput{
+		tables:  map[string]struct{}{},
+		res:     res,
+		log:     res.Logger(),
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if m.driver, err = conf.FieldString("driver"); err != nil {
+		return nil, err
+	}
+	if m.dsn, err = conf.FieldString("dsn"); err != nil {
+		return nil, err
+	}
+	if m.mysqlConf, err = mysql.ParseDSN(m.dsn); err != nil {
+		return nil, fmt.Errorf("failed to parse dsn: %w", err)
+	}
+
+	tables, err := conf.FieldStringList("tables")
+	if err != nil {
+		return nil, err
+	}
+	if len(tables) == 0 {
+		return nil, errors.New("at least one table must be specified")
+	}
+	for _, t := range tables {
+		if !strings.Contains(t, ".") {
+			if m.mysqlConf.DBName == "" {
+				return nil, fmt.Errorf("table '%v' must be qualified with a database name as the dsn does not specify a database", t)
+			}
+			t = m.mysqlConf.DBName + "." + t
+		}
+		m.tables[t] = struct{}{}
+	}
+
+	if conf.Contains("checkpoint_cache") {
+		if m.checkpointCache, err = conf.FieldString("checkpoint_cache"); err != nil {
+			return nil, err
+		}
+		if !res.HasCache(m.checkpointCache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", m.checkpointCache)
+		}
+	}
+	if m.checkpointKey, err = conf.FieldString("checkpoint_key"); err != nil {
+		return nil, err
+	}
+	if m.startFromOldest, err = conf.FieldBool("start_from_oldest"); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains("server_id") {
+		id, err := conf.FieldInt("server_id")
+		if err != nil {
+			return nil, err
+		}
+		if id < 1 || id > math.MaxUint32 {
+			return nil, fmt.Errorf("server_id must be between 1 and %v", uint32(math.MaxUint32))
+		}
+		m.serverID = uint32(id)
+	} else {
+		m.serverID = uint32(rand.Int63n(math.MaxUint32-1000)) + 1001
+	}
+
+	if m.maxBatchSize, err = conf.FieldInt("max_batch_size"); err != nil {
+		return nil, err
+	}
+	if m.maxBatchSize < 1 {
+		return nil, errors.New("max_batch_size must be at least 1")
+	}
+	if m.checkpointLimit, err = conf.FieldInt("checkpoint_limit"); err != nil {
+		return nil, err
+	}
+	if m.checkpointLimit < m.maxBatchSize {
+		return nil, errors.New("checkpoint_limit must be at least max_batch_size")
+	}
+	return m, nil
+}
+
+func (m *mysqlCDCInput) getBatchChan() chan mysqlCDCBatch {
+	c, _ := m.batchChan.Load().(chan mysqlCDCBatch)
+	return c
+}
+
+func (m *mysqlCDCInput) storeBatchChan(c chan mysqlCDCBatch) {
+	m.batchChan.Store(c)
+}
+
+// checkServerConfig ensures that the server logs row changes, returning
+// whether events are checksummed.
+func (m *mysqlCDCInput) checkServerConfig(ctx context.Context) (bool, error) {
+	var format, checksum string
+	if err := m.db.QueryRowContext(ctx, "SELECT @@global.binlog_format, @@global.binlog_checksum").Scan(&format, &checksum); err != nil {
+		return false, fmt.Errorf("failed to query binlog configuration: %w", err)
+	}
+	if !strings.EqualFold(format, "ROW") {
+		return false, fmt.Errorf("binlog_format must be ROW, got %v", format)
+	}
+	return strings.EqualFold(checksum, "CRC32"), nil
+}
+
+// queryFirstRow runs a query and returns the first two columns of the first
+// row as strings, returning false if there are no rows.
+func queryFirstRow(ctx context.Context, db *sql.DB, query string) (string, string, bool, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return "", "", false, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", "", false, err
+	}
+	if len(columns) < 2 {
+		return "", "", false, fmt.Errorf("expected at least two columns, got %v", len(columns))
+	}
+	if !rows.Next() {
+		return "", "", false, rows.Err()
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dests := make([]any, len(columns))
+	for i := range values {
+		dests[i] = &values[i]
+	}
+	if err := rows.Scan(dests...); err != nil {
+		return "", "", false, err
+	}
+	return string(values[0]), string(values[1]), true, nil
+}
+
+// startPosition returns the position to stream changes from, which is the
+// latest acknowledged position, the checkpoint stored within the cache, or
+// otherwise a position obtained from the server.
+func (m *mysqlCDCInput) startPosition(ctx context.Context) (mysqlBinlogPosition, error) {
+	m.posMut.Lock()
+	defer m.posMut.Unlock()
+
+	if m.position != nil {
+		return *m.position, nil
+	}
+
+	if m.checkpointCache != "" {
+		var pos *mysqlBinlogPosition
+		var cErr error
+		if err := m.res.AccessCache(ctx, m.checkpointCache, func(c service.Cache) {
+			var posBytes []byte
+			if posBytes, cErr = c.Get(ctx, m.checkpointKey); cErr != nil {
+				if errors.Is(cErr, service.ErrKeyNotFound) {
+					cErr = nil
+				}
+				return
+			}
+			var p mysqlBinlogPosition
+			if p, cErr = parseMySQLBinlogPosition(string(posBytes)); cErr == nil {
+				pos = &p
+			}
+		}); err != nil {
+			return mysqlBinlogPosition{}, fmt.Errorf("failed to access checkpoint cache: %w", err)
+		}
+		if cErr != nil {
+			return mysqlBinlogPosition{}, fmt.Errorf("failed to obtain checkpoint: %w", cErr)
+		}
+		if pos != nil {
+			m.position, m.posStored = pos, true
+			return *pos, nil
+		}
+	}
+
+	var pos mysqlBinlogPosition
+	if m.startFromOldest {
+		file, _, exists, err := queryFirstRow(ctx, m.db, "SHOW BINARY LOGS")
+		if err != nil {
+			return pos, fmt.Errorf("failed to query binlog files: %w", err)
+		}
+		if !exists {
+			return pos, errors.New("binary logging is not enabled on the server")
+		}
+		// Events begin after the magic number at the start of the file.
+		pos = mysqlBinlogPosition{File: file, Pos: 4}
+	} else {
+		// SHOW MASTER STATUS was replaced by SHOW BINARY LOG STATUS in newer
+		// versions of MySQL.
+		file, posStr, exists, err := queryFirstRow(ctx, m.db, "SHOW MASTER STATUS")
+		if err != nil {
+			var sErr error
+			if file, posStr, exists, sErr = queryFirstRow(ctx, m.db, "SHOW BINARY LOG STATUS"); sErr != nil {
+				return pos, fmt.Errorf("failed to query binlog status: %w", err)
+			}
+		}
+		if !exists {
+			return pos, errors.New("binary logging is not enabled on the server")
+		}
+		if pos, err = parseMySQLBinlogPosition(file + ":" + posStr); err != nil {
+			return pos, err
+		}
+	}
+	m.position = &pos
+	return pos, nil
+}
+
+// release releases a checkpoint and stores the resulting position, which is
+// also written to the cache when persist is true. Positions from transactions
+// without captured changes aren't persisted immediately in order to avoid
+// writing to the cache for each of them.
+func (m *mysqlCDCInput) release(ctx context.Context, releaseFn func() *mysqlBinlogPosition, persist bool) error {
+	m.posMut.Lock()
+	defer m.posMut.Unlock()
+
+	highest := releaseFn()
+	if highest == nil {
+		return nil
+	}
+	if m.position != nil && *m.position == *highest {
+		return nil
+	}
+	pos := *highest
+	m.position, m.posStored = &pos, false
+	if persist {
+		return m.storePositionLocked(ctx)
+	}
+	return nil
+}
+
+func (m *mysqlCDCInput) storePositionLocked(ctx context.Context) error {
+	if m.checkpointCache == "" || m.position == nil || m.posStored {
+		return nil
+	}
+	var cErr error
+	if err := m.res.AccessCache(ctx, m.checkpointCache, func(c service.Cache) {
+		cErr = c.Set(ctx, m.checkpointKey, []byte(m.position.String()), nil)
+	}); err != nil {
+		return err
+	}
+	if cErr != nil {
+		return cErr
+	}
+	m.posStored = true
+	return nil
+}
+
+func (m *mysqlCDCInput) Connect(ctx context.Context) error {
+	if m.getBatchChan() != nil {
+		return nil
+	}
+	if m.shutSig.ShouldCloseAtLeisure() {
+		return service.ErrEndOfInput
+	}
+
+	if m.db == nil {
+		db, err := sqlOpenWithReworks(m.log, m.driver, m.dsn)
+		if err != nil {
+			return err
+		}
+		m.db = db
+	}
+
+	checksum, err := m.checkServerConfig(ctx)
+	if err != nil {
+		return err
+	}
+	startPos, err := m.startPosition(ctx)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dialMySQLReplConn(ctx, m.mysqlConf)
+	if err != nil {
+		return fmt.Errorf("failed to connect for replication: %w", err)
+	}
+	for _, stmt := range []string{
+		// Informs the server that we are able to handle checksums.
+		"SET @master_binlog_checksum = @@global.binlog_checksum",
+		fmt.Sprintf("SET @master_heartbeat_period = %v", mysqlCDCHeartbeatPeriod.Nanoseconds()),
+	} {
+		if err := conn.exec(stmt); err != nil {
+			conn.Close()
+			return fmt.Errorf("failed to prepare replication connection: %w", err)
+		}
+	}
+	if err := conn.startBinlogDump(startPos, m.serverID); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start binlog dump: %w", err)
+	}
+
+	m.schemas = map[string][]binlogColumn{}
+
+	batchChan := make(chan mysqlCDCBatch)
+	go func() {
+		defer func() {
+			conn.Close()
+			m.storeBatchChan(nil)
+			close(batchChan)
+			if m.shutSig.ShouldCloseAtLeisure() {
+				m.closeAtShutdown()
+				m.shutSig.ShutdownComplete()
+			}
+		}()
+
+		closeCtx, done := m.shutSig.CloseAtLeisureCtx(context.Background())
+		defer done()
+
+		// Reads from the connection are unblocked by closing it.
+		streamDone := make(chan struct{})
+		defer close(streamDone)
+		go func() {
+			select {
+			case <-closeCtx.Done():
+				conn.Close()
+			case <-streamDone:
+			}
+		}()
+
+		if err := m.streamEvents(closeCtx, conn, startPos, checksum, batchChan); err != nil && closeCtx.Err() == nil {
+			m.log.Errorf("Binlog stream failed: %v", err)
+		}
+	}()
+
+	m.storeBatchChan(batchChan)
+	m.log.Infof("Receiving changes from the binlog starting at %v", startPos)
+	return nil
+}
+
+// closeAtShutdown stores the latest acknowledged position and closes the
+// database.
+func (m *mysqlCDCInput) closeAtShutdown() {
+	m.posMut.Lock()
+	storeCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+	if err := m.storePositionLocked(storeCtx); err != nil {
+		m.log.Errorf("Failed to store binlog position: %v", err)
+	}
+	done()
+	m.posMut.Unlock()
+
+	if m.db != nil {
+		_ = m.db.Close()
+		m.db = nil
+	}
+}
+
+// parseMySQLEnumValues parses the members of an enum or set column type such
+// as enum('a','b').
+func parseMySQLEnumValues(columnType string) []string {
+	start, end := strings.IndexByte(columnType, '('), strings.LastIndexByte(columnType, ')')
+	if start < 0 || end < start {
+		return nil
+	}
+
+	var values []string
+	var current strings.Builder
+	inQuote := false
+	s := columnType[start+1 : end]
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'' && inQuote && i+1 < len(s) && s[i+1] == '\'':
+			current.WriteByte('\'')
+			i++
+		case c == '\'':
+			if inQuote {
+				values = append(values, current.String())
+				current.Reset()
+			}
+			inQuote = !inQuote
+		case inQuote:
+			current.WriteByte(c)
+		}
+	}
+	return values
+}
+
+// tableColumns returns the columns of a table, querying the schema of the
+// table when it isn't cached.
+func (m *mysqlCDCInput) tableColumns(ctx context.Context, schema, table string) ([]binlogColumn, error) {
+	key := schema + "." + table
+	if cols, exists := m.schemas[key]; exists {
+		return cols, nil
+	}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT COLUMN_NAME, DATA_TYPE, COLUMN_TYPE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION", schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema of table %v: %w", key, err)
+	}
+	defer rows.Close()
+
+	var cols []binlogColumn
+	for rows.Next() {
+		var col binlogColumn
+		var columnType string
+		if err := rows.Scan(&col.name, &col.dataType, &columnType); err != nil {
+			return nil, err
+		}
+		col.dataType = strings.ToLower(col.dataType)
+		columnType = strings.ToLower(columnType)
+		col.unsigned = strings.Contains(columnType, "unsigned")
+		if col.dataType == "enum" || col.dataType == "set" {
+			col.values = parseMySQLEnumValues(columnType)
+		}
+		cols = append(cols, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query schema of table %v: %w", key, err)
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("table %v was not found", key)
+	}
+	m.schemas[key] = cols
+	return cols, nil
+}
+
+func anyOrNil(m map[string]any) any {
+	if m == nil {
+		return nil
+	}
+	return m
+}
+
+func newMySQLCDCMessage(operation string, table *binlogTableMap, change binlogRowChange, pos mysqlBinlogPosition, gtid string, timestamp uint32) *service.Message {
+	msg := service.NewMessage(nil)
+	msg.SetStructuredMut(map[string]any{
+		"before": anyOrNil(change.before),
+		"after":  anyOrNil(change.after),
+	})
+	msg.MetaSetMut("operation", operation)
+	msg.MetaSetMut("database", table.schema)
+	msg.MetaSetMut("table", table.table)
+	msg.MetaSetMut("binlog_file", pos.File)
+	msg.MetaSetMut("binlog_position", fmt.Sprintf("%v", pos.Pos))
+	if gtid != "" {
+		msg.MetaSetMut("gtid", gtid)
+	}
+	msg.MetaSetMut("timestamp", time.Unix(int64(timestamp), 0).UTC().Format(time.RFC3339))
+	return msg
+}
+
+// streamEvents streams binlog events until the context is cancelled or an
+// error occurs.
+func (m *mysqlCDCInput) streamEvents(ctx context.Context, conn *mysqlReplConn, pos mysqlBinlogPosition, checksum bool, batchChan chan<- mysqlCDCBatch) error {
+	checkpoints := checkpoint.NewCapped[mysqlBinlogPosition](int64(m.checkpointLimit))
+
+	committed := pos
+	var gtid string
+	var inTxn bool
+	var pending service.MessageBatch
+
+	flush := func(to mysqlBinlogPosition) error {
+		if len(pending) == 0 {
+			return nil
+		}
+		batch := pending
+		pending = nil
+
+		releaseFn, err := checkpoints.Track(ctx, to, int64(len(batch)))
+		if err != nil {
+			return err
+		}
+		select {
+		case batchChan <- mysqlCDCBatch{batch: batch, onAck: func(ctx context.Context) error {
+			return m.release(ctx, releaseFn, true)
+		}}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	commit := func(to mysqlBinlogPosition) error {
+		committed, gtid, inTxn = to, "", false
+		if len(pending) > 0 {
+			return flush(to)
+		}
+		// Transactions without captured changes are tracked in order to
+		// advance the position once prior batches are acknowledged.
+		releaseFn, err := checkpoints.Track(ctx, to, 1)
+		if err != nil {
+			return err
+		}
+		return m.release(ctx, releaseFn, false)
+	}
+
+	tableIDSize := 6
+	tableMaps := map[uint64]*binlogTableMap{}
+	for {
+		data, err := conn.readEvent(2 * mysqlCDCHeartbeatPeriod)
+		if err != nil {
+			return err
+		}
+		header, body, err := parseBinlogEvent(data, checksum)
+		if err != nil {
+			return err
+		}
+
+		// Heartbeats and events generated for the start of the stream do not
+		// represent positions of the binlog.
+		eventPos := pos
+		if header.logPos > 0 && header.eventType != binlogHeartbeatEvent {
+			eventPos.Pos = header.logPos - header.eventSize
+			pos.Pos = header.logPos
+		}
+
+		switch header.eventType {
+		case binlogRotateEvent:
+			if pos, err = parseBinlogRotate(body); err != nil {
+				return err
+			}
+			if !inTxn {
+				if err := commit(pos); err != nil {
+					return err
+				}
+			}
+		case binlogFormatDescriptionEvent:
+			format, err := parseBinlogFormatDescription(body)
+			if err != nil {
+				return err
+			}
+			checksum = format.checksumEnabled
+			tableIDSize = format.tableIDSize()
+		case binlogGTIDEvent:
+			if gtid, err = parseBinlogGTID(body); err != nil {
+				return err
+			}
+		case binlogAnonymousGTIDEvent:
+			gtid = ""
+		case binlogQueryEvent:
+			q, err := parseBinlogQuery(body)
+			if err != nil {
+				return err
+			}
+			switch strings.ToUpper(strings.TrimSpace(q.query)) {
+			case "BEGIN":
+				inTxn = true
+			case "COMMIT":
+				if err := commit(pos); err != nil {
+					return err
+				}
+			default:
+				// Other statements may change the schema of tables, which is
+				// therefore refreshed.
+				m.schemas = map[string][]binlogColumn{}
+				if !inTxn {
+					if err := commit(pos); err != nil {
+						return err
+					}
+				}
+			}
+		case binlogXIDEvent:
+			if err := commit(pos); err != nil {
+				return err
+			}
+		case binlogTableMapEvent:
+			t, err := parseBinlogTableMap(body, tableIDSize)
+			if err != nil {
+				return err
+			}
+			tableMaps[t.id] = t
+		case binlogWriteRowsEventV1, binlogUpdateRowsEventV1, binlogDeleteRowsEventV1,
+			binlogWriteRowsEventV2, binlogUpdateRowsEventV2, binlogDeleteRowsEventV2:
+			e, err := parseBinlogRowsEvent(header.eventType, body, tableIDSize)
+			if err != nil {
+				return err
+			}
+			t, exists := tableMaps[e.tableID]
+			if !exists {
+				return fmt.Errorf("received rows event for unknown table id %v", e.tableID)
+			}
+			if _, captured := m.tables[t.schema+"."+t.table]; !captured {
+				continue
+			}
+			cols, err := m.tableColumns(ctx, t.schema, t.table)
+			if err != nil {
+				return err
+			}
+			changes, err := e.decodeRows(t, cols)
+			if err != nil {
+				return err
+			}
+			for _, c := range changes {
+				pending = append(pending, newMySQLCDCMessage(e.operation, t, c, eventPos, gtid, header.timestamp))
+				if len(pending) >= m.maxBatchSize {
+					// Batches emitted before the end of a transaction only
+					// advance the position to the end of the previous
+					// transaction.
+					if err := flush(committed); err != nil {
+						return err
+					}
+				}
+			}
+		case binlogTransactionPayload:
+			return errors.New("compressed transaction payloads are not supported, binlog_transaction_compression must be disabled")
+		case binlogPartialUpdateRowsEvent:
+			return errors.New("partial JSON updates are not supported, binlog_row_value_options must not include PARTIAL_JSON")
+		}
+	}
+}
+
+func (m *mysqlCDCInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	batchChan := m.getBatchChan()
+	if batchChan == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	var b mysqlCDCBatch
+	var open bool
+	select {
+	case b, open = <-batchChan:
+		if !open {
+			return nil, nil, service.ErrNotConnected
+		}
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	return b.batch, func(ctx context.Context, res error) error {
+		// Res will always be nil because we initialize with service.AutoRetryNacksBatched
+		return b.onAck(ctx)
+	}, nil
+}
+
+func (m *mysqlCDCInput) Close(ctx context.Context) error {
+	go func() {
+		m.shutSig.CloseAtLeisure()
+		if m.getBatchChan() == nil {
+			// If the batch chan is already nil then we might've not been
+			// connected, so force the shutdown complete signal.
+			m.closeAtShutdown()
+			m.shutSig.ShutdownComplete()
+		}
+	}()
+	select {
+	case <-m.shutSig.HasClosedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestMySQLCDCInputConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		conf      string
+		tables    []string
+		serverID  uint32
+		errString string
+	}{
+		{
+			name: "valid",
+			conf: `
+dsn: foouser:foopassword@tcp(localhost:3306)/foodb
+tables: [ foo, bar.baz ]
+server_id: 1234
+`,
+			tables:   []string{"bar.baz", "foodb.foo"},
+			serverID: 1234,
+		},
+		{
+			name: "unqualified table without database",
+			conf: `
+dsn: foouser:foopassword@tcp(localhost:3306)/
+tables: [ foo ]
+`,
+			errString: "table 'foo' must be qualified with a database name as the dsn does not specify a database",
+		},
+		{
+			name: "no tables",
+			conf: `
+dsn: foouser:foopassword@tcp(localhost:3306)/foodb
+tables: []
+`,
+			errString: "at least one table must be specified",
+		},
+		{
+			name: "missing cache",
+			conf: `
+dsn: foouser:foopassword@tcp(localhost:3306)/foodb
+tables: [ foo ]
+checkpoint_cache: nope
+`,
+			errString: "cache resource 'nope' was not found",
+		},
+		{
+			name: "bad server id",
+			conf: `
+dsn: foouser:foopassword@tcp(localhost:3306)/foodb
+tables: [ foo ]
+server_id: 0
+`,
+			errString: "server_id must be between 1 and 4294967295",
+		},
+		{
+			name: "bad batch size",
+			conf: `
+dsn: foouser:foopassword@tcp(localhost:3306)/foodb
+tables: [ foo ]
+max_batch_size: 0
+`,
+			errString: "max_batch_size must be at least 1",
+		},
+		{
+			name: "checkpoint limit below batch size",
+			conf: `
+dsn: foouser:foopassword@tcp(localhost:3306)/foodb
+tables: [ foo ]
+max_batch_size: 100
+checkpoint_limit: 10
+`,
+			errString: "checkpoint_limit must be at least max_batch_size",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := mysqlCDCInputConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			i, err := newMySQLCDCInputFromConfig(conf, service.MockResources())
+			if test.errString != "" {
+				require.EqualError(t, err, test.errString)
+				return
+			}
+			require.NoError(t, err)
+
+			var tables []string
+			for k := range i.tables {
+				tables = append(tables, k)
+			}
+			assert.ElementsMatch(t, test.tables, tables)
+			assert.Equal(t, test.serverID, i.serverID)
+		})
+	}
+}
+
+func TestNewMySQLCDCMessage(t *testing.T) {
+	table := &binlogTableMap{schema: "shop", table: "items"}
+	change := binlogRowChange{
+		before: map[string]any{"id": int64(1), "name": "foo"},
+		after:  map[string]any{"id": int64(1), "name": "bar"},
+	}
+
+	msg := newMySQLCDCMessage("update", table, change, mysqlBinlogPosition{File: "mysql-bin.000003", Pos: 1543}, "3e11fa47-71ca-11e1-9e33-c80aa9429562:23", 1680674828)
+
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"after":{"id":1,"name":"bar"},"before":{"id":1,"name":"foo"}}`, string(b))
+
+	for k, exp := range map[string]string{
+		"operation":       "update",
+		"database":        "shop",
+		"table":           "items",
+		"binlog_file":     "mysql-bin.000003",
+		"binlog_position": "1543",
+		"gtid":            "3e11fa47-71ca-11e1-9e33-c80aa9429562:23",
+		"timestamp":       "2023-04-05T06:07:08Z",
+	} {
+		v, _ := msg.MetaGet(k)
+		assert.Equal(t, exp, v, k)
+	}
+
+	msg = newMySQLCDCMessage("insert", table, binlogRowChange{after: change.after}, mysqlBinlogPosition{File: "mysql-bin.000003", Pos: 4}, "", 0)
+	b, err = msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"after":{"id":1,"name":"bar"},"before":null}`, string(b))
+
+	_, exists := msg.MetaGet("gtid")
+	assert.False(t, exists)
+}
@@ -0,0 +1,174 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/integration"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestIntegrationMySQLCDC(t *testing.T) {
+	integration.CheckSkip(t)
+	t.Parallel()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Skipf("Could not connect to docker: %s", err)
+	}
+	pool.MaxWait = 3 * time.Minute
+
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository:   "mysql",
+		Tag:          "8.0",
+		ExposedPorts: []string{"3306/tcp"},
+		Env: []string{
+			"MYSQL_ROOT_PASSWORD=testpass",
+			"MYSQL_DATABASE=testdb",
+		},
+	})
+	require.NoError(t, err)
+
+	var db *sql.DB
+	t.Cleanup(func() {
+		if err = pool.Purge(resource); err != nil {
+			t.Logf("Failed to clean up docker resource: %s", err)
+		}
+		if db != nil {
+			db.Close()
+		}
+	})
+
+	dsn := fmt.Sprintf("root:testpass@tcp(localhost:%s)/testdb", resource.GetPort("3306/tcp"))
+	require.NoError(t, pool.Retry(func() error {
+		if db, err = sql.Open("mysql", dsn); err != nil {
+			return err
+		}
+		if err = db.Ping(); err != nil {
+			db.Close()
+			db = nil
+			return err
+		}
+		_, err = db.Exec(`CREATE TABLE foo (id INT PRIMARY KEY, name VARCHAR(50), price DECIMAL(6,2), meta JSON)`)
+		return err
+	}))
+
+	exec := func(query string) {
+		t.Helper()
+		_, err := db.Exec(query)
+		require.NoError(t, err)
+	}
+
+	// Changes to other tables are ignored.
+	exec(`CREATE TABLE bar (id INT PRIMARY KEY)`)
+
+	template := fmt.Sprintf(`
+mysql_cdc:
+  dsn: %v
+  tables: [ foo ]
+  checkpoint_cache: foocache
+`, dsn)
+
+	cacheConf := fmt.Sprintf(`
+label: foocache
+file:
+  directory: %v
+`, t.TempDir())
+
+	type change struct {
+		operation string
+		table     string
+		content   string
+	}
+
+	var changes []change
+	var changesMut sync.Mutex
+
+	runStream := func() *service.Stream {
+		t.Helper()
+
+		streamBuilder := service.NewStreamBuilder()
+		require.NoError(t, streamBuilder.SetLoggerYAML(`level: OFF`))
+		require.NoError(t, streamBuilder.AddCacheYAML(cacheConf))
+		require.NoError(t, streamBuilder.AddInputYAML(template))
+		require.NoError(t, streamBuilder.AddBatchConsumerFunc(func(c context.Context, mb service.MessageBatch) error {
+			changesMut.Lock()
+			defer changesMut.Unlock()
+			for _, msg := range mb {
+				msgBytes, err := msg.AsBytes()
+				require.NoError(t, err)
+				op, _ := msg.MetaGet("operation")
+				table, _ := msg.MetaGet("table")
+				changes = append(changes, change{operation: op, table: table, content: string(msgBytes)})
+			}
+			return nil
+		}))
+
+		stream, err := streamBuilder.Build()
+		require.NoError(t, err)
+
+		go func() {
+			assert.NoError(t, stream.Run(context.Background()))
+		}()
+		return stream
+	}
+
+	waitForChanges := func(n int) []change {
+		t.Helper()
+
+		assert.Eventually(t, func() bool {
+			changesMut.Lock()
+			defer changesMut.Unlock()
+			return len(changes) >= n
+		}, time.Second*30, time.Millisecond*100)
+
+		changesMut.Lock()
+		defer changesMut.Unlock()
+		c := changes
+		changes = nil
+		return c
+	}
+
+	stream := runStream()
+
+	// Give the input a moment to begin streaming from the latest position.
+	time.Sleep(time.Second * 2)
+
+	exec(`INSERT INTO bar VALUES (1)`)
+	exec(`INSERT INTO foo VALUES (1, 'foo1', 12.34, '{"n":1}')`)
+	exec(`UPDATE foo SET name = 'bar1' WHERE id = 1`)
+	exec(`DELETE FROM foo WHERE id = 1`)
+
+	assert.Equal(t, []change{
+		{operation: "insert", table: "foo", content: `{"after":{"id":1,"meta":{"n":1},"name":"foo1","price":12.34},"before":null}`},
+		{operation: "update", table: "foo", content: `{"after":{"id":1,"meta":{"n":1},"name":"bar1","price":12.34},"before":{"id":1,"meta":{"n":1},"name":"foo1","price":12.34}}`},
+		{operation: "delete", table: "foo", content: `{"after":null,"before":{"id":1,"meta":{"n":1},"name":"bar1","price":12.34}}`},
+	}, waitForChanges(3))
+
+	require.NoError(t, stream.StopWithin(time.Second*10))
+
+	//--------------------------------------------------------------------------
+
+	// Changes made while stopped are streamed when resuming from the
+	// checkpoint, without a replay of acknowledged changes.
+	exec(`INSERT INTO foo VALUES (2, 'foo2', NULL, NULL)`)
+
+	stream = runStream()
+
+	exec(`INSERT INTO foo VALUES (3, 'foo3', NULL, NULL)`)
+
+	assert.Equal(t, []change{
+		{operation: "insert", table: "foo", content: `{"after":{"id":2,"meta":null,"name":"foo2","price":null},"before":null}`},
+		{operation: "insert", table: "foo", content: `{"after":{"id":3,"meta":null,"name":"foo3","price":null},"before":null}`},
+	}, waitForChanges(2))
+
+	require.NoError(t, stream.StopWithin(time.Second*10))
+}
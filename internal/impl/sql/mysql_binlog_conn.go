@@ -0,0 +1,502 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// The database/sql driver does not expose the replication protocol, and
+// therefore binlog events are streamed over a connection implementing the
+// subset of the MySQL client/server protocol documented at
+// https://dev.mysql.com/doc/dev/mysql-server/latest/PAGE_PROTOCOL.html that
+// is required for authenticating, running statements and dumping the binlog.
+
+const (
+	mysqlClientLongPassword               = 0x00000001
+	mysqlClientLongFlag                   = 0x00000004
+	mysqlClientProtocol41                 = 0x00000200
+	mysqlClientSSL                        = 0x00000800
+	mysqlClientTransactions               = 0x00002000
+	mysqlClientSecureConnection           = 0x00008000
+	mysqlClientPluginAuth                 = 0x00080000
+	mysqlClientPluginAuthLenEncClientData = 0x00200000
+
+	mysqlComQuery      = 0x03
+	mysqlComBinlogDump = 0x12
+
+	mysqlPacketOK        = 0x00
+	mysqlPacketAuthMore  = 0x01
+	mysqlPacketEOF       = 0xfe
+	mysqlPacketErr       = 0xff
+	mysqlMaxPacketSize   = 1<<24 - 1
+	mysqlCharsetUTF8MB4  = 45
+	mysqlCachingSHA2Fast = 3
+	mysqlCachingSHA2Full = 4
+)
+
+// mysqlReplConn is a connection to a MySQL server that is used for streaming
+// binlog events.
+type mysqlReplConn struct {
+	conn net.Conn
+	seq  uint8
+
+	cfg   *mysql.Config
+	isTLS bool
+}
+
+// dialMySQLReplConn connects and authenticates to the server of a parsed DSN.
+func dialMySQLReplConn(ctx context.Context, cfg *mysql.Config) (*mysqlReplConn, error) {
+	var dialer net.Dialer
+	dialer.Timeout = cfg.Timeout
+	conn, err := dialer.DialContext(ctx, cfg.Net, cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &mysqlReplConn{conn: conn, cfg: cfg}
+
+	// The context only applies to the handshake, after which deadlines are
+	// set for each read.
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if err := c.handshake(); err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+	_ = c.conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+func (c *mysqlReplConn) Close() error {
+	return c.conn.Close()
+}
+
+//------------------------------------------------------------------------------
+
+func (c *mysqlReplConn) readPacket() ([]byte, error) {
+	var payload []byte
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(c.conn, header[:]); err != nil {
+			return nil, err
+		}
+		if header[3] != c.seq {
+			return nil, fmt.Errorf("received packet with sequence %v, expected %v", header[3], c.seq)
+		}
+		c.seq++
+
+		n := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+		start := len(payload)
+		payload = append(payload, make([]byte, n)...)
+		if _, err := io.ReadFull(c.conn, payload[start:]); err != nil {
+			return nil, err
+		}
+
+		// Payloads of the maximum size are continued in the next packet.
+		if n < mysqlMaxPacketSize {
+			return payload, nil
+		}
+	}
+}
+
+func (c *mysqlReplConn) writePacket(payload []byte) error {
+	if len(payload) >= mysqlMaxPacketSize {
+		return fmt.Errorf("packet of size %v exceeds the maximum packet size", len(payload))
+	}
+	data := make([]byte, 4, 4+len(payload))
+	data[0] = byte(len(payload))
+	data[1] = byte(len(payload) >> 8)
+	data[2] = byte(len(payload) >> 16)
+	data[3] = c.seq
+	c.seq++
+	_, err := c.conn.Write(append(data, payload...))
+	return err
+}
+
+func (c *mysqlReplConn) writeCommand(payload []byte) error {
+	c.seq = 0
+	return c.writePacket(payload)
+}
+
+// parseMySQLErrPacket parses an ERR packet into a driver error.
+func parseMySQLErrPacket(data []byte) error {
+	if len(data) < 3 || data[0] != mysqlPacketErr {
+		return errors.New("malformed error packet")
+	}
+	mErr := &mysql.MySQLError{Number: binary.LittleEndian.Uint16(data[1:3])}
+	msg := data[3:]
+	if len(msg) >= 6 && msg[0] == '#' {
+		copy(mErr.SQLState[:], msg[1:6])
+		msg = msg[6:]
+	}
+	mErr.Message = string(msg)
+	return mErr
+}
+
+// readOK reads the response to a command that is expected to return an OK
+// packet.
+func (c *mysqlReplConn) readOK() error {
+	data, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return errors.New("received empty packet")
+	}
+	switch data[0] {
+	case mysqlPacketOK:
+		return nil
+	case mysqlPacketErr:
+		return parseMySQLErrPacket(data)
+	}
+	return fmt.Errorf("unexpected response packet type 0x%02x", data[0])
+}
+
+// exec runs a statement that does not return rows.
+func (c *mysqlReplConn) exec(query string) error {
+	if err := c.writeCommand(append([]byte{mysqlComQuery}, query...)); err != nil {
+		return err
+	}
+	return c.readOK()
+}
+
+//------------------------------------------------------------------------------
+
+type mysqlHandshake struct {
+	capabilities uint32
+	authData     []byte
+	authPlugin   string
+}
+
+func parseMySQLHandshake(data []byte) (mysqlHandshake, error) {
+	var h mysqlHandshake
+	if len(data) > 0 && data[0] == mysqlPacketErr {
+		return h, parseMySQLErrPacket(data)
+	}
+
+	r := &binlogReader{data: data}
+	if v := r.uint8(); v != 10 && r.err == nil {
+		return h, fmt.Errorf("unsupported protocol version %v", v)
+	}
+	_ = r.nulString() // Server version
+	_ = r.uint32()    // Connection ID
+	h.authData = append(h.authData, r.next(8)...)
+	_ = r.uint8() // Filler
+	h.capabilities = uint32(r.uint16())
+	_ = r.uint8()  // Character set
+	_ = r.uint16() // Status flags
+	h.capabilities |= uint32(r.uint16()) << 16
+	authDataLen := int(r.uint8())
+	_ = r.next(10) // Reserved
+	if r.err != nil {
+		return h, fmt.Errorf("failed to parse handshake: %w", r.err)
+	}
+
+	for _, required := range []uint32{mysqlClientProtocol41, mysqlClientSecureConnection, mysqlClientPluginAuth} {
+		if h.capabilities&required == 0 {
+			return h, fmt.Errorf("server does not support required capabilities 0x%x", required)
+		}
+	}
+
+	// The second part of the auth data is at least 13 bytes including a null
+	// terminator.
+	if authDataLen -= 8; authDataLen < 13 {
+		authDataLen = 13
+	}
+	h.authData = append(h.authData, r.next(authDataLen)...)
+	h.authData = bytes.TrimSuffix(h.authData, []byte{0})
+
+	// Some servers omit the terminator of the plugin name.
+	if i := bytes.IndexByte(r.data, 0); i >= 0 {
+		h.authPlugin = string(r.data[:i])
+	} else {
+		h.authPlugin = string(r.data)
+	}
+	if r.err != nil {
+		return h, fmt.Errorf("failed to parse handshake: %w", r.err)
+	}
+	return h, nil
+}
+
+func (c *mysqlReplConn) handshake() error {
+	data, err := c.readPacket()
+	if err != nil {
+		return fmt.Errorf("failed to read handshake: %w", err)
+	}
+	h, err := parseMySQLHandshake(data)
+	if err != nil {
+		return err
+	}
+
+	capabilities := uint32(mysqlClientLongPassword | mysqlClientLongFlag | mysqlClientProtocol41 |
+		mysqlClientTransactions | mysqlClientSecureConnection | mysqlClientPluginAuth |
+		mysqlClientPluginAuthLenEncClientData)
+
+	if c.cfg.TLS != nil {
+		if h.capabilities&mysqlClientSSL != 0 {
+			capabilities |= mysqlClientSSL
+			if err := c.writePacket(mysqlHandshakeResponse(capabilities, "", nil, "", true)); err != nil {
+				return err
+			}
+			tlsConn := tls.Client(c.conn, c.cfg.TLS)
+			if err := tlsConn.Handshake(); err != nil {
+				return fmt.Errorf("tls handshake failed: %w", err)
+			}
+			c.conn = tlsConn
+			c.isTLS = true
+		} else if !c.cfg.AllowFallbackToPlaintext {
+			return errors.New("server does not support TLS")
+		}
+	}
+
+	plugin := h.authPlugin
+	authResp, err := c.authResponse(plugin, h.authData)
+	if err != nil {
+		return err
+	}
+	if err := c.writePacket(mysqlHandshakeResponse(capabilities, c.cfg.User, authResp, plugin, false)); err != nil {
+		return err
+	}
+	return c.authenticate(plugin, h.authData)
+}
+
+func mysqlHandshakeResponse(capabilities uint32, user string, authResp []byte, plugin string, sslRequest bool) []byte {
+	data := make([]byte, 32, 64+len(user)+len(authResp)+len(plugin))
+	binary.LittleEndian.PutUint32(data, capabilities)
+	binary.LittleEndian.PutUint32(data[4:], mysqlMaxPacketSize)
+	data[8] = mysqlCharsetUTF8MB4
+	if sslRequest {
+		return data
+	}
+	data = append(data, user...)
+	data = append(data, 0)
+	data = appendLenEncInt(data, uint64(len(authResp)))
+	data = append(data, authResp...)
+	data = append(data, plugin...)
+	return append(data, 0)
+}
+
+func appendLenEncInt(data []byte, v uint64) []byte {
+	switch {
+	case v < 251:
+		return append(data, byte(v))
+	case v < 1<<16:
+		return append(data, 0xfc, byte(v), byte(v>>8))
+	case v < 1<<24:
+		return append(data, 0xfd, byte(v), byte(v>>8), byte(v>>16))
+	}
+	data = append(data, 0xfe)
+	return binary.LittleEndian.AppendUint64(data, v)
+}
+
+// authResponse returns the initial response to an auth challenge for a plugin.
+func (c *mysqlReplConn) authResponse(plugin string, authData []byte) ([]byte, error) {
+	password := c.cfg.Passwd
+	switch plugin {
+	case "mysql_native_password":
+		if password == "" {
+			return nil, nil
+		}
+		return scrambleMySQLNativePassword(authData, password), nil
+	case "caching_sha2_password":
+		if password == "" {
+			return nil, nil
+		}
+		return scrambleMySQLSHA256Password(authData, password), nil
+	case "sha256_password":
+		if password == "" {
+			return []byte{0}, nil
+		}
+		if c.isTLS {
+			return append([]byte(password), 0), nil
+		}
+		// Request the public key of the server.
+		return []byte{1}, nil
+	case "mysql_clear_password":
+		if !c.cfg.AllowCleartextPasswords {
+			return nil, errors.New("cleartext password authentication is not allowed, enable it with the DSN parameter allowCleartextPasswords=true")
+		}
+		return append([]byte(password), 0), nil
+	}
+	return nil, fmt.Errorf("unsupported authentication plugin '%v'", plugin)
+}
+
+// authenticate handles the responses of the server to an auth response until
+// authentication either succeeds or fails.
+func (c *mysqlReplConn) authenticate(plugin string, authData []byte) error {
+	for {
+		data, err := c.readPacket()
+		if err != nil {
+			return fmt.Errorf("failed to read authentication result: %w", err)
+		}
+		if len(data) == 0 {
+			return errors.New("received empty authentication result")
+		}
+
+		var resp []byte
+		switch data[0] {
+		case mysqlPacketOK:
+			return nil
+		case mysqlPacketErr:
+			return parseMySQLErrPacket(data)
+		case mysqlPacketEOF:
+			// The server requests that we switch to a different plugin.
+			r := &binlogReader{data: data[1:]}
+			plugin = r.nulString()
+			if r.err != nil {
+				return fmt.Errorf("failed to parse auth switch request: %w", r.err)
+			}
+			authData = bytes.TrimSuffix(r.data, []byte{0})
+			if resp, err = c.authResponse(plugin, authData); err != nil {
+				return err
+			}
+		case mysqlPacketAuthMore:
+			if resp, err = c.authMoreData(plugin, authData, data[1:]); err != nil {
+				return err
+			}
+			if resp == nil {
+				// Fast authentication succeeded and is followed by an OK.
+				continue
+			}
+		default:
+			return fmt.Errorf("unexpected authentication result packet type 0x%02x", data[0])
+		}
+		if err := c.writePacket(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// authMoreData handles extra data sent by the server during authentication,
+// returning the response to send, if any.
+func (c *mysqlReplConn) authMoreData(plugin string, authData, data []byte) ([]byte, error) {
+	if plugin == "caching_sha2_password" && len(data) == 1 {
+		switch data[0] {
+		case mysqlCachingSHA2Fast:
+			return nil, nil
+		case mysqlCachingSHA2Full:
+			if c.isTLS || c.cfg.Net == "unix" {
+				return append([]byte(c.cfg.Passwd), 0), nil
+			}
+			// Request the public key of the server.
+			return []byte{2}, nil
+		}
+		return nil, fmt.Errorf("unexpected caching_sha2_password state 0x%02x", data[0])
+	}
+	if plugin != "caching_sha2_password" && plugin != "sha256_password" {
+		return nil, fmt.Errorf("unexpected auth data for plugin '%v'", plugin)
+	}
+
+	// Otherwise we have received a public key to encrypt the password with.
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to decode public key of server")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key of server: %w", err)
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("expected RSA public key, got %T", pub)
+	}
+	return encryptMySQLPassword(c.cfg.Passwd, authData, rsaPub)
+}
+
+// scrambleMySQLNativePassword computes
+// SHA1(password) XOR SHA1(challenge + SHA1(SHA1(password))).
+func scrambleMySQLNativePassword(challenge []byte, password string) []byte {
+	if len(challenge) > 20 {
+		challenge = challenge[:20]
+	}
+	stage1 := sha1.Sum([]byte(password))
+	stage2 := sha1.Sum(stage1[:])
+
+	h := sha1.New()
+	h.Write(challenge)
+	h.Write(stage2[:])
+	scramble := h.Sum(nil)
+	for i := range scramble {
+		scramble[i] ^= stage1[i]
+	}
+	return scramble
+}
+
+// scrambleMySQLSHA256Password computes
+// SHA256(password) XOR SHA256(SHA256(SHA256(password)) + challenge).
+func scrambleMySQLSHA256Password(challenge []byte, password string) []byte {
+	stage1 := sha256.Sum256([]byte(password))
+	stage2 := sha256.Sum256(stage1[:])
+
+	h := sha256.New()
+	h.Write(stage2[:])
+	h.Write(challenge)
+	scramble := h.Sum(nil)
+	for i := range scramble {
+		scramble[i] ^= stage1[i]
+	}
+	return scramble
+}
+
+func encryptMySQLPassword(password string, challenge []byte, pub *rsa.PublicKey) ([]byte, error) {
+	plain := append([]byte(password), 0)
+	for i := range plain {
+		plain[i] ^= challenge[i%len(challenge)]
+	}
+	return rsa.EncryptOAEP(sha1.New(), rand.Reader, pub, plain, nil)
+}
+
+//------------------------------------------------------------------------------
+
+// startBinlogDump requests that the server streams binlog events starting from
+// a position, after which events are read with readEvent.
+func (c *mysqlReplConn) startBinlogDump(pos mysqlBinlogPosition, serverID uint32) error {
+	data := make([]byte, 11, 11+len(pos.File))
+	data[0] = mysqlComBinlogDump
+	binary.LittleEndian.PutUint32(data[1:], pos.Pos)
+	binary.LittleEndian.PutUint16(data[5:], 0) // Flags
+	binary.LittleEndian.PutUint32(data[7:], serverID)
+	return c.writeCommand(append(data, pos.File...))
+}
+
+// readEvent reads the next binlog event, blocking until either an event is
+// received or the timeout elapses.
+func (c *mysqlReplConn) readEvent(timeout time.Duration) ([]byte, error) {
+	if timeout > 0 {
+		_ = c.conn.SetReadDeadline(time.Now().Add(timeout))
+	}
+	data, err := c.readPacket()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("received empty packet")
+	}
+	switch data[0] {
+	case mysqlPacketOK:
+		return data[1:], nil
+	case mysqlPacketErr:
+		return nil, parseMySQLErrPacket(data)
+	case mysqlPacketEOF:
+		if len(data) < 9 {
+			return nil, io.EOF
+		}
+	}
+	return nil, fmt.Errorf("unexpected binlog packet type 0x%02x", data[0])
+}
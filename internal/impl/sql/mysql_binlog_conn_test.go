@@ -0,0 +1,250 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMySQLChallenge = []byte("abcdefghijklmnopqrst")
+
+func testMySQLHandshake(plugin string) []byte {
+	capabilities := uint32(mysqlClientProtocol41 | mysqlClientSecureConnection | mysqlClientPluginAuth | mysqlClientLongPassword)
+
+	data := []byte{10}
+	data = append(data, "8.0.32\x00"...)
+	data = binary.LittleEndian.AppendUint32(data, 7) // Connection ID
+	data = append(data, testMySQLChallenge[:8]...)
+	data = append(data, 0)
+	data = binary.LittleEndian.AppendUint16(data, uint16(capabilities))
+	data = append(data, mysqlCharsetUTF8MB4)
+	data = binary.LittleEndian.AppendUint16(data, 2) // Status
+	data = binary.LittleEndian.AppendUint16(data, uint16(capabilities>>16))
+	data = append(data, byte(len(testMySQLChallenge)+1))
+	data = append(data, make([]byte, 10)...)
+	data = append(data, testMySQLChallenge[8:]...)
+	data = append(data, 0)
+	data = append(data, plugin...)
+	return append(data, 0)
+}
+
+type testMySQLHandshakeResponse struct {
+	user     string
+	authResp []byte
+	plugin   string
+}
+
+func parseTestMySQLHandshakeResponse(t *testing.T, data []byte) testMySQLHandshakeResponse {
+	t.Helper()
+
+	r := &binlogReader{data: data}
+	capabilities := r.uint32()
+	assert.NotZero(t, capabilities&mysqlClientProtocol41)
+	_ = r.next(28)
+
+	var resp testMySQLHandshakeResponse
+	resp.user = r.nulString()
+	resp.authResp = r.next(int(r.lenEncInt()))
+	resp.plugin = r.nulString()
+	require.NoError(t, r.err)
+	return resp
+}
+
+// runFakeMySQLServer runs a function acting as the server of a connection,
+// returning the client side of the connection once it has authenticated.
+func runFakeMySQLServer(t *testing.T, password string, serverFn func(s *mysqlReplConn)) (*mysqlReplConn, error) {
+	t.Helper()
+
+	clientConn, serverConn := net.Pipe()
+	t.Cleanup(func() {
+		clientConn.Close()
+		serverConn.Close()
+	})
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		serverFn(&mysqlReplConn{conn: serverConn})
+	}()
+	t.Cleanup(func() {
+		serverConn.Close()
+		<-serverDone
+	})
+
+	c := &mysqlReplConn{
+		conn: clientConn,
+		cfg:  &mysql.Config{User: "foo", Passwd: password, Net: "tcp", Addr: "localhost:3306"},
+	}
+	_ = clientConn.SetDeadline(time.Now().Add(time.Second * 10))
+	if err := c.handshake(); err != nil {
+		return nil, err
+	}
+	_ = clientConn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+func TestMySQLReplConnNativePassword(t *testing.T) {
+	eventData := bytes.Repeat([]byte("x"), 100)
+
+	c, err := runFakeMySQLServer(t, "bar", func(s *mysqlReplConn) {
+		require.NoError(t, s.writePacket(testMySQLHandshake("mysql_native_password")))
+
+		data, err := s.readPacket()
+		require.NoError(t, err)
+		resp := parseTestMySQLHandshakeResponse(t, data)
+		assert.Equal(t, "foo", resp.user)
+		assert.Equal(t, "mysql_native_password", resp.plugin)
+
+		// SHA1(password) XOR SHA1(challenge + SHA1(SHA1(password)))
+		stage1 := sha1.Sum([]byte("bar"))
+		stage2 := sha1.Sum(stage1[:])
+		expected := sha1.Sum(append(append([]byte(nil), testMySQLChallenge...), stage2[:]...))
+		for i := range expected {
+			expected[i] ^= stage1[i]
+		}
+		assert.Equal(t, expected[:], resp.authResp)
+		require.NoError(t, s.writePacket([]byte{mysqlPacketOK, 0, 0, 2, 0, 0, 0}))
+
+		s.seq = 0
+		data, err = s.readPacket()
+		require.NoError(t, err)
+		assert.Equal(t, append([]byte{mysqlComQuery}, "SET @foo = 1"...), data)
+		require.NoError(t, s.writePacket([]byte{mysqlPacketOK, 0, 0, 2, 0, 0, 0}))
+
+		s.seq = 0
+		data, err = s.readPacket()
+		require.NoError(t, err)
+		expectedDump := []byte{mysqlComBinlogDump, 4, 0, 0, 0, 0, 0, 42, 0, 0, 0}
+		assert.Equal(t, append(expectedDump, "mysql-bin.000001"...), data)
+
+		require.NoError(t, s.writePacket(append([]byte{mysqlPacketOK}, eventData...)))
+		require.NoError(t, s.writePacket([]byte{mysqlPacketEOF, 0, 0, 2, 0}))
+		require.NoError(t, s.writePacket(append([]byte{mysqlPacketErr, 0xe9, 0x04}, "#HY000Could not find first log file name in binary log index file"...)))
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, c.exec("SET @foo = 1"))
+	require.NoError(t, c.startBinlogDump(mysqlBinlogPosition{File: "mysql-bin.000001", Pos: 4}, 42))
+
+	event, err := c.readEvent(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, eventData, event)
+
+	_, err = c.readEvent(time.Second)
+	require.ErrorIs(t, err, io.EOF)
+
+	_, err = c.readEvent(time.Second)
+	var mErr *mysql.MySQLError
+	require.ErrorAs(t, err, &mErr)
+	assert.Equal(t, uint16(1257), mErr.Number)
+	assert.Equal(t, "HY000", string(mErr.SQLState[:]))
+	assert.Equal(t, "Could not find first log file name in binary log index file", mErr.Message)
+}
+
+func TestMySQLReplConnCachingSHA2FullAuth(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	pubBytes, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes})
+
+	_, err = runFakeMySQLServer(t, "bar", func(s *mysqlReplConn) {
+		require.NoError(t, s.writePacket(testMySQLHandshake("caching_sha2_password")))
+
+		data, err := s.readPacket()
+		require.NoError(t, err)
+		resp := parseTestMySQLHandshakeResponse(t, data)
+		assert.Equal(t, "caching_sha2_password", resp.plugin)
+		assert.Equal(t, scrambleMySQLSHA256Password(testMySQLChallenge, "bar"), resp.authResp)
+
+		// The password isn't cached and so a full authentication is requested,
+		// where the client requests the public key of the server.
+		require.NoError(t, s.writePacket([]byte{mysqlPacketAuthMore, mysqlCachingSHA2Full}))
+		data, err = s.readPacket()
+		require.NoError(t, err)
+		assert.Equal(t, []byte{2}, data)
+
+		require.NoError(t, s.writePacket(append([]byte{mysqlPacketAuthMore}, pubPEM...)))
+		data, err = s.readPacket()
+		require.NoError(t, err)
+
+		plain, err := rsa.DecryptOAEP(sha1.New(), rand.Reader, key, data, nil)
+		require.NoError(t, err)
+		for i := range plain {
+			plain[i] ^= testMySQLChallenge[i%len(testMySQLChallenge)]
+		}
+		assert.Equal(t, "bar\x00", string(plain))
+		require.NoError(t, s.writePacket([]byte{mysqlPacketOK, 0, 0, 2, 0, 0, 0}))
+	})
+	require.NoError(t, err)
+}
+
+func TestMySQLReplConnAuthSwitch(t *testing.T) {
+	newChallenge := []byte("ABCDEFGHIJKLMNOPQRST")
+
+	_, err := runFakeMySQLServer(t, "bar", func(s *mysqlReplConn) {
+		require.NoError(t, s.writePacket(testMySQLHandshake("caching_sha2_password")))
+		_, err := s.readPacket()
+		require.NoError(t, err)
+
+		switchReq := append([]byte{mysqlPacketEOF}, "mysql_native_password\x00"...)
+		switchReq = append(switchReq, newChallenge...)
+		require.NoError(t, s.writePacket(append(switchReq, 0)))
+
+		data, err := s.readPacket()
+		require.NoError(t, err)
+		assert.Equal(t, scrambleMySQLNativePassword(newChallenge, "bar"), data)
+
+		// Fast authentication is followed by an OK packet.
+		require.NoError(t, s.writePacket([]byte{mysqlPacketOK, 0, 0, 2, 0, 0, 0}))
+	})
+	require.NoError(t, err)
+}
+
+func TestMySQLReplConnAuthFailure(t *testing.T) {
+	_, err := runFakeMySQLServer(t, "bar", func(s *mysqlReplConn) {
+		require.NoError(t, s.writePacket(testMySQLHandshake("caching_sha2_password")))
+		_, err := s.readPacket()
+		require.NoError(t, err)
+		require.NoError(t, s.writePacket([]byte{mysqlPacketAuthMore, mysqlCachingSHA2Fast}))
+		require.NoError(t, s.writePacket(append([]byte{mysqlPacketErr, 0x15, 0x04}, "#28000Access denied for user 'foo'"...)))
+	})
+	require.Error(t, err)
+
+	var mErr *mysql.MySQLError
+	require.ErrorAs(t, err, &mErr)
+	assert.Equal(t, uint16(1045), mErr.Number)
+}
+
+func TestMySQLReplConnUnsupportedPlugin(t *testing.T) {
+	_, err := runFakeMySQLServer(t, "bar", func(s *mysqlReplConn) {
+		require.NoError(t, s.writePacket(testMySQLHandshake("authentication_ldap_sasl_client")))
+	})
+	require.EqualError(t, err, "unsupported authentication plugin 'authentication_ldap_sasl_client'")
+}
+
+func TestDialMySQLReplConnRefused(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	_, err = dialMySQLReplConn(ctx, &mysql.Config{Net: "tcp", Addr: addr})
+	require.Error(t, err)
+}
@@ -0,0 +1,484 @@
+package sql
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+)
+
+// Binlog events are documented at
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_replication_binlog_event.html
+
+const (
+	binlogQueryEvent             = 2
+	binlogRotateEvent            = 4
+	binlogFormatDescriptionEvent = 15
+	binlogXIDEvent               = 16
+	binlogTableMapEvent          = 19
+	binlogWriteRowsEventV1       = 23
+	binlogUpdateRowsEventV1      = 24
+	binlogDeleteRowsEventV1      = 25
+	binlogHeartbeatEvent         = 27
+	binlogWriteRowsEventV2       = 30
+	binlogUpdateRowsEventV2      = 31
+	binlogDeleteRowsEventV2      = 32
+	binlogGTIDEvent              = 33
+	binlogAnonymousGTIDEvent     = 34
+	binlogPartialUpdateRowsEvent = 39
+	binlogTransactionPayload     = 40
+
+	binlogEventHeaderLen = 19
+	binlogChecksumLen    = 4
+	binlogChecksumCRC32  = 1
+)
+
+// mysqlBinlogPosition is a position within the binlog of a server.
+type mysqlBinlogPosition struct {
+	File string
+	Pos  uint32
+}
+
+func (p mysqlBinlogPosition) String() string {
+	return fmt.Sprintf("%v:%v", p.File, p.Pos)
+}
+
+// parseMySQLBinlogPosition parses a position in the form file:position.
+func parseMySQLBinlogPosition(s string) (mysqlBinlogPosition, error) {
+	i := strings.LastIndexByte(s, ':')
+	if i <= 0 {
+		return mysqlBinlogPosition{}, fmt.Errorf("expected binlog position of the form file:position, got '%v'", s)
+	}
+	pos, err := strconv.ParseUint(s[i+1:], 10, 32)
+	if err != nil {
+		return mysqlBinlogPosition{}, fmt.Errorf("failed to parse binlog position '%v': %w", s, err)
+	}
+	return mysqlBinlogPosition{File: s[:i], Pos: uint32(pos)}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// binlogReader reads little endian values from binlog data, where the first
+// error encountered is sticky.
+type binlogReader struct {
+	data []byte
+	err  error
+}
+
+func (r *binlogReader) fail(format string, args ...any) {
+	if r.err == nil {
+		r.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (r *binlogReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.data) < n {
+		r.fail("data truncated, expected %v more bytes but got %v", n, len(r.data))
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *binlogReader) uint8() uint8 {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *binlogReader) uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *binlogReader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *binlogReader) uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+// uintN reads an unsigned little endian integer of n bytes.
+func (r *binlogReader) uintN(n int) uint64 {
+	var v uint64
+	for i, c := range r.next(n) {
+		v |= uint64(c) << (8 * i)
+	}
+	return v
+}
+
+func (r *binlogReader) lenEncInt() uint64 {
+	switch c := r.uint8(); c {
+	case 0xfc:
+		return r.uintN(2)
+	case 0xfd:
+		return r.uintN(3)
+	case 0xfe:
+		return r.uint64()
+	case 0xfb, 0xff:
+		r.fail("unexpected length encoded integer prefix 0x%02x", c)
+		return 0
+	default:
+		return uint64(c)
+	}
+}
+
+func (r *binlogReader) nulString() string {
+	if r.err != nil {
+		return ""
+	}
+	for i, c := range r.data {
+		if c == 0 {
+			s := string(r.data[:i])
+			r.data = r.data[i+1:]
+			return s
+		}
+	}
+	r.fail("data truncated, expected a null terminated string")
+	return ""
+}
+
+// bitmap reads a bitmap of n bits.
+func (r *binlogReader) bitmap(n int) []byte {
+	return r.next((n + 7) / 8)
+}
+
+func bitmapIsSet(bitmap []byte, i int) bool {
+	return bitmap[i/8]&(1<<(uint(i)%8)) != 0
+}
+
+//------------------------------------------------------------------------------
+
+type binlogEventHeader struct {
+	timestamp uint32
+	eventType byte
+	eventSize uint32
+	logPos    uint32 // The position of the next event
+}
+
+// parseBinlogEvent parses the header of an event, returning the body of the
+// event with the checksum removed.
+func parseBinlogEvent(data []byte, checksum bool) (binlogEventHeader, []byte, error) {
+	if len(data) < binlogEventHeaderLen {
+		return binlogEventHeader{}, nil, fmt.Errorf("expected event of at least length %v, got %v", binlogEventHeaderLen, len(data))
+	}
+
+	r := &binlogReader{data: data}
+	var h binlogEventHeader
+	h.timestamp = r.uint32()
+	h.eventType = r.uint8()
+	_ = r.uint32() // Server ID
+	h.eventSize = r.uint32()
+	h.logPos = r.uint32()
+	_ = r.uint16() // Flags
+
+	if int(h.eventSize) != len(data) {
+		return h, nil, fmt.Errorf("event has size %v but header specifies %v", len(data), h.eventSize)
+	}
+
+	body := r.data
+	hasChecksum := checksum
+
+	// Format description events end with the checksum algorithm followed by
+	// space for a checksum regardless of whether checksums are enabled.
+	if h.eventType == binlogFormatDescriptionEvent {
+		if len(body) < binlogChecksumLen+1 {
+			return h, nil, errors.New("format description event is too short")
+		}
+		hasChecksum = true
+		checksum = body[len(body)-binlogChecksumLen-1] == binlogChecksumCRC32
+	}
+	if hasChecksum {
+		if len(body) < binlogChecksumLen {
+			return h, nil, errors.New("event is too short to contain a checksum")
+		}
+		body = body[:len(body)-binlogChecksumLen]
+	}
+	if checksum {
+		expected := binary.LittleEndian.Uint32(data[len(data)-binlogChecksumLen:])
+		if actual := crc32.ChecksumIEEE(data[:len(data)-binlogChecksumLen]); actual != expected {
+			return h, nil, fmt.Errorf("event checksum 0x%08x does not match expected 0x%08x", actual, expected)
+		}
+	}
+	return h, body, nil
+}
+
+type binlogFormatDescription struct {
+	serverVersion   string
+	postHeaderLens  []byte
+	checksumEnabled bool
+}
+
+func parseBinlogFormatDescription(body []byte) (binlogFormatDescription, error) {
+	r := &binlogReader{data: body}
+	var f binlogFormatDescription
+	if v := r.uint16(); v != 4 && r.err == nil {
+		return f, fmt.Errorf("unsupported binlog version %v", v)
+	}
+	f.serverVersion = strings.TrimRight(string(r.next(50)), "\x00")
+	_ = r.uint32() // Creation timestamp
+	if l := r.uint8(); l != binlogEventHeaderLen && r.err == nil {
+		return f, fmt.Errorf("unsupported event header length %v", l)
+	}
+	if r.err != nil {
+		return f, fmt.Errorf("failed to parse format description: %w", r.err)
+	}
+
+	// Servers supporting checksums end the event with the checksum algorithm,
+	// where the checksum itself has already been removed from the body.
+	f.postHeaderLens = r.data
+	if len(f.postHeaderLens) > 0 {
+		f.checksumEnabled = f.postHeaderLens[len(f.postHeaderLens)-1] == binlogChecksumCRC32
+		f.postHeaderLens = f.postHeaderLens[:len(f.postHeaderLens)-1]
+	}
+	return f, nil
+}
+
+// tableIDSize returns the number of bytes used to encode table IDs, which is
+// determined by the post header length of table map events.
+func (f binlogFormatDescription) tableIDSize() int {
+	if len(f.postHeaderLens) >= binlogTableMapEvent && f.postHeaderLens[binlogTableMapEvent-1] == 6 {
+		return 4
+	}
+	return 6
+}
+
+func parseBinlogRotate(body []byte) (mysqlBinlogPosition, error) {
+	r := &binlogReader{data: body}
+	pos := r.uint64()
+	if r.err != nil {
+		return mysqlBinlogPosition{}, fmt.Errorf("failed to parse rotate event: %w", r.err)
+	}
+	return mysqlBinlogPosition{File: string(r.data), Pos: uint32(pos)}, nil
+}
+
+type binlogQuery struct {
+	schema string
+	query  string
+}
+
+func parseBinlogQuery(body []byte) (binlogQuery, error) {
+	r := &binlogReader{data: body}
+	_ = r.uint32() // Thread ID
+	_ = r.uint32() // Execution time
+	schemaLen := int(r.uint8())
+	_ = r.uint16() // Error code
+	_ = r.next(int(r.uint16()))
+	var q binlogQuery
+	q.schema = string(r.next(schemaLen))
+	_ = r.uint8()
+	if r.err != nil {
+		return q, fmt.Errorf("failed to parse query event: %w", r.err)
+	}
+	q.query = string(r.data)
+	return q, nil
+}
+
+// parseBinlogGTID parses a GTID event into the form source_id:transaction_id.
+func parseBinlogGTID(body []byte) (string, error) {
+	r := &binlogReader{data: body}
+	_ = r.uint8() // Flags
+	sid := r.next(16)
+	gno := r.uint64()
+	if r.err != nil {
+		return "", fmt.Errorf("failed to parse gtid event: %w", r.err)
+	}
+	u := hex.EncodeToString(sid)
+	return fmt.Sprintf("%v-%v-%v-%v-%v:%v", u[:8], u[8:12], u[12:16], u[16:20], u[20:], gno), nil
+}
+
+//------------------------------------------------------------------------------
+
+// binlogColumn describes a column of a table as obtained from the schema of
+// the table, which is required for decoding values as binlog events only
+// describe the storage types of columns.
+type binlogColumn struct {
+	name     string
+	dataType string
+	unsigned bool
+	values   []string // The members of enum and set columns
+}
+
+type binlogTableMap struct {
+	id          uint64
+	schema      string
+	table       string
+	columnTypes []byte
+	columnMeta  []uint16
+}
+
+func parseBinlogTableMap(body []byte, tableIDSize int) (*binlogTableMap, error) {
+	r := &binlogReader{data: body}
+	t := &binlogTableMap{}
+	t.id = r.uintN(tableIDSize)
+	_ = r.uint16() // Flags
+	t.schema = string(r.next(int(r.uint8())))
+	_ = r.uint8()
+	t.table = string(r.next(int(r.uint8())))
+	_ = r.uint8()
+
+	columnCount := int(r.lenEncInt())
+	t.columnTypes = r.next(columnCount)
+
+	meta := &binlogReader{data: r.next(int(r.lenEncInt()))}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to parse table map event: %w", r.err)
+	}
+
+	t.columnMeta = make([]uint16, columnCount)
+	for i, ct := range t.columnTypes {
+		switch ct {
+		case mysqlTypeFloat, mysqlTypeDouble, mysqlTypeBlob, mysqlTypeGeometry, mysqlTypeJSON,
+			mysqlTypeTimestamp2, mysqlTypeDatetime2, mysqlTypeTime2:
+			t.columnMeta[i] = uint16(meta.uint8())
+		case mysqlTypeVarchar, mysqlTypeVarString, mysqlTypeBit:
+			t.columnMeta[i] = meta.uint16()
+		case mysqlTypeNewDecimal, mysqlTypeString, mysqlTypeEnum, mysqlTypeSet:
+			// These are stored big endian.
+			b := meta.next(2)
+			if b != nil {
+				t.columnMeta[i] = uint16(b[0])<<8 | uint16(b[1])
+			}
+		}
+	}
+	if meta.err != nil {
+		return nil, fmt.Errorf("failed to parse table map column metadata: %w", meta.err)
+	}
+	return t, nil
+}
+
+// binlogRowChange is the before and after image of a row, where columns
+// that were not included within the image are omitted.
+type binlogRowChange struct {
+	before map[string]any
+	after  map[string]any
+}
+
+type binlogRowsEvent struct {
+	tableID   uint64
+	operation string
+	body      *binlogReader
+
+	columnCount   int
+	beforeColumns []byte
+	afterColumns  []byte
+}
+
+// parseBinlogRowsEvent parses the header of a rows event, the rows of which
+// can be decoded once the table is known with decodeRows.
+func parseBinlogRowsEvent(eventType byte, body []byte, tableIDSize int) (*binlogRowsEvent, error) {
+	e := &binlogRowsEvent{body: &binlogReader{data: body}}
+	switch eventType {
+	case binlogWriteRowsEventV1, binlogWriteRowsEventV2:
+		e.operation = "insert"
+	case binlogUpdateRowsEventV1, binlogUpdateRowsEventV2:
+		e.operation = "update"
+	case binlogDeleteRowsEventV1, binlogDeleteRowsEventV2:
+		e.operation = "delete"
+	default:
+		return nil, fmt.Errorf("unsupported rows event type %v", eventType)
+	}
+
+	r := e.body
+	e.tableID = r.uintN(tableIDSize)
+	_ = r.uint16() // Flags
+	if eventType >= binlogWriteRowsEventV2 {
+		// The length of extra data includes the length itself.
+		_ = r.next(int(r.uint16()) - 2)
+	}
+	e.columnCount = int(r.lenEncInt())
+	switch e.operation {
+	case "insert":
+		e.afterColumns = r.bitmap(e.columnCount)
+	case "delete":
+		e.beforeColumns = r.bitmap(e.columnCount)
+	case "update":
+		e.beforeColumns = r.bitmap(e.columnCount)
+		e.afterColumns = r.bitmap(e.columnCount)
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to parse rows event: %w", r.err)
+	}
+	return e, nil
+}
+
+// decodeRows decodes the rows of the event using the table map and the
+// columns of the table.
+func (e *binlogRowsEvent) decodeRows(table *binlogTableMap, columns []binlogColumn) ([]binlogRowChange, error) {
+	if e.columnCount != len(table.columnTypes) {
+		return nil, fmt.Errorf("rows event has %v columns but table map of %v.%v has %v", e.columnCount, table.schema, table.table, len(table.columnTypes))
+	}
+	if len(columns) != e.columnCount {
+		return nil, fmt.Errorf("binlog rows of %v.%v have %v columns but the table has %v, the schema of the table may have changed since the rows were written", table.schema, table.table, e.columnCount, len(columns))
+	}
+
+	var changes []binlogRowChange
+	for r := e.body; len(r.data) > 0; {
+		var c binlogRowChange
+		var err error
+		if e.beforeColumns != nil {
+			if c.before, err = e.decodeRow(r, e.beforeColumns, table, columns); err != nil {
+				return nil, err
+			}
+		}
+		if e.afterColumns != nil {
+			if c.after, err = e.decodeRow(r, e.afterColumns, table, columns); err != nil {
+				return nil, err
+			}
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+func (e *binlogRowsEvent) decodeRow(r *binlogReader, present []byte, table *binlogTableMap, columns []binlogColumn) (map[string]any, error) {
+	var presentCount int
+	for i := 0; i < e.columnCount; i++ {
+		if bitmapIsSet(present, i) {
+			presentCount++
+		}
+	}
+
+	// The null bitmap only contains bits for columns that are present.
+	nulls := r.bitmap(presentCount)
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to parse row: %w", r.err)
+	}
+
+	row := make(map[string]any, presentCount)
+	var n int
+	for i := 0; i < e.columnCount; i++ {
+		if !bitmapIsSet(present, i) {
+			continue
+		}
+		isNull := bitmapIsSet(nulls, n)
+		n++
+		if isNull {
+			row[columns[i].name] = nil
+			continue
+		}
+		v, err := decodeBinlogValue(r, table.columnTypes[i], table.columnMeta[i], columns[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode column %v of %v.%v: %w", columns[i].name, table.schema, table.table, err)
+		}
+		row[columns[i].name] = v
+	}
+	return row, nil
+}
@@ -0,0 +1,288 @@
+package sql
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBinlogEvent(eventType byte, logPos uint32, body []byte, checksum bool) []byte {
+	size := binlogEventHeaderLen + len(body)
+	if checksum {
+		size += binlogChecksumLen
+	}
+	data := make([]byte, binlogEventHeaderLen, size)
+	binary.LittleEndian.PutUint32(data, 1680674828)
+	data[4] = eventType
+	binary.LittleEndian.PutUint32(data[5:], 1)
+	binary.LittleEndian.PutUint32(data[9:], uint32(size))
+	binary.LittleEndian.PutUint32(data[13:], logPos)
+	data = append(data, body...)
+	if checksum {
+		data = binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(data))
+	}
+	return data
+}
+
+func TestMySQLBinlogPosition(t *testing.T) {
+	pos, err := parseMySQLBinlogPosition("mysql-bin.000003:1543")
+	require.NoError(t, err)
+	assert.Equal(t, mysqlBinlogPosition{File: "mysql-bin.000003", Pos: 1543}, pos)
+	assert.Equal(t, "mysql-bin.000003:1543", pos.String())
+
+	for _, bad := range []string{"", "mysql-bin.000003", ":4", "mysql-bin.000003:foo", "mysql-bin.000003:-1"} {
+		_, err := parseMySQLBinlogPosition(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestParseBinlogEvent(t *testing.T) {
+	body := []byte("hello world")
+
+	header, parsed, err := parseBinlogEvent(testBinlogEvent(binlogQueryEvent, 100, body, false), false)
+	require.NoError(t, err)
+	assert.Equal(t, binlogEventHeader{
+		timestamp: 1680674828,
+		eventType: binlogQueryEvent,
+		eventSize: uint32(binlogEventHeaderLen + len(body)),
+		logPos:    100,
+	}, header)
+	assert.Equal(t, body, parsed)
+
+	data := testBinlogEvent(binlogQueryEvent, 100, body, true)
+	_, parsed, err = parseBinlogEvent(data, true)
+	require.NoError(t, err)
+	assert.Equal(t, body, parsed)
+
+	data[binlogEventHeaderLen] = 'j'
+	_, _, err = parseBinlogEvent(data, true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match")
+
+	_, _, err = parseBinlogEvent(data[:10], true)
+	require.Error(t, err)
+
+	_, _, err = parseBinlogEvent(data[:len(data)-1], true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "header specifies")
+}
+
+func testFormatDescriptionBody(alg byte) []byte {
+	body := binary.LittleEndian.AppendUint16(nil, 4)
+	version := make([]byte, 50)
+	copy(version, "8.0.32")
+	body = append(body, version...)
+	body = binary.LittleEndian.AppendUint32(body, 0)
+	body = append(body, binlogEventHeaderLen)
+	postHeaderLens := make([]byte, 40)
+	postHeaderLens[binlogTableMapEvent-1] = 8
+	body = append(body, postHeaderLens...)
+	return append(body, alg)
+}
+
+func TestParseBinlogFormatDescription(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		alg      byte
+		checksum bool
+	}{
+		{name: "crc32", alg: binlogChecksumCRC32, checksum: true},
+		{name: "off", alg: 0, checksum: false},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// The event is always followed by space for a checksum, regardless
+			// of whether checksums were previously enabled.
+			data := testBinlogEvent(binlogFormatDescriptionEvent, 0, testFormatDescriptionBody(test.alg), true)
+			_, body, err := parseBinlogEvent(data, !test.checksum)
+			require.NoError(t, err)
+
+			format, err := parseBinlogFormatDescription(body)
+			require.NoError(t, err)
+			assert.Equal(t, "8.0.32", format.serverVersion)
+			assert.Equal(t, test.checksum, format.checksumEnabled)
+			assert.Equal(t, 6, format.tableIDSize())
+		})
+	}
+
+	_, err := parseBinlogFormatDescription([]byte{3, 0})
+	require.EqualError(t, err, "unsupported binlog version 3")
+}
+
+func TestParseBinlogRotateQueryGTID(t *testing.T) {
+	pos, err := parseBinlogRotate(append(binary.LittleEndian.AppendUint64(nil, 4), "mysql-bin.000004"...))
+	require.NoError(t, err)
+	assert.Equal(t, mysqlBinlogPosition{File: "mysql-bin.000004", Pos: 4}, pos)
+
+	_, err = parseBinlogRotate([]byte{1, 2})
+	require.Error(t, err)
+
+	queryBody := make([]byte, 13)
+	queryBody[8] = 4                                  // Schema length
+	binary.LittleEndian.PutUint16(queryBody[11:], 3)  // Status vars length
+	queryBody = append(queryBody, 0x01, 0x02, 0x03)   // Status vars
+	queryBody = append(queryBody, "shop\x00BEGIN"...) // Schema and query
+	q, err := parseBinlogQuery(queryBody)
+	require.NoError(t, err)
+	assert.Equal(t, binlogQuery{schema: "shop", query: "BEGIN"}, q)
+
+	gtidBody := []byte{0x01}
+	gtidBody = append(gtidBody, 0x3e, 0x11, 0xfa, 0x47, 0x71, 0xca, 0x11, 0xe1, 0x9e, 0x33, 0xc8, 0x0a, 0xa9, 0x42, 0x95, 0x62)
+	gtidBody = binary.LittleEndian.AppendUint64(gtidBody, 23)
+	gtid, err := parseBinlogGTID(gtidBody)
+	require.NoError(t, err)
+	assert.Equal(t, "3e11fa47-71ca-11e1-9e33-c80aa9429562:23", gtid)
+}
+
+func testTableMapBody() []byte {
+	body := []byte{42, 0, 0, 0, 0, 0} // Table ID
+	body = append(body, 0, 0)         // Flags
+	body = append(body, 4)
+	body = append(body, "shop\x00"...)
+	body = append(body, 5)
+	body = append(body, "items\x00"...)
+	body = append(body, 4) // Column count
+	body = append(body, mysqlTypeLong, mysqlTypeVarchar, mysqlTypeNewDecimal, mysqlTypeTiny)
+	body = append(body, 4)      // Metadata length
+	body = append(body, 40, 0)  // Varchar max length
+	body = append(body, 5, 2)   // Decimal precision and scale
+	body = append(body, 0b1110) // Null bitmap
+	return body
+}
+
+var testItemColumns = []binlogColumn{
+	{name: "id", dataType: "int"},
+	{name: "name", dataType: "varchar"},
+	{name: "price", dataType: "decimal"},
+	{name: "stock", dataType: "tinyint", unsigned: true},
+}
+
+func TestParseBinlogTableMap(t *testing.T) {
+	table, err := parseBinlogTableMap(testTableMapBody(), 6)
+	require.NoError(t, err)
+	assert.Equal(t, &binlogTableMap{
+		id:          42,
+		schema:      "shop",
+		table:       "items",
+		columnTypes: []byte{mysqlTypeLong, mysqlTypeVarchar, mysqlTypeNewDecimal, mysqlTypeTiny},
+		columnMeta:  []uint16{0, 40, 5<<8 | 2, 0},
+	}, table)
+
+	_, err = parseBinlogTableMap(testTableMapBody()[:20], 6)
+	require.Error(t, err)
+}
+
+func testRowsEventHeader(columnBitmaps int) []byte {
+	body := []byte{42, 0, 0, 0, 0, 0} // Table ID
+	body = append(body, 0, 0)         // Flags
+	body = append(body, 2, 0)         // Extra data length
+	body = append(body, 4)            // Column count
+	for i := 0; i < columnBitmaps; i++ {
+		body = append(body, 0b1111)
+	}
+	return body
+}
+
+func TestBinlogRowsEvents(t *testing.T) {
+	table, err := parseBinlogTableMap(testTableMapBody(), 6)
+	require.NoError(t, err)
+
+	row1 := []byte{0b0000}
+	row1 = binary.LittleEndian.AppendUint32(row1, 1)
+	row1 = append(row1, 3, 'f', 'o', 'o')
+	row1 = append(row1, 0x80, 0x0c, 0x22) // 12.34
+	row1 = append(row1, 0xff)
+
+	row2 := []byte{0b0010}
+	row2 = binary.LittleEndian.AppendUint32(row2, 2)
+	row2 = append(row2, 0x7f, 0xf3, 0xdd) // -12.34
+	row2 = append(row2, 0x00)
+
+	item1 := map[string]any{"id": int64(1), "name": "foo", "price": json.Number("12.34"), "stock": int64(255)}
+	item2 := map[string]any{"id": int64(2), "name": nil, "price": json.Number("-12.34"), "stock": int64(0)}
+
+	tests := []struct {
+		name      string
+		eventType byte
+		body      []byte
+		operation string
+		changes   []binlogRowChange
+	}{
+		{
+			name:      "insert",
+			eventType: binlogWriteRowsEventV2,
+			body:      append(append(testRowsEventHeader(1), row1...), row2...),
+			operation: "insert",
+			changes:   []binlogRowChange{{after: item1}, {after: item2}},
+		},
+		{
+			name:      "update",
+			eventType: binlogUpdateRowsEventV2,
+			body:      append(append(testRowsEventHeader(2), row1...), row2...),
+			operation: "update",
+			changes:   []binlogRowChange{{before: item1, after: item2}},
+		},
+		{
+			name:      "delete",
+			eventType: binlogDeleteRowsEventV2,
+			body:      append(testRowsEventHeader(1), row2...),
+			operation: "delete",
+			changes:   []binlogRowChange{{before: item2}},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			e, err := parseBinlogRowsEvent(test.eventType, test.body, 6)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(42), e.tableID)
+			assert.Equal(t, test.operation, e.operation)
+
+			changes, err := e.decodeRows(table, testItemColumns)
+			require.NoError(t, err)
+			assert.Equal(t, test.changes, changes)
+		})
+	}
+}
+
+func TestBinlogRowsEventPartialImage(t *testing.T) {
+	table, err := parseBinlogTableMap(testTableMapBody(), 6)
+	require.NoError(t, err)
+
+	// With a minimal row image only the primary key is included.
+	body := []byte{42, 0, 0, 0, 0, 0, 0, 0}
+	body = append(body, 4, 0b0001) // Column count and bitmap, v1 has no extra data
+	body = append(body, 0b0)
+	body = binary.LittleEndian.AppendUint32(body, 7)
+
+	e, err := parseBinlogRowsEvent(binlogDeleteRowsEventV1, body, 6)
+	require.NoError(t, err)
+
+	changes, err := e.decodeRows(table, testItemColumns)
+	require.NoError(t, err)
+	assert.Equal(t, []binlogRowChange{{before: map[string]any{"id": int64(7)}}}, changes)
+}
+
+func TestBinlogRowsEventErrors(t *testing.T) {
+	table, err := parseBinlogTableMap(testTableMapBody(), 6)
+	require.NoError(t, err)
+
+	e, err := parseBinlogRowsEvent(binlogWriteRowsEventV2, append(testRowsEventHeader(1), 0b0000, 1, 0), 6)
+	require.NoError(t, err)
+	_, err = e.decodeRows(table, testItemColumns)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decode column id of shop.items")
+
+	e, err = parseBinlogRowsEvent(binlogWriteRowsEventV2, testRowsEventHeader(1), 6)
+	require.NoError(t, err)
+	_, err = e.decodeRows(table, testItemColumns[:3])
+	require.EqualError(t, err, "binlog rows of shop.items have 4 columns but the table has 3, the schema of the table may have changed since the rows were written")
+
+	_, err = parseBinlogRowsEvent(binlogQueryEvent, nil, 6)
+	require.Error(t, err)
+}
@@ -0,0 +1,579 @@
+package sql
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Column types as they appear within table map events.
+const (
+	mysqlTypeTiny       = 1
+	mysqlTypeShort      = 2
+	mysqlTypeLong       = 3
+	mysqlTypeFloat      = 4
+	mysqlTypeDouble     = 5
+	mysqlTypeTimestamp  = 7
+	mysqlTypeLongLong   = 8
+	mysqlTypeInt24      = 9
+	mysqlTypeDate       = 10
+	mysqlTypeTime       = 11
+	mysqlTypeDatetime   = 12
+	mysqlTypeYear       = 13
+	mysqlTypeVarchar    = 15
+	mysqlTypeBit        = 16
+	mysqlTypeTimestamp2 = 17
+	mysqlTypeDatetime2  = 18
+	mysqlTypeTime2      = 19
+	mysqlTypeJSON       = 245
+	mysqlTypeNewDecimal = 246
+	mysqlTypeEnum       = 247
+	mysqlTypeSet        = 248
+	mysqlTypeBlob       = 252
+	mysqlTypeVarString  = 253
+	mysqlTypeString     = 254
+	mysqlTypeGeometry   = 255
+)
+
+// decodeBinlogValue decodes a non-null value of a column from a rows event.
+//
+// Integers, floats and years are decoded as numbers, decimals as exact
+// numbers, JSON columns are parsed, binary strings are decoded as bytes and
+// temporal types are formatted as strings.
+func decodeBinlogValue(r *binlogReader, colType byte, meta uint16, col binlogColumn) (any, error) {
+	var v any
+	switch colType {
+	case mysqlTypeTiny:
+		v = decodeBinlogInt(r.uintN(1), 1, col.unsigned)
+	case mysqlTypeShort:
+		v = decodeBinlogInt(r.uintN(2), 2, col.unsigned)
+	case mysqlTypeInt24:
+		v = decodeBinlogInt(r.uintN(3), 3, col.unsigned)
+	case mysqlTypeLong:
+		v = decodeBinlogInt(r.uintN(4), 4, col.unsigned)
+	case mysqlTypeLongLong:
+		v = decodeBinlogInt(r.uintN(8), 8, col.unsigned)
+	case mysqlTypeFloat:
+		// Floats are formatted with the precision of a float32 in order to
+		// avoid representation artifacts such as 1.100000023841858.
+		f := math.Float32frombits(r.uint32())
+		v, _ = strconv.ParseFloat(strconv.FormatFloat(float64(f), 'g', -1, 32), 64)
+	case mysqlTypeDouble:
+		v = math.Float64frombits(r.uint64())
+	case mysqlTypeYear:
+		year := int64(r.uint8())
+		if year > 0 {
+			year += 1900
+		}
+		v = year
+	case mysqlTypeNewDecimal:
+		d, err := decodeBinlogDecimal(r, int(meta>>8), int(meta&0xff))
+		if err != nil {
+			return nil, err
+		}
+		v = d
+	case mysqlTypeDate:
+		d := r.uintN(3)
+		v = fmt.Sprintf("%04d-%02d-%02d", d>>9, (d>>5)&15, d&31)
+	case mysqlTypeTime:
+		t := int64(r.uintN(3))
+		if t&0x800000 != 0 {
+			t -= 1 << 24
+		}
+		sign := ""
+		if t < 0 {
+			sign, t = "-", -t
+		}
+		v = fmt.Sprintf("%v%02d:%02d:%02d", sign, t/10000, (t%10000)/100, t%100)
+	case mysqlTypeDatetime:
+		dt := r.uint64()
+		d, t := dt/1000000, dt%1000000
+		v = fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", d/10000, (d%10000)/100, d%100, t/10000, (t%10000)/100, t%100)
+	case mysqlTypeTimestamp:
+		v = formatBinlogTimestamp(int64(r.uint32()), 0, 0)
+	case mysqlTypeDatetime2:
+		intPart := int64(readBinlogUintBE(r, 5)) - 0x8000000000
+		frac := readBinlogFraction(r, int(meta))
+		v = formatBinlogDatetime(intPart, frac, int(meta))
+	case mysqlTypeTimestamp2:
+		secs := int64(readBinlogUintBE(r, 4))
+		v = formatBinlogTimestamp(secs, readBinlogFraction(r, int(meta)), int(meta))
+	case mysqlTypeTime2:
+		v = decodeBinlogTime2(r, int(meta))
+	case mysqlTypeVarchar, mysqlTypeVarString:
+		n := 1
+		if meta > 255 {
+			n = 2
+		}
+		v = binlogStringOrBytes(r.next(int(r.uintN(n))), col)
+	case mysqlTypeString, mysqlTypeEnum, mysqlTypeSet:
+		// The real type and length of strings are packed into the metadata,
+		// where the length may use bits of the first byte.
+		realType, length := byte(meta>>8), int(meta&0xff)
+		if realType&0x30 != 0x30 {
+			length |= int((realType&0x30)^0x30) << 4
+			realType |= 0x30
+		}
+		switch realType {
+		case mysqlTypeEnum:
+			i := int(r.uintN(length))
+			switch {
+			case i == 0:
+				v = ""
+			case i <= len(col.values):
+				v = col.values[i-1]
+			default:
+				return nil, fmt.Errorf("enum index %v out of range of %v values", i, len(col.values))
+			}
+		case mysqlTypeSet:
+			bits := r.uintN(length)
+			members := []string{}
+			for i, m := range col.values {
+				if bits&(1<<uint(i)) != 0 {
+					members = append(members, m)
+				}
+			}
+			v = strings.Join(members, ",")
+		default:
+			n := 1
+			if length > 255 {
+				n = 2
+			}
+			v = binlogStringOrBytes(r.next(int(r.uintN(n))), col)
+		}
+	case mysqlTypeBit:
+		nBits := int(meta>>8)*8 + int(meta&0xff)
+		v = readBinlogUintBE(r, (nBits+7)/8)
+	case mysqlTypeBlob, mysqlTypeGeometry:
+		b := r.next(int(r.uintN(int(meta))))
+		if colType == mysqlTypeGeometry {
+			v = append([]byte(nil), b...)
+		} else {
+			v = binlogStringOrBytes(b, col)
+		}
+	case mysqlTypeJSON:
+		b := r.next(int(r.uintN(int(meta))))
+		if r.err != nil {
+			break
+		}
+		j, err := decodeBinlogJSON(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode json: %w", err)
+		}
+		v = j
+	default:
+		return nil, fmt.Errorf("unsupported column type %v", colType)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return v, nil
+}
+
+func decodeBinlogInt(v uint64, size int, unsigned bool) any {
+	if unsigned {
+		if size == 8 {
+			return v
+		}
+		return int64(v)
+	}
+	// Sign extend from the size of the integer.
+	shift := 64 - 8*uint(size)
+	return int64(v<<shift) >> shift
+}
+
+// binlogStringOrBytes returns the value of a string column as a string,
+// unless the column stores binary data.
+func binlogStringOrBytes(b []byte, col binlogColumn) any {
+	switch col.dataType {
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		return append([]byte(nil), b...)
+	}
+	return string(b)
+}
+
+func readBinlogUintBE(r *binlogReader, n int) uint64 {
+	var v uint64
+	for _, c := range r.next(n) {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// readBinlogFraction reads the fractional seconds of a temporal value as
+// microseconds, where the storage size depends on the precision.
+func readBinlogFraction(r *binlogReader, fsp int) int64 {
+	switch fsp {
+	case 1, 2:
+		return int64(readBinlogUintBE(r, 1)) * 10000
+	case 3, 4:
+		return int64(readBinlogUintBE(r, 2)) * 100
+	case 5, 6:
+		return int64(readBinlogUintBE(r, 3))
+	}
+	return 0
+}
+
+func formatBinlogFraction(micros int64, fsp int) string {
+	if fsp <= 0 {
+		return ""
+	}
+	if fsp > 6 {
+		fsp = 6
+	}
+	return "." + fmt.Sprintf("%06d", micros)[:fsp]
+}
+
+// formatBinlogDatetime formats a datetime packed as year*13+month, day, hour,
+// minute and second.
+func formatBinlogDatetime(intPart, micros int64, fsp int) string {
+	ymd, hms := intPart>>17, intPart%(1<<17)
+	ym := ymd >> 5
+	return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", ym/13, ym%13, ymd%(1<<5), hms>>12, (hms>>6)%(1<<6), hms%(1<<6)) +
+		formatBinlogFraction(micros, fsp)
+}
+
+// formatBinlogTimestamp formats a timestamp as RFC 3339 in UTC, with the
+// exception of the zero value.
+func formatBinlogTimestamp(secs, micros int64, fsp int) string {
+	if secs == 0 && micros == 0 {
+		return "0000-00-00 00:00:00" + formatBinlogFraction(0, fsp)
+	}
+	return time.Unix(secs, 0).UTC().Format("2006-01-02T15:04:05") + formatBinlogFraction(micros, fsp) + "Z"
+}
+
+func decodeBinlogTime2(r *binlogReader, fsp int) string {
+	// The integer and fractional parts are stored together as a signed value
+	// with an offset, where negative values with fractions borrow from the
+	// integer part.
+	var packed int64
+	switch fsp {
+	case 1, 2, 3, 4:
+		intPart := int64(readBinlogUintBE(r, 3)) - 0x800000
+		var frac int64
+		if fsp <= 2 {
+			frac = int64(readBinlogUintBE(r, 1))
+			if intPart < 0 && frac != 0 {
+				intPart++
+				frac -= 0x100
+			}
+			frac *= 10000
+		} else {
+			frac = int64(readBinlogUintBE(r, 2))
+			if intPart < 0 && frac != 0 {
+				intPart++
+				frac -= 0x10000
+			}
+			frac *= 100
+		}
+		packed = intPart<<24 + frac
+	case 5, 6:
+		packed = int64(readBinlogUintBE(r, 6)) - 0x800000000000
+	default:
+		packed = (int64(readBinlogUintBE(r, 3)) - 0x800000) << 24
+	}
+	return formatBinlogPackedTime(packed, fsp)
+}
+
+func formatBinlogPackedTime(packed int64, fsp int) string {
+	sign := ""
+	if packed < 0 {
+		sign, packed = "-", -packed
+	}
+	hms, micros := packed>>24, packed%(1<<24)
+	return fmt.Sprintf("%v%02d:%02d:%02d", sign, (hms>>12)%(1<<10), (hms>>6)%(1<<6), hms%(1<<6)) +
+		formatBinlogFraction(micros, fsp)
+}
+
+var binlogDecimalDigitBytes = [10]int{0, 1, 1, 2, 2, 3, 3, 4, 4, 4}
+
+// decodeBinlogDecimal decodes a decimal stored in the binary format of MySQL,
+// where groups of nine digits are stored as big endian integers of four bytes
+// and the sign is encoded within the first bit.
+func decodeBinlogDecimal(r *binlogReader, precision, scale int) (json.Number, error) {
+	if scale > precision || precision > 65 {
+		return "", fmt.Errorf("invalid decimal precision %v and scale %v", precision, scale)
+	}
+	intg := precision - scale
+	intg0, intg0x := intg/9, intg%9
+	frac0, frac0x := scale/9, scale%9
+
+	raw := r.next(intg0*4 + binlogDecimalDigitBytes[intg0x] + frac0*4 + binlogDecimalDigitBytes[frac0x])
+	if r.err != nil {
+		return "", r.err
+	}
+	if len(raw) == 0 {
+		return "0", nil
+	}
+
+	data := append([]byte(nil), raw...)
+	negative := data[0]&0x80 == 0
+	data[0] ^= 0x80
+	if negative {
+		for i := range data {
+			data[i] ^= 0xff
+		}
+	}
+
+	d := &binlogReader{data: data}
+	var digits strings.Builder
+	if intg0x > 0 {
+		fmt.Fprintf(&digits, "%0*d", intg0x, readBinlogUintBE(d, binlogDecimalDigitBytes[intg0x]))
+	}
+	for i := 0; i < intg0; i++ {
+		fmt.Fprintf(&digits, "%09d", readBinlogUintBE(d, 4))
+	}
+	intStr := strings.TrimLeft(digits.String(), "0")
+	if intStr == "" {
+		intStr = "0"
+	}
+
+	var s strings.Builder
+	if negative {
+		s.WriteByte('-')
+	}
+	s.WriteString(intStr)
+	if scale > 0 {
+		s.WriteByte('.')
+		for i := 0; i < frac0; i++ {
+			fmt.Fprintf(&s, "%09d", readBinlogUintBE(d, 4))
+		}
+		if frac0x > 0 {
+			fmt.Fprintf(&s, "%0*d", frac0x, readBinlogUintBE(d, binlogDecimalDigitBytes[frac0x]))
+		}
+	}
+	return json.Number(s.String()), nil
+}
+
+//------------------------------------------------------------------------------
+
+// JSON columns are stored in the binary format documented at
+// https://dev.mysql.com/doc/dev/mysql-server/latest/json__binary_8h.html
+
+const (
+	binlogJSONSmallObject = 0x00
+	binlogJSONLargeObject = 0x01
+	binlogJSONSmallArray  = 0x02
+	binlogJSONLargeArray  = 0x03
+	binlogJSONLiteral     = 0x04
+	binlogJSONInt16       = 0x05
+	binlogJSONUint16      = 0x06
+	binlogJSONInt32       = 0x07
+	binlogJSONUint32      = 0x08
+	binlogJSONInt64       = 0x09
+	binlogJSONUint64      = 0x0a
+	binlogJSONDouble      = 0x0b
+	binlogJSONString      = 0x0c
+	binlogJSONOpaque      = 0x0f
+)
+
+var errBinlogJSONTruncated = errors.New("json value truncated")
+
+func decodeBinlogJSON(data []byte) (any, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return decodeBinlogJSONValue(data[0], data[1:])
+}
+
+func decodeBinlogJSONValue(t byte, data []byte) (any, error) {
+	fixed := func(n int) ([]byte, error) {
+		if len(data) < n {
+			return nil, errBinlogJSONTruncated
+		}
+		return data[:n], nil
+	}
+
+	switch t {
+	case binlogJSONSmallObject, binlogJSONLargeObject, binlogJSONSmallArray, binlogJSONLargeArray:
+		return decodeBinlogJSONComposite(t, data)
+	case binlogJSONLiteral:
+		b, err := fixed(1)
+		if err != nil {
+			return nil, err
+		}
+		switch b[0] {
+		case 0x00:
+			return nil, nil
+		case 0x01:
+			return true, nil
+		case 0x02:
+			return false, nil
+		}
+		return nil, fmt.Errorf("unknown json literal 0x%02x", b[0])
+	case binlogJSONInt16, binlogJSONUint16:
+		b, err := fixed(2)
+		if err != nil {
+			return nil, err
+		}
+		if t == binlogJSONInt16 {
+			return int64(int16(binary.LittleEndian.Uint16(b))), nil
+		}
+		return int64(binary.LittleEndian.Uint16(b)), nil
+	case binlogJSONInt32, binlogJSONUint32:
+		b, err := fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		if t == binlogJSONInt32 {
+			return int64(int32(binary.LittleEndian.Uint32(b))), nil
+		}
+		return int64(binary.LittleEndian.Uint32(b)), nil
+	case binlogJSONInt64, binlogJSONUint64, binlogJSONDouble:
+		b, err := fixed(8)
+		if err != nil {
+			return nil, err
+		}
+		v := binary.LittleEndian.Uint64(b)
+		switch t {
+		case binlogJSONInt64:
+			return int64(v), nil
+		case binlogJSONUint64:
+			return v, nil
+		}
+		return math.Float64frombits(v), nil
+	case binlogJSONString:
+		n, l, err := decodeBinlogJSONVarLen(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) < l+n {
+			return nil, errBinlogJSONTruncated
+		}
+		return string(data[l : l+n]), nil
+	case binlogJSONOpaque:
+		if len(data) < 1 {
+			return nil, errBinlogJSONTruncated
+		}
+		n, l, err := decodeBinlogJSONVarLen(data[1:])
+		if err != nil {
+			return nil, err
+		}
+		if len(data) < 1+l+n {
+			return nil, errBinlogJSONTruncated
+		}
+		return decodeBinlogJSONOpaque(data[0], data[1+l:1+l+n])
+	}
+	return nil, fmt.Errorf("unknown json value type 0x%02x", t)
+}
+
+// decodeBinlogJSONVarLen decodes a length stored in groups of seven bits,
+// returning the length and the number of bytes it occupied.
+func decodeBinlogJSONVarLen(data []byte) (length, size int, err error) {
+	for i := 0; i < 5 && i < len(data); i++ {
+		length |= int(data[i]&0x7f) << (7 * i)
+		if data[i]&0x80 == 0 {
+			return length, i + 1, nil
+		}
+	}
+	return 0, 0, errBinlogJSONTruncated
+}
+
+func decodeBinlogJSONComposite(t byte, data []byte) (any, error) {
+	large := t == binlogJSONLargeObject || t == binlogJSONLargeArray
+	isObject := t == binlogJSONSmallObject || t == binlogJSONLargeObject
+
+	offsetSize := 2
+	if large {
+		offsetSize = 4
+	}
+	readOffset := func(b []byte) int {
+		if large {
+			return int(binary.LittleEndian.Uint32(b))
+		}
+		return int(binary.LittleEndian.Uint16(b))
+	}
+
+	if len(data) < 2*offsetSize {
+		return nil, errBinlogJSONTruncated
+	}
+	count, size := readOffset(data), readOffset(data[offsetSize:])
+	if size > len(data) {
+		return nil, errBinlogJSONTruncated
+	}
+	data = data[:size]
+
+	keyEntrySize, valueEntrySize := offsetSize+2, 1+offsetSize
+	headerSize := 2*offsetSize + count*valueEntrySize
+	if isObject {
+		headerSize += count * keyEntrySize
+	}
+	if headerSize > len(data) {
+		return nil, errBinlogJSONTruncated
+	}
+
+	values := make([]any, count)
+	for i := range values {
+		entry := 2*offsetSize + i*valueEntrySize
+		if isObject {
+			entry += count * keyEntrySize
+		}
+		vt := data[entry]
+		inline := data[entry+1 : entry+valueEntrySize]
+
+		var err error
+		switch {
+		case vt == binlogJSONLiteral, vt == binlogJSONInt16, vt == binlogJSONUint16,
+			large && (vt == binlogJSONInt32 || vt == binlogJSONUint32):
+			// Small scalars are stored within the value entry.
+			values[i], err = decodeBinlogJSONValue(vt, inline)
+		default:
+			offset := readOffset(inline)
+			if offset >= len(data) {
+				return nil, errBinlogJSONTruncated
+			}
+			values[i], err = decodeBinlogJSONValue(vt, data[offset:])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !isObject {
+		return values, nil
+	}
+
+	obj := make(map[string]any, count)
+	for i, v := range values {
+		entry := 2*offsetSize + i*keyEntrySize
+		offset, length := readOffset(data[entry:]), int(binary.LittleEndian.Uint16(data[entry+offsetSize:]))
+		if offset+length > len(data) {
+			return nil, errBinlogJSONTruncated
+		}
+		obj[string(data[offset:offset+length])] = v
+	}
+	return obj, nil
+}
+
+// decodeBinlogJSONOpaque decodes values of MySQL types that have no JSON
+// equivalent, where unrecognised types are returned as bytes.
+func decodeBinlogJSONOpaque(fieldType byte, data []byte) (any, error) {
+	switch fieldType {
+	case mysqlTypeNewDecimal:
+		if len(data) < 2 {
+			return nil, errBinlogJSONTruncated
+		}
+		return decodeBinlogDecimal(&binlogReader{data: data[2:]}, int(data[0]), int(data[1]))
+	case mysqlTypeDate, mysqlTypeDatetime, mysqlTypeTimestamp, mysqlTypeTime:
+		if len(data) < 8 {
+			return nil, errBinlogJSONTruncated
+		}
+		packed := int64(binary.LittleEndian.Uint64(data))
+		if fieldType == mysqlTypeTime {
+			return formatBinlogPackedTime(packed, 6), nil
+		}
+		if packed < 0 {
+			packed = -packed
+		}
+		s := formatBinlogDatetime(packed>>24, packed%(1<<24), 6)
+		if fieldType == mysqlTypeDate {
+			return s[:10], nil
+		}
+		return s, nil
+	}
+	return append([]byte(nil), data...), nil
+}
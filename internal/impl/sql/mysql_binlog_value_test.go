@@ -0,0 +1,166 @@
+package sql
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeBinlogValue(t *testing.T) {
+	datetime := func(year, month, day, hour, minute, second int64) []byte {
+		intPart := ((year*13+month)<<5|day)<<17 | hour<<12 | minute<<6 | second
+		v := uint64(intPart + 0x8000000000)
+		return []byte{byte(v >> 32), byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	}
+	time2 := func(negative bool, hour, minute, second int64) []byte {
+		intPart := hour<<12 | minute<<6 | second
+		if negative {
+			intPart = -intPart
+		}
+		v := uint64(intPart + 0x800000)
+		return []byte{byte(v >> 16), byte(v >> 8), byte(v)}
+	}
+
+	tests := []struct {
+		name     string
+		colType  byte
+		meta     uint16
+		col      binlogColumn
+		data     []byte
+		expected any
+	}{
+		{name: "tiny signed", colType: mysqlTypeTiny, data: []byte{0xff}, expected: int64(-1)},
+		{name: "tiny unsigned", colType: mysqlTypeTiny, col: binlogColumn{unsigned: true}, data: []byte{0xff}, expected: int64(255)},
+		{name: "short", colType: mysqlTypeShort, data: []byte{0x00, 0x80}, expected: int64(math.MinInt16)},
+		{name: "int24 signed", colType: mysqlTypeInt24, data: []byte{0xfe, 0xff, 0xff}, expected: int64(-2)},
+		{name: "int24 unsigned", colType: mysqlTypeInt24, col: binlogColumn{unsigned: true}, data: []byte{0xfe, 0xff, 0xff}, expected: int64(1<<24 - 2)},
+		{name: "long", colType: mysqlTypeLong, data: binary.LittleEndian.AppendUint32(nil, 123456), expected: int64(123456)},
+		{name: "longlong signed", colType: mysqlTypeLongLong, data: binary.LittleEndian.AppendUint64(nil, math.MaxUint64), expected: int64(-1)},
+		{name: "longlong unsigned", colType: mysqlTypeLongLong, col: binlogColumn{unsigned: true}, data: binary.LittleEndian.AppendUint64(nil, math.MaxUint64), expected: uint64(math.MaxUint64)},
+		{name: "float", colType: mysqlTypeFloat, meta: 4, data: binary.LittleEndian.AppendUint32(nil, math.Float32bits(1.1)), expected: 1.1},
+		{name: "double", colType: mysqlTypeDouble, meta: 8, data: binary.LittleEndian.AppendUint64(nil, math.Float64bits(-2.5)), expected: -2.5},
+		{name: "year", colType: mysqlTypeYear, data: []byte{123}, expected: int64(2023)},
+		{name: "zero year", colType: mysqlTypeYear, data: []byte{0}, expected: int64(0)},
+		{name: "decimal", colType: mysqlTypeNewDecimal, meta: 14<<8 | 4, data: []byte{0x81, 0x0d, 0xfb, 0x38, 0xd2, 0x04, 0xd2}, expected: json.Number("1234567890.1234")},
+		{name: "negative decimal", colType: mysqlTypeNewDecimal, meta: 14<<8 | 4, data: []byte{0x7e, 0xf2, 0x04, 0xc7, 0x2d, 0xfb, 0x2d}, expected: json.Number("-1234567890.1234")},
+		{name: "decimal without scale", colType: mysqlTypeNewDecimal, meta: 3 << 8, data: []byte{0x80, 0x07}, expected: json.Number("7")},
+		{name: "date", colType: mysqlTypeDate, data: []byte{0x85, 0xce, 0x0f}, expected: "2023-04-05"},
+		{name: "datetime2", colType: mysqlTypeDatetime2, data: datetime(2023, 4, 5, 6, 7, 8), expected: "2023-04-05 06:07:08"},
+		{name: "datetime2 fraction", colType: mysqlTypeDatetime2, meta: 6, data: append(datetime(2023, 4, 5, 6, 7, 8), 0x01, 0xe2, 0x40), expected: "2023-04-05 06:07:08.123456"},
+		{name: "datetime2 short fraction", colType: mysqlTypeDatetime2, meta: 3, data: append(datetime(2023, 4, 5, 6, 7, 8), 0x04, 0xce), expected: "2023-04-05 06:07:08.123"},
+		{name: "timestamp2", colType: mysqlTypeTimestamp2, data: []byte{0x64, 0x2d, 0x10, 0x0c}, expected: "2023-04-05T06:07:08Z"},
+		{name: "timestamp2 fraction", colType: mysqlTypeTimestamp2, meta: 2, data: []byte{0x64, 0x2d, 0x10, 0x0c, 12}, expected: "2023-04-05T06:07:08.12Z"},
+		{name: "zero timestamp2", colType: mysqlTypeTimestamp2, data: []byte{0, 0, 0, 0}, expected: "0000-00-00 00:00:00"},
+		{name: "time2", colType: mysqlTypeTime2, data: time2(false, 12, 34, 56), expected: "12:34:56"},
+		{name: "negative time2", colType: mysqlTypeTime2, data: time2(true, 1, 2, 3), expected: "-01:02:03"},
+		{name: "time2 fraction", colType: mysqlTypeTime2, meta: 2, data: append(time2(false, 1, 2, 3), 50), expected: "01:02:03.50"},
+		{name: "negative time2 fraction", colType: mysqlTypeTime2, meta: 2, data: append(time2(true, 1, 2, 4), 0x100-50), expected: "-01:02:03.50"},
+		{name: "varchar", colType: mysqlTypeVarchar, meta: 40, data: []byte{3, 'f', 'o', 'o'}, expected: "foo"},
+		{name: "long varchar", colType: mysqlTypeVarchar, meta: 1024, data: []byte{3, 0, 'f', 'o', 'o'}, expected: "foo"},
+		{name: "varbinary", colType: mysqlTypeVarchar, meta: 40, col: binlogColumn{dataType: "varbinary"}, data: []byte{2, 0x00, 0xff}, expected: []byte{0x00, 0xff}},
+		{name: "char", colType: mysqlTypeString, meta: uint16(mysqlTypeString)<<8 | 40, data: []byte{3, 'b', 'a', 'r'}, expected: "bar"},
+		{name: "long char", colType: mysqlTypeString, meta: uint16(mysqlTypeString&^0x10)<<8 | 0x2c, data: []byte{3, 0, 'b', 'a', 'r'}, expected: "bar"},
+		{name: "enum", colType: mysqlTypeString, meta: uint16(mysqlTypeEnum)<<8 | 1, col: binlogColumn{values: []string{"small", "large"}}, data: []byte{2}, expected: "large"},
+		{name: "empty enum", colType: mysqlTypeString, meta: uint16(mysqlTypeEnum)<<8 | 1, col: binlogColumn{values: []string{"small", "large"}}, data: []byte{0}, expected: ""},
+		{name: "set", colType: mysqlTypeString, meta: uint16(mysqlTypeSet)<<8 | 1, col: binlogColumn{values: []string{"a", "b", "c"}}, data: []byte{0b101}, expected: "a,c"},
+		{name: "bit", colType: mysqlTypeBit, meta: 1<<8 | 2, data: []byte{0x01, 0x02}, expected: uint64(0x0102)},
+		{name: "text", colType: mysqlTypeBlob, meta: 2, col: binlogColumn{dataType: "text"}, data: []byte{3, 0, 'b', 'a', 'z'}, expected: "baz"},
+		{name: "blob", colType: mysqlTypeBlob, meta: 2, col: binlogColumn{dataType: "blob"}, data: []byte{3, 0, 'b', 'a', 'z'}, expected: []byte("baz")},
+		{name: "json", colType: mysqlTypeJSON, meta: 4, data: []byte{2, 0, 0, 0, binlogJSONLiteral, 0x01}, expected: true},
+		{name: "empty json", colType: mysqlTypeJSON, meta: 4, data: []byte{0, 0, 0, 0}, expected: nil},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			r := &binlogReader{data: test.data}
+			v, err := decodeBinlogValue(r, test.colType, test.meta, test.col)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, v)
+			assert.Empty(t, r.data, "all data should be consumed")
+		})
+	}
+}
+
+func TestDecodeBinlogValueErrors(t *testing.T) {
+	_, err := decodeBinlogValue(&binlogReader{data: []byte{1}}, mysqlTypeLong, 0, binlogColumn{})
+	require.Error(t, err)
+
+	_, err = decodeBinlogValue(&binlogReader{data: []byte{3}}, mysqlTypeString, uint16(mysqlTypeEnum)<<8|1, binlogColumn{values: []string{"a"}})
+	require.EqualError(t, err, "enum index 3 out of range of 1 values")
+
+	_, err = decodeBinlogValue(&binlogReader{data: []byte{1}}, 0, 0, binlogColumn{})
+	require.EqualError(t, err, "unsupported column type 0")
+}
+
+func TestDecodeBinlogJSON(t *testing.T) {
+	// {"a":1,"b":[true,"x"],"c":{"d":1.5}}
+	data := []byte{
+		binlogJSONSmallObject,
+		3, 0, 60, 0, // Count and size
+		// Keys
+		25, 0, 1, 0,
+		26, 0, 1, 0,
+		27, 0, 1, 0,
+		// Values
+		binlogJSONInt16, 1, 0,
+		binlogJSONSmallArray, 28, 0,
+		binlogJSONSmallObject, 40, 0,
+		'a', 'b', 'c',
+		// Array
+		2, 0, 12, 0,
+		binlogJSONLiteral, 1, 0,
+		binlogJSONString, 10, 0,
+		1, 'x',
+		// Object
+		1, 0, 20, 0,
+		11, 0, 1, 0,
+		binlogJSONDouble, 12, 0,
+		'd',
+		0, 0, 0, 0, 0, 0, 0xf8, 0x3f,
+	}
+
+	v, err := decodeBinlogJSON(data)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"a": int64(1),
+		"b": []any{true, "x"},
+		"c": map[string]any{"d": 1.5},
+	}, v)
+
+	_, err = decodeBinlogJSON(data[:20])
+	require.Error(t, err)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected any
+	}{
+		{name: "null", data: []byte{binlogJSONLiteral, 0}, expected: nil},
+		{name: "false", data: []byte{binlogJSONLiteral, 2}, expected: false},
+		{name: "int32", data: []byte{binlogJSONInt32, 0xfe, 0xff, 0xff, 0xff}, expected: int64(-2)},
+		{name: "uint64", data: append([]byte{binlogJSONUint64}, binary.LittleEndian.AppendUint64(nil, math.MaxUint64)...), expected: uint64(math.MaxUint64)},
+		{name: "long string", data: append([]byte{binlogJSONString, 0x80, 0x01}, make([]byte, 128)...), expected: string(make([]byte, 128))},
+		{name: "decimal", data: []byte{binlogJSONOpaque, mysqlTypeNewDecimal, 5, 5, 2, 0x80, 0x0c, 0x22}, expected: json.Number("12.34")},
+		{name: "datetime", data: append([]byte{binlogJSONOpaque, mysqlTypeDatetime, 8}, binary.LittleEndian.AppendUint64(nil, uint64((((2023*13+4)<<5|5)<<17|6<<12|7<<6|8)<<24|500000))...), expected: "2023-04-05 06:07:08.500000"},
+		{name: "date", data: append([]byte{binlogJSONOpaque, mysqlTypeDate, 8}, binary.LittleEndian.AppendUint64(nil, uint64(((2023*13+4)<<5|5)<<17<<24))...), expected: "2023-04-05"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			v, err := decodeBinlogJSON(test.data)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, v)
+		})
+	}
+}
+
+func TestParseMySQLEnumValues(t *testing.T) {
+	assert.Equal(t, []string{"a", "b c", "it's", ""}, parseMySQLEnumValues(`enum('a','b c','it''s','')`))
+	assert.Equal(t, []string{"x", "(y)"}, parseMySQLEnumValues(`set('x','(y)')`))
+	assert.Nil(t, parseMySQLEnumValues("int"))
+}
@@ -3,6 +3,8 @@ package integration
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
 )
 
 // CacheTestOpenClose checks that the cache can be started, an item added, and
@@ -122,3 +125,93 @@ func CacheTestGetAndSet(n int) CacheTestDefinition {
 		},
 	)
 }
+
+// CacheTestIncr checks that concurrent increments of a counter are atomic.
+func CacheTestIncr(n int) CacheTestDefinition {
+	return namedCacheTest(
+		"can increment counters",
+		func(t *testing.T, env *cacheTestEnvironment) {
+			c := initCache(t, env)
+			t.Cleanup(func() {
+				closeCache(t, c)
+			})
+
+			var wg sync.WaitGroup
+			for i := 0; i < n; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := cache.Incr(env.ctx, c, "incrkey", 2, nil)
+					assert.NoError(t, err)
+				}()
+			}
+			wg.Wait()
+
+			count, err := cache.Incr(env.ctx, c, "incrkey", -1, nil)
+			require.NoError(t, err)
+			assert.Equal(t, int64(n*2-1), count)
+
+			res, err := c.Get(env.ctx, "incrkey")
+			require.NoError(t, err)
+			assert.Equal(t, strconv.Itoa(n*2-1), string(res))
+		},
+	)
+}
+
+// CacheTestGetMulti checks that we can set n items and then get them all in
+// one call, with missing keys omitted.
+func CacheTestGetMulti(n int) CacheTestDefinition {
+	return namedCacheTest(
+		"can get multiple keys",
+		func(t *testing.T, env *cacheTestEnvironment) {
+			c := initCache(t, env)
+			t.Cleanup(func() {
+				closeCache(t, c)
+			})
+
+			keys := []string{"missingkey"}
+			exp := map[string][]byte{}
+			for i := 0; i < n; i++ {
+				key := fmt.Sprintf("multikey:%v", i)
+				value := fmt.Sprintf("value:%v", i)
+				require.NoError(t, c.Set(env.ctx, key, []byte(value), nil))
+				keys = append(keys, key)
+				exp[key] = []byte(value)
+			}
+
+			res, err := cache.GetMulti(env.ctx, c, keys...)
+			require.NoError(t, err)
+			assert.Equal(t, exp, res)
+		},
+	)
+}
+
+// CacheTestScan checks that we can set n items and then enumerate them with a
+// prefix.
+func CacheTestScan(n int) CacheTestDefinition {
+	return namedCacheTest(
+		"can scan keys",
+		func(t *testing.T, env *cacheTestEnvironment) {
+			c := initCache(t, env)
+			t.Cleanup(func() {
+				closeCache(t, c)
+			})
+
+			exp := map[string]string{}
+			for i := 0; i < n; i++ {
+				key := fmt.Sprintf("scankey:%v", i)
+				value := fmt.Sprintf("value:%v", i)
+				require.NoError(t, c.Set(env.ctx, key, []byte(value), nil))
+				require.NoError(t, c.Set(env.ctx, fmt.Sprintf("otherkey:%v", i), []byte(value), nil))
+				exp[key] = value
+			}
+
+			act := map[string]string{}
+			require.NoError(t, cache.Scan(env.ctx, c, "scankey:", func(key string, value []byte) error {
+				act[key] = string(value)
+				return nil
+			}))
+			assert.Equal(t, exp, act)
+		},
+	)
+}
@@ -55,6 +55,14 @@ func (w *InputWrapper) CloseExistingInput(ctx context.Context, forSwap bool) err
 		return nil
 	}
 
+	if !forSwap {
+		// The wrapper is closed along with the input so that a transaction
+		// held by the wrapper that nothing is consuming is rejected, otherwise
+		// the input would never finish closing.
+		w.TriggerStopConsuming()
+		return w.WaitForClose(ctx)
+	}
+
 	tmpInput.TriggerStopConsuming()
 	return tmpInput.WaitForClose(ctx)
 }
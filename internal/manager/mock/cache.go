@@ -2,6 +2,8 @@ package mock
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/benthosdev/benthos/v4/internal/component"
@@ -66,6 +68,23 @@ func (c *Cache) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
+// Scan mock cache items with a given key prefix in lexicographical order.
+func (c *Cache) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) error) error {
+	keys := make([]string, 0, len(c.Values))
+	for k := range c.Values {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := fn(k, []byte(c.Values[k].Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Close does nothing.
 func (c *Cache) Close(ctx context.Context) error {
 	return nil
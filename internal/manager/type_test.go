@@ -539,3 +539,25 @@ func TestManagerPipeGetSet(t *testing.T) {
 		t.Error("Wrong transaction chan returned")
 	}
 }
+
+func TestManagerRemoveInputUnconsumed(t *testing.T) {
+	mgr, err := manager.New(manager.NewResourceConfig())
+	require.NoError(t, err)
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	inConf, err := testutil.InputFromYAML(`
+generate:
+  mapping: 'root = "hello world"'
+  interval: ""
+`)
+	require.NoError(t, err)
+	require.NoError(t, mgr.StoreInput(tCtx, "foo", inConf))
+
+	// Give the input a chance to read a message that is never consumed.
+	time.Sleep(time.Millisecond * 50)
+
+	require.NoError(t, mgr.RemoveInput(tCtx, "foo"))
+	require.False(t, mgr.ProbeInput("foo"))
+}
@@ -22,6 +22,7 @@ type Config struct {
 	Buffer   buffer.Config   `yaml:"buffer"`
 	Pipeline pipeline.Config `yaml:"pipeline"`
 	Output   output.Config   `yaml:"output"`
+	Quotas   QuotasConfig    `yaml:"quotas"`
 
 	rawSource any
 }
@@ -60,5 +61,11 @@ func FromParsed(prov docs.Provider, pConf *docs.ParsedConfig, rawSource any) (co
 	if conf.Output, err = output.FromAny(prov, v); err != nil {
 		return
 	}
+
+	if pConf.Contains(fieldQuotas) {
+		if conf.Quotas, err = quotasFromParsed(pConf.Namespace(fieldQuotas)); err != nil {
+			return
+		}
+	}
 	return
 }
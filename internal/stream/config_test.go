@@ -45,6 +45,32 @@ output:
 				assert.Equal(t, v.Pipeline.Threads, 123)
 				assert.Equal(t, v.Output.Label, "c")
 				assert.Equal(t, v.Output.Type, "reject")
+				assert.True(t, v.Quotas.IsZero())
+			},
+		},
+		{
+			name: "quotas",
+			input: `
+input:
+  generate:
+    count: 1
+    mapping: 'root.id = "a"'
+    interval: 1s
+
+output:
+  drop: {}
+
+quotas:
+  max_in_flight: 10
+  max_in_flight_bytes: 1024
+  max_processor_threads: 2
+`,
+			validateFn: func(t testing.TB, v stream.Config) {
+				assert.Equal(t, stream.QuotasConfig{
+					MaxInFlight:         10,
+					MaxInFlightBytes:    1024,
+					MaxProcessorThreads: 2,
+				}, v.Quotas)
 			},
 		},
 	}
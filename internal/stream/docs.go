@@ -29,5 +29,6 @@ func Spec() docs.FieldSpecs {
 		}),
 		pipeline.ConfigSpec(),
 		docs.FieldOutput(fieldOutput, "An output to sink messages to.").HasDefault(defaultOutput),
+		quotasSpec(),
 	}
 }
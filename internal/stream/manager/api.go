@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 
@@ -14,11 +15,6 @@ import (
 	"github.com/gorilla/mux"
 	"gopkg.in/yaml.v3"
 
-	"github.com/benthosdev/benthos/v4/internal/component/cache"
-	"github.com/benthosdev/benthos/v4/internal/component/input"
-	"github.com/benthosdev/benthos/v4/internal/component/output"
-	"github.com/benthosdev/benthos/v4/internal/component/processor"
-	"github.com/benthosdev/benthos/v4/internal/component/ratelimit"
 	"github.com/benthosdev/benthos/v4/internal/config"
 	"github.com/benthosdev/benthos/v4/internal/docs"
 	"github.com/benthosdev/benthos/v4/internal/stream"
@@ -40,6 +36,11 @@ func (m *Type) registerEndpoints(enableCrud bool) {
 		"POST: Create or replace a given resource configuration of a specified type. Types supported are `cache`, `input`, `output`, `processor` and `rate_limit`.",
 		m.HandleResourceCRUD,
 	)
+	m.manager.RegisterEndpoint(
+		"/apply",
+		"POST: Validate and apply a set of stream and resource configs together, all changes are rolled back if any of them fail. Returns a diff of the changes.",
+		m.HandleApply,
+	)
 	m.manager.RegisterEndpoint(
 		"/streams/{id}/stats",
 		"GET a structured JSON object containing metrics for the stream.",
@@ -99,6 +100,13 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	// Changes to the set of streams are serialised with transactions so that
+	// they are not interleaved with, or rolled back by, an apply.
+	if r.Method == "POST" {
+		m.txLock.Lock()
+		defer m.txLock.Unlock()
+	}
+
 	type confInfo struct {
 		Active    bool    `json:"active"`
 		Uptime    float64 `json:"uptime"`
@@ -284,6 +292,14 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Changes to a stream are serialised with transactions so that they are not
+	// interleaved with, or rolled back by, an apply.
+	switch r.Method {
+	case "POST", "PUT", "DELETE", "PATCH":
+		m.txLock.Lock()
+		defer m.txLock.Unlock()
+	}
+
 	readConfig := func() (confOut stream.Config, lints []string, err error) {
 		var confBytes []byte
 		if confBytes, err = io.ReadAll(r.Body); err != nil {
@@ -379,17 +395,24 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 			conf := info.Config()
 			sanit := conf.GetRawSource()
 
+			var quotas *QuotaStatus
+			if qStatus, hasQuotas := info.Quotas(); hasQuotas {
+				quotas = &qStatus
+			}
+
 			var bodyBytes []byte
 			if bodyBytes, serverErr = json.Marshal(struct {
-				Active    bool    `json:"active"`
-				Uptime    float64 `json:"uptime"`
-				UptimeStr string  `json:"uptime_str"`
-				Config    any     `json:"config"`
+				Active    bool         `json:"active"`
+				Uptime    float64      `json:"uptime"`
+				UptimeStr string       `json:"uptime_str"`
+				Config    any          `json:"config"`
+				Quotas    *QuotaStatus `json:"quotas,omitempty"`
 			}{
 				Active:    info.IsRunning(),
 				Uptime:    info.Uptime().Seconds(),
 				UptimeStr: info.Uptime().String(),
 				Config:    sanit,
+				Quotas:    quotas,
 			}); serverErr != nil {
 				return
 			}
@@ -470,51 +493,8 @@ func (m *Type) HandleResourceCRUD(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
-	var storeFn func(*yaml.Node)
-
 	docType := docs.Type(mux.Vars(r)["type"])
-	switch docType {
-	case docs.TypeCache:
-		storeFn = func(n *yaml.Node) {
-			var cacheConf cache.Config
-			if cacheConf, requestErr = cache.FromAny(m.manager.Environment(), n); requestErr != nil {
-				return
-			}
-			serverErr = m.manager.StoreCache(ctx, id, cacheConf)
-		}
-	case docs.TypeInput:
-		storeFn = func(n *yaml.Node) {
-			var inputConf input.Config
-			if inputConf, requestErr = input.FromAny(m.manager.Environment(), n); requestErr != nil {
-				return
-			}
-			serverErr = m.manager.StoreInput(ctx, id, inputConf)
-		}
-	case docs.TypeOutput:
-		storeFn = func(n *yaml.Node) {
-			var outputConf output.Config
-			if outputConf, requestErr = output.FromAny(m.manager.Environment(), n); requestErr != nil {
-				return
-			}
-			serverErr = m.manager.StoreOutput(ctx, id, outputConf)
-		}
-	case docs.TypeProcessor:
-		storeFn = func(n *yaml.Node) {
-			var procConf processor.Config
-			if procConf, requestErr = processor.FromAny(m.manager.Environment(), n); requestErr != nil {
-				return
-			}
-			serverErr = m.manager.StoreProcessor(ctx, id, procConf)
-		}
-	case docs.TypeRateLimit:
-		storeFn = func(n *yaml.Node) {
-			var rlConf ratelimit.Config
-			if rlConf, requestErr = ratelimit.FromAny(m.manager.Environment(), n); requestErr != nil {
-				return
-			}
-			serverErr = m.manager.StoreRateLimit(ctx, id, rlConf)
-		}
-	default:
+	if !isResourceKind(docType) {
 		http.Error(w, "Var `type` must be set to one of `cache`, `input`, `output`, `processor` or `rate_limit`", http.StatusBadRequest)
 		return
 	}
@@ -562,7 +542,193 @@ func (m *Type) HandleResourceCRUD(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	storeFn(confNode)
+	var conf any
+	if conf, requestErr = m.parseResource(docType, confNode); requestErr != nil {
+		return
+	}
+
+	m.txLock.Lock()
+	serverErr = m.storeResource(ctx, docType, id, conf)
+	m.txLock.Unlock()
+}
+
+// HandleApply is an http.HandleFunc for validating and applying a set of
+// stream and resource configs together, where all changes are rolled back if
+// any of them fail.
+func (m *Type) HandleApply(w http.ResponseWriter, r *http.Request) {
+	var serverErr, requestErr error
+	defer func() {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		if serverErr != nil {
+			m.manager.Logger().Error("Apply Error: %v\n", serverErr)
+			http.Error(w, fmt.Sprintf("Error: %v", serverErr), http.StatusBadGateway)
+			return
+		}
+		if requestErr != nil {
+			m.manager.Logger().Debug("Apply request Error: %v\n", requestErr)
+			http.Error(w, fmt.Sprintf("Error: %v", requestErr), http.StatusBadRequest)
+			return
+		}
+	}()
+
+	if r.Method != "POST" {
+		requestErr = fmt.Errorf("verb not supported: %v", r.Method)
+		return
+	}
+
+	ignoreLints := r.URL.Query().Get("chilled") == "true"
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	var confBytes []byte
+	if confBytes, requestErr = io.ReadAll(r.Body); requestErr != nil {
+		return
+	}
+
+	if confBytes, requestErr = config.ReplaceEnvVariables(confBytes, os.LookupEnv); requestErr != nil {
+		var errEnvMissing *config.ErrMissingEnvVars
+		if ignoreLints && errors.As(requestErr, &errEnvMissing) {
+			confBytes = errEnvMissing.BestAttempt
+			requestErr = nil
+		} else {
+			return
+		}
+	}
+
+	var txNodes struct {
+		Resources map[string]map[string]yaml.Node `yaml:"resources"`
+		Streams   map[string]yaml.Node            `yaml:"streams"`
+	}
+	if requestErr = yaml.Unmarshal(confBytes, &txNodes); requestErr != nil {
+		return
+	}
+
+	isNull := func(n *yaml.Node) bool {
+		return n.Kind == 0 || (n.Kind == yaml.ScalarNode && n.Tag == "!!null")
+	}
+
+	tx := Transaction{
+		Streams:   map[string]*stream.Config{},
+		Resources: map[docs.Type]map[string]any{},
+	}
+
+	var lints []string
+	for kindStr, nodes := range txNodes.Resources {
+		kind := docs.Type(kindStr)
+		if !isResourceKind(kind) {
+			requestErr = fmt.Errorf("resource type must be one of `cache`, `input`, `output`, `processor` or `rate_limit`, got: %v", kindStr)
+			return
+		}
+		confs := map[string]any{}
+		for id, node := range nodes {
+			node := node
+			if isNull(&node) {
+				confs[id] = nil
+				continue
+			}
+			if !ignoreLints {
+				for _, l := range docs.LintYAML(m.lintCtx(), kind, &node) {
+					lints = append(lints, fmt.Sprintf("%v resource '%v': %v", kind, id, l.Error()))
+				}
+			}
+			var conf any
+			if conf, requestErr = m.parseResource(kind, &node); requestErr != nil {
+				requestErr = fmt.Errorf("%v resource '%v': %w", kind, id, requestErr)
+				return
+			}
+			confs[id] = conf
+		}
+		tx.Resources[kind] = confs
+	}
+
+	for id, node := range txNodes.Streams {
+		node := node
+		if isNull(&node) {
+			tx.Streams[id] = nil
+			continue
+		}
+		if !ignoreLints {
+			for _, l := range m.lintStreamConfigNode(&node) {
+				lints = append(lints, fmt.Sprintf("stream '%v': %v", id, l))
+			}
+		}
+
+		var rawSource any
+		_ = node.Decode(&rawSource)
+
+		var pConf *docs.ParsedConfig
+		if pConf, requestErr = stream.Spec().ParsedConfigFromAny(&node); requestErr != nil {
+			requestErr = fmt.Errorf("stream '%v': %w", id, requestErr)
+			return
+		}
+		var conf stream.Config
+		if conf, requestErr = stream.FromParsed(m.manager.Environment(), pConf, rawSource); requestErr != nil {
+			requestErr = fmt.Errorf("stream '%v': %w", id, requestErr)
+			return
+		}
+		tx.Streams[id] = &conf
+	}
+
+	if len(lints) > 0 {
+		sort.Strings(lints)
+		for _, l := range lints {
+			m.manager.Logger().Info("Apply config: %v\n", l)
+		}
+		errBytes, _ := json.Marshal(lintErrors{
+			LintErrs: lints,
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write(errBytes)
+		return
+	}
+
+	type applyResult struct {
+		Applied        bool     `json:"applied"`
+		DryRun         bool     `json:"dry_run"`
+		Diff           []Change `json:"diff"`
+		Error          string   `json:"error,omitempty"`
+		RolledBack     bool     `json:"rolled_back,omitempty"`
+		RollbackErrors []string `json:"rollback_errors,omitempty"`
+	}
+
+	res := applyResult{DryRun: dryRun}
+	status := http.StatusOK
+	if dryRun {
+		if res.Diff, requestErr = m.Diff(tx); requestErr != nil {
+			return
+		}
+	} else {
+		var err error
+		res.Diff, err = m.Apply(r.Context(), tx)
+
+		var txErr *TransactionError
+		switch {
+		case errors.As(err, &txErr):
+			m.manager.Logger().Error("Apply Error: %v\n", err)
+			status = http.StatusBadGateway
+			res.Error = txErr.Err.Error()
+			res.RolledBack = len(txErr.RollbackErrs) == 0
+			for _, rErr := range txErr.RollbackErrs {
+				res.RollbackErrors = append(res.RollbackErrors, rErr.Error())
+			}
+		case err != nil:
+			requestErr = err
+			return
+		default:
+			res.Applied = true
+		}
+	}
+
+	resBytes, err := json.Marshal(res)
+	if err != nil {
+		serverErr = err
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(resBytes)
 }
 
 // HandleStreamStats is an http.HandleFunc for obtaining metrics for a stream.
@@ -2,6 +2,7 @@ package manager_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -40,6 +41,7 @@ func router(m *manager.Type) *mux.Router {
 	router.HandleFunc("/streams/{id}", m.HandleStreamCRUD)
 	router.HandleFunc("/streams/{id}/stats", m.HandleStreamStats)
 	router.HandleFunc("/resources/{type}/{id}", m.HandleResourceCRUD)
+	router.HandleFunc("/apply", m.HandleApply)
 	return router
 }
 
@@ -1017,3 +1019,57 @@ output:
 		return response.Code == http.StatusServiceUnavailable
 	}, time.Second*10, time.Millisecond*50)
 }
+
+func TestTypeAPIQuotas(t *testing.T) {
+	mgr, err := bmanager.New(bmanager.NewResourceConfig())
+	require.NoError(t, err)
+
+	smgr := manager.New(mgr)
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Millisecond*100)
+		defer done()
+		_ = smgr.Stop(ctx)
+	})
+
+	r := router(smgr)
+
+	request := genYAMLRequest("POST", "/streams/foo", `
+input:
+  generate:
+    mapping: 'root = "hello world"'
+    interval: ""
+pipeline:
+  threads: 4
+output:
+  inproc: TestTypeAPIQuotas
+quotas:
+  max_in_flight: 1
+  max_processor_threads: 2
+`)
+	response := httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	var quotas *gabs.Container
+	assert.Eventually(t, func() bool {
+		request = genRequest("GET", "/streams/foo", nil)
+		response = httptest.NewRecorder()
+		r.ServeHTTP(response, request)
+		if response.Code != http.StatusOK {
+			return false
+		}
+		body, err := gabs.ParseJSON(response.Body.Bytes())
+		if err != nil {
+			return false
+		}
+		quotas = body.S("quotas")
+		return quotas.S("limit_hits", "max_in_flight").Data() != nil
+	}, time.Second*5, time.Millisecond*10)
+
+	assert.Equal(t, float64(1), quotas.S("in_flight").Data())
+	assert.Equal(t, float64(1), quotas.S("max_in_flight").Data())
+	assert.Equal(t, float64(2), quotas.S("processor_threads").Data())
+	assert.Equal(t, float64(2), quotas.S("max_processor_threads").Data())
+	assert.Equal(t, []any{"max_in_flight", "max_processor_threads"}, quotas.S("limited").Data())
+	assert.Equal(t, float64(1), quotas.S("limit_hits", "max_processor_threads").Data())
+}
@@ -0,0 +1,305 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
+	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/stream"
+)
+
+// Names of the quotas that can be reached by a stream.
+const (
+	QuotaMaxInFlight         = "max_in_flight"
+	QuotaMaxInFlightBytes    = "max_in_flight_bytes"
+	QuotaMaxProcessorThreads = "max_processor_threads"
+)
+
+// QuotaStatus describes the resources currently consumed by a stream against
+// its configured quotas, and how many times each quota has been reached.
+type QuotaStatus struct {
+	InFlight            int64            `json:"in_flight"`
+	MaxInFlight         int              `json:"max_in_flight"`
+	InFlightBytes       int64            `json:"in_flight_bytes"`
+	MaxInFlightBytes    int64            `json:"max_in_flight_bytes"`
+	ProcessorThreads    int              `json:"processor_threads"`
+	MaxProcessorThreads int              `json:"max_processor_threads"`
+	Limited             []string         `json:"limited"`
+	LimitHits           map[string]int64 `json:"limit_hits"`
+}
+
+// applyThreadQuota caps the number of processing threads of a stream config,
+// returning true if the configured number of threads was reduced.
+func applyThreadQuota(conf *stream.Config) bool {
+	maxThreads := conf.Quotas.MaxProcessorThreads
+	if maxThreads <= 0 {
+		return false
+	}
+	// A non-positive number of threads means one per CPU.
+	if conf.Pipeline.Threads <= 0 || conf.Pipeline.Threads > maxThreads {
+		conf.Pipeline.Threads = maxThreads
+		return true
+	}
+	return false
+}
+
+// checkQuotaBatching returns an error if an output of a stream config has a
+// batching policy that can never be flushed within the in flight quotas of the
+// stream. Since the quotas are only released once messages are acknowledged
+// such a stream would stall permanently.
+func (m *Type) checkQuotaBatching(conf stream.Config) error {
+	maxCount, maxBytes := conf.Quotas.MaxInFlight, conf.Quotas.MaxInFlightBytes
+	if maxCount <= 0 && maxBytes <= 0 {
+		return nil
+	}
+
+	node, err := streamConfigNode(conf)
+	if err != nil {
+		return err
+	}
+	return stream.Spec().WalkYAML(node, m.manager.Environment(), func(c docs.WalkedYAMLComponent) error {
+		if c.ComponentType != docs.TypeOutput {
+			return nil
+		}
+		pluginNode := componentPluginNode(c)
+		if pluginNode == nil || pluginNode.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		var batching *yaml.Node
+		for i := 0; i < len(pluginNode.Content)-1; i += 2 {
+			if pluginNode.Content[i].Value == "batching" {
+				batching = pluginNode.Content[i+1]
+				break
+			}
+		}
+		if batching == nil {
+			return nil
+		}
+
+		var policy struct {
+			Count    int    `yaml:"count"`
+			ByteSize int64  `yaml:"byte_size"`
+			Period   string `yaml:"period"`
+			Check    string `yaml:"check"`
+		}
+		if err := batching.Decode(&policy); err != nil {
+			return nil
+		}
+		if policy.Period != "" || policy.Check != "" || (policy.Count <= 0 && policy.ByteSize <= 0) {
+			return nil
+		}
+
+		countReachable := policy.Count > 0 && (maxCount <= 0 || policy.Count <= maxCount)
+		bytesReachable := policy.ByteSize > 0 && (maxBytes <= 0 || policy.ByteSize <= maxBytes)
+		if countReachable || bytesReachable {
+			return nil
+		}
+		if policy.Count > 0 {
+			return fmt.Errorf("the batching count %v of output %v exceeds the %v quota of %v, and without a period the batch would never be flushed", policy.Count, c.Name, QuotaMaxInFlight, maxCount)
+		}
+		return fmt.Errorf("the batching byte_size %v of output %v exceeds the %v quota of %v, and without a period the batch would never be flushed", policy.ByteSize, c.Name, QuotaMaxInFlightBytes, maxBytes)
+	})
+}
+
+// quotaGate applies back pressure to the transactions of a stream whenever the
+// messages that are in flight exceed its quotas.
+type quotaGate struct {
+	conf stream.QuotasConfig
+
+	mut           sync.Mutex
+	inFlight      int64
+	inFlightBytes int64
+	limited       map[string]bool
+	limitHits     map[string]int64
+	releasedChan  chan struct{}
+
+	threads        int
+	threadsLimited bool
+
+	mInFlight      metrics.StatGauge
+	mInFlightBytes metrics.StatGauge
+	mLimitHits     metrics.StatCounterVec
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+func newQuotaGate(conf stream.Config, threadsLimited bool, stats metrics.Type) *quotaGate {
+	q := &quotaGate{
+		conf:           conf.Quotas,
+		limited:        map[string]bool{},
+		limitHits:      map[string]int64{},
+		releasedChan:   make(chan struct{}),
+		threads:        conf.Pipeline.Threads,
+		threadsLimited: threadsLimited,
+		mInFlight:      stats.GetGauge("stream_in_flight"),
+		mInFlightBytes: stats.GetGauge("stream_in_flight_bytes"),
+		mLimitHits:     stats.GetCounterVec("stream_quota_limited", "quota"),
+		closeChan:      make(chan struct{}),
+	}
+	if threadsLimited {
+		q.limitHits[QuotaMaxProcessorThreads] = 1
+		q.mLimitHits.With(QuotaMaxProcessorThreads).Incr(1)
+	}
+	return q
+}
+
+func batchBytes(b message.Batch) (n int64) {
+	for _, p := range b {
+		n += int64(len(p.AsBytes()))
+	}
+	return
+}
+
+// exceeded returns the names of quotas that would be exceeded by adding a
+// number of messages and bytes to those in flight. A batch is always admitted
+// when nothing else is in flight, as otherwise it would never progress.
+func (q *quotaGate) exceeded(count, size int64) (names []string) {
+	if q.inFlight == 0 {
+		return nil
+	}
+	if q.conf.MaxInFlight > 0 && q.inFlight+count > int64(q.conf.MaxInFlight) {
+		names = append(names, QuotaMaxInFlight)
+	}
+	if q.conf.MaxInFlightBytes > 0 && q.inFlightBytes+size > q.conf.MaxInFlightBytes {
+		names = append(names, QuotaMaxInFlightBytes)
+	}
+	return
+}
+
+// acquire blocks until a batch of messages can be admitted within the quotas,
+// returns false if the gate was closed before the batch was admitted.
+func (q *quotaGate) acquire(count, size int64) bool {
+	var wasLimited bool
+	for {
+		q.mut.Lock()
+		names := q.exceeded(count, size)
+		if len(names) == 0 {
+			q.inFlight += count
+			q.inFlightBytes += size
+			q.limited = map[string]bool{}
+			q.mInFlight.Set(q.inFlight)
+			q.mInFlightBytes.Set(q.inFlightBytes)
+			q.mut.Unlock()
+			return true
+		}
+		if !wasLimited {
+			wasLimited = true
+			for _, name := range names {
+				q.limitHits[name]++
+				q.mLimitHits.With(name).Incr(1)
+			}
+		}
+		for _, name := range names {
+			q.limited[name] = true
+		}
+		releasedChan := q.releasedChan
+		q.mut.Unlock()
+
+		select {
+		case <-releasedChan:
+		case <-q.closeChan:
+			return false
+		}
+	}
+}
+
+func (q *quotaGate) release(count, size int64) {
+	q.mut.Lock()
+	q.inFlight -= count
+	q.inFlightBytes -= size
+	q.mInFlight.Set(q.inFlight)
+	q.mInFlightBytes.Set(q.inFlightBytes)
+	close(q.releasedChan)
+	q.releasedChan = make(chan struct{})
+	q.mut.Unlock()
+}
+
+// wrap returns a channel of transactions that are admitted from another channel
+// according to the quotas, the returned channel is closed when the source
+// channel is closed or the gate is closed.
+func (q *quotaGate) wrap(tranChan <-chan message.Transaction) <-chan message.Transaction {
+	outChan := make(chan message.Transaction)
+	go func() {
+		defer close(outChan)
+		for {
+			var tran message.Transaction
+			var open bool
+			select {
+			case tran, open = <-tranChan:
+				if !open {
+					return
+				}
+			case <-q.closeChan:
+				return
+			}
+
+			// Transactions that are not admitted before the gate is closed are
+			// rejected so that the input is able to redeliver them.
+			count, size := int64(len(tran.Payload)), batchBytes(tran.Payload)
+			if !q.acquire(count, size) {
+				_ = tran.Ack(context.Background(), component.ErrTypeClosed)
+				return
+			}
+
+			var releaseOnce sync.Once
+			gatedTran := message.NewTransactionFunc(tran.Payload, func(ctx context.Context, err error) error {
+				releaseOnce.Do(func() {
+					q.release(count, size)
+				})
+				return tran.Ack(ctx, err)
+			})
+
+			select {
+			case outChan <- gatedTran:
+			case <-q.closeChan:
+				_ = gatedTran.Ack(context.Background(), component.ErrTypeClosed)
+				return
+			}
+		}
+	}()
+	return outChan
+}
+
+// close stops the gate from admitting any further transactions.
+func (q *quotaGate) close() {
+	q.closeOnce.Do(func() {
+		close(q.closeChan)
+	})
+}
+
+// status returns the current status of the quotas.
+func (q *quotaGate) status() QuotaStatus {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	s := QuotaStatus{
+		InFlight:            q.inFlight,
+		MaxInFlight:         q.conf.MaxInFlight,
+		InFlightBytes:       q.inFlightBytes,
+		MaxInFlightBytes:    q.conf.MaxInFlightBytes,
+		ProcessorThreads:    q.threads,
+		MaxProcessorThreads: q.conf.MaxProcessorThreads,
+		Limited:             []string{},
+		LimitHits:           map[string]int64{},
+	}
+	for _, name := range []string{QuotaMaxInFlight, QuotaMaxInFlightBytes} {
+		if q.limited[name] {
+			s.Limited = append(s.Limited, name)
+		}
+	}
+	if q.threadsLimited {
+		s.Limited = append(s.Limited, QuotaMaxProcessorThreads)
+	}
+	for k, v := range q.limitHits {
+		s.LimitHits[k] = v
+	}
+	return s
+}
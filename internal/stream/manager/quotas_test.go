@@ -0,0 +1,261 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
+	"github.com/benthosdev/benthos/v4/internal/component/testutil"
+	bmanager "github.com/benthosdev/benthos/v4/internal/manager"
+	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/stream"
+)
+
+func TestQuotaGateInFlight(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	var conf stream.Config
+	conf.Quotas.MaxInFlight = 3
+
+	stats := metrics.NewLocal()
+	gate := newQuotaGate(conf, false, stats)
+	t.Cleanup(gate.close)
+
+	inChan := make(chan message.Transaction)
+	outChan := gate.wrap(inChan)
+
+	resChan := make(chan error, 10)
+	send := func(contents ...string) {
+		t.Helper()
+		var batch message.Batch
+		for _, c := range contents {
+			batch = append(batch, message.NewPart([]byte(c)))
+		}
+		select {
+		case inChan <- message.NewTransaction(batch, resChan):
+		case <-tCtx.Done():
+			t.Fatal("timed out")
+		}
+	}
+	receive := func() message.Transaction {
+		t.Helper()
+		select {
+		case tran := <-outChan:
+			return tran
+		case <-tCtx.Done():
+			t.Fatal("timed out")
+		}
+		return message.Transaction{}
+	}
+
+	send("a", "b")
+	first := receive()
+	assert.Len(t, first.Payload, 2)
+
+	send("c", "d")
+	select {
+	case <-outChan:
+		t.Fatal("expected batch to be blocked by quota")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	status := gate.status()
+	assert.Equal(t, int64(2), status.InFlight)
+	assert.Equal(t, int64(2), status.InFlightBytes)
+	assert.Equal(t, []string{QuotaMaxInFlight}, status.Limited)
+	assert.Equal(t, map[string]int64{QuotaMaxInFlight: 1}, status.LimitHits)
+
+	require.NoError(t, first.Ack(tCtx, nil))
+	require.NoError(t, <-resChan)
+
+	second := receive()
+	assert.Len(t, second.Payload, 2)
+
+	status = gate.status()
+	assert.Equal(t, int64(2), status.InFlight)
+	assert.Equal(t, []string{}, status.Limited)
+
+	require.NoError(t, second.Ack(tCtx, nil))
+	require.NoError(t, <-resChan)
+	assert.Equal(t, int64(0), gate.status().InFlight)
+
+	assert.Equal(t, int64(1), stats.GetCounters()["stream_quota_limited{quota=\"max_in_flight\"}"])
+}
+
+func TestQuotaGateInFlightBytes(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	var conf stream.Config
+	conf.Quotas.MaxInFlightBytes = 10
+
+	gate := newQuotaGate(conf, false, metrics.Noop())
+	t.Cleanup(gate.close)
+
+	inChan := make(chan message.Transaction)
+	outChan := gate.wrap(inChan)
+
+	resChan := make(chan error, 10)
+
+	// A batch larger than the quota is admitted when nothing else is in
+	// flight.
+	inChan <- message.NewTransaction(message.QuickBatch([][]byte{[]byte("hello world")}), resChan)
+	first := <-outChan
+
+	go func() {
+		inChan <- message.NewTransaction(message.QuickBatch([][]byte{[]byte("foo")}), resChan)
+	}()
+
+	select {
+	case <-outChan:
+		t.Fatal("expected batch to be blocked by quota")
+	case <-time.After(time.Millisecond * 50):
+	}
+	assert.Equal(t, []string{QuotaMaxInFlightBytes}, gate.status().Limited)
+
+	require.NoError(t, first.Ack(tCtx, nil))
+
+	select {
+	case second := <-outChan:
+		assert.Equal(t, "foo", string(second.Payload[0].AsBytes()))
+		assert.Equal(t, int64(3), gate.status().InFlightBytes)
+	case <-tCtx.Done():
+		t.Fatal("timed out")
+	}
+}
+
+func TestQuotaGateClose(t *testing.T) {
+	var conf stream.Config
+	conf.Quotas.MaxInFlight = 1
+
+	gate := newQuotaGate(conf, false, metrics.Noop())
+
+	inChan := make(chan message.Transaction)
+	outChan := gate.wrap(inChan)
+
+	resChan := make(chan error, 10)
+	inChan <- message.NewTransaction(message.QuickBatch([][]byte{[]byte("a")}), resChan)
+	<-outChan
+	inChan <- message.NewTransaction(message.QuickBatch([][]byte{[]byte("b")}), resChan)
+
+	gate.close()
+
+	select {
+	case _, open := <-outChan:
+		assert.False(t, open)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+
+	// The transaction that was blocked by the quota is rejected.
+	select {
+	case err := <-resChan:
+		assert.ErrorIs(t, err, component.ErrTypeClosed)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+}
+
+func TestQuotaGateCloseWhileSending(t *testing.T) {
+	gate := newQuotaGate(stream.Config{}, false, metrics.Noop())
+
+	inChan := make(chan message.Transaction)
+	_ = gate.wrap(inChan)
+
+	// The transaction is admitted but never read from the output channel.
+	resChan := make(chan error, 10)
+	inChan <- message.NewTransaction(message.QuickBatch([][]byte{[]byte("a")}), resChan)
+
+	gate.close()
+
+	select {
+	case err := <-resChan:
+		assert.ErrorIs(t, err, component.ErrTypeClosed)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	assert.Equal(t, int64(0), gate.status().InFlight)
+}
+
+func TestApplyThreadQuota(t *testing.T) {
+	tests := []struct {
+		threads, max, exp int
+		limited           bool
+	}{
+		{threads: -1, max: 0, exp: -1},
+		{threads: -1, max: 2, exp: 2, limited: true},
+		{threads: 4, max: 2, exp: 2, limited: true},
+		{threads: 1, max: 2, exp: 1},
+	}
+
+	for _, test := range tests {
+		var conf stream.Config
+		conf.Pipeline.Threads = test.threads
+		conf.Quotas.MaxProcessorThreads = test.max
+
+		assert.Equal(t, test.limited, applyThreadQuota(&conf))
+		assert.Equal(t, test.exp, conf.Pipeline.Threads)
+	}
+}
+
+func TestQuotaBatchingRejected(t *testing.T) {
+	tests := map[string]struct {
+		batching string
+		errStr   string
+	}{
+		"count within quota": {
+			batching: `{ count: 5 }`,
+		},
+		"count exceeds quota": {
+			batching: `{ count: 20 }`,
+			errStr:   "the batching count 20 of output broker exceeds the max_in_flight quota of 10",
+		},
+		"count exceeds quota with period": {
+			batching: `{ count: 20, period: 1s }`,
+		},
+		"count exceeds quota with reachable byte size": {
+			batching: `{ count: 20, byte_size: 100 }`,
+		},
+		"byte size exceeds quota": {
+			batching: `{ byte_size: 2000 }`,
+			errStr:   "the batching byte_size 2000 of output broker exceeds the max_in_flight_bytes quota of 1000",
+		},
+	}
+
+	bmgr, err := bmanager.New(bmanager.NewResourceConfig())
+	require.NoError(t, err)
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := testutil.StreamFromYAML(`
+input:
+  generate:
+    mapping: 'root = deleted()'
+output:
+  broker:
+    outputs: [ { drop: {} } ]
+    batching: %v
+quotas:
+  max_in_flight: 10
+  max_in_flight_bytes: 1000
+`, test.batching)
+			require.NoError(t, err)
+
+			mgr := New(bmgr)
+			err = mgr.checkQuotaBatching(conf)
+			if test.errStr == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errStr)
+			}
+		})
+	}
+}
@@ -0,0 +1,677 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
+	"github.com/benthosdev/benthos/v4/internal/component/input"
+	"github.com/benthosdev/benthos/v4/internal/component/output"
+	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/component/ratelimit"
+	"github.com/benthosdev/benthos/v4/internal/docs"
+	bmanager "github.com/benthosdev/benthos/v4/internal/manager"
+	"github.com/benthosdev/benthos/v4/internal/stream"
+)
+
+// resourceKinds lists the types of resources that can be modified through the
+// stream manager, in the order in which they are stored by a transaction.
+// Components are only able to reference resources of a kind that precedes
+// their own, and so resources are removed in the reverse order.
+var resourceKinds = []docs.Type{
+	docs.TypeRateLimit,
+	docs.TypeCache,
+	docs.TypeProcessor,
+	docs.TypeInput,
+	docs.TypeOutput,
+}
+
+// transactionRollbackTimeout is the maximum period of time allowed for rolling
+// back the changes of a failed transaction. Rollbacks are not bound to the
+// context of the transaction since it might have been the cause of the failure.
+var transactionRollbackTimeout = time.Minute
+
+func isResourceKind(kind docs.Type) bool {
+	for _, k := range resourceKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// OptResourceConfig sets the resource configs that the underlying manager was
+// created with. The stream manager tracks the configs of resources so that they
+// can be restored when a transaction fails, and resources that were created
+// before the stream manager cannot be modified by a transaction unless their
+// configs are provided with this option.
+func OptResourceConfig(conf bmanager.ResourceConfig) func(*Type) {
+	return func(t *Type) {
+		for _, c := range conf.ResourceRateLimits {
+			t.setResourceConf(docs.TypeRateLimit, c.Label, c)
+		}
+		for _, c := range conf.ResourceCaches {
+			t.setResourceConf(docs.TypeCache, c.Label, c)
+		}
+		for _, c := range conf.ResourceProcessors {
+			t.setResourceConf(docs.TypeProcessor, c.Label, c)
+		}
+		for _, c := range conf.ResourceInputs {
+			t.setResourceConf(docs.TypeInput, c.Label, c)
+		}
+		for _, c := range conf.ResourceOutputs {
+			t.setResourceConf(docs.TypeOutput, c.Label, c)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (m *Type) setResourceConf(kind docs.Type, id string, conf any) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	confs, exists := m.resources[kind]
+	if !exists {
+		confs = map[string]any{}
+		m.resources[kind] = confs
+	}
+	if conf == nil {
+		delete(confs, id)
+		return
+	}
+	confs[id] = conf
+}
+
+func (m *Type) getResourceConf(kind docs.Type, id string) (any, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	conf, exists := m.resources[kind][id]
+	return conf, exists
+}
+
+// parseResource parses a resource config of a given kind.
+func (m *Type) parseResource(kind docs.Type, node *yaml.Node) (any, error) {
+	switch kind {
+	case docs.TypeCache:
+		return cache.FromAny(m.manager.Environment(), node)
+	case docs.TypeInput:
+		return input.FromAny(m.manager.Environment(), node)
+	case docs.TypeOutput:
+		return output.FromAny(m.manager.Environment(), node)
+	case docs.TypeProcessor:
+		return processor.FromAny(m.manager.Environment(), node)
+	case docs.TypeRateLimit:
+		return ratelimit.FromAny(m.manager.Environment(), node)
+	}
+	return nil, fmt.Errorf("resource type not supported: %v", kind)
+}
+
+func (m *Type) probeResource(kind docs.Type, id string) bool {
+	switch kind {
+	case docs.TypeCache:
+		return m.manager.ProbeCache(id)
+	case docs.TypeInput:
+		return m.manager.ProbeInput(id)
+	case docs.TypeOutput:
+		return m.manager.ProbeOutput(id)
+	case docs.TypeProcessor:
+		return m.manager.ProbeProcessor(id)
+	case docs.TypeRateLimit:
+		return m.manager.ProbeRateLimit(id)
+	}
+	return false
+}
+
+// storeResource creates or replaces a resource and records its config.
+func (m *Type) storeResource(ctx context.Context, kind docs.Type, id string, conf any) (err error) {
+	switch c := conf.(type) {
+	case cache.Config:
+		err = m.manager.StoreCache(ctx, id, c)
+	case input.Config:
+		err = m.manager.StoreInput(ctx, id, c)
+	case output.Config:
+		err = m.manager.StoreOutput(ctx, id, c)
+	case processor.Config:
+		err = m.manager.StoreProcessor(ctx, id, c)
+	case ratelimit.Config:
+		err = m.manager.StoreRateLimit(ctx, id, c)
+	default:
+		err = fmt.Errorf("resource type not supported: %T", conf)
+	}
+	if err == nil {
+		m.setResourceConf(kind, id, conf)
+	}
+	return
+}
+
+// removeResource closes and removes a resource along with its config.
+func (m *Type) removeResource(ctx context.Context, kind docs.Type, id string) (err error) {
+	switch kind {
+	case docs.TypeCache:
+		err = m.manager.RemoveCache(ctx, id)
+	case docs.TypeInput:
+		err = m.manager.RemoveInput(ctx, id)
+	case docs.TypeOutput:
+		err = m.manager.RemoveOutput(ctx, id)
+	case docs.TypeProcessor:
+		err = m.manager.RemoveProcessor(ctx, id)
+	case docs.TypeRateLimit:
+		err = m.manager.RemoveRateLimit(ctx, id)
+	default:
+		err = fmt.Errorf("resource type not supported: %v", kind)
+	}
+	if err == nil {
+		m.setResourceConf(kind, id, nil)
+	}
+	return
+}
+
+// resourceRaw converts a resource config into the structure that it would be
+// written as within a config file.
+func resourceRaw(conf any) any {
+	var node yaml.Node
+	if err := node.Encode(conf); err != nil {
+		return nil
+	}
+	var fields map[string]any
+	if err := node.Decode(&fields); err != nil {
+		return nil
+	}
+	return componentRaw(fields)
+}
+
+func componentRaw(fields map[string]any) map[string]any {
+	typeStr, _ := fields["type"].(string)
+	raw := map[string]any{
+		typeStr: fields["plugin"],
+	}
+	if label, _ := fields["label"].(string); label != "" {
+		raw["label"] = label
+	}
+	if procs, _ := fields["processors"].([]any); len(procs) > 0 {
+		rawProcs := make([]any, 0, len(procs))
+		for _, p := range procs {
+			pFields, _ := p.(map[string]any)
+			rawProcs = append(rawProcs, componentRaw(pFields))
+		}
+		raw["processors"] = rawProcs
+	}
+	return raw
+}
+
+//------------------------------------------------------------------------------
+
+// Transaction describes a set of changes to streams and resources that are
+// validated and applied together. A nil config deletes the stream or resource
+// of the given id.
+type Transaction struct {
+	// Streams maps stream ids to their new configs.
+	Streams map[string]*stream.Config
+
+	// Resources maps resource types to a map of resource ids to their new
+	// configs, which must be of the config type of the resource (cache.Config,
+	// input.Config, etc).
+	Resources map[docs.Type]map[string]any
+}
+
+// The actions that a transaction can perform on a stream or resource.
+const (
+	ChangeCreate    = "create"
+	ChangeUpdate    = "update"
+	ChangeDelete    = "delete"
+	ChangeUnchanged = "unchanged"
+)
+
+// Change describes the effect of a transaction on a stream or resource, where
+// the kind is either "stream" or the type of a resource.
+type Change struct {
+	Kind   string `json:"kind"`
+	ID     string `json:"id"`
+	Action string `json:"action"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// TransactionError is returned when a transaction could not be applied, and
+// contains any errors encountered whilst rolling back the changes that were
+// applied before the failure.
+type TransactionError struct {
+	Err          error
+	RollbackErrs []error
+}
+
+// Error returns a human readable error string.
+func (e *TransactionError) Error() string {
+	if len(e.RollbackErrs) == 0 {
+		return fmt.Sprintf("failed to apply transaction: %v", e.Err)
+	}
+	rbErrs := make([]string, 0, len(e.RollbackErrs))
+	for _, err := range e.RollbackErrs {
+		rbErrs = append(rbErrs, err.Error())
+	}
+	return fmt.Sprintf("failed to apply transaction: %v, and failed to roll back: %v", e.Err, strings.Join(rbErrs, ", "))
+}
+
+// Unwrap returns the underlying error that caused the transaction to fail.
+func (e *TransactionError) Unwrap() error {
+	return e.Err
+}
+
+// plannedChange is a change along with the typed configs required in order to
+// apply it and to undo it.
+type plannedChange struct {
+	Change
+	resourceKind docs.Type
+	prev, next   any
+}
+
+func changesOf(planned []plannedChange) []Change {
+	changes := make([]Change, 0, len(planned))
+	for _, p := range planned {
+		changes = append(changes, p.Change)
+	}
+	return changes
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *Type) planTransaction(tx Transaction) ([]plannedChange, error) {
+	var planned []plannedChange
+
+	for kind := range tx.Resources {
+		if !isResourceKind(kind) {
+			return nil, fmt.Errorf("resource type not supported: %v", kind)
+		}
+	}
+
+	for _, kind := range resourceKinds {
+		confs := tx.Resources[kind]
+		for _, id := range sortedKeys(confs) {
+			conf := confs[id]
+			prev, known := m.getResourceConf(kind, id)
+			exists := m.probeResource(kind, id)
+			if exists && !known {
+				return nil, fmt.Errorf("%v resource '%v' was not created by the streams API and cannot be modified", kind, id)
+			}
+
+			c := plannedChange{
+				Change:       Change{Kind: string(kind), ID: id},
+				resourceKind: kind,
+				next:         conf,
+			}
+			if exists {
+				c.prev = prev
+				c.Before = resourceRaw(prev)
+			}
+			if conf != nil {
+				c.After = resourceRaw(conf)
+			}
+
+			switch {
+			case conf == nil && !exists:
+				return nil, fmt.Errorf("%v resource '%v' does not exist", kind, id)
+			case conf == nil:
+				c.Action = ChangeDelete
+			case !exists:
+				c.Action = ChangeCreate
+			case reflect.DeepEqual(c.Before, c.After):
+				c.Action = ChangeUnchanged
+			default:
+				c.Action = ChangeUpdate
+			}
+			planned = append(planned, c)
+		}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.closed {
+		return nil, errors.New("stream manager is closed")
+	}
+
+	for _, id := range sortedKeys(tx.Streams) {
+		conf := tx.Streams[id]
+
+		c := plannedChange{
+			Change: Change{Kind: "stream", ID: id},
+		}
+		wrapper, exists := m.streams[id]
+		if exists {
+			prev := wrapper.Config()
+			c.prev = &prev
+			c.Before = prev.GetRawSource()
+		}
+		if conf != nil {
+			c.next = conf
+			c.After = conf.GetRawSource()
+		}
+
+		switch {
+		case conf == nil && !exists:
+			return nil, fmt.Errorf("stream '%v': %w", id, ErrStreamDoesNotExist)
+		case conf == nil:
+			c.Action = ChangeDelete
+		case !exists:
+			c.Action = ChangeCreate
+		case c.Before != nil && reflect.DeepEqual(c.Before, c.After):
+			c.Action = ChangeUnchanged
+		default:
+			c.Action = ChangeUpdate
+		}
+		planned = append(planned, c)
+	}
+
+	if err := m.checkResourceRefs(tx, planned); err != nil {
+		return nil, err
+	}
+	return planned, nil
+}
+
+// checkResourceRefs returns an error if a resource deleted by a transaction is
+// referenced by a stream that remains once the transaction is applied. The
+// manager lock must be held by the caller.
+func (m *Type) checkResourceRefs(tx Transaction, planned []plannedChange) error {
+	deleted := map[docs.Type]map[string]struct{}{}
+	for _, c := range planned {
+		if c.resourceKind == "" || c.Action != ChangeDelete {
+			continue
+		}
+		if deleted[c.resourceKind] == nil {
+			deleted[c.resourceKind] = map[string]struct{}{}
+		}
+		deleted[c.resourceKind][c.ID] = struct{}{}
+	}
+	if len(deleted) == 0 {
+		return nil
+	}
+
+	remaining := map[string]stream.Config{}
+	for id, wrapper := range m.streams {
+		remaining[id] = wrapper.Config()
+	}
+	for id, conf := range tx.Streams {
+		if conf == nil {
+			delete(remaining, id)
+		} else {
+			remaining[id] = *conf
+		}
+	}
+
+	for _, id := range sortedKeys(remaining) {
+		refs, err := m.streamResourceRefs(remaining[id])
+		if err != nil {
+			return fmt.Errorf("stream '%v': %w", id, err)
+		}
+		for _, kind := range resourceKinds {
+			for _, name := range sortedKeys(refs[kind]) {
+				if _, exists := deleted[kind][name]; exists {
+					return fmt.Errorf("%v resource '%v' cannot be deleted as it is referenced by stream '%v'", kind, name, id)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// resourceRefFields lists the fields of components that reference a resource
+// by name, beyond the fields `cache` and `rate_limit` which always reference a
+// resource of the respective kind.
+var resourceRefFields = map[docs.Type]map[string]map[string]docs.Type{
+	docs.TypeInput: {
+		"cache": {"resource": docs.TypeCache},
+	},
+	docs.TypeOutput: {
+		"cache": {"target": docs.TypeCache},
+	},
+	docs.TypeProcessor: {
+		"cache":      {"resource": docs.TypeCache},
+		"rate_limit": {"resource": docs.TypeRateLimit},
+	},
+}
+
+// streamConfigNode returns the config of a stream as it would be written within
+// a config file.
+func streamConfigNode(conf stream.Config) (*yaml.Node, error) {
+	raw := conf.GetRawSource()
+	if raw == nil {
+		procs := make([]any, 0, len(conf.Pipeline.Processors))
+		for _, p := range conf.Pipeline.Processors {
+			procs = append(procs, resourceRaw(p))
+		}
+		raw = map[string]any{
+			"input":    resourceRaw(conf.Input),
+			"pipeline": map[string]any{"processors": procs},
+			"output":   resourceRaw(conf.Output),
+		}
+	}
+
+	var node yaml.Node
+	if err := node.Encode(raw); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// componentPluginNode returns the config node specific to the implementation
+// of a walked component, or nil if it is not set.
+func componentPluginNode(c docs.WalkedYAMLComponent) *yaml.Node {
+	for i := 0; i < len(c.Conf.Content)-1; i += 2 {
+		if c.Conf.Content[i].Value == c.Name {
+			return c.Conf.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// streamResourceRefs walks the components of a stream config and returns the
+// names of the resources that they reference.
+func (m *Type) streamResourceRefs(conf stream.Config) (map[docs.Type]map[string]struct{}, error) {
+	node, err := streamConfigNode(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := map[docs.Type]map[string]struct{}{}
+	addRef := func(kind docs.Type, name string) {
+		if name == "" {
+			return
+		}
+		if refs[kind] == nil {
+			refs[kind] = map[string]struct{}{}
+		}
+		refs[kind][name] = struct{}{}
+	}
+
+	// Fields named cache or rate_limit are checked at any depth, as they're
+	// often nested within common objects such as retry or request fields.
+	var scanFields func(key string, n *yaml.Node)
+	scanFields = func(key string, n *yaml.Node) {
+		switch n.Kind {
+		case yaml.ScalarNode:
+			switch key {
+			case "cache":
+				addRef(docs.TypeCache, n.Value)
+			case "rate_limit":
+				addRef(docs.TypeRateLimit, n.Value)
+			}
+		case yaml.MappingNode:
+			for i := 0; i < len(n.Content)-1; i += 2 {
+				scanFields(n.Content[i].Value, n.Content[i+1])
+			}
+		case yaml.SequenceNode:
+			for _, v := range n.Content {
+				scanFields("", v)
+			}
+		}
+	}
+
+	err = stream.Spec().WalkYAML(node, m.manager.Environment(), func(c docs.WalkedYAMLComponent) error {
+		pluginNode := componentPluginNode(c)
+		if pluginNode == nil {
+			return nil
+		}
+		if c.Name == "resource" {
+			if pluginNode.Kind == yaml.ScalarNode {
+				addRef(c.ComponentType, pluginNode.Value)
+			}
+			return nil
+		}
+		if pluginNode.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		fields := resourceRefFields[c.ComponentType][c.Name]
+		for i := 0; i < len(pluginNode.Content)-1; i += 2 {
+			key, v := pluginNode.Content[i].Value, pluginNode.Content[i+1]
+			if refKind, exists := fields[key]; exists && v.Kind == yaml.ScalarNode {
+				addRef(refKind, v.Value)
+				continue
+			}
+			scanFields(key, v)
+		}
+		return nil
+	})
+	return refs, err
+}
+
+// Diff validates a transaction and returns the changes that it would make
+// without applying them.
+func (m *Type) Diff(tx Transaction) ([]Change, error) {
+	planned, err := m.planTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	return changesOf(planned), nil
+}
+
+// Apply validates a transaction and applies all of its changes, returning the
+// changes that were made. Resources are stored before streams are modified, and
+// resources are only removed once streams have been modified, so that streams
+// never reference resources that do not exist. If any change fails then all
+// changes that were already applied are rolled back to their previous configs
+// and a *TransactionError is returned.
+func (m *Type) Apply(ctx context.Context, tx Transaction) ([]Change, error) {
+	m.txLock.Lock()
+	defer m.txLock.Unlock()
+
+	planned, err := m.planTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var undo []func(context.Context) error
+	fail := func(err error) ([]Change, error) {
+		rbCtx, done := context.WithTimeout(context.Background(), transactionRollbackTimeout)
+		defer done()
+
+		txErr := &TransactionError{Err: err}
+		for i := len(undo) - 1; i >= 0; i-- {
+			if rErr := undo[i](rbCtx); rErr != nil {
+				txErr.RollbackErrs = append(txErr.RollbackErrs, rErr)
+			}
+		}
+		return changesOf(planned), txErr
+	}
+
+	for _, c := range planned {
+		if c.resourceKind == "" || (c.Action != ChangeCreate && c.Action != ChangeUpdate) {
+			continue
+		}
+		if err := m.storeResource(ctx, c.resourceKind, c.ID, c.next); err != nil {
+			return fail(fmt.Errorf("%v resource '%v': %w", c.Kind, c.ID, err))
+		}
+		undo = append(undo, m.resourceUndo(c))
+	}
+
+	for _, action := range []string{ChangeDelete, ChangeUpdate, ChangeCreate} {
+		for _, c := range planned {
+			if c.resourceKind != "" || c.Action != action {
+				continue
+			}
+			var err error
+			switch action {
+			case ChangeDelete:
+				err = m.Delete(ctx, c.ID)
+			case ChangeUpdate:
+				err = m.Update(ctx, c.ID, *c.next.(*stream.Config))
+			case ChangeCreate:
+				err = m.Create(c.ID, *c.next.(*stream.Config))
+			}
+			// An update may fail after the previous stream was removed, and so
+			// the undo is always registered.
+			undo = append(undo, m.streamUndo(c))
+			if err != nil {
+				return fail(fmt.Errorf("stream '%v': %w", c.ID, err))
+			}
+		}
+	}
+
+	for i := len(planned) - 1; i >= 0; i-- {
+		c := planned[i]
+		if c.resourceKind == "" || c.Action != ChangeDelete {
+			continue
+		}
+		if err := m.removeResource(ctx, c.resourceKind, c.ID); err != nil {
+			return fail(fmt.Errorf("%v resource '%v': %w", c.Kind, c.ID, err))
+		}
+		undo = append(undo, m.resourceUndo(c))
+	}
+
+	return changesOf(planned), nil
+}
+
+// resourceUndo returns a func that restores a resource to its config prior to
+// a change.
+func (m *Type) resourceUndo(c plannedChange) func(context.Context) error {
+	return func(ctx context.Context) error {
+		var err error
+		if c.prev == nil {
+			err = m.removeResource(ctx, c.resourceKind, c.ID)
+		} else {
+			err = m.storeResource(ctx, c.resourceKind, c.ID, c.prev)
+		}
+		if err != nil {
+			return fmt.Errorf("%v resource '%v': %w", c.Kind, c.ID, err)
+		}
+		return nil
+	}
+}
+
+// streamUndo returns a func that restores a stream to its config prior to a
+// change.
+func (m *Type) streamUndo(c plannedChange) func(context.Context) error {
+	return func(ctx context.Context) error {
+		var err error
+		if c.prev == nil {
+			if err = m.Delete(ctx, c.ID); errors.Is(err, ErrStreamDoesNotExist) {
+				err = nil
+			}
+		} else {
+			prev := *c.prev.(*stream.Config)
+			if err = m.Update(ctx, c.ID, prev); errors.Is(err, ErrStreamDoesNotExist) {
+				err = m.Create(c.ID, prev)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("stream '%v': %w", c.ID, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,380 @@
+package manager_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
+	"github.com/benthosdev/benthos/v4/internal/component/testutil"
+	"github.com/benthosdev/benthos/v4/internal/docs"
+	bmanager "github.com/benthosdev/benthos/v4/internal/manager"
+	"github.com/benthosdev/benthos/v4/internal/stream"
+	"github.com/benthosdev/benthos/v4/internal/stream/manager"
+)
+
+type applyResult struct {
+	Applied        bool             `json:"applied"`
+	DryRun         bool             `json:"dry_run"`
+	Diff           []manager.Change `json:"diff"`
+	Error          string           `json:"error"`
+	RolledBack     bool             `json:"rolled_back"`
+	RollbackErrors []string         `json:"rollback_errors"`
+}
+
+func doApply(t testing.TB, mgr *manager.Type, url, body string) (int, applyResult) {
+	t.Helper()
+
+	response := httptest.NewRecorder()
+	router(mgr).ServeHTTP(response, genYAMLRequest("POST", url, body))
+
+	var res applyResult
+	if response.Header().Get("Content-Type") == "application/json" {
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &res), response.Body.String())
+	}
+	return response.Code, res
+}
+
+func changeActions(changes []manager.Change) map[string]string {
+	actions := map[string]string{}
+	for _, c := range changes {
+		actions[c.Kind+":"+c.ID] = c.Action
+	}
+	return actions
+}
+
+func TestTypeAPIApply(t *testing.T) {
+	bmgr, err := bmanager.New(bmanager.NewResourceConfig())
+	require.NoError(t, err)
+
+	mgr := manager.New(bmgr)
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+		defer done()
+		assert.NoError(t, mgr.Stop(ctx))
+	})
+
+	code, res := doApply(t, mgr, "/apply", `
+resources:
+  input:
+    foo_in:
+      generate:
+        mapping: 'root = "foo"'
+        interval: 1s
+  cache:
+    foo_cache:
+      memory: {}
+streams:
+  foo:
+    input:
+      resource: foo_in
+    output:
+      drop: {}
+`)
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, res.Applied)
+	assert.Equal(t, map[string]string{
+		"cache:foo_cache": manager.ChangeCreate,
+		"input:foo_in":    manager.ChangeCreate,
+		"stream:foo":      manager.ChangeCreate,
+	}, changeActions(res.Diff))
+	assert.True(t, bmgr.ProbeInput("foo_in"))
+	assert.True(t, bmgr.ProbeCache("foo_cache"))
+
+	_, err = mgr.Read("foo")
+	require.NoError(t, err)
+
+	code, res = doApply(t, mgr, "/apply?dry_run=true", `
+resources:
+  cache:
+    foo_cache:
+      memory:
+        default_ttl: 10s
+    bar_cache:
+      memory: {}
+  input:
+    foo_in:
+      generate:
+        mapping: 'root = "foo"'
+        interval: 1s
+streams:
+  foo: null
+`)
+	require.Equal(t, http.StatusOK, code)
+	assert.False(t, res.Applied)
+	assert.True(t, res.DryRun)
+	assert.Equal(t, map[string]string{
+		"cache:bar_cache": manager.ChangeCreate,
+		"cache:foo_cache": manager.ChangeUpdate,
+		"input:foo_in":    manager.ChangeUnchanged,
+		"stream:foo":      manager.ChangeDelete,
+	}, changeActions(res.Diff))
+	assert.False(t, bmgr.ProbeCache("bar_cache"))
+
+	_, err = mgr.Read("foo")
+	require.NoError(t, err)
+
+	// The second stream references a resource that does not exist, and so all
+	// changes must be rolled back.
+	code, res = doApply(t, mgr, "/apply", `
+resources:
+  cache:
+    foo_cache:
+      memory:
+        default_ttl: 10s
+    bar_cache:
+      memory: {}
+streams:
+  foo: null
+  bar:
+    input:
+      resource: does_not_exist
+    output:
+      drop: {}
+`)
+	require.Equal(t, http.StatusBadGateway, code)
+	assert.False(t, res.Applied)
+	assert.True(t, res.RolledBack)
+	assert.Empty(t, res.RollbackErrors)
+	assert.Contains(t, res.Error, "stream 'bar'")
+
+	assert.False(t, bmgr.ProbeCache("bar_cache"))
+	_, err = mgr.Read("foo")
+	require.NoError(t, err)
+	_, err = mgr.Read("bar")
+	require.Equal(t, manager.ErrStreamDoesNotExist, err)
+
+	changes, err := mgr.Diff(manager.Transaction{
+		Resources: map[docs.Type]map[string]any{
+			docs.TypeCache: {
+				"foo_cache": cache.Config{Type: "memory", Plugin: map[string]any{}},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"cache:foo_cache": manager.ChangeUnchanged,
+	}, changeActions(changes))
+
+	code, res = doApply(t, mgr, "/apply", `
+resources:
+  input:
+    foo_in: null
+streams:
+  foo: null
+`)
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, res.Applied)
+	assert.Equal(t, map[string]string{
+		"input:foo_in": manager.ChangeDelete,
+		"stream:foo":   manager.ChangeDelete,
+	}, changeActions(res.Diff))
+	assert.False(t, bmgr.ProbeInput("foo_in"))
+
+	_, err = mgr.Read("foo")
+	require.Equal(t, manager.ErrStreamDoesNotExist, err)
+}
+
+func TestTypeAPIApplyValidation(t *testing.T) {
+	resConf := bmanager.NewResourceConfig()
+	cacheConf := cache.NewConfig()
+	cacheConf.Label = "foo_cache"
+	resConf.ResourceCaches = append(resConf.ResourceCaches, cacheConf)
+
+	bmgr, err := bmanager.New(resConf)
+	require.NoError(t, err)
+
+	mgr := manager.New(bmgr)
+
+	code, res := doApply(t, mgr, "/apply", `
+resources:
+  cache:
+    bar_cache:
+      memory:
+        nope: nah
+streams:
+  foo:
+    input:
+      generate:
+        mapping: 'root = "foo"'
+        nope: nah
+    output:
+      drop: {}
+`)
+	require.Equal(t, http.StatusBadRequest, code)
+	assert.False(t, res.Applied)
+	assert.False(t, bmgr.ProbeCache("bar_cache"))
+
+	request := genYAMLRequest("POST", "/apply", `
+resources:
+  cache:
+    foo_cache: null
+`)
+	response := httptest.NewRecorder()
+	router(mgr).ServeHTTP(response, request)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), "cannot be modified")
+	assert.True(t, bmgr.ProbeCache("foo_cache"))
+
+	mgr = manager.New(bmgr, manager.OptResourceConfig(resConf))
+
+	code, res = doApply(t, mgr, "/apply", `
+resources:
+  cache:
+    foo_cache: null
+`)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]string{
+		"cache:foo_cache": manager.ChangeDelete,
+	}, changeActions(res.Diff))
+	assert.False(t, bmgr.ProbeCache("foo_cache"))
+}
+
+func TestTypeAPIApplyResourceInUse(t *testing.T) {
+	bmgr, err := bmanager.New(bmanager.NewResourceConfig())
+	require.NoError(t, err)
+
+	mgr := manager.New(bmgr)
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+		defer done()
+		assert.NoError(t, mgr.Stop(ctx))
+	})
+
+	code, _ := doApply(t, mgr, "/apply", `
+resources:
+  cache:
+    foo_cache:
+      memory: {}
+  rate_limit:
+    foo_rl:
+      local: {}
+streams:
+  foo:
+    input:
+      generate:
+        mapping: 'root = "foo"'
+        interval: 1s
+    pipeline:
+      processors:
+        - cache:
+            resource: foo_cache
+            operator: set
+            key: foo
+            value: bar
+    output:
+      drop: {}
+  bar:
+    input:
+      generate:
+        mapping: 'root = "bar"'
+        interval: 1s
+    output:
+      http_client:
+        url: http://localhost:1
+        rate_limit: foo_rl
+`)
+	require.Equal(t, http.StatusOK, code)
+
+	request := genYAMLRequest("POST", "/apply", `
+resources:
+  cache:
+    foo_cache: null
+`)
+	response := httptest.NewRecorder()
+	router(mgr).ServeHTTP(response, request)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), "cache resource 'foo_cache' cannot be deleted as it is referenced by stream 'foo'")
+	assert.True(t, bmgr.ProbeCache("foo_cache"))
+
+	request = genYAMLRequest("POST", "/apply", `
+resources:
+  rate_limit:
+    foo_rl: null
+streams:
+  foo: null
+`)
+	response = httptest.NewRecorder()
+	router(mgr).ServeHTTP(response, request)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), "rate_limit resource 'foo_rl' cannot be deleted as it is referenced by stream 'bar'")
+	assert.True(t, bmgr.ProbeRateLimit("foo_rl"))
+
+	code, res := doApply(t, mgr, "/apply", `
+resources:
+  cache:
+    foo_cache: null
+streams:
+  foo: null
+`)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]string{
+		"cache:foo_cache": manager.ChangeDelete,
+		"stream:foo":      manager.ChangeDelete,
+	}, changeActions(res.Diff))
+	assert.False(t, bmgr.ProbeCache("foo_cache"))
+}
+
+func TestTypeApplyStreamUpdate(t *testing.T) {
+	bmgr, err := bmanager.New(bmanager.NewResourceConfig())
+	require.NoError(t, err)
+
+	mgr := manager.New(bmgr)
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+		defer done()
+		assert.NoError(t, mgr.Stop(ctx))
+	})
+
+	conf, err := testutil.StreamFromYAML(`
+input:
+  generate:
+    mapping: 'root = "foo"'
+    interval: 1s
+output:
+  drop: {}
+`)
+	require.NoError(t, err)
+	require.NoError(t, mgr.Create("foo", conf))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	changes, err := mgr.Apply(ctx, manager.Transaction{
+		Streams: map[string]*stream.Config{"foo": &conf},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"stream:foo": manager.ChangeUnchanged,
+	}, changeActions(changes))
+
+	newConf, err := testutil.StreamFromYAML(`
+input:
+  generate:
+    mapping: 'root = "bar"'
+    interval: 1s
+output:
+  drop: {}
+`)
+	require.NoError(t, err)
+
+	changes, err = mgr.Apply(ctx, manager.Transaction{
+		Streams: map[string]*stream.Config{"foo": &newConf},
+	})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, manager.ChangeUpdate, changes[0].Action)
+	assert.Equal(t, conf.GetRawSource(), changes[0].Before)
+	assert.Equal(t, newConf.GetRawSource(), changes[0].After)
+
+	status, err := mgr.Read("foo")
+	require.NoError(t, err)
+	currentConf := status.Config()
+	assert.Equal(t, newConf.GetRawSource(), currentConf.GetRawSource())
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/metrics"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/docs"
 	"github.com/benthosdev/benthos/v4/internal/stream"
 )
 
@@ -21,6 +22,7 @@ type StreamStatus struct {
 	config       stream.Config
 	strm         *stream.Type
 	metrics      *metrics.Local
+	quotas       *quotaGate
 	createdAt    time.Time
 }
 
@@ -66,6 +68,15 @@ func (s *StreamStatus) Metrics() *metrics.Local {
 	return s.metrics
 }
 
+// Quotas returns the status of the quotas of the stream, or false if the
+// stream does not have quotas configured.
+func (s *StreamStatus) Quotas() (QuotaStatus, bool) {
+	if s.quotas == nil {
+		return QuotaStatus{}, false
+	}
+	return s.quotas.status(), true
+}
+
 // setClosed sets the flag indicating that the stream is closed.
 func (s *StreamStatus) setClosed() {
 	atomic.SwapInt64(&s.stoppedAfter, int64(time.Since(s.createdAt)))
@@ -85,16 +96,22 @@ type Type struct {
 	closed  bool
 	streams map[string]*StreamStatus
 
+	// Configs of resources that were created through the stream manager, or
+	// provided with OptResourceConfig, by type and id.
+	resources map[docs.Type]map[string]any
+
 	manager    bundle.NewManagement
 	apiEnabled bool
 
-	lock sync.Mutex
+	lock   sync.Mutex
+	txLock sync.Mutex
 }
 
 // New creates a new stream manager.Type.
 func New(mgr bundle.NewManagement, opts ...func(*Type)) *Type {
 	t := &Type{
 		streams:    map[string]*StreamStatus{},
+		resources:  map[docs.Type]map[string]any{},
 		apiEnabled: true,
 		manager:    mgr,
 	}
@@ -139,6 +156,10 @@ func (m *Type) Create(id string, conf stream.Config) error {
 		return ErrStreamExists
 	}
 
+	if err := m.checkQuotaBatching(conf); err != nil {
+		return err
+	}
+
 	strmFlatMetrics := metrics.NewLocal()
 	sMgr := m.manager.ForStream(id).WithAddedMetrics(strmFlatMetrics)
 
@@ -148,10 +169,25 @@ func (m *Type) Create(id string, conf stream.Config) error {
 	// This seems a bit wonky but we can't rule out a race condition between
 	// the stream terminating and setClosed and actually initialising a status.
 	wrapper := newStreamStatus(conf, strmFlatMetrics)
-	strm, err := stream.New(conf, sMgr, stream.OptOnClose(func() {
-		wrapper.setClosed()
-	}))
+	opts := []func(*stream.Type){
+		stream.OptOnClose(func() {
+			if wrapper.quotas != nil {
+				wrapper.quotas.close()
+			}
+			wrapper.setClosed()
+		}),
+	}
+	if !conf.Quotas.IsZero() {
+		threadsLimited := applyThreadQuota(&conf)
+		wrapper.quotas = newQuotaGate(conf, threadsLimited, sMgr.Metrics())
+		opts = append(opts, stream.OptWrapTransactions(wrapper.quotas.wrap))
+	}
+
+	strm, err := stream.New(conf, sMgr, opts...)
 	if err != nil {
+		if wrapper.quotas != nil {
+			wrapper.quotas.close()
+		}
 		return err
 	}
 
@@ -2,10 +2,14 @@ package manager
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/component"
@@ -113,3 +117,53 @@ func TestTypeBasicClose(t *testing.T) {
 		t.Errorf("Unexpected error: %v != %v", act, exp)
 	}
 }
+
+func TestTypeStreamCRUDWaitsForTransactions(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	res, err := bmanager.New(bmanager.NewResourceConfig())
+	require.NoError(t, err)
+
+	mgr := New(res)
+	t.Cleanup(func() {
+		require.NoError(t, mgr.Stop(ctx))
+	})
+	require.NoError(t, mgr.Create("foo", harmlessConf(t)))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/streams", mgr.HandleStreamsCRUD)
+	router.HandleFunc("/streams/{id}", mgr.HandleStreamCRUD)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("DELETE", "/streams/foo", http.NoBody),
+		httptest.NewRequest("POST", "/streams", strings.NewReader(`{}`)),
+	} {
+		// Hold the lock as if a transaction were being applied.
+		mgr.txLock.Lock()
+
+		doneChan := make(chan int)
+		go func(req *http.Request) {
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, req)
+			doneChan <- response.Code
+		}(req)
+
+		select {
+		case <-doneChan:
+			t.Fatalf("%v %v completed during a transaction", req.Method, req.URL)
+		case <-time.After(time.Millisecond * 50):
+		}
+		mgr.txLock.Unlock()
+
+		select {
+		case code := <-doneChan:
+			require.Equal(t, http.StatusOK, code)
+		case <-ctx.Done():
+			t.Fatal("timed out")
+		}
+	}
+
+	_, err = mgr.Read("foo")
+	require.ErrorIs(t, err, ErrStreamDoesNotExist)
+}
@@ -0,0 +1,55 @@
+package stream
+
+import (
+	"github.com/benthosdev/benthos/v4/internal/docs"
+)
+
+const (
+	fieldQuotas                    = "quotas"
+	fieldQuotasMaxInFlight         = "max_in_flight"
+	fieldQuotasMaxInFlightBytes    = "max_in_flight_bytes"
+	fieldQuotasMaxProcessorThreads = "max_processor_threads"
+)
+
+func quotasSpec() docs.FieldSpec {
+	return docs.FieldObject(
+		fieldQuotas, "Limits on the resources that a stream may consume, which are enforced when running in [streams mode](/docs/guides/streams_mode/about) in order to prevent a single stream from starving others running within the same process. When a limit is reached the stream applies back pressure to its input until messages are acknowledged.",
+	).WithChildren(
+		docs.FieldInt(fieldQuotasMaxInFlight, "The maximum number of messages that may be processed or delivered by the stream at any given time. Messages held by a buffer are not counted. An output batching policy must be able to flush within this limit, either with a `count` that does not exceed it or with a `period`, otherwise the stream is rejected. If zero there is no limit.").HasDefault(0),
+		docs.FieldInt(fieldQuotasMaxInFlightBytes, "The maximum total size in bytes of message batches that may be processed or delivered by the stream at any given time. Messages held by a buffer are not counted, and so the memory of buffered messages should be limited with the buffer itself. An output batching policy must be able to flush within this limit, either with a `byte_size` that does not exceed it or with a `period`, otherwise the stream is rejected. If zero there is no limit.").HasDefault(0),
+		docs.FieldInt(fieldQuotasMaxProcessorThreads, "The maximum number of processing threads that the pipeline of the stream may use, which caps the value of `pipeline.threads`. If zero there is no limit.").HasDefault(0),
+	).Advanced().Optional()
+}
+
+// QuotasConfig describes limits on the resources that a stream may consume.
+type QuotasConfig struct {
+	MaxInFlight         int   `yaml:"max_in_flight"`
+	MaxInFlightBytes    int64 `yaml:"max_in_flight_bytes"`
+	MaxProcessorThreads int   `yaml:"max_processor_threads"`
+}
+
+// IsZero returns true if none of the quotas are set.
+func (q QuotasConfig) IsZero() bool {
+	return q.MaxInFlight <= 0 && q.MaxInFlightBytes <= 0 && q.MaxProcessorThreads <= 0
+}
+
+func quotasFromParsed(pConf *docs.ParsedConfig) (conf QuotasConfig, err error) {
+	if pConf.Contains(fieldQuotasMaxInFlight) {
+		if conf.MaxInFlight, err = pConf.FieldInt(fieldQuotasMaxInFlight); err != nil {
+			return
+		}
+	}
+	if pConf.Contains(fieldQuotasMaxInFlightBytes) {
+		var maxBytes int
+		if maxBytes, err = pConf.FieldInt(fieldQuotasMaxInFlightBytes); err != nil {
+			return
+		}
+		conf.MaxInFlightBytes = int64(maxBytes)
+	}
+	if pConf.Contains(fieldQuotasMaxProcessorThreads) {
+		if conf.MaxProcessorThreads, err = pConf.FieldInt(fieldQuotasMaxProcessorThreads); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -29,6 +29,8 @@ type Type struct {
 
 	manager bundle.NewManagement
 
+	wrapTransactions func(<-chan message.Transaction) <-chan message.Transaction
+
 	onClose func()
 	closed  uint32
 }
@@ -87,6 +89,16 @@ func OptOnClose(onClose func()) func(*Type) {
 	}
 }
 
+// OptWrapTransactions sets a closure that wraps the channel of transactions
+// consumed by the pipeline layer of the stream, or the output layer when there
+// are no processors. This can be used in order to observe or apply back
+// pressure to transactions after they have left the input and buffer layers.
+func OptWrapTransactions(fn func(<-chan message.Transaction) <-chan message.Transaction) func(*Type) {
+	return func(t *Type) {
+		t.wrapTransactions = fn
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // IsReady returns a boolean indicating whether both the input and output layers
@@ -128,6 +140,9 @@ func (t *Type) start() (err error) {
 		}
 		nextTranChan = t.bufferLayer.TransactionChan()
 	}
+	if t.wrapTransactions != nil {
+		nextTranChan = t.wrapTransactions(nextTranChan)
+	}
 	if t.pipelineLayer != nil {
 		if err = t.pipelineLayer.Consume(nextTranChan); err != nil {
 			return
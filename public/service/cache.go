@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/benthosdev/benthos/v4/internal/component"
@@ -39,6 +40,58 @@ type Cache interface {
 	Closer
 }
 
+// CounterCache is an optional interface that can be implemented by caches in
+// order to support atomically incrementing integer values, which allows
+// components to maintain counters without a racy read-modify-write.
+//
+// Caches obtained with Resources.AccessCache always implement this interface,
+// but fall back to a Get followed by a Set for cache plugins that do not
+// implement it, which is only atomic with respect to other increments made
+// through the same cache resource within this process.
+type CounterCache interface {
+	Cache
+
+	// Incr atomically increments the integer value of a key by delta and
+	// returns the result. Keys that do not exist are treated as zero. The ttl
+	// is applied in the same way as Set, and an error is returned if the
+	// existing value is not an integer.
+	Incr(ctx context.Context, key string, delta int64, ttl *time.Duration) (int64, error)
+}
+
+// MultiGetCache is an optional interface that can be implemented by caches in
+// order to obtain the values of multiple keys in as few requests as possible.
+//
+// Caches obtained with Resources.AccessCache always implement this interface,
+// but fall back to obtaining each key individually for cache plugins that do
+// not implement it.
+type MultiGetCache interface {
+	Cache
+
+	// GetMulti attempts to obtain the values of multiple keys. Keys that do
+	// not exist are omitted from the result, and an error is returned if the
+	// operation fails.
+	GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error)
+}
+
+// ErrCacheScanUnsupported is returned when attempting to scan a cache that
+// does not implement ScannableCache.
+var ErrCacheScanUnsupported = cache.ErrScanUnsupported
+
+// ScannableCache is an optional interface that can be implemented by caches in
+// order to support enumerating the keys they hold.
+//
+// Caches obtained with Resources.AccessCache implement this interface only when
+// the underlying cache supports scanning.
+type ScannableCache interface {
+	Cache
+
+	// Scan calls fn with each key held by the cache that begins with prefix,
+	// along with its value. Keys are visited in no particular order, and keys
+	// modified during a scan may or may not be visited. The scan stops when
+	// fn returns an error, which is then returned by Scan.
+	Scan(ctx context.Context, prefix string, fn func(key string, value []byte) error) error
+}
+
 // CacheItem represents an individual cache item.
 type CacheItem struct {
 	Key   string
@@ -61,11 +114,20 @@ type batchedCache interface {
 type airGapCache struct {
 	c  Cache
 	cm batchedCache
+	cc CounterCache
+	mg MultiGetCache
+	sc ScannableCache
+
+	// Serialises increments of caches that do not implement CounterCache.
+	incrMut sync.Mutex
 }
 
 func newAirGapCache(c Cache, stats metrics.Type) cache.V1 {
 	ag := &airGapCache{c: c, cm: nil}
 	ag.cm, _ = c.(batchedCache)
+	ag.cc, _ = c.(CounterCache)
+	ag.mg, _ = c.(MultiGetCache)
+	ag.sc, _ = c.(ScannableCache)
 	return cache.MetricsForCache(ag, stats)
 }
 
@@ -77,6 +139,24 @@ func (a *airGapCache) Get(ctx context.Context, key string) ([]byte, error) {
 	return b, err
 }
 
+func (a *airGapCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	if a.mg != nil {
+		return a.mg.GetMulti(ctx, keys...)
+	}
+	values := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		b, err := a.Get(ctx, k)
+		if err != nil {
+			if errors.Is(err, component.ErrKeyNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		values[k] = b
+	}
+	return values, nil
+}
+
 func (a *airGapCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	return a.c.Set(ctx, key, value, ttl)
 }
@@ -109,10 +189,30 @@ func (a *airGapCache) Add(ctx context.Context, key string, value []byte, ttl *ti
 	return err
 }
 
+func (a *airGapCache) Incr(ctx context.Context, key string, delta int64, ttl *time.Duration) (int64, error) {
+	if a.cc != nil {
+		return a.cc.Incr(ctx, key, delta, ttl)
+	}
+	a.incrMut.Lock()
+	defer a.incrMut.Unlock()
+	return cache.IncrNonAtomic(ctx, a, key, delta, ttl)
+}
+
 func (a *airGapCache) Delete(ctx context.Context, key string) error {
 	return a.c.Delete(ctx, key)
 }
 
+func (a *airGapCache) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) error) error {
+	if a.sc != nil {
+		return a.sc.Scan(ctx, prefix, fn)
+	}
+	return cache.ErrScanUnsupported
+}
+
+func (a *airGapCache) ScanSupported() bool {
+	return a.sc != nil
+}
+
 func (a *airGapCache) Close(ctx context.Context) error {
 	return a.c.Close(ctx)
 }
@@ -124,7 +224,10 @@ type reverseAirGapCache struct {
 	c cache.V1
 }
 
-func newReverseAirGapCache(c cache.V1) *reverseAirGapCache {
+func newReverseAirGapCache(c cache.V1) Cache {
+	if cache.ScanSupported(c) {
+		return &reverseAirGapScannableCache{reverseAirGapCache{c}}
+	}
 	return &reverseAirGapCache{c}
 }
 
@@ -136,6 +239,10 @@ func (r *reverseAirGapCache) Get(ctx context.Context, key string) ([]byte, error
 	return b, err
 }
 
+func (r *reverseAirGapCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	return cache.GetMulti(ctx, r.c, keys...)
+}
+
 func (r *reverseAirGapCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	return r.c.Set(ctx, key, value, ttl)
 }
@@ -147,6 +254,10 @@ func (r *reverseAirGapCache) Add(ctx context.Context, key string, value []byte,
 	return
 }
 
+func (r *reverseAirGapCache) Incr(ctx context.Context, key string, delta int64, ttl *time.Duration) (int64, error) {
+	return cache.Incr(ctx, r.c, key, delta, ttl)
+}
+
 func (r *reverseAirGapCache) Delete(ctx context.Context, key string) error {
 	return r.c.Delete(ctx, key)
 }
@@ -154,3 +265,12 @@ func (r *reverseAirGapCache) Delete(ctx context.Context, key string) error {
 func (r *reverseAirGapCache) Close(ctx context.Context) error {
 	return r.c.Close(ctx)
 }
+
+// Implements ScannableCache around a types.Cache that supports scanning.
+type reverseAirGapScannableCache struct {
+	reverseAirGapCache
+}
+
+func (r *reverseAirGapScannableCache) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) error) error {
+	return cache.Scan(ctx, r.c, prefix, fn)
+}
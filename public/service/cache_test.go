@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -490,3 +491,123 @@ func TestCacheReverseAirGapDelete(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, map[string]testCacheItem{}, rl.m)
 }
+
+func TestCacheAirGapIncrFallback(t *testing.T) {
+	ctx := context.Background()
+	rl := &closableCache{
+		m: map[string]testCacheItem{},
+	}
+	agrl := newAirGapCache(rl, metrics.Noop()).(cache.CounterV1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				_, err := agrl.Incr(ctx, "foo", 1, nil)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	n, err := agrl.Incr(ctx, "foo", -50, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(50), n)
+	assert.Equal(t, "50", string(rl.m["foo"].b))
+}
+
+type closableCacheCounter struct {
+	*closableCache
+
+	incrs int
+}
+
+func (c *closableCacheCounter) Incr(ctx context.Context, key string, delta int64, ttl *time.Duration) (int64, error) {
+	c.incrs++
+	return delta, nil
+}
+
+func (c *closableCacheCounter) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	return map[string][]byte{"multi": []byte("yep")}, nil
+}
+
+func TestCacheAirGapIncrGetMultiPassthrough(t *testing.T) {
+	ctx := context.Background()
+	rl := &closableCacheCounter{
+		closableCache: &closableCache{
+			m: map[string]testCacheItem{},
+		},
+	}
+	agrl := newAirGapCache(rl, metrics.Noop())
+
+	n, err := agrl.(cache.CounterV1).Incr(ctx, "foo", 5, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	assert.Equal(t, 1, rl.incrs)
+	assert.Empty(t, rl.m)
+
+	values, err := agrl.(cache.GetMultiV1).GetMulti(ctx, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"multi": []byte("yep")}, values)
+}
+
+func TestCacheAirGapGetMultiFallback(t *testing.T) {
+	ctx := context.Background()
+	rl := &closableCache{
+		m: map[string]testCacheItem{
+			"foo": {b: []byte("bar")},
+		},
+	}
+	agrl := newAirGapCache(rl, metrics.Noop()).(cache.GetMultiV1)
+
+	values, err := agrl.GetMulti(ctx, "foo", "baz")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"foo": []byte("bar")}, values)
+}
+
+func TestCacheReverseAirGapIncrGetMulti(t *testing.T) {
+	ctx := context.Background()
+	rl := &closableCacheType{
+		m: map[string]testCacheItem{
+			"foo": {b: []byte("10")},
+		},
+	}
+	var agrl Cache = newReverseAirGapCache(rl)
+
+	n, err := agrl.(CounterCache).Incr(ctx, "foo", 3, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(13), n)
+
+	values, err := agrl.(MultiGetCache).GetMulti(ctx, "foo", "bar")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"foo": []byte("13")}, values)
+}
+
+func (c *closableCacheCounter) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) error) error {
+	return fn(prefix+"scanned", []byte("yep"))
+}
+
+func TestCacheAirGapScan(t *testing.T) {
+	ctx := context.Background()
+
+	agrl := newAirGapCache(&closableCache{}, metrics.Noop())
+	err := cache.Scan(ctx, agrl, "", func(key string, value []byte) error {
+		return nil
+	})
+	assert.Equal(t, ErrCacheScanUnsupported, err)
+
+	_, scannable := newReverseAirGapCache(agrl).(ScannableCache)
+	assert.False(t, scannable)
+
+	agrl = newAirGapCache(&closableCacheCounter{closableCache: &closableCache{}}, metrics.Noop())
+
+	var rl Cache = newReverseAirGapCache(agrl)
+	values := map[string]string{}
+	assert.NoError(t, rl.(ScannableCache).Scan(ctx, "foo", func(key string, value []byte) error {
+		values[key] = string(value)
+		return nil
+	}))
+	assert.Equal(t, map[string]string{"fooscanned": "yep"}, values)
+}
@@ -19,6 +19,27 @@ type RateLimit interface {
 	Closer
 }
 
+// ErrRateLimitCostExceedsCapacity should be returned, optionally wrapped, by
+// weighted rate limits when the cost requested exceeds the number of tokens
+// that could ever be available within a single period.
+var ErrRateLimitCostExceedsCapacity = ratelimit.ErrCostExceedsCapacity
+
+// WeightedRateLimit is an optional interface that can be implemented by rate
+// limits in order to support accessing the rate limited resource at a cost of
+// more than one token, such as the number of bytes or records being sent.
+//
+// Rate limits obtained with Resources.AccessRateLimit always implement this
+// interface, but return an error when a cost greater than one is requested from
+// a rate limit plugin that does not support it.
+type WeightedRateLimit interface {
+	RateLimit
+
+	// AccessN is equivalent to Access but consumes n tokens rather than one.
+	// ErrRateLimitCostExceedsCapacity is returned if n exceeds the number of
+	// tokens that could ever be available within a single period.
+	AccessN(ctx context.Context, n int64) (time.Duration, error)
+}
+
 //------------------------------------------------------------------------------
 
 func newAirGapRateLimit(c RateLimit, stats metrics.Type) ratelimit.V1 {
@@ -40,6 +61,10 @@ func (a *reverseAirGapRateLimit) Access(ctx context.Context) (time.Duration, err
 	return a.r.Access(ctx)
 }
 
+func (a *reverseAirGapRateLimit) AccessN(ctx context.Context, n int64) (time.Duration, error) {
+	return ratelimit.AccessN(ctx, a.r, n)
+}
+
 func (a *reverseAirGapRateLimit) Close(ctx context.Context) error {
 	return a.r.Close(ctx)
 }
@@ -86,3 +86,33 @@ func TestRateLimitReverseAirGapShutdown(t *testing.T) {
 	assert.NoError(t, agrl.Close(context.Background()))
 	assert.True(t, rl.closed)
 }
+
+type weightedRateLimitType struct {
+	closableRateLimitType
+	tokens int64
+}
+
+func (w *weightedRateLimitType) AccessN(ctx context.Context, n int64) (time.Duration, error) {
+	w.tokens += n
+	return w.next, w.err
+}
+
+func TestRateLimitAirGapAccessN(t *testing.T) {
+	ctx := context.Background()
+
+	rl := &weightedRateLimitType{}
+	agrl := newReverseAirGapRateLimit(newAirGapRateLimit(rl, metrics.Noop()))
+
+	var wrl WeightedRateLimit = agrl
+	_, err := wrl.AccessN(ctx, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), rl.tokens)
+
+	agrl = newReverseAirGapRateLimit(newAirGapRateLimit(&closableRateLimit{}, metrics.Noop()))
+
+	_, err = agrl.AccessN(ctx, 1)
+	assert.NoError(t, err)
+
+	_, err = agrl.AccessN(ctx, 10)
+	assert.Error(t, err)
+}